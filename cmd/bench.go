@@ -0,0 +1,112 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "measure the throughput of the configured filters and handlers",
+	Long: `
+Generates synthetic pod events in a fake cluster and sends them through the controller
+and the handlers configured in ~/.kubewatch.yaml: namespaces, queue rate limits, handler
+filters, transforms, routes and storm summaries. The handlers discard the events unless
+--send is given. Prints the number of deliveries, the throughput and the latencies.
+
+Examples:
+  # a storm of 10000 events about 500 pods, as fast as possible
+  kubewatch bench --events 10000 --objects 500
+
+  # a 10 minutes soak at 50 events per second, with slow and failing receivers
+  kubewatch bench --events 30000 --rate 50 --latency 100ms --failure-rate 0.05`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		// the events are only logged on demand, logs would slow the pipeline down
+		if logLevel == "" && os.Getenv("LOG_LEVEL") == "" {
+			conf.Log.Level = "warning"
+		}
+		configureLogging(conf)
+
+		var opts client.BenchOptions
+		opts.Events, _ = cmd.Flags().GetInt("events")
+		opts.Objects, _ = cmd.Flags().GetInt("objects")
+		opts.Namespaces, _ = cmd.Flags().GetStringSlice("namespace")
+		opts.Rate, _ = cmd.Flags().GetFloat64("rate")
+		opts.Latency, _ = cmd.Flags().GetDuration("latency")
+		opts.FailureRate, _ = cmd.Flags().GetFloat64("failure-rate")
+		opts.Settle, _ = cmd.Flags().GetDuration("settle")
+		opts.Send, _ = cmd.Flags().GetBool("send")
+
+		result, err := client.Bench(conf, opts)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		printBench(os.Stdout, result)
+	},
+}
+
+// printBench prints the report of a bench
+func printBench(out io.Writer, result client.BenchResult) {
+	fmt.Fprintf(out, "generated:  %d events\n", result.Generated)
+	names := make([]string, 0, len(result.Delivered))
+	for name := range result.Delivered {
+		names = append(names, name)
+	}
+	for name := range result.Failed {
+		if _, ok := result.Delivered[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "%-11s %d delivered, %d failed\n", name+":", result.Delivered[name], result.Failed[name])
+	}
+	if result.Summaries > 0 {
+		fmt.Fprintf(out, "storms:     %d notifications\n", result.Summaries)
+	}
+	fmt.Fprintf(out, "elapsed:    %s\n", result.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "throughput: %.1f deliveries/s\n", result.Throughput())
+	fmt.Fprintf(out, "latency:    p50 %s, p99 %s, max %s\n",
+		result.Latency(0.5).Round(time.Microsecond), result.Latency(0.99).Round(time.Microsecond), result.Latency(1).Round(time.Microsecond))
+}
+
+func init() {
+	RootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().Int("events", 1000, "Number of synthetic events")
+	benchCmd.Flags().Int("objects", 100, "Number of pods the events are about, each created, updated and deleted in turn")
+	benchCmd.Flags().StringSlice("namespace", nil, "Namespaces the pods are spread over, the watched namespaces when empty")
+	benchCmd.Flags().Float64("rate", 0, "Events generated per second, 0 as fast as possible")
+	benchCmd.Flags().Duration("latency", 0, "Time each delivery takes, simulating slow receivers")
+	benchCmd.Flags().Float64("failure-rate", 0, "Fraction of the deliveries failing, between 0 and 1")
+	benchCmd.Flags().Duration("settle", time.Second, "Time without handled events after which the bench ends, once every event is generated")
+	benchCmd.Flags().Bool("send", false, "Send the events to the configured handlers instead of discarding them")
+}
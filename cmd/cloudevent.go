@@ -27,7 +27,7 @@ var cloudEventConfigCmd = &cobra.Command{
 	Short: "specific cloudevent configuration",
 	Long:  `specific cloudevent configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -0,0 +1,120 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "output shell completion code",
+	Long: `
+Outputs shell completion code for bash, zsh or fish. To load completions:
+
+  bash: source <(kubewatch completion bash)
+  zsh:  source <(kubewatch completion zsh)
+  fish: kubewatch completion fish | source`,
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Args:      cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := genCompletion(os.Stdout, RootCmd, args[0]); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func genCompletion(w io.Writer, root *cobra.Command, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletion(w)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return genFishCompletion(w, root)
+	}
+	return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)
+}
+
+// genFishCompletion writes the fish completions of the subcommands and flags of root
+func genFishCompletion(w io.Writer, root *cobra.Command) error {
+	name := root.Name()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# fish completion for %s\n", name)
+	fmt.Fprintf(&buf, `function __%[1]s_using_command
+    set -l words
+    for word in (commandline -opc)[2..-1]
+        if not string match -q -- '-*' $word
+            set words $words $word
+        end
+    end
+    test "$words" = "$argv"
+end
+complete -c %[1]s -f
+`, name)
+	writeFishCommand(&buf, name, root, nil)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeFishCommand(buf *bytes.Buffer, name string, cmd *cobra.Command, path []string) {
+	condition := fmt.Sprintf("__%s_using_command %s", name, strings.Join(path, " "))
+	condition = strings.TrimSpace(condition)
+
+	for _, arg := range cmd.ValidArgs {
+		fmt.Fprintf(buf, "complete -c %s -n '%s' -a %s\n", name, condition, arg)
+	}
+	writeFlags := func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		line := fmt.Sprintf("complete -c %s -n '%s' -l %s", name, condition, flag.Name)
+		if flag.Shorthand != "" {
+			line += " -s " + flag.Shorthand
+		}
+		if flag.Value.Type() != "bool" {
+			line += " -r"
+		}
+		buf.WriteString(line + " -d " + fishQuote(flag.Usage) + "\n")
+	}
+	cmd.NonInheritedFlags().VisitAll(writeFlags)
+	cmd.InheritedFlags().VisitAll(writeFlags)
+
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() {
+			continue
+		}
+		fmt.Fprintf(buf, "complete -c %s -n '%s' -a %s -d %s\n", name, condition, sub.Name(), fishQuote(sub.Short))
+		writeFishCommand(buf, name, sub, append(append([]string{}, path...), sub.Name()))
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+}
@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -102,6 +104,108 @@ Display the contents of the contents of ~/.kubewatch.yaml`,
 	},
 }
 
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "validate ~/.kubewatch.yaml",
+	Long: `
+Validates ~/.kubewatch.yaml, or the given config file: handler settings, watched kinds,
+and, unless --offline is set, that the watched resources are served by the clusters`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var conf *config.Config
+		var err error
+		if len(args) > 0 {
+			conf, err = config.LoadFile(args[0])
+		} else {
+			conf, err = config.New()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid config: %v\n", err)
+			os.Exit(1)
+		}
+		conf.CheckMissingResourceEnvvars()
+
+		errs := conf.Validate()
+		errs = append(errs, controller.ValidateKinds(conf)...)
+		if _, err := controller.ParseLinks(conf.Links); err != nil {
+			errs = append(errs, fmt.Errorf("links: %v", err))
+		}
+		if _, err := client.NewEventHandler(conf); err != nil {
+			errs = append(errs, fmt.Errorf("handler: %v", err))
+		}
+		if offline, _ := cmd.Flags().GetBool("offline"); !offline {
+			clusters, err := client.Clusters(conf)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, cluster := range clusters {
+				errs = append(errs, controller.ValidateResources(conf, cluster)...)
+			}
+		}
+
+		if len(errs) > 0 {
+			fmt.Fprintln(os.Stderr, "Invalid config:")
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "  %v\n", err)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("Config is valid")
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file]",
+	Short: "migrate ~/.kubewatch.yaml to the current schema",
+	Long: `
+Migrates ~/.kubewatch.yaml, or the given config file, to the current schema: settings
+written under a former name are renamed, handler settings written outside of the handler
+section are moved into it, and the apiVersion and kind are added. The former content is
+kept in a backup file, and each change is explained`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := config.Path()
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		var changes []config.Change
+		var backup string
+		var err error
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if dryRun {
+			var b, migrated []byte
+			if b, err = os.ReadFile(path); err == nil {
+				if migrated, changes, err = config.Migrate(b); err == nil {
+					fmt.Print(string(migrated))
+				}
+			}
+		} else {
+			changes, backup, err = config.MigrateFile(path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot migrate %s:\n", path)
+			for _, err := range strings.Split(err.Error(), "\n") {
+				fmt.Fprintf(os.Stderr, "  %s\n", err)
+			}
+			os.Exit(1)
+		}
+
+		switch {
+		case len(changes) == 0:
+			fmt.Fprintf(os.Stderr, "%s is up to date\n", path)
+			return
+		case dryRun:
+			fmt.Fprintf(os.Stderr, "Changes to %s:\n", path)
+		default:
+			fmt.Fprintf(os.Stderr, "Migrated %s, former content saved to %s:\n", path, backup)
+		}
+		for _, change := range changes {
+			fmt.Fprintf(os.Stderr, "  %v\n", change)
+		}
+	},
+}
+
 func init() {
 	RootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(
@@ -109,7 +213,11 @@ func init() {
 		configTestCmd,
 		configSampleCmd,
 		configViewCmd,
+		configValidateCmd,
+		configMigrateCmd,
 	)
+	configValidateCmd.Flags().Bool("offline", false, "Skip the checks requiring access to the clusters")
+	configMigrateCmd.Flags().Bool("dry-run", false, "Print the migrated config file instead of writing it")
 
 	configAddCmd.AddCommand(
 		slackConfigCmd,
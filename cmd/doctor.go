@@ -0,0 +1,63 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "check that kubewatch is ready to run",
+	Long: `
+Checks the validity of ~/.kubewatch.yaml, the connectivity to the API server,
+the RBAC permissions on every watched resource and the reachability of the
+configured handlers, and prints a readiness report`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		failed := false
+		for _, check := range client.Diagnose(conf) {
+			if check.Err != nil {
+				failed = true
+				fmt.Printf("[FAIL] %s:\n       %s\n", check.Name, strings.ReplaceAll(check.Err.Error(), "\n", "\n       "))
+				continue
+			}
+			fmt.Printf("[ OK ] %s: %s\n", check.Name, check.Detail)
+		}
+		if failed {
+			fmt.Println("\nkubewatch is not ready")
+			os.Exit(1)
+		}
+		fmt.Println("\nkubewatch is ready")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(doctorCmd)
+}
@@ -28,7 +28,7 @@ var flockConfigCmd = &cobra.Command{
 	Short: "specific flock configuration",
 	Long:  `specific flock configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -28,7 +28,7 @@ var hipchatConfigCmd = &cobra.Command{
 	Short: "specific hipchat configuration",
 	Long:  `specific hipchat configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -28,7 +28,7 @@ var larkConfigCmd = &cobra.Command{
 	Short: "specific lark configuration",
 	Long:  `specific lark configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -28,7 +28,7 @@ var mattermostConfigCmd = &cobra.Command{
 	Short: "specific mattermost configuration",
 	Long:  `specific mattermost configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
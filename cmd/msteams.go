@@ -24,11 +24,11 @@ import (
 
 // msteamsConfigCmd represents the msteams subcommand
 var msteamsConfigCmd = &cobra.Command{
-	Use:   "MS Teams FLAG",
+	Use:   "msteams",
 	Short: "specific MS Teams configuration",
 	Long:  `specific MS Teams configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
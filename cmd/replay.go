@@ -0,0 +1,91 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/bitnami-labs/kubewatch/pkg/history"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "send stored events again through the configured handlers",
+	Long: `
+Sends the events of the event store configured in ~/.kubewatch.yaml again, oldest first,
+through the configured handlers or only through the handlers given with --handler.
+Useful after a chat outage, or to fill a newly configured sink.
+
+Examples:
+  # send the events of the last 2 hours to Slack again
+  kubewatch replay --since 2h --handler slack
+
+  # list the warnings of a namespace that would be sent
+  kubewatch replay --since 24h --namespace payments --severity warning --dry-run`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		values := url.Values{}
+		for _, name := range []string{"cluster", "namespace", "kind", "name", "severity", "since"} {
+			if value, _ := cmd.Flags().GetString(name); value != "" {
+				values.Set(name, value)
+			}
+		}
+		query, err := history.ParseQuery(values)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		query.Limit, _ = cmd.Flags().GetInt("limit")
+
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		opts := client.ReplayOptions{Query: query}
+		opts.Handlers, _ = cmd.Flags().GetStringArray("handler")
+		opts.DryRun, _ = cmd.Flags().GetBool("dry-run")
+
+		result, err := client.Replay(conf, opts, os.Stdout)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if !opts.DryRun {
+			fmt.Printf("%d deliveries, %d failed\n", result.Sent+result.Failed, result.Failed)
+		}
+		if result.Failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringArray("handler", nil, "Send the events through this handler instead of the configured ones (repeatable)")
+	replayCmd.Flags().String("since", "1h", "Send the events younger than this duration, or since an RFC 3339 time")
+	replayCmd.Flags().Int("limit", 0, "Send at most the most recent events up to this number, 0 for no limit")
+	replayCmd.Flags().String("cluster", "", "Only send the events of this cluster")
+	replayCmd.Flags().StringP("namespace", "n", "", "Only send the events of this namespace")
+	replayCmd.Flags().StringP("kind", "k", "", "Only send the events of this kind, e.g. Pod")
+	replayCmd.Flags().String("name", "", "Only send the events of objects with this name")
+	replayCmd.Flags().String("severity", "", "Only send the events of this severity: info, warning or critical")
+	replayCmd.Flags().Bool("dry-run", false, "Print the events that would be sent without sending them")
+}
@@ -46,7 +46,7 @@ var resourceConfigAddCmd = &cobra.Command{
 	Long: `
 adds specific resources to be watched`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -63,7 +63,7 @@ var resourceConfigRemoveCmd = &cobra.Command{
 	Long: `
 remove specific resources being watched`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -109,6 +109,10 @@ func configureResource(operation string, cmd *cobra.Command, conf *config.Config
 			"job",
 			&conf.Resource.Job,
 		},
+		{
+			"cronjob",
+			&conf.Resource.CronJob,
+		},
 		{
 			"pv",
 			&conf.Resource.PersistentVolume,
@@ -153,6 +157,10 @@ func configureResource(operation string, cmd *cobra.Command, conf *config.Config
 			"coreevent",
 			&conf.Resource.CoreEvent,
 		},
+		{
+			"helmrelease",
+			&conf.Resource.HelmRelease,
+		},
 	}
 
 	for _, flag := range flags {
@@ -173,6 +181,31 @@ func configureResource(operation string, cmd *cobra.Command, conf *config.Config
 		}
 	}
 
+	gvrs, err := cmd.Flags().GetStringArray("gvr")
+	if err != nil {
+		logrus.Fatal("gvr", err)
+	}
+	for _, gvr := range gvrs {
+		crd, err := config.ParseGVR(gvr)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		switch operation {
+		case "add":
+			if conf.AddCustomResource(crd) {
+				logrus.Infof("resource %s configured", crd)
+			} else {
+				logrus.Infof("resource %s already configured", crd)
+			}
+		case "remove":
+			if conf.RemoveCustomResource(crd) {
+				logrus.Infof("resource %s removed", crd)
+			} else {
+				logrus.Infof("resource %s was not configured", crd)
+			}
+		}
+	}
+
 	if err := conf.Write(); err != nil {
 		logrus.Fatal(err)
 	}
@@ -193,6 +226,7 @@ func init() {
 	resourceConfigCmd.PersistentFlags().Bool("ns", false, "watch for namespaces")
 	resourceConfigCmd.PersistentFlags().Bool("pv", false, "watch for persistent volumes")
 	resourceConfigCmd.PersistentFlags().Bool("job", false, "watch for jobs")
+	resourceConfigCmd.PersistentFlags().Bool("cronjob", false, "watch for cronjobs")
 	resourceConfigCmd.PersistentFlags().Bool("ds", false, "watch for daemonsets")
 	resourceConfigCmd.PersistentFlags().Bool("secret", false, "watch for plain secrets")
 	resourceConfigCmd.PersistentFlags().Bool("cm", false, "watch for plain configmaps")
@@ -201,5 +235,8 @@ func init() {
 	resourceConfigCmd.PersistentFlags().Bool("clusterrole", false, "watch for cluster roles")
 	resourceConfigCmd.PersistentFlags().Bool("clusterrolebinding", false, "watch for cluster roles binding")
 	resourceConfigCmd.PersistentFlags().Bool("sa", false, "watch for service accounts")
+	resourceConfigCmd.PersistentFlags().Bool("statefulset", false, "watch for statefulsets")
 	resourceConfigCmd.PersistentFlags().Bool("coreevent", false, "watch for events (old events object)")
+	resourceConfigCmd.PersistentFlags().Bool("helmrelease", false, "watch for Helm release changes")
+	resourceConfigCmd.PersistentFlags().StringArray("gvr", nil, "watch for a resource given as group/version/resource, e.g. argoproj.io/v1alpha1/rollouts (repeatable)")
 }
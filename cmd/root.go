@@ -18,19 +18,24 @@ package cmd
 
 import (
 	"fmt"
-	"net/http"
 	"os"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	c "github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/debug"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	_ "net/http/pprof"
 )
 
 var cfgFile string
 
+// logLevel and logFormat are set by the --log-level and --log-format flags
+var logLevel, logFormat string
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "kubewatch",
@@ -52,12 +57,44 @@ supported webhooks:
 `,
 
 	Run: func(cmd *cobra.Command, args []string) {
-		config := &config.Config{}
-		if err := config.Load(); err != nil {
+		if address := debugAddress(cmd); address != "" {
+			go debug.Serve(address)
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		resource, _ := cmd.Flags().GetString("config-resource")
+		if resource == "" {
+			resource = os.Getenv("KW_CONFIG_RESOURCE")
+		}
+		if resource != "" {
+			ref, err := c.ParseConfigResource(resource)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			conf, watchConfig, err := c.LoadConfigResource(ref)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			configureLogging(conf)
+			logrus.Infof("Using the configuration of the KubewatchConfig %s", ref)
+			conf.DryRun = conf.DryRun || dryRun
+			c.Run(conf, watchConfig)
+			return
+		}
+
+		conf := &config.Config{}
+		if err := conf.Load(); err != nil {
 			logrus.Fatal(err)
 		}
-		config.CheckMissingResourceEnvvars()
-		c.Run(config)
+		configureLogging(conf)
+		if warnings := conf.Warnings(); len(warnings) > 0 {
+			for _, warning := range warnings {
+				logrus.Warnf("Ignoring %v", warning)
+			}
+			logrus.Warnf("Set apiVersion: %s and kind: %s in the config file to reject unknown fields", config.APIVersion, config.Kind)
+		}
+		conf.CheckMissingResourceEnvvars()
+		conf.DryRun = conf.DryRun || dryRun
+		c.Run(conf, controller.WatchConfigFile)
 	},
 }
 
@@ -71,49 +108,68 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
-	initLogger()
+	cobra.OnInitialize(initConfig, initLogger)
 
 	// Disable Help subcommand
 	RootCmd.SetHelpCommand(&cobra.Command{
 		Use:    "no-help",
 		Hidden: true,
 	})
+	RootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Minimum level of logged entries: trace, debug, info, warning or error (env LOG_LEVEL)")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Format of the logs: text or json (env LOG_FORMATTER)")
+	RootCmd.PersistentFlags().StringVar(&config.Profile, "profile", config.Profile, "Profile of the config file to apply (env KW_PROFILE)")
+	RootCmd.PersistentFlags().StringVar(&utils.KubeconfigFlags.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file to use instead of the in-cluster config, $KUBECONFIG or ~/.kube/config")
+	RootCmd.PersistentFlags().StringVar(&utils.KubeconfigFlags.Context, "context", "", "Name of the kubeconfig context to use instead of the in-cluster config or the current context")
+	RootCmd.PersistentFlags().StringVar(&utils.KubeconfigFlags.As, "as", "", "User to impersonate")
+	RootCmd.PersistentFlags().StringArrayVar(&utils.KubeconfigFlags.AsGroups, "as-group", nil, "Group to impersonate, can be repeated")
+	RootCmd.Flags().Bool("dry-run", false, "Log the events the handler would send instead of sending them")
+	RootCmd.Flags().String("config-resource", "", "Read the configuration from the spec of a KubewatchConfig custom resource, as namespace/name, instead of the config file (env KW_CONFIG_RESOURCE)")
 	//RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kubewatch.yaml)")
+	RootCmd.Flags().String("debug-address", "", "Address of the debug server exposing pprof profiles, goroutine dumps and informer cache sizes, e.g. localhost:6060 (env KW_DEBUG_ADDRESS)")
+}
+
+// debugAddress returns the address of the debug server, empty when disabled
+func debugAddress(cmd *cobra.Command) string {
+	if address, _ := cmd.Flags().GetString("debug-address"); address != "" {
+		return address
+	}
+	if address := os.Getenv("KW_DEBUG_ADDRESS"); address != "" {
+		return address
+	}
 	if os.Getenv("ENABLE_PPROF") == "True" {
-		go func() {
-			pprofAddr := "localhost:6060"
-			logrus.Infof("Initializing pprof %s", pprofAddr)
-			err := http.ListenAndServe(pprofAddr, nil)
-			if err != nil {
-				logrus.Errorf("Failed to initialize pprof %s", err)
-			}
-		}()
+		return "localhost:6060"
 	}
+	return ""
 }
 
+// initLogger configures the logs from the flags and environment variables, until the config
+// file is read
 func initLogger() {
-	logLevel := os.Getenv("LOG_LEVEL")
-	if logLevel != "" {
-		logrus.Printf("Custom log level: %s", logLevel)
-		parsedLevel, err := logrus.ParseLevel(logLevel)
-		if err == nil {
-			logrus.Printf("Setting custom log level to: %s", logLevel)
-			logrus.SetLevel(parsedLevel)
-		} else {
-			logrus.Errorf("Illegal custom log level: %s. Ignoring custom log level", logLevel)
-		}
+	configureLogging(nil)
+}
+
+// configureLogging configures the logs from the --log-level and --log-format flags, falling
+// back to the LOG_LEVEL and LOG_FORMATTER environment variables, then to the log settings of conf
+func configureLogging(conf *config.Config) {
+	level, format := logLevel, logFormat
+	if level == "" {
+		level = os.Getenv("LOG_LEVEL")
 	}
-	logFormatter := os.Getenv("LOG_FORMATTER")
-	if logFormatter != "" {
-		logrus.Printf("Custom log formatter: %s", logFormatter)
-		if logFormatter == "json" {
-			logrus.Printf("Setting custom log formatter to: %s", logFormatter)
-			logrus.SetFormatter(new(logrus.JSONFormatter))
-		} else {
-			logrus.Errorf("Illegal custom log formatter: %s. Ignoring custom log formatter", logFormatter)
+	if format == "" {
+		format = os.Getenv("LOG_FORMATTER")
+	}
+	var modules map[string]string
+	if conf != nil {
+		if level == "" {
+			level = conf.Log.Level
+		}
+		if format == "" {
+			format = conf.Log.Format
 		}
+		modules = conf.Log.Modules
+	}
+	if err := logging.Configure(format, level, modules); err != nil {
+		logrus.Errorf("Ignoring invalid log settings: %v", err)
 	}
 }
 
@@ -28,7 +28,7 @@ var slackConfigCmd = &cobra.Command{
 	Short: "specific slack configuration",
 	Long:  `specific slack configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -28,7 +28,7 @@ var slackwebhookConfigCmd = &cobra.Command{
 	Short: "specific Slack webhook configuration",
 	Long:  `specific Slack webhook configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
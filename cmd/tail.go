@@ -0,0 +1,98 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var tailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "print the events of a running kubewatch as they happen",
+	Long: `
+Connects to the event stream of a running kubewatch and prints its events as they
+are delivered, or with --local watches the clusters of ~/.kubewatch.yaml in process.
+
+Examples:
+  # follow the events of kubewatch running in the cluster
+  kubectl port-forward deploy/kubewatch 2112 &
+  kubewatch tail --namespace payments
+
+  # watch without a running kubewatch, printing warnings only
+  kubewatch tail --local --severity warning`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		query := url.Values{}
+		for _, name := range []string{"cluster", "namespace", "kind", "name", "severity", "since"} {
+			if value, _ := cmd.Flags().GetString(name); value != "" {
+				query.Set(name, value)
+			}
+		}
+		noColor, _ := cmd.Flags().GetBool("no-color")
+		opts := client.TailOptions{Query: query, Color: !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)}
+
+		if local, _ := cmd.Flags().GetBool("local"); local {
+			// keep the controller logs from drowning the events
+			if logLevel == "" && os.Getenv("LOG_LEVEL") == "" {
+				logrus.SetLevel(logrus.WarnLevel)
+			}
+			conf, err := config.New()
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if err := client.TailLocal(conf, opts, os.Stdout); err != nil {
+				logrus.Fatal(err)
+			}
+			return
+		}
+
+		opts.URL, _ = cmd.Flags().GetString("url")
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := client.Tail(ctx, opts, os.Stdout); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+// isTerminal returns whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	RootCmd.AddCommand(tailCmd)
+	tailCmd.Flags().String("url", "http://localhost:2112", "URL of the metrics server of the running kubewatch")
+	tailCmd.Flags().Bool("local", false, "Watch the clusters of the config file in process instead of connecting to a running kubewatch")
+	tailCmd.Flags().String("cluster", "", "Only print the events of this cluster")
+	tailCmd.Flags().StringP("namespace", "n", "", "Only print the events of this namespace")
+	tailCmd.Flags().StringP("kind", "k", "", "Only print the events of this kind, e.g. Pod")
+	tailCmd.Flags().String("name", "", "Only print the events of objects with this name")
+	tailCmd.Flags().String("severity", "", "Only print the events of this severity: info, warning or critical")
+	tailCmd.Flags().String("since", "", "Print the recent events younger than this duration first, e.g. 10m")
+	tailCmd.Flags().Bool("no-color", false, "Do not color severities (env NO_COLOR)")
+}
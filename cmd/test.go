@@ -0,0 +1,72 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [handler]",
+	Short: "send a test notification through the configured handlers",
+	Long: `
+Sends a test notification through each handler configured in ~/.kubewatch.yaml,
+or only through the named one, and reports whether each delivery succeeded`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		names := client.ConfiguredHandlers(conf)
+		if len(args) == 1 {
+			names = args
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(os.Stderr, "No handler configured")
+			os.Exit(1)
+		}
+
+		failed := false
+		for _, name := range names {
+			reported, err := client.SendTestEvent(name, conf)
+			switch {
+			case err != nil:
+				failed = true
+				fmt.Printf("%s: FAILED: %v\n", name, err)
+			case !reported:
+				fmt.Printf("%s: sent (delivery errors are only logged)\n", name)
+			default:
+				fmt.Printf("%s: ok\n", name)
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(testCmd)
+}
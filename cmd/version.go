@@ -17,6 +17,13 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -25,20 +32,72 @@ var (
 	buildDate, gitCommit string
 )
 
+// versionInfo describes the build of kubewatch
+type versionInfo struct {
+	GitCommit               string `json:"gitCommit"`
+	BuildDate               string `json:"buildDate"`
+	GoVersion               string `json:"goVersion"`
+	KubernetesClientVersion string `json:"kubernetesClientVersion"`
+	Platform                string `json:"platform"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "print version",
-	Long:  `print version`,
+	Long: `
+Prints the build commit and date, the Go version and the version of the Kubernetes
+client kubewatch is built with`,
 	Run: func(cmd *cobra.Command, args []string) {
-		versionPrettyString()
+		output, _ := cmd.Flags().GetString("output")
+		if err := printVersion(os.Stdout, getVersion(), output); err != nil {
+			logrus.Fatal(err)
+		}
 	},
 }
 
-func versionPrettyString() {
-	logrus.Info("gitCommit: ", gitCommit)
-	logrus.Info("buildDate: ", buildDate)
+func getVersion() versionInfo {
+	info := versionInfo{
+		GitCommit:               gitCommit,
+		BuildDate:               buildDate,
+		GoVersion:               runtime.Version(),
+		KubernetesClientVersion: "unknown",
+		Platform:                runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range build.Deps {
+			if dep.Path == "k8s.io/client-go" {
+				info.KubernetesClientVersion = dep.Version
+			}
+		}
+		// binaries built without the Makefile ldflags still know their VCS revision
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.GitCommit == "":
+				info.GitCommit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// printVersion writes info to w as text, or as JSON when output is json
+func printVersion(w io.Writer, info versionInfo, output string) error {
+	switch output {
+	case "":
+		_, err := fmt.Fprintf(w, "gitCommit: %s\nbuildDate: %s\ngoVersion: %s\nkubernetesClientVersion: %s\nplatform: %s\n",
+			info.GitCommit, info.BuildDate, info.GoVersion, info.KubernetesClientVersion, info.Platform)
+		return err
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	return fmt.Errorf("invalid output %q, expected json", output)
 }
 
 func init() {
 	RootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().StringP("output", "o", "", "Output format, json for tooling")
 }
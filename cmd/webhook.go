@@ -30,7 +30,7 @@ var webhookConfigCmd = &cobra.Command{
 	Short: "specific webhook configuration",
 	Long:  `specific webhook configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		conf, err := config.New()
+		conf, err := config.NewRaw()
 		if err != nil {
 			logrus.Fatal(err)
 		}
@@ -19,10 +19,13 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -59,6 +62,7 @@ type Resource struct {
 	Services              bool `json:"svc"`
 	Pod                   bool `json:"po"`
 	Job                   bool `json:"job"`
+	CronJob               bool `json:"cronjob"`
 	Node                  bool `json:"node"`
 	ClusterRole           bool `json:"clusterrole"`
 	ClusterRoleBinding    bool `json:"clusterrolebinding"`
@@ -71,18 +75,112 @@ type Resource struct {
 	HPA                   bool `json:"hpa"`
 	Event                 bool `json:"event"`
 	CoreEvent             bool `json:"coreevent"`
+	// HelmRelease reports Helm release installs, upgrades, rollbacks, failures and uninstalls,
+	// read from the Secrets Helm stores releases in.
+	HelmRelease bool `json:"helmrelease"`
 }
 
 type CRD struct {
 	Group    string `json:"group"`
 	Version  string `json:"version"`
 	Resource string `json:"resource"`
+	// Conditions map the status.conditions of the resource to alerts. When set, updates
+	// are only reported when an object enters or leaves one of these conditions.
+	Conditions []ConditionRule `json:"conditions,omitempty"`
+	// Fields are extracted from the objects into their notifications
+	Fields []FieldPath `json:"fields,omitempty"`
+}
+
+// FieldPath is a field of custom resources added to their notifications
+type FieldPath struct {
+	// Name of the field in notifications, such as Revision
+	Name string `json:"name"`
+	// Path is a JSONPath expression selecting the value of the field, such as
+	// .status.sync.revision, braces being optional
+	Path string `json:"path"`
+}
+
+// Template returns the JSONPath template of the field, its path within braces
+func (f FieldPath) Template() string {
+	if strings.HasPrefix(f.Path, "{") {
+		return f.Path
+	}
+	return "{" + f.Path + "}"
+}
+
+// ConditionRule is a status condition of a custom resource that raises an alert
+type ConditionRule struct {
+	// Type of the condition, such as Ready
+	Type string `json:"type"`
+	// Status of the condition raising the alert: True, False or Unknown (default False)
+	Status string `json:"status,omitempty"`
+	// Severity of the alert: info, warning, policy-violation or critical (default warning)
+	Severity string `json:"severity,omitempty"`
+}
+
+// ParseGVR parses a resource written as group/version/resource, or version/resource
+// for the core group, e.g. argoproj.io/v1alpha1/rollouts or v1/pods
+func ParseGVR(s string) (CRD, error) {
+	parts := strings.Split(s, "/")
+	var crd CRD
+	switch len(parts) {
+	case 2:
+		crd = CRD{Version: parts[0], Resource: parts[1]}
+	case 3:
+		crd = CRD{Group: parts[0], Version: parts[1], Resource: parts[2]}
+	default:
+		return CRD{}, fmt.Errorf("invalid resource %q, expected group/version/resource", s)
+	}
+	if crd.Version == "" || crd.Resource == "" {
+		return CRD{}, fmt.Errorf("invalid resource %q, expected group/version/resource", s)
+	}
+	return crd, nil
+}
+
+func (crd CRD) String() string {
+	if crd.Group == "" {
+		return crd.Version + "/" + crd.Resource
+	}
+	return crd.Group + "/" + crd.Version + "/" + crd.Resource
+}
+
+// AddCustomResource adds crd to the watched custom resources, returning false when it is
+// already watched
+func (c *Config) AddCustomResource(crd CRD) bool {
+	for _, watched := range c.CustomResources {
+		if watched.String() == crd.String() {
+			return false
+		}
+	}
+	c.CustomResources = append(c.CustomResources, crd)
+	return true
+}
+
+// RemoveCustomResource removes crd from the watched custom resources, returning false when
+// it is not watched
+func (c *Config) RemoveCustomResource(crd CRD) bool {
+	for i, watched := range c.CustomResources {
+		if watched.String() == crd.String() {
+			c.CustomResources = append(c.CustomResources[:i], c.CustomResources[i+1:]...)
+			return true
+		}
+	}
+	return false
 }
 
 type Config struct {
+	// APIVersion of the config file schema, kubewatch.io/v1. Unknown fields are rejected
+	// in versioned config files, and only ignored in config files without apiVersion.
+	APIVersion string `json:"apiVersion" yaml:"apiVersion,omitempty"`
+	// Kind of the config file, KubewatchConfig.
+	Kind string `json:"kind" yaml:"kind,omitempty"`
+
 	// Handlers know how to send notifications to specific services.
 	Handler Handler `json:"handler"`
 
+	// HTTP configures the HTTP client shared by the handlers, pooling their connections.
+	HTTP HTTPClient `json:"http"`
+
 	//Reason   []string `json:"reason"`
 
 	// Resources to watch.
@@ -94,6 +192,719 @@ type Config struct {
 	// For watching specific namespace, leave it empty for watching all.
 	// this config is ignored when watching namespaces
 	Namespace string `json:"namespace,omitempty"`
+
+	// For watching a list of namespaces, takes precedence over namespace.
+	// Only these namespaces need to be readable by kubewatch.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// For watching the namespaces matching a label selector, e.g. "kubewatch.io/watch=true".
+	// Namespaces are picked up as they get labeled or unlabeled. Takes precedence over namespace.
+	NamespaceSelector string `json:"namespaceselector,omitempty"`
+
+	// Queue configures how events are queued before being handled.
+	Queue Queue `json:"queue"`
+
+	// Report objects already existing at startup as created. By default only objects
+	// created after kubewatch started are reported.
+	StartupInventory bool `json:"startupinventory"`
+
+	// Report Deployment rollouts as a single stream of Rollout events (rollout started,
+	// ReplicaSets created and scaled, rollout complete or failed), instead of separate
+	// Deployment and ReplicaSet updates. Requires watching deployments and replicasets.
+	Rollouts bool `json:"rollouts"`
+
+	// Report nodes being cordoned, uncordoned, drained and deleted as dedicated Node events
+	// instead of node updates. Drains are detected from pod evictions and require watching pods.
+	NodeLifecycle bool `json:"nodelifecycle"`
+
+	// Report pods evicted by the kubelet, the eviction API or the taint manager, and pods
+	// preempted by the scheduler, as PodEvicted and PodPreempted events naming the node and
+	// the preemptor. Found in pod updates and in Kubernetes Events about pods.
+	Disruptions bool `json:"disruptions"`
+
+	// Enrich Kubernetes Events with the labels, owner and namespace annotations of the object
+	// they are about, looked up in the caches of the watched resources.
+	InvolvedObjects bool `json:"involvedobjects"`
+
+	// Attach a condensed kubectl describe of failing objects to their events: owner, conditions
+	// and recent Kubernetes Events, looked up in the caches of the watched resources.
+	// Kubernetes Events are only found when the event resource is watched.
+	Describe bool `json:"describe"`
+
+	// Send a notification for every watch kubewatch lacks RBAC permissions for, as found at startup.
+	NotifyAccessDenied bool `json:"notifyaccessdenied"`
+
+	// Send a notification through every handler still working when kubewatch is failing:
+	// deliveries to the event handler fail repeatedly, or a watch is forbidden.
+	NotifyInternalErrors bool `json:"notifyinternalerrors"`
+
+	// Time allowed to handle queued events on shutdown (default 20s).
+	ShutdownTimeout time.Duration `json:"shutdowntimeout"`
+
+	// Informer resync period (e.g. 30m). Resyncs replay every cached object as an update,
+	// 0 disables them.
+	ResyncPeriod time.Duration `json:"resyncperiod"`
+
+	// Resync period per kind (e.g. Pod: 1h), overriding resyncperiod. 0 disables resyncs of the kind.
+	ResyncPeriods map[string]time.Duration `json:"resyncperiods,omitempty"`
+
+	// Kinds watched with metadata-only informers (e.g. Pod, Secret). Only metadata is
+	// cached and updates are reported only when metadata changes, cutting memory usage.
+	MetadataOnly []string `json:"metadataonly,omitempty"`
+
+	// Cache strips fields kubewatch does not need from objects before they are cached by
+	// informers, cutting memory usage on large clusters.
+	Cache Cache `json:"cache"`
+
+	// Checkpoint persists the versions of notified objects, so that they are not
+	// announced again when kubewatch restarts.
+	Checkpoint Checkpoint `json:"checkpoint"`
+
+	// Clusters to watch, leave it empty for watching the cluster kubewatch
+	// runs in (or the current kubeconfig context).
+	Clusters []Cluster `json:"clusters"`
+
+	// Name of the watched cluster attached to every event, when clusters is empty.
+	ClusterName string `json:"clustername"`
+	// Environment of the watched clusters (e.g. production), attached to every event.
+	Environment string `json:"environment"`
+	// Region of the watched clusters (e.g. eu-west-1), attached to every event.
+	Region string `json:"region"`
+	// Arbitrary key/values attached to every event (e.g. team: platform).
+	ClusterLabels map[string]string `json:"clusterlabels,omitempty"`
+
+	// Links appended to events, such as dashboards or logs of the affected object.
+	Links []Link `json:"links"`
+
+	// GroupKeys are Go templates of the keys grouping the events of a kind into a single
+	// notification thread, by kind, rendered with the event. {{controller .}} renders the
+	// kind/name of the controller of the object, e.g. Pod: "{{.Namespace}}/{{controller .}}"
+	// groups the pods of a ReplicaSet. Keys default to namespace/kind/name.
+	GroupKeys map[string]string `json:"groupkeys,omitempty"`
+
+	// CertManager enables cert-manager Certificate notifications.
+	CertManager CertManager `json:"certmanager"`
+
+	// GitOps enables health notifications for Argo Rollouts, Argo CD Applications and Flux
+	// Kustomizations and HelmReleases.
+	GitOps GitOps `json:"gitops"`
+
+	// ContainerLogs attaches the last log lines of failing containers to pod events.
+	ContainerLogs ContainerLogs `json:"containerlogs"`
+
+	// Redaction replaces sensitive values in the objects of events with [REDACTED] before
+	// they are sent: Secret data, values of env vars with sensitive names and of annotations.
+	Redaction Redaction `json:"redaction"`
+
+	// Maximum size of events sent to each handler in bytes (e.g. webhook: 65536), 0 for no
+	// limit: the size of the rendered message, or of the JSON payload with its objects for
+	// the webhook and CloudEvent handlers. Larger events are truncated, dropping objects,
+	// older log lines and long diff values first. Chat handlers have defaults below the
+	// limits of their services.
+	PayloadLimits map[string]int `json:"payloadlimits,omitempty"`
+
+	// HandlerFilters restrict the events sent to each handler (e.g. webhook) to the kinds
+	// and operations it wants, such as a ticketing webhook only receiving Jobs. Handlers
+	// without filter receive every event.
+	HandlerFilters map[string]HandlerFilter `json:"handlerfilters,omitempty"`
+
+	// Transforms rewrite fields of events before they are dispatched, such as normalizing
+	// names or mapping reasons, in order. Each sets a field to the value rendered by a Go
+	// template of the event.
+	Transforms []Transform `json:"transforms,omitempty"`
+
+	// Localization translates the messages sent to handlers, per handler.
+	Localization Localization `json:"localization"`
+
+	// Theme customizes the emoji, colors and prefixes of the messages of every handler.
+	Theme Theme `json:"theme"`
+
+	// Storm summarizes events while they arrive faster than a threshold, such as when a
+	// node failure updates thousands of pods, instead of sending each of them.
+	Storm Storm `json:"storm"`
+
+	// ImagePolicy flags created and updated workloads whose containers use latest tags or
+	// images from registries not allowed, with policy-violation events.
+	ImagePolicy ImagePolicy `json:"imagepolicy"`
+
+	// PodSecurity flags created and updated workloads of restricted namespaces requesting
+	// privileged mode, host networking or hostPath volumes, or running as root.
+	PodSecurity PodSecurity `json:"podsecurity"`
+
+	// VolumeFailures reports pods whose volumes keep failing to attach or mount for longer
+	// than a threshold, once per pod and volume.
+	VolumeFailures VolumeFailures `json:"volumefailures"`
+
+	// SlowStarts reports pods whose containers take longer than a threshold to start, pulling
+	// their image or setting up their network, to spot registry or CNI problems early.
+	SlowStarts SlowStarts `json:"slowstarts"`
+
+	// IngressTLS periodically checks the certificates of the TLS Secrets referenced by
+	// Ingresses, reporting those about to expire.
+	IngressTLS IngressTLS `json:"ingresstls"`
+
+	// NodePressure periodically sends a digest of the nodes under memory, disk or PID
+	// pressure or evicting many pods, instead of their eviction Kubernetes Events.
+	NodePressure NodePressure `json:"nodepressure"`
+
+	// NodeInterruptions warns about nodes about to go away, tainted or conditioned by the
+	// cluster autoscaler or the cloud provider for a spot interruption or a maintenance.
+	NodeInterruptions NodeInterruptions `json:"nodeinterruptions"`
+
+	// KEDA enables health notifications for KEDA ScaledObjects.
+	KEDA KEDA `json:"keda"`
+
+	// OOMRisk warns about containers whose memory usage, read from the metrics API, nears
+	// their memory limit, before they are OOMKilled.
+	OOMRisk OOMRisk `json:"oomrisk"`
+
+	// VPA reports VerticalPodAutoscalers whose recommendations differ much from the requests
+	// of their workload, as right-sizing nudges.
+	VPA VPA `json:"vpa"`
+
+	// Karpenter watches Karpenter NodeClaims and NodePools, summarizing node provisioning,
+	// consolidation and disruption.
+	Karpenter Karpenter `json:"karpenter"`
+
+	// ClusterAutoscaler reports the node groups scaled by the cluster autoscaler, read from its
+	// status ConfigMap, instead of its Kubernetes Events about every pending pod.
+	ClusterAutoscaler ClusterAutoscaler `json:"clusterautoscaler"`
+
+	// Deprecations periodically reports objects and clients using Kubernetes APIs removed in
+	// the next minor version, and kubelets outside of the supported version skew.
+	Deprecations Deprecations `json:"deprecations"`
+
+	// DryRun logs the events the handler would send instead of sending them.
+	DryRun bool `json:"dryrun"`
+
+	// Log configures the logs of kubewatch.
+	Log Log `json:"log"`
+
+	// Heartbeat sends notifications about kubewatch itself, so that its failures are noticed.
+	Heartbeat Heartbeat `json:"heartbeat"`
+
+	// Latency sets an objective on the time from object changes to the delivery of their events.
+	Latency Latency `json:"latency"`
+
+	// History keeps the last events sent to the handler in memory, to be queried over HTTP.
+	History History `json:"history"`
+
+	// Store persists the events sent to the handler on disk, serving them to the events
+	// API and skipping events already sent before a restart.
+	Store EventStore `json:"store"`
+
+	// Outbox persists events before sending them to the handler until they are delivered,
+	// redelivering the events left undelivered when kubewatch restarts.
+	Outbox Outbox `json:"outbox"`
+
+	// Admin configures the admin API of the metrics server, managing silences at runtime.
+	Admin Admin `json:"admin"`
+
+	// Routes send events to other handlers than the handler receiving events, depending on
+	// their severity and on the time of the week, such as chat during business hours and a
+	// paging service off-hours. The first matching route wins, events matching no route are
+	// sent to the handler.
+	Routes []Route `json:"routes,omitempty"`
+
+	// Escalation re-sends the urgent events not acknowledged in time to another handler,
+	// such as a paging service. Requires the event store.
+	Escalation Escalation `json:"escalation"`
+
+	// Resolutions track problems, such as pods in CrashLoopBackOff, NotReady nodes and
+	// failed rollouts, sending a Resolved event with their duration when they clear.
+	Resolutions Resolutions `json:"resolutions"`
+
+	// Alertmanager accepts Prometheus Alertmanager webhook notifications on the metrics
+	// server, forwarding their alerts to the handler.
+	Alertmanager Alertmanager `json:"alertmanager"`
+
+	// Ownership attaches the team, owner, Slack channel and paging service of namespaces to
+	// their events, read from a metadata service or a ConfigMap, for routing and display.
+	Ownership Ownership `json:"ownership"`
+
+	// Serve a dashboard of recent events, handler deliveries and filter statistics at /ui/
+	// on the metrics server.
+	WebUI bool `json:"webui"`
+
+	// Profiles are named sets of settings (e.g. prod, staging) overriding the settings
+	// above when selected with --profile or KW_PROFILE. Maps are merged, other values
+	// such as lists are replaced.
+	Profiles map[string]yaml.Node `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+
+	// warnings about the unknown fields ignored in config files without apiVersion
+	warnings []error
+}
+
+// Queue contains the event queue configuration of the resource controllers
+type Queue struct {
+	// Maximum number of events handled per second by each controller, 0 for no limit.
+	QPS float64 `json:"qps"`
+	// Number of events that can be handled at once above qps.
+	Burst int `json:"burst"`
+	// Number of times delivery of an event is retried before giving up (default 5).
+	MaxRetries int `json:"maxretries"`
+	// Number of workers handling the events of each kind (default 1). With more than one
+	// worker, events of a same object may be handled out of order.
+	Workers int `json:"workers"`
+	// Number of workers per kind (e.g. Pod: 4), overriding workers.
+	Concurrency map[string]int `json:"concurrency,omitempty"`
+	// Time a delivery may take before it is canceled and retried, 0 for no limit.
+	DeliveryTimeout time.Duration `json:"deliverytimeout"`
+}
+
+// WorkersFor returns the number of workers handling the events of kind
+func (q Queue) WorkersFor(kind string) int {
+	for k, workers := range q.Concurrency {
+		if strings.EqualFold(k, kind) && workers > 0 {
+			return workers
+		}
+	}
+	if q.Workers > 0 {
+		return q.Workers
+	}
+	return DefaultWorkers
+}
+
+// Checkpoint contains the storage of notified object versions, set one of file or configmap
+type Checkpoint struct {
+	// File checkpoints are written to, on a persistent volume.
+	File string `json:"file"`
+	// ConfigMap checkpoints are written to, as namespace/name, in the first watched cluster.
+	ConfigMap string `json:"configmap"`
+}
+
+// Link is a deep link appended to events
+type Link struct {
+	// Text of the link.
+	Name string `json:"name"`
+	// Go template of the URL, rendered with the event: {{.Namespace}}, {{.Name}}, {{.Kind}},
+	// {{.ClusterName}}, {{.Labels}}... Use urlquery to escape values.
+	URL string `json:"url"`
+	// Only append the link to events of these kinds (e.g. Pod), all events when empty.
+	Kinds []string `json:"kinds"`
+}
+
+// Cluster contains the connection settings of a watched cluster
+type Cluster struct {
+	// Name attached to every event of this cluster, defaults to the context name.
+	Name string `json:"name"`
+	// Path of the kubeconfig file, defaults to $KUBECONFIG or ~/.kube/config.
+	Kubeconfig string `json:"kubeconfig"`
+	// Kubeconfig context to use, defaults to the current context.
+	Context string `json:"context"`
+	// Environment of this cluster, overriding the top-level environment.
+	Environment string `json:"environment"`
+	// Region of this cluster, overriding the top-level region.
+	Region string `json:"region"`
+	// Key/values attached to the events of this cluster, merged over clusterlabels.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CertManager contains cert-manager integration configuration
+type CertManager struct {
+	// Watch cert-manager Certificates for NotReady conditions and upcoming expiry.
+	Enabled bool `json:"enabled"`
+	// Alert when a certificate expires within this window (default 720h).
+	ExpiryWindow time.Duration `json:"expirywindow"`
+}
+
+// GitOps contains the Argo and Flux integration configuration. Resources that are not
+// configured as custom resources only send health notifications.
+type GitOps struct {
+	// Watch Argo Rollouts for degraded, aborted and paused rollouts.
+	ArgoRollouts bool `json:"argorollouts"`
+	// Watch Argo CD Applications for degraded, failed to sync, suspended and out of sync applications.
+	ArgoCD bool `json:"argocd"`
+	// Watch Flux Kustomizations and HelmReleases for failed reconciliations and suspensions.
+	Flux bool `json:"flux"`
+}
+
+// ImagePolicy contains the rules checked on the container images of workloads
+type ImagePolicy struct {
+	// Flag images tagged latest or untagged, unless pinned by digest.
+	DisallowLatest bool `json:"disallowlatest"`
+	// Registries images may come from, optionally with a repository prefix (e.g. ghcr.io/acme),
+	// docker.io for Docker Hub images. Images from any registry are allowed when empty.
+	AllowedRegistries []string `json:"allowedregistries,omitempty"`
+}
+
+// Enabled returns whether any image rule is configured
+func (p ImagePolicy) Enabled() bool {
+	return p.DisallowLatest || len(p.AllowedRegistries) > 0
+}
+
+// PodSecurity contains the configuration of the pod security checks
+type PodSecurity struct {
+	// Check the pod templates of workloads in restricted namespaces.
+	Enabled bool `json:"enabled"`
+	// Label selector of the restricted namespaces
+	// (default pod-security.kubernetes.io/enforce=restricted).
+	NamespaceSelector string `json:"namespaceselector"`
+}
+
+// VolumeFailures contains the configuration of the volume failure reports
+type VolumeFailures struct {
+	// Report FailedMount and FailedAttachVolume Kubernetes Events of pods.
+	Enabled bool `json:"enabled"`
+	// Time a volume keeps failing before it is reported (default 5m).
+	Threshold time.Duration `json:"threshold"`
+}
+
+// SlowStarts contains the configuration of the slow container start reports
+type SlowStarts struct {
+	// Report the watched pods slow to start, once per pod.
+	Enabled bool `json:"enabled"`
+	// Time from the scheduling of a pod, or the completion of its init containers, beyond
+	// which a container is slow to start (default 5m).
+	Threshold time.Duration `json:"threshold"`
+}
+
+// IngressTLS contains the configuration of the Ingress certificate checks
+type IngressTLS struct {
+	// Check the TLS Secrets of the Ingresses of the watched namespaces, which requires
+	// get rights on Secrets.
+	Enabled bool `json:"enabled"`
+	// Alert when a certificate expires within this window (default 720h).
+	ExpiryWindow time.Duration `json:"expirywindow"`
+	// Interval between checks (default 1h).
+	Interval time.Duration `json:"interval"`
+}
+
+// NodePressure contains the configuration of the node pressure digests
+type NodePressure struct {
+	// Send digests of the watched nodes, dropping the Evicted and EvictionThresholdMet
+	// Kubernetes Events.
+	Enabled bool `json:"enabled"`
+	// Interval between digests (default 15m).
+	Interval time.Duration `json:"interval"`
+	// Number of pods a node must evict within an interval to be listed (default 5).
+	EvictionThreshold int `json:"evictionthreshold"`
+}
+
+// NodeInterruptions contains the configuration of the node interruption warnings
+type NodeInterruptions struct {
+	// Warn when a watched node gets a known interruption taint or condition: the cluster
+	// autoscaler scale-down taint, the GKE, AWS node termination handler and AKS spot
+	// interruption and scheduled maintenance taints and conditions.
+	Enabled bool `json:"enabled"`
+	// Additional taint keys announcing an interruption, such as those of a custom
+	// termination handler.
+	Taints []string `json:"taints"`
+}
+
+// KEDA contains the configuration of the KEDA integration
+type KEDA struct {
+	// Watch ScaledObjects of keda.sh/v1alpha1 for failing triggers, ScaledObjects not ready
+	// and paused autoscaling.
+	Enabled bool `json:"enabled"`
+}
+
+// OOMRisk contains the configuration of the OOM risk warnings
+type OOMRisk struct {
+	// Check the memory working set of the containers of the watched pods periodically.
+	// Requires metrics-server and watching pods.
+	Enabled bool `json:"enabled"`
+	// Part of the memory limit, in percent, from which a container is at risk (default 90).
+	Threshold int `json:"threshold"`
+	// Interval between checks (default 1m).
+	Interval time.Duration `json:"interval"`
+}
+
+// VPA contains the configuration of the VerticalPodAutoscaler recommendation reports
+type VPA struct {
+	// Watch VerticalPodAutoscalers of autoscaling.k8s.io/v1, sending only their drift
+	// reports unless configured as custom resources.
+	Enabled bool `json:"enabled"`
+	// Difference between the target recommendation and the request of a container, in
+	// percent of the request, beyond which it is reported (default 30).
+	Threshold int `json:"threshold"`
+}
+
+// Karpenter contains the configuration of the Karpenter integration
+type Karpenter struct {
+	// Watch NodeClaims and NodePools of karpenter.sh/v1, sending only their summaries unless
+	// configured as custom resources, and drop the Kubernetes Events of Karpenter about pods.
+	Enabled bool `json:"enabled"`
+}
+
+// ClusterAutoscaler contains the configuration of the cluster autoscaler activity reports
+type ClusterAutoscaler struct {
+	// Watch the status ConfigMap of the cluster autoscaler, dropping its Kubernetes Events
+	// about pods.
+	Enabled bool `json:"enabled"`
+	// Namespace of the cluster autoscaler (default kube-system).
+	Namespace string `json:"namespace"`
+	// Name of its status ConfigMap (default cluster-autoscaler-status).
+	ConfigMap string `json:"configmap"`
+}
+
+// Deprecations contains the configuration of the deprecated API and version skew checks
+type Deprecations struct {
+	// Check watched objects, API server metrics and node versions periodically.
+	Enabled bool `json:"enabled"`
+	// Interval between checks (default 6h).
+	Interval time.Duration `json:"interval"`
+}
+
+// Log contains the logging configuration, overridden by the --log-level and --log-format
+// flags and the LOG_LEVEL and LOG_FORMATTER environment variables
+type Log struct {
+	// Level is the minimum level of logged entries: trace, debug, info, warning or error (default info).
+	Level string `json:"level"`
+	// Format is text or json (default text).
+	Format string `json:"format"`
+	// Modules overrides Level for modules, named after the pkg field of log entries without
+	// the kubewatch- prefix (e.g. rbac: debug, Pod: warning).
+	Modules map[string]string `json:"modules"`
+}
+
+// Heartbeat contains the configuration of the notifications about kubewatch itself
+type Heartbeat struct {
+	// Interval between "kubewatch alive" notifications (e.g. 1h), 0 to disable them.
+	Interval time.Duration `json:"interval"`
+	// Lifecycle sends a notification when kubewatch starts and when it stops.
+	Lifecycle bool `json:"lifecycle"`
+	// Handler receiving the notifications (e.g. slack), among the configured handlers.
+	// Defaults to the handler receiving events.
+	Handler string `json:"handler"`
+}
+
+// Latency contains the delivery latency objective
+type Latency struct {
+	// Maximum time from an object change to the delivery of its event (e.g. 1m), 0 to disable.
+	// Slower deliveries are counted by the kubewatch_delivery_slo_violations_total metric.
+	SLO time.Duration `json:"slo"`
+	// Send a warning event when deliveries exceed the SLO, at most every 10 minutes per resource.
+	Notify bool `json:"notify"`
+}
+
+// Admin contains the configuration of the admin API
+type Admin struct {
+	// Bearer token required by the admin API, which is disabled without token.
+	// Use ${VAR} or file:// to read it from a Secret.
+	Token string `json:"token"`
+}
+
+// Route sends the events matching its severities, days and hours to a handler
+type Route struct {
+	// Handler receiving the matching events (e.g. webhook), among the configured handlers.
+	Handler string `json:"handler"`
+	// Severities of the matching events (e.g. [warning, critical]), all when empty.
+	Severities []string `json:"severities,omitempty"`
+	// Days of the week of the matching events (e.g. [mon-fri] or [sat, sun]), every day
+	// when empty.
+	Days []string `json:"days,omitempty"`
+	// Hours of the matching events, as a start-end range of 24h times (e.g. 09:00-18:00),
+	// all day when empty. Ranges ending before their start span midnight.
+	Hours string `json:"hours,omitempty"`
+	// Timezone of the days and hours (e.g. Europe/Paris), UTC when empty.
+	Timezone string `json:"timezone,omitempty"`
+	// Teams owning the namespaces of the matching events, as found by ownership, all
+	// events when empty.
+	Teams []string `json:"teams,omitempty"`
+}
+
+// Escalation contains the configuration of the escalation of unacknowledged events
+type Escalation struct {
+	// Handler receiving the events not acknowledged in time (e.g. a webhook of a paging
+	// service, or smtp to an SMS gateway), among the configured handlers. Escalation is
+	// disabled when empty.
+	Handler string `json:"handler"`
+	// Delay after which unacknowledged events are escalated (default 15m).
+	After time.Duration `json:"after"`
+	// Minimum severity of the escalated events: info, warning, policy-violation or
+	// critical (default critical).
+	Severity string `json:"severity"`
+	// Signing secret of the Slack app, adding an Acknowledge button to the Slack messages
+	// of escalated events. Use ${VAR} or file:// to read it from a Secret.
+	SlackSigningSecret string `json:"slacksigningsecret"`
+}
+
+// Resolutions contains the configuration of the tracking of problems until they clear
+type Resolutions struct {
+	// Send Resolved events when problems clear. Open problems are kept in the directory of
+	// the event store when set, so that they survive restarts.
+	Enabled bool `json:"enabled"`
+	// Problems neither resolved nor reported again for this long are forgotten (default 168h).
+	Expire time.Duration `json:"expire"`
+}
+
+// Alertmanager contains the configuration of the Alertmanager webhook receiver
+type Alertmanager struct {
+	// Accept notifications at /api/v1/alerts, to be configured as a webhook receiver
+	// of Alertmanager.
+	Enabled bool `json:"enabled"`
+	// Bearer token required from Alertmanager (http_config.authorization), required when
+	// enabled. Use ${VAR} or file:// to read it from a Secret.
+	Token string `json:"token"`
+}
+
+// Transform sets a field of events to the value rendered by a template
+type Transform struct {
+	// Kinds of the transformed events (e.g. [Pod, Deployment]), all when empty.
+	Kinds []string `json:"kinds,omitempty"`
+	// Field set by the transform: name, namespace, kind, reason, status, severity,
+	// groupkey, labels.<key> or fields.<name>, the latter adding a field to the message.
+	Field string `json:"field"`
+	// Go template of the value, rendered with the event (e.g. {{.Name | lower}}). Values
+	// rendering empty leave the field unchanged.
+	Template string `json:"template"`
+}
+
+// Localization selects the language of the messages sent to each handler
+type Localization struct {
+	// Language of the messages sent to each handler (e.g. smtp: de), English when not set.
+	// Builtin catalogs translate to de, es and fr.
+	Languages map[string]string `json:"languages,omitempty"`
+	// Catalogs are YAML files mapping English messages to their translation, by language
+	// (e.g. pt: /etc/kubewatch/pt.yaml), completing the builtin catalog of the language.
+	Catalogs map[string]string `json:"catalogs,omitempty"`
+}
+
+// Theme is the emoji, colors and prefixes of messages, shared by every handler
+type Theme struct {
+	// Name of the builtin theme: default, with an emoji and a color per severity, or
+	// minimal, without emoji, messages starting with their severity such as [CRITICAL].
+	Name string `json:"name"`
+	// Emoji prefixing the messages of text-only handlers, by severity (info, warning,
+	// policy-violation or critical), overriding the theme. Empty removes the emoji.
+	Emoji map[string]string `json:"emoji,omitempty"`
+	// Colors of messages, by severity, as #RRGGBB, overriding the theme.
+	Colors map[string]string `json:"colors,omitempty"`
+	// Prefixes of messages and summaries, by severity or operation (create, update, delete,
+	// scale or notify), overriding the theme (e.g. delete: "[DELETED]"). Empty removes the
+	// prefix.
+	Prefixes map[string]string `json:"prefixes,omitempty"`
+}
+
+// HandlerFilter is the kinds and operations of the events sent to a handler
+type HandlerFilter struct {
+	// Kinds of the events sent to the handler (e.g. [Job, NodeNotReady]), all when empty.
+	Kinds []string `json:"kinds,omitempty"`
+	// Operations of the events sent to the handler: create, update, delete, scale or
+	// notify, all when empty.
+	Operations []string `json:"operations,omitempty"`
+}
+
+// Ownership contains the configuration of the ownership metadata of namespaces. It is
+// disabled unless url, configmap or annotations are set.
+type Ownership struct {
+	// URL of an HTTP endpoint returning the ownership of namespaces as a JSON object by
+	// namespace, such as {"payments": {"team": "payments", "owner": "alice@example.com",
+	// "slackChannel": "#payments", "pagerService": "payments-api"}}.
+	URL string `json:"url"`
+	// ConfigMap holding the ownership of namespaces as namespace/name, read from the first
+	// cluster. Its keys are namespaces, their values the JSON or YAML ownership.
+	ConfigMap string `json:"configmap"`
+	// Interval between reads of the ownership (default 5m).
+	Interval time.Duration `json:"interval"`
+	// Annotations of namespaces holding their ownership, taking precedence over url and
+	// configmap for the namespaces having them.
+	Annotations OwnershipAnnotations `json:"annotations"`
+}
+
+// OwnershipAnnotations names the annotations of namespaces holding their ownership
+type OwnershipAnnotations struct {
+	// Read the ownership of namespaces from their annotations.
+	Enabled bool `json:"enabled"`
+	// Annotation naming the team owning the namespace (default team).
+	Team string `json:"team"`
+	// Annotation naming the owner of the namespace (default owner).
+	Owner string `json:"owner"`
+	// Annotation naming the Slack channel of the owner (default slack-channel).
+	SlackChannel string `json:"slackchannel"`
+	// Annotation naming the paging service of the owner (default pager-service).
+	PagerService string `json:"pagerservice"`
+}
+
+// History contains the configuration of the recent events served by the events API
+type History struct {
+	// Number of events kept in memory and served by /api/v1/events (default 1000),
+	// -1 to disable the events API.
+	Size int `json:"size"`
+}
+
+// EventStore contains the configuration of the events persisted on disk
+type EventStore struct {
+	// Directory events are written to, on a persistent volume. The store is disabled
+	// when empty.
+	Path string `json:"path"`
+	// How long events are kept (default 168h).
+	Retention time.Duration `json:"retention"`
+}
+
+// HTTPClient contains the configuration of the HTTP client of the handlers
+type HTTPClient struct {
+	// Timeout of requests (default 10s).
+	Timeout time.Duration `json:"timeout"`
+	// Timeout of requests per handler (e.g. webhook: 30s), overriding timeout.
+	Timeouts map[string]time.Duration `json:"timeouts,omitempty"`
+	// Idle connections kept open per host (default 10).
+	MaxIdleConnsPerHost int `json:"maxidleconnsperhost"`
+	// How long idle connections are kept open (default 90s).
+	IdleConnTimeout time.Duration `json:"idleconntimeout"`
+	// Open a new connection per request.
+	DisableKeepAlives bool `json:"disablekeepalives"`
+	// Handlers whose request bodies are gzip compressed (e.g. [webhook, cloudevent]), for
+	// receivers accepting Content-Encoding: gzip.
+	Gzip []string `json:"gzip,omitempty"`
+}
+
+// TimeoutFor returns the timeout of the requests of handler name
+func (h HTTPClient) TimeoutFor(name string) time.Duration {
+	if timeout, ok := h.Timeouts[name]; ok {
+		return timeout
+	}
+	return h.Timeout
+}
+
+// Storm contains the configuration of the summary of event storms
+type Storm struct {
+	// Number of events within window above which events are summarized, 0 to disable.
+	Threshold int `json:"threshold"`
+	// Period events are counted over (default 1m).
+	Window time.Duration `json:"window"`
+}
+
+// Cache contains the configuration of the informer caches
+type Cache struct {
+	// Drop the field sets of metadata.managedFields, keeping their managers and times.
+	TrimManagedFields bool `json:"trimmanagedfields"`
+	// Drop the kubectl.kubernetes.io/last-applied-configuration annotation.
+	StripLastApplied bool `json:"striplastapplied"`
+	// Drop the env and envFrom of the containers of pods.
+	StripPodEnv bool `json:"strippodenv"`
+}
+
+// Outbox contains the configuration of the outbox of undelivered events
+type Outbox struct {
+	// Directory pending events are written to, on a persistent volume. The outbox is
+	// disabled when empty.
+	Path string `json:"path"`
+	// Pending events older than this are dropped instead of being redelivered (default 24h).
+	MaxAge time.Duration `json:"maxage"`
+}
+
+// Redaction contains the configuration of the redaction of sensitive values in events
+type Redaction struct {
+	// Send objects as they are, without redacting them.
+	Disabled bool `json:"disabled"`
+	// Regular expressions of env var names whose values are redacted, case insensitive, in
+	// addition to names containing PASSWORD, TOKEN, SECRET, API_KEY or CREDENTIAL.
+	EnvVars []string `json:"envvars,omitempty"`
+	// Keys of annotations whose values are redacted, as shell globs (e.g. vault.hashicorp.com/*).
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// ContainerLogs contains the configuration of logs attached to pod failure events
+type ContainerLogs struct {
+	// Fetch the logs of containers that crashed, were OOMKilled or exited with a non-zero
+	// code. Requires watching pods and the get permission on pods/log.
+	Enabled bool `json:"enabled"`
+	// Number of log lines attached (default 20).
+	Lines int64 `json:"lines"`
+	// Maximum size of attached logs in bytes (default 2048), older lines are dropped.
+	MaxBytes int `json:"maxbytes"`
 }
 
 // Slack contains slack configuration
@@ -104,6 +915,28 @@ type Slack struct {
 	Channel string `json:"channel"`
 	// Title of the message.
 	Title string `json:"title"`
+	// Post the events of a same group key as replies in the thread of the first one.
+	Threads bool `json:"threads"`
+	// Teams @-mentioned in the messages of urgent events about their objects.
+	Mentions SlackMentions `json:"mentions"`
+	// Post the events to the Slack channel of the owner of their namespace, when ownership
+	// knows it, instead of channel.
+	OwnerChannel bool `json:"ownerchannel"`
+}
+
+// SlackMentions maps the teams owning objects to the Slack user groups or users
+// @-mentioned in the messages of urgent events about them
+type SlackMentions struct {
+	// Minimum severity of the events mentioning the owning team: info, warning,
+	// policy-violation or critical (default critical).
+	Severity string `json:"severity"`
+	// Label naming the team owning an object (e.g. team).
+	Label string `json:"label"`
+	// Slack user group (S...) or user (U...) IDs by value of the team label, or else by
+	// team owning the namespace when ownership is configured.
+	Teams map[string]string `json:"teams"`
+	// Slack user group or user IDs by namespace, for objects without team.
+	Namespaces map[string]string `json:"namespaces"`
 }
 
 // SlackWebhook contains slack configuration
@@ -116,6 +949,8 @@ type SlackWebhook struct {
 	Emoji string `json:"emoji"`
 	// Slack Webhook Url.
 	Slackwebhookurl string `json:"slackwebhookurl"`
+	// Teams @-mentioned in the messages of urgent events about their objects.
+	Mentions SlackMentions `json:"mentions"`
 }
 
 // Hipchat contains hipchat configuration
@@ -174,7 +1009,7 @@ type SMTP struct {
 	From string `json:"from" yaml:"from,omitempty"`
 	// Smarthost, aka "SMTP server"; address of server used to send email.
 	Smarthost string `json:"smarthost" yaml:"smarthost,omitempty"`
-	// Subject of the outgoing emails.
+	// Subject of the outgoing emails, defaults to the summary of the event.
 	Subject string `json:"subject" yaml:"subject,omitempty"`
 	// Extra e-mail headers to be added to all outgoing messages.
 	Headers map[string]string `json:"headers" yaml:"headers,omitempty"`
@@ -207,6 +1042,17 @@ func New() (*Config, error) {
 	return c, nil
 }
 
+// NewRaw creates new config object without expanding environment variable and file
+// references, for commands rewriting the config file
+func NewRaw() (*Config, error) {
+	c := &Config{}
+	if err := c.load(false); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
 func createIfNotExist() error {
 	// create file if not exist
 	configFile := filepath.Join(configDir(), ConfigFileName)
@@ -225,8 +1071,13 @@ func createIfNotExist() error {
 	return nil
 }
 
-// Load loads configuration from config file
+// Load loads configuration from config file, replacing ${VAR} references by environment
+// variables and file:// references by the content of the files
 func (c *Config) Load() error {
+	return c.load(true)
+}
+
+func (c *Config) load(expand bool) error {
 	err := createIfNotExist()
 	if err != nil {
 		return err
@@ -242,6 +1093,9 @@ func (c *Config) Load() error {
 		return err
 	}
 
+	if expand {
+		return unmarshal(b, c)
+	}
 	if len(b) != 0 {
 		return yaml.Unmarshal(b, c)
 	}
@@ -249,6 +1103,52 @@ func (c *Config) Load() error {
 	return nil
 }
 
+// WatchedNamespaces returns the namespaces to watch, a single empty
+// namespace meaning all namespaces
+func (c *Config) WatchedNamespaces() []string {
+	seen := map[string]bool{}
+	namespaces := make([]string, 0, len(c.Namespaces))
+	for _, namespace := range c.Namespaces {
+		if namespace == "" || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		namespaces = append(namespaces, namespace)
+	}
+	if len(namespaces) == 0 {
+		return []string{c.Namespace}
+	}
+	return namespaces
+}
+
+// IsMetadataOnly returns whether kind is configured to be watched with a metadata-only informer
+func (c *Config) IsMetadataOnly(kind string) bool {
+	for _, k := range c.MetadataOnly {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resync returns the informer resync period of kind
+func (c *Config) Resync(kind string) time.Duration {
+	for k, period := range c.ResyncPeriods {
+		if strings.EqualFold(k, kind) {
+			return period
+		}
+	}
+	return c.ResyncPeriod
+}
+
+// PayloadLimit returns the maximum size of events sent to handler name in bytes, 0 for no limit
+func (c *Config) PayloadLimit(name string) int {
+	if limit, ok := c.PayloadLimits[name]; ok {
+		return limit
+	}
+	return DefaultPayloadLimits[name]
+}
+
 // CheckMissingResourceEnvvars will read the environment for equivalent config variables to set
 func (c *Config) CheckMissingResourceEnvvars() {
 	if !c.Resource.DaemonSet && os.Getenv("KW_DAEMONSET") == "true" {
@@ -275,6 +1175,9 @@ func (c *Config) CheckMissingResourceEnvvars() {
 	if !c.Resource.Job && os.Getenv("KW_JOB") == "true" {
 		c.Resource.Job = true
 	}
+	if !c.Resource.CronJob && os.Getenv("KW_CRONJOB") == "true" {
+		c.Resource.CronJob = true
+	}
 	if !c.Resource.PersistentVolume && os.Getenv("KW_PERSISTENT_VOLUME") == "true" {
 		c.Resource.PersistentVolume = true
 	}
@@ -299,6 +1202,9 @@ func (c *Config) CheckMissingResourceEnvvars() {
 	if !c.Resource.ClusterRoleBinding && os.Getenv("KW_CLUSTER_ROLE_BINDING") == "true" {
 		c.Resource.ClusterRoleBinding = true
 	}
+	if !c.Resource.HelmRelease && os.Getenv("KW_HELM_RELEASE") == "true" {
+		c.Resource.HelmRelease = true
+	}
 	if (c.Handler.Slack.Channel == "") && (os.Getenv("SLACK_CHANNEL") != "") {
 		c.Handler.Slack.Channel = os.Getenv("SLACK_CHANNEL")
 	}
@@ -310,7 +1216,9 @@ func (c *Config) CheckMissingResourceEnvvars() {
 	}
 }
 
+// Write writes c to the config file, with the apiVersion and kind of the current schema
 func (c *Config) Write() error {
+	c.APIVersion, c.Kind = APIVersion, Kind
 	f, err := os.OpenFile(getConfigFile(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
@@ -322,8 +1230,13 @@ func (c *Config) Write() error {
 	return enc.Encode(c)
 }
 
+// Path returns the path of the config file, in $KW_CONFIG or else the home directory
+func Path() string {
+	return filepath.Join(configDir(), ConfigFileName)
+}
+
 func getConfigFile() string {
-	configFile := filepath.Join(configDir(), ConfigFileName)
+	configFile := Path()
 	if _, err := os.Stat(configFile); err == nil {
 		return configFile
 	}
@@ -17,9 +17,11 @@ limitations under the License.
 package config
 
 import (
-//"io/ioutil"
-//"os"
-//"testing"
+	"reflect"
+	"testing"
+	"time"
+	//"io/ioutil"
+	//"os"
 )
 
 var configStr = `
@@ -75,3 +77,143 @@ var configStr = `
 //		t.Fatalf("TestLoad(): %+v", err)
 //	}
 //}
+
+func TestWatchedNamespaces(t *testing.T) {
+	var Tests = []struct {
+		namespace  string
+		namespaces []string
+		expected   []string
+	}{
+		{"", nil, []string{""}},
+		{"default", nil, []string{"default"}},
+		{"default", []string{"team-a", "team-b"}, []string{"team-a", "team-b"}},
+		{"", []string{"team-a", "", "team-a"}, []string{"team-a"}},
+		{"default", []string{""}, []string{"default"}},
+	}
+
+	for _, tt := range Tests {
+		c := &Config{Namespace: tt.namespace, Namespaces: tt.namespaces}
+		if got := c.WatchedNamespaces(); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("WatchedNamespaces(): expected %v, got %v", tt.expected, got)
+		}
+	}
+}
+
+func TestResync(t *testing.T) {
+	c := &Config{
+		ResyncPeriod:  30 * time.Minute,
+		ResyncPeriods: map[string]time.Duration{"pod": time.Hour, "Secret": 0},
+	}
+
+	var Tests = []struct {
+		kind     string
+		expected time.Duration
+	}{
+		{"Pod", time.Hour},
+		{"Secret", 0},
+		{"Deployment", 30 * time.Minute},
+	}
+
+	for _, tt := range Tests {
+		if got := c.Resync(tt.kind); got != tt.expected {
+			t.Errorf("Resync(%q): expected %v, got %v", tt.kind, tt.expected, got)
+		}
+	}
+}
+
+func TestPayloadLimit(t *testing.T) {
+	c := &Config{PayloadLimits: map[string]int{"slack": 0, "webhook": 65536}}
+
+	var Tests = []struct {
+		handler  string
+		expected int
+	}{
+		{"slack", 0},
+		{"webhook", 65536},
+		{"msteams", DefaultPayloadLimits["msteams"]},
+		{"cloudevent", 0},
+	}
+
+	for _, tt := range Tests {
+		if got := c.PayloadLimit(tt.handler); got != tt.expected {
+			t.Errorf("PayloadLimit(%q): expected %d, got %d", tt.handler, tt.expected, got)
+		}
+	}
+}
+
+func TestWorkersFor(t *testing.T) {
+	var Tests = []struct {
+		queue    Queue
+		kind     string
+		expected int
+	}{
+		{Queue{}, "Pod", 1},
+		{Queue{Workers: 2}, "Pod", 2},
+		{Queue{Workers: 2, Concurrency: map[string]int{"pod": 8}}, "Pod", 8},
+		{Queue{Workers: 2, Concurrency: map[string]int{"Pod": 8}}, "Node", 2},
+		{Queue{Concurrency: map[string]int{"Pod": 0}}, "Pod", 1},
+	}
+
+	for _, tt := range Tests {
+		if got := tt.queue.WorkersFor(tt.kind); got != tt.expected {
+			t.Errorf("WorkersFor(%q): expected %d, got %d", tt.kind, tt.expected, got)
+		}
+	}
+}
+
+func TestSampleIsValid(t *testing.T) {
+	c := &Config{}
+	if err := unmarshal([]byte(ConfigSample), c); err != nil {
+		t.Fatalf("ConfigSample: %v", err)
+	}
+	if len(c.Warnings()) > 0 {
+		t.Errorf("ConfigSample warnings: %v", c.Warnings())
+	}
+}
+
+func TestParseGVR(t *testing.T) {
+	var Tests = []struct {
+		gvr      string
+		expected CRD
+		err      bool
+	}{
+		{"argoproj.io/v1alpha1/rollouts", CRD{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}, false},
+		{"apps/v1/deployments", CRD{Group: "apps", Version: "v1", Resource: "deployments"}, false},
+		{"v1/pods", CRD{Version: "v1", Resource: "pods"}, false},
+		{"pods", CRD{}, true},
+		{"apps//deployments", CRD{}, true},
+		{"a/b/c/d", CRD{}, true},
+	}
+
+	for _, tt := range Tests {
+		crd, err := ParseGVR(tt.gvr)
+		if !reflect.DeepEqual(crd, tt.expected) || (err != nil) != tt.err {
+			t.Errorf("ParseGVR(%q) = %v, %v, expected %v, error: %v", tt.gvr, crd, err, tt.expected, tt.err)
+		}
+		if err == nil && crd.String() != tt.gvr {
+			t.Errorf("ParseGVR(%q).String() = %q", tt.gvr, crd.String())
+		}
+	}
+}
+
+func TestAddRemoveCustomResource(t *testing.T) {
+	rollouts := CRD{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+	certificates := CRD{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+	c := &Config{}
+
+	if !c.AddCustomResource(rollouts) || !c.AddCustomResource(certificates) {
+		t.Fatal("AddCustomResource() = false for new resources")
+	}
+	if c.AddCustomResource(rollouts) {
+		t.Error("AddCustomResource() = true for an already watched resource")
+	}
+	if !c.RemoveCustomResource(rollouts) {
+		t.Error("RemoveCustomResource() = false for a watched resource")
+	}
+	if c.RemoveCustomResource(rollouts) {
+		t.Error("RemoveCustomResource() = true for a resource no longer watched")
+	}
+	if !reflect.DeepEqual(c.CustomResources, []CRD{certificates}) {
+		t.Errorf("CustomResources = %v, expected %v", c.CustomResources, []CRD{certificates})
+	}
+}
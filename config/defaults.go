@@ -0,0 +1,186 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "time"
+
+// Defaults of settings left unset in the config file
+const (
+	DefaultShutdownTimeout         = 20 * time.Second
+	DefaultMaxRetries              = 5
+	DefaultWorkers                 = 1
+	DefaultCertificateExpiryWindow = 30 * 24 * time.Hour
+	DefaultLogLines                = 20
+	DefaultLogMaxBytes             = 2048
+	DefaultHistorySize             = 1000
+	DefaultStoreRetention          = 7 * 24 * time.Hour
+	DefaultOutboxMaxAge            = 24 * time.Hour
+	DefaultEscalationAfter         = 15 * time.Minute
+	DefaultEscalationSeverity      = "critical"
+	DefaultResolutionsExpire       = 7 * 24 * time.Hour
+	DefaultOwnershipInterval       = 5 * time.Minute
+	DefaultTeamAnnotation          = "team"
+	DefaultOwnerAnnotation         = "owner"
+	DefaultSlackChannelAnnotation  = "slack-channel"
+	DefaultPagerServiceAnnotation  = "pager-service"
+	DefaultStormWindow             = time.Minute
+	DefaultDeprecationsInterval    = 6 * time.Hour
+	DefaultVolumeFailureThreshold  = 5 * time.Minute
+	DefaultNodePressureInterval    = 15 * time.Minute
+	DefaultIngressTLSInterval      = time.Hour
+	DefaultSlowStartThreshold      = 5 * time.Minute
+	DefaultEvictionThreshold       = 5
+	DefaultVPADriftThreshold       = 30
+	DefaultOOMRiskThreshold        = 90
+	DefaultOOMRiskInterval         = time.Minute
+	DefaultPodSecuritySelector     = "pod-security.kubernetes.io/enforce=restricted"
+	DefaultConditionStatus         = "False"
+	DefaultConditionSeverity       = "warning"
+	DefaultAutoscalerNamespace     = "kube-system"
+	DefaultAutoscalerStatus        = "cluster-autoscaler-status"
+	DefaultHTTPTimeout             = 10 * time.Second
+	DefaultMaxIdleConnsPerHost     = 10
+	DefaultIdleConnTimeout         = 90 * time.Second
+)
+
+// DefaultPayloadLimits are the message size limits of handlers of services rejecting or
+// cutting large messages, overridden by payloadlimits
+var DefaultPayloadLimits = map[string]int{
+	"slack":        16000,
+	"slackwebhook": 16000,
+	"mattermost":   16000,
+	"hipchat":      10000,
+	"msteams":      25000,
+	"lark":         20000,
+}
+
+// SetDefaults sets the settings left unset to their default values
+func (c *Config) SetDefaults() {
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.Queue.MaxRetries == 0 {
+		c.Queue.MaxRetries = DefaultMaxRetries
+	}
+	if c.Queue.Workers == 0 {
+		c.Queue.Workers = DefaultWorkers
+	}
+	if c.CertManager.ExpiryWindow == 0 {
+		c.CertManager.ExpiryWindow = DefaultCertificateExpiryWindow
+	}
+	if c.ContainerLogs.Lines == 0 {
+		c.ContainerLogs.Lines = DefaultLogLines
+	}
+	if c.ContainerLogs.MaxBytes == 0 {
+		c.ContainerLogs.MaxBytes = DefaultLogMaxBytes
+	}
+	if c.History.Size == 0 {
+		c.History.Size = DefaultHistorySize
+	}
+	if c.Store.Retention == 0 {
+		c.Store.Retention = DefaultStoreRetention
+	}
+	if c.Outbox.MaxAge == 0 {
+		c.Outbox.MaxAge = DefaultOutboxMaxAge
+	}
+	if c.Escalation.After == 0 {
+		c.Escalation.After = DefaultEscalationAfter
+	}
+	if c.Escalation.Severity == "" {
+		c.Escalation.Severity = DefaultEscalationSeverity
+	}
+	if c.Resolutions.Expire == 0 {
+		c.Resolutions.Expire = DefaultResolutionsExpire
+	}
+	if c.Ownership.Interval == 0 {
+		c.Ownership.Interval = DefaultOwnershipInterval
+	}
+	if c.Ownership.Annotations.Team == "" {
+		c.Ownership.Annotations.Team = DefaultTeamAnnotation
+	}
+	if c.Ownership.Annotations.Owner == "" {
+		c.Ownership.Annotations.Owner = DefaultOwnerAnnotation
+	}
+	if c.Ownership.Annotations.SlackChannel == "" {
+		c.Ownership.Annotations.SlackChannel = DefaultSlackChannelAnnotation
+	}
+	if c.Ownership.Annotations.PagerService == "" {
+		c.Ownership.Annotations.PagerService = DefaultPagerServiceAnnotation
+	}
+	if c.HTTP.Timeout == 0 {
+		c.HTTP.Timeout = DefaultHTTPTimeout
+	}
+	if c.HTTP.MaxIdleConnsPerHost == 0 {
+		c.HTTP.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if c.HTTP.IdleConnTimeout == 0 {
+		c.HTTP.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if c.Storm.Window == 0 {
+		c.Storm.Window = DefaultStormWindow
+	}
+	if c.PodSecurity.NamespaceSelector == "" {
+		c.PodSecurity.NamespaceSelector = DefaultPodSecuritySelector
+	}
+	if c.VolumeFailures.Threshold == 0 {
+		c.VolumeFailures.Threshold = DefaultVolumeFailureThreshold
+	}
+	if c.Deprecations.Interval == 0 {
+		c.Deprecations.Interval = DefaultDeprecationsInterval
+	}
+	if c.SlowStarts.Threshold == 0 {
+		c.SlowStarts.Threshold = DefaultSlowStartThreshold
+	}
+	if c.IngressTLS.ExpiryWindow == 0 {
+		c.IngressTLS.ExpiryWindow = DefaultCertificateExpiryWindow
+	}
+	if c.IngressTLS.Interval == 0 {
+		c.IngressTLS.Interval = DefaultIngressTLSInterval
+	}
+	if c.NodePressure.Interval == 0 {
+		c.NodePressure.Interval = DefaultNodePressureInterval
+	}
+	if c.OOMRisk.Threshold == 0 {
+		c.OOMRisk.Threshold = DefaultOOMRiskThreshold
+	}
+	if c.OOMRisk.Interval == 0 {
+		c.OOMRisk.Interval = DefaultOOMRiskInterval
+	}
+	if c.VPA.Threshold == 0 {
+		c.VPA.Threshold = DefaultVPADriftThreshold
+	}
+	if c.ClusterAutoscaler.Namespace == "" {
+		c.ClusterAutoscaler.Namespace = DefaultAutoscalerNamespace
+	}
+	if c.ClusterAutoscaler.ConfigMap == "" {
+		c.ClusterAutoscaler.ConfigMap = DefaultAutoscalerStatus
+	}
+	if c.NodePressure.EvictionThreshold == 0 {
+		c.NodePressure.EvictionThreshold = DefaultEvictionThreshold
+	}
+	for i := range c.CustomResources {
+		for j := range c.CustomResources[i].Conditions {
+			rule := &c.CustomResources[i].Conditions[j]
+			if rule.Status == "" {
+				rule.Status = DefaultConditionStatus
+			}
+			if rule.Severity == "" {
+				rule.Severity = DefaultConditionSeverity
+			}
+		}
+	}
+}
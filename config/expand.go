@@ -0,0 +1,149 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileRefPrefix prefixes values read from a file, such as a mounted Secret
+const fileRefPrefix = "file://"
+
+// envRef matches ${VAR}, ${VAR:-default} and the escaped form $${VAR}
+var envRef = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// unmarshal decodes the config file content b into c, replacing environment variable
+// and file references in its values
+func unmarshal(b []byte, c *Config) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+	if node.Kind == 0 {
+		c.SetDefaults()
+		return nil
+	}
+	root := node.Content[0]
+
+	versioned, err := checkHeader(root)
+	if err != nil {
+		return err
+	}
+	if errs := unknownFields(root, reflect.TypeOf(c).Elem(), ""); len(errs) > 0 {
+		if versioned {
+			return errors.Join(errs...)
+		}
+		c.warnings = errs
+	}
+	if err := applyProfile(root, Profile); err != nil {
+		return err
+	}
+
+	if err := expand(&node, ""); err != nil {
+		return err
+	}
+	if err := node.Decode(c); err != nil {
+		return err
+	}
+	c.SetDefaults()
+	return nil
+}
+
+// Warnings returns the problems found loading a config file that were not reported as
+// errors, such as unknown fields in config files without apiVersion
+func (c *Config) Warnings() []error {
+	return c.warnings
+}
+
+// expand replaces the references in the string values of node, path being its location
+// in the config file
+func expand(node *yaml.Node, path string) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			if err := expand(n, path); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := expand(node.Content[i+1], join(path, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			if err := expand(n, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" {
+			return nil
+		}
+		value, err := expandValue(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if value != node.Value {
+			node.Value = value
+			// unquoted values are resolved again, e.g. port: ${SMTP_PORT} is decoded as a number
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	}
+	return nil
+}
+
+// expandValue returns the content of the file value refers to, when it starts with file://,
+// or value with its ${VAR} references replaced by the environment variables
+func expandValue(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, fileRefPrefix); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+
+	var err error
+	expanded := envRef.ReplaceAllStringFunc(value, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		m := envRef.FindStringSubmatch(ref)
+		if v, ok := os.LookupEnv(m[1]); ok {
+			return v
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("environment variable %s is not set", m[1])
+		}
+		return ref
+	})
+	return expanded, err
+}
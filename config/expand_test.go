@@ -0,0 +1,87 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalExpandsReferences(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("xoxb-from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KW_TEST_CHANNEL", "#alerts")
+	t.Setenv("KW_TEST_SMTP_PORT", "2525")
+
+	b := []byte(`
+handler:
+  slack:
+    token: file://` + tokenFile + `
+    channel: ${KW_TEST_CHANNEL}
+  smtp:
+    smarthost: smtp.example.com:${KW_TEST_SMTP_PORT}
+    from: ${KW_TEST_UNSET:-kubewatch@example.com}
+    to: "$${KW_TEST_CHANNEL}"
+namespaces: [default, "${KW_TEST_CHANNEL}"]
+queue:
+  workers: ${KW_TEST_SMTP_PORT}
+`)
+	c := &Config{}
+	if err := unmarshal(b, c); err != nil {
+		t.Fatalf("unmarshal() = %v", err)
+	}
+
+	var Tests = []struct {
+		field    string
+		value    interface{}
+		expected interface{}
+	}{
+		{"handler.slack.token", c.Handler.Slack.Token, "xoxb-from-file"},
+		{"handler.slack.channel", c.Handler.Slack.Channel, "#alerts"},
+		{"handler.smtp.smarthost", c.Handler.SMTP.Smarthost, "smtp.example.com:2525"},
+		{"handler.smtp.from", c.Handler.SMTP.From, "kubewatch@example.com"},
+		{"handler.smtp.to", c.Handler.SMTP.To, "${KW_TEST_CHANNEL}"},
+		{"namespaces[1]", c.Namespaces[1], "#alerts"},
+		{"queue.workers", c.Queue.Workers, 2525},
+	}
+	for _, tt := range Tests {
+		if tt.value != tt.expected {
+			t.Errorf("%s = %v, expected %v", tt.field, tt.value, tt.expected)
+		}
+	}
+}
+
+func TestUnmarshalReferenceErrors(t *testing.T) {
+	var Tests = []struct {
+		config   string
+		expected string
+	}{
+		{"handler:\n  slack:\n    token: ${KW_TEST_UNSET}\n", "handler.slack.token: environment variable KW_TEST_UNSET is not set"},
+		{"namespaces:\n- file:///nonexistent/kubewatch\n", "namespaces[0]: open /nonexistent/kubewatch"},
+	}
+
+	for _, tt := range Tests {
+		err := unmarshal([]byte(tt.config), &Config{})
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("unmarshal(%q) = %v, expected %q", tt.config, err, tt.expected)
+		}
+	}
+}
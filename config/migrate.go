@@ -0,0 +1,277 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BackupSuffix is appended to the path of config files to name their backup before
+// they are migrated
+const BackupSuffix = ".bak"
+
+// helmValues maps the Helm chart values often copied into config files to their setting
+var helmValues = map[string]string{
+	"resourcesToWatch":  "resource",
+	"namespaceToWatch":  "namespace",
+	"namespacesToWatch": "namespaces",
+}
+
+// Change is a change made by Migrate to a config file
+type Change struct {
+	// Path of the changed setting in the migrated config file
+	Path string
+	// Reason of the change
+	Reason string
+}
+
+func (c Change) String() string {
+	return c.Path + ": " + c.Reason
+}
+
+// Migrate upgrades the content b of a config file to the current schema: settings written
+// under a former name are renamed, handler settings written outside of the handler section
+// are moved into it, and the apiVersion and kind are added. It returns the migrated content
+// and the changes made, b itself when it is up to date, and an error listing the unknown
+// fields left once migrated, which have to be fixed by hand
+func Migrate(b []byte) ([]byte, []Change, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, nil, err
+	}
+	if node.Kind == 0 {
+		return b, nil, nil
+	}
+	root := node.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, errors.New("expected a map of settings")
+	}
+	versioned, err := checkHeader(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := moveHandlers(root, "")
+	if profiles := mappingValue(root, "profiles"); profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			if profile := profiles.Content[i+1]; profile.Kind == yaml.MappingNode {
+				changes = append(changes, moveHandlers(profile, join("profiles", profiles.Content[i].Value))...)
+			}
+		}
+	}
+	changes = append(changes, renameFields(root, reflect.TypeOf(Config{}), "")...)
+
+	if errs := unknownFields(root, reflect.TypeOf(Config{}), ""); len(errs) > 0 {
+		return nil, changes, errors.Join(errs...)
+	}
+	if !versioned {
+		setHeader(root)
+		changes = append(changes, Change{
+			Path:   "apiVersion",
+			Reason: fmt.Sprintf("set to %s with kind %s, unknown fields are now rejected at startup", APIVersion, Kind),
+		})
+	}
+	if len(changes) == 0 {
+		return b, nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), changes, nil
+}
+
+// MigrateFile migrates the config file at path with Migrate, keeping its former content
+// in a backup file, whose path it returns: path with BackupSuffix, or with a number after
+// it when former backups exist, which are never overwritten. The file is left untouched
+// when it is up to date.
+func MigrateFile(path string) ([]Change, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	migrated, changes, err := Migrate(b)
+	if err != nil || len(changes) == 0 {
+		return changes, "", err
+	}
+
+	backup, err := writeBackup(path, b, info.Mode().Perm())
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+		return nil, "", err
+	}
+	return changes, backup, nil
+}
+
+// writeBackup writes b to the first backup path of path not taken, and returns it
+func writeBackup(path string, b []byte, perm os.FileMode) (string, error) {
+	for i := 0; ; i++ {
+		backup := path + BackupSuffix
+		if i > 0 {
+			backup = fmt.Sprintf("%s.%d", backup, i)
+		}
+		f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(b); err != nil {
+			f.Close()
+			return "", err
+		}
+		return backup, f.Close()
+	}
+}
+
+// moveHandlers moves the handler settings of the mapping node settings, written at the top
+// level by early config files, into its handler section. A handler set in both places is
+// left to be reported as an unknown field.
+func moveHandlers(settings *yaml.Node, path string) []Change {
+	handlers := yamlFields(reflect.TypeOf(Handler{}))
+
+	var changes []Change
+	for i := 0; i+1 < len(settings.Content); {
+		key := settings.Content[i]
+		name := strings.ToLower(key.Value)
+		if _, ok := handlers[name]; !ok {
+			i += 2
+			continue
+		}
+
+		handler := mappingValue(settings, "handler")
+		if handler == nil {
+			handler = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			settings.Content = append(settings.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "handler"}, handler)
+		}
+		if handler.Kind == yaml.ScalarNode && handler.ShortTag() == "!!null" {
+			handler.Kind, handler.Tag, handler.Value = yaml.MappingNode, "!!map", ""
+		}
+		if handler.Kind != yaml.MappingNode || mappingValue(handler, name) != nil {
+			i += 2
+			continue
+		}
+
+		handler.Content = append(handler.Content, settings.Content[i], settings.Content[i+1])
+		settings.Content = append(settings.Content[:i], settings.Content[i+2:]...)
+		changes = append(changes, Change{
+			Path:   join(path, "handler."+key.Value),
+			Reason: fmt.Sprintf("moved from %s, handler settings belong in the handler section", join(path, key.Value)),
+		})
+	}
+	return changes
+}
+
+// renameFields renames the keys of node written under a former name of a field of t,
+// keeping the keys whose new name is also set, to be reported as unknown fields
+func renameFields(node *yaml.Node, t reflect.Type, path string) []Change {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// profiles hold settings of the config file
+	if t == reflect.TypeOf(yaml.Node{}) {
+		t = reflect.TypeOf(Config{})
+	}
+
+	var changes []Change
+	switch {
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if _, ok := fields[key.Value]; !ok {
+				if name, reason := formerName(t, key.Value); name != "" && mappingValue(node, name) == nil {
+					changes = append(changes, Change{
+						Path:   join(path, name),
+						Reason: fmt.Sprintf("renamed from %s, %s", key.Value, reason),
+					})
+					key.Value = name
+				}
+			}
+			if field, ok := fields[key.Value]; ok {
+				changes = append(changes, renameFields(node.Content[i+1], field, join(path, key.Value))...)
+			}
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			changes = append(changes, renameFields(node.Content[i+1], t.Elem(), join(path, node.Content[i].Value))...)
+		}
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for i, n := range node.Content {
+			changes = append(changes, renameFields(n, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return changes
+}
+
+// formerName returns the yaml key of the field of struct t that key is a former name of,
+// with the reason of the rename, empty if none
+func formerName(t reflect.Type, key string) (string, string) {
+	if name, ok := helmValues[key]; ok && t == reflect.TypeOf(Config{}) {
+		return name, "the name of the Helm chart value"
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" {
+			continue
+		}
+		if jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ","); jsonName == key {
+			return name, "the name of the setting in JSON"
+		}
+		if strings.EqualFold(name, key) {
+			return name, "keys are case sensitive"
+		}
+	}
+	return "", ""
+}
+
+// setHeader sets the apiVersion and kind of the current schema at the top of the mapping
+// node root, before the comment of its first setting
+func setHeader(root *yaml.Node) {
+	header := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiVersion"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: APIVersion},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "kind"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: Kind},
+	}
+	if len(root.Content) > 0 {
+		header[0].HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append(header, root.Content...)
+}
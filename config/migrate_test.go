@@ -0,0 +1,175 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const versionHeader = "apiVersion: kubewatch.io/v1\nkind: KubewatchConfig\n"
+
+func TestMigrate(t *testing.T) {
+	var Tests = []struct {
+		name     string
+		config   string
+		expected string
+		changes  []string
+		err      string
+	}{
+		{
+			name:     "up to date",
+			config:   versionHeader + "resource:\n  pod: true\n",
+			expected: versionHeader + "resource:\n  pod: true\n",
+		},
+		{
+			name:     "versionHeader",
+			config:   "# watched kinds\nresource:\n  pod: true\n",
+			expected: "# watched kinds\n" + versionHeader + "resource:\n  pod: true\n",
+			changes:  []string{"apiVersion: set to kubewatch.io/v1 with kind KubewatchConfig, unknown fields are now rejected at startup"},
+		},
+		{
+			name:     "top level handlers",
+			config:   versionHeader + "slack:\n  channel: \"#alerts\" # team channel\nhandler:\n  webhook:\n    url: https://example.com\n",
+			expected: versionHeader + "handler:\n  webhook:\n    url: https://example.com\n  slack:\n    channel: \"#alerts\" # team channel\n",
+			changes:  []string{"handler.slack: moved from slack, handler settings belong in the handler section"},
+		},
+		{
+			name:     "top level handlers without handler section",
+			config:   versionHeader + "msteams:\n  webhookurl: https://example.com\n",
+			expected: versionHeader + "handler:\n  msteams:\n    webhookurl: https://example.com\n",
+			changes:  []string{"handler.msteams: moved from msteams, handler settings belong in the handler section"},
+		},
+		{
+			name:     "renamed fields",
+			config:   versionHeader + "resourcesToWatch:\n  po: true\n  Deployment: true\nhandler:\n  mattermost:\n    room: alerts\n",
+			expected: versionHeader + "resource:\n  pod: true\n  deployment: true\nhandler:\n  mattermost:\n    channel: alerts\n",
+			changes: []string{
+				"resource: renamed from resourcesToWatch, the name of the Helm chart value",
+				"resource.pod: renamed from po, the name of the setting in JSON",
+				"resource.deployment: renamed from Deployment, keys are case sensitive",
+				"handler.mattermost.channel: renamed from room, the name of the setting in JSON",
+			},
+		},
+		{
+			name:     "profiles",
+			config:   versionHeader + "profiles:\n  prod:\n    slack:\n      token: xoxb-prod\n    resource:\n      svc: true\n",
+			expected: versionHeader + "profiles:\n  prod:\n    resource:\n      services: true\n    handler:\n      slack:\n        token: xoxb-prod\n",
+			changes: []string{
+				"profiles.prod.handler.slack: moved from profiles.prod.slack, handler settings belong in the handler section",
+				"profiles.prod.resource.services: renamed from svc, the name of the setting in JSON",
+			},
+		},
+		{
+			name:   "conflicting names",
+			config: versionHeader + "resource:\n  po: true\n  pod: false\n",
+			err:    "resource.po: unknown field, did you mean pod?",
+		},
+		{
+			name:   "unknown fields",
+			config: "rsources:\n  pod: true\n",
+			err:    "rsources: unknown field, did you mean resource?",
+		},
+		{
+			name:   "unsupported apiVersion",
+			config: "apiVersion: kubewatch.io/v2\nkind: KubewatchConfig\n",
+			err:    `apiVersion: unsupported version "kubewatch.io/v2", expected kubewatch.io/v1`,
+		},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, changes, err := Migrate([]byte(tt.config))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Migrate() = %v, expected %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Migrate() = %v, expected no error", err)
+			}
+			if string(migrated) != tt.expected {
+				t.Errorf("Migrate() = %q, expected %q", migrated, tt.expected)
+			}
+
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.changes, "\n") {
+				t.Errorf("Migrate() changes = %q, expected %q", got, tt.changes)
+			}
+
+			c := &Config{}
+			if err := unmarshal(migrated, c); err != nil {
+				t.Errorf("unmarshal() = %v, expected the migrated config to load", err)
+			}
+		})
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	legacy := "resource:\n  po: true\n"
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, backup, err := MigrateFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || backup != path+BackupSuffix {
+		t.Errorf("MigrateFile() = %v, %q, expected 2 changes saved to %q", changes, backup, path+BackupSuffix)
+	}
+	if b, _ := os.ReadFile(backup); string(b) != legacy {
+		t.Errorf("backup = %q, expected %q", b, legacy)
+	}
+	if b, _ := os.ReadFile(path); string(b) != versionHeader+"resource:\n  pod: true\n" {
+		t.Errorf("migrated = %q", b)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("migrated mode = %v, expected 0600", info.Mode().Perm())
+	}
+
+	// up to date config files are left untouched
+	os.Remove(path + BackupSuffix)
+	if changes, backup, err := MigrateFile(path); err != nil || len(changes) != 0 || backup != "" {
+		t.Errorf("MigrateFile() = %v, %q, %v, expected no changes", changes, backup, err)
+	}
+	if _, err := os.Stat(path + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("backup written for an up to date config file")
+	}
+
+	// former backups are kept
+	for _, expected := range []string{path + BackupSuffix, path + BackupSuffix + ".1"} {
+		if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, backup, err := MigrateFile(path); err != nil || backup != expected {
+			t.Errorf("MigrateFile() saved the former content to %q, %v, expected %q", backup, err, expected)
+		}
+	}
+	if b, _ := os.ReadFile(path + BackupSuffix); string(b) != legacy {
+		t.Errorf("first backup = %q, expected %q", b, legacy)
+	}
+}
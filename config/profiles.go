@@ -0,0 +1,94 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is the name of the profile applied to config files, set by KW_PROFILE or --profile
+var Profile = os.Getenv("KW_PROFILE")
+
+// applyProfile merges the settings of the profile called name into root, the config file
+// document root. The profiles themselves are left in root.
+func applyProfile(root *yaml.Node, name string) error {
+	profiles := mappingValue(root, "profiles")
+	if name == "" {
+		return nil
+	}
+	profile := mappingValue(profiles, name)
+	if profile == nil {
+		return fmt.Errorf("profile %q is not defined, defined profiles: %s", name, strings.Join(profileNames(profiles), ", "))
+	}
+	if profile.Kind != yaml.MappingNode {
+		return fmt.Errorf("profiles.%s: expected a map of settings", name)
+	}
+	if mappingValue(profile, "profiles") != nil {
+		return fmt.Errorf("profiles.%s.profiles: profiles cannot be nested", name)
+	}
+	merge(root, profile)
+	return nil
+}
+
+// merge sets the keys of the mapping node src in the mapping node dst, merging the maps
+// set in both
+func merge(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		existing := mappingValue(dst, key.Value)
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			merge(existing, value)
+		default:
+			*existing = *value
+		}
+	}
+}
+
+// mappingValue returns the value of key in the mapping node, nil if it is not set
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func profileNames(profiles *yaml.Node) []string {
+	names := []string{}
+	if profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			names = append(names, profiles.Content[i].Value)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+	return names
+}
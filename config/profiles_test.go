@@ -0,0 +1,89 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+var profilesConfig = `
+apiVersion: kubewatch.io/v1
+kind: KubewatchConfig
+handler:
+  slack:
+    channel: "#kubewatch"
+resource:
+  deployment: true
+namespaces: [default]
+profiles:
+  prod:
+    clustername: prod
+    handler:
+      slack:
+        token: xoxb-prod
+    namespaces: [payments, billing]
+  staging:
+    resource:
+      pod: true
+`
+
+func TestProfiles(t *testing.T) {
+	var Tests = []struct {
+		profile     string
+		clusterName string
+		slack       Slack
+		resource    Resource
+		namespaces  []string
+		err         string
+	}{
+		{"", "", Slack{Channel: "#kubewatch"}, Resource{Deployment: true}, []string{"default"}, ""},
+		{"prod", "prod", Slack{Channel: "#kubewatch", Token: "xoxb-prod"}, Resource{Deployment: true}, []string{"payments", "billing"}, ""},
+		{"staging", "", Slack{Channel: "#kubewatch"}, Resource{Deployment: true, Pod: true}, []string{"default"}, ""},
+		{"dev", "", Slack{}, Resource{}, nil, `profile "dev" is not defined, defined profiles: prod, staging`},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			defer func(profile string) { Profile = profile }(Profile)
+			Profile = tt.profile
+
+			c := &Config{}
+			err := unmarshal([]byte(profilesConfig), c)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("unmarshal() = %v, expected %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshal() = %v", err)
+			}
+			if c.ClusterName != tt.clusterName || !reflect.DeepEqual(c.Handler.Slack, tt.slack) || c.Resource != tt.resource || !reflect.DeepEqual(c.Namespaces, tt.namespaces) {
+				t.Errorf("unmarshal() = clustername %q, slack %+v, resource %+v, namespaces %v, expected %q, %+v, %+v, %v",
+					c.ClusterName, c.Handler.Slack, c.Resource, c.Namespaces, tt.clusterName, tt.slack, tt.resource, tt.namespaces)
+			}
+		})
+	}
+}
+
+func TestProfilesUnknownFields(t *testing.T) {
+	err := unmarshal([]byte("apiVersion: kubewatch.io/v1\nkind: KubewatchConfig\nprofiles:\n  prod:\n    handlr: {}\n"), &Config{})
+	if err == nil || err.Error() != "profiles.prod.handlr: unknown field, did you mean handler?" {
+		t.Errorf("unmarshal() = %v, expected an unknown field error", err)
+	}
+}
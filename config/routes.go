@@ -0,0 +1,121 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// timezones are embedded, the kubewatch image has no tzdata
+	_ "time/tzdata"
+)
+
+// weekdays maps the names of the days of the week of routes to their weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is the time of the week of the events matching a route
+type Schedule struct {
+	// days holds the matching days of the week, every day when nil
+	days map[time.Weekday]bool
+	// from and to are the start and end of the matching hours in minutes since midnight,
+	// all day when equal
+	from, to int
+	location *time.Location
+}
+
+// Schedule returns the schedule of the days, hours and timezone of r
+func (r Route) Schedule() (*Schedule, error) {
+	s := &Schedule{location: time.UTC}
+	if r.Timezone != "" {
+		location, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %v", err)
+		}
+		s.location = location
+	}
+	for _, days := range r.Days {
+		first, last, isRange := strings.Cut(strings.ToLower(days), "-")
+		if !isRange {
+			last = first
+		}
+		from, firstOK := weekdays[first]
+		to, lastOK := weekdays[last]
+		if !firstOK || !lastOK {
+			return nil, fmt.Errorf("days: invalid day %q, expected mon, tue, wed, thu, fri, sat or sun, or a range such as mon-fri", days)
+		}
+		if s.days == nil {
+			s.days = map[time.Weekday]bool{}
+		}
+		for day := from; ; day = (day + 1) % 7 {
+			s.days[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	if r.Hours != "" {
+		start, end, ok := strings.Cut(r.Hours, "-")
+		var err error
+		if ok {
+			if s.from, err = parseClock(start); err == nil {
+				s.to, err = parseClock(end)
+			}
+		}
+		if !ok || err != nil || s.from == s.to {
+			return nil, fmt.Errorf("hours: invalid range %q, expected start-end such as 09:00-18:00", r.Hours)
+		}
+	}
+	return s, nil
+}
+
+// parseClock returns the minutes since midnight of a 24h time such as 09:30
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Includes returns whether t is within the days and hours of s. The days of ranges spanning
+// midnight are the days they start.
+func (s *Schedule) Includes(t time.Time) bool {
+	t = t.In(s.location)
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case s.from < s.to && (minute < s.from || minute >= s.to):
+		return false
+	case s.from > s.to:
+		if minute >= s.to && minute < s.from {
+			return false
+		}
+		if minute < s.to {
+			// early hours of a range started the day before
+			day = (day + 6) % 7
+		}
+	}
+	return s.days == nil || s.days[day]
+}
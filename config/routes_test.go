@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleIncludes(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Wednesday 1 May 2024
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, paris)
+	}
+
+	var Tests = []struct {
+		route    Route
+		time     time.Time
+		includes bool
+	}{
+		{Route{}, at(1, 3, 0), true},
+		{Route{Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "Europe/Paris"}, at(1, 9, 0), true},
+		{Route{Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "Europe/Paris"}, at(1, 18, 0), false},
+		{Route{Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "Europe/Paris"}, at(4, 10, 0), false},
+		// UTC when no timezone is set
+		{Route{Days: []string{"mon-fri"}, Hours: "09:00-18:00"}, at(1, 10, 30), false},
+		{Route{Days: []string{"mon-fri"}, Hours: "09:00-18:00"}, at(1, 19, 30), true},
+		{Route{Days: []string{"sat", "Sun"}}, at(5, 12, 0), true},
+		{Route{Days: []string{"fri-mon"}}, at(6, 12, 0), true},
+		{Route{Days: []string{"fri-mon"}}, at(7, 12, 0), false},
+		// overnight ranges belong to the day they start
+		{Route{Days: []string{"fri"}, Hours: "18:00-09:00", Timezone: "Europe/Paris"}, at(3, 23, 0), true},
+		{Route{Days: []string{"fri"}, Hours: "18:00-09:00", Timezone: "Europe/Paris"}, at(4, 8, 0), true},
+		{Route{Days: []string{"fri"}, Hours: "18:00-09:00", Timezone: "Europe/Paris"}, at(4, 12, 0), false},
+		{Route{Days: []string{"fri"}, Hours: "18:00-09:00", Timezone: "Europe/Paris"}, at(3, 8, 0), false},
+	}
+
+	for i, test := range Tests {
+		s, err := test.route.Schedule()
+		if err != nil {
+			t.Fatalf("%d: Schedule() = %v", i, err)
+		}
+		if includes := s.Includes(test.time); includes != test.includes {
+			t.Errorf("%d: Includes(%s) = %v, expected %v", i, test.time.Format(time.RFC1123), includes, test.includes)
+		}
+	}
+}
+
+func TestScheduleErrors(t *testing.T) {
+	for _, route := range []Route{
+		{Timezone: "Mars/Olympus"},
+		{Days: []string{"monday"}},
+		{Days: []string{"mon-"}},
+		{Hours: "09:00"},
+		{Hours: "9h-18h"},
+		{Hours: "09:00-09:00"},
+	} {
+		if _, err := route.Schedule(); err == nil {
+			t.Errorf("expected %+v to be rejected", route)
+		}
+	}
+}
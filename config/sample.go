@@ -1,6 +1,11 @@
 package config
 
-var yannotated = `# Handlers know how to send notifications to specific services.
+var yannotated = `# APIVersion of the config file schema, kubewatch.io/v1. Unknown fields are rejected
+# in versioned config files, and only ignored in config files without apiVersion.
+apiVersion: kubewatch.io/v1
+# Kind of the config file, KubewatchConfig.
+kind: KubewatchConfig
+# Handlers know how to send notifications to specific services.
 handler:
   slack:
     # Slack "legacy" API token.
@@ -9,6 +14,23 @@ handler:
     channel: ""
     # Title of the message.
     title: ""
+    # Post the events of a same group key as replies in the thread of the first one.
+    threads: false
+    # Teams @-mentioned in the messages of urgent events about their objects.
+    mentions:
+      # Minimum severity of the events mentioning the owning team: info, warning,
+      # policy-violation or critical (default critical).
+      severity: ""
+      # Label naming the team owning an object (e.g. team).
+      label: ""
+      # Slack user group (S...) or user (U...) IDs by value of the team label, or else by
+      # team owning the namespace when ownership is configured.
+      teams: {}
+      # Slack user group or user IDs by namespace, for objects without team.
+      namespaces: {}
+    # Post the events to the Slack channel of the owner of their namespace, when ownership
+    # knows it, instead of channel.
+    ownerchannel: false
   hipchat:
     # Hipchat token.
     token: ""
@@ -17,7 +39,7 @@ handler:
     # URL of the hipchat server.
     url: ""
   mattermost:
-    room: ""
+    channel: ""
     url: ""
     username: ""
   flock:
@@ -27,7 +49,7 @@ handler:
     # Webhook URL.
     url: ""
     # Whether skip tls or not.
-    tlsskip: ""
+    tlsskip: false
     # Path of webhook cert. Default value is false.
     cert: ""
   cloudevent:
@@ -43,7 +65,7 @@ handler:
     from: ""
     # Smarthost, aka "SMTP server"; address of server used to send email.
     smarthost: ""
-    # Subject of the outgoing emails.
+    # Subject of the outgoing emails, defaults to the summary of the event.
     subject: ""
     # Extra e-mail headers to be added to all outgoing messages.
     headers: {}
@@ -61,26 +83,448 @@ handler:
     requireTLS: false
     # SMTP hello field (optional)
     hello: ""
+# HTTP configures the HTTP client shared by the handlers, pooling their connections.
+http:
+  # Timeout of requests (default 10s).
+  timeout: 0s
+  # Timeout of requests per handler (e.g. webhook: 30s), overriding timeout.
+  timeouts: {}
+  # Idle connections kept open per host (default 10).
+  maxidleconnsperhost: 0
+  # How long idle connections are kept open (default 90s).
+  idleconntimeout: 0s
+  # Open a new connection per request.
+  disablekeepalives: false
+  # Handlers whose request bodies are gzip compressed (e.g. [webhook, cloudevent]), for
+  # receivers accepting Content-Encoding: gzip.
+  gzip: []
 # Resources to watch.
 resource:
   deployment: false
-  rc: false
-  rs: false
-  ds: false
-  svc: false
-  po: false
+  replicationcontroller: false
+  replicaset: false
+  daemonset: false
+  services: false
+  pod: false
   job: false
+  cronjob: false
   node: false
   clusterrole: false
   clusterrolebinding: false
-  sa: false
-  pv: false
-  ns: false
+  serviceaccount: false
+  persistentvolume: false
+  namespace: false
   hpa: false
   secret: false
   configmap: false
-  ing: false
+  ingress: false
+  # Helm release installs, upgrades, rollbacks, failures and uninstalls.
+  helmrelease: false
 # For watching specific namespace, leave it empty for watching all.
 # this config is ignored when watching namespaces
 namespace: ""
+# For watching a list of namespaces, takes precedence over namespace.
+# Only these namespaces need to be readable by kubewatch.
+namespaces: []
+# For watching the namespaces matching a label selector, e.g. "kubewatch.io/watch=true".
+# Namespaces are picked up as they get labeled or unlabeled. Takes precedence over namespace.
+namespaceselector: ""
+# Queue configures how events are queued before being handled.
+queue:
+  # Maximum number of events handled per second by each controller, 0 for no limit.
+  qps: 0
+  # Number of events that can be handled at once above qps.
+  burst: 0
+  # Number of times delivery of an event is retried before giving up (default 5).
+  maxretries: 0
+  # Number of workers handling the events of each kind (default 1). With more than one
+  # worker, events of a same object may be handled out of order.
+  workers: 0
+  # Number of workers per kind (e.g. Pod: 4), overriding workers.
+  concurrency: {}
+  # Time a delivery may take before it is canceled and retried, 0 for no limit.
+  deliverytimeout: 0s
+# Report objects already existing at startup as created. By default only objects
+# created after kubewatch started are reported.
+startupinventory: false
+# Report Deployment rollouts as a single stream of Rollout events (rollout started,
+# ReplicaSets created and scaled, rollout complete or failed), instead of separate
+# Deployment and ReplicaSet updates. Requires watching deployments and replicasets.
+rollouts: false
+# Report nodes being cordoned, uncordoned, drained and deleted as dedicated Node events
+# instead of node updates. Drains are detected from pod evictions and require watching pods.
+nodelifecycle: false
+# Report pods evicted by the kubelet, the eviction API or the taint manager, and pods
+# preempted by the scheduler, as PodEvicted and PodPreempted events naming the node and
+# the preemptor. Found in pod updates and in Kubernetes Events about pods.
+disruptions: false
+# Enrich Kubernetes Events with the labels, owner and namespace annotations of the object
+# they are about, looked up in the caches of the watched resources.
+involvedobjects: false
+# Attach a condensed kubectl describe of failing objects to their events: owner, conditions
+# and recent Kubernetes Events, looked up in the caches of the watched resources.
+# Kubernetes Events are only found when the event resource is watched.
+describe: false
+# Send a notification for every watch kubewatch lacks RBAC permissions for, as found at startup.
+notifyaccessdenied: false
+# Send a notification through every handler still working when kubewatch is failing:
+# deliveries to the event handler fail repeatedly, or a watch is forbidden.
+notifyinternalerrors: false
+# Time allowed to handle queued events on shutdown (default 20s).
+shutdowntimeout: 0s
+# Informer resync period (e.g. 30m). Resyncs replay every cached object as an update,
+# 0 disables them.
+resyncperiod: 0s
+# Resync period per kind (e.g. Pod: 1h), overriding resyncperiod. 0 disables resyncs of the kind.
+resyncperiods: {}
+# Kinds watched with metadata-only informers (e.g. Pod, Secret). Only metadata is
+# cached and updates are reported only when metadata changes, cutting memory usage.
+metadataonly: []
+# Cache strips fields kubewatch does not need from objects before they are cached by
+# informers, cutting memory usage on large clusters.
+cache:
+  # Drop the field sets of metadata.managedFields, keeping their managers and times.
+  trimmanagedfields: false
+  # Drop the kubectl.kubernetes.io/last-applied-configuration annotation.
+  striplastapplied: false
+  # Drop the env and envFrom of the containers of pods.
+  strippodenv: false
+# Checkpoint persists the versions of notified objects, so that they are not
+# announced again when kubewatch restarts.
+checkpoint:
+  # File checkpoints are written to, on a persistent volume.
+  file: ""
+  # ConfigMap checkpoints are written to, as namespace/name, in the first watched cluster.
+  configmap: ""
+# Clusters to watch, leave it empty for watching the cluster kubewatch
+# runs in (or the current kubeconfig context).
+clusters: []
+# Name of the watched cluster attached to every event, when clusters is empty.
+clustername: ""
+# Environment of the watched clusters (e.g. production), attached to every event.
+environment: ""
+# Region of the watched clusters (e.g. eu-west-1), attached to every event.
+region: ""
+# Arbitrary key/values attached to every event (e.g. team: platform).
+clusterlabels: {}
+# Links appended to events, such as dashboards or logs of the affected object.
+# The url is a Go template rendered with the event, kinds restricts the link to
+# events of these kinds (all events when empty), e.g.:
+# - name: Grafana
+#   url: https://grafana.example.com/d/pods?var-namespace={{.Namespace}}&var-pod={{.Name}}
+#   kinds: [Pod]
+links: []
+# GroupKeys are Go templates of the keys grouping the events of a kind into a single
+# notification thread, by kind, rendered with the event. {{controller .}} renders the
+# kind/name of the controller of the object, e.g. Pod: "{{.Namespace}}/{{controller .}}"
+# groups the pods of a ReplicaSet. Keys default to namespace/kind/name.
+groupkeys: {}
+# CertManager enables cert-manager Certificate notifications.
+certmanager:
+  # Watch cert-manager Certificates for NotReady conditions and upcoming expiry.
+  enabled: false
+  # Alert when a certificate expires within this window (default 720h).
+  expirywindow: 0s
+# GitOps enables health notifications for Argo Rollouts, Argo CD Applications and Flux
+# Kustomizations and HelmReleases.
+gitops:
+  # Watch Argo Rollouts for degraded, aborted and paused rollouts.
+  argorollouts: false
+  # Watch Argo CD Applications for degraded, failed to sync, suspended and out of sync applications.
+  argocd: false
+  # Watch Flux Kustomizations and HelmReleases for failed reconciliations and suspensions.
+  flux: false
+# ContainerLogs attaches the last log lines of failing containers to pod events.
+containerlogs:
+  # Fetch the logs of containers that crashed, were OOMKilled or exited with a non-zero
+  # code. Requires watching pods and the get permission on pods/log.
+  enabled: false
+  # Number of log lines attached (default 20).
+  lines: 0
+  # Maximum size of attached logs in bytes (default 2048), older lines are dropped.
+  maxbytes: 0
+# Redaction replaces sensitive values in the objects of events with [REDACTED] before
+# they are sent: Secret data, values of env vars with sensitive names and of annotations.
+redaction:
+  # Send objects as they are, without redacting them.
+  disabled: false
+  # Regular expressions of env var names whose values are redacted, case insensitive, in
+  # addition to names containing PASSWORD, TOKEN, SECRET, API_KEY or CREDENTIAL.
+  envvars: []
+  # Keys of annotations whose values are redacted, as shell globs (e.g. vault.hashicorp.com/*).
+  annotations: []
+# Maximum size of events sent to each handler in bytes (e.g. webhook: 65536), 0 for no
+# limit: the size of the rendered message, or of the JSON payload with its objects for
+# the webhook and CloudEvent handlers. Larger events are truncated, dropping objects,
+# older log lines and long diff values first. Chat handlers have defaults below the
+# limits of their services.
+payloadlimits: {}
+# HandlerFilters restrict the events sent to each handler (e.g. webhook) to the kinds
+# and operations it wants, such as a ticketing webhook only receiving Jobs. Handlers
+# without filter receive every event.
+handlerfilters: {}
+# Transforms rewrite fields of events before they are dispatched, such as normalizing
+# names or mapping reasons, in order. Each sets a field to the value rendered by a Go
+# template of the event.
+transforms: []
+# Localization translates the messages sent to handlers, per handler.
+localization:
+  # Language of the messages sent to each handler (e.g. smtp: de), English when not set.
+  # Builtin catalogs translate to de, es and fr.
+  languages: {}
+  # Catalogs are YAML files mapping English messages to their translation, by language
+  # (e.g. pt: /etc/kubewatch/pt.yaml), completing the builtin catalog of the language.
+  catalogs: {}
+# Theme customizes the emoji, colors and prefixes of the messages of every handler.
+theme:
+  # Name of the builtin theme: default, with an emoji and a color per severity, or
+  # minimal, without emoji, messages starting with their severity such as [CRITICAL].
+  name: ""
+  # Emoji prefixing the messages of text-only handlers, by severity (info, warning,
+  # policy-violation or critical), overriding the theme. Empty removes the emoji.
+  emoji: {}
+  # Colors of messages, by severity, as #RRGGBB, overriding the theme.
+  colors: {}
+  # Prefixes of messages and summaries, by severity or operation (create, update, delete,
+  # scale or notify), overriding the theme (e.g. delete: "[DELETED]"). Empty removes the
+  # prefix.
+  prefixes: {}
+# Storm summarizes events while they arrive faster than a threshold, such as when a
+# node failure updates thousands of pods, instead of sending each of them.
+storm:
+  # Number of events within window above which events are summarized, 0 to disable.
+  threshold: 0
+  # Period events are counted over (default 1m).
+  window: 0s
+# ImagePolicy flags created and updated workloads whose containers use latest tags or
+# images from registries not allowed, with policy-violation events.
+imagepolicy:
+  # Flag images tagged latest or untagged, unless pinned by digest.
+  disallowlatest: false
+  # Registries images may come from, optionally with a repository prefix (e.g. ghcr.io/acme),
+  # docker.io for Docker Hub images. Images from any registry are allowed when empty.
+  allowedregistries: []
+# PodSecurity flags created and updated workloads of restricted namespaces requesting
+# privileged mode, host networking or hostPath volumes, or running as root.
+podsecurity:
+  # Check the pod templates of workloads in restricted namespaces.
+  enabled: false
+  # Label selector of the restricted namespaces
+  # (default pod-security.kubernetes.io/enforce=restricted).
+  namespaceselector: ""
+# VolumeFailures reports pods whose volumes keep failing to attach or mount for longer
+# than a threshold, once per pod and volume.
+volumefailures:
+  # Report FailedMount and FailedAttachVolume Kubernetes Events of pods.
+  enabled: false
+  # Time a volume keeps failing before it is reported (default 5m).
+  threshold: 0s
+# SlowStarts reports pods whose containers take longer than a threshold to start, pulling
+# their image or setting up their network, to spot registry or CNI problems early.
+slowstarts:
+  # Report the watched pods slow to start, once per pod.
+  enabled: false
+  # Time from the scheduling of a pod, or the completion of its init containers, beyond
+  # which a container is slow to start (default 5m).
+  threshold: 0s
+# IngressTLS periodically checks the certificates of the TLS Secrets referenced by
+# Ingresses, reporting those about to expire.
+ingresstls:
+  # Check the TLS Secrets of the Ingresses of the watched namespaces, which requires
+  # get rights on Secrets.
+  enabled: false
+  # Alert when a certificate expires within this window (default 720h).
+  expirywindow: 0s
+  # Interval between checks (default 1h).
+  interval: 0s
+# NodePressure periodically sends a digest of the nodes under memory, disk or PID
+# pressure or evicting many pods, instead of their eviction Kubernetes Events.
+nodepressure:
+  # Send digests of the watched nodes, dropping the Evicted and EvictionThresholdMet
+  # Kubernetes Events.
+  enabled: false
+  # Interval between digests (default 15m).
+  interval: 0s
+  # Number of pods a node must evict within an interval to be listed (default 5).
+  evictionthreshold: 0
+# NodeInterruptions warns about nodes about to go away, tainted or conditioned by the
+# cluster autoscaler or the cloud provider for a spot interruption or a maintenance.
+nodeinterruptions:
+  # Warn when a watched node gets a known interruption taint or condition: the cluster
+  # autoscaler scale-down taint, the GKE, AWS node termination handler and AKS spot
+  # interruption and scheduled maintenance taints and conditions.
+  enabled: false
+  # Additional taint keys announcing an interruption, such as those of a custom
+  # termination handler.
+  taints: []
+# KEDA enables health notifications for KEDA ScaledObjects.
+keda:
+  # Watch ScaledObjects of keda.sh/v1alpha1 for failing triggers, ScaledObjects not ready
+  # and paused autoscaling.
+  enabled: false
+# OOMRisk warns about containers whose memory usage, read from the metrics API, nears
+# their memory limit, before they are OOMKilled.
+oomrisk:
+  # Check the memory working set of the containers of the watched pods periodically.
+  # Requires metrics-server and watching pods.
+  enabled: false
+  # Part of the memory limit, in percent, from which a container is at risk (default 90).
+  threshold: 0
+  # Interval between checks (default 1m).
+  interval: 0s
+# VPA reports VerticalPodAutoscalers whose recommendations differ much from the requests
+# of their workload, as right-sizing nudges.
+vpa:
+  # Watch VerticalPodAutoscalers of autoscaling.k8s.io/v1, sending only their drift
+  # reports unless configured as custom resources.
+  enabled: false
+  # Difference between the target recommendation and the request of a container, in
+  # percent of the request, beyond which it is reported (default 30).
+  threshold: 0
+# Karpenter watches Karpenter NodeClaims and NodePools, summarizing node provisioning,
+# consolidation and disruption.
+karpenter:
+  # Watch NodeClaims and NodePools of karpenter.sh/v1, sending only their summaries unless
+  # configured as custom resources, and drop the Kubernetes Events of Karpenter about pods.
+  enabled: false
+# ClusterAutoscaler reports the node groups scaled by the cluster autoscaler, read from its
+# status ConfigMap, instead of its Kubernetes Events about every pending pod.
+clusterautoscaler:
+  # Watch the status ConfigMap of the cluster autoscaler, dropping its Kubernetes Events
+  # about pods.
+  enabled: false
+  # Namespace of the cluster autoscaler (default kube-system).
+  namespace: ""
+  # Name of its status ConfigMap (default cluster-autoscaler-status).
+  configmap: ""
+# Deprecations periodically reports objects and clients using Kubernetes APIs removed in
+# the next minor version, and kubelets outside of the supported version skew.
+deprecations:
+  # Check watched objects, API server metrics and node versions periodically.
+  enabled: false
+  # Interval between checks (default 6h).
+  interval: 0s
+# DryRun logs the events the handler would send instead of sending them.
+dryrun: false
+# Heartbeat sends notifications about kubewatch itself, so that its failures are noticed.
+heartbeat:
+  # Interval between "kubewatch alive" notifications (e.g. 1h), 0 to disable them.
+  interval: 0s
+  # Send a notification when kubewatch starts and when it stops.
+  lifecycle: false
+  # Handler receiving the notifications (e.g. slack), among the configured handlers.
+  # Defaults to the handler receiving events.
+  handler: ""
+# Latency sets an objective on the time from object changes to the delivery of their events.
+latency:
+  # Maximum time from an object change to the delivery of its event (e.g. 1m), 0 to disable.
+  # Slower deliveries are counted by the kubewatch_delivery_slo_violations_total metric.
+  slo: 0s
+  # Send a warning event when deliveries exceed the SLO, at most every 10 minutes per resource.
+  notify: false
+# History keeps the last events sent to the handler in memory, to be queried over HTTP.
+history:
+  # Number of events kept in memory and served by /api/v1/events (default 1000),
+  # -1 to disable the events API.
+  size: 0
+# Store persists the events sent to the handler on disk, serving them to the events
+# API and skipping events already sent before a restart.
+store:
+  # Directory events are written to, on a persistent volume. The store is disabled
+  # when empty.
+  path: ""
+  # How long events are kept (default 168h).
+  retention: 0s
+# Outbox persists events before sending them to the handler until they are delivered,
+# redelivering the events left undelivered when kubewatch restarts.
+outbox:
+  # Directory pending events are written to, on a persistent volume. The outbox is
+  # disabled when empty.
+  path: ""
+  # Pending events older than this are dropped instead of being redelivered (default 24h).
+  maxage: 0s
+# Admin configures the admin API of the metrics server, managing silences at runtime.
+admin:
+  # Bearer token required by the admin API, which is disabled without token.
+  # Use ${VAR} or file:// to read it from a Secret.
+  token: ""
+# Routes send events to other handlers than the handler receiving events, depending on
+# their severity and on the time of the week, such as chat during business hours and a
+# paging service off-hours. The first matching route wins, events matching no route are
+# sent to the handler.
+routes: []
+# Escalation re-sends the urgent events not acknowledged in time to another handler,
+# such as a paging service. Requires the event store.
+escalation:
+  # Handler receiving the events not acknowledged in time (e.g. a webhook of a paging
+  # service, or smtp to an SMS gateway), among the configured handlers. Escalation is
+  # disabled when empty.
+  handler: ""
+  # Delay after which unacknowledged events are escalated (default 15m).
+  after: 0s
+  # Minimum severity of the escalated events: info, warning, policy-violation or
+  # critical (default critical).
+  severity: ""
+  # Signing secret of the Slack app, adding an Acknowledge button to the Slack messages
+  # of escalated events. Use ${VAR} or file:// to read it from a Secret.
+  slacksigningsecret: ""
+# Resolutions track problems, such as pods in CrashLoopBackOff, NotReady nodes and
+# failed rollouts, sending a Resolved event with their duration when they clear.
+resolutions:
+  # Send Resolved events when problems clear. Open problems are kept in the directory of
+  # the event store when set, so that they survive restarts.
+  enabled: false
+  # Problems neither resolved nor reported again for this long are forgotten (default 168h).
+  expire: 0s
+# Alertmanager accepts Prometheus Alertmanager webhook notifications on the metrics
+# server, forwarding their alerts to the handler.
+alertmanager:
+  # Accept notifications at /api/v1/alerts, to be configured as a webhook receiver
+  # of Alertmanager.
+  enabled: false
+  # Bearer token required from Alertmanager (http_config.authorization), required when
+  # enabled. Use ${VAR} or file:// to read it from a Secret.
+  token: ""
+# Ownership attaches the team, owner, Slack channel and paging service of namespaces to
+# their events, read from a metadata service or a ConfigMap, for routing and display.
+ownership:
+  # URL of an HTTP endpoint returning the ownership of namespaces as a JSON object by
+  # namespace, such as {"payments": {"team": "payments", "owner": "alice@example.com",
+  # "slackChannel": "#payments", "pagerService": "payments-api"}}.
+  url: ""
+  # ConfigMap holding the ownership of namespaces as namespace/name, read from the first
+  # cluster. Its keys are namespaces, their values the JSON or YAML ownership.
+  configmap: ""
+  # Interval between reads of the ownership (default 5m).
+  interval: 0s
+  # Annotations of namespaces holding their ownership, taking precedence over url and
+  # configmap for the namespaces having them.
+  annotations:
+    # Read the ownership of namespaces from their annotations.
+    enabled: false
+    # Annotation naming the team owning the namespace (default team).
+    team: ""
+    # Annotation naming the owner of the namespace (default owner).
+    owner: ""
+    # Annotation naming the Slack channel of the owner (default slack-channel).
+    slackchannel: ""
+    # Annotation naming the paging service of the owner (default pager-service).
+    pagerservice: ""
+# Serve a dashboard of recent events, handler deliveries and filter statistics at /ui/
+# on the metrics server.
+webui: false
+# Log configures the logs of kubewatch, overridden by the --log-level and --log-format
+# flags and the LOG_LEVEL and LOG_FORMATTER environment variables.
+log:
+  # Minimum level of logged entries: trace, debug, info, warning or error (default info).
+  level: ""
+  # text or json (default text).
+  format: ""
+  # Levels of modules, named after the pkg field of log entries without the kubewatch-
+  # prefix (e.g. rbac: debug, Pod: warning).
+  modules: {}
+# Profiles are named sets of settings (e.g. prod, staging) overriding the settings
+# above when selected with --profile or KW_PROFILE. Maps are merged, other values
+# such as lists are replaced.
+profiles: {}
 `
@@ -0,0 +1,171 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersion is the version of the config file schema
+	APIVersion = "kubewatch.io/v1"
+	// Kind is the kind of config files
+	Kind = "KubewatchConfig"
+)
+
+// checkHeader returns whether the config file document root declares the apiVersion and
+// kind of the schema, an error when they are not supported
+func checkHeader(root *yaml.Node) (bool, error) {
+	var header struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+	}
+	if err := root.Decode(&header); err != nil {
+		return false, err
+	}
+	switch {
+	case header.APIVersion == "" && header.Kind == "":
+		return false, nil
+	case header.APIVersion != APIVersion:
+		return false, fmt.Errorf("apiVersion: unsupported version %q, expected %s", header.APIVersion, APIVersion)
+	case header.Kind != Kind:
+		return false, fmt.Errorf("kind: unsupported kind %q, expected %s", header.Kind, Kind)
+	}
+	return true, nil
+}
+
+// unknownFields returns an error per key of node that is not a field of t, prefixed by
+// its path in the config file
+func unknownFields(node *yaml.Node, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// profiles hold settings of the config file
+	if t == reflect.TypeOf(yaml.Node{}) {
+		t = reflect.TypeOf(Config{})
+	}
+
+	var errs []error
+	switch {
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			field, ok := fields[key]
+			if !ok {
+				errs = append(errs, unknownField(join(path, key), key, fields))
+				continue
+			}
+			errs = append(errs, unknownFields(node.Content[i+1], field, join(path, key))...)
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, unknownFields(node.Content[i+1], t.Elem(), join(path, node.Content[i].Value))...)
+		}
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for i, n := range node.Content {
+			errs = append(errs, unknownFields(n, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+func unknownField(path, key string, fields map[string]reflect.Type) error {
+	if suggestion := closest(key, fields); suggestion != "" {
+		return fmt.Errorf("%s: unknown field, did you mean %s?", path, suggestion)
+	}
+	return fmt.Errorf("%s: unknown field", path)
+}
+
+// yamlFields returns the types of the fields of struct t by yaml key, the lowercased
+// field name unless set by a yaml tag
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name := yamlName(f); name != "" {
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
+
+// yamlName returns the yaml key of field f, empty when it is not decoded
+func yamlName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// closest returns the field key is most likely a typo of, empty if none is close enough
+func closest(key string, fields map[string]reflect.Type) string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", len(key)/3+1
+	for _, name := range names {
+		if d := distance(strings.ToLower(key), strings.ToLower(name)); d <= bestDistance && (best == "" || d < bestDistance) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
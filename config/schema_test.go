@@ -0,0 +1,111 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalSchema(t *testing.T) {
+	var Tests = []struct {
+		name     string
+		config   string
+		err      string
+		warnings []string
+	}{
+		{
+			name:   "versioned",
+			config: "apiVersion: kubewatch.io/v1\nkind: KubewatchConfig\nresource:\n  pod: true\n",
+		},
+		{
+			name:   "versioned with unknown fields",
+			config: "apiVersion: kubewatch.io/v1\nkind: KubewatchConfig\nrsources:\n  pod: true\nhandler:\n  slack:\n    chanel: alerts\n",
+			err:    "rsources: unknown field, did you mean resource?\nhandler.slack.chanel: unknown field, did you mean channel?",
+		},
+		{
+			name:   "unknown fields in lists and maps",
+			config: "apiVersion: kubewatch.io/v1\nkind: KubewatchConfig\ncustomresources:\n- group: example.com\n  versions: v1\nclusters:\n- name: prod\n  labels:\n    team: platform\n",
+			err:    "customresources[0].versions: unknown field, did you mean version?",
+		},
+		{
+			name:     "legacy",
+			config:   "rsources:\n  pod: true\n",
+			warnings: []string{"rsources: unknown field, did you mean resource?"},
+		},
+		{
+			name:   "unsupported apiVersion",
+			config: "apiVersion: kubewatch.io/v2\nkind: KubewatchConfig\n",
+			err:    `apiVersion: unsupported version "kubewatch.io/v2", expected kubewatch.io/v1`,
+		},
+		{
+			name:   "missing kind",
+			config: "apiVersion: kubewatch.io/v1\n",
+			err:    `kind: unsupported kind "", expected KubewatchConfig`,
+		},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := unmarshal([]byte(tt.config), c)
+			if tt.err == "" && err != nil {
+				t.Fatalf("unmarshal() = %v, expected no error", err)
+			}
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("unmarshal() = %v, expected %q", err, tt.err)
+				}
+				return
+			}
+
+			var warnings []string
+			for _, w := range c.Warnings() {
+				warnings = append(warnings, w.Error())
+			}
+			if strings.Join(warnings, "\n") != strings.Join(tt.warnings, "\n") {
+				t.Errorf("Warnings() = %q, expected %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestUnmarshalDefaults(t *testing.T) {
+	c := &Config{}
+	if err := unmarshal([]byte("apiVersion: kubewatch.io/v1\nkind: KubewatchConfig\nqueue:\n  maxretries: 2\n"), c); err != nil {
+		t.Fatal(err)
+	}
+
+	var Tests = []struct {
+		field    string
+		value    interface{}
+		expected interface{}
+	}{
+		{"queue.maxretries", c.Queue.MaxRetries, 2},
+		{"queue.workers", c.Queue.Workers, DefaultWorkers},
+		{"shutdowntimeout", c.ShutdownTimeout, DefaultShutdownTimeout},
+		{"certmanager.expirywindow", c.CertManager.ExpiryWindow, 720 * time.Hour},
+		{"containerlogs.lines", c.ContainerLogs.Lines, int64(DefaultLogLines)},
+		{"containerlogs.maxbytes", c.ContainerLogs.MaxBytes, DefaultLogMaxBytes},
+	}
+	for _, tt := range Tests {
+		if tt.value != tt.expected {
+			t.Errorf("%s = %v, expected %v", tt.field, tt.value, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// slackMentionID matches the IDs of Slack user groups (S...) and users (U... or W...)
+var slackMentionID = regexp.MustCompile(`^[SUW][A-Z0-9]+$`)
+
+// hexColor matches the colors of themes, as #RRGGBB
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// Parse returns the configuration of the config file content b
+func Parse(b []byte) (*Config, error) {
+	c := &Config{}
+	if err := unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadFile loads the configuration of the file at path
+func LoadFile(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks the settings of c, returning an error per invalid setting prefixed by
+// its path in the config file. Required handler settings are checked by the handlers.
+func (c *Config) Validate() []error {
+	errs := append([]error{}, c.warnings...)
+	invalid := func(path string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	for _, u := range []struct{ path, url string }{
+		{"handler.slackwebhook.slackwebhookurl", c.Handler.SlackWebhook.Slackwebhookurl},
+		{"handler.hipchat.url", c.Handler.Hipchat.Url},
+		{"handler.mattermost.url", c.Handler.Mattermost.Url},
+		{"handler.flock.url", c.Handler.Flock.Url},
+		{"handler.webhook.url", c.Handler.Webhook.Url},
+		{"handler.cloudevent.url", c.Handler.CloudEvent.Url},
+		{"handler.msteams.webhookurl", c.Handler.MSTeams.WebhookURL},
+		{"handler.lark.webhookurl", c.Handler.Lark.WebhookURL},
+	} {
+		if err := validateURL(u.url); err != nil {
+			invalid(u.path, "%v", err)
+		}
+	}
+	for _, m := range []struct {
+		path     string
+		mentions SlackMentions
+	}{
+		{"handler.slack.mentions", c.Handler.Slack.Mentions},
+		{"handler.slackwebhook.mentions", c.Handler.SlackWebhook.Mentions},
+	} {
+		if !validSeverity(m.mentions.Severity) {
+			invalid(m.path+".severity", "expected info, warning, policy-violation or critical, got %q", m.mentions.Severity)
+		}
+		for team, id := range m.mentions.Teams {
+			if !slackMentionID.MatchString(id) {
+				invalid(m.path+".teams."+team, "expected a Slack user group or user ID, got %q", id)
+			}
+		}
+		for namespace, id := range m.mentions.Namespaces {
+			if !slackMentionID.MatchString(id) {
+				invalid(m.path+".namespaces."+namespace, "expected a Slack user group or user ID, got %q", id)
+			}
+		}
+	}
+	if cert := c.Handler.Webhook.Cert; cert != "" {
+		if _, err := os.Stat(cert); err != nil {
+			invalid("handler.webhook.cert", "%v", err)
+		}
+	}
+	if smtp := c.Handler.SMTP; smtp.Smarthost != "" || smtp.To != "" {
+		if _, _, err := net.SplitHostPort(smtp.Smarthost); err != nil {
+			invalid("handler.smtp.smarthost", "expected host:port, %v", err)
+		}
+		if _, err := mail.ParseAddressList(smtp.To); err != nil {
+			invalid("handler.smtp.to", "%v", err)
+		}
+		if _, err := mail.ParseAddress(smtp.From); err != nil {
+			invalid("handler.smtp.from", "%v", err)
+		}
+	}
+
+	for i, crd := range c.CustomResources {
+		if crd.Version == "" || crd.Resource == "" {
+			invalid(fmt.Sprintf("customresources[%d]", i), "version and resource are required")
+		}
+		for j, rule := range crd.Conditions {
+			path := fmt.Sprintf("customresources[%d].conditions[%d]", i, j)
+			if rule.Type == "" {
+				invalid(path+".type", "is required")
+			}
+			switch rule.Status {
+			case "", "True", "False", "Unknown":
+			default:
+				invalid(path+".status", "expected True, False or Unknown, got %q", rule.Status)
+			}
+			if !validSeverity(rule.Severity) {
+				invalid(path+".severity", "expected info, warning, policy-violation or critical, got %q", rule.Severity)
+			}
+		}
+		for j, field := range crd.Fields {
+			path := fmt.Sprintf("customresources[%d].fields[%d]", i, j)
+			if field.Name == "" || field.Path == "" {
+				invalid(path, "name and path are required")
+			} else if err := jsonpath.New(field.Name).Parse(field.Template()); err != nil {
+				invalid(path+".path", "%v", err)
+			}
+		}
+	}
+	if c.NamespaceSelector != "" {
+		if _, err := labels.Parse(c.NamespaceSelector); err != nil {
+			invalid("namespaceselector", "%v", err)
+		}
+	}
+
+	if c.Queue.QPS < 0 || c.Queue.Burst < 0 || c.Queue.MaxRetries < 0 || c.Queue.Workers < 0 || c.Queue.DeliveryTimeout < 0 {
+		invalid("queue", "qps, burst, maxretries, workers and deliverytimeout cannot be negative")
+	}
+	for kind, workers := range c.Queue.Concurrency {
+		if workers < 0 {
+			invalid("queue.concurrency."+kind, "cannot be negative")
+		}
+	}
+	if c.ShutdownTimeout < 0 {
+		invalid("shutdowntimeout", "cannot be negative")
+	}
+	if c.ResyncPeriod < 0 {
+		invalid("resyncperiod", "cannot be negative")
+	}
+	for kind, period := range c.ResyncPeriods {
+		if period < 0 {
+			invalid("resyncperiods."+kind, "cannot be negative")
+		}
+	}
+	for name, limit := range c.PayloadLimits {
+		if limit < 0 {
+			invalid("payloadlimits."+name, "cannot be negative")
+		}
+	}
+	for i, transform := range c.Transforms {
+		path := fmt.Sprintf("transforms[%d]", i)
+		if !TransformField(transform.Field) {
+			invalid(path+".field", "expected name, namespace, kind, reason, status, severity, groupkey, labels.<key> or fields.<name>, got %q", transform.Field)
+		}
+		if transform.Template == "" {
+			invalid(path+".template", "is required")
+		}
+	}
+	for name, language := range c.Localization.Languages {
+		if language == "" {
+			invalid("localization.languages."+name, "cannot be empty")
+		}
+	}
+	for language, file := range c.Localization.Catalogs {
+		if file == "" {
+			invalid("localization.catalogs."+language, "cannot be empty")
+		}
+	}
+	switch c.Theme.Name {
+	case "", "default", "minimal":
+	default:
+		invalid("theme.name", "expected default or minimal, got %q", c.Theme.Name)
+	}
+	for severity := range c.Theme.Emoji {
+		if severity == "" || !validSeverity(severity) {
+			invalid("theme.emoji", "expected info, warning, policy-violation or critical, got %q", severity)
+		}
+	}
+	for severity, color := range c.Theme.Colors {
+		if severity == "" || !validSeverity(severity) {
+			invalid("theme.colors", "expected info, warning, policy-violation or critical, got %q", severity)
+		}
+		if !hexColor.MatchString(color) {
+			invalid("theme.colors."+severity, "expected #RRGGBB, got %q", color)
+		}
+	}
+	for key := range c.Theme.Prefixes {
+		switch key {
+		case "info", "warning", "policy-violation", "critical", "create", "update", "delete", "scale", "notify":
+		default:
+			invalid("theme.prefixes", "expected a severity or an operation, got %q", key)
+		}
+	}
+	for name, filter := range c.HandlerFilters {
+		for _, kind := range filter.Kinds {
+			if kind == "" {
+				invalid("handlerfilters."+name+".kinds", "kinds cannot be empty")
+			}
+		}
+		for _, operation := range filter.Operations {
+			switch operation {
+			case "create", "update", "delete", "scale", "notify":
+			default:
+				invalid("handlerfilters."+name+".operations", "expected create, update, delete, scale or notify, got %q", operation)
+			}
+		}
+	}
+
+	if c.Heartbeat.Interval < 0 {
+		invalid("heartbeat.interval", "cannot be negative")
+	}
+	if c.HTTP.Timeout < 0 || c.HTTP.MaxIdleConnsPerHost < 0 || c.HTTP.IdleConnTimeout < 0 {
+		invalid("http", "timeout, maxidleconnsperhost and idleconntimeout cannot be negative")
+	}
+	for name, timeout := range c.HTTP.Timeouts {
+		if timeout < 0 {
+			invalid("http.timeouts."+name, "cannot be negative")
+		}
+	}
+	if c.Storm.Threshold < 0 || c.Storm.Window < 0 {
+		invalid("storm", "threshold and window cannot be negative")
+	}
+	if c.Deprecations.Interval < 0 {
+		invalid("deprecations.interval", "cannot be negative")
+	}
+	if c.SlowStarts.Threshold < 0 {
+		invalid("slowstarts.threshold", "cannot be negative")
+	}
+	if c.IngressTLS.ExpiryWindow < 0 || c.IngressTLS.Interval < 0 {
+		invalid("ingresstls", "expirywindow and interval cannot be negative")
+	}
+	if c.NodePressure.Interval < 0 || c.NodePressure.EvictionThreshold < 0 {
+		invalid("nodepressure", "interval and evictionthreshold cannot be negative")
+	}
+	if c.OOMRisk.Threshold < 0 || c.OOMRisk.Threshold > 100 {
+		invalid("oomrisk.threshold", "must be a percentage between 0 and 100")
+	}
+	if c.OOMRisk.Interval < 0 {
+		invalid("oomrisk.interval", "cannot be negative")
+	}
+	if c.VPA.Threshold < 0 {
+		invalid("vpa.threshold", "cannot be negative")
+	}
+	for _, taint := range c.NodeInterruptions.Taints {
+		if taint == "" {
+			invalid("nodeinterruptions.taints", "cannot hold empty taint keys")
+		}
+	}
+	if c.VolumeFailures.Threshold < 0 {
+		invalid("volumefailures.threshold", "cannot be negative")
+	}
+	if c.Latency.SLO < 0 {
+		invalid("latency.slo", "cannot be negative")
+	}
+	if c.Latency.Notify && c.Latency.SLO == 0 {
+		invalid("latency.notify", "requires latency.slo")
+	}
+	if c.Store.Retention < 0 {
+		invalid("store.retention", "cannot be negative")
+	}
+	if c.Alertmanager.Enabled && c.Alertmanager.Token == "" {
+		invalid("alertmanager.token", "required when alertmanager is enabled, the receiver is exposed on the metrics server")
+	}
+	if c.Outbox.MaxAge < 0 {
+		invalid("outbox.maxage", "cannot be negative")
+	}
+	if c.Outbox.Path != "" && c.Outbox.Path == c.Store.Path {
+		invalid("outbox.path", "must differ from store.path")
+	}
+
+	if c.Log.Level != "" {
+		if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+			invalid("log.level", "%v", err)
+		}
+	}
+	if c.Log.Format != "" && c.Log.Format != "text" && c.Log.Format != "json" {
+		invalid("log.format", "expected text or json, got %q", c.Log.Format)
+	}
+	for module, level := range c.Log.Modules {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			invalid("log.modules."+module, "%v", err)
+		}
+	}
+
+	if c.Checkpoint.File != "" && c.Checkpoint.ConfigMap != "" {
+		invalid("checkpoint", "set one of file or configmap")
+	}
+	if cm := c.Checkpoint.ConfigMap; cm != "" {
+		if namespace, name, ok := strings.Cut(cm, "/"); !ok || namespace == "" || name == "" {
+			invalid("checkpoint.configmap", "expected namespace/name, got %q", cm)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, cluster := range c.Clusters {
+		if cluster.Name != "" && names[cluster.Name] {
+			invalid(fmt.Sprintf("clusters[%d].name", i), "cluster %q is configured more than once", cluster.Name)
+		}
+		names[cluster.Name] = true
+	}
+
+	if c.CertManager.ExpiryWindow < 0 {
+		invalid("certmanager.expirywindow", "cannot be negative")
+	}
+	for i, pattern := range c.Redaction.EnvVars {
+		if _, err := regexp.Compile(pattern); err != nil {
+			invalid(fmt.Sprintf("redaction.envvars[%d]", i), "invalid regular expression: %v", err)
+		}
+	}
+	for i, pattern := range c.Redaction.Annotations {
+		if _, err := path.Match(pattern, ""); err != nil {
+			invalid(fmt.Sprintf("redaction.annotations[%d]", i), "invalid pattern %q: %v", pattern, err)
+		}
+	}
+	for i, registry := range c.ImagePolicy.AllowedRegistries {
+		if registry == "" || strings.Contains(registry, "://") {
+			invalid(fmt.Sprintf("imagepolicy.allowedregistries[%d]", i), "invalid registry %q, expected a host optionally followed by a repository prefix", registry)
+		}
+	}
+	if c.PodSecurity.NamespaceSelector != "" {
+		if _, err := labels.Parse(c.PodSecurity.NamespaceSelector); err != nil {
+			invalid("podsecurity.namespaceselector", "%v", err)
+		}
+	}
+	for i, route := range c.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		if route.Handler == "" {
+			invalid(path+".handler", "a route needs a handler")
+		}
+		for _, severity := range route.Severities {
+			if severity == "" || !validSeverity(severity) {
+				invalid(path+".severities", "expected info, warning, policy-violation or critical, got %q", severity)
+			}
+		}
+		if _, err := route.Schedule(); err != nil {
+			invalid(path, "%v", err)
+		}
+		if len(route.Teams) > 0 && c.Ownership.URL == "" && c.Ownership.ConfigMap == "" && !c.Ownership.Annotations.Enabled {
+			invalid(path+".teams", "routing by team requires ownership, set ownership.url, ownership.configmap or ownership.annotations")
+		}
+	}
+	if c.Escalation.Handler != "" && c.Store.Path == "" {
+		invalid("escalation.handler", "escalation requires the event store, set store.path")
+	}
+	if c.Escalation.After < 0 {
+		invalid("escalation.after", "cannot be negative")
+	}
+	if !validSeverity(c.Escalation.Severity) {
+		invalid("escalation.severity", "expected info, warning, policy-violation or critical, got %q", c.Escalation.Severity)
+	}
+	if c.Resolutions.Expire < 0 {
+		invalid("resolutions.expire", "cannot be negative")
+	}
+	if err := validateURL(c.Ownership.URL); err != nil {
+		invalid("ownership.url", "%v", err)
+	}
+	if c.Ownership.URL != "" && c.Ownership.ConfigMap != "" {
+		invalid("ownership", "url and configmap are mutually exclusive")
+	}
+	if namespace, name, ok := strings.Cut(c.Ownership.ConfigMap, "/"); c.Ownership.ConfigMap != "" && (!ok || namespace == "" || name == "") {
+		invalid("ownership.configmap", "expected namespace/name, got %q", c.Ownership.ConfigMap)
+	}
+	if c.Ownership.Interval < 0 {
+		invalid("ownership.interval", "cannot be negative")
+	}
+	if c.ContainerLogs.Lines < 0 || c.ContainerLogs.MaxBytes < 0 {
+		invalid("containerlogs", "lines and maxbytes cannot be negative")
+	}
+	return errs
+}
+
+// TransformField returns whether field can be set by transforms
+func TransformField(field string) bool {
+	switch field {
+	case "name", "namespace", "kind", "reason", "status", "severity", "groupkey":
+		return true
+	}
+	for _, prefix := range []string{"labels.", "fields."} {
+		if strings.HasPrefix(field, prefix) && len(field) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validSeverity returns whether s is empty or the name of a severity
+func validSeverity(s string) bool {
+	switch s {
+	case "", "info", "warning", "policy-violation", "critical":
+		return true
+	}
+	return false
+}
+
+// validateURL checks u is empty or an absolute http(s) URL
+func validateURL(u string) error {
+	if u == "" {
+		return nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("expected an http(s) URL, got %q", u)
+	}
+	return nil
+}
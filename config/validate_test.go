@@ -0,0 +1,99 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	var Tests = []struct {
+		config Config
+		errors []string
+	}{
+		{Config{}, nil},
+		{Config{Handler: Handler{Webhook: Webhook{Url: "https://example.com/hook"}}}, nil},
+		{Config{Handler: Handler{Webhook: Webhook{Url: "example.com/hook"}}}, []string{"handler.webhook.url"}},
+		{Config{Handler: Handler{MSTeams: MSTeams{WebhookURL: "ftp://example.com"}}}, []string{"handler.msteams.webhookurl"}},
+		{Config{Handler: Handler{Webhook: Webhook{Url: "https://example.com", Cert: "/nonexistent/ca.pem"}}}, []string{"handler.webhook.cert"}},
+		{Config{Handler: Handler{SMTP: SMTP{To: "ops@example.com", From: "kubewatch", Smarthost: "smtp.example.com"}}},
+			[]string{"handler.smtp.smarthost", "handler.smtp.from"}},
+		{Config{Handler: Handler{Slack: Slack{Mentions: SlackMentions{Severity: "urgent", Teams: map[string]string{"payments": "@payments-oncall"}}}}},
+			[]string{"handler.slack.mentions.severity", "handler.slack.mentions.teams.payments"}},
+		{Config{CustomResources: []CRD{{Group: "example.com", Resource: "widgets"}}}, []string{"customresources[0]"}},
+		{Config{CustomResources: []CRD{{Group: "cert-manager.io", Version: "v1", Resource: "certificates", Conditions: []ConditionRule{{Status: "false", Severity: "high"}}}}},
+			[]string{"customresources[0].conditions[0].type", "customresources[0].conditions[0].status", "customresources[0].conditions[0].severity"}},
+		{Config{CustomResources: []CRD{{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications", Fields: []FieldPath{{Name: "Revision"}, {Name: "Sync", Path: ".status.sync[.status"}}}}},
+			[]string{"customresources[0].fields[0]", "customresources[0].fields[1].path"}},
+		{Config{NamespaceSelector: "team in (payments"}, []string{"namespaceselector"}},
+		{Config{Queue: Queue{Workers: -1}}, []string{"queue"}},
+		{Config{Queue: Queue{DeliveryTimeout: -time.Second}}, []string{"queue"}},
+		{Config{Checkpoint: Checkpoint{File: "/data/checkpoints", ConfigMap: "kubewatch"}}, []string{"checkpoint", "checkpoint.configmap"}},
+		{Config{Clusters: []Cluster{{Name: "prod"}, {Name: "prod"}}}, []string{"clusters[1].name"}},
+		{Config{ContainerLogs: ContainerLogs{Lines: -1}}, []string{"containerlogs"}},
+		{Config{PayloadLimits: map[string]int{"webhook": -1}}, []string{"payloadlimits.webhook"}},
+		{Config{HandlerFilters: map[string]HandlerFilter{"webhook": {Kinds: []string{""}, Operations: []string{"failed"}}}},
+			[]string{"handlerfilters.webhook.kinds", "handlerfilters.webhook.operations"}},
+		{Config{Transforms: []Transform{{Field: "reason", Template: "{{.Reason}}"}, {Field: "labels.", Template: ""}}},
+			[]string{"transforms[1].field", "transforms[1].template"}},
+		{Config{Localization: Localization{Languages: map[string]string{"slack": "de", "smtp": ""}, Catalogs: map[string]string{"pt": ""}}},
+			[]string{"localization.languages.smtp", "localization.catalogs.pt"}},
+		{Config{Theme: Theme{Name: "plain"}}, []string{"theme.name"}},
+		{Config{Theme: Theme{Name: "minimal", Emoji: map[string]string{"critical": "", "urgent": "!"}, Colors: map[string]string{"warning": "orange"}, Prefixes: map[string]string{"delete": "[DELETED]", "Deleted": "x"}}},
+			[]string{"theme.emoji", "theme.colors.warning", "theme.prefixes"}},
+		{Config{Storm: Storm{Threshold: -1}}, []string{"storm"}},
+		{Config{Alertmanager: Alertmanager{Enabled: true}}, []string{"alertmanager.token"}},
+		{Config{Alertmanager: Alertmanager{Enabled: true, Token: "secret"}}, nil},
+		{Config{Deprecations: Deprecations{Interval: -time.Hour}}, []string{"deprecations.interval"}},
+		{Config{VolumeFailures: VolumeFailures{Threshold: -time.Minute}}, []string{"volumefailures.threshold"}},
+		{Config{PodSecurity: PodSecurity{Enabled: true, NamespaceSelector: "!"}}, []string{"podsecurity.namespaceselector"}},
+		{Config{ImagePolicy: ImagePolicy{AllowedRegistries: []string{"ghcr.io/acme", "https://registry.example.com"}}}, []string{"imagepolicy.allowedregistries[1]"}},
+		{Config{Routes: []Route{{Handler: "slack", Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "Europe/Paris"}, {Severities: []string{"urgent"}, Hours: "9-18"}}},
+			[]string{"routes[1].handler", "routes[1].severities", "routes[1]"}},
+		{Config{Routes: []Route{{Handler: "pagerduty", Teams: []string{"payments"}}}}, []string{"routes[0].teams"}},
+		{Config{Routes: []Route{{Handler: "pagerduty", Teams: []string{"payments"}}}, Ownership: Ownership{Annotations: OwnershipAnnotations{Enabled: true}}}, nil},
+		{Config{Ownership: Ownership{URL: "owners.example.com", ConfigMap: "kubewatch", Interval: -time.Minute}},
+			[]string{"ownership.url", "ownership", "ownership.configmap", "ownership.interval"}},
+		{Config{Escalation: Escalation{Handler: "webhook", Severity: "high"}}, []string{"escalation.handler", "escalation.severity"}},
+		{Config{Escalation: Escalation{Handler: "webhook", After: -time.Minute}, Store: EventStore{Path: "/data/events"}}, []string{"escalation.after"}},
+		{Config{SlowStarts: SlowStarts{Enabled: true, Threshold: -time.Minute}}, []string{"slowstarts.threshold"}},
+		{Config{OOMRisk: OOMRisk{Enabled: true, Threshold: 120, Interval: -time.Minute}}, []string{"oomrisk.threshold", "oomrisk.interval"}},
+		{Config{VPA: VPA{Enabled: true, Threshold: -10}}, []string{"vpa.threshold"}},
+		{Config{NodeInterruptions: NodeInterruptions{Enabled: true, Taints: []string{""}}}, []string{"nodeinterruptions.taints"}},
+		{Config{IngressTLS: IngressTLS{Enabled: true, Interval: -time.Hour}}, []string{"ingresstls"}},
+		{Config{NodePressure: NodePressure{Enabled: true, EvictionThreshold: -1}}, []string{"nodepressure"}},
+		{Config{Resolutions: Resolutions{Enabled: true, Expire: -time.Hour}}, []string{"resolutions.expire"}},
+		{Config{HTTP: HTTPClient{Timeouts: map[string]time.Duration{"webhook": -time.Second}}}, []string{"http.timeouts.webhook"}},
+		{Config{Redaction: Redaction{EnvVars: []string{"_URL$", "(DSN"}, Annotations: []string{"vault.hashicorp.com/["}}},
+			[]string{"redaction.envvars[1]", "redaction.annotations[0]"}},
+	}
+
+	for i, test := range Tests {
+		errs := test.config.Validate()
+		if len(errs) != len(test.errors) {
+			t.Errorf("%d: Validate() = %v, expected errors for %v", i, errs, test.errors)
+			continue
+		}
+		for j, err := range errs {
+			if !strings.HasPrefix(err.Error(), test.errors[j]+": ") {
+				t.Errorf("%d: Validate() = %v, expected errors for %v", i, errs, test.errors)
+			}
+		}
+	}
+}
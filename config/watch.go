@@ -0,0 +1,54 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"time"
+)
+
+// Watch checks the config file every interval until stopCh is closed. Whenever its content
+// changes, onChange is called with the reloaded configuration, or the error met loading it.
+func Watch(interval time.Duration, stopCh <-chan struct{}, onChange func(*Config, error)) {
+	last, _ := os.ReadFile(getConfigFile())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		b, err := os.ReadFile(getConfigFile())
+		// an empty file is being rewritten, it is read again on the next tick
+		if err != nil || len(b) == 0 || bytes.Equal(b, last) {
+			continue
+		}
+		last = b
+
+		c := &Config{}
+		if err := unmarshal(b, c); err != nil {
+			onChange(nil, err)
+			continue
+		}
+		c.CheckMissingResourceEnvvars()
+		onChange(c, nil)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KW_CONFIG", dir)
+	path := filepath.Join(dir, ConfigFileName)
+	if err := os.WriteFile(path, []byte("resource:\n  pod: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan *Config, 1)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go Watch(10*time.Millisecond, stopCh, func(c *Config, err error) {
+		if err != nil {
+			t.Errorf("Watch(): %v", err)
+			return
+		}
+		changes <- c
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("resource:\n  pod: true\n  node: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		if !c.Resource.Pod || !c.Resource.Node {
+			t.Fatalf("expected reloaded resources, got %+v", c.Resource)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected config change to be detected")
+	}
+}
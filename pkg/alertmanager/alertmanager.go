@@ -0,0 +1,210 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alertmanager
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Path is where Alertmanager webhook notifications are received on the metrics server
+const Path = "/api/v1/alerts"
+
+// Kind is the kind of the events forwarding alerts
+const Kind = "Alert"
+
+// maxNotificationBytes is the size of the largest notification accepted
+const maxNotificationBytes = 4 << 20
+
+// Notification is the payload Alertmanager sends to webhook receivers (version 4)
+type Notification struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert is an alert of a notification
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// Receiver accepts Alertmanager webhook notifications and sends their alerts to Handler,
+// formatted and routed like the events of the watched clusters
+type Receiver struct {
+	Handler handlers.Handler
+	// Token is the bearer token required from Alertmanager, every notification is rejected
+	// when empty
+	Token string
+	// ClusterName, Environment and Region describe the cluster of alerts without cluster label
+	ClusterName string
+	Environment string
+	Region      string
+}
+
+// NewReceiver returns the receiver of conf sending alerts to handler
+func NewReceiver(conf *config.Config, handler handlers.Handler) *Receiver {
+	return &Receiver{
+		Handler:     handler,
+		Token:       conf.Alertmanager.Token,
+		ClusterName: conf.ClusterName,
+		Environment: conf.Environment,
+		Region:      conf.Region,
+	}
+}
+
+// ServeHTTP forwards the alerts of a notification authenticated with Token. It fails when
+// an alert could not be delivered, for Alertmanager to retry the notification.
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	auth := r.Header.Get("Authorization")
+	if rc.Token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+rc.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var n Notification
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxNotificationBytes)).Decode(&n); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("notification larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("invalid notification: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	logger := logrus.WithField("pkg", "kubewatch-alertmanager")
+	failed := 0
+	for _, alert := range n.Alerts {
+		e := rc.Event(n, alert)
+		if err := handlers.Deliver(r.Context(), rc.Handler, e); err != nil {
+			logger.WithFields(logging.EventFields(e)).Errorf("Failed to forward alert: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		http.Error(w, fmt.Sprintf("%d of %d alerts could not be delivered", failed, len(n.Alerts)), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Event converts alert, received in n, to an event: firing alerts are reported with the
+// severity of their severity label, resolved alerts as info
+func (rc *Receiver) Event(n Notification, alert Alert) event.Event {
+	labels := alert.Labels
+	annotations := alert.Annotations
+	status := alert.Status
+	if status == "" {
+		status = n.Status
+	}
+
+	e := event.Event{
+		Kind:          Kind,
+		Name:          labels["alertname"],
+		Namespace:     labels["namespace"],
+		Labels:        labels,
+		ID:            uuid.New().String(),
+		CorrelationID: alert.Fingerprint,
+		ClusterName:   rc.ClusterName,
+		Environment:   rc.Environment,
+		Region:        rc.Region,
+		Timestamp:     alert.StartsAt,
+	}
+	if cluster := labels["cluster"]; cluster != "" {
+		e.ClusterName = cluster
+	}
+	if e.CorrelationID == "" {
+		e.CorrelationID = n.GroupKey
+	}
+
+	var lines []string
+	if summary := annotations["summary"]; summary != "" {
+		lines = append(lines, summary)
+	}
+	if description := annotations["description"]; description != "" {
+		lines = append(lines, description)
+	} else if message := annotations["message"]; message != "" {
+		lines = append(lines, message)
+	}
+
+	if status == "resolved" {
+		e.Status = "Normal"
+		e.Severity = event.SeverityInfo
+		e.Reason = "Resolved"
+		if !alert.EndsAt.IsZero() {
+			e.Timestamp = alert.EndsAt
+		}
+	} else {
+		e.Status, e.Severity = alertSeverity(labels["severity"])
+		e.Reason = "Firing"
+	}
+	if len(lines) > 0 {
+		e.Reason += ": " + strings.Join(lines, "\n")
+	}
+
+	if alert.GeneratorURL != "" {
+		e.Links = append(e.Links, event.Link{Name: "Source", URL: alert.GeneratorURL})
+	}
+	if runbook := annotations["runbook_url"]; runbook != "" {
+		e.Links = append(e.Links, event.Link{Name: "Runbook", URL: runbook})
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	return e
+}
+
+// alertSeverity maps the severity label of a firing alert to a status and severity
+func alertSeverity(severity string) (string, event.Severity) {
+	switch strings.ToLower(severity) {
+	case "critical", "error", "page":
+		return "Danger", event.SeverityCritical
+	case "info", "none":
+		return "Normal", event.SeverityInfo
+	}
+	return "Warning", event.SeverityWarning
+}
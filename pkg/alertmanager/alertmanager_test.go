@@ -0,0 +1,136 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alertmanager
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+const notification = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"KubePodCrashLooping\"}",
+  "status": "firing",
+  "receiver": "kubewatch",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "KubePodCrashLooping", "namespace": "payments", "severity": "critical", "cluster": "prod"},
+      "annotations": {"summary": "Pod is crash looping.", "description": "Pod payments/api-7d9f is restarting 3 times / 10 minutes.", "runbook_url": "https://runbooks.example.com/crashloop"},
+      "startsAt": "2024-05-01T10:00:00Z",
+      "generatorURL": "http://prometheus/graph?g0.expr=up",
+      "fingerprint": "c4a2f1b"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "KubeMemoryOvercommit", "severity": "warning"},
+      "annotations": {"summary": "Cluster has overcommitted memory."},
+      "startsAt": "2024-05-01T09:00:00Z",
+      "endsAt": "2024-05-01T10:05:00Z",
+      "fingerprint": "9e01d3a"
+    }
+  ]
+}`
+
+// recordingHandler records the events it receives, failing with err
+type recordingHandler struct {
+	events []event.Event
+	err    error
+}
+
+func (r *recordingHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *recordingHandler) Handle(e event.Event) {
+	r.HandleWithError(e)
+}
+
+func (r *recordingHandler) HandleWithError(e event.Event) error {
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func TestReceiver(t *testing.T) {
+	r := &recordingHandler{}
+	rc := &Receiver{Handler: r, Token: "secret", ClusterName: "default"}
+	req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(notification))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	rc.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the notification to be accepted, got %d: %s", w.Code, w.Body)
+	}
+	if len(r.events) != 2 {
+		t.Fatalf("expected an event per alert, got %d", len(r.events))
+	}
+
+	firing, resolved := r.events[0], r.events[1]
+	if firing.Kind != Kind || firing.Name != "KubePodCrashLooping" || firing.Namespace != "payments" || firing.ClusterName != "prod" {
+		t.Errorf("expected the firing alert to be identified by its labels, got %+v", firing)
+	}
+	if firing.Severity != event.SeverityCritical || firing.Status != "Danger" || firing.CorrelationID != "c4a2f1b" {
+		t.Errorf("expected a critical event correlated by fingerprint, got %+v", firing)
+	}
+	if !strings.HasPrefix(firing.Reason, "Firing: Pod is crash looping.\n") || len(firing.Links) != 2 {
+		t.Errorf("expected the annotations and links of the alert, got %q %v", firing.Reason, firing.Links)
+	}
+	if resolved.Severity != event.SeverityInfo || resolved.Reason != "Resolved: Cluster has overcommitted memory." || resolved.ClusterName != "default" {
+		t.Errorf("expected an info event in the default cluster, got %+v", resolved)
+	}
+	if resolved.Timestamp.Hour() != 10 || resolved.Timestamp.Minute() != 5 {
+		t.Errorf("expected the resolved alert to be timestamped when it ended, got %v", resolved.Timestamp)
+	}
+}
+
+func TestReceiverErrors(t *testing.T) {
+	var Tests = []struct {
+		method     string
+		configured string
+		token      string
+		body       string
+		err        error
+		code       int
+	}{
+		{http.MethodGet, "secret", "secret", "", nil, http.StatusMethodNotAllowed},
+		{http.MethodPost, "secret", "", notification, nil, http.StatusUnauthorized},
+		{http.MethodPost, "secret", "wrong", notification, nil, http.StatusUnauthorized},
+		{http.MethodPost, "", "", notification, nil, http.StatusUnauthorized},
+		{http.MethodPost, "secret", "secret", "{", nil, http.StatusBadRequest},
+		{http.MethodPost, "secret", "secret", `{"alerts":[` + strings.Repeat(`{"labels":{}},`, maxNotificationBytes/10) + `{}]}`, nil, http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "secret", "secret", notification, errors.New("slack is down"), http.StatusBadGateway},
+		{http.MethodPost, "secret", "secret", notification, nil, http.StatusOK},
+	}
+	for i, test := range Tests {
+		rc := &Receiver{Handler: &recordingHandler{err: test.err}, Token: test.configured}
+		req := httptest.NewRequest(test.method, Path, strings.NewReader(test.body))
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		rc.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("%d: expected status %d, got %d", i, test.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/storm"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// BenchOptions configures the synthetic events of Bench
+type BenchOptions struct {
+	// Events is the number of synthetic events
+	Events int
+	// Objects is the number of pods the events are about. Each pod is created, updated
+	// and deleted in turn.
+	Objects int
+	// Namespaces the pods are spread over, the watched namespaces of the configuration
+	// when empty
+	Namespaces []string
+	// Rate is the number of events generated per second, 0 as fast as possible
+	Rate float64
+	// Latency is the time each delivery takes, simulating slow receivers
+	Latency time.Duration
+	// FailureRate is the fraction of deliveries failing, between 0 and 1
+	FailureRate float64
+	// Settle is how long Bench waits for more events to be handled once none was, after
+	// the last one was generated (default 1s)
+	Settle time.Duration
+	// Send sends the events to the configured handlers instead of discarding them
+	Send bool
+}
+
+// BenchResult reports the events handled during Bench
+type BenchResult struct {
+	Generated int
+	// Delivered and Failed count the deliveries to each handler
+	Delivered map[string]int
+	Failed    map[string]int
+	// Summaries counts the deliveries of storm notifications
+	Summaries int
+	// Elapsed is the time from the first generated event to the last delivery
+	Elapsed time.Duration
+	// Latencies are the times from the last change of the pods of the delivered events to
+	// their delivery, sorted
+	Latencies []time.Duration
+}
+
+// Throughput returns the number of deliveries per second
+func (r BenchResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	deliveries := 0
+	for _, n := range r.Delivered {
+		deliveries += n
+	}
+	return float64(deliveries) / r.Elapsed.Seconds()
+}
+
+// Latency returns the latency of the fraction q of the deliveries, e.g. 0.99
+func (r BenchResult) Latency(q float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	return r.Latencies[int(q*float64(len(r.Latencies)-1))]
+}
+
+// bench records the deliveries of synthetic events
+type bench struct {
+	mu        sync.Mutex
+	rand      *rand.Rand
+	generated map[string]time.Time
+	start     time.Time
+	last      time.Time
+	result    BenchResult
+}
+
+// benchSink delivers events to handler as the handler called name, or discards them when
+// handler is nil, recording the deliveries
+type benchSink struct {
+	name    string
+	handler handlers.Handler
+	opts    BenchOptions
+	bench   *bench
+}
+
+// Init initializes the handler
+func (s *benchSink) Init(c *config.Config) error {
+	return nil
+}
+
+// Handle delivers e
+func (s *benchSink) Handle(e event.Event) {
+	if err := s.HandleWithError(e); err != nil {
+		logging.HandlerLogger(s.name, e).Debugf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError delivers e, failing the fraction FailureRate of the deliveries
+func (s *benchSink) HandleWithError(e event.Event) error {
+	return s.HandleContext(context.Background(), e)
+}
+
+// HandleContext waits for the simulated latency, then fails at the simulated rate or sends
+// e to the handler when events are sent. It fails with the error of ctx when it is done first.
+func (s *benchSink) HandleContext(ctx context.Context, e event.Event) error {
+	s.bench.mu.Lock()
+	failed := s.bench.rand.Float64() < s.opts.FailureRate
+	s.bench.mu.Unlock()
+
+	var err error
+	select {
+	case <-time.After(s.opts.Latency):
+		switch {
+		case failed:
+			err = errors.New("synthetic delivery failure")
+		case s.handler != nil:
+			err = handlers.Deliver(ctx, s.handler, e)
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.bench.mu.Lock()
+	defer s.bench.mu.Unlock()
+	now := time.Now()
+	s.bench.last = now
+	if err != nil {
+		s.bench.result.Failed[s.name]++
+		return err
+	}
+	s.bench.result.Delivered[s.name]++
+	if e.Kind == storm.Kind {
+		s.bench.result.Summaries++
+	} else if generated, ok := s.bench.generated[e.Namespace+"/"+e.Name]; ok {
+		s.bench.result.Latencies = append(s.bench.result.Latencies, now.Sub(generated))
+	}
+	return nil
+}
+
+// Flush flushes the handler when it buffers events
+func (s *benchSink) Flush() error {
+	if f, ok := s.handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Bench generates synthetic pod events in a fake cluster, sending them through the
+// controller and the handlers of conf: filters, transforms, routes, rate limits and storm
+// summaries. The handlers discard the events unless opts.Send is set.
+func Bench(conf *config.Config, opts BenchOptions) (BenchResult, error) {
+	if opts.Events <= 0 || opts.Objects <= 0 {
+		return BenchResult{}, errors.New("the numbers of events and objects must be positive")
+	}
+	if opts.FailureRate < 0 || opts.FailureRate > 1 {
+		return BenchResult{}, errors.New("the failure rate must be between 0 and 1")
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = time.Second
+	}
+	namespaces := opts.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = conf.Namespaces
+	}
+	if len(namespaces) == 0 && conf.Namespace != "" {
+		namespaces = []string{conf.Namespace}
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{"default"}
+	}
+
+	// only the synthetic pods are watched, and nothing is written outside of the bench
+	benchConf := *conf
+	benchConf.Resource = config.Resource{Pod: true}
+	benchConf.CustomResources = nil
+	benchConf.MetadataOnly = nil
+	benchConf.NamespaceSelector = ""
+	benchConf.Checkpoint = config.Checkpoint{}
+	// the fake cluster is empty at start, every pod is new
+	benchConf.StartupInventory = true
+
+	b := &bench{
+		rand:      rand.New(rand.NewSource(1)),
+		generated: map[string]time.Time{},
+		result:    BenchResult{Delivered: map[string]int{}, Failed: map[string]int{}},
+	}
+	newHandler := func(name string) (handlers.Handler, error) {
+		sink := &benchSink{name: name, opts: opts, bench: b}
+		if opts.Send {
+			handler, err := NewNamedHandler(name, &benchConf)
+			if err != nil {
+				return nil, err
+			}
+			sink.handler = handler
+		}
+		return wrapHandler(name, sink, &benchConf, false)
+	}
+
+	eventsHandler, err := newHandler(EventHandlerName(&benchConf))
+	if err != nil {
+		return BenchResult{}, err
+	}
+	if len(benchConf.Routes) > 0 {
+		if eventsHandler, err = newRouterWith(&benchConf, eventsHandler, newHandler); err != nil {
+			return BenchResult{}, err
+		}
+	}
+	stopCh := make(chan struct{})
+	if benchConf.Storm.Threshold > 0 {
+		stormHandler := storm.NewHandler(&benchConf, eventsHandler)
+		go stormHandler.Run(stopCh)
+		eventsHandler = stormHandler
+	}
+	if len(benchConf.Transforms) > 0 {
+		transforms, err := handlers.ParseTransforms(benchConf.Transforms)
+		if err != nil {
+			return BenchResult{}, fmt.Errorf("parsing the transforms: %v", err)
+		}
+		eventsHandler = &handlers.Transformed{Transforms: transforms, Handler: eventsHandler}
+	}
+
+	watches := &benchWatches{watches: map[string]*benchWatch{}, started: make(chan struct{})}
+	client := fake.NewSimpleClientset()
+	client.PrependWatchReactor("pods", watches.react)
+	cluster := controller.Cluster{
+		Name:          benchConf.ClusterName,
+		KubeClient:    client,
+		DynamicClient: dynamicfake.NewSimpleDynamicClient(scheme.Scheme),
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		controller.Run(&benchConf, eventsHandler, []controller.Cluster{cluster}, func(<-chan struct{}, func(*config.Config, error)) {}, stopCh)
+	}()
+	stop := func() {
+		close(stopCh)
+		<-done
+	}
+	err = wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, time.Minute, true, func(context.Context) (bool, error) {
+		return controller.Ready() == nil, nil
+	})
+	if err != nil {
+		stop()
+		return BenchResult{}, fmt.Errorf("controller not ready: %v", controller.Ready())
+	}
+
+	b.generate(watches, opts, namespaces)
+	b.settle(opts.Settle)
+	stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.last.After(b.start) {
+		b.result.Elapsed = b.last.Sub(b.start)
+	}
+	sort.Slice(b.result.Latencies, func(i, j int) bool { return b.result.Latencies[i] < b.result.Latencies[j] })
+	return b.result, nil
+}
+
+// generate creates, updates and deletes the pods of opts in turn, at opts.Rate
+func (b *bench) generate(watches *benchWatches, opts BenchOptions, namespaces []string) {
+	b.mu.Lock()
+	b.start = time.Now()
+	b.mu.Unlock()
+	for i := 0; i < opts.Events; i++ {
+		if opts.Rate > 0 {
+			time.Sleep(time.Until(b.start.Add(time.Duration(float64(i) / opts.Rate * float64(time.Second)))))
+		}
+		object := i % opts.Objects
+		pod := &api_v1.Pod{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace:       namespaces[object%len(namespaces)],
+				Name:            fmt.Sprintf("bench-%d", object),
+				Labels:          map[string]string{"bench": fmt.Sprint(i)},
+				ResourceVersion: fmt.Sprint(i + 1),
+			},
+			Spec: api_v1.PodSpec{Containers: []api_v1.Container{{Name: "bench", Image: "busybox"}}},
+		}
+		eventType := []watch.EventType{watch.Added, watch.Modified, watch.Deleted}[(i/opts.Objects)%3]
+
+		b.mu.Lock()
+		b.generated[pod.Namespace+"/"+pod.Name] = time.Now()
+		b.mu.Unlock()
+		watches.send(pod.Namespace, watch.Event{Type: eventType, Object: pod})
+		b.mu.Lock()
+		b.result.Generated++
+		b.mu.Unlock()
+	}
+}
+
+// benchWatches are the watches of the pods of the fake cluster of Bench, sending the
+// synthetic events to the informers and blocking while they lag behind, where the watches
+// of fake clientsets fail once 100 events are pending
+type benchWatches struct {
+	mu sync.Mutex
+	// watches are the current watches by namespace, empty for all namespaces
+	watches map[string]*benchWatch
+	// started is closed when a watch starts
+	started chan struct{}
+}
+
+// benchWatch is a watch of benchWatches
+type benchWatch struct {
+	result  chan watch.Event
+	stopped chan struct{}
+	stop    sync.Once
+}
+
+// ResultChan implements watch.Interface
+func (w *benchWatch) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface
+func (w *benchWatch) Stop() {
+	w.stop.Do(func() { close(w.stopped) })
+}
+
+// react is the watch reactor of the pods of the fake cluster
+func (b *benchWatches) react(action k8stesting.Action) (bool, watch.Interface, error) {
+	w := &benchWatch{result: make(chan watch.Event), stopped: make(chan struct{})}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.watches[action.GetNamespace()] = w
+	close(b.started)
+	b.started = make(chan struct{})
+	return true, w, nil
+}
+
+// send sends e, about a pod of namespace, to the watches of namespace and of all
+// namespaces
+func (b *benchWatches) send(namespace string, e watch.Event) {
+	b.sendTo(namespace, e)
+	b.sendTo("", e)
+}
+
+// sendTo sends e to the watch of namespace, waiting for the next watch when the current
+// one is stopped
+func (b *benchWatches) sendTo(namespace string, e watch.Event) {
+	for {
+		b.mu.Lock()
+		w, ok := b.watches[namespace]
+		started := b.started
+		b.mu.Unlock()
+		if !ok {
+			return
+		}
+		select {
+		case w.result <- e:
+			return
+		case <-w.stopped:
+			<-started
+		}
+	}
+}
+
+// settle waits until no event was handled for d
+func (b *bench) settle(d time.Duration) {
+	processed := controller.ProcessedEvents()
+	idle := time.Now()
+	for time.Since(idle) < d {
+		time.Sleep(d / 10)
+		if current := controller.ProcessedEvents(); current != processed {
+			processed = current
+			idle = time.Now()
+		}
+	}
+}
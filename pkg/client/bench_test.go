@@ -0,0 +1,82 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+func TestBench(t *testing.T) {
+	tests := []struct {
+		name      string
+		conf      config.Config
+		opts      BenchOptions
+		delivered int
+		failed    int
+		summaries int
+	}{
+		{
+			name:      "every event delivered",
+			opts:      BenchOptions{Events: 30, Objects: 10},
+			delivered: 30,
+		},
+		{
+			name:      "handler filter",
+			conf:      config.Config{HandlerFilters: map[string]config.HandlerFilter{"default": {Operations: []string{"create"}}}},
+			opts:      BenchOptions{Events: 30, Objects: 10},
+			delivered: 10,
+		},
+		{
+			name:      "watched namespaces",
+			conf:      config.Config{Namespaces: []string{"watched"}},
+			opts:      BenchOptions{Events: 30, Objects: 10, Namespaces: []string{"watched", "ignored"}},
+			delivered: 15,
+		},
+		{
+			name:      "storm",
+			conf:      config.Config{Storm: config.Storm{Threshold: 5, Window: time.Minute}},
+			opts:      BenchOptions{Events: 20, Objects: 20},
+			delivered: 7,
+			summaries: 2,
+		},
+		{
+			name:   "failures",
+			conf:   config.Config{Queue: config.Queue{MaxRetries: 1}},
+			opts:   BenchOptions{Events: 5, Objects: 5, FailureRate: 1},
+			failed: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Settle = 200 * time.Millisecond
+			result, err := Bench(&tt.conf, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Generated != tt.opts.Events {
+				t.Errorf("expected %d generated events, got %d", tt.opts.Events, result.Generated)
+			}
+			if result.Delivered["default"] != tt.delivered || result.Failed["default"] != tt.failed || result.Summaries != tt.summaries {
+				t.Errorf("expected %d deliveries, %d failures and %d summaries, got %d, %d and %d",
+					tt.delivered, tt.failed, tt.summaries, result.Delivered["default"], result.Failed["default"], result.Summaries)
+			}
+		})
+	}
+}
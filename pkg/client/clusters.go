@@ -0,0 +1,133 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+)
+
+// Clusters returns the clients of the clusters configured in conf.
+// Without configured clusters, the cluster kubewatch runs in is used
+// (or the current kubeconfig context when running out of cluster).
+func Clusters(conf *config.Config) ([]controller.Cluster, error) {
+	links, err := controller.ParseLinks(conf.Links)
+	if err != nil {
+		return nil, err
+	}
+	groupKeys, err := controller.ParseGroupKeys(conf.GroupKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conf.Clusters) == 0 {
+		restConfig, err := utils.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("Can not get kubernetes config: %v", err)
+		}
+		cluster, err := newCluster(conf.ClusterName, restConfig)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Environment = conf.Environment
+		cluster.Region = conf.Region
+		cluster.Labels = conf.ClusterLabels
+		cluster.Links = links
+		cluster.GroupKeys = groupKeys
+		return []controller.Cluster{cluster}, nil
+	}
+
+	clusters := make([]controller.Cluster, 0, len(conf.Clusters))
+	names := map[string]bool{}
+	for _, c := range conf.Clusters {
+		restConfig, context, err := utils.GetClusterConfig(c.Kubeconfig, c.Context)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %v", c.Name, err)
+		}
+
+		name := c.Name
+		if name == "" {
+			name = context
+		}
+		if names[name] {
+			return nil, fmt.Errorf("cluster %q is configured more than once", name)
+		}
+		names[name] = true
+
+		cluster, err := newCluster(name, restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %v", name, err)
+		}
+		cluster.Environment, cluster.Region, cluster.Labels = clusterMetadata(conf, c)
+		cluster.Links = links
+		cluster.GroupKeys = groupKeys
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// clusterMetadata returns the environment, region and labels of c, defaulting to
+// those of conf
+func clusterMetadata(conf *config.Config, c config.Cluster) (string, string, map[string]string) {
+	environment, region := c.Environment, c.Region
+	if environment == "" {
+		environment = conf.Environment
+	}
+	if region == "" {
+		region = conf.Region
+	}
+	if len(c.Labels) == 0 {
+		return environment, region, conf.ClusterLabels
+	}
+	labels := make(map[string]string, len(conf.ClusterLabels)+len(c.Labels))
+	for k, v := range conf.ClusterLabels {
+		labels[k] = v
+	}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	return environment, region, labels
+}
+
+func newCluster(name string, restConfig *rest.Config) (controller.Cluster, error) {
+	kubeClient, err := kubernetes.NewForConfig(utils.WithProtobuf(restConfig))
+	if err != nil {
+		return controller.Cluster{}, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return controller.Cluster{}, err
+	}
+	metadataClient, err := metadata.NewForConfig(restConfig)
+	if err != nil {
+		return controller.Cluster{}, err
+	}
+
+	return controller.Cluster{
+		Name:           name,
+		KubeClient:     kubeClient,
+		DynamicClient:  dynamicClient,
+		MetadataClient: metadataClient,
+	}, nil
+}
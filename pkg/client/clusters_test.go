@@ -0,0 +1,106 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+var kubeconfig = `
+apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+- name: staging
+  context:
+    cluster: staging
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func TestClusters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{Clusters: []config.Cluster{
+		{Kubeconfig: path},
+		{Name: "stg", Kubeconfig: path, Context: "staging"},
+	}}
+	clusters, err := Clusters(conf)
+	if err != nil {
+		t.Fatalf("Clusters(): %v", err)
+	}
+	if len(clusters) != 2 || clusters[0].Name != "prod" || clusters[1].Name != "stg" {
+		t.Fatalf("unexpected clusters: %+v", clusters)
+	}
+
+	conf.Clusters = append(conf.Clusters, config.Cluster{Kubeconfig: path, Context: "prod"})
+	if _, err := Clusters(conf); err == nil {
+		t.Fatalf("expected duplicated cluster name to fail")
+	}
+
+	conf.Clusters = []config.Cluster{{Kubeconfig: path, Context: "missing"}}
+	if _, err := Clusters(conf); err == nil {
+		t.Fatalf("expected unknown context to fail")
+	}
+}
+
+func TestClusterMetadata(t *testing.T) {
+	conf := &config.Config{Environment: "production", Region: "eu-west-1", ClusterLabels: map[string]string{"team": "platform", "tier": "1"}}
+
+	var Tests = []struct {
+		cluster     config.Cluster
+		environment string
+		region      string
+		labels      map[string]string
+	}{
+		{config.Cluster{}, "production", "eu-west-1", map[string]string{"team": "platform", "tier": "1"}},
+		{config.Cluster{Environment: "staging", Region: "us-east-1"}, "staging", "us-east-1", map[string]string{"team": "platform", "tier": "1"}},
+		{config.Cluster{Labels: map[string]string{"tier": "2"}}, "production", "eu-west-1", map[string]string{"team": "platform", "tier": "2"}},
+	}
+
+	for _, test := range Tests {
+		environment, region, labels := clusterMetadata(conf, test.cluster)
+		if environment != test.environment || region != test.region || !reflect.DeepEqual(labels, test.labels) {
+			t.Errorf("clusterMetadata(%+v) = %q, %q, %v, expected %q, %q, %v", test.cluster, environment, region, labels, test.environment, test.region, test.labels)
+		}
+	}
+	if conf.ClusterLabels["tier"] != "1" {
+		t.Errorf("expected cluster labels not to modify the top-level labels")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// kubewatchConfigs are the KubewatchConfig custom resources kubewatch can read its configuration from
+var kubewatchConfigs = schema.GroupVersionResource{Group: "kubewatch.io", Version: "v1", Resource: "kubewatchconfigs"}
+
+// ConfigResource is the KubewatchConfig custom resource holding the configuration in its spec
+type ConfigResource struct {
+	Namespace string
+	Name      string
+}
+
+// ParseConfigResource parses a KubewatchConfig reference written as namespace/name
+func ParseConfigResource(s string) (ConfigResource, error) {
+	namespace, name, ok := strings.Cut(s, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return ConfigResource{}, fmt.Errorf("invalid config resource %q, expected namespace/name", s)
+	}
+	return ConfigResource{Namespace: namespace, Name: name}, nil
+}
+
+func (r ConfigResource) String() string {
+	return r.Namespace + "/" + r.Name
+}
+
+// LoadConfigResource returns the configuration in the spec of the KubewatchConfig r of the
+// cluster kubewatch runs in, and a ConfigWatcher reporting the changes of its spec
+func LoadConfigResource(r ConfigResource) (*config.Config, controller.ConfigWatcher, error) {
+	restConfig, err := utils.GetConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	obj, err := client.Resource(kubewatchConfigs).Namespace(r.Namespace).Get(context.Background(), r.Name, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("config resource %s: %v", r, err)
+	}
+	conf, err := configFromResource(obj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config resource %s: %v", r, err)
+	}
+
+	watchConfig := func(stopCh <-chan struct{}, onChange func(*config.Config, error)) {
+		watchConfigResource(client, r, obj.GetGeneration(), stopCh, onChange)
+	}
+	return conf, watchConfig, nil
+}
+
+// configFromResource returns the configuration in the spec of a KubewatchConfig. The spec
+// follows the schema of config files, unknown fields are rejected.
+func configFromResource(obj *unstructured.Unstructured) (*config.Config, error) {
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	spec["apiVersion"], spec["kind"] = config.APIVersion, config.Kind
+	b, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := config.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	conf.CheckMissingResourceEnvvars()
+	return conf, nil
+}
+
+// watchConfigResource calls onChange with the configuration of the KubewatchConfig r each
+// time its spec changes, from generation, until stopCh is closed
+func watchConfigResource(client dynamic.Interface, r ConfigResource, generation int64, stopCh <-chan struct{}, onChange func(*config.Config, error)) {
+	resourceClient := client.Resource(kubewatchConfigs).Namespace(r.Namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", r.Name).String()
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return resourceClient.List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return resourceClient.Watch(context.Background(), options)
+			},
+		},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{},
+	)
+
+	changed := func(obj interface{}) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || u.GetGeneration() == generation {
+			return
+		}
+		generation = u.GetGeneration()
+		logrus.WithField("pkg", "kubewatch-reload").Infof("Config resource %s changed (generation %d)", r, generation)
+		conf, err := configFromResource(u)
+		if err != nil {
+			err = fmt.Errorf("config resource %s: %v", r, err)
+		}
+		onChange(conf, err)
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    changed,
+		UpdateFunc: func(old, new interface{}) { changed(new) },
+		DeleteFunc: func(obj interface{}) {
+			logrus.WithField("pkg", "kubewatch-reload").Warnf("Config resource %s deleted, keeping the current configuration", r)
+		},
+	})
+	informer.Run(stopCh)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestParseConfigResource(t *testing.T) {
+	var Tests = []struct {
+		ref      string
+		expected ConfigResource
+		err      bool
+	}{
+		{"kubewatch/default", ConfigResource{Namespace: "kubewatch", Name: "default"}, false},
+		{"default", ConfigResource{}, true},
+		{"kubewatch/", ConfigResource{}, true},
+		{"a/b/c", ConfigResource{}, true},
+	}
+
+	for _, tt := range Tests {
+		ref, err := ParseConfigResource(tt.ref)
+		if ref != tt.expected || (err != nil) != tt.err {
+			t.Errorf("ParseConfigResource(%q) = %v, %v, expected %v, error: %v", tt.ref, ref, err, tt.expected, tt.err)
+		}
+	}
+}
+
+func kubewatchConfig(generation int64, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kubewatch.io/v1",
+		"kind":       "KubewatchConfig",
+		"metadata": map[string]interface{}{
+			"name":       "default",
+			"namespace":  "kubewatch",
+			"generation": generation,
+		},
+		"spec": spec,
+	}}
+	return obj
+}
+
+func TestConfigFromResource(t *testing.T) {
+	conf, err := configFromResource(kubewatchConfig(1, map[string]interface{}{
+		"resource":        map[string]interface{}{"deployment": true},
+		"handler":         map[string]interface{}{"webhook": map[string]interface{}{"url": "https://hooks.example.com"}},
+		"shutdowntimeout": "45s",
+		"queue":           map[string]interface{}{"workers": int64(3)},
+	}))
+	if err != nil {
+		t.Fatalf("configFromResource() = %v", err)
+	}
+	if !conf.Resource.Deployment || conf.Handler.Webhook.Url != "https://hooks.example.com" || conf.ShutdownTimeout != 45*time.Second || conf.Queue.Workers != 3 {
+		t.Errorf("configFromResource() = %+v, expected the settings of the spec", conf)
+	}
+
+	_, err = configFromResource(kubewatchConfig(1, map[string]interface{}{
+		"rsources": map[string]interface{}{"deployment": true},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "rsources: unknown field") {
+		t.Errorf("configFromResource() = %v, expected an unknown field error", err)
+	}
+}
+
+func TestWatchConfigResource(t *testing.T) {
+	scheme := runtime.NewScheme()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme,
+		map[schema.GroupVersionResource]string{kubewatchConfigs: "KubewatchConfigList"},
+		kubewatchConfig(1, map[string]interface{}{"resource": map[string]interface{}{"deployment": true}}))
+
+	changes := make(chan *config.Config, 1)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go watchConfigResource(client, ConfigResource{Namespace: "kubewatch", Name: "default"}, 1, stopCh, func(conf *config.Config, err error) {
+		if err != nil {
+			t.Errorf("onChange() error = %v", err)
+			return
+		}
+		changes <- conf
+	})
+
+	updated := kubewatchConfig(2, map[string]interface{}{"resource": map[string]interface{}{"pod": true}})
+	// the watch may not be established yet, retry the update until the change is reported
+	timeout := time.After(5 * time.Second)
+	for {
+		if _, err := client.Resource(kubewatchConfigs).Namespace("kubewatch").Update(context.Background(), updated, meta_v1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case conf := <-changes:
+			if !conf.Resource.Pod || conf.Resource.Deployment {
+				t.Errorf("onChange() config resources = %+v, expected only pods", conf.Resource)
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("configuration change not reported")
+		}
+	}
+}
+
+func TestReloadHandler(t *testing.T) {
+	conf := &config.Config{}
+	reloadable := handlers.NewReloadable(&handlers.Default{})
+
+	newConf := &config.Config{}
+	newConf.Handler.Webhook.Url = "https://hooks.example.com"
+	watchConfig := func(stopCh <-chan struct{}, onChange func(*config.Config, error)) {
+		onChange(newConf, nil)
+	}
+
+	var reported *config.Config
+	reloadHandler(reloadable, conf, watchConfig)(nil, func(c *config.Config, err error) {
+		reported = c
+	})
+	if reported != newConf {
+		t.Errorf("reloadHandler() did not forward the new configuration")
+	}
+	if counted, ok := reloadable.Set(nil).(*handlers.Counted); !ok || counted.Name != "webhook" {
+		t.Errorf("reloadHandler() did not replace the handler by the counted webhook handler")
+	} else if _, ok := counted.Handler.(*webhook.Webhook); !ok {
+		t.Errorf("reloadHandler() did not replace the handler by the webhook handler")
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+)
+
+// dialTimeout bounds the time spent checking that a handler endpoint is reachable
+var dialTimeout = 5 * time.Second
+
+// Check is the outcome of a diagnostic run by Diagnose
+type Check struct {
+	Name string
+	// Detail describes a successful check
+	Detail string
+	Err    error
+}
+
+// Diagnose checks the validity of conf, the connectivity to the API server of every
+// configured cluster, the RBAC permissions of the watched resources and the reachability
+// of the configured handlers
+func Diagnose(conf *config.Config) []Check {
+	checks := []Check{{Name: "config", Detail: "valid", Err: errors.Join(conf.Validate()...)}}
+
+	clusters, err := Clusters(conf)
+	if err != nil {
+		checks = append(checks, Check{Name: "clusters", Err: err})
+	}
+	for _, cluster := range clusters {
+		name := "cluster"
+		if cluster.Name != "" {
+			name = fmt.Sprintf("cluster %s", cluster.Name)
+		}
+
+		version, err := cluster.KubeClient.Discovery().ServerVersion()
+		if err != nil {
+			checks = append(checks, Check{Name: name + ": API server", Err: err})
+			continue
+		}
+		checks = append(checks, Check{Name: name + ": API server", Detail: "reachable, version " + version.GitVersion})
+		checks = append(checks, accessCheck(name, conf, cluster))
+	}
+
+	for _, handler := range ConfiguredHandlers(conf) {
+		checks = append(checks, handlerCheck(handler, conf))
+	}
+	return checks
+}
+
+func accessCheck(name string, conf *config.Config, cluster controller.Cluster) Check {
+	check := Check{Name: name + ": RBAC", Detail: "all watched resources allowed"}
+	denials, err := controller.CheckAccess(conf, cluster)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	var errs []error
+	for _, denial := range denials {
+		resource := denial.Resource
+		if denial.Group != "" {
+			resource += "." + denial.Group
+		}
+		scope := "cluster-wide"
+		if denial.Namespace != "" {
+			scope = "in namespace " + denial.Namespace
+		}
+		errs = append(errs, fmt.Errorf("cannot %s %s %s", strings.Join(denial.Verbs, ", "), resource, scope))
+	}
+	check.Err = errors.Join(errs...)
+	return check
+}
+
+// handlerCheck initializes the handler called name and connects to its endpoint
+func handlerCheck(name string, conf *config.Config) Check {
+	check := Check{Name: "handler " + name}
+	if _, err := NewNamedHandler(name, conf); err != nil {
+		check.Err = err
+		return check
+	}
+	address, err := handlerAddress(name, conf)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	if address == "" {
+		check.Detail = "configured, no endpoint to check"
+		return check
+	}
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	conn.Close()
+	check.Detail = address + " reachable"
+	return check
+}
+
+// handlerAddress returns the host:port the handler called name sends events to,
+// empty when it is not set in conf
+func handlerAddress(name string, conf *config.Config) (string, error) {
+	var endpoint string
+	switch name {
+	case "slack":
+		endpoint = "https://slack.com"
+	case "slackwebhook":
+		endpoint = conf.Handler.SlackWebhook.Slackwebhookurl
+	case "hipchat":
+		endpoint = conf.Handler.Hipchat.Url
+		if endpoint == "" {
+			endpoint = "https://api.hipchat.com"
+		}
+	case "mattermost":
+		endpoint = conf.Handler.Mattermost.Url
+	case "flock":
+		endpoint = conf.Handler.Flock.Url
+	case "webhook":
+		endpoint = conf.Handler.Webhook.Url
+	case "cloudevent":
+		endpoint = conf.Handler.CloudEvent.Url
+	case "msteams":
+		endpoint = conf.Handler.MSTeams.WebhookURL
+	case "lark":
+		endpoint = conf.Handler.Lark.WebhookURL
+	case "smtp":
+		return conf.Handler.SMTP.Smarthost, nil
+	}
+	if endpoint == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+func TestHandlerAddress(t *testing.T) {
+	var Tests = []struct {
+		handler  string
+		conf     func(conf *config.Config)
+		expected string
+	}{
+		{"webhook", func(conf *config.Config) { conf.Handler.Webhook.Url = "https://hooks.example.com/kubewatch" }, "hooks.example.com:443"},
+		{"webhook", func(conf *config.Config) { conf.Handler.Webhook.Url = "http://hooks.example.com/kubewatch" }, "hooks.example.com:80"},
+		{"mattermost", func(conf *config.Config) { conf.Handler.Mattermost.Url = "http://chat.example.com:8065/hooks/x" }, "chat.example.com:8065"},
+		{"hipchat", func(conf *config.Config) {}, "api.hipchat.com:443"},
+		{"smtp", func(conf *config.Config) { conf.Handler.SMTP.Smarthost = "smtp.example.com:587" }, "smtp.example.com:587"},
+		{"msteams", func(conf *config.Config) {}, ""},
+	}
+
+	for _, tt := range Tests {
+		conf := &config.Config{}
+		tt.conf(conf)
+		address, err := handlerAddress(tt.handler, conf)
+		if err != nil || address != tt.expected {
+			t.Errorf("handlerAddress(%s) = %q, %v, expected %q", tt.handler, address, err, tt.expected)
+		}
+	}
+}
+
+func TestHandlerCheck(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	conf := &config.Config{}
+	conf.Handler.Webhook.Url = ts.URL
+	if check := handlerCheck("webhook", conf); check.Err != nil || !strings.HasSuffix(check.Detail, "reachable") {
+		t.Errorf("expected the webhook to be reachable, got %+v", check)
+	}
+
+	ts.Close()
+	if check := handlerCheck("webhook", conf); check.Err == nil {
+		t.Errorf("expected the closed webhook to be unreachable, got %+v", check)
+	}
+
+	if check := handlerCheck("webhook", &config.Config{}); check.Err == nil {
+		t.Errorf("expected the unconfigured webhook to fail, got %+v", check)
+	}
+}
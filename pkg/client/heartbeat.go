@@ -0,0 +1,123 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// heartbeat sends the notifications about kubewatch itself configured in conf.Heartbeat
+type heartbeat struct {
+	conf        config.Heartbeat
+	clusterName string
+	handler     handlers.Handler
+	// processed returns the number of events handled since kubewatch started
+	processed func() uint64
+}
+
+// newHeartbeat returns the heartbeat of conf, sending its notifications to eventHandler
+// unless another handler is configured
+func newHeartbeat(conf *config.Config, eventHandler handlers.Handler) (*heartbeat, error) {
+	h := &heartbeat{
+		conf:        conf.Heartbeat,
+		clusterName: conf.ClusterName,
+		handler:     eventHandler,
+		processed:   controller.ProcessedEvents,
+	}
+	if name := conf.Heartbeat.Handler; name != "" {
+		handler, err := runHandler(name, conf, conf.DryRun)
+		if err != nil {
+			return nil, fmt.Errorf("heartbeat handler: %v", err)
+		}
+		h.handler = handler
+	}
+	return h, nil
+}
+
+// started notifies that kubewatch started watching clusters
+func (h *heartbeat) started(clusters int) {
+	if h.conf.Lifecycle {
+		h.send("Normal", fmt.Sprintf("kubewatch started, watching %d cluster(s)", clusters))
+	}
+}
+
+// stopped notifies that kubewatch stopped
+func (h *heartbeat) stopped() {
+	if h.conf.Lifecycle {
+		h.send("Warning", "kubewatch stopped")
+	}
+}
+
+// run sends a notification with the number of processed events every interval,
+// until stopCh is closed
+func (h *heartbeat) run(stopCh <-chan struct{}) {
+	if h.conf.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.conf.Interval)
+	defer ticker.Stop()
+
+	last := h.processed()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			processed := h.processed()
+			h.send("Normal", fmt.Sprintf("kubewatch alive, processed %d events in the last %s", processed-last, formatInterval(h.conf.Interval)))
+			last = processed
+		}
+	}
+}
+
+func (h *heartbeat) send(status string, reason string) {
+	e := event.Event{
+		ID:          uuid.New().String(),
+		Name:        "kubewatch",
+		Kind:        "Heartbeat",
+		Reason:      reason,
+		Status:      status,
+		Severity:    event.SeverityFromStatus(status),
+		Timestamp:   time.Now(),
+		ClusterName: h.clusterName,
+	}
+	if err := handlers.Deliver(context.Background(), h.handler, e); err != nil {
+		logrus.WithField("pkg", "kubewatch-heartbeat").Errorf("Error sending heartbeat: %v", err)
+	}
+}
+
+// formatInterval renders d without its zero minutes and seconds, such as 1h or 30m
+func formatInterval(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
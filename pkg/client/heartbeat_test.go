@@ -0,0 +1,110 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// heartbeatRecorder records the reasons of handled events
+type heartbeatRecorder struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *heartbeatRecorder) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *heartbeatRecorder) Handle(e event.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, e.Reason)
+}
+
+func (r *heartbeatRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.reasons...)
+}
+
+func TestHeartbeat(t *testing.T) {
+	r := &heartbeatRecorder{}
+	conf := &config.Config{Heartbeat: config.Heartbeat{Interval: 20 * time.Millisecond, Lifecycle: true}}
+	h, err := newHeartbeat(conf, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var processed uint64 = 10
+	var mu sync.Mutex
+	h.processed = func() uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		processed += 3
+		return processed
+	}
+
+	h.started(2)
+	stopCh := make(chan struct{})
+	go h.run(stopCh)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.get()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a heartbeat, got %v", r.get())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(stopCh)
+	h.stopped()
+
+	reasons := r.get()
+	if reasons[0] != "kubewatch started, watching 2 cluster(s)" {
+		t.Errorf("expected a startup notification, got %q", reasons[0])
+	}
+	if reasons[1] != "kubewatch alive, processed 3 events in the last 20ms" {
+		t.Errorf("expected a heartbeat, got %q", reasons[1])
+	}
+	if last := reasons[len(reasons)-1]; last != "kubewatch stopped" {
+		t.Errorf("expected a shutdown notification, got %q", last)
+	}
+
+	if _, err := newHeartbeat(&config.Config{Heartbeat: config.Heartbeat{Handler: "webhook"}}, r); err == nil {
+		t.Error("expected an error for an unconfigured heartbeat handler")
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	var Tests = []struct {
+		interval time.Duration
+		expected string
+	}{
+		{time.Hour, "1h"},
+		{30 * time.Minute, "30m"},
+		{90 * time.Second, "1m30s"},
+		{2*time.Hour + 30*time.Minute, "2h30m"},
+	}
+	for _, tt := range Tests {
+		if s := formatInterval(tt.interval); s != tt.expected {
+			t.Errorf("formatInterval(%s) = %s, expected %s", tt.interval, s, tt.expected)
+		}
+	}
+}
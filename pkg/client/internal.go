@@ -0,0 +1,73 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/sirupsen/logrus"
+)
+
+// internalErrorHandler sends the events about kubewatch failures to every configured handler
+// still working: the event handler unless its deliveries are failing, and the other handlers
+type internalErrorHandler struct {
+	eventHandler handlers.Handler
+	// failing returns whether deliveries to eventHandler are failing
+	failing func() bool
+	others  []handlers.Handler
+}
+
+func newInternalErrorHandler(conf *config.Config, eventHandler handlers.Handler) *internalErrorHandler {
+	h := &internalErrorHandler{eventHandler: eventHandler, failing: controller.HandlerFailing}
+	for _, name := range ConfiguredHandlers(conf) {
+		if name == EventHandlerName(conf) {
+			continue
+		}
+		other, err := runHandler(name, conf, conf.DryRun)
+		if err != nil {
+			logrus.WithField("pkg", "kubewatch-internal").Warnf("Internal errors will not be sent to the %s handler: %v", name, err)
+			continue
+		}
+		h.others = append(h.others, other)
+	}
+	return h
+}
+
+// Init does nothing, the handlers are initialized by newInternalErrorHandler
+func (h *internalErrorHandler) Init(c *config.Config) error {
+	return nil
+}
+
+// Handle sends e to every handler still working
+func (h *internalErrorHandler) Handle(e event.Event) {
+	if !h.failing() {
+		deliver(h.eventHandler, e)
+	}
+	for _, other := range h.others {
+		deliver(other, e)
+	}
+}
+
+// deliver sends e to h, logging delivery errors
+func deliver(h handlers.Handler, e event.Event) {
+	if err := handlers.Deliver(context.Background(), h, e); err != nil {
+		logrus.WithField("pkg", "kubewatch-internal").Errorf("Error sending %s event: %v", e.Kind, err)
+	}
+}
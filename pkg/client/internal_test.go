@@ -0,0 +1,50 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestInternalErrorHandler(t *testing.T) {
+	conf := &config.Config{DryRun: true}
+	conf.Handler.Webhook.Url = "http://localhost:8080"
+	conf.Handler.Slack.Token = "token"
+	conf.Handler.Slack.Channel = "alerts"
+
+	eventHandler := &heartbeatRecorder{}
+	h := newInternalErrorHandler(conf, eventHandler)
+	if len(h.others) != 1 {
+		t.Fatalf("expected the internal errors to be sent to 1 other handler, got %d", len(h.others))
+	}
+
+	failing := true
+	h.failing = func() bool { return failing }
+	h.Handle(event.Event{Kind: "KubewatchError", Reason: "deliveries failed"})
+	if reasons := eventHandler.get(); len(reasons) != 0 {
+		t.Errorf("expected the failing event handler to be skipped, got %v", reasons)
+	}
+
+	failing = false
+	h.Handle(event.Event{Kind: "KubewatchError", Reason: "deliveries recovered"})
+	if reasons := eventHandler.get(); len(reasons) != 1 || reasons[0] != "deliveries recovered" {
+		t.Errorf("expected the event handler to receive the recovery, got %v", reasons)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/history"
+)
+
+// ReplayOptions selects the stored events sent again by Replay
+type ReplayOptions struct {
+	Query history.Query
+	// Handlers the events are sent to, the configured handlers when empty
+	Handlers []string
+	// DryRun prints the selected events instead of sending them
+	DryRun bool
+}
+
+// ReplayResult counts the events sent by Replay
+type ReplayResult struct {
+	Sent   int
+	Failed int
+}
+
+// Replay sends the events of the event store of conf matching opts.Query again, oldest
+// first, printing a line per event to out. Events keep their ID, for receivers to
+// recognize events they already got.
+func Replay(conf *config.Config, opts ReplayOptions, out io.Writer) (ReplayResult, error) {
+	var result ReplayResult
+	if conf.Store.Path == "" {
+		return result, errors.New("no event store configured, set store.path")
+	}
+	if _, err := os.Stat(conf.Store.Path); err != nil {
+		return result, fmt.Errorf("opening the event store: %v", err)
+	}
+	store, err := history.OpenStore(conf.Store.Path, conf.Store.Retention)
+	if err != nil {
+		return result, fmt.Errorf("opening the event store: %v", err)
+	}
+	defer store.Close()
+	stored, err := store.Query(opts.Query)
+	if err != nil {
+		return result, err
+	}
+
+	names := opts.Handlers
+	if len(names) == 0 {
+		names = ConfiguredHandlers(conf)
+	}
+	if len(names) == 0 {
+		return result, errors.New("no handler configured")
+	}
+	eventHandlers := make([]handlers.Handler, len(names))
+	for i, name := range names {
+		if opts.DryRun {
+			continue
+		}
+		if eventHandlers[i], err = NewNamedHandler(name, conf); err != nil {
+			return result, fmt.Errorf("%s: invalid configuration: %v", name, err)
+		}
+		if limit := conf.PayloadLimit(name); limit > 0 {
+			eventHandlers[i] = &handlers.Limited{Name: name, MaxBytes: limit, Handler: eventHandlers[i]}
+		}
+	}
+
+	for i := len(stored) - 1; i >= 0; i-- {
+		fmt.Fprintln(out, FormatTail(stored[i], false))
+		if opts.DryRun {
+			continue
+		}
+		e := stored[i].Event()
+		for j, h := range eventHandlers {
+			if err := handlers.Deliver(context.Background(), h, e); err != nil {
+				result.Failed++
+				fmt.Fprintf(out, "  %s: FAILED: %v\n", names[j], err)
+				continue
+			}
+			result.Sent++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/history"
+)
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	store, err := history.OpenStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []event.Event{
+		{ID: "1", Kind: "Pod", Name: "api", Namespace: "payments", Reason: "Created", Timestamp: time.Now().Add(-30 * time.Minute)},
+		{ID: "2", Kind: "Pod", Name: "web", Namespace: "shop", Reason: "Created", Timestamp: time.Now().Add(-20 * time.Minute)},
+		{ID: "3", Kind: "Pod", Name: "api", Namespace: "payments", Reason: "Deleted", Timestamp: time.Now().Add(-10 * time.Minute)},
+	} {
+		if err := store.Add("", e.Payload()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	var mu sync.Mutex
+	var received []string
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message struct {
+			Event event.Payload `json:"event"`
+		}
+		json.NewDecoder(r.Body).Decode(&message)
+		mu.Lock()
+		received = append(received, message.Event.ID)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	conf := &config.Config{}
+	conf.Handler.Webhook.Url = ts.URL
+	conf.Store = config.EventStore{Path: dir, Retention: time.Hour}
+
+	var out bytes.Buffer
+	result, err := Replay(conf, ReplayOptions{Query: history.Query{Namespace: "payments"}, DryRun: true}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 0 || len(received) != 0 || bytes.Count(out.Bytes(), []byte("\n")) != 2 {
+		t.Errorf("expected a dry run to list 2 events without sending them, got %+v:\n%s", result, out.String())
+	}
+
+	result, err = Replay(conf, ReplayOptions{Query: history.Query{Namespace: "payments"}}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sent != 2 || !reflect.DeepEqual(received, []string{"1", "3"}) {
+		t.Errorf("expected the payments events to be sent oldest first with their ID, got %+v %v", result, received)
+	}
+
+	status = http.StatusBadGateway
+	if result, err = Replay(conf, ReplayOptions{Query: history.Query{Since: time.Now().Add(-15 * time.Minute)}, Handlers: []string{"webhook"}}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 {
+		t.Errorf("expected the failed delivery to be reported, got %+v", result)
+	}
+
+	if _, err := Replay(&config.Config{}, ReplayOptions{}, &out); err == nil {
+		t.Error("expected an error without event store")
+	}
+}
@@ -17,12 +17,18 @@ limitations under the License.
 package client
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
+	"reflect"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/alertmanager"
 	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/escalation"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/cloudevent"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/flock"
@@ -34,58 +40,402 @@ import (
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/slackwebhook"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/smtp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/history"
+	"github.com/bitnami-labs/kubewatch/pkg/outbox"
+	"github.com/bitnami-labs/kubewatch/pkg/ownership"
+	"github.com/bitnami-labs/kubewatch/pkg/problems"
+	"github.com/bitnami-labs/kubewatch/pkg/silence"
+	"github.com/bitnami-labs/kubewatch/pkg/stats"
+	"github.com/bitnami-labs/kubewatch/pkg/storm"
+	"github.com/bitnami-labs/kubewatch/pkg/ui"
 	"github.com/sirupsen/logrus"
 )
 
-// Run runs the event loop processing with given handler
-func Run(conf *config.Config) {
+// Run runs the event loop processing with the handler of conf. New versions of the
+// configuration reported by watchConfig replace the handler when its settings change.
+func Run(conf *config.Config, watchConfig controller.ConfigWatcher) {
 	listenAddress := os.Getenv("LISTEN_ADDRESS")
 	if listenAddress == "" {
 		listenAddress = ":2112"
 	}
+	var recent *history.Buffer
+	if conf.History.Size > 0 {
+		recent = history.NewBuffer(conf.History.Size)
+	}
+	var store *history.Store
+	if conf.Store.Path != "" {
+		var err error
+		if store, err = history.OpenStore(conf.Store.Path, conf.Store.Retention); err != nil {
+			logrus.Fatalf("Error opening the event store: %v", err)
+		}
+		defer store.Close()
+	}
+	var box *outbox.Outbox
+	if conf.Outbox.Path != "" {
+		var err error
+		if box, err = outbox.Open(conf.Outbox.Path, conf.Outbox.MaxAge); err != nil {
+			logrus.Fatalf("Error opening the outbox: %v", err)
+		}
+	}
+	silences := silence.NewStore()
 
+	// profiles are only served by the debug server
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/rbac", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(controller.AccessDenials())
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats.Get())
+	})
+	if store != nil {
+		mux.Handle("/api/v1/events", store)
+	} else if recent != nil {
+		mux.Handle("/api/v1/events", recent)
+	}
+	if recent != nil {
+		mux.HandleFunc("/api/v1/events/stream", recent.Stream)
+	}
+	if conf.Admin.Token != "" {
+		api := silences.API(conf.Admin.Token)
+		mux.Handle(silence.APIPath, api)
+		mux.Handle(silence.APIPath+"/", api)
+	}
+	if conf.WebUI {
+		mux.Handle(ui.Path, ui.Handler())
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := controller.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
 	go func() {
-		http.Handle("/metrics", promhttp.Handler())
 		logrus.Infof("Starting metrics server on port %s", listenAddress)
-		if err := http.ListenAndServe(listenAddress, nil); err != nil {
+		if err := http.ListenAndServe(listenAddress, mux); err != nil {
 			logrus.Errorf("Error starting metrics server on port %s: %v", listenAddress, err)
 		}
 	}()
 
-	var eventHandler = ParseEventHandler(conf)
-	controller.Start(conf, eventHandler)
+	clusters, err := Clusters(conf)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	eventHandler, err := runEventHandler(conf, conf.DryRun)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if conf.DryRun {
+		logrus.Infof("Dry run: events are logged instead of being sent to the %s handler", EventHandlerName(conf))
+	}
+	reloadable := handlers.NewReloadable(eventHandler)
+	if conf.NotifyInternalErrors {
+		controller.SetInternalErrorHandler(newInternalErrorHandler(conf, reloadable))
+	}
+
+	hb, err := newHeartbeat(conf, reloadable)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	hb.started(len(clusters))
+	stopCh := make(chan struct{})
+	go hb.run(stopCh)
+	if store != nil {
+		go store.Run(stopCh)
+	}
+
+	var eventsHandler handlers.Handler = reloadable
+	if len(conf.Routes) > 0 {
+		router, err := newRouter(conf, reloadable)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		eventsHandler = router
+	}
+	owners, err := ownership.New(conf, clusters[0].KubeClient)
+	if err != nil {
+		logrus.Fatalf("Error reading the ownership of namespaces: %v", err)
+	}
+	if owners != nil {
+		go owners.Run(stopCh)
+		// the events made by the handlers below, such as Resolved events, are routed
+		// with their ownership too
+		eventsHandler = &ownership.Handler{Directory: owners, Handler: eventsHandler}
+	}
+	if name := conf.Escalation.Handler; name != "" {
+		escalationHandler, err := runHandler(name, conf, conf.DryRun)
+		if err != nil {
+			logrus.Fatalf("Escalation handler: %v", err)
+		}
+		escalator, err := escalation.Open(conf.Store.Path, conf.Escalation, escalationHandler)
+		if err != nil {
+			logrus.Fatalf("Error opening the escalations: %v", err)
+		}
+		if conf.Admin.Token != "" {
+			api := escalator.API(conf.Admin.Token)
+			mux.Handle(escalation.APIPath, api)
+			mux.Handle(escalation.APIPath+"/", api)
+		}
+		if secret := conf.Escalation.SlackSigningSecret; secret != "" {
+			mux.Handle(escalation.SlackPath, escalator.SlackInteractions(secret))
+		}
+		go escalator.Run(stopCh)
+		eventsHandler = &escalation.Handler{Escalator: escalator, Handler: eventsHandler}
+	}
+	// summarized events are still recorded by the history, the store and the outbox
+	if conf.Storm.Threshold > 0 {
+		stormHandler := storm.NewHandler(conf, eventsHandler)
+		go stormHandler.Run(stopCh)
+		eventsHandler = stormHandler
+	}
+	if recent != nil {
+		eventsHandler = &history.Handler{Buffer: recent, Handler: eventsHandler}
+	}
+	if store != nil {
+		eventsHandler = &history.StoreHandler{Store: store, Handler: eventsHandler}
+	}
+	if box != nil {
+		outboxHandler := &outbox.Handler{Outbox: box, Handler: eventsHandler}
+		go outboxHandler.Redeliver()
+		eventsHandler = outboxHandler
+	}
+	if conf.Admin.Token != "" {
+		eventsHandler = &silence.Handler{Store: silences, Handler: eventsHandler}
+	}
+	if conf.Resolutions.Enabled {
+		tracker, err := problems.Open(conf.Store.Path, conf.Resolutions)
+		if err != nil {
+			logrus.Fatalf("Error opening the problems: %v", err)
+		}
+		go tracker.Run(stopCh)
+		eventsHandler = &problems.Handler{Tracker: tracker, Handler: eventsHandler}
+	}
+	if len(conf.Transforms) > 0 {
+		transforms, err := handlers.ParseTransforms(conf.Transforms)
+		if err != nil {
+			logrus.Fatalf("Error parsing the transforms: %v", err)
+		}
+		// transforms see the ownership of events, attached first
+		eventsHandler = &handlers.Transformed{Transforms: transforms, Handler: eventsHandler}
+	}
+	if owners != nil {
+		eventsHandler = &ownership.Handler{Directory: owners, Handler: eventsHandler}
+	}
+	if conf.Alertmanager.Enabled {
+		mux.Handle(alertmanager.Path, alertmanager.NewReceiver(conf, eventsHandler))
+	}
+	controller.Start(conf, eventsHandler, clusters, reloadHandler(reloadable, conf, watchConfig))
+
+	close(stopCh)
+	hb.stopped()
+}
+
+// runEventHandler returns the handler of conf, logging events instead of sending them on dry runs
+func runEventHandler(conf *config.Config, dryRun bool) (handlers.Handler, error) {
+	return runHandler(EventHandlerName(conf), conf, dryRun)
+}
+
+// runHandler returns the handler called name, translating events to its language and
+// rendering them with the theme, logging events instead of sending them on dry runs,
+// counting its deliveries in the statistics, and only sending it the events its filter
+// wants
+func runHandler(name string, conf *config.Config, dryRun bool) (handlers.Handler, error) {
+	handler, err := NewNamedHandler(name, conf)
+	if err != nil {
+		return nil, err
+	}
+	return wrapHandler(name, handler, conf, dryRun)
+}
+
+// wrapHandler wraps handler, sending the events of the handler called name, as runHandler
+// does
+func wrapHandler(name string, handler handlers.Handler, conf *config.Config, dryRun bool) (handlers.Handler, error) {
+	if limit := conf.PayloadLimit(name); limit > 0 {
+		handler = &handlers.Limited{Name: name, MaxBytes: limit, Handler: handler}
+	}
+	// events are translated before being truncated to the size of their translation
+	if language := conf.Localization.Languages[name]; language != "" {
+		catalog, err := event.LoadCatalog(language, conf.Localization.Catalogs[language])
+		if err != nil {
+			return nil, fmt.Errorf("localization of %s: %v", name, err)
+		}
+		handler = &handlers.Localized{Catalog: catalog, Handler: handler}
+	}
+	if t := conf.Theme; t.Name != "" || len(t.Emoji) > 0 || len(t.Colors) > 0 || len(t.Prefixes) > 0 {
+		theme, err := event.NewTheme(t.Name, t.Emoji, t.Colors, t.Prefixes)
+		if err != nil {
+			return nil, fmt.Errorf("theme: %v", err)
+		}
+		handler = &handlers.Themed{Theme: theme, Handler: handler}
+	}
+	if dryRun {
+		handler = &handlers.DryRun{Name: name, Handler: handler}
+	}
+	handler = &handlers.Counted{Name: name, Handler: handler}
+	// filtered out events are not counted as deliveries
+	if filter, ok := conf.HandlerFilters[name]; ok {
+		handler = handlers.NewFiltered(name, filter, handler)
+	}
+	return handler, nil
+}
+
+// newRouter returns the router of the routes of conf, sending the events matching no route
+// to eventHandler. Routes to the handler receiving events use eventHandler too.
+func newRouter(conf *config.Config, eventHandler handlers.Handler) (*handlers.Router, error) {
+	return newRouterWith(conf, eventHandler, func(name string) (handlers.Handler, error) {
+		return runHandler(name, conf, conf.DryRun)
+	})
+}
+
+// newRouterWith returns the router of the routes of conf as newRouter does, creating the
+// handlers of the routes with newHandler
+func newRouterWith(conf *config.Config, eventHandler handlers.Handler, newHandler func(name string) (handlers.Handler, error)) (*handlers.Router, error) {
+	routeHandlers := map[string]handlers.Handler{EventHandlerName(conf): eventHandler}
+	var routes []handlers.Route
+	for i, r := range conf.Routes {
+		handler, ok := routeHandlers[r.Handler]
+		if !ok {
+			var err error
+			if handler, err = newHandler(r.Handler); err != nil {
+				return nil, fmt.Errorf("routes[%d] handler: %v", i, err)
+			}
+			routeHandlers[r.Handler] = handler
+		}
+		route, err := handlers.NewRoute(r, handler)
+		if err != nil {
+			return nil, fmt.Errorf("routes[%d]: %v", i, err)
+		}
+		routes = append(routes, route)
+	}
+	return handlers.NewRouter(routes, eventHandler), nil
+}
+
+// reloadHandler returns a ConfigWatcher replacing the handler of reloadable each time the
+// handler settings of the configurations reported by watchConfig change
+func reloadHandler(reloadable *handlers.Reloadable, conf *config.Config, watchConfig controller.ConfigWatcher) controller.ConfigWatcher {
+	dryRun := conf.DryRun
+	current := conf.Handler
+	return func(stopCh <-chan struct{}, onChange func(*config.Config, error)) {
+		watchConfig(stopCh, func(newConf *config.Config, err error) {
+			if err == nil && !reflect.DeepEqual(newConf.Handler, current) {
+				eventHandler, err := runEventHandler(newConf, dryRun)
+				if err != nil {
+					logrus.Errorf("Keeping the current handler, the new handler configuration is invalid: %v", err)
+				} else {
+					current = newConf.Handler
+					previous := reloadable.Set(eventHandler)
+					if f, ok := previous.(handlers.Flusher); ok {
+						if err := f.Flush(); err != nil {
+							logrus.Errorf("Error flushing the previous handler: %v", err)
+						}
+					}
+					logrus.Infof("Handler configuration changed, events are sent to the %s handler", EventHandlerName(newConf))
+				}
+			}
+			onChange(newConf, err)
+		})
+	}
 }
 
 // ParseEventHandler returns the respective handler object specified in the config file.
 func ParseEventHandler(conf *config.Config) handlers.Handler {
+	eventHandler, err := NewEventHandler(conf)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	return eventHandler
+}
+
+// NewEventHandler returns the handler configured in conf, initialized.
+// When several handlers are configured, the first one in handlerSpecs is used.
+func NewEventHandler(conf *config.Config) (handlers.Handler, error) {
+	return NewNamedHandler(EventHandlerName(conf), conf)
+}
+
+// EventHandlerName returns the name of the handler NewEventHandler returns
+func EventHandlerName(conf *config.Config) string {
+	names := ConfiguredHandlers(conf)
+	if len(names) == 0 {
+		return "default"
+	}
+	return names[0]
+}
+
+// handlerSpec tells how to detect that a handler is configured, and how to create it
+type handlerSpec struct {
+	name       string
+	configured func(conf *config.Config) bool
+	new        func() handlers.Handler
+}
 
+// handlerSpecs lists the handlers by order of precedence
+var handlerSpecs = []handlerSpec{
+	{"slack", func(conf *config.Config) bool {
+		return len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0
+	}, func() handlers.Handler { return new(slack.Slack) }},
+	{"slackwebhook", func(conf *config.Config) bool {
+		return len(conf.Handler.SlackWebhook.Channel) > 0 || len(conf.Handler.SlackWebhook.Username) > 0 || len(conf.Handler.SlackWebhook.Slackwebhookurl) > 0
+	}, func() handlers.Handler { return new(slackwebhook.SlackWebhook) }},
+	{"hipchat", func(conf *config.Config) bool {
+		return len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0
+	}, func() handlers.Handler { return new(hipchat.Hipchat) }},
+	{"mattermost", func(conf *config.Config) bool {
+		return len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0
+	}, func() handlers.Handler { return new(mattermost.Mattermost) }},
+	{"flock", func(conf *config.Config) bool {
+		return len(conf.Handler.Flock.Url) > 0
+	}, func() handlers.Handler { return new(flock.Flock) }},
+	{"webhook", func(conf *config.Config) bool {
+		return len(conf.Handler.Webhook.Url) > 0
+	}, func() handlers.Handler { return new(webhook.Webhook) }},
+	{"cloudevent", func(conf *config.Config) bool {
+		return len(conf.Handler.CloudEvent.Url) > 0
+	}, func() handlers.Handler { return new(cloudevent.CloudEvent) }},
+	{"msteams", func(conf *config.Config) bool {
+		return len(conf.Handler.MSTeams.WebhookURL) > 0
+	}, func() handlers.Handler { return new(msteam.MSTeams) }},
+	{"smtp", func(conf *config.Config) bool {
+		return len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0
+	}, func() handlers.Handler { return new(smtp.SMTP) }},
+	{"lark", func(conf *config.Config) bool {
+		return len(conf.Handler.Lark.WebhookURL) > 0
+	}, func() handlers.Handler { return new(lark.Webhook) }},
+}
+
+// ConfiguredHandlers returns the names of the handlers configured in conf, by order of precedence
+func ConfiguredHandlers(conf *config.Config) []string {
+	var names []string
+	for _, spec := range handlerSpecs {
+		if spec.configured(conf) {
+			names = append(names, spec.name)
+		}
+	}
+	return names
+}
+
+// NewNamedHandler returns the handler called name, initialized with conf
+func NewNamedHandler(name string, conf *config.Config) (handlers.Handler, error) {
 	var eventHandler handlers.Handler
-	switch {
-	case len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0:
-		eventHandler = new(slack.Slack)
-	case len(conf.Handler.SlackWebhook.Channel) > 0 || len(conf.Handler.SlackWebhook.Username) > 0 || len(conf.Handler.SlackWebhook.Slackwebhookurl) > 0:
-		eventHandler = new(slackwebhook.SlackWebhook)
-	case len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0:
-		eventHandler = new(hipchat.Hipchat)
-	case len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0:
-		eventHandler = new(mattermost.Mattermost)
-	case len(conf.Handler.Flock.Url) > 0:
-		eventHandler = new(flock.Flock)
-	case len(conf.Handler.Webhook.Url) > 0:
-		eventHandler = new(webhook.Webhook)
-	case len(conf.Handler.CloudEvent.Url) > 0:
-		eventHandler = new(cloudevent.CloudEvent)
-	case len(conf.Handler.MSTeams.WebhookURL) > 0:
-		eventHandler = new(msteam.MSTeams)
-	case len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0:
-		eventHandler = new(smtp.SMTP)
-	case len(conf.Handler.Lark.WebhookURL) > 0:
-		eventHandler = new(lark.Webhook)
-	default:
+	if name == "default" {
 		eventHandler = new(handlers.Default)
 	}
+	for _, spec := range handlerSpecs {
+		if spec.name == name {
+			eventHandler = spec.new()
+		}
+	}
+	if eventHandler == nil {
+		return nil, fmt.Errorf("unknown handler %q", name)
+	}
 	if err := eventHandler.Init(conf); err != nil {
-		logrus.Fatal(err)
+		return nil, err
 	}
-	return eventHandler
+	return eventHandler, nil
 }
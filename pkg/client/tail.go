@@ -0,0 +1,144 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/history"
+)
+
+// ANSI colors of severities in tailed events
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorAmber = "\033[33m"
+)
+
+// TailOptions configures Tail and TailLocal
+type TailOptions struct {
+	// URL of the metrics server of a running kubewatch, such as http://localhost:2112
+	URL string
+	// Query selects the events, with the parameters of the events API
+	Query url.Values
+	// Color highlights severities with ANSI colors
+	Color bool
+}
+
+// Tail prints the events streamed by a running kubewatch to out, until ctx is done
+func Tail(ctx context.Context, opts TailOptions, out io.Writer) error {
+	streamURL := strings.TrimSuffix(opts.URL, "/") + "/api/v1/events/stream?" + opts.Query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	err = history.ReadStream(resp.Body, func(p event.Payload) error {
+		_, err := fmt.Fprintln(out, FormatTail(p, opts.Color))
+		return err
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// TailLocal watches the clusters of conf in process instead of connecting to a running
+// kubewatch, printing the events matching opts.Query to out until interrupted.
+// Checkpoints are neither read nor written.
+func TailLocal(conf *config.Config, opts TailOptions, out io.Writer) error {
+	query, err := history.ParseQuery(opts.Query)
+	if err != nil {
+		return err
+	}
+	query.Limit = 0
+	clusters, err := Clusters(conf)
+	if err != nil {
+		return err
+	}
+	conf.Checkpoint = config.Checkpoint{}
+	printer := &tailPrinter{query: query, color: opts.Color, out: out}
+	controller.Start(conf, printer, clusters, func(<-chan struct{}, func(*config.Config, error)) {})
+	return nil
+}
+
+// tailPrinter is a handler printing the events matching query
+type tailPrinter struct {
+	mu    sync.Mutex
+	query history.Query
+	color bool
+	out   io.Writer
+}
+
+func (t *tailPrinter) Init(c *config.Config) error {
+	return nil
+}
+
+func (t *tailPrinter) Handle(e event.Event) {
+	p := e.Payload()
+	if !t.query.Matches(p) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintln(t.out, FormatTail(p, t.color))
+}
+
+// FormatTail renders p as a line of kubewatch tail: time, severity, object and summary
+func FormatTail(p event.Payload, color bool) string {
+	object := p.Kind + "/" + p.Object.Name
+	if p.Object.Namespace != "" {
+		object = p.Object.Namespace + "/" + object
+	}
+	if p.Cluster != nil && p.Cluster.Name != "" {
+		object = p.Cluster.Name + ":" + object
+	}
+	severity := fmt.Sprintf("%-8s", strings.ToUpper(string(p.Severity)))
+	if color {
+		severity = severityColor(p.Severity) + severity + colorReset
+	}
+	return fmt.Sprintf("%s %s %s %s", p.Timestamp.Local().Format("15:04:05"), severity, object, p.Summary)
+}
+
+func severityColor(severity event.Severity) string {
+	switch severity {
+	case event.SeverityCritical:
+		return colorRed
+	case event.SeverityWarning, event.SeverityPolicyViolation:
+		return colorAmber
+	}
+	return colorGreen
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/history"
+)
+
+// syncWriter guards writes to w, read with readSync
+type syncWriter struct {
+	mu sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (s *syncWriter) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.String()
+}
+
+func TestFormatTail(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local)
+	p := event.Payload{
+		Kind:      "Pod",
+		Summary:   "Pod payments/api deleted",
+		Severity:  event.SeverityCritical,
+		Timestamp: timestamp,
+		Object:    event.ObjectRef{Namespace: "payments", Name: "api"},
+		Cluster:   &event.ClusterRef{Name: "prod"},
+	}
+	if line := FormatTail(p, false); line != "10:30:00 CRITICAL prod:payments/Pod/api Pod payments/api deleted" {
+		t.Errorf("unexpected line %q", line)
+	}
+	if line := FormatTail(p, true); !strings.Contains(line, colorRed+"CRITICAL"+colorReset) {
+		t.Errorf("expected a red severity, got %q", line)
+	}
+}
+
+func TestTail(t *testing.T) {
+	recent := history.NewBuffer(10)
+	recent.Add(event.Payload{Kind: "Pod", Summary: "api deleted", Severity: event.SeverityWarning, Timestamp: time.Now(), Object: event.ObjectRef{Namespace: "payments", Name: "api"}})
+	recent.Add(event.Payload{Kind: "Pod", Summary: "web deleted", Severity: event.SeverityWarning, Timestamp: time.Now(), Object: event.ObjectRef{Namespace: "shop", Name: "web"}})
+	server := httptest.NewServer(http.HandlerFunc(recent.Stream))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncWriter{w: &bytes.Buffer{}}
+	done := make(chan error)
+	go func() {
+		done <- Tail(ctx, TailOptions{URL: server.URL, Query: url.Values{"namespace": {"payments"}, "since": {"1h"}}}, out)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "api deleted") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the api event to be printed, got %q", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected Tail to stop without error, got %v", err)
+	}
+	if strings.Contains(out.String(), "web deleted") {
+		t.Errorf("expected the events of other namespaces to be filtered out, got %q", out.String())
+	}
+
+	if err := Tail(context.Background(), TailOptions{URL: server.URL, Query: url.Values{"since": {"yesterday"}}}, out); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected an invalid query to be reported, got %v", err)
+	}
+}
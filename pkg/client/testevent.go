@@ -0,0 +1,64 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/google/uuid"
+)
+
+// TestEvent returns the synthetic event sent by kubewatch test
+func TestEvent(conf *config.Config) event.Event {
+	return event.Event{
+		ID:          uuid.New().String(),
+		Namespace:   "default",
+		Name:        "kubewatch",
+		Kind:        "TestNotification",
+		Reason:      "Test notification sent by kubewatch, this handler is configured correctly",
+		Status:      "Normal",
+		Severity:    event.SeverityInfo,
+		Timestamp:   time.Now(),
+		ClusterName: conf.ClusterName,
+	}
+}
+
+// SendTestEvent initializes the handler called name and sends it TestEvent.
+// It returns false when the handler does not report delivery failures, so
+// that only its initialization is known to have succeeded.
+func SendTestEvent(name string, conf *config.Config) (bool, error) {
+	eventHandler, err := NewNamedHandler(name, conf)
+	if err != nil {
+		return false, fmt.Errorf("invalid configuration: %v", err)
+	}
+	err = handlers.Deliver(context.Background(), eventHandler, TestEvent(conf))
+	return reportsFailures(eventHandler), err
+}
+
+// reportsFailures returns whether h reports delivery failures
+func reportsFailures(h handlers.Handler) bool {
+	switch h.(type) {
+	case handlers.ContextHandler, handlers.RetryableHandler:
+		return true
+	}
+	return false
+}
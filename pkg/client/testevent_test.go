@@ -0,0 +1,79 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+func TestConfiguredHandlers(t *testing.T) {
+	conf := &config.Config{}
+	conf.Handler.Slack.Token = "xoxb-token"
+	conf.Handler.Webhook.Url = "https://hooks.example.com"
+	conf.Handler.SMTP.To = "ops@example.com"
+
+	expected := []string{"slack", "webhook", "smtp"}
+	if names := ConfiguredHandlers(conf); !reflect.DeepEqual(names, expected) {
+		t.Errorf("ConfiguredHandlers() = %v, expected %v", names, expected)
+	}
+	if names := ConfiguredHandlers(&config.Config{}); names != nil {
+		t.Errorf("ConfiguredHandlers() = %v without handlers, expected none", names)
+	}
+}
+
+func TestSendTestEvent(t *testing.T) {
+	var Tests = []struct {
+		name     string
+		handler  string
+		status   int
+		url      bool
+		reported bool
+		err      bool
+	}{
+		{"delivered", "webhook", http.StatusOK, true, true, false},
+		{"rejected", "webhook", http.StatusUnauthorized, true, true, true},
+		{"misconfigured", "webhook", http.StatusOK, false, false, true},
+		{"unknown handler", "pagerduty", http.StatusOK, true, false, true},
+		{"default handler", "default", http.StatusOK, true, false, false},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			conf := &config.Config{}
+			if tt.url {
+				conf.Handler.Webhook.Url = ts.URL
+			}
+			reported, err := SendTestEvent(tt.handler, conf)
+			if reported != tt.reported {
+				t.Errorf("SendTestEvent() reported = %v, expected %v", reported, tt.reported)
+			}
+			if (err != nil) != tt.err {
+				t.Errorf("SendTestEvent() error = %v, expected error: %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"gopkg.in/yaml.v3"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// autoscalerComponent is the source of the Kubernetes Events of the cluster autoscaler
+const autoscalerComponent = "cluster-autoscaler"
+
+// autoscalerHealth matches the sizes of a node group in the health line of the legacy
+// status format of the cluster autoscaler
+var autoscalerHealth = regexp.MustCompile(`(cloudProviderTarget|minSize|maxSize)=(\d+)`)
+
+// nodeGroupStatus is the status of a node group of the cluster autoscaler
+type nodeGroupStatus struct {
+	target, minSize, maxSize int
+	// scaleUp is the scale-up status, such as NoActivity, InProgress or Backoff
+	scaleUp string
+}
+
+// autoscalerStatus is the YAML status format of cluster autoscaler 1.30 and later
+type autoscalerStatus struct {
+	NodeGroups []struct {
+		Name   string `yaml:"name"`
+		Health struct {
+			CloudProviderTarget int `yaml:"cloudProviderTarget"`
+			MinSize             int `yaml:"minSize"`
+			MaxSize             int `yaml:"maxSize"`
+		} `yaml:"health"`
+		ScaleUp struct {
+			Status string `yaml:"status"`
+		} `yaml:"scaleUp"`
+	} `yaml:"nodeGroups"`
+}
+
+// clusterAutoscaler reports the node groups scaled by the cluster autoscaler, and those
+// whose scale-up backs off, from the changes of its status ConfigMap.
+type clusterAutoscaler struct {
+	w         *clusterWatcher
+	namespace string
+	name      string
+
+	mu sync.Mutex
+	// groups holds the last status of every node group, nil until the status is first read
+	groups map[string]nodeGroupStatus
+}
+
+func newClusterAutoscaler(w *clusterWatcher, namespace, name string) *clusterAutoscaler {
+	return &clusterAutoscaler{w: w, namespace: namespace, name: name}
+}
+
+// watch watches the status ConfigMap until stopCh is closed
+func (a *clusterAutoscaler) watch(stopCh <-chan struct{}) {
+	kubeClient := a.w.kubeClient
+	selector := "metadata.name=" + a.name
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return kubeClient.CoreV1().ConfigMaps(a.namespace).List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return kubeClient.CoreV1().ConfigMaps(a.namespace).Watch(context.Background(), options)
+			},
+		},
+		&api_v1.ConfigMap{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			a.notify(obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			a.notify(new)
+		},
+	})
+	informer.Run(stopCh)
+}
+
+func (a *clusterAutoscaler) notify(obj interface{}) {
+	configMap, ok := obj.(*api_v1.ConfigMap)
+	if !ok {
+		return
+	}
+	for _, e := range a.observe(configMap) {
+		a.w.notify(e)
+	}
+}
+
+// observe returns the events about the node groups whose size or scale-up status changed
+// since the previous status. The first status read is only recorded.
+func (a *clusterAutoscaler) observe(configMap *api_v1.ConfigMap) []event.Event {
+	groups := parseAutoscalerStatus(configMap.Data["status"])
+	a.mu.Lock()
+	previous := a.groups
+	a.groups = groups
+	a.mu.Unlock()
+	if previous == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var events []event.Event
+	for _, name := range names {
+		group, old := groups[name], previous[name]
+		if _, ok := previous[name]; !ok {
+			continue
+		}
+		if group.target >= 0 && old.target >= 0 && group.target != old.target {
+			events = append(events, a.event(name, "Normal",
+				fmt.Sprintf("Scaled node group %s from %d to %d%s", name, old.target, group.target, group.bounds())))
+		}
+		if group.scaleUp == "Backoff" && old.scaleUp != "Backoff" {
+			events = append(events, a.event(name, "Warning",
+				fmt.Sprintf("Scale-up of node group %s is backing off after failing to add nodes%s", name, group.bounds())))
+		}
+	}
+	return events
+}
+
+func (a *clusterAutoscaler) event(group, status, reason string) event.Event {
+	return event.Event{
+		Name:       group,
+		Kind:       "ClusterAutoscaler",
+		APIVersion: V1,
+		Status:     status,
+		Reason:     reason,
+		Obj: &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{
+			Name: a.name, Namespace: a.namespace,
+		}},
+	}
+}
+
+// bounds renders the size bounds of the node group, when known
+func (s nodeGroupStatus) bounds() string {
+	if s.maxSize <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (min %d, max %d)", s.minSize, s.maxSize)
+}
+
+// parseAutoscalerStatus returns the status of every node group of a cluster autoscaler
+// status, in the YAML format or in the legacy text format
+func parseAutoscalerStatus(status string) map[string]nodeGroupStatus {
+	groups := map[string]nodeGroupStatus{}
+	if !strings.HasPrefix(status, "Cluster-autoscaler status at") {
+		var parsed autoscalerStatus
+		if err := yaml.Unmarshal([]byte(status), &parsed); err == nil {
+			for _, group := range parsed.NodeGroups {
+				groups[group.Name] = nodeGroupStatus{
+					target:  group.Health.CloudProviderTarget,
+					minSize: group.Health.MinSize,
+					maxSize: group.Health.MaxSize,
+					scaleUp: group.ScaleUp.Status,
+				}
+			}
+		}
+		return groups
+	}
+
+	// the legacy format lists the node groups after NodeGroups:, as "Name:", "Health:",
+	// "ScaleUp:" and "ScaleDown:" lines
+	name, inGroups := "", false
+	for _, line := range strings.Split(status, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "NodeGroups:" {
+			inGroups = true
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !inGroups || !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			name = value
+			groups[name] = nodeGroupStatus{target: -1}
+		case "Health":
+			group := groups[name]
+			for _, m := range autoscalerHealth.FindAllStringSubmatch(value, -1) {
+				n, _ := strconv.Atoi(m[2])
+				switch m[1] {
+				case "cloudProviderTarget":
+					group.target = n
+				case "minSize":
+					group.minSize = n
+				case "maxSize":
+					group.maxSize = n
+				}
+			}
+			groups[name] = group
+		case "ScaleUp":
+			group := groups[name]
+			group.scaleUp, _, _ = strings.Cut(value, " ")
+			groups[name] = group
+		}
+	}
+	delete(groups, "")
+	return groups
+}
+
+// autoscalerPodEvent returns whether obj is a Kubernetes Event of the cluster autoscaler
+// about a pod, such as TriggeredScaleUp or NotTriggerScaleUp
+func autoscalerPodEvent(obj runtime.Object) bool {
+	switch ev := obj.(type) {
+	case *api_v1.Event:
+		return ev.Source.Component == autoscalerComponent && ev.InvolvedObject.Kind == "Pod"
+	case *events_v1.Event:
+		return ev.ReportingController == autoscalerComponent && ev.Regarding.Kind == "Pod"
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const legacyAutoscalerStatus = `Cluster-autoscaler status at 2024-05-01 12:00:00.123 +0000 UTC:
+Cluster-wide:
+  Health:      Healthy (ready=8 unready=0 notStarted=0 longNotStarted=0 registered=8 longUnregistered=0)
+  ScaleUp:     InProgress (ready=8 registered=8)
+  ScaleDown:   NoCandidates (candidates=0)
+
+NodeGroups:
+  Name:        eks-workers
+  Health:      Healthy (ready=5 unready=0 notStarted=0 longNotStarted=0 registered=5 longUnregistered=0 cloudProviderTarget=7 (minSize=1, maxSize=10))
+  ScaleUp:     InProgress (ready=5 cloudProviderTarget=7)
+  ScaleDown:   NoCandidates (candidates=0)
+
+  Name:        eks-gpu
+  Health:      Healthy (ready=3 unready=0 notStarted=0 longNotStarted=0 registered=3 longUnregistered=0 cloudProviderTarget=3 (minSize=0, maxSize=3))
+  ScaleUp:     Backoff (ready=3 cloudProviderTarget=3)
+  ScaleDown:   NoCandidates (candidates=0)
+`
+
+const autoscalerStatusYAML = `time: 2024-05-01 12:00:00.123 +0000 UTC
+autoscalerStatus: Running
+clusterWide:
+  health:
+    status: Healthy
+nodeGroups:
+- name: eks-workers
+  health:
+    status: Healthy
+    cloudProviderTarget: 7
+    minSize: 1
+    maxSize: 10
+  scaleUp:
+    status: InProgress
+`
+
+func TestParseAutoscalerStatus(t *testing.T) {
+	var Tests = []struct {
+		name     string
+		status   string
+		expected map[string]nodeGroupStatus
+	}{
+		{"legacy", legacyAutoscalerStatus, map[string]nodeGroupStatus{
+			"eks-workers": {target: 7, minSize: 1, maxSize: 10, scaleUp: "InProgress"},
+			"eks-gpu":     {target: 3, minSize: 0, maxSize: 3, scaleUp: "Backoff"},
+		}},
+		{"yaml", autoscalerStatusYAML, map[string]nodeGroupStatus{
+			"eks-workers": {target: 7, minSize: 1, maxSize: 10, scaleUp: "InProgress"},
+		}},
+		{"empty", "", map[string]nodeGroupStatus{}},
+	}
+
+	for _, tt := range Tests {
+		if groups := parseAutoscalerStatus(tt.status); !reflect.DeepEqual(groups, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, groups)
+		}
+	}
+}
+
+func TestClusterAutoscaler(t *testing.T) {
+	w := newClusterWatcher(&config.Config{}, &recorder{}, Cluster{}, nil, nil)
+	a := newClusterAutoscaler(w, "kube-system", "cluster-autoscaler-status")
+	status := func(s string) *api_v1.ConfigMap {
+		return &api_v1.ConfigMap{Data: map[string]string{"status": s}}
+	}
+	before := `nodeGroups:
+- name: eks-workers
+  health: {cloudProviderTarget: 5, minSize: 1, maxSize: 10}
+  scaleUp: {status: NoActivity}
+- name: eks-gpu
+  health: {cloudProviderTarget: 3, minSize: 0, maxSize: 3}
+  scaleUp: {status: NoActivity}
+`
+
+	if events := a.observe(status(before)); len(events) != 0 {
+		t.Errorf("expected the first status to be recorded only, got %+v", events)
+	}
+	events := a.observe(status(legacyAutoscalerStatus))
+	var reasons []string
+	for _, e := range events {
+		reasons = append(reasons, e.Status+": "+e.Reason)
+	}
+	expected := []string{
+		"Warning: Scale-up of node group eks-gpu is backing off after failing to add nodes (min 0, max 3)",
+		"Normal: Scaled node group eks-workers from 5 to 7 (min 1, max 10)",
+	}
+	if !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("expected %q, got %q", expected, reasons)
+	}
+	if events := a.observe(status(legacyAutoscalerStatus)); len(events) != 0 {
+		t.Errorf("expected no event for an unchanged status, got %+v", events)
+	}
+}
+
+func TestAutoscalerPodEvent(t *testing.T) {
+	var Tests = []struct {
+		obj      runtime.Object
+		expected bool
+	}{
+		{&api_v1.Event{Reason: "TriggeredScaleUp", Source: api_v1.EventSource{Component: "cluster-autoscaler"}, InvolvedObject: api_v1.ObjectReference{Kind: "Pod"}}, true},
+		{&api_v1.Event{Reason: "ScaleDown", Source: api_v1.EventSource{Component: "cluster-autoscaler"}, InvolvedObject: api_v1.ObjectReference{Kind: "Node"}}, false},
+		{&api_v1.Event{Reason: "FailedScheduling", Source: api_v1.EventSource{Component: "default-scheduler"}, InvolvedObject: api_v1.ObjectReference{Kind: "Pod"}}, false},
+		{&events_v1.Event{Reason: "NotTriggerScaleUp", ReportingController: "cluster-autoscaler", Regarding: api_v1.ObjectReference{Kind: "Pod"}}, true},
+		{&api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "cluster-autoscaler"}}, false},
+	}
+
+	for _, tt := range Tests {
+		if got := autoscalerPodEvent(tt.obj); got != tt.expected {
+			t.Errorf("%+v: expected %v, got %v", tt.obj, tt.expected, got)
+		}
+	}
+}
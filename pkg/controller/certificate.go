@@ -0,0 +1,210 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+)
+
+const CERT_MANAGER_GROUP = "cert-manager.io"
+const CERT_MANAGER_VERSION = "v1"
+const CERT_MANAGER_CERTIFICATES = "certificates"
+
+// certificateCheckInterval is how often the informer cache is scanned for expiring certificates
+const certificateCheckInterval = time.Hour
+
+// certificateCRD is the custom resource watched when cert-manager support is enabled
+var certificateCRD = config.CRD{
+	Group:    CERT_MANAGER_GROUP,
+	Version:  CERT_MANAGER_VERSION,
+	Resource: CERT_MANAGER_CERTIFICATES,
+}
+
+func isCertificateCRD(crd config.CRD) bool {
+	return crd.Group == CERT_MANAGER_GROUP && crd.Resource == CERT_MANAGER_CERTIFICATES
+}
+
+// withCertificateCRD returns crds with the cert-manager Certificate resource appended
+// unless it is already configured
+func withCertificateCRD(crds []config.CRD) []config.CRD {
+	for _, crd := range crds {
+		if isCertificateCRD(crd) {
+			return crds
+		}
+	}
+	return append(crds, certificateCRD)
+}
+
+// certificateNotReady reports whether the Certificate has a Ready condition set to False,
+// along with the condition message
+func certificateNotReady(obj *unstructured.Unstructured) (bool, string) {
+	conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil || !found {
+		return false, ""
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == "False" {
+			message, _ := condition["message"].(string)
+			return true, message
+		}
+		return false, ""
+	}
+	return false, ""
+}
+
+// certificateNotAfter returns the expiry time read from status.notAfter
+func certificateNotAfter(obj *unstructured.Unstructured) (time.Time, bool) {
+	notAfter, found, err := unstructured.NestedString(obj.Object, "status", "notAfter")
+	if err != nil || !found {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, notAfter)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// certificateExpiresWithin reports whether the Certificate expires before now+window
+func certificateExpiresWithin(obj *unstructured.Unstructured, window time.Duration, now time.Time) bool {
+	notAfter, ok := certificateNotAfter(obj)
+	if !ok {
+		return false
+	}
+	return notAfter.Before(now.Add(window))
+}
+
+// certificateWatcher scans a Certificate informer for certificates about to expire.
+// Each certificate is reported once per notAfter value, so a renewed certificate
+// will be reported again when it approaches its new expiry.
+type certificateWatcher struct {
+	controller *Controller
+	window     time.Duration
+
+	mu sync.Mutex
+	// queued holds the expiry of the certificates whose expiring event is in the queue
+	queued map[types.UID]time.Time
+	// notified holds the expiry of the certificates whose expiring event was delivered
+	notified map[types.UID]time.Time
+}
+
+func newCertificateWatcher(c *Controller, conf config.CertManager) *certificateWatcher {
+	window := conf.ExpiryWindow
+	if window <= 0 {
+		window = config.DefaultCertificateExpiryWindow
+	}
+	return &certificateWatcher{
+		controller: c,
+		window:     window,
+		queued:     map[types.UID]time.Time{},
+		notified:   map[types.UID]time.Time{},
+	}
+}
+
+// Run periodically checks the informer cache until stopCh is closed
+func (w *certificateWatcher) Run(stopCh <-chan struct{}) {
+	if !cache.WaitForCacheSync(stopCh, w.controller.HasSynced) {
+		return
+	}
+	wait.Until(w.check, certificateCheckInterval, stopCh)
+}
+
+func (w *certificateWatcher) check() {
+	for _, item := range w.controller.informer.GetStore().List() {
+		obj, ok := item.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		w.checkCertificate(obj, time.Now())
+	}
+}
+
+func (w *certificateWatcher) checkCertificate(obj *unstructured.Unstructured, now time.Time) {
+	if !certificateExpiresWithin(obj, w.window, now) {
+		return
+	}
+	notAfter, _ := certificateNotAfter(obj)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if last, ok := w.notified[obj.GetUID()]; ok && last.Equal(notAfter) {
+		return
+	}
+	if queued, ok := w.queued[obj.GetUID()]; ok && queued.Equal(notAfter) {
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	w.queued[obj.GetUID()] = notAfter
+	w.controller.queue.Add(Event{
+		id:           uuid.New().String(),
+		key:          key,
+		eventType:    "expiring",
+		resourceType: w.controller.resourceType,
+		apiVersion:   w.controller.apiVersion,
+		obj:          obj,
+	})
+}
+
+// delivered records that the expiry of the certificate obj was notified
+func (w *certificateWatcher) delivered(obj *unstructured.Unstructured) {
+	notAfter, _ := certificateNotAfter(obj)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.queued, obj.GetUID())
+	w.notified[obj.GetUID()] = notAfter
+}
+
+// dropped forgets the expiring event of the certificate obj given up after its retries,
+// so that the next check queues it again
+func (w *certificateWatcher) dropped(obj runtime.Object) {
+	cert, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.queued, cert.GetUID())
+}
+
+// forget removes the deleted certificate obj
+func (w *certificateWatcher) forget(obj runtime.Object) {
+	cert, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.queued, cert.GetUID())
+	delete(w.notified, cert.GetUID())
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newCertificate(uid string, ready string, notAfter string) *unstructured.Unstructured {
+	status := map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"type":    "Ready",
+				"status":  ready,
+				"message": "Issuing certificate as Secret does not exist",
+			},
+		},
+	}
+	if notAfter != "" {
+		status["notAfter"] = notAfter
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Certificate",
+		"metadata": map[string]interface{}{
+			"name":      "example-tls",
+			"namespace": "default",
+			"uid":       uid,
+		},
+		"status": status,
+	}}
+}
+
+func TestCertificateNotReady(t *testing.T) {
+	notReady, message := certificateNotReady(newCertificate("1", "False", ""))
+	if !notReady || message != "Issuing certificate as Secret does not exist" {
+		t.Errorf("expected NotReady with message, got %v %q", notReady, message)
+	}
+	if notReady, _ := certificateNotReady(newCertificate("1", "True", "")); notReady {
+		t.Errorf("expected Ready certificate")
+	}
+	if notReady, _ := certificateNotReady(&unstructured.Unstructured{Object: map[string]interface{}{}}); notReady {
+		t.Errorf("expected certificate without status to be ignored")
+	}
+}
+
+func TestCertificateExpiresWithin(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var Tests = []struct {
+		notAfter string
+		expected bool
+	}{
+		{"2024-01-10T00:00:00Z", true},
+		{"2024-03-01T00:00:00Z", false},
+		{"2023-12-01T00:00:00Z", true},
+		{"not-a-date", false},
+		{"", false},
+	}
+
+	for _, tt := range Tests {
+		cert := newCertificate("1", "True", tt.notAfter)
+		if got := certificateExpiresWithin(cert, 30*24*time.Hour, now); got != tt.expected {
+			t.Errorf("certificateExpiresWithin(%q): expected %v, got %v", tt.notAfter, tt.expected, got)
+		}
+	}
+}
+
+func TestCertificateWatcherNotifiesOnce(t *testing.T) {
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     cache.NewSharedIndexInformer(nil, &unstructured.Unstructured{}, 0, cache.Indexers{}),
+		resourceType: CERT_MANAGER_CERTIFICATES,
+		apiVersion:   "cert-manager.io/v1",
+	}
+	w := newCertificateWatcher(c, config.CertManager{Enabled: true})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cert := newCertificate("1", "True", "2024-01-10T00:00:00Z")
+	w.checkCertificate(cert, now)
+	w.checkCertificate(cert, now)
+	if c.queue.Len() != 1 {
+		t.Fatalf("expected 1 queued expiry event, got %d", c.queue.Len())
+	}
+
+	// an expiry given up after its retries is queued again
+	w.dropped(cert)
+	w.checkCertificate(cert, now)
+	if c.queue.Len() != 2 {
+		t.Fatalf("expected the dropped expiry event to be queued again, got %d", c.queue.Len())
+	}
+	w.delivered(cert)
+	w.checkCertificate(cert, now)
+	if c.queue.Len() != 2 {
+		t.Fatalf("expected the delivered expiry event not to be queued again, got %d", c.queue.Len())
+	}
+
+	// a renewed certificate is reported again once it nears its new expiry
+	w.checkCertificate(newCertificate("1", "True", "2024-01-20T00:00:00Z"), now)
+	if c.queue.Len() != 3 {
+		t.Fatalf("expected 3 queued expiry events, got %d", c.queue.Len())
+	}
+
+	// deleted certificates are forgotten
+	w.forget(cert)
+	if len(w.queued) != 0 || len(w.notified) != 0 {
+		t.Errorf("expected the deleted certificate to be forgotten, got %v queued and %v notified", w.queued, w.notified)
+	}
+}
+
+func TestCertificateNotReadyRetried(t *testing.T) {
+	old := newCertificate("1", "True", "")
+	cert := newCertificate("1", "False", "")
+	informer := cache.NewSharedIndexInformer(nil, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	if err := informer.GetIndexer().Add(cert); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &flakyRecorder{failures: 1, kind: "CertificateNotReady"}
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     informer,
+		eventHandler: r,
+		resourceType: CERT_MANAGER_CERTIFICATES,
+	}
+	c.certificates = newCertificateWatcher(c, config.CertManager{Enabled: true})
+	update := Event{id: uuid.New().String(), key: "default/example-tls", eventType: "update", resourceType: CERT_MANAGER_CERTIFICATES, obj: cert, oldObj: old}
+
+	if err := c.processItem(update); err == nil {
+		t.Fatal("expected the NotReady delivery to fail")
+	}
+	if err := c.processItem(update); err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	for _, e := range r.events {
+		kinds = append(kinds, e.Kind)
+	}
+	if len(kinds) != 2 || kinds[0] != CERT_MANAGER_CERTIFICATES || kinds[1] != "CertificateNotReady" {
+		t.Errorf("expected the update once and the retried NotReady event, got %v", kinds)
+	}
+}
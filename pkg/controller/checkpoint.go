@@ -0,0 +1,472 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// checkpointSaveInterval is how often modified checkpoints are persisted
+const checkpointSaveInterval = 10 * time.Second
+
+// checkpointConfigMapKey is the ConfigMap data key holding the checkpoints
+const checkpointConfigMapKey = "checkpoints.json"
+
+// checkpointState tells how an object listed at startup relates to the checkpoints
+type checkpointState int
+
+const (
+	// checkpointUnknown means no checkpoint exists for the object's scope
+	checkpointUnknown checkpointState = iota
+	// checkpointNotified means the object was already notified in its current version
+	checkpointNotified
+	// checkpointChanged means the object was updated since it was last notified
+	checkpointChanged
+	// checkpointMissed means the object was created while kubewatch was not running
+	checkpointMissed
+)
+
+// checkpointStore persists checkpoints, mapping a scope to the resourceVersion of every object by UID
+type checkpointStore interface {
+	Load() (map[string]map[string]string, error)
+	Save(map[string]map[string]string) error
+}
+
+// checkpoints records the last notified resourceVersion of every watched object, so that
+// objects already notified are not announced again when kubewatch restarts.
+// Scopes identify the objects of one controller (cluster, resource type and namespace).
+type checkpoints struct {
+	store checkpointStore
+
+	mu       sync.Mutex
+	versions map[string]map[string]string
+	// loaded holds the scopes restored from the store
+	loaded map[string]bool
+	dirty  bool
+}
+
+// newCheckpoints returns the checkpoints configured in conf, nil when disabled
+func newCheckpoints(conf config.Checkpoint, kubeClient kubernetes.Interface) (*checkpoints, error) {
+	var store checkpointStore
+	switch {
+	case conf.File != "":
+		store = &fileCheckpointStore{path: conf.File}
+	case conf.ConfigMap != "":
+		parts := strings.SplitN(conf.ConfigMap, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid checkpoint configmap %q, expected namespace/name", conf.ConfigMap)
+		}
+		store = &configMapCheckpointStore{client: kubeClient, namespace: parts[0], name: parts[1]}
+	default:
+		return nil, nil
+	}
+
+	versions, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading checkpoints: %v", err)
+	}
+	if versions == nil {
+		versions = map[string]map[string]string{}
+	}
+	loaded := map[string]bool{}
+	for scope := range versions {
+		loaded[scope] = true
+	}
+	return &checkpoints{store: store, versions: versions, loaded: loaded}, nil
+}
+
+func checkpointScope(clusterName, resourceType, namespace string) string {
+	return clusterName + "/" + resourceType + "/" + namespace
+}
+
+// objectVersion returns the UID and resourceVersion of obj, unwrapping delete tombstones
+func objectVersion(obj interface{}) (string, string, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil || accessor.GetUID() == "" {
+		return "", "", false
+	}
+	return string(accessor.GetUID()), accessor.GetResourceVersion(), true
+}
+
+// state compares an object listed at startup with its checkpoint
+func (c *checkpoints) state(scope string, obj interface{}) checkpointState {
+	if c == nil {
+		return checkpointUnknown
+	}
+	uid, version, ok := objectVersion(obj)
+	if !ok {
+		return checkpointUnknown
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded[scope] {
+		return checkpointUnknown
+	}
+	notified, ok := c.versions[scope][uid]
+	switch {
+	case !ok:
+		return checkpointMissed
+	case notified == version:
+		return checkpointNotified
+	default:
+		return checkpointChanged
+	}
+}
+
+// record marks the current version of obj as notified
+func (c *checkpoints) record(scope string, obj interface{}) {
+	if c == nil {
+		return
+	}
+	uid, version, ok := objectVersion(obj)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions[scope] == nil {
+		c.versions[scope] = map[string]string{}
+	}
+	if c.versions[scope][uid] != version {
+		c.versions[scope][uid] = version
+		c.dirty = true
+	}
+}
+
+// forget removes the checkpoint of a deleted object
+func (c *checkpoints) forget(scope string, obj interface{}) {
+	if c == nil {
+		return
+	}
+	uid, _, ok := objectVersion(obj)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.versions[scope][uid]; ok {
+		delete(c.versions[scope], uid)
+		c.dirty = true
+	}
+}
+
+// prune removes the checkpoints of objects deleted while kubewatch was not running,
+// objs being the objects currently existing in scope
+func (c *checkpoints) prune(scope string, objs []interface{}) {
+	if c == nil {
+		return
+	}
+	existing := map[string]bool{}
+	for _, obj := range objs {
+		if uid, _, ok := objectVersion(obj); ok {
+			existing[uid] = true
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uid := range c.versions[scope] {
+		if !existing[uid] {
+			delete(c.versions[scope], uid)
+			c.dirty = true
+		}
+	}
+}
+
+// holds returns whether obj, of kind resourceType, is one of the ConfigMaps the checkpoints
+// are stored in. Their changes are not notified, since each save would be notified again.
+func (c *checkpoints) holds(resourceType string, obj interface{}) bool {
+	if c == nil || resourceType != objName(api_v1.ConfigMap{}) {
+		return false
+	}
+	store, ok := c.store.(*configMapCheckpointStore)
+	if !ok {
+		return false
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return store.holds(accessor.GetNamespace(), accessor.GetName())
+}
+
+// Run periodically persists the checkpoints until stopCh is closed
+func (c *checkpoints) Run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := c.save(); err != nil {
+			logrus.Errorf("Error saving checkpoints: %v", err)
+		}
+	}, checkpointSaveInterval, stopCh)
+}
+
+// save persists the checkpoints if they changed since the last save
+func (c *checkpoints) save() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	versions := make(map[string]map[string]string, len(c.versions))
+	for scope, objects := range c.versions {
+		versions[scope] = make(map[string]string, len(objects))
+		for uid, version := range objects {
+			versions[scope][uid] = version
+		}
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	if err := c.store.Save(versions); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// fileCheckpointStore keeps checkpoints in a JSON file
+type fileCheckpointStore struct {
+	path string
+}
+
+func (s *fileCheckpointStore) Load() (map[string]map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions map[string]map[string]string
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (s *fileCheckpointStore) Save(versions map[string]map[string]string) error {
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	// write then rename, so a crash never leaves a truncated file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// configMapCheckpointStore keeps checkpoints in ConfigMaps: name, then name-1, name-2...
+// once the checkpoints outgrow maxCheckpointShardBytes, so that each stays under the size
+// limit of Kubernetes objects
+type configMapCheckpointStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+
+	// shards is the number of ConfigMaps last loaded or saved
+	shards int
+}
+
+// maxCheckpointShardBytes bounds the size of the checkpoints of one ConfigMap, below the
+// 1MiB limit of Kubernetes objects
+var maxCheckpointShardBytes = 900 * 1024
+
+// shardName returns the name of the ConfigMap holding shard i of the checkpoints
+func (s *configMapCheckpointStore) shardName(i int) string {
+	if i == 0 {
+		return s.name
+	}
+	return fmt.Sprintf("%s-%d", s.name, i)
+}
+
+// holds returns whether the ConfigMap namespace/name holds checkpoints
+func (s *configMapCheckpointStore) holds(namespace, name string) bool {
+	if namespace != s.namespace {
+		return false
+	}
+	if name == s.name {
+		return true
+	}
+	shard, ok := strings.CutPrefix(name, s.name+"-")
+	if !ok || shard == "" {
+		return false
+	}
+	_, err := strconv.Atoi(shard)
+	return err == nil
+}
+
+func (s *configMapCheckpointStore) Load() (map[string]map[string]string, error) {
+	var versions map[string]map[string]string
+	s.shards = 0
+	for {
+		cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(context.Background(), s.shardName(s.shards), meta_v1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.shards++
+		data, ok := cm.Data[checkpointConfigMapKey]
+		if !ok {
+			continue
+		}
+		var shard map[string]map[string]string
+		if err := json.Unmarshal([]byte(data), &shard); err != nil {
+			return nil, fmt.Errorf("%s: %v", cm.Name, err)
+		}
+		if versions == nil {
+			versions = map[string]map[string]string{}
+		}
+		for scope, objects := range shard {
+			if versions[scope] == nil {
+				versions[scope] = map[string]string{}
+			}
+			for uid, version := range objects {
+				versions[scope][uid] = version
+			}
+		}
+	}
+}
+
+func (s *configMapCheckpointStore) Save(versions map[string]map[string]string) error {
+	shards := shardCheckpoints(versions, maxCheckpointShardBytes)
+	for i, shard := range shards {
+		data, err := json.Marshal(shard)
+		if err != nil {
+			return err
+		}
+		if err := s.saveShard(s.shardName(i), string(data)); err != nil {
+			return err
+		}
+	}
+
+	// remove the shards left by larger checkpoints
+	for i := len(shards); i < s.shards; i++ {
+		err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(context.Background(), s.shardName(i), meta_v1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	s.shards = len(shards)
+	return nil
+}
+
+// saveShard writes data in the ConfigMap name, creating it when missing
+func (s *configMapCheckpointStore) saveShard(name string, data string) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(context.Background(), name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(context.Background(), &api_v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: s.namespace},
+			Data:       map[string]string{checkpointConfigMapKey: data},
+		}, meta_v1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data[checkpointConfigMapKey] == data {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[checkpointConfigMapKey] = data
+	_, err = configMaps.Update(context.Background(), cm, meta_v1.UpdateOptions{})
+	return err
+}
+
+// shardCheckpoints splits versions into shards whose JSON encoding is about maxBytes at
+// most, always returning at least one shard
+func shardCheckpoints(versions map[string]map[string]string, maxBytes int) []map[string]map[string]string {
+	scopes := make([]string, 0, len(versions))
+	for scope := range versions {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	shards := []map[string]map[string]string{{}}
+	size := 2
+	for _, scope := range scopes {
+		uids := make([]string, 0, len(versions[scope]))
+		for uid := range versions[scope] {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+
+		for _, uid := range uids {
+			// "uid":"version", and "scope":{} for the first object of the scope in the shard
+			object := len(uid) + len(versions[scope][uid]) + 6
+			shard := shards[len(shards)-1]
+			entry := object
+			if shard[scope] == nil {
+				entry += len(scope) + 6
+			}
+			if size+entry > maxBytes && len(shard) > 0 {
+				shard = map[string]map[string]string{}
+				shards = append(shards, shard)
+				size, entry = 2, object+len(scope)+6
+			}
+			if shard[scope] == nil {
+				shard[scope] = map[string]string{}
+			}
+			shard[scope][uid] = versions[scope][uid]
+			size += entry
+		}
+	}
+	return shards
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newVersionedPod(name string, uid string, version string) *api_v1.Pod {
+	pod := newPod(name, time.Now().Add(-time.Hour))
+	pod.UID = types.UID(uid)
+	pod.ResourceVersion = version
+	return pod
+}
+
+func TestCheckpointStores(t *testing.T) {
+	versions := map[string]map[string]string{"/Pod/": {"uid-a": "1"}}
+
+	stores := map[string]checkpointStore{
+		"file":      &fileCheckpointStore{path: filepath.Join(t.TempDir(), "checkpoints.json")},
+		"configmap": &configMapCheckpointStore{client: fake.NewSimpleClientset(), namespace: "kubewatch", name: "checkpoints"},
+	}
+	for name, store := range stores {
+		loaded, err := store.Load()
+		if err != nil || loaded != nil {
+			t.Fatalf("%s: expected no checkpoints before the first save, got %v, %v", name, loaded, err)
+		}
+		// saving twice also covers updating an existing checkpoint
+		for i := 0; i < 2; i++ {
+			if err := store.Save(versions); err != nil {
+				t.Fatalf("%s: Save(): %v", name, err)
+			}
+		}
+		if loaded, err = store.Load(); err != nil || !reflect.DeepEqual(loaded, versions) {
+			t.Fatalf("%s: expected %v, got %v, %v", name, versions, loaded, err)
+		}
+	}
+}
+
+func TestNewCheckpoints(t *testing.T) {
+	if cp, err := newCheckpoints(config.Checkpoint{}, nil); cp != nil || err != nil {
+		t.Fatalf("expected checkpoints to be disabled, got %v, %v", cp, err)
+	}
+	if _, err := newCheckpoints(config.Checkpoint{ConfigMap: "checkpoints"}, fake.NewSimpleClientset()); err == nil {
+		t.Fatalf("expected an error for a configmap without namespace")
+	}
+}
+
+func TestCheckpointState(t *testing.T) {
+	cp := &checkpoints{
+		versions: map[string]map[string]string{"/Pod/default": {"uid-a": "1", "uid-b": "1"}},
+		loaded:   map[string]bool{"/Pod/default": true},
+	}
+
+	var Tests = []struct {
+		scope    string
+		pod      *api_v1.Pod
+		expected checkpointState
+	}{
+		{"/Pod/default", newVersionedPod("a", "uid-a", "1"), checkpointNotified},
+		{"/Pod/default", newVersionedPod("b", "uid-b", "2"), checkpointChanged},
+		{"/Pod/default", newVersionedPod("c", "uid-c", "1"), checkpointMissed},
+		{"/Pod/other", newVersionedPod("a", "uid-a", "1"), checkpointUnknown},
+	}
+
+	for _, tt := range Tests {
+		if got := cp.state(tt.scope, tt.pod); got != tt.expected {
+			t.Errorf("state(%s, %s): expected %v, got %v", tt.scope, tt.pod.Name, tt.expected, got)
+		}
+	}
+
+	var disabled *checkpoints
+	if got := disabled.state("/Pod/default", newVersionedPod("a", "uid-a", "1")); got != checkpointUnknown {
+		t.Errorf("expected disabled checkpoints to be unknown, got %v", got)
+	}
+}
+
+func TestCheckpointRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	if err := (&fileCheckpointStore{path: path}).Save(map[string]map[string]string{
+		"/Pod/": {"uid-notified": "1", "uid-changed": "1", "uid-deleted": "1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := newCheckpoints(config.Checkpoint{File: path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := fake.NewSimpleClientset()
+	for _, pod := range []*api_v1.Pod{
+		newVersionedPod("notified", "uid-notified", "1"),
+		newVersionedPod("changed", "uid-changed", "2"),
+		newVersionedPod("missed", "uid-missed", "1"),
+	} {
+		if _, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, meta_v1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &recorder{}
+	stopCh := make(chan struct{})
+	w := startCluster(&config.Config{Resource: config.Resource{Pod: true}}, r, Cluster{KubeClient: client}, cp, stopCh)
+
+	if err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return len(r.names()) >= 2, nil
+	}); err != nil {
+		t.Fatalf("expected 2 events, got %v", r.names())
+	}
+	close(stopCh)
+	w.wait(5 * time.Second)
+
+	names := r.names()
+	sort.Strings(names)
+	if expected := []string{"Created missed", "Updated changed"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected events %v, got %v", expected, names)
+	}
+
+	if err := cp.save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := (&fileCheckpointStore{path: path}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"/Pod/": {"uid-notified": "1", "uid-changed": "2", "uid-missed": "1"},
+	}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Fatalf("expected checkpoints %v, got %v", expected, saved)
+	}
+}
+
+func TestConfigMapCheckpointShards(t *testing.T) {
+	defer func(max int) { maxCheckpointShardBytes = max }(maxCheckpointShardBytes)
+	maxCheckpointShardBytes = 100
+
+	client := fake.NewSimpleClientset()
+	store := &configMapCheckpointStore{client: client, namespace: "kubewatch", name: "checkpoints"}
+	versions := map[string]map[string]string{"/Pod/": {}, "/Deployment/": {"uid-deploy": "1"}}
+	for i := 0; i < 10; i++ {
+		versions["/Pod/"][fmt.Sprintf("uid-pod-%d", i)] = "1"
+	}
+	if err := store.Save(versions); err != nil {
+		t.Fatal(err)
+	}
+
+	shards, err := client.CoreV1().ConfigMaps("kubewatch").List(context.Background(), meta_v1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shards.Items) < 2 {
+		t.Fatalf("expected the checkpoints to be sharded, got %d ConfigMaps", len(shards.Items))
+	}
+	for _, cm := range shards.Items {
+		if size := len(cm.Data[checkpointConfigMapKey]); size > maxCheckpointShardBytes {
+			t.Errorf("%s: %d bytes, expected at most %d", cm.Name, size, maxCheckpointShardBytes)
+		}
+		if !store.holds(cm.Namespace, cm.Name) {
+			t.Errorf("%s: expected to be a checkpoint shard", cm.Name)
+		}
+	}
+	loaded, err := (&configMapCheckpointStore{client: client, namespace: "kubewatch", name: "checkpoints"}).Load()
+	if err != nil || !reflect.DeepEqual(loaded, versions) {
+		t.Fatalf("expected %v, got %v, %v", versions, loaded, err)
+	}
+
+	// shards no longer needed are removed
+	small := map[string]map[string]string{"/Deployment/": {"uid-deploy": "2"}}
+	if err := store.Save(small); err != nil {
+		t.Fatal(err)
+	}
+	if shards, _ = client.CoreV1().ConfigMaps("kubewatch").List(context.Background(), meta_v1.ListOptions{}); len(shards.Items) != 1 {
+		t.Errorf("expected 1 ConfigMap once the checkpoints shrink, got %d", len(shards.Items))
+	}
+	if loaded, err = store.Load(); err != nil || !reflect.DeepEqual(loaded, small) {
+		t.Fatalf("expected %v, got %v, %v", small, loaded, err)
+	}
+}
+
+func TestCheckpointsHold(t *testing.T) {
+	cp := &checkpoints{store: &configMapCheckpointStore{namespace: "kubewatch", name: "checkpoints"}}
+	newConfigMap := func(namespace, name string) *api_v1.ConfigMap {
+		return &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Namespace: namespace, Name: name}}
+	}
+
+	var Tests = []struct {
+		resourceType string
+		obj          interface{}
+		expected     bool
+	}{
+		{"ConfigMap", newConfigMap("kubewatch", "checkpoints"), true},
+		{"ConfigMap", newConfigMap("kubewatch", "checkpoints-2"), true},
+		{"ConfigMap", cache.DeletedFinalStateUnknown{Obj: newConfigMap("kubewatch", "checkpoints-1")}, true},
+		{"ConfigMap", newConfigMap("kubewatch", "checkpoints-config"), false},
+		{"ConfigMap", newConfigMap("default", "checkpoints"), false},
+		{"Secret", newConfigMap("kubewatch", "checkpoints"), false},
+	}
+	for _, tt := range Tests {
+		if got := cp.holds(tt.resourceType, tt.obj); got != tt.expected {
+			t.Errorf("holds(%s, %v) = %v, expected %v", tt.resourceType, tt.obj, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckpointConfigMapNotNotified(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cp, err := newCheckpoints(config.Checkpoint{ConfigMap: "default/checkpoints"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.record("/Pod/", newVersionedPod("web", "uid-web", "1"))
+	if err := cp.save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().ConfigMaps("default").Create(context.Background(), &api_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "app", Namespace: "default"},
+	}, meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the checkpoints are listed with the other ConfigMaps, but only app is notified
+	r := &recorder{}
+	stopCh := make(chan struct{})
+	conf := &config.Config{Resource: config.Resource{ConfigMap: true}, StartupInventory: true}
+	w := startCluster(conf, r, Cluster{KubeClient: client}, cp, stopCh)
+	defer func() {
+		close(stopCh)
+		w.wait(5 * time.Second)
+	}()
+	r.waitFor(t, "Created app")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// conditionStatuses are the statuses of the events raised by the condition rules of
+// custom resources, per severity
+var conditionStatuses = map[string]string{
+	string(event.SeverityInfo):            "Normal",
+	string(event.SeverityWarning):         "Warning",
+	string(event.SeverityPolicyViolation): "Warning",
+	string(event.SeverityCritical):        "Danger",
+}
+
+// conditionEvent returns the event about a custom resource update entering or leaving a
+// condition of the rules, nil when none changed. The first rule changing is reported.
+func (c *Controller) conditionEvent(e Event) *event.Event {
+	obj, ok := e.obj.(*unstructured.Unstructured)
+	if !ok || e.eventType != "update" {
+		return nil
+	}
+	for _, rule := range c.conditionRules {
+		condition, _ := findCondition(e.obj, rule.Type)
+		oldCondition, _ := findCondition(e.oldObj, rule.Type)
+		matches, matched := matchesRule(condition, rule), matchesRule(oldCondition, rule)
+		if matches == matched {
+			continue
+		}
+
+		kbEvent := &event.Event{
+			Name:       e.key,
+			Namespace:  e.namespace,
+			Kind:       obj.GetKind(),
+			APIVersion: e.apiVersion,
+			Obj:        e.obj,
+			OldObj:     e.oldObj,
+		}
+		if matches {
+			kbEvent.Status = conditionStatuses[rule.Severity]
+			kbEvent.Severity = event.Severity(rule.Severity)
+			kbEvent.Reason = fmt.Sprintf("%s is %s", rule.Type, condition.Status)
+			if text := conditionText(condition); text != "" {
+				kbEvent.Reason += ": " + text
+			}
+			return kbEvent
+		}
+		kbEvent.Status = "Normal"
+		if condition.Status == "" {
+			kbEvent.Reason = fmt.Sprintf("%s condition removed, recovered from %s", rule.Type, rule.Status)
+		} else {
+			kbEvent.Reason = fmt.Sprintf("%s is %s, recovered from %s", rule.Type, condition.Status, rule.Status)
+		}
+		return kbEvent
+	}
+	return nil
+}
+
+// matchesRule returns whether condition has the type and status of rule
+func matchesRule(condition event.Condition, rule config.ConditionRule) bool {
+	return condition.Type == rule.Type && condition.Status == rule.Status
+}
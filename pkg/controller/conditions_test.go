@@ -0,0 +1,77 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newConditionObject(conditions ...map[string]interface{}) *unstructured.Unstructured {
+	items := make([]interface{}, 0, len(conditions))
+	for _, condition := range conditions {
+		items = append(items, condition)
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"conditions": items},
+	}}
+	obj.SetKind("Certificate")
+	obj.SetName("api-tls")
+	obj.SetNamespace("apps")
+	return obj
+}
+
+func TestConditionEvent(t *testing.T) {
+	rules := []config.ConditionRule{
+		{Type: "Ready", Status: "False", Severity: "critical"},
+		{Type: "Issuing", Status: "True", Severity: "info"},
+	}
+	ready := map[string]interface{}{"type": "Ready", "status": "True"}
+	notReady := map[string]interface{}{"type": "Ready", "status": "False", "reason": "DoesNotExist", "message": "Issuer letsencrypt not found"}
+	issuing := map[string]interface{}{"type": "Issuing", "status": "True"}
+
+	var Tests = []struct {
+		oldObj, obj *unstructured.Unstructured
+		status      string
+		severity    event.Severity
+		reason      string
+	}{
+		{newConditionObject(ready), newConditionObject(ready), "", "", ""},
+		{newConditionObject(ready), newConditionObject(notReady), "Danger", event.SeverityCritical, "Ready is False: DoesNotExist: Issuer letsencrypt not found"},
+		{newConditionObject(notReady), newConditionObject(notReady, issuing), "Normal", event.SeverityInfo, "Issuing is True"},
+		{newConditionObject(notReady), newConditionObject(ready), "Normal", "", "Ready is True, recovered from False"},
+		{newConditionObject(notReady), newConditionObject(), "Normal", "", "Ready condition removed, recovered from False"},
+		{newConditionObject(), newConditionObject(ready), "", "", ""},
+	}
+
+	c := &Controller{conditionRules: rules}
+	for i, tt := range Tests {
+		e := c.conditionEvent(Event{key: "apps/api-tls", eventType: "update", namespace: "apps", obj: tt.obj, oldObj: tt.oldObj})
+		if tt.reason == "" {
+			if e != nil {
+				t.Errorf("%d: expected no event, got %+v", i, e)
+			}
+			continue
+		}
+		if e == nil || e.Kind != "Certificate" || e.Status != tt.status || e.Severity != tt.severity || e.Reason != tt.reason {
+			t.Errorf("%d: expected a %s event %q, got %+v", i, tt.status, tt.reason, e)
+		}
+	}
+}
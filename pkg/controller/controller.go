@@ -25,22 +25,25 @@ import (
 	"os/signal"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/stats"
 	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 
-	apps_v1 "k8s.io/api/apps/v1"
-	autoscaling_v1 "k8s.io/api/autoscaling/v1"
-	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
 	events_v1 "k8s.io/api/events/v1"
-	networking_v1 "k8s.io/api/networking/v1"
-	rbac_v1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -48,15 +51,11 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
-
-	"github.com/prometheus/client_golang/prometheus"
-    "github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-const maxRetries = 5
 const V1 = "v1"
 const AUTOSCALING_V1 = "autoscaling/v1"
 const APPS_V1 = "apps/v1"
@@ -65,10 +64,10 @@ const RBAC_V1 = "rbac.authorization.k8s.io/v1"
 const NETWORKING_V1 = "networking.k8s.io/v1"
 const EVENTS_V1 = "events.k8s.io/v1"
 
-var serverStartTime time.Time
-
 // Event indicate the informerEvent
 type Event struct {
+	// id identifies the event in the queue, it is kept when the event is retried
+	id           string
 	key          string
 	eventType    string
 	namespace    string
@@ -76,598 +75,795 @@ type Event struct {
 	apiVersion   string
 	obj          runtime.Object
 	oldObj       runtime.Object
+	// missed is set on objects created while kubewatch was not running
+	missed bool
+	// finalStateUnknown is set on deletions missed while the watch was interrupted,
+	// obj is then the last known state of the object
+	finalStateUnknown bool
 }
 
 // Controller object
 type Controller struct {
 	logger       *logrus.Entry
 	clientset    kubernetes.Interface
-	queue        workqueue.RateLimitingInterface
+	queue        workqueue.TypedRateLimitingInterface[Event]
 	informer     cache.SharedIndexInformer
 	eventHandler handlers.Handler
+	clusterName  string
+	resourceType string
+	apiVersion   string
+	// namespace is the namespace watched by the controller, empty for all namespaces
+	namespace    string
+	certificates *certificateWatcher
+	// cluster holds the metadata attached to events
+	cluster Cluster
+
+	// startupInventory reports objects already existing when the controller starts
+	startupInventory bool
+	startTime        time.Time
+
+	// maxRetries is the number of times a failed event is retried
+	maxRetries int
+	// deliveries is the context of deliveries, each taking at most deliveryTimeout unless 0
+	deliveries      context.Context
+	deliveryTimeout time.Duration
+	// workers is the number of goroutines handling queued events
+	workers int
+	// limiter smooths bursts of events, nil for no limit
+	limiter *rate.Limiter
+
+	// rollouts folds ReplicaSet and Deployment events into rollout events
+	rollouts bool
+
+	// imagePolicy flags workloads whose images break the image policy, nil when disabled
+	imagePolicy *imagePolicy
+	// podSecurity flags workloads of restricted namespaces breaking the pod security checks,
+	// nil when disabled
+	podSecurity *podSecurity
+	// ownership attaches the ownership read from the annotations of namespaces to events,
+	// nil when disabled
+	ownership *namespaceOwnership
+
+	// gitops reports the health changes of Argo, Flux and KEDA resources, nil for other
+	// resources
+	gitops *gitOpsResource
+	// gitOpsHealthOnly only reports health changes, for GitOps resources that are not
+	// configured as custom resources
+	gitOpsHealthOnly bool
+	// karpenter is the Karpenter resource whose changes are summarized, empty for other
+	// resources
+	karpenter string
+	// karpenterOnly only reports the summaries, for Karpenter resources that are not
+	// configured as custom resources
+	karpenterOnly bool
+	// dropKarpenterPodEvents drops the Kubernetes Events of Karpenter about pods
+	dropKarpenterPodEvents bool
+	// vpaDrift reports VerticalPodAutoscaler recommendations drifting from the requests,
+	// nil for other resources
+	vpaDrift *vpaDrift
+	// vpaDriftOnly only reports drifts, for VerticalPodAutoscalers that are not configured
+	// as custom resources
+	vpaDriftOnly bool
+	// conditionRules map the status conditions of custom resources to alerts, reported
+	// instead of their updates, empty for other resources
+	conditionRules []config.ConditionRule
+	// fields are extracted from custom resources into their events, empty for other
+	// resources
+	fields []fieldPath
+
+	// nodeDrains reports node cordons, drains and deletions, nil when disabled
+	nodeDrains *nodeDrains
+	// interruptions warns about nodes about to be interrupted, nil when disabled
+	interruptions *nodeInterruptions
+	// digestEvictions drops the eviction Kubernetes Events, summarized in node pressure digests
+	digestEvictions bool
+	// dropAutoscalerPodEvents drops the Kubernetes Events of the cluster autoscaler about
+	// pods, its activity being reported from its status instead
+	dropAutoscalerPodEvents bool
+	// slowStarts reports the pods slow to start, nil when disabled
+	slowStarts *slowStarts
+	// disruptions reports pod evictions and preemptions, nil when disabled
+	disruptions *disruptions
+
+	// objects holds the informer caches of the cluster, nil unless involved objects
+	// or descriptions are enabled
+	objects *objectCache
+	// involved resolves the objects Kubernetes Events are about
+	involved bool
+	// describe attaches a description of failing objects to their events
+	describe bool
+
+	// containerLogs configures the logs attached to pod failures, nil when disabled
+	containerLogs *config.ContainerLogs
+
+	// redactor hides the sensitive values of objects, nil when redaction is disabled
+	redactor *redactor
+
+	// checkpoints records notified objects under checkpointScope, nil when disabled
+	checkpoints     *checkpoints
+	checkpointScope string
+
+	// latencySLO is the maximum delivery latency, 0 when there is no objective
+	latencySLO time.Duration
+	// notifySLO sends a warning event when deliveries exceed latencySLO
+	notifySLO bool
+	// sloWarned holds when the last SLO warning was sent, in Unix nanoseconds
+	sloWarned atomic.Int64
+
+	// sent records the notifications of queued events already delivered, so that retries
+	// only send the notifications that failed
+	sent sentNotifications
+}
+
+// tag attaches the metadata of the cluster to e
+func (cl Cluster) tag(e *event.Event) {
+	e.Environment = cl.Environment
+	e.Region = cl.Region
+	e.ClusterLabels = cl.Labels
+}
+
+// populate sets the ID, API version, severity, timestamp, UID, labels and correlation ID
+// of e when not set yet
+func populate(e *event.Event) {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	e.APIVersion = e.ResolvedAPIVersion()
+	e.ApiVersion = e.APIVersion
+	if e.Severity == "" {
+		e.Severity = event.SeverityFromStatus(e.Status)
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	if e.Obj != nil && e.UID == "" {
+		if accessor, err := meta.Accessor(e.Obj); err == nil {
+			e.UID = accessor.GetUID()
+			if e.Labels == nil {
+				e.Labels = accessor.GetLabels()
+			}
+		}
+	}
+	if e.CorrelationID == "" {
+		e.CorrelationID = correlationID(e)
+	}
+}
+
+// correlationID links the events of a same object: the UID of the object, or of the
+// object a Kubernetes Event is about
+func correlationID(e *event.Event) string {
+	switch obj := e.Obj.(type) {
+	case *api_v1.Event:
+		if obj.InvolvedObject.UID != "" {
+			return string(obj.InvolvedObject.UID)
+		}
+	case *events_v1.Event:
+		if obj.Regarding.UID != "" {
+			return string(obj.Regarding.UID)
+		}
+	}
+	return string(e.UID)
 }
 
 func objName(obj interface{}) string {
 	return reflect.TypeOf(obj).Name()
 }
 
-// TODO: we don't need the informer to be indexed
-// Start prepares watchers and run their controllers, then waits for process termination signals
-func Start(conf *config.Config, eventHandler handlers.Handler) {
-	var kubeClient kubernetes.Interface
-	var dynamicClient dynamic.Interface
-	
-	kubewatchEventsMetrics := promauto.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "kubewatch_events_total",
-			Help: "The total number of Kubernetes events observed by Kubewatch, labeled by resource and event type",
-		},
-		[]string{"resourceType", "eventType"},
-	)
+// Cluster holds the clients of a cluster watched by kubewatch
+type Cluster struct {
+	// Name is attached to every event from this cluster, empty for single-cluster setups
+	Name string
+	// Environment, Region and Labels are attached to every event from this cluster
+	Environment string
+	Region      string
+	Labels      map[string]string
+	// Links are appended to every event from this cluster
+	Links []Link
+	// GroupKeys render the group keys of the events from this cluster, by kind
+	GroupKeys      GroupKeys
+	KubeClient     kubernetes.Interface
+	DynamicClient  dynamic.Interface
+	MetadataClient metadata.Interface
+}
 
-	if _, err := rest.InClusterConfig(); err != nil {
-		kubeClient = utils.GetClientOutOfCluster()
-		dynamicClient = utils.GetDynamicClientOutOfCluster()
-	} else {
-		kubeClient = utils.GetClient()
-		dynamicClient = utils.GetDynamicClient()
+// ConfigWatcher calls onChange with each new version of the configuration, or the error met
+// loading it, until stopCh is closed
+type ConfigWatcher func(stopCh <-chan struct{}, onChange func(*config.Config, error))
+
+// WatchConfigFile is the ConfigWatcher of the config file
+func WatchConfigFile(stopCh <-chan struct{}, onChange func(*config.Config, error)) {
+	config.Watch(configReloadInterval, stopCh, onChange)
+}
+
+// Start prepares watchers and run their controllers for every cluster, then waits for process termination signals.
+// Resources added or removed from the configuration reported by watchConfig are applied without restarting.
+// On termination, informers are stopped and queued events are handled before returning.
+func Start(conf *config.Config, eventHandler handlers.Handler, clusters []Cluster, watchConfig ConfigWatcher) {
+	stopCh := make(chan struct{})
+	go func() {
+		sigterm := make(chan os.Signal, 1)
+		signal.Notify(sigterm, syscall.SIGTERM)
+		signal.Notify(sigterm, syscall.SIGINT)
+		<-sigterm
+		close(stopCh)
+	}()
+	Run(conf, eventHandler, clusters, watchConfig, stopCh)
+}
+
+// Run runs the controllers of every cluster as Start does, until stopCh is closed rather
+// than until a termination signal, then handles the queued events before returning.
+func Run(conf *config.Config, eventHandler handlers.Handler, clusters []Cluster, watchConfig ConfigWatcher, stopCh <-chan struct{}) {
+	var cp *checkpoints
+	if len(clusters) > 0 {
+		var err error
+		if cp, err = newCheckpoints(conf.Checkpoint, clusters[0].KubeClient); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	var watchers []*clusterWatcher
+	for _, cluster := range clusters {
+		watchers = append(watchers, startCluster(conf, eventHandler, cluster, cp, stopCh))
+	}
+	if cp != nil {
+		go cp.Run(stopCh)
+	}
+	for _, w := range watchers {
+		go w.checkAccess(conf.NotifyAccessDenied)
+		if conf.Deprecations.Enabled {
+			go w.checkDeprecations(conf.Deprecations.Interval, stopCh)
+		}
+		if w.nodePressure != nil {
+			go w.nodePressure.run(conf.NodePressure.Interval, stopCh)
+		}
+		if conf.IngressTLS.Enabled {
+			go w.checkIngressCertificates(conf.IngressTLS.ExpiryWindow, conf.IngressTLS.Interval, stopCh)
+		}
+		if w.autoscaler != nil {
+			go w.autoscaler.watch(stopCh)
+		}
+		if conf.OOMRisk.Enabled {
+			go w.checkMemoryUsage(conf.OOMRisk.Threshold, conf.OOMRisk.Interval, stopCh)
+		}
+	}
+	setStarted()
+	go reportCacheSizes(stopCh)
+
+	go watchConfig(stopCh, func(newConf *config.Config, err error) {
+		if err != nil {
+			logrus.Errorf("Error reloading config: %v", err)
+			return
+		}
+		for _, w := range watchers {
+			w.reload(newConf)
+		}
+	})
+
+	<-stopCh
+
+	timeout := conf.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = config.DefaultShutdownTimeout
+	}
+	logrus.Infof("Shutting down, draining queued events (timeout %s)", timeout)
+	deadline := time.Now().Add(timeout)
+	for _, w := range watchers {
+		if !w.wait(time.Until(deadline)) {
+			logrus.Warnf("Timed out draining queued events after %s, some events may be lost", timeout)
+			break
+		}
+	}
+	setStopped()
+
+	if f, ok := eventHandler.(handlers.Flusher); ok {
+		if err := f.Flush(); err != nil {
+			logrus.Errorf("Error flushing handler: %v", err)
+		}
+	}
+	if cp != nil {
+		if err := cp.save(); err != nil {
+			logrus.Errorf("Error saving checkpoints: %v", err)
+		}
+	}
+}
+
+// TODO: we don't need the informer to be indexed
+// startCluster prepares watchers of a cluster and runs their controllers until stopCh is closed.
+// Notified objects are recorded in cp, unless it is nil.
+func startCluster(conf *config.Config, eventHandler handlers.Handler, cluster Cluster, cp *checkpoints, stopCh <-chan struct{}) *clusterWatcher {
+	w := newClusterWatcher(conf, eventHandler, cluster, cp, stopCh)
+
+	if conf.PodSecurity.Enabled {
+		w.podSecurity = newPodSecurity(w.kubeClient, conf.PodSecurity.NamespaceSelector)
+		go w.podSecurity.Run(stopCh)
+		cache.WaitForCacheSync(stopCh, w.podSecurity.informer.HasSynced)
+	}
+	if conf.Ownership.Annotations.Enabled {
+		w.ownership = newNamespaceOwnership(w.kubeClient, conf.Ownership.Annotations)
+		go w.ownership.Run(stopCh)
+		cache.WaitForCacheSync(stopCh, w.ownership.informer.HasSynced)
 	}
 
 	// User Configured Events
-	if conf.Resource.CoreEvent {
-		allCoreEventsInformer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					options.FieldSelector = ""
-					return kubeClient.CoreV1().Events(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					options.FieldSelector = ""
-					return kubeClient.CoreV1().Events(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Event{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		allCoreEventsController := newResourceController(kubeClient, eventHandler, allCoreEventsInformer, objName(api_v1.Event{}), V1, kubewatchEventsMetrics)
-		stopAllCoreEventsCh := make(chan struct{})
-		defer close(stopAllCoreEventsCh)
-
-		go allCoreEventsController.Run(stopAllCoreEventsCh)
-	}
-
-	if conf.Resource.Event {
-		allEventsInformer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					options.FieldSelector = ""
-					return kubeClient.EventsV1().Events(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					options.FieldSelector = ""
-					return kubeClient.EventsV1().Events(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&events_v1.Event{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		allEventsController := newResourceController(kubeClient, eventHandler, allEventsInformer, objName(events_v1.Event{}), EVENTS_V1, kubewatchEventsMetrics)
-		stopAllEventsCh := make(chan struct{})
-		defer close(stopAllEventsCh)
-
-		go allEventsController.Run(stopAllEventsCh)
-	}
-
-	if conf.Resource.Pod {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Pods(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Pods(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Pod{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Pod{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.HPA {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AutoscalingV1().HorizontalPodAutoscalers(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AutoscalingV1().HorizontalPodAutoscalers(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&autoscaling_v1.HorizontalPodAutoscaler{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
+	w.reconcile(conf.Resource, conf.CustomResources)
 
-		c := newResourceController(kubeClient, eventHandler, informer, objName(autoscaling_v1.HorizontalPodAutoscaler{}), AUTOSCALING_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
+	if conf.NamespaceSelector != "" {
+		go newNamespaceSelector(w, conf.NamespaceSelector).Run(stopCh)
+		return w
+	}
+	for _, namespace := range conf.WatchedNamespaces() {
+		w.startNamespace(namespace, stopCh)
+	}
+	return w
+}
 
-		go c.Run(stopCh)
+// clusterWatcher creates the controllers of a cluster
+type clusterWatcher struct {
+	conf           *config.Config
+	eventHandler   handlers.Handler
+	cluster        Cluster
+	clusterName    string
+	kubeClient     kubernetes.Interface
+	dynamicClient  dynamic.Interface
+	metadataClient metadata.Interface
+	checkpoints    *checkpoints
+	stopCh         <-chan struct{}
+	// deliveries is the context of the deliveries of the controllers
+	deliveries context.Context
+	// drains is shared by the node and pod controllers to report node drains
+	drains *nodeDrains
+	// objects holds the informer caches of every controller
+	objects *objectCache
+	// redactor is shared by the controllers, nil when redaction is disabled
+	redactor *redactor
+	// imagePolicy is shared by the controllers, nil when no image rule is configured
+	imagePolicy *imagePolicy
+	// volumeFailures reports the volumes failing to attach or mount, nil when disabled
+	volumeFailures *volumeFailures
+	// nodePressure sends digests of the nodes under pressure, nil when disabled
+	nodePressure *nodePressure
+	// slowStarts is shared by the pod controllers, nil when slow starts are not reported
+	slowStarts *slowStarts
+	// disruptions is shared by the pod and event controllers, nil when disabled
+	disruptions *disruptions
+	// interruptions is shared by the node controllers, nil when disabled
+	interruptions *nodeInterruptions
+	// autoscaler reports the activity of the cluster autoscaler, nil when disabled
+	autoscaler *clusterAutoscaler
+	// vpaDrift is shared by the VerticalPodAutoscaler controllers, nil when disabled
+	vpaDrift *vpaDrift
+	// podSecurity is shared by the controllers, nil when pod security checks are disabled
+	podSecurity *podSecurity
+	// ownership is shared by the controllers, nil unless ownership annotations are enabled
+	ownership *namespaceOwnership
+	// transform strips fields from objects before they are cached, nil when none is configured
+	transform cache.TransformFunc
+
+	mu sync.Mutex
+	// watched holds the watched resources by key
+	watched map[string]*watchedResource
+	// namespaces holds the stop channel of every watched namespace
+	namespaces map[string]<-chan struct{}
+
+	// controllers tracks running controllers, until their queue is drained
+	controllers sync.WaitGroup
+}
 
+func newClusterWatcher(conf *config.Config, eventHandler handlers.Handler, cluster Cluster, cp *checkpoints, stopCh <-chan struct{}) *clusterWatcher {
+	w := &clusterWatcher{
+		conf:           conf,
+		eventHandler:   eventHandler,
+		checkpoints:    cp,
+		cluster:        cluster,
+		clusterName:    cluster.Name,
+		kubeClient:     cluster.KubeClient,
+		dynamicClient:  cluster.DynamicClient,
+		metadataClient: cluster.MetadataClient,
+		stopCh:         stopCh,
+		deliveries:     deliveryContext(stopCh, conf.ShutdownTimeout),
+		drains:         newNodeDrains(),
+		objects:        newObjectCache(),
+		redactor:       newRedactor(conf.Redaction),
+		transform:      cacheTransform(conf.Cache),
+		imagePolicy:    newImagePolicy(conf.ImagePolicy),
+		watched:        map[string]*watchedResource{},
+		namespaces:     map[string]<-chan struct{}{},
+	}
+	if conf.VolumeFailures.Enabled {
+		w.volumeFailures = newVolumeFailures(w, conf.VolumeFailures.Threshold)
+	}
+	if conf.NodePressure.Enabled {
+		w.nodePressure = newNodePressure(w, conf.NodePressure.EvictionThreshold)
+	}
+	if conf.SlowStarts.Enabled {
+		w.slowStarts = newSlowStarts(conf.SlowStarts.Threshold)
 	}
+	if conf.Disruptions {
+		w.disruptions = newDisruptions(w)
+	}
+	if conf.NodeInterruptions.Enabled {
+		w.interruptions = newNodeInterruptions(conf.NodeInterruptions, w.objects)
+	}
+	if conf.VPA.Enabled {
+		w.vpaDrift = newVPADrift(w.kubeClient, w.objects, conf.VPA.Threshold)
+	}
+	if conf.ClusterAutoscaler.Enabled {
+		w.autoscaler = newClusterAutoscaler(w, conf.ClusterAutoscaler.Namespace, conf.ClusterAutoscaler.ConfigMap)
+	}
+	return w
+}
 
-	if conf.Resource.DaemonSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().DaemonSets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().DaemonSets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.DaemonSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.DaemonSet{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.StatefulSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().StatefulSets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().StatefulSets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.StatefulSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.StatefulSet{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ReplicaSet {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().ReplicaSets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().ReplicaSets(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.ReplicaSet{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.ReplicaSet{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Services {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Services(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Services(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Service{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Service{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Deployment {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().Deployments(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().Deployments(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&apps_v1.Deployment{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(apps_v1.Deployment{}), APPS_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Namespace {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Namespace{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Namespace{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ReplicationController {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ReplicationControllers(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ReplicationControllers(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.ReplicationController{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.ReplicationController{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Job {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.BatchV1().Jobs(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.BatchV1().Jobs(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&batch_v1.Job{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(batch_v1.Job{}), BATCH_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Node {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Nodes().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Nodes().Watch(context.Background(), options)
-				},
-			},
-			&api_v1.Node{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Node{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ServiceAccount {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ServiceAccounts(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ServiceAccounts(conf.Namespace).Watch(context.Background(), options)
-				},
-			},
-			&api_v1.ServiceAccount{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.ServiceAccount{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ClusterRole {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.RbacV1().ClusterRoles().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.RbacV1().ClusterRoles().Watch(context.Background(), options)
-				},
-			},
-			&rbac_v1.ClusterRole{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(rbac_v1.ClusterRole{}), RBAC_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ClusterRoleBinding {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.RbacV1().ClusterRoleBindings().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.RbacV1().ClusterRoleBindings().Watch(context.Background(), options)
-				},
-			},
-			&rbac_v1.ClusterRoleBinding{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(rbac_v1.ClusterRoleBinding{}), RBAC_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.PersistentVolume {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().PersistentVolumes().List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().PersistentVolumes().Watch(context.Background(), options)
-				},
-			},
-			&api_v1.PersistentVolume{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.PersistentVolume{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Secret {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Secrets(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Secrets(conf.Namespace).Watch(context.Background(), options)
-				},
+// notify sends an event about the cluster found outside of the resource controllers
+func (w *clusterWatcher) notify(e event.Event) {
+	e.ClusterName = w.clusterName
+	w.cluster.tag(&e)
+	populate(&e)
+	w.ownership.own(&e)
+	w.cluster.group(&e)
+	w.redactor.redact(&e)
+	w.eventHandler.Handle(e)
+}
+
+// run runs the controller until stopCh is closed and its queued events are handled
+func (w *clusterWatcher) run(c *Controller, stopCh <-chan struct{}) {
+	w.objects.add(c.resourceType, c.informer.GetStore(), stopCh)
+	w.controllers.Add(1)
+	register(c)
+	go func() {
+		defer w.controllers.Done()
+		defer unregister(c)
+		c.Run(stopCh)
+	}()
+}
+
+// wait waits for every controller to stop, returning false if timeout expires first
+func (w *clusterWatcher) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		w.controllers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// startNamespace runs the controllers of every namespaced resource in namespace until stopCh is closed,
+// an empty namespace meaning all namespaces
+func (w *clusterWatcher) startNamespace(namespace string, stopCh <-chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.namespaces[namespace] = stopCh
+	for _, wr := range w.watched {
+		if wr.namespaced {
+			w.startWatched(wr, namespace, stopCh)
+		}
+	}
+	if w.volumeFailures != nil {
+		go w.volumeFailures.watch(namespace, stopCh)
+	}
+	if w.nodePressure != nil {
+		go w.nodePressure.watch(namespace, stopCh)
+	}
+
+	go func() {
+		<-stopCh
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.namespaces[namespace] == stopCh {
+			delete(w.namespaces, namespace)
+		}
+	}()
+}
+
+// newController creates a resource controller configured for this cluster
+func (w *clusterWatcher) newController(informer cache.SharedIndexInformer, resourceType string, apiVersion string, namespace string) *Controller {
+	if w.transform != nil {
+		if err := informer.SetTransform(w.transform); err != nil {
+			logrus.Warnf("Caching %s objects as they are: %v", resourceType, err)
+		}
+	}
+	c := newResourceController(w.kubeClient, w.eventHandler, informer, w.clusterName, resourceType, apiVersion)
+	c.cluster = w.cluster
+	c.namespace = namespace
+	c.startupInventory = w.conf.StartupInventory
+	c.rollouts = w.conf.Rollouts
+	c.imagePolicy = w.imagePolicy
+	c.podSecurity = w.podSecurity
+	c.ownership = w.ownership
+	if w.conf.NodeLifecycle {
+		c.nodeDrains = w.drains
+	}
+	c.digestEvictions = w.nodePressure != nil
+	c.dropAutoscalerPodEvents = w.autoscaler != nil
+	c.dropKarpenterPodEvents = w.conf.Karpenter.Enabled
+	c.slowStarts = w.slowStarts
+	c.disruptions = w.disruptions
+	c.interruptions = w.interruptions
+	if w.conf.InvolvedObjects || w.conf.Describe {
+		c.objects = w.objects
+		c.involved = w.conf.InvolvedObjects
+		c.describe = w.conf.Describe
+	}
+	if w.conf.ContainerLogs.Enabled {
+		c.containerLogs = &w.conf.ContainerLogs
+	}
+	c.redactor = w.redactor
+	c.checkpoints = w.checkpoints
+	c.checkpointScope = checkpointScope(w.clusterName, resourceType, namespace)
+	if w.conf.Queue.MaxRetries > 0 {
+		c.maxRetries = w.conf.Queue.MaxRetries
+	}
+	c.deliveries = w.deliveries
+	c.deliveryTimeout = w.conf.Queue.DeliveryTimeout
+	c.workers = w.conf.Queue.WorkersFor(resourceType)
+	c.latencySLO = w.conf.Latency.SLO
+	c.notifySLO = w.conf.Latency.Notify
+	if w.conf.Queue.QPS > 0 {
+		burst := w.conf.Queue.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(w.conf.Queue.QPS), burst)
+	}
+	return c
+}
+
+func (w *clusterWatcher) startResource(r resource, namespace string, stopCh <-chan struct{}) {
+	if w.metadataClient != nil && w.conf.IsMetadataOnly(r.name) {
+		w.startMetadataResource(r, namespace, stopCh)
+		return
+	}
+
+	// index Kubernetes Events by the object they are about, for descriptions
+	indexers := cache.Indexers{}
+	if _, ok := regarding(r.object); ok {
+		indexers[involvedUIDIndex] = involvedUID
+	}
+
+	kubeClient := w.kubeClient
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return r.list(kubeClient, namespace, options)
 			},
-			&api_v1.Secret{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.Secret{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.ConfigMap {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ConfigMaps(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ConfigMaps(conf.Namespace).Watch(context.Background(), options)
-				},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return r.watch(kubeClient, namespace, options)
 			},
-			&api_v1.ConfigMap{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(api_v1.ConfigMap{}), V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	if conf.Resource.Ingress {
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.NetworkingV1().Ingresses(conf.Namespace).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.NetworkingV1().Ingresses(conf.Namespace).Watch(context.Background(), options)
-				},
+		},
+		r.object,
+		w.conf.Resync(r.name),
+		indexers,
+	)
+
+	c := w.newController(informer, r.name, r.apiVersion, namespace)
+	if r.transform != nil {
+		if err := informer.SetTransform(chainTransforms(w.transform, r.transform)); err != nil {
+			logrus.Warnf("Caching %s objects as they are: %v", r.name, err)
+		}
+	}
+	if _, ok := r.object.(*api_v1.Node); ok {
+		w.drains.setNodes(informer.GetStore())
+	}
+
+	w.run(c, stopCh)
+}
+
+// startCustomResource runs the controller of crd in namespace until stopCh is closed.
+// GitOps, Karpenter and VerticalPodAutoscaler resources watched for healthOnly only report
+// their dedicated events, and custom resources with condition rules report the changes of
+// their conditions instead of their updates.
+func (w *clusterWatcher) startCustomResource(crd config.CRD, healthOnly bool, namespace string, stopCh <-chan struct{}) {
+	resourceClient := w.dynamicClient.Resource(schema.GroupVersionResource{
+		Group:    crd.Group,
+		Version:  crd.Version,
+		Resource: crd.Resource,
+	})
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				if namespace != "" {
+					return resourceClient.Namespace(namespace).List(context.Background(), options)
+				}
+				return resourceClient.List(context.Background(), options)
 			},
-			&networking_v1.Ingress{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
-
-		c := newResourceController(kubeClient, eventHandler, informer, objName(networking_v1.Ingress{}), NETWORKING_V1, kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
-	}
-
-	for _, curRes := range conf.CustomResources {
-		crd := curRes
-		informer := cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return dynamicClient.Resource(schema.GroupVersionResource{
-						Group:    crd.Group,
-						Version:  crd.Version,
-						Resource: crd.Resource,
-					}).List(context.Background(), options)
-				},
-				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return dynamicClient.Resource(schema.GroupVersionResource{
-						Group:    crd.Group,
-						Version:  crd.Version,
-						Resource: crd.Resource,
-					}).Watch(context.Background(), options)
-				},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				if namespace != "" {
+					return resourceClient.Namespace(namespace).Watch(context.Background(), options)
+				}
+				return resourceClient.Watch(context.Background(), options)
 			},
-			&unstructured.Unstructured{},
-			0, //Skip resync
-			cache.Indexers{},
-		)
+		},
+		&unstructured.Unstructured{},
+		w.conf.Resync(crd.Resource),
+		cache.Indexers{},
+	)
 
-		c := newResourceController(kubeClient, eventHandler, informer, crd.Resource, fmt.Sprintf("%s/%s", crd.Group, crd.Version), kubewatchEventsMetrics)
-		stopCh := make(chan struct{})
-		defer close(stopCh)
+	c := w.newController(informer, crd.Resource, fmt.Sprintf("%s/%s", crd.Group, crd.Version), namespace)
 
-		go c.Run(stopCh)
+	if w.conf.CertManager.Enabled && isCertificateCRD(crd) {
+		c.certificates = newCertificateWatcher(c, w.conf.CertManager)
+		go c.certificates.Run(stopCh)
+	}
+	if c.gitops = gitOpsResourceOf(w.conf.GitOps, crd); c.gitops != nil {
+		c.gitOpsHealthOnly = healthOnly
+	}
+	if w.conf.KEDA.Enabled && isScaledObjectCRD(crd) {
+		c.gitops = &kedaScaledObjects
+		c.gitOpsHealthOnly = healthOnly
+	}
+	if w.conf.Karpenter.Enabled {
+		c.karpenter = karpenterResourceOf(crd)
+		c.karpenterOnly = healthOnly
+	}
+	if w.vpaDrift != nil && isVPACRD(crd) {
+		c.vpaDrift = w.vpaDrift
+		c.vpaDriftOnly = healthOnly
 	}
+	c.conditionRules = crd.Conditions
+	fields, err := parseFields(crd.Fields)
+	if err != nil {
+		c.logger.Warnf("Cannot extract the fields of %s: %v", crd, err)
+	}
+	c.fields = fields
 
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGTERM)
-	signal.Notify(sigterm, syscall.SIGINT)
-	<-sigterm
+	w.run(c, stopCh)
+}
+
+// isNamespacedCustomResource uses the discovery API to find out whether a custom resource is namespaced.
+// Resources that cannot be discovered are assumed to be namespaced.
+func isNamespacedCustomResource(kubeClient kubernetes.Interface, crd config.CRD) bool {
+	groupVersion := schema.GroupVersion{Group: crd.Group, Version: crd.Version}.String()
+	resourceList, err := kubeClient.Discovery().ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		logrus.Warnf("Cannot discover %s/%s, assuming it is namespaced: %v", groupVersion, crd.Resource, err)
+		return true
+	}
+	for _, r := range resourceList.APIResources {
+		if r.Name == crd.Resource {
+			return r.Namespaced
+		}
+	}
+	return true
 }
 
-func newResourceController(client kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, resourceType string, apiVersion string, kubewatchEventsMetrics *prometheus.CounterVec) *Controller {
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+func newResourceController(client kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, clusterName string, resourceType string, apiVersion string) *Controller {
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]())
+	logger := logrus.WithFields(logrus.Fields{"pkg": "kubewatch-" + resourceType, "kind": resourceType})
+	if clusterName != "" {
+		logger = logger.WithField("cluster", clusterName)
+	}
+
+	c := &Controller{
+		logger:       logger,
+		clientset:    client,
+		informer:     informer,
+		queue:        queue,
+		eventHandler: eventHandler,
+		clusterName:  clusterName,
+		resourceType: resourceType,
+		apiVersion:   apiVersion,
+		maxRetries:   config.DefaultMaxRetries,
+		workers:      1,
+	}
+
 	var newEvent Event
 	var err error
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
+	informer.AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if c.checkpoints.holds(resourceType, obj) {
+				return
+			}
+			eventType := "create"
+			missed := false
+			if isInInitialList {
+				// objects already existing when the informer started are compared to their checkpoint
+				switch c.checkpoints.state(c.checkpointScope, obj) {
+				case checkpointNotified:
+					c.observed(obj, "create")
+					return
+				case checkpointChanged:
+					eventType = "update"
+				case checkpointMissed:
+					missed = true
+				default:
+					if !c.startupInventory {
+						// and are not reported without checkpoint
+						c.checkpoints.record(c.checkpointScope, obj)
+						c.observed(obj, "create")
+						return
+					}
+				}
+			}
 			var ok bool
 			newEvent.namespace = "" // namespace retrived in processItem incase namespace value is empty
+			newEvent.id = uuid.New().String()
 			newEvent.key, err = cache.MetaNamespaceKeyFunc(obj)
-			newEvent.eventType = "create"
+			newEvent.eventType = eventType
+			newEvent.missed = missed
+			newEvent.finalStateUnknown = false
+			newEvent.oldObj = nil
 			newEvent.resourceType = resourceType
 			newEvent.apiVersion = apiVersion
 			newEvent.obj, ok = obj.(runtime.Object)
 			if !ok {
-				logrus.WithField("pkg", "kubewatch-"+resourceType).Errorf("cannot convert to runtime.Object for add on %v", obj)
+				c.logger.Errorf("cannot convert to runtime.Object for add on %v", obj)
 			}
-			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing add to %v: %s", resourceType, newEvent.key)
+			c.objectLogger(newEvent.key).Infof("Processing add to %v: %s", resourceType, newEvent.key)
 			if err == nil {
 				queue.Add(newEvent)
 			}
 
-			kubewatchEventsMetrics.WithLabelValues(resourceType, "create").Inc()
+			c.observed(obj, "create")
 		},
 		UpdateFunc: func(old, new interface{}) {
+			if !metadataChanged(old, new) || c.checkpoints.holds(resourceType, new) {
+				return
+			}
 			var ok bool
 			newEvent.namespace = "" // namespace retrived in processItem incase namespace value is empty
+			newEvent.id = uuid.New().String()
 			newEvent.key, err = cache.MetaNamespaceKeyFunc(old)
 			newEvent.eventType = "update"
+			newEvent.missed = false
+			newEvent.finalStateUnknown = false
 			newEvent.resourceType = resourceType
 			newEvent.apiVersion = apiVersion
 			newEvent.obj, ok = new.(runtime.Object)
 			if !ok {
-				logrus.WithField("pkg", "kubewatch-"+resourceType).Errorf("cannot convert to runtime.Object for update on %v", new)
+				c.logger.Errorf("cannot convert to runtime.Object for update on %v", new)
 			}
 			newEvent.oldObj, ok = old.(runtime.Object)
 			if !ok {
-				logrus.WithField("pkg", "kubewatch-"+resourceType).Errorf("cannot convert old to runtime.Object for update on %v", old)
+				c.logger.Errorf("cannot convert old to runtime.Object for update on %v", old)
 			}
-			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing update to %v: %s", resourceType, newEvent.key)
+			c.objectLogger(newEvent.key).Infof("Processing update to %v: %s", resourceType, newEvent.key)
 			if err == nil {
 				queue.Add(newEvent)
 			}
 
-			kubewatchEventsMetrics.WithLabelValues(resourceType, "update").Inc()
+			c.observed(new, "update")
 		},
 		DeleteFunc: func(obj interface{}) {
+			if c.checkpoints.holds(resourceType, obj) {
+				return
+			}
 			var ok bool
 			newEvent.namespace = "" // namespace retrived in processItem incase namespace value is empty
+			newEvent.id = uuid.New().String()
 			newEvent.key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 			newEvent.eventType = "delete"
+			newEvent.missed = false
 			newEvent.resourceType = resourceType
 			newEvent.apiVersion = apiVersion
+			obj, newEvent.finalStateUnknown = finalState(obj)
 			newEvent.obj, ok = obj.(runtime.Object)
 			if !ok {
-				logrus.WithField("pkg", "kubewatch-"+resourceType).Errorf("cannot convert to runtime.Object for delete on %v", obj)
+				c.logger.Errorf("cannot convert to runtime.Object for delete on %v", obj)
 			}
-			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing delete to %v: %s", resourceType, newEvent.key)
+			c.objectLogger(newEvent.key).Infof("Processing delete to %v: %s", resourceType, newEvent.key)
 			if err == nil {
 				queue.Add(newEvent)
 			}
 
-			kubewatchEventsMetrics.WithLabelValues(resourceType, "delete").Inc()
+			c.observed(obj, "delete")
 		},
 	})
 
-	return &Controller{
-		logger:       logrus.WithField("pkg", "kubewatch-"+resourceType),
-		clientset:    client,
-		informer:     informer,
-		queue:        queue,
-		eventHandler: eventHandler,
+	return c
+}
+
+// observed counts an event of the informer in the metrics and statistics
+func (c *Controller) observed(obj interface{}, eventType string) {
+	metrics.EventsTotal.WithLabelValues(c.resourceType, eventType).Inc()
+	var namespace string
+	if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+		namespace, _, _ = cache.SplitMetaNamespaceKey(key)
 	}
+	stats.Observed(c.clusterName, c.resourceType, namespace)
 }
 
 // Run starts the kubewatch controller
@@ -675,9 +871,17 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
 
+	// stop accepting events when stopping, the worker exits once queued events are handled
+	go func() {
+		<-stopCh
+		c.queue.ShutDownWithDrain()
+	}()
+
 	c.logger.Info("Starting kubewatch controller")
-	serverStartTime = time.Now().Local()
+	c.startTime = time.Now().Local()
 
+	// only fails once the informer is started
+	_ = c.informer.SetWatchErrorHandlerWithContext(c.watchErrorHandler)
 	go c.informer.Run(stopCh)
 
 	if !cache.WaitForCacheSync(stopCh, c.HasSynced) {
@@ -685,9 +889,31 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		return
 	}
 
+	c.checkpoints.prune(c.checkpointScope, c.informer.GetStore().List())
 	c.logger.Info("Kubewatch controller synced and ready")
 
-	wait.Until(c.runWorker, time.Second, stopCh)
+	var workers sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			wait.Until(c.runWorker, time.Second, stopCh)
+		}()
+	}
+	workers.Wait()
+}
+
+// objectLogger returns the logger of the controller with the namespace and name of the object of key
+func (c *Controller) objectLogger(key string) *logrus.Entry {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return c.logger
+	}
+	fields := logrus.Fields{"name": name}
+	if namespace != "" {
+		fields["namespace"] = namespace
+	}
+	return c.logger.WithFields(fields)
 }
 
 // HasSynced is required for the cache.Controller interface.
@@ -713,23 +939,97 @@ func (c *Controller) processNextItem() bool {
 		return false
 	}
 	defer c.queue.Done(newEvent)
-	err := c.processItem(newEvent.(Event))
+
+	// smooth bursts of events
+	if c.limiter != nil {
+		if err := c.limiter.Wait(context.Background()); err != nil {
+			c.logger.Errorf("Error waiting for rate limiter: %v", err)
+		}
+	}
+
+	err := c.processItem(newEvent)
 	if err == nil {
 		// No error, reset the ratelimit counters
 		c.queue.Forget(newEvent)
-	} else if c.queue.NumRequeues(newEvent) < maxRetries {
-		c.logger.Errorf("Error processing %s (will retry): %v", newEvent.(Event).key, err)
+		c.sent.forget(newEvent.id)
+	} else if c.queue.NumRequeues(newEvent) < c.maxRetries {
+		c.objectLogger(newEvent.key).Errorf("Error processing %s (will retry): %v", newEvent.key, err)
 		c.queue.AddRateLimited(newEvent)
 	} else {
 		// err != nil and too many retries
-		c.logger.Errorf("Error processing %s (giving up): %v", newEvent.(Event).key, err)
+		c.objectLogger(newEvent.key).Errorf("Error processing %s (giving up): %v", newEvent.key, err)
 		c.queue.Forget(newEvent)
+		c.sent.forget(newEvent.id)
+		if newEvent.eventType == "expiring" {
+			c.certificates.dropped(newEvent.obj)
+		}
 		utilruntime.HandleError(err)
 	}
 
 	return true
 }
 
+// handle tags the event with the controller's cluster and passes it to the event handler.
+// Delivery errors are only reported by handlers implementing handlers.ContextHandler or
+// handlers.RetryableHandler.
+func (c *Controller) handle(e event.Event) error {
+	e.ClusterName = c.clusterName
+	c.cluster.tag(&e)
+	populate(&e)
+	c.ownership.own(&e)
+	if c.involved && e.Involved == nil {
+		e.Involved = c.involvedObject(e.Obj)
+	}
+	if c.describe && e.Description == nil {
+		e.Description = c.description(&e)
+	}
+	if len(c.fields) > 0 && e.Fields == nil {
+		e.Fields = extractFields(c.fields, e.Obj)
+	}
+	c.cluster.link(&e)
+	c.cluster.group(&e)
+	c.redactor.redact(&e)
+	e.Diff = withoutImageChanges(e.Diff, e.ImageChanges)
+	ctx := c.deliveries
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if c.deliveryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.deliveryTimeout)
+		defer cancel()
+	}
+	err := handlers.Deliver(ctx, c.eventHandler, e)
+	recordDelivery(err)
+	if err != nil {
+		stats.Failed()
+		return err
+	}
+	processed.Add(1)
+	stats.Sent()
+	c.observeLatency(e)
+	return nil
+}
+
+// deliveryContext returns the context of the deliveries of the controllers stopped by
+// stopCh, canceled when the queued events are not handled within timeout once stopCh is
+// closed
+func deliveryContext(stopCh <-chan struct{}, timeout time.Duration) context.Context {
+	if stopCh == nil {
+		return context.Background()
+	}
+	if timeout <= 0 {
+		timeout = config.DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		<-time.After(timeout)
+		cancel()
+	}()
+	return ctx
+}
+
 /* TODOs
 - Enhance event creation using client-side cacheing machanisms - pending
 - Enhance the processItem to classify events - done
@@ -746,8 +1046,12 @@ func (c *Controller) processItem(newEvent Event) error {
 	// get object's metedata
 	objectMeta := utils.GetObjectMetaData(obj)
 
-	// hold status type for default critical alerts
-	var status string
+	// send the latest known state of the object when retrying a failed delivery
+	if obj != nil && c.queue.NumRequeues(newEvent) > 0 && (newEvent.eventType == "create" || newEvent.eventType == "update") {
+		if latest, ok := obj.(runtime.Object); ok {
+			newEvent.obj = latest
+		}
+	}
 
 	// namespace retrived from event key incase namespace value is empty
 	if newEvent.namespace == "" && strings.Contains(newEvent.key, "/") {
@@ -758,12 +1062,176 @@ func (c *Controller) processItem(newEvent Event) error {
 		newEvent.namespace = objectMeta.Namespace
 	}
 
+	if err := c.notifyEvent(newEvent, objectMeta); err != nil {
+		return err
+	}
+	c.checkpoint(newEvent)
+	return nil
+}
+
+// notifyEvent sends the notifications of the queued event newEvent about the object with
+// objectMeta
+func (c *Controller) notifyEvent(newEvent Event, objectMeta meta_v1.ObjectMeta) error {
+	// hold status type for default critical alerts
+	var status string
+	var err error
+
+	// flag created and updated workloads whose images break the image policy
+	if c.imagePolicy != nil {
+		if kbEvent := c.imagePolicyEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// flag created and updated workloads of restricted namespaces breaking pod security
+	if c.podSecurity != nil {
+		if kbEvent := c.podSecurityEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// fold ReplicaSet and Deployment events into rollout events
+	if c.rollouts {
+		if kbEvent, ok := c.rolloutEvent(newEvent); ok {
+			if kbEvent != nil {
+				if err := c.notify(newEvent, *kbEvent); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	// warn about nodes about to be interrupted, besides the update
+	if c.interruptions != nil {
+		if kbEvent := c.nodeInterruptionEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// report node cordons, drains and deletions
+	if c.nodeDrains != nil {
+		if kbEvent, ok := c.nodeLifecycleEvent(newEvent); ok {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+			return nil
+		}
+		if kbEvent := c.nodeDrainEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// report Helm releases changes instead of the changes of their Secrets
+	if c.resourceType == helmReleaseKind {
+		if kbEvent := c.helmReleaseEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// report the health changes of GitOps resources, only them unless the resource is
+	// configured as a custom resource
+	if c.gitops != nil {
+		if kbEvent := c.gitOpsEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+		if c.gitOpsHealthOnly {
+			return nil
+		}
+	}
+
+	// summarize the changes of Karpenter NodeClaims and NodePools, only them unless the
+	// resource is configured as a custom resource
+	if c.karpenter != "" {
+		if kbEvent := c.karpenterEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+		if c.karpenterOnly {
+			return nil
+		}
+	}
+
+	// report VerticalPodAutoscaler recommendations drifting from the requests, only them
+	// unless the resource is configured as a custom resource
+	if c.vpaDrift != nil {
+		if kbEvent := c.vpaDriftEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+		if c.vpaDriftOnly {
+			return nil
+		}
+	}
+
+	// report custom resources entering or leaving the conditions of their rules, instead of
+	// their updates
+	if len(c.conditionRules) > 0 && newEvent.eventType == "update" {
+		if kbEvent := c.conditionEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// report CronJobs being suspended or resumed, besides the update
+	if kbEvent := cronJobSuspendEvent(newEvent); kbEvent != nil {
+		if err := c.notify(newEvent, *kbEvent); err != nil {
+			return err
+		}
+	}
+
+	// report the pods whose containers are slow to start, besides the update
+	if c.slowStarts != nil {
+		if kbEvent := c.slowStartEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// report pod evictions and preemptions, besides the update or the Kubernetes Event
+	if c.disruptions != nil {
+		if kbEvent := c.disruptionEvent(newEvent); kbEvent != nil {
+			if err := c.notify(newEvent, *kbEvent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// evictions are summarized in node pressure digests instead
+	if c.digestEvictions && evictionEvent(newEvent.obj) {
+		return nil
+	}
+
+	// the activity of the cluster autoscaler and of Karpenter is reported from their
+	// status and resources instead
+	if (c.dropAutoscalerPodEvents && autoscalerPodEvent(newEvent.obj)) || (c.dropKarpenterPodEvents && karpenterPodEvent(newEvent.obj)) {
+		return nil
+	}
+
 	// process events based on its type
 	switch newEvent.eventType {
 	case "create":
-		// compare CreationTimestamp and serverStartTime and alert only on latest events
-		// Could be Replaced by using Delta or DeltaFIFO
-		if objectMeta.CreationTimestamp.Sub(serverStartTime).Seconds() > 0 {
+		// compare CreationTimestamp and startTime and alert only on latest events,
+		// unless the startup inventory was requested
+		if c.startupInventory || newEvent.missed || objectMeta.CreationTimestamp.Sub(c.startTime).Seconds() > 0 {
 			switch newEvent.resourceType {
 			case "NodeNotReady":
 				status = "Danger"
@@ -780,13 +1248,14 @@ func (c *Controller) processItem(newEvent Event) error {
 				Name:       newEvent.key,
 				Namespace:  newEvent.namespace,
 				Kind:       newEvent.resourceType,
-				ApiVersion: newEvent.apiVersion,
+				APIVersion: newEvent.apiVersion,
 				Status:     status,
 				Reason:     "Created",
 				Obj:        newEvent.obj,
 			}
-			c.eventHandler.Handle(kbEvent)
-			return nil
+			if err := c.notify(newEvent, kbEvent); err != nil {
+				return err
+			}
 		}
 	case "update":
 		/* TODOs
@@ -802,26 +1271,167 @@ func (c *Controller) processItem(newEvent Event) error {
 			Name:       newEvent.key,
 			Namespace:  newEvent.namespace,
 			Kind:       newEvent.resourceType,
-			ApiVersion: newEvent.apiVersion,
+			APIVersion: newEvent.apiVersion,
 			Status:     status,
 			Reason:     "Updated",
 			Obj:        newEvent.obj,
 			OldObj:     newEvent.oldObj,
 		}
-		c.eventHandler.Handle(kbEvent)
+		if kbEvent.Diff, err = diff.Compute(newEvent.oldObj, newEvent.obj); err != nil {
+			c.logger.Debugf("Error computing diff of %s: %v", newEvent.key, err)
+		}
+		kbEvent.ImageChanges = imageChanges(newEvent.oldObj, newEvent.obj)
+		kbEvent.ChangedBy = changedBy(newEvent.oldObj, newEvent.obj)
+		if kbEvent.JobRun = jobRun(newEvent.oldObj, newEvent.obj); kbEvent.JobRun != nil {
+			kbEvent.Status = "Normal"
+		}
+		if c.containerLogs != nil {
+			kbEvent.Logs = c.failureLogs(newEvent)
+		}
+		if kbEvent.Scale = scaleChange(newEvent.oldObj, newEvent.obj); kbEvent.Scale != nil && onlyScaled(kbEvent.Diff) {
+			kbEvent.Status = "Normal"
+			kbEvent.Reason = "Scaled"
+			kbEvent.Diff = nil
+		}
+		if err := c.notify(newEvent, kbEvent); err != nil {
+			return err
+		}
+		if c.certificates != nil {
+			return c.handleCertificateUpdate(newEvent)
+		}
+		return nil
+	case "expiring":
+		cert, ok := newEvent.obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil
+		}
+		notAfter, _ := certificateNotAfter(cert)
+		kbEvent := event.Event{
+			Name:       newEvent.key,
+			Namespace:  newEvent.namespace,
+			Kind:       "CertificateExpiring",
+			APIVersion: newEvent.apiVersion,
+			Status:     "Warning",
+			Reason:     fmt.Sprintf("expires at %s", notAfter.Format(time.RFC3339)),
+			Obj:        newEvent.obj,
+		}
+		if err := c.notify(newEvent, kbEvent); err != nil {
+			return err
+		}
+		c.certificates.delivered(cert)
 		return nil
 	case "delete":
 		kbEvent := event.Event{
 			Name:       newEvent.key,
 			Namespace:  newEvent.namespace,
 			Kind:       newEvent.resourceType,
-			ApiVersion: newEvent.apiVersion,
+			APIVersion: newEvent.apiVersion,
 			Status:     "Danger",
 			Reason:     "Deleted",
 			Obj:        newEvent.obj,
+			Deletion:   deletion(newEvent),
 		}
-		c.eventHandler.Handle(kbEvent)
+		if err := c.notify(newEvent, kbEvent); err != nil {
+			return err
+		}
+		if c.certificates != nil {
+			c.certificates.forget(newEvent.obj)
+		}
+	}
+	return nil
+}
+
+// checkpoint records the object of the queued event newEvent as notified, or forgets it
+// once deleted
+func (c *Controller) checkpoint(newEvent Event) {
+	if newEvent.eventType == "delete" {
+		c.checkpoints.forget(c.checkpointScope, newEvent.obj)
+	} else {
+		c.checkpoints.record(c.checkpointScope, newEvent.obj)
+	}
+}
+
+// notify sends the notification e of the queued event newEvent. Its ID is derived from
+// the queued event and the kind of the notification, so that retries of a failed delivery
+// send the same notification again rather than a new one. Notifications already delivered
+// by a previous attempt are not sent again.
+func (c *Controller) notify(newEvent Event, e event.Event) error {
+	if newEvent.id == "" {
+		return c.handle(e)
+	}
+	if c.sent.has(newEvent.id, e.Kind) {
 		return nil
 	}
+	if e.ID == "" {
+		e.ID = uuid.NewSHA1(uuid.MustParse(newEvent.id), []byte(e.Kind)).String()
+	}
+	if err := c.handle(e); err != nil {
+		return err
+	}
+	c.sent.add(newEvent.id, e.Kind)
 	return nil
 }
+
+// sentNotifications holds the kinds of the notifications delivered for each queued event,
+// by ID, until the queued event is done
+type sentNotifications struct {
+	mu    sync.Mutex
+	kinds map[string]map[string]bool
+}
+
+func (s *sentNotifications) has(id, kind string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.kinds[id][kind]
+}
+
+func (s *sentNotifications) add(id, kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kinds == nil {
+		s.kinds = map[string]map[string]bool{}
+	}
+	if s.kinds[id] == nil {
+		s.kinds[id] = map[string]bool{}
+	}
+	s.kinds[id][kind] = true
+}
+
+func (s *sentNotifications) forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.kinds, id)
+}
+
+// handleCertificateUpdate sends a CertificateNotReady event when a cert-manager
+// Certificate's Ready condition turns False, and checks the updated certificate for expiry
+func (c *Controller) handleCertificateUpdate(newEvent Event) error {
+	cert, ok := newEvent.obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	c.certificates.checkCertificate(cert, time.Now())
+
+	notReady, message := certificateNotReady(cert)
+	if !notReady {
+		return nil
+	}
+	if oldCert, ok := newEvent.oldObj.(*unstructured.Unstructured); ok {
+		if wasNotReady, _ := certificateNotReady(oldCert); wasNotReady {
+			return nil
+		}
+	}
+	if message == "" {
+		message = "Ready condition is False"
+	}
+	return c.notify(newEvent, event.Event{
+		Name:       newEvent.key,
+		Namespace:  newEvent.namespace,
+		Kind:       "CertificateNotReady",
+		APIVersion: newEvent.apiVersion,
+		Status:     "Danger",
+		Reason:     message,
+		Obj:        newEvent.obj,
+		OldObj:     newEvent.oldObj,
+	})
+}
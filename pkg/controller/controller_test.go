@@ -0,0 +1,487 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/outbox"
+	"github.com/bitnami-labs/kubewatch/pkg/stats"
+	"github.com/google/uuid"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// recorder is a handler recording the events it receives
+type recorder struct {
+	mu     sync.Mutex
+	events []event.Event
+}
+
+func (r *recorder) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *recorder) Handle(e event.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, e := range r.events {
+		names = append(names, e.Reason+" "+e.Name)
+	}
+	return names
+}
+
+func (r *recorder) waitFor(t *testing.T, expected ...string) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return len(r.names()) >= len(expected), nil
+	})
+	names := r.names()
+	if err != nil || len(names) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, names)
+		}
+	}
+}
+
+// flakyRecorder fails the first deliveries, only of events of kind when set
+type flakyRecorder struct {
+	recorder
+	failures int
+	kind     string
+}
+
+func (r *flakyRecorder) HandleWithError(e event.Event) error {
+	r.mu.Lock()
+	if r.failures > 0 && (r.kind == "" || e.Kind == r.kind) {
+		r.failures--
+		r.mu.Unlock()
+		return errors.New("endpoint unavailable")
+	}
+	r.mu.Unlock()
+	r.Handle(e)
+	return nil
+}
+
+func newPod(name string, created time.Time) *api_v1.Pod {
+	return &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{
+		Name:              name,
+		Namespace:         "default",
+		CreationTimestamp: meta_v1.NewTime(created),
+	}}
+}
+
+// startPods runs a Pod controller against a fake clientset
+func startPods(t *testing.T, conf *config.Config, objects ...*api_v1.Pod) (*fake.Clientset, *recorder) {
+	client := fake.NewSimpleClientset()
+	for _, pod := range objects {
+		if _, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, meta_v1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf.Resource.Pod = true
+	r := &recorder{}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	startCluster(conf, r, Cluster{KubeClient: client}, nil, stopCh)
+	return client, r
+}
+
+func TestStartupSuppression(t *testing.T) {
+	client, r := startPods(t, &config.Config{}, newPod("existing", time.Now().Add(-time.Hour)))
+
+	// give the informer time to list the existing pod
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.CoreV1().Pods("default").Create(context.Background(), newPod("new", time.Now().Add(time.Minute)), meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.waitFor(t, "Created new")
+}
+
+func TestStartupInventory(t *testing.T) {
+	_, r := startPods(t, &config.Config{StartupInventory: true}, newPod("existing", time.Now().Add(-time.Hour)))
+
+	r.waitFor(t, "Created existing")
+}
+
+func TestRetryFailedDelivery(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := &flakyRecorder{failures: 2}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	startCluster(&config.Config{Resource: config.Resource{Pod: true}}, r, Cluster{KubeClient: client}, nil, stopCh)
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.CoreV1().Pods("default").Create(context.Background(), newPod("new", time.Now().Add(time.Minute)), meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.waitFor(t, "Created new")
+}
+
+func TestRetryReplacesOutboxEntry(t *testing.T) {
+	dir := t.TempDir()
+	box, err := outbox.Open(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewSimpleClientset()
+	r := &flakyRecorder{failures: 1}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	startCluster(&config.Config{Resource: config.Resource{Pod: true}}, &outbox.Handler{Outbox: box, Handler: r}, Cluster{KubeClient: client}, nil, stopCh)
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.CoreV1().Pods("default").Create(context.Background(), newPod("new", time.Now().Add(time.Minute)), meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.waitFor(t, "Created new")
+
+	// the retry delivered the event, nothing is left to redeliver after a restart
+	var pending []event.Payload
+	err = wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 2*time.Second, true, func(context.Context) (bool, error) {
+		restarted, err := outbox.Open(dir, time.Hour)
+		if err != nil {
+			return false, err
+		}
+		pending, err = restarted.Pending()
+		return err == nil && len(pending) == 0, err
+	})
+	if err != nil {
+		t.Fatalf("expected the outbox to be empty, got %d events: %v", len(pending), err)
+	}
+}
+
+func TestRetrySkipsDeliveredNotifications(t *testing.T) {
+	suspend := true
+	old := &batch_v1.CronJob{ObjectMeta: meta_v1.ObjectMeta{Name: "backup", Namespace: "default"}}
+	cronJob := old.DeepCopy()
+	cronJob.Spec.Suspend = &suspend
+	informer := cache.NewSharedIndexInformer(nil, &batch_v1.CronJob{}, 0, cache.Indexers{})
+	if err := informer.GetIndexer().Add(cronJob); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &flakyRecorder{failures: 1, kind: "CronJob"}
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     informer,
+		eventHandler: r,
+	}
+	update := Event{id: uuid.New().String(), key: "default/backup", eventType: "update", resourceType: "CronJob", obj: cronJob, oldObj: old}
+	processedBefore, statsBefore := ProcessedEvents(), stats.Get()
+
+	// the suspension is delivered, the update fails
+	if err := c.processItem(update); err == nil {
+		t.Fatal("expected the update delivery to fail")
+	}
+	c.queue.AddRateLimited(update)
+	if err := c.processItem(update); err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	for _, e := range r.events {
+		kinds = append(kinds, e.Kind)
+	}
+	if len(kinds) != 2 || kinds[0] != "CronJobSuspended" || kinds[1] != "CronJob" {
+		t.Errorf("expected the suspension to be sent once, got %v", kinds)
+	}
+	summary := stats.Get()
+	if processed := ProcessedEvents() - processedBefore; processed != 2 || summary.Sent-statsBefore.Sent != 2 || summary.Failed-statsBefore.Failed != 1 {
+		t.Errorf("expected 2 events counted as sent and 1 failure, got %d processed, %+v", processed, summary)
+	}
+}
+
+func TestRetrySendsLatestObject(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(nil, &api_v1.Pod{}, 0, cache.Indexers{})
+	latest := newPod("foo", time.Now())
+	latest.Labels = map[string]string{"version": "2"}
+	if err := informer.GetIndexer().Add(latest); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &recorder{}
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     informer,
+		eventHandler: r,
+	}
+	stale := Event{key: "default/foo", eventType: "update", resourceType: "Pod", obj: newPod("foo", time.Now())}
+
+	// a first delivery failed
+	c.queue.AddRateLimited(stale)
+	if err := c.processItem(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.events) != 1 || r.events[0].Obj != latest {
+		t.Fatalf("expected retry to send the latest object, got %+v", r.events)
+	}
+}
+
+// slowRecorder takes a while to handle each event
+type slowRecorder struct {
+	recorder
+}
+
+func (r *slowRecorder) Handle(e event.Event) {
+	time.Sleep(20 * time.Millisecond)
+	r.recorder.Handle(e)
+}
+
+func TestShutdownDrainsQueue(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Pods("").List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Pods("").Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Pod{}, 0, cache.Indexers{},
+	)
+	r := &slowRecorder{}
+	c := newResourceController(client, r, informer, "", "Pod", "v1")
+
+	w := newClusterWatcher(&config.Config{}, r, Cluster{KubeClient: client}, nil, nil)
+	stopCh := make(chan struct{})
+	w.run(c, stopCh)
+
+	// wait for the worker to be running
+	c.queue.Add(Event{key: "default/first", eventType: "update", resourceType: "Pod", obj: newPod("first", time.Now())})
+	r.waitFor(t, "Updated first")
+
+	for _, name := range []string{"a", "b", "c"} {
+		c.queue.Add(Event{key: "default/" + name, eventType: "update", resourceType: "Pod", obj: newPod(name, time.Now())})
+	}
+	close(stopCh)
+
+	if !w.wait(5 * time.Second) {
+		t.Fatal("controller did not stop")
+	}
+	if names := r.names(); len(names) != 4 {
+		t.Fatalf("expected queued events to be handled, got %v", names)
+	}
+}
+
+func TestUpdateDiff(t *testing.T) {
+	r := &recorder{}
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     cache.NewSharedIndexInformer(nil, &api_v1.Pod{}, 0, cache.Indexers{}),
+		eventHandler: r,
+	}
+	old := newPod("foo", time.Now())
+	new := old.DeepCopy()
+	new.Labels = map[string]string{"version": "2"}
+
+	if err := c.processItem(Event{key: "default/foo", eventType: "update", resourceType: "Pod", obj: new, oldObj: old}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.events) != 1 || len(r.events[0].Diff) != 1 || r.events[0].Diff[0].Path != "metadata.labels.version" {
+		t.Fatalf("expected the label change in the event diff, got %+v", r.events)
+	}
+}
+
+func TestEventPopulated(t *testing.T) {
+	r := &recorder{}
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     cache.NewSharedIndexInformer(nil, &api_v1.Pod{}, 0, cache.Indexers{}),
+		eventHandler: r,
+		clusterName:  "prod-eu",
+		cluster:      Cluster{Environment: "production"},
+	}
+	pod := newPod("foo", time.Now())
+	pod.UID = "1234"
+	pod.Labels = map[string]string{"app": "foo"}
+
+	if err := c.processItem(Event{key: "default/foo", eventType: "delete", resourceType: "Pod", apiVersion: V1, obj: pod}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.events) != 1 {
+		t.Fatalf("expected one event, got %+v", r.events)
+	}
+	e := r.events[0]
+	if e.Severity != event.SeverityCritical || e.Timestamp.IsZero() || e.UID != "1234" || e.Labels["app"] != "foo" || e.APIVersion != V1 {
+		t.Errorf("expected severity, timestamp, UID, labels and API version to be set, got %+v", e)
+	}
+	if e.ClusterName != "prod-eu" || e.Environment != "production" {
+		t.Errorf("expected the cluster metadata to be set, got %+v", e)
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	pod := newPod("web", time.Now())
+	pod.UID = "1234"
+	coreEvent := &api_v1.Event{InvolvedObject: api_v1.ObjectReference{Kind: "Pod", Name: "web", UID: "1234"}}
+	coreEvent.UID = "5678"
+
+	var Tests = []struct {
+		event       event.Event
+		correlation string
+	}{
+		{event.Event{Obj: pod}, "1234"},
+		{event.Event{Obj: coreEvent}, "1234"},
+		{event.Event{Obj: pod, CorrelationID: "rollout"}, "rollout"},
+	}
+
+	ids := map[string]bool{}
+	for i, test := range Tests {
+		e := test.event
+		populate(&e)
+		if e.CorrelationID != test.correlation {
+			t.Errorf("%d: expected correlation ID %q, got %q", i, test.correlation, e.CorrelationID)
+		}
+		if e.ID == "" || ids[e.ID] {
+			t.Errorf("%d: expected a unique ID, got %q", i, e.ID)
+		}
+		ids[e.ID] = true
+	}
+}
+
+func TestPopulateAPIVersion(t *testing.T) {
+	for _, e := range []event.Event{{APIVersion: "apps/v1"}, {ApiVersion: "apps/v1"}} {
+		populate(&e)
+		if e.APIVersion != "apps/v1" || e.ApiVersion != "apps/v1" {
+			t.Errorf("populate() set API versions %q and deprecated %q, expected both to be apps/v1", e.APIVersion, e.ApiVersion)
+		}
+	}
+}
+
+// TestOldObjPropagation checks updates of every built-in kind carry the previous object,
+// which filters and diffs depend on
+func TestOldObjPropagation(t *testing.T) {
+	for _, res := range resources {
+		if res.name == helmReleaseKind {
+			// Helm release Secrets are reported as release changes, see TestHelmReleaseEvent
+			continue
+		}
+		t.Run(res.name+" "+res.apiVersion, func(t *testing.T) {
+			gvr := res.groupVersionResource()
+			obj := res.object.DeepCopyObject()
+			object, err := meta.Accessor(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			object.SetName("kubewatch")
+			if res.namespaced {
+				object.SetNamespace("default")
+			}
+
+			client := fake.NewSimpleClientset()
+			if err := client.Tracker().Create(gvr, obj, object.GetNamespace()); err != nil {
+				t.Fatal(err)
+			}
+			r := &recorder{}
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			w := newClusterWatcher(&config.Config{StartupInventory: true}, r, Cluster{KubeClient: client}, nil, stopCh)
+			w.startResource(res, "", stopCh)
+			r.waitFor(t, "Created kubewatch")
+
+			updated := obj.DeepCopyObject()
+			updatedObject, _ := meta.Accessor(updated)
+			updatedObject.SetLabels(map[string]string{"version": "2"})
+			if err := client.Tracker().Update(gvr, updated, object.GetNamespace()); err != nil {
+				t.Fatal(err)
+			}
+			r.waitFor(t, "Created kubewatch", "Updated kubewatch")
+
+			e := r.events[1]
+			old, err := meta.Accessor(e.OldObj)
+			if err != nil {
+				t.Fatalf("expected the previous object, got %v", e.OldObj)
+			}
+			if old.GetLabels() != nil {
+				t.Errorf("expected the previous labels, got %v", old.GetLabels())
+			}
+			if len(e.Diff) != 1 || e.Diff[0].Path != "metadata.labels.version" {
+				t.Errorf("expected the label change, got %v", e.Diff)
+			}
+		})
+	}
+}
+
+func TestCustomResourceOldObj(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "kubewatch", "namespace": "default"},
+		"spec":       map[string]interface{}{"size": int64(1)},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "WidgetList"}, widget)
+
+	r := &recorder{}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	w := newClusterWatcher(&config.Config{StartupInventory: true}, r, Cluster{DynamicClient: client}, nil, stopCh)
+	w.startCustomResource(config.CRD{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}, false, "", stopCh)
+	r.waitFor(t, "Created kubewatch")
+
+	updated := widget.DeepCopy()
+	if err := unstructured.SetNestedField(updated.Object, int64(2), "spec", "size"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Resource(gvr).Namespace("default").Update(context.Background(), updated, meta_v1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	r.waitFor(t, "Created kubewatch", "Updated kubewatch")
+
+	old, ok := r.events[1].OldObj.(*unstructured.Unstructured)
+	if !ok {
+		t.Fatalf("expected the previous object, got %v", r.events[1].OldObj)
+	}
+	if size, _, _ := unstructured.NestedInt64(old.Object, "spec", "size"); size != 1 {
+		t.Errorf("expected the previous size, got %d", size)
+	}
+}
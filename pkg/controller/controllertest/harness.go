@@ -0,0 +1,223 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controllertest runs the controller against fake clientsets, for tests covering
+// the whole path of events: watched objects, the filters of the controller, and the
+// handlers, ending with a Recorder of the delivered events.
+package controllertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// timeout is how long the harness waits for the controller to start and for deliveries
+const timeout = 5 * time.Second
+
+// Harness runs the controller against a fake clientset until the test ends
+type Harness struct {
+	t      testing.TB
+	Client *fake.Clientset
+}
+
+// Start runs the controller with conf against cluster, sending the events to handler, and
+// waits for its informer caches to be synced. A fake clientset is used when the cluster
+// has no KubeClient, the harness then creates and updates objects through it. The
+// controller is stopped, and its queued events handled, when the test ends.
+func Start(t testing.TB, conf *config.Config, handler handlers.Handler, cluster controller.Cluster) *Harness {
+	t.Helper()
+	h := &Harness{t: t}
+	if cluster.KubeClient == nil {
+		h.Client = fake.NewSimpleClientset()
+		cluster.KubeClient = h.Client
+	}
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		controller.Run(conf, handler, []controller.Cluster{cluster}, func(<-chan struct{}, func(*config.Config, error)) {}, stopCh)
+	}()
+	t.Cleanup(func() {
+		close(stopCh)
+		<-done
+	})
+
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, timeout, true, func(context.Context) (bool, error) {
+		return controller.Ready() == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("controller not ready: %v", controller.Ready())
+	}
+	return h
+}
+
+// Create creates obj, created now unless it has a creation time, so that it is notified as
+// created after the controller started
+func (h *Harness) Create(obj runtime.Object) {
+	h.t.Helper()
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if created := m.GetCreationTimestamp(); created.IsZero() {
+		m.SetCreationTimestamp(meta_v1.NewTime(time.Now().Add(time.Second)))
+	}
+	if err := h.Client.Tracker().Create(h.resource(obj), obj, m.GetNamespace()); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// Update updates obj
+func (h *Harness) Update(obj runtime.Object) {
+	h.t.Helper()
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if err := h.Client.Tracker().Update(h.resource(obj), obj, m.GetNamespace()); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// Delete deletes obj
+func (h *Harness) Delete(obj runtime.Object) {
+	h.t.Helper()
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if err := h.Client.Tracker().Delete(h.resource(obj), m.GetNamespace(), m.GetName()); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// resource returns the resource of the kind of obj
+func (h *Harness) resource(obj runtime.Object) schema.GroupVersionResource {
+	h.t.Helper()
+	kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	resource, _ := meta.UnsafeGuessKindToResource(kinds[0])
+	return resource
+}
+
+// Recorder is a handler recording the events delivered to it. The first Failures
+// deliveries fail, for tests of retries.
+type Recorder struct {
+	Failures int
+
+	mu       sync.Mutex
+	events   []event.Event
+	attempts int
+}
+
+// Init implements handlers.Handler
+func (r *Recorder) Init(c *config.Config) error {
+	return nil
+}
+
+// Handle records e
+func (r *Recorder) Handle(e event.Event) {
+	if err := r.HandleWithError(e); err != nil {
+		logging.HandlerLogger("recorder", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError records e, or fails while failures remain
+func (r *Recorder) HandleWithError(e event.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.Failures > 0 {
+		r.Failures--
+		return errors.New("delivery failed")
+	}
+	r.events = append(r.events, e)
+	return nil
+}
+
+// Events returns the recorded events
+func (r *Recorder) Events() []event.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]event.Event(nil), r.events...)
+}
+
+// Attempts returns the number of deliveries, including the failed ones
+func (r *Recorder) Attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.attempts
+}
+
+// Describe renders e as "Reason Kind namespace/name", the way tests expect events
+func Describe(e event.Event) string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s %s", e.Reason, e.Kind, name)
+}
+
+// descriptions returns the recorded events as rendered by Describe
+func (r *Recorder) descriptions() []string {
+	var descriptions []string
+	for _, e := range r.Events() {
+		descriptions = append(descriptions, Describe(e))
+	}
+	return descriptions
+}
+
+// Expect waits for the events described by expected, in order, failing the test when
+// other events are recorded or the expected ones are not within the timeout
+func (r *Recorder) Expect(t testing.TB, expected ...string) {
+	t.Helper()
+	_ = wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, timeout, true, func(context.Context) (bool, error) {
+		return len(r.Events()) >= len(expected), nil
+	})
+	if got := r.descriptions(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected events %q, got %q", expected, got)
+	}
+}
+
+// ExpectNone fails the test when events are recorded within d
+func (r *Recorder) ExpectNone(t testing.TB, d time.Duration) {
+	t.Helper()
+	time.Sleep(d)
+	if got := r.descriptions(); len(got) > 0 {
+		t.Fatalf("expected no events, got %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllertest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func pod(namespace, name string) *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       api_v1.PodSpec{Containers: []api_v1.Container{{Name: "web", Image: "nginx:1.27"}}},
+	}
+}
+
+func TestPodLifecycle(t *testing.T) {
+	conf := &config.Config{}
+	conf.Resource.Pod = true
+	r := &Recorder{}
+	h := Start(t, conf, r, controller.Cluster{})
+
+	p := pod("default", "web")
+	h.Create(p)
+	r.Expect(t, "Created Pod default/web")
+
+	p = p.DeepCopy()
+	p.Labels = map[string]string{"app": "web"}
+	h.Update(p)
+	r.Expect(t, "Created Pod default/web", "Updated Pod default/web")
+
+	h.Delete(p)
+	r.Expect(t, "Created Pod default/web", "Updated Pod default/web", "Deleted Pod default/web")
+}
+
+func TestNamespaces(t *testing.T) {
+	conf := &config.Config{Namespaces: []string{"watched"}}
+	conf.Resource.Pod = true
+	r := &Recorder{}
+	h := Start(t, conf, r, controller.Cluster{})
+
+	h.Create(pod("ignored", "web"))
+	h.Create(pod("watched", "web"))
+	r.Expect(t, "Created Pod watched/web")
+}
+
+func TestHandlerChain(t *testing.T) {
+	transforms, err := handlers.ParseTransforms([]config.Transform{{Field: "name", Template: `{{upper .Name}}`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Recorder{}
+	handler := &handlers.Transformed{
+		Transforms: transforms,
+		Handler:    handlers.NewFiltered("recorder", config.HandlerFilter{Operations: []string{"create"}}, r),
+	}
+
+	conf := &config.Config{}
+	conf.Resource.Pod = true
+	h := Start(t, conf, handler, controller.Cluster{})
+
+	p := pod("default", "web")
+	h.Create(p)
+	r.Expect(t, "Created Pod default/WEB")
+	h.Delete(p)
+	h.Create(pod("default", "api"))
+	r.Expect(t, "Created Pod default/WEB", "Created Pod default/API")
+}
+
+func TestRetries(t *testing.T) {
+	conf := &config.Config{}
+	conf.Resource.Pod = true
+	r := &Recorder{Failures: 2}
+	h := Start(t, conf, r, controller.Cluster{})
+
+	h.Create(pod("default", "web"))
+	r.Expect(t, "Created Pod default/web")
+	if attempts := r.Attempts(); attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestNoEventsForExistingObjects(t *testing.T) {
+	existing := pod("default", "existing")
+	existing.CreationTimestamp = meta_v1.NewTime(time.Now().Add(-time.Hour))
+	conf := &config.Config{}
+	conf.Resource.Pod = true
+	r := &Recorder{}
+	Start(t, conf, r, controller.Cluster{KubeClient: fake.NewSimpleClientset(existing)})
+
+	r.ExpectNone(t, 200*time.Millisecond)
+}
+
+// blockingHandler blocks deliveries until their context is done, counting them
+type blockingHandler struct {
+	timeouts atomic.Int32
+}
+
+func (b *blockingHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (b *blockingHandler) HandleContext(ctx context.Context, e event.Event) error {
+	<-ctx.Done()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		b.timeouts.Add(1)
+	}
+	return ctx.Err()
+}
+
+func TestDeliveryTimeout(t *testing.T) {
+	conf := &config.Config{Queue: config.Queue{DeliveryTimeout: 20 * time.Millisecond, MaxRetries: 1}}
+	conf.Resource.Pod = true
+	b := &blockingHandler{}
+	h := Start(t, conf, handlers.FromContext(b), controller.Cluster{})
+
+	h.Create(pod("default", "web"))
+	deadline := time.Now().Add(timeout)
+	for b.timeouts.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if timeouts := b.timeouts.Load(); timeouts != 2 {
+		t.Errorf("expected the delivery and its retry to time out, got %d timeouts", timeouts)
+	}
+}
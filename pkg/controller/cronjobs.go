@@ -0,0 +1,66 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	batch_v1 "k8s.io/api/batch/v1"
+)
+
+// cronJobSuspendEvent returns the CronJobSuspended or CronJobResumed event about a CronJob
+// update toggling spec.suspend, nil for other events. The field manager owning
+// spec.suspend tells who made the change.
+func cronJobSuspendEvent(e Event) *event.Event {
+	if e.eventType != "update" {
+		return nil
+	}
+	oldCronJob, ok := e.oldObj.(*batch_v1.CronJob)
+	if !ok {
+		return nil
+	}
+	cronJob, ok := e.obj.(*batch_v1.CronJob)
+	if !ok {
+		return nil
+	}
+	suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+	if suspended == (oldCronJob.Spec.Suspend != nil && *oldCronJob.Spec.Suspend) {
+		return nil
+	}
+
+	by := "an update"
+	if entry := specFieldManager(cronJob.GetManagedFields(), "f:suspend"); entry != nil {
+		by += " from " + entry.Manager
+	}
+	kbEvent := &event.Event{
+		Name:       e.key,
+		Namespace:  e.namespace,
+		Kind:       "CronJobResumed",
+		APIVersion: e.apiVersion,
+		Status:     "Normal",
+		Reason:     fmt.Sprintf("CronJob %s resumed by %s, next runs follow its schedule %s", cronJob.Name, by, cronJob.Spec.Schedule),
+		Obj:        e.obj,
+		OldObj:     e.oldObj,
+	}
+	if suspended {
+		kbEvent.Kind = "CronJobSuspended"
+		kbEvent.Status = "Warning"
+		kbEvent.Reason = fmt.Sprintf("CronJob %s suspended by %s, scheduled runs are skipped until it is resumed", cronJob.Name, by)
+	}
+	return kbEvent
+}
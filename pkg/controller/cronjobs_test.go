@@ -0,0 +1,64 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCronJobSuspendEvent(t *testing.T) {
+	cronJob := func(suspend *bool, manager string) *batch_v1.CronJob {
+		cj := &batch_v1.CronJob{ObjectMeta: meta_v1.ObjectMeta{Name: "nightly", Namespace: "batch"}}
+		cj.Spec.Schedule = "0 2 * * *"
+		cj.Spec.Suspend = suspend
+		if manager != "" {
+			cj.ManagedFields = []meta_v1.ManagedFieldsEntry{{Manager: manager, Operation: meta_v1.ManagedFieldsOperationUpdate,
+				FieldsV1: &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:suspend":{}}}`)}}}
+		}
+		return cj
+	}
+	yes, no := true, false
+
+	var Tests = []struct {
+		old, new *batch_v1.CronJob
+		kind     string
+		reason   string
+	}{
+		{cronJob(nil, ""), cronJob(&yes, "kubectl-patch"), "CronJobSuspended",
+			"CronJob nightly suspended by an update from kubectl-patch, scheduled runs are skipped until it is resumed"},
+		{cronJob(&yes, ""), cronJob(&no, ""), "CronJobResumed",
+			"CronJob nightly resumed by an update, next runs follow its schedule 0 2 * * *"},
+		{cronJob(nil, ""), cronJob(&no, ""), "", ""},
+		{cronJob(&yes, ""), cronJob(&yes, ""), "", ""},
+	}
+
+	for i, test := range Tests {
+		e := cronJobSuspendEvent(Event{eventType: "update", key: "nightly", namespace: "batch", oldObj: test.old, obj: test.new})
+		if test.kind == "" {
+			if e != nil {
+				t.Errorf("%d: expected no event, got %+v", i, e)
+			}
+			continue
+		}
+		if e == nil || e.Kind != test.kind || e.Reason != test.reason {
+			t.Errorf("%d: unexpected event %+v", i, e)
+		}
+	}
+}
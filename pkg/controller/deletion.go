@@ -0,0 +1,122 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// deletedByAnnotation records who deleted an object. Admission webhooks, audit pipelines
+// or deletion tooling can set it before deleting objects that have finalizers.
+const deletedByAnnotation = "kubewatch.io/deleted-by"
+
+// changedByAnnotation records who made the last change of an object, set by admission
+// webhooks or audit pipelines
+const changedByAnnotation = "kubewatch.io/changed-by"
+
+// finalState unwraps the last known state of objects whose deletion was missed while
+// the watch was interrupted, and reports whether obj was such a tombstone
+func finalState(obj interface{}) (interface{}, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj, true
+	}
+	return obj, false
+}
+
+// deletion describes the deletion of the object of e from its last known state,
+// nil when nothing is known
+func deletion(e Event) *event.Deletion {
+	d := &event.Deletion{FinalStateUnknown: e.finalStateUnknown}
+	if object, err := meta.Accessor(e.obj); err == nil {
+		d.DeletedBy = object.GetAnnotations()[deletedByAnnotation]
+		d.LastManager = lastManager(object.GetManagedFields())
+		d.Finalizers = object.GetFinalizers()
+	}
+	if d.DeletedBy == "" && d.LastManager == "" && d.Finalizers == nil && !d.FinalStateUnknown {
+		return nil
+	}
+	return d
+}
+
+// changedBy returns who made the update from old to new: the changed-by annotation when
+// set, else the field manager of the change, kubectl when the update changed the
+// last-applied configuration, empty when unknown
+func changedBy(old, new interface{}) string {
+	object, err := meta.Accessor(new)
+	if err != nil {
+		return ""
+	}
+	if user := object.GetAnnotations()[changedByAnnotation]; user != "" {
+		return user
+	}
+	oldObject, err := meta.Accessor(old)
+	if err != nil {
+		return lastManager(object.GetManagedFields())
+	}
+	if manager := changeManager(oldObject.GetManagedFields(), object.GetManagedFields()); manager != "" {
+		return manager
+	}
+	if applied := object.GetAnnotations()[lastAppliedAnnotation]; applied != "" && applied != oldObject.GetAnnotations()[lastAppliedAnnotation] {
+		return "kubectl"
+	}
+	return ""
+}
+
+// changeManager returns the field manager of the entry an update added or refreshed,
+// preferring changes to the object over changes to its status, empty if none
+func changeManager(old, new []meta_v1.ManagedFieldsEntry) string {
+	previous := map[string]*meta_v1.Time{}
+	for _, entry := range old {
+		previous[entry.Manager+"/"+string(entry.Operation)+"/"+entry.Subresource] = entry.Time
+	}
+	var manager, statusManager string
+	var last, statusLast *meta_v1.Time
+	for _, entry := range new {
+		if entry.Time == nil {
+			continue
+		}
+		if t, ok := previous[entry.Manager+"/"+string(entry.Operation)+"/"+entry.Subresource]; ok && t != nil && !t.Before(entry.Time) {
+			continue
+		}
+		if entry.Subresource == "status" {
+			if statusLast == nil || !entry.Time.Before(statusLast) {
+				statusManager, statusLast = entry.Manager, entry.Time
+			}
+		} else if last == nil || !entry.Time.Before(last) {
+			manager, last = entry.Manager, entry.Time
+		}
+	}
+	if manager == "" {
+		return statusManager
+	}
+	return manager
+}
+
+// lastManager returns the field manager of the most recent change of an object
+func lastManager(entries []meta_v1.ManagedFieldsEntry) string {
+	var manager string
+	var last *meta_v1.Time
+	for i := range entries {
+		if t := entries[i].Time; t != nil && (last == nil || !t.Before(last)) {
+			manager, last = entries[i].Manager, t
+		}
+	}
+	return manager
+}
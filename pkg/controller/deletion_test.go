@@ -0,0 +1,129 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestFinalState(t *testing.T) {
+	pod := newPod("web", time.Now())
+
+	obj, unknown := finalState(cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod})
+	if obj != pod || !unknown {
+		t.Errorf("finalState(tombstone) = %v, %t, expected the last known pod", obj, unknown)
+	}
+	obj, unknown = finalState(pod)
+	if obj != pod || unknown {
+		t.Errorf("finalState(pod) = %v, %t, expected the pod", obj, unknown)
+	}
+}
+
+func TestDeletion(t *testing.T) {
+	earlier, later := meta_v1.NewTime(time.Now().Add(-time.Hour)), meta_v1.NewTime(time.Now())
+	annotated := newPod("web", time.Now())
+	annotated.Annotations = map[string]string{deletedByAnnotation: "alice"}
+	annotated.Finalizers = []string{"example.com/cleanup"}
+	annotated.ManagedFields = []meta_v1.ManagedFieldsEntry{
+		{Manager: "kubectl-edit", Time: &later},
+		{Manager: "kube-controller-manager", Time: &earlier},
+		{Manager: "kubelet"},
+	}
+
+	var Tests = []struct {
+		e        Event
+		deletion *event.Deletion
+	}{
+		{Event{obj: annotated}, &event.Deletion{DeletedBy: "alice", LastManager: "kubectl-edit", Finalizers: []string{"example.com/cleanup"}}},
+		{Event{obj: newPod("web", time.Now()), finalStateUnknown: true}, &event.Deletion{FinalStateUnknown: true}},
+		{Event{obj: newPod("web", time.Now())}, nil},
+		{Event{}, nil},
+	}
+
+	for i, test := range Tests {
+		if d := deletion(test.e); !reflect.DeepEqual(d, test.deletion) {
+			t.Errorf("%d: deletion() = %+v, expected %+v", i, d, test.deletion)
+		}
+	}
+}
+
+func TestTombstoneDelete(t *testing.T) {
+	r := &recorder{}
+	c := &Controller{
+		queue:        workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[Event]()),
+		informer:     cache.NewSharedIndexInformer(nil, &api_v1.Pod{}, 0, cache.Indexers{}),
+		eventHandler: r,
+	}
+	pod := newPod("web", time.Now())
+	pod.Labels = map[string]string{"app": "web"}
+
+	obj, unknown := finalState(cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod})
+	e := Event{key: "default/web", eventType: "delete", resourceType: "Pod", obj: obj.(*api_v1.Pod), finalStateUnknown: unknown}
+	if err := c.processItem(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.events) != 1 {
+		t.Fatalf("expected one event, got %+v", r.events)
+	}
+	deleted := r.events[0]
+	if deleted.Obj != pod || deleted.Labels["app"] != "web" {
+		t.Errorf("expected the last known pod, got %+v", deleted)
+	}
+	if deleted.Deletion == nil || !deleted.Deletion.FinalStateUnknown {
+		t.Errorf("expected the deletion to be flagged as missed, got %+v", deleted.Deletion)
+	}
+}
+
+func TestChangedBy(t *testing.T) {
+	earlier := meta_v1.NewTime(time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC))
+	later := meta_v1.NewTime(earlier.Add(time.Hour))
+	configMap := func(annotations map[string]string, entries ...meta_v1.ManagedFieldsEntry) *api_v1.ConfigMap {
+		return &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "web", Annotations: annotations, ManagedFields: entries}}
+	}
+	helm := meta_v1.ManagedFieldsEntry{Manager: "helm", Operation: meta_v1.ManagedFieldsOperationUpdate, Time: &earlier}
+	edit := meta_v1.ManagedFieldsEntry{Manager: "kubectl-edit", Operation: meta_v1.ManagedFieldsOperationUpdate, Time: &later}
+	status := meta_v1.ManagedFieldsEntry{Manager: "kubelet", Operation: meta_v1.ManagedFieldsOperationUpdate, Subresource: "status", Time: &later}
+	helmLater := helm
+	helmLater.Time = &later
+
+	var Tests = []struct {
+		old, new  *api_v1.ConfigMap
+		changedBy string
+	}{
+		{configMap(nil, helm), configMap(nil, helm, edit), "kubectl-edit"},
+		{configMap(nil, helm, edit), configMap(nil, helmLater, edit, status), "helm"},
+		{configMap(nil, helm), configMap(nil, helm, status), "kubelet"},
+		{configMap(nil, helm), configMap(map[string]string{changedByAnnotation: "alice@example.com"}, helm, edit), "alice@example.com"},
+		{configMap(map[string]string{lastAppliedAnnotation: "{}"}), configMap(map[string]string{lastAppliedAnnotation: `{"data":{}}`}), "kubectl"},
+		{configMap(nil, helm), configMap(nil, helm), ""},
+	}
+
+	for i, test := range Tests {
+		if changedBy := changedBy(test.old, test.new); changedBy != test.changedBy {
+			t.Errorf("%d: changedBy() = %q, expected %q", i, changedBy, test.changedBy)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// deprecationStartDelay leaves time for informer caches to sync before the first check
+const deprecationStartDelay = time.Minute
+
+// deprecatedAPIsMetric is the API server metric set for the deprecated APIs requested by clients
+const deprecatedAPIsMetric = "apiserver_requested_deprecated_apis"
+
+// maxKubeletSkew is the number of minor versions kubelets may be older than the API server
+const maxKubeletSkew = 3
+
+// metricLabel matches the labels of a Prometheus text format sample
+var metricLabel = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// removedAPI is a Kubernetes API version removed in a minor release
+type removedAPI struct {
+	// removedIn is the minor version the API is removed in
+	removedIn int
+	// replacement is the API version to migrate to, empty if the kind is removed
+	replacement string
+}
+
+// removedAPIs holds the removed versions of built-in kinds, by apiVersion/kind
+var removedAPIs = map[string]removedAPI{
+	"extensions/v1beta1/DaemonSet":         {16, "apps/v1"},
+	"extensions/v1beta1/Deployment":        {16, "apps/v1"},
+	"extensions/v1beta1/ReplicaSet":        {16, "apps/v1"},
+	"extensions/v1beta1/NetworkPolicy":     {16, "networking.k8s.io/v1"},
+	"extensions/v1beta1/PodSecurityPolicy": {16, "policy/v1beta1"},
+	"apps/v1beta1/Deployment":              {16, "apps/v1"},
+	"apps/v1beta1/StatefulSet":             {16, "apps/v1"},
+	"apps/v1beta2/DaemonSet":               {16, "apps/v1"},
+	"apps/v1beta2/Deployment":              {16, "apps/v1"},
+	"apps/v1beta2/ReplicaSet":              {16, "apps/v1"},
+	"apps/v1beta2/StatefulSet":             {16, "apps/v1"},
+
+	"extensions/v1beta1/Ingress":                                            {22, "networking.k8s.io/v1"},
+	"networking.k8s.io/v1beta1/Ingress":                                     {22, "networking.k8s.io/v1"},
+	"networking.k8s.io/v1beta1/IngressClass":                                {22, "networking.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1/ClusterRole":                         {22, "rbac.authorization.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1/ClusterRoleBinding":                  {22, "rbac.authorization.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1/Role":                                {22, "rbac.authorization.k8s.io/v1"},
+	"rbac.authorization.k8s.io/v1beta1/RoleBinding":                         {22, "rbac.authorization.k8s.io/v1"},
+	"apiextensions.k8s.io/v1beta1/CustomResourceDefinition":                 {22, "apiextensions.k8s.io/v1"},
+	"apiregistration.k8s.io/v1beta1/APIService":                             {22, "apiregistration.k8s.io/v1"},
+	"admissionregistration.k8s.io/v1beta1/MutatingWebhookConfiguration":     {22, "admissionregistration.k8s.io/v1"},
+	"admissionregistration.k8s.io/v1beta1/ValidatingWebhookConfiguration":   {22, "admissionregistration.k8s.io/v1"},
+	"certificates.k8s.io/v1beta1/CertificateSigningRequest":                 {22, "certificates.k8s.io/v1"},
+	"coordination.k8s.io/v1beta1/Lease":                                     {22, "coordination.k8s.io/v1"},
+	"scheduling.k8s.io/v1beta1/PriorityClass":                               {22, "scheduling.k8s.io/v1"},
+	"storage.k8s.io/v1beta1/CSIDriver":                                      {22, "storage.k8s.io/v1"},
+	"storage.k8s.io/v1beta1/CSINode":                                        {22, "storage.k8s.io/v1"},
+	"storage.k8s.io/v1beta1/StorageClass":                                   {22, "storage.k8s.io/v1"},
+	"storage.k8s.io/v1beta1/VolumeAttachment":                               {22, "storage.k8s.io/v1"},
+	"batch/v1beta1/CronJob":                                                 {25, "batch/v1"},
+	"discovery.k8s.io/v1beta1/EndpointSlice":                                {25, "discovery.k8s.io/v1"},
+	"events.k8s.io/v1beta1/Event":                                           {25, "events.k8s.io/v1"},
+	"autoscaling/v2beta1/HorizontalPodAutoscaler":                           {25, "autoscaling/v2"},
+	"policy/v1beta1/PodDisruptionBudget":                                    {25, "policy/v1"},
+	"policy/v1beta1/PodSecurityPolicy":                                      {25, ""},
+	"node.k8s.io/v1beta1/RuntimeClass":                                      {25, "node.k8s.io/v1"},
+	"autoscaling/v2beta2/HorizontalPodAutoscaler":                           {26, "autoscaling/v2"},
+	"flowcontrol.apiserver.k8s.io/v1beta1/FlowSchema":                       {26, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta1/PriorityLevelConfiguration":       {26, "flowcontrol.apiserver.k8s.io/v1"},
+	"storage.k8s.io/v1beta1/CSIStorageCapacity":                             {27, "storage.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta2/FlowSchema":                       {29, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta2/PriorityLevelConfiguration":       {29, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta3/FlowSchema":                       {32, "flowcontrol.apiserver.k8s.io/v1"},
+	"flowcontrol.apiserver.k8s.io/v1beta3/PriorityLevelConfiguration":       {32, "flowcontrol.apiserver.k8s.io/v1"},
+	"admissionregistration.k8s.io/v1beta1/ValidatingAdmissionPolicy":        {32, "admissionregistration.k8s.io/v1"},
+	"admissionregistration.k8s.io/v1beta1/ValidatingAdmissionPolicyBinding": {32, "admissionregistration.k8s.io/v1"},
+}
+
+// deprecationChecker reports the deprecated APIs used in a cluster and the kubelets outside
+// of the supported version skew. Each finding is reported once.
+type deprecationChecker struct {
+	w        *clusterWatcher
+	logger   *logrus.Entry
+	notified map[string]bool
+	// metricsDenied is set once reading the API server metrics failed, to log it once
+	metricsDenied bool
+}
+
+// checkDeprecations runs the deprecation checks every interval until stopCh is closed
+func (w *clusterWatcher) checkDeprecations(interval time.Duration, stopCh <-chan struct{}) {
+	d := &deprecationChecker{w: w, logger: logrus.WithField("pkg", "kubewatch-deprecations"), notified: map[string]bool{}}
+	if w.clusterName != "" {
+		d.logger = d.logger.WithField("cluster", w.clusterName)
+	}
+	select {
+	case <-time.After(deprecationStartDelay):
+	case <-stopCh:
+		return
+	}
+	wait.Until(d.check, interval, stopCh)
+}
+
+func (d *deprecationChecker) check() {
+	version, err := d.w.kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		d.logger.Warnf("Skipping deprecation checks, cannot read the cluster version: %v", err)
+		return
+	}
+	minor, ok := minorVersion(version.Minor)
+	if !ok {
+		d.logger.Warnf("Skipping deprecation checks, unexpected cluster version %s", version.GitVersion)
+		return
+	}
+
+	var events []event.Event
+	events = append(events, d.objectEvents(minor)...)
+	events = append(events, d.requestedAPIEvents(minor)...)
+	events = append(events, d.kubeletEvents(minor)...)
+	for _, e := range events {
+		d.w.notify(e)
+	}
+}
+
+// objectEvents reports the cached objects last applied with an API removed in the next
+// minor version or before
+func (d *deprecationChecker) objectEvents(minor int) []event.Event {
+	var events []event.Event
+	for _, item := range d.w.objects.all() {
+		object, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		applied := object.GetAnnotations()[lastAppliedAnnotation]
+		if applied == "" {
+			continue
+		}
+		var typeMeta struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := json.Unmarshal([]byte(applied), &typeMeta); err != nil {
+			continue
+		}
+		removed, ok := removedAPIs[typeMeta.APIVersion+"/"+typeMeta.Kind]
+		if !ok || removed.removedIn > minor+1 {
+			continue
+		}
+		key := "object/" + string(object.GetUID()) + "/" + typeMeta.APIVersion
+		if d.notified[key] {
+			continue
+		}
+		d.notified[key] = true
+
+		obj, _ := item.(runtime.Object)
+		events = append(events, event.Event{
+			Name:       object.GetName(),
+			Namespace:  object.GetNamespace(),
+			Kind:       "DeprecatedAPI",
+			APIVersion: typeMeta.APIVersion,
+			Status:     removalStatus(removed.removedIn, minor),
+			Reason: fmt.Sprintf("%s %s was last applied as %s, %s",
+				typeMeta.Kind, object.GetName(), typeMeta.APIVersion, removal(removed, minor)),
+			Obj: obj,
+		})
+	}
+	return events
+}
+
+// requestedAPIEvents reports the deprecated APIs requested by clients since the API server
+// started, removed in the next minor version or before, read from the API server metrics
+func (d *deprecationChecker) requestedAPIEvents(minor int) []event.Event {
+	client := d.w.kubeClient.Discovery().RESTClient()
+	if client == nil {
+		return nil
+	}
+	data, err := client.Get().AbsPath("/metrics").DoRaw(context.Background())
+	if err != nil {
+		if !d.metricsDenied {
+			d.logger.Warnf("Cannot read the API server metrics, deprecated APIs requested by clients will not be reported: %v", err)
+			d.metricsDenied = true
+		}
+		return nil
+	}
+
+	var events []event.Event
+	for _, labels := range deprecatedAPISamples(data) {
+		removedIn, ok := minorVersion(strings.TrimPrefix(labels["removed_release"], "1."))
+		if !ok || removedIn > minor+1 {
+			continue
+		}
+		apiVersion := labels["version"]
+		if labels["group"] != "" {
+			apiVersion = labels["group"] + "/" + apiVersion
+		}
+		resource := labels["resource"]
+		if labels["subresource"] != "" {
+			resource += "/" + labels["subresource"]
+		}
+		key := "request/" + apiVersion + "/" + resource
+		if d.notified[key] {
+			continue
+		}
+		d.notified[key] = true
+
+		events = append(events, event.Event{
+			Name:       resource,
+			Kind:       "DeprecatedAPI",
+			APIVersion: apiVersion,
+			Status:     removalStatus(removedIn, minor),
+			Reason: fmt.Sprintf("%s %s was requested by a client since the API server started, %s",
+				apiVersion, resource, removal(removedAPI{removedIn: removedIn}, minor)),
+		})
+	}
+	return events
+}
+
+// deprecatedAPISamples returns the labels of the samples of the deprecated APIs metric
+func deprecatedAPISamples(data []byte) []map[string]string {
+	var samples []map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, deprecatedAPIsMetric+"{") {
+			continue
+		}
+		labels := map[string]string{}
+		for _, match := range metricLabel.FindAllStringSubmatch(line[:strings.LastIndex(line, "}")+1], -1) {
+			labels[match[1]] = match[2]
+		}
+		samples = append(samples, labels)
+	}
+	return samples
+}
+
+// kubeletEvents reports the cached nodes whose kubelet is newer than the API server or more
+// than maxKubeletSkew minor versions older
+func (d *deprecationChecker) kubeletEvents(minor int) []event.Event {
+	var events []event.Event
+	for _, item := range d.w.objects.list(objName(api_v1.Node{})) {
+		node, ok := item.(*api_v1.Node)
+		if !ok {
+			continue
+		}
+		version := node.Status.NodeInfo.KubeletVersion
+		parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		kubelet, ok := minorVersion(parts[1])
+		if !ok || kubelet <= minor && kubelet >= minor-maxKubeletSkew {
+			continue
+		}
+		key := "kubelet/" + string(node.UID) + "/" + version
+		if d.notified[key] {
+			continue
+		}
+		d.notified[key] = true
+
+		skew := fmt.Sprintf("more than %d minor versions older than the API server 1.%d", maxKubeletSkew, minor)
+		if kubelet > minor {
+			skew = fmt.Sprintf("newer than the API server 1.%d", minor)
+		}
+		events = append(events, event.Event{
+			Name:       node.Name,
+			Kind:       "VersionSkew",
+			APIVersion: V1,
+			Status:     "Warning",
+			Reason:     fmt.Sprintf("Kubelet %s of node %s is %s, which is not supported", version, node.Name, skew),
+			Obj:        node,
+		})
+	}
+	return events
+}
+
+// minorVersion parses a minor version such as "28" or "28+"
+func minorVersion(minor string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSuffix(minor, "+"))
+	return n, err == nil
+}
+
+// removal describes when api is removed relative to the cluster minor version
+func removal(api removedAPI, minor int) string {
+	var msg string
+	if api.removedIn <= minor {
+		msg = fmt.Sprintf("which was removed in Kubernetes 1.%d (cluster runs 1.%d)", api.removedIn, minor)
+	} else {
+		msg = fmt.Sprintf("which is removed in Kubernetes 1.%d (cluster runs 1.%d)", api.removedIn, minor)
+	}
+	if api.replacement != "" {
+		msg += ", use " + api.replacement
+	}
+	return msg
+}
+
+// removalStatus is Danger for APIs already removed, which break the next apply, and Warning
+// for APIs removed in the next minor version
+func removalStatus(removedIn int, minor int) string {
+	if removedIn <= minor {
+		return "Danger"
+	}
+	return "Warning"
+}
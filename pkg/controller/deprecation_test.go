@@ -0,0 +1,97 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDeprecatedAPISamples(t *testing.T) {
+	metrics := `# HELP apiserver_requested_deprecated_apis [STABLE] Gauge of deprecated APIs that have been requested, broken out by API group, version, resource, subresource, and removed_release.
+# TYPE apiserver_requested_deprecated_apis gauge
+apiserver_requested_deprecated_apis{group="batch",removed_release="1.25",resource="cronjobs",subresource="",version="v1beta1"} 1
+apiserver_requested_deprecated_apis{group="",removed_release="",resource="componentstatuses",subresource="",version="v1"} 1
+apiserver_request_total{code="200",resource="pods",verb="LIST",version="v1"} 42
+`
+	expected := []map[string]string{
+		{"group": "batch", "removed_release": "1.25", "resource": "cronjobs", "subresource": "", "version": "v1beta1"},
+		{"group": "", "removed_release": "", "resource": "componentstatuses", "subresource": "", "version": "v1"},
+	}
+	if samples := deprecatedAPISamples([]byte(metrics)); !reflect.DeepEqual(samples, expected) {
+		t.Errorf("expected %v, got %v", expected, samples)
+	}
+}
+
+func TestCheckDeprecations(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{Major: "1", Minor: "24+", GitVersion: "v1.24.3-eks"}
+
+	r := &recorder{}
+	w := newClusterWatcher(&config.Config{}, r, Cluster{KubeClient: client}, nil, nil)
+	objects := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	applied := func(name, apiVersion, kind string) *api_v1.ConfigMap {
+		return &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "jobs", UID: types.UID(name),
+			Annotations: map[string]string{lastAppliedAnnotation: `{"apiVersion":"` + apiVersion + `","kind":"` + kind + `"}`}}}
+	}
+	objects.Add(applied("nightly", "batch/v1beta1", "CronJob"))
+	objects.Add(applied("hpa", "autoscaling/v2beta2", "HorizontalPodAutoscaler"))
+	objects.Add(applied("web", "networking.k8s.io/v1beta1", "Ingress"))
+	objects.Add(applied("api", "apps/v1", "Deployment"))
+	w.objects.add("ConfigMap", objects, nil)
+
+	nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	node := func(name, kubelet string) *api_v1.Node {
+		return &api_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: name, UID: types.UID(name)},
+			Status: api_v1.NodeStatus{NodeInfo: api_v1.NodeSystemInfo{KubeletVersion: kubelet}}}
+	}
+	nodes.Add(node("node-a", "v1.24.3-eks"))
+	nodes.Add(node("node-b", "v1.20.1"))
+	nodes.Add(node("node-c", "v1.25.0"))
+	w.objects.add(objName(api_v1.Node{}), nodes, nil)
+
+	d := &deprecationChecker{w: w, logger: logrus.WithField("pkg", "test"), notified: map[string]bool{}}
+	d.check()
+	d.check()
+
+	expected := map[string]string{
+		"nightly": "Warning CronJob nightly was last applied as batch/v1beta1, which is removed in Kubernetes 1.25 (cluster runs 1.24), use batch/v1",
+		"web":     "Danger Ingress web was last applied as networking.k8s.io/v1beta1, which was removed in Kubernetes 1.22 (cluster runs 1.24), use networking.k8s.io/v1",
+		"node-b":  "Warning Kubelet v1.20.1 of node node-b is more than 3 minor versions older than the API server 1.24, which is not supported",
+		"node-c":  "Warning Kubelet v1.25.0 of node node-c is newer than the API server 1.24, which is not supported",
+	}
+	got := map[string]string{}
+	for _, e := range r.events {
+		got[e.Name] = e.Status + " " + e.Reason
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if len(r.events) != len(expected) {
+		t.Errorf("expected findings to be reported once, got %d events", len(r.events))
+	}
+}
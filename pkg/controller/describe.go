@@ -0,0 +1,195 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// maxDescribedEvents is the number of recent Kubernetes Events in descriptions
+const maxDescribedEvents = 5
+
+// involvedUIDIndex indexes the cached Kubernetes Events by the UID of the object they are about
+const involvedUIDIndex = "involvedObject.uid"
+
+// description summarizes the object of a failing event, or the object a failing Kubernetes
+// Event is about: its owner, conditions and recent Kubernetes Events, all read from the
+// informer caches. It returns nil for events that do not signal a failure and objects with
+// nothing to describe.
+func (c *Controller) description(e *event.Event) *event.Description {
+	if e.Obj == nil || !failing(e) {
+		return nil
+	}
+
+	var uid types.UID
+	obj := e.Obj
+	if ref, ok := regarding(e.Obj); ok {
+		uid = ref.UID
+		obj = c.objects.getObject(ref.Kind, ref.Namespace, ref.Name)
+	} else if object, err := meta.Accessor(obj); err == nil {
+		uid = object.GetUID()
+	}
+
+	d := &event.Description{Events: c.recentEvents(uid)}
+	if object, err := meta.Accessor(obj); err == nil {
+		d.Owner = c.owner(object)
+		d.Conditions = conditions(obj)
+	}
+	if d.Owner == "" && d.Conditions == nil && d.Events == nil {
+		return nil
+	}
+	return d
+}
+
+// failing tells whether e signals a failure: a critical event, or a warning about a Warning
+// Kubernetes Event or an object whose status reports a failure
+func failing(e *event.Event) bool {
+	switch e.ResolvedSeverity() {
+	case event.SeverityInfo:
+		return false
+	case event.SeverityCritical:
+		return true
+	}
+	switch o := e.Obj.(type) {
+	case *api_v1.Event:
+		return o.Type == api_v1.EventTypeWarning
+	case *events_v1.Event:
+		return o.Type == api_v1.EventTypeWarning
+	}
+	return failedStatus(e.Obj)
+}
+
+// failedStatus tells whether the status of obj reports a failure: a Failed phase, a Failed
+// condition that is True or a Ready condition that is False
+func failedStatus(obj runtime.Object) bool {
+	switch o := obj.(type) {
+	case *api_v1.Pod:
+		if o.Status.Phase == api_v1.PodFailed {
+			return true
+		}
+	case *unstructured.Unstructured:
+		if phase, _, _ := unstructured.NestedString(o.Object, "status", "phase"); phase == "Failed" {
+			return true
+		}
+	}
+	for _, condition := range conditions(obj) {
+		if (condition.Type == "Failed" && condition.Status == "True") || (condition.Type == "Ready" && condition.Status == "False") {
+			return true
+		}
+	}
+	return false
+}
+
+// involvedUID is the index function of involvedUIDIndex
+func involvedUID(obj interface{}) ([]string, error) {
+	if object, ok := obj.(runtime.Object); ok {
+		if ref, ok := regarding(object); ok && ref.UID != "" {
+			return []string{string(ref.UID)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// regarding returns the reference to the object obj is about when it is a Kubernetes Event
+func regarding(obj runtime.Object) (api_v1.ObjectReference, bool) {
+	switch e := obj.(type) {
+	case *api_v1.Event:
+		return e.InvolvedObject, true
+	case *events_v1.Event:
+		return e.Regarding, true
+	}
+	return api_v1.ObjectReference{}, false
+}
+
+// conditions returns the status conditions of obj, typed or not
+func conditions(obj runtime.Object) []event.Condition {
+	var fields map[string]interface{}
+	if u, ok := obj.(runtime.Unstructured); ok {
+		fields = u.UnstructuredContent()
+	} else {
+		var err error
+		if fields, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return nil
+		}
+	}
+
+	list, _, _ := unstructured.NestedSlice(fields, "status", "conditions")
+	var conditions []event.Condition
+	for _, item := range list {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condition := event.Condition{}
+		condition.Type, _, _ = unstructured.NestedString(fields, "type")
+		condition.Status, _, _ = unstructured.NestedString(fields, "status")
+		condition.Reason, _, _ = unstructured.NestedString(fields, "reason")
+		condition.Message, _, _ = unstructured.NestedString(fields, "message")
+		if condition.Type != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions
+}
+
+// recentEvents returns the most recent cached Kubernetes Events about the object with uid,
+// newest first
+func (c *Controller) recentEvents(uid types.UID) []event.RecentEvent {
+	if uid == "" {
+		return nil
+	}
+	type recent struct {
+		event.RecentEvent
+		lastSeen time.Time
+	}
+
+	var events []recent
+	for _, obj := range c.objects.byIndex(objName(api_v1.Event{}), involvedUIDIndex, string(uid)) {
+		switch e := obj.(type) {
+		case *api_v1.Event:
+			lastSeen := e.LastTimestamp.Time
+			if lastSeen.IsZero() {
+				lastSeen = e.EventTime.Time
+			}
+			events = append(events, recent{event.RecentEvent{Type: e.Type, Reason: e.Reason, Message: e.Message, Count: e.Count}, lastSeen})
+		case *events_v1.Event:
+			lastSeen, count := e.EventTime.Time, e.DeprecatedCount
+			if e.Series != nil {
+				lastSeen, count = e.Series.LastObservedTime.Time, e.Series.Count
+			} else if lastSeen.IsZero() {
+				lastSeen = e.DeprecatedLastTimestamp.Time
+			}
+			events = append(events, recent{event.RecentEvent{Type: e.Type, Reason: e.Reason, Message: e.Note, Count: count}, lastSeen})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].lastSeen.After(events[j].lastSeen) })
+	var described []event.RecentEvent
+	for i := 0; i < len(events) && i < maxDescribedEvents; i++ {
+		described = append(described, events[i].RecentEvent)
+	}
+	return described
+}
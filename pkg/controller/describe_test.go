@@ -0,0 +1,101 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDescription(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	objects := newObjectCache()
+	store := func(kind string, objs ...interface{}) {
+		s := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{involvedUIDIndex: involvedUID})
+		for _, obj := range objs {
+			s.Add(obj)
+		}
+		objects.add(kind, s, stopCh)
+	}
+
+	now := time.Now()
+	pod := newPod("backup-28-abc", now)
+	pod.UID = "backup-28-abc-uid"
+	pod.OwnerReferences = ownedBy("Job", "backup-28")
+	pod.Status.Conditions = []api_v1.PodCondition{
+		{Type: api_v1.PodReady, Status: api_v1.ConditionFalse, Reason: "ContainersNotReady", Message: "containers with unready status: [backup]"},
+	}
+	backOff := eventAbout("Pod", "backup-28-abc")
+	backOff.InvolvedObject.UID = pod.UID
+	backOff.Name, backOff.Type, backOff.Reason, backOff.Count = "backup.2", "Warning", "BackOff", 5
+	backOff.Message = "Back-off restarting failed container"
+	backOff.LastTimestamp = meta_v1.NewTime(now)
+	pulled := eventAbout("Pod", "backup-28-abc")
+	pulled.InvolvedObject.UID = pod.UID
+	pulled.Name, pulled.Type, pulled.Reason, pulled.Count = "backup.1", "Normal", "Pulled", 1
+	pulled.LastTimestamp = meta_v1.NewTime(now.Add(-time.Minute))
+	running := newPod("web", now)
+	running.UID = "web-uid"
+	running.OwnerReferences = ownedBy("ReplicaSet", "web-7d9f")
+	failed := newPod("migrate", now)
+	failed.OwnerReferences = ownedBy("Job", "migrate")
+	failed.Status.Phase = api_v1.PodFailed
+	scheduled := eventAbout("Pod", "web")
+	scheduled.InvolvedObject.UID = running.UID
+	scheduled.Type, scheduled.Reason = "Normal", "Scheduled"
+	store("Pod", pod, newPod("debug", now), running, failed)
+	store("Event", pulled, backOff, eventAbout("Pod", "other"), scheduled)
+
+	c := &Controller{objects: objects, resourceType: "Pod"}
+	described := &event.Description{
+		Owner: "Job/backup-28",
+		Conditions: []event.Condition{
+			{Type: "Ready", Status: "False", Reason: "ContainersNotReady", Message: "containers with unready status: [backup]"},
+		},
+		Events: []event.RecentEvent{
+			{Type: "Warning", Reason: "BackOff", Message: "Back-off restarting failed container", Count: 5},
+			{Type: "Normal", Reason: "Pulled", Count: 1},
+		},
+	}
+
+	var Tests = []struct {
+		e           event.Event
+		description *event.Description
+	}{
+		{event.Event{Obj: pod, Status: "Danger"}, described},
+		{event.Event{Obj: backOff, Status: "Warning"}, described},
+		{event.Event{Obj: pod, Status: "Normal"}, nil},
+		{event.Event{Obj: running, Status: "Warning", Reason: "Updated"}, nil},
+		{event.Event{Obj: scheduled, Status: "Warning"}, nil},
+		{event.Event{Obj: failed, Status: "Warning", Reason: "Updated"}, &event.Description{Owner: "Job/migrate"}},
+		{event.Event{Obj: newPod("debug", now), Status: "Danger"}, nil},
+		{event.Event{Status: "Danger"}, nil},
+	}
+
+	for i, test := range Tests {
+		if description := c.description(&test.e); !reflect.DeepEqual(description, test.description) {
+			t.Errorf("%d: description() = %+v, expected %+v", i, description, test.description)
+		}
+	}
+}
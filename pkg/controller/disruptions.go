@@ -0,0 +1,251 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// reasons of the DisruptionTarget pod condition not defined by k8s.io/api
+	reasonEvictionByEvictionAPI  = "EvictionByEvictionAPI"
+	reasonDeletionByTaintManager = "DeletionByTaintManager"
+
+	// disruptionsExpiry is how long a disrupted pod is remembered, so that the pod update and
+	// the Kubernetes Event reporting the same disruption are only sent once
+	disruptionsExpiry = time.Hour
+)
+
+// evictedBy names what evicted a pod, by reason of its DisruptionTarget condition
+var evictedBy = map[string]string{
+	api_v1.PodReasonTerminationByKubelet: "the kubelet",
+	reasonEvictionByEvictionAPI:          "the eviction API",
+	reasonDeletionByTaintManager:         "the taint manager",
+}
+
+var (
+	// preemptedMessage is the message of the Preempted Kubernetes Events of the scheduler,
+	// naming the preemptor by UID, or by namespace and name before Kubernetes 1.26
+	preemptedMessage = regexp.MustCompile(`^Preempted by (?:pod )?(\S+) on node (\S+)`)
+	// preemptingMessage is the message of the DisruptionTarget condition of preempted pods
+	preemptingMessage = regexp.MustCompile(`^(\S+/\S+): preempting to accommodate a higher priority pod`)
+)
+
+// disruptions reports the pods evicted by the kubelet, the eviction API or the taint
+// manager, and the pods preempted by the scheduler, as PodEvicted and PodPreempted events.
+// Disruptions are found in pod updates and in Kubernetes Events, whichever comes first:
+// each pod is reported once.
+type disruptions struct {
+	w   *clusterWatcher
+	now func() time.Time
+
+	mu sync.Mutex
+	// notified holds when each disrupted pod was reported
+	notified map[types.UID]time.Time
+}
+
+// disruption is a pod eviction or preemption
+type disruption struct {
+	kind      string
+	namespace string
+	pod       string
+	uid       types.UID
+	node      string
+	// by is what evicted the pod, or the pod that preempted it
+	by      string
+	message string
+}
+
+func newDisruptions(w *clusterWatcher) *disruptions {
+	return &disruptions{w: w, now: time.Now, notified: map[types.UID]time.Time{}}
+}
+
+// disruptionEvent returns the PodEvicted or PodPreempted event about a pod update or a
+// Kubernetes Event reporting the pod was evicted or preempted, nil for other events.
+// Evictions are left to node pressure digests when they are enabled.
+func (c *Controller) disruptionEvent(e Event) *event.Event {
+	var d *disruption
+	switch obj := e.obj.(type) {
+	case *api_v1.Pod:
+		if e.eventType != "update" && e.eventType != "delete" {
+			return nil
+		}
+		oldPod, _ := e.oldObj.(*api_v1.Pod)
+		d = podDisruption(oldPod, obj)
+	case *api_v1.Event:
+		if e.eventType != "create" && e.eventType != "update" {
+			return nil
+		}
+		d = c.disruptions.eventDisruption(obj)
+	}
+	if d == nil || (d.kind == "PodEvicted" && c.digestEvictions) {
+		return nil
+	}
+	if !c.disruptions.first(d.uid) {
+		return nil
+	}
+	return &event.Event{
+		Name:       d.pod,
+		Namespace:  d.namespace,
+		Kind:       d.kind,
+		APIVersion: V1,
+		Status:     "Warning",
+		Reason:     d.reason(),
+		Obj:        e.obj,
+	}
+}
+
+// podDisruption returns the disruption of pod when it is new since oldPod, nil otherwise
+func podDisruption(oldPod, pod *api_v1.Pod) *disruption {
+	d := &disruption{namespace: pod.Namespace, pod: pod.Name, uid: pod.UID, node: pod.Spec.NodeName}
+	if condition := disruptionTarget(pod); condition != nil && (oldPod == nil || disruptionTarget(oldPod) == nil) {
+		switch {
+		case condition.Reason == api_v1.PodReasonPreemptionByScheduler:
+			d.kind = "PodPreempted"
+			if m := preemptingMessage.FindStringSubmatch(condition.Message); m != nil {
+				d.by = m[1]
+			}
+		case evictedBy[condition.Reason] != "":
+			d.kind = "PodEvicted"
+			d.by = evictedBy[condition.Reason]
+			d.message = condition.Message
+		default:
+			return nil
+		}
+		return d
+	}
+	if oldPod != nil && oldPod.Status.Reason == pod.Status.Reason {
+		return nil
+	}
+	switch pod.Status.Reason {
+	case "Evicted":
+		d.kind = "PodEvicted"
+		d.by = evictedBy[api_v1.PodReasonTerminationByKubelet]
+		d.message = pod.Status.Message
+	case "Preempted":
+		d.kind = "PodPreempted"
+	default:
+		return nil
+	}
+	return d
+}
+
+// eventDisruption returns the disruption reported by a Kubernetes Event about a pod, nil
+// for other Kubernetes Events
+func (s *disruptions) eventDisruption(ev *api_v1.Event) *disruption {
+	if ev.InvolvedObject.Kind != "Pod" {
+		return nil
+	}
+	d := &disruption{
+		namespace: ev.InvolvedObject.Namespace,
+		pod:       ev.InvolvedObject.Name,
+		uid:       ev.InvolvedObject.UID,
+		node:      ev.Source.Host,
+	}
+	switch ev.Reason {
+	case "Evicted":
+		d.kind = "PodEvicted"
+		d.by = evictedBy[api_v1.PodReasonTerminationByKubelet]
+		d.message = ev.Message
+	case "TaintManagerEviction":
+		d.kind = "PodEvicted"
+		d.by = evictedBy[reasonDeletionByTaintManager]
+	case "Preempted":
+		d.kind = "PodPreempted"
+		if m := preemptedMessage.FindStringSubmatch(ev.Message); m != nil {
+			d.by = s.preemptor(m[1])
+			d.node = m[2]
+		}
+	default:
+		return nil
+	}
+	if d.node == "" {
+		if pod, ok := s.w.objects.getObject(objName(api_v1.Pod{}), d.namespace, d.pod).(*api_v1.Pod); ok {
+			d.node = pod.Spec.NodeName
+		}
+	}
+	return d
+}
+
+// preemptor returns the namespace and name of the preempting pod, looked up in the cache of
+// the watched pods when the scheduler only named it by UID
+func (s *disruptions) preemptor(ref string) string {
+	if strings.Contains(ref, "/") {
+		return ref
+	}
+	for _, item := range s.w.objects.list(objName(api_v1.Pod{})) {
+		if pod, ok := item.(*api_v1.Pod); ok && string(pod.UID) == ref {
+			return pod.Namespace + "/" + pod.Name
+		}
+	}
+	return "with UID " + ref
+}
+
+// first returns whether the disruption of the pod of uid was not reported yet, and
+// remembers it was
+func (s *disruptions) first(uid types.UID) bool {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for notified, at := range s.notified {
+		if now.Sub(at) > disruptionsExpiry {
+			delete(s.notified, notified)
+		}
+	}
+	if _, ok := s.notified[uid]; ok && uid != "" {
+		return false
+	}
+	s.notified[uid] = now
+	return true
+}
+
+// reason renders the disruption, such as "Pod api-7d9f was preempted on node node-1 by pod
+// batch/trainer-0"
+func (d *disruption) reason() string {
+	verb, at, by := "evicted", " from node ", " by "
+	if d.kind == "PodPreempted" {
+		verb, at, by = "preempted", " on node ", " by pod "
+	}
+	reason := "Pod " + d.pod + " was " + verb
+	if d.node != "" {
+		reason += at + d.node
+	}
+	if d.by != "" {
+		reason += by + d.by
+	}
+	if d.message != "" {
+		reason += ": " + d.message
+	}
+	return reason
+}
+
+// disruptionTarget returns the DisruptionTarget condition of pod when True, nil otherwise
+func disruptionTarget(pod *api_v1.Pod) *api_v1.PodCondition {
+	for i, condition := range pod.Status.Conditions {
+		if condition.Type == api_v1.DisruptionTarget && condition.Status == api_v1.ConditionTrue {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
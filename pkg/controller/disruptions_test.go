@@ -0,0 +1,116 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDisruptions(t *testing.T) {
+	pod := func(reason string, conditions ...api_v1.PodCondition) *api_v1.Pod {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api-7d9f", Namespace: "payments", UID: "pod-uid"}}
+		p.Spec.NodeName = "node-1"
+		p.Status.Reason = reason
+		p.Status.Message = "The node was low on resource: memory."
+		p.Status.Conditions = conditions
+		return p
+	}
+	target := func(reason, message string) api_v1.PodCondition {
+		return api_v1.PodCondition{Type: api_v1.DisruptionTarget, Status: api_v1.ConditionTrue, Reason: reason, Message: message}
+	}
+	podEvent := func(reason, message, host string) *api_v1.Event {
+		return &api_v1.Event{
+			ObjectMeta:     meta_v1.ObjectMeta{Name: "api-7d9f.17a", Namespace: "payments"},
+			InvolvedObject: api_v1.ObjectReference{Kind: "Pod", Namespace: "payments", Name: "api-7d9f", UID: "pod-uid"},
+			Reason:         reason,
+			Message:        message,
+			Source:         api_v1.EventSource{Host: host},
+		}
+	}
+	preemptor := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "trainer-0", Namespace: "batch", UID: "preemptor-uid"}}
+
+	var Tests = []struct {
+		name         string
+		event        Event
+		digest       bool
+		kind, reason string
+	}{
+		{"preempted by the scheduler", Event{eventType: "update", oldObj: pod(""),
+			obj: pod("", target(api_v1.PodReasonPreemptionByScheduler, "batch/trainer-0: preempting to accommodate a higher priority pod"))},
+			false, "PodPreempted", "Pod api-7d9f was preempted on node node-1 by pod batch/trainer-0"},
+		{"drained", Event{eventType: "update", oldObj: pod(""), obj: pod("", target("EvictionByEvictionAPI", "Eviction API: evicting"))},
+			false, "PodEvicted", "Pod api-7d9f was evicted from node node-1 by the eviction API: Eviction API: evicting"},
+		{"garbage collected", Event{eventType: "update", oldObj: pod(""), obj: pod("", target("DeletionByPodGC", "PodGC: node no longer exists"))},
+			false, "", ""},
+		{"already disrupted", Event{eventType: "update", oldObj: pod("", target("EvictionByEvictionAPI", "")), obj: pod("", target("EvictionByEvictionAPI", ""))},
+			false, "", ""},
+		{"evicted by the kubelet", Event{eventType: "update", oldObj: pod(""), obj: pod("Evicted")},
+			false, "PodEvicted", "Pod api-7d9f was evicted from node node-1 by the kubelet: The node was low on resource: memory."},
+		{"evicted with digests", Event{eventType: "update", oldObj: pod(""), obj: pod("Evicted")},
+			true, "", ""},
+		{"preempted Kubernetes Event", Event{eventType: "create", obj: podEvent("Preempted", "Preempted by pod preemptor-uid on node node-2", "")},
+			true, "PodPreempted", "Pod api-7d9f was preempted on node node-2 by pod batch/trainer-0"},
+		{"preempted Kubernetes Event before 1.26", Event{eventType: "create", obj: podEvent("Preempted", "Preempted by ml/job-1 on node node-2", "")},
+			false, "PodPreempted", "Pod api-7d9f was preempted on node node-2 by pod ml/job-1"},
+		{"evicted Kubernetes Event", Event{eventType: "create", obj: podEvent("Evicted", "The node was low on resource: ephemeral-storage.", "node-3")},
+			false, "PodEvicted", "Pod api-7d9f was evicted from node node-3 by the kubelet: The node was low on resource: ephemeral-storage."},
+		{"other Kubernetes Event", Event{eventType: "create", obj: podEvent("BackOff", "Back-off restarting failed container", "node-3")},
+			false, "", ""},
+	}
+
+	for _, tt := range Tests {
+		w := newClusterWatcher(&config.Config{Disruptions: true}, &recorder{}, Cluster{KubeClient: fake.NewSimpleClientset()}, nil, nil)
+		store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		store.Add(preemptor)
+		w.objects.add(objName(api_v1.Pod{}), store, nil)
+		c := &Controller{disruptions: w.disruptions, digestEvictions: tt.digest}
+
+		e := c.disruptionEvent(tt.event)
+		switch {
+		case tt.kind == "" && e != nil:
+			t.Errorf("%s: expected no event, got %+v", tt.name, e)
+		case tt.kind != "" && e == nil:
+			t.Errorf("%s: expected a %s event, got none", tt.name, tt.kind)
+		case e != nil && (e.Kind != tt.kind || e.Reason != tt.reason || e.Name != "api-7d9f" || e.Namespace != "payments"):
+			t.Errorf("%s: expected %s %q, got %s %q", tt.name, tt.kind, tt.reason, e.Kind, e.Reason)
+		}
+	}
+}
+
+func TestDisruptionsReportedOnce(t *testing.T) {
+	w := newClusterWatcher(&config.Config{Disruptions: true}, &recorder{}, Cluster{KubeClient: fake.NewSimpleClientset()}, nil, nil)
+	c := &Controller{disruptions: w.disruptions}
+	pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api-7d9f", Namespace: "payments", UID: "pod-uid"}}
+	pod.Status.Reason = "Evicted"
+	ev := &api_v1.Event{
+		InvolvedObject: api_v1.ObjectReference{Kind: "Pod", Namespace: "payments", Name: "api-7d9f", UID: "pod-uid"},
+		Reason:         "Evicted",
+	}
+
+	if e := c.disruptionEvent(Event{eventType: "update", oldObj: &api_v1.Pod{}, obj: pod}); e == nil {
+		t.Fatal("expected a PodEvicted event")
+	}
+	if e := c.disruptionEvent(Event{eventType: "create", obj: ev}); e != nil {
+		t.Errorf("expected the eviction to be reported once, got %+v", e)
+	}
+}
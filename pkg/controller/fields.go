@@ -0,0 +1,67 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// fieldPath is a field extracted from custom resources into their notifications
+type fieldPath struct {
+	name string
+	path *jsonpath.JSONPath
+}
+
+// parseFields parses the JSONPath expressions of the configured fields
+func parseFields(fields []config.FieldPath) ([]fieldPath, error) {
+	parsed := make([]fieldPath, 0, len(fields))
+	for _, f := range fields {
+		path := jsonpath.New(f.Name).AllowMissingKeys(true)
+		if err := path.Parse(f.Template()); err != nil {
+			return nil, fmt.Errorf("field %q: %v", f.Name, err)
+		}
+		parsed = append(parsed, fieldPath{name: f.Name, path: path})
+	}
+	return parsed, nil
+}
+
+// extractFields returns the values of fields in obj, leaving out the missing and empty ones
+func extractFields(fields []fieldPath, obj runtime.Object) []event.Field {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	var extracted []event.Field
+	for _, f := range fields {
+		var value bytes.Buffer
+		if err := f.path.Execute(&value, u.Object); err != nil {
+			continue
+		}
+		if v := strings.TrimSpace(value.String()); v != "" {
+			extracted = append(extracted, event.Field{Name: f.name, Value: v})
+		}
+	}
+	return extracted
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestExtractFields(t *testing.T) {
+	fields, err := parseFields([]config.FieldPath{
+		{Name: "Revision", Path: ".status.sync.revision"},
+		{Name: "Health", Path: "{.status.health.status}"},
+		{Name: "Images", Path: `{range .status.summary.images[*]}{@} {end}`},
+		{Name: "Operation", Path: ".status.operationState.phase"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newGitOpsObject("Application", map[string]interface{}{
+		"status": map[string]interface{}{
+			"sync":    map[string]interface{}{"status": "Synced", "revision": "4f2a9c1"},
+			"health":  map[string]interface{}{"status": "Healthy"},
+			"summary": map[string]interface{}{"images": []interface{}{"nginx:1.27", "redis:7"}},
+		},
+	})
+
+	expected := []event.Field{
+		{Name: "Revision", Value: "4f2a9c1"},
+		{Name: "Health", Value: "Healthy"},
+		{Name: "Images", Value: "nginx:1.27 redis:7"},
+	}
+	if got := extractFields(fields, app); !reflect.DeepEqual(got, expected) {
+		t.Errorf("extractFields() = %v, expected %v", got, expected)
+	}
+	if got := extractFields(fields, &api_v1.Pod{}); got != nil {
+		t.Errorf("expected no fields for a typed object, got %v", got)
+	}
+	if _, err := parseFields([]config.FieldPath{{Name: "Revision", Path: ".status.sync[.revision"}}); err == nil {
+		t.Error("expected an error for an invalid path")
+	}
+}
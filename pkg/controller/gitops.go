@@ -0,0 +1,299 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Health states of GitOps resources, appended to their kind in the kind of events
+const (
+	gitOpsHealthy   = "Healthy"
+	gitOpsDegraded  = "Degraded"
+	gitOpsSuspended = "Suspended"
+	gitOpsOutOfSync = "OutOfSync"
+)
+
+// maxOutOfSyncResources is the number of out of sync resources listed in events
+const maxOutOfSyncResources = 5
+
+// rolloutRevisionAnnotation holds the revision of Argo Rollouts
+const rolloutRevisionAnnotation = "rollout.argoproj.io/revision"
+
+// gitOpsResource is a GitOps custom resource whose health changes are reported
+type gitOpsResource struct {
+	crd  config.CRD
+	kind string
+	// health returns the health of an object of the resource
+	health func(obj *unstructured.Unstructured) gitOpsHealth
+}
+
+// gitOpsHealth is the health of a GitOps object along with the revision it is at
+type gitOpsHealth struct {
+	state    string
+	message  string
+	revision string
+}
+
+var argoRollouts = gitOpsResource{
+	crd:    config.CRD{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"},
+	kind:   "Rollout",
+	health: rolloutHealth,
+}
+
+var argoApplications = gitOpsResource{
+	crd:    config.CRD{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"},
+	kind:   "Application",
+	health: applicationHealth,
+}
+
+var fluxKustomizations = gitOpsResource{
+	crd:    config.CRD{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"},
+	kind:   "Kustomization",
+	health: fluxHealth,
+}
+
+var fluxHelmReleases = gitOpsResource{
+	crd:    config.CRD{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"},
+	kind:   "HelmRelease",
+	health: fluxHealth,
+}
+
+// gitOpsResources returns the GitOps resources enabled in conf
+func gitOpsResources(conf config.GitOps) []*gitOpsResource {
+	var enabled []*gitOpsResource
+	if conf.ArgoRollouts {
+		enabled = append(enabled, &argoRollouts)
+	}
+	if conf.ArgoCD {
+		enabled = append(enabled, &argoApplications)
+	}
+	if conf.Flux {
+		enabled = append(enabled, &fluxKustomizations, &fluxHelmReleases)
+	}
+	return enabled
+}
+
+// gitOpsResourceOf returns the GitOps resource of crd enabled in conf, nil if none
+func gitOpsResourceOf(conf config.GitOps, crd config.CRD) *gitOpsResource {
+	for _, r := range gitOpsResources(conf) {
+		if r.crd.Group == crd.Group && r.crd.Resource == crd.Resource {
+			return r
+		}
+	}
+	return nil
+}
+
+// withGitOpsCRDs returns crds with the GitOps resources enabled in conf appended unless
+// they are already configured
+func withGitOpsCRDs(crds []config.CRD, conf config.GitOps) []config.CRD {
+	for _, r := range gitOpsResources(conf) {
+		configured := false
+		for _, crd := range crds {
+			if crd.Group == r.crd.Group && crd.Resource == r.crd.Resource {
+				configured = true
+				break
+			}
+		}
+		if !configured {
+			crds = append(crds, r.crd)
+		}
+	}
+	return crds
+}
+
+// rolloutHealth reads the health of an Argo Rollout from status.phase, aborted rollouts
+// being degraded and paused ones suspended
+func rolloutHealth(obj *unstructured.Unstructured) gitOpsHealth {
+	health := gitOpsHealth{state: gitOpsHealthy, revision: obj.GetAnnotations()[rolloutRevisionAnnotation]}
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	message, _, _ := unstructured.NestedString(obj.Object, "status", "message")
+	aborted, _, _ := unstructured.NestedBool(obj.Object, "status", "abort")
+	paused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused")
+
+	switch {
+	case aborted || phase == "Degraded":
+		health.state, health.message = gitOpsDegraded, message
+		if health.message == "" {
+			health.message = "Rollout aborted"
+		}
+	case paused || phase == "Paused":
+		health.state, health.message = gitOpsSuspended, message
+		if health.message == "" {
+			health.message = pauseReasons(obj)
+		}
+	}
+	return health
+}
+
+// pauseReasons lists the reasons of the pause conditions of an Argo Rollout
+func pauseReasons(obj *unstructured.Unstructured) string {
+	list, _, _ := unstructured.NestedSlice(obj.Object, "status", "pauseConditions")
+	var reasons []string
+	for _, item := range list {
+		fields, _ := item.(map[string]interface{})
+		if reason, _, _ := unstructured.NestedString(fields, "reason"); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	if len(reasons) == 0 {
+		return "Rollout paused"
+	}
+	return "Rollout paused: " + strings.Join(reasons, ", ")
+}
+
+// applicationHealth reads the health of an Argo CD Application from its health and sync
+// status, failed syncs being degraded
+func applicationHealth(obj *unstructured.Unstructured) gitOpsHealth {
+	health := gitOpsHealth{state: gitOpsHealthy}
+	health.revision, _, _ = unstructured.NestedString(obj.Object, "status", "sync", "revision")
+	if health.revision == "" {
+		// multi-source applications have a revision per source
+		revisions, _, _ := unstructured.NestedStringSlice(obj.Object, "status", "sync", "revisions")
+		health.revision = strings.Join(revisions, ", ")
+	}
+	status, _, _ := unstructured.NestedString(obj.Object, "status", "health", "status")
+	message, _, _ := unstructured.NestedString(obj.Object, "status", "health", "message")
+	sync, _, _ := unstructured.NestedString(obj.Object, "status", "sync", "status")
+	operation, _, _ := unstructured.NestedString(obj.Object, "status", "operationState", "phase")
+	operationMessage, _, _ := unstructured.NestedString(obj.Object, "status", "operationState", "message")
+
+	switch {
+	case operation == "Failed" || operation == "Error":
+		health.state, health.message = gitOpsDegraded, "Sync failed"
+		if operationMessage != "" {
+			health.message += ": " + operationMessage
+		}
+	case status == "Degraded" || status == "Missing":
+		health.state, health.message = gitOpsDegraded, message
+		if health.message == "" {
+			health.message = "Health status is " + status
+		}
+	case status == "Suspended":
+		health.state, health.message = gitOpsSuspended, message
+		if health.message == "" {
+			health.message = "Health status is Suspended"
+		}
+	case sync == "OutOfSync":
+		health.state, health.message = gitOpsOutOfSync, outOfSyncResources(obj)
+	}
+	return health
+}
+
+// outOfSyncResources lists the resources of an Argo CD Application that are out of sync
+func outOfSyncResources(obj *unstructured.Unstructured) string {
+	list, _, _ := unstructured.NestedSlice(obj.Object, "status", "resources")
+	var resources []string
+	for _, item := range list {
+		fields, _ := item.(map[string]interface{})
+		if status, _, _ := unstructured.NestedString(fields, "status"); status != "OutOfSync" {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(fields, "kind")
+		name, _, _ := unstructured.NestedString(fields, "name")
+		resources = append(resources, kind+"/"+name)
+	}
+	switch {
+	case len(resources) == 0:
+		return "Out of sync with the target revision"
+	case len(resources) > maxOutOfSyncResources:
+		more := len(resources) - maxOutOfSyncResources
+		resources = append(resources[:maxOutOfSyncResources], fmt.Sprintf("%d more", more))
+	}
+	return "Out of sync resources: " + strings.Join(resources, ", ")
+}
+
+// fluxHealth reads the health of a Flux Kustomization or HelmRelease from spec.suspend
+// and its Ready condition
+func fluxHealth(obj *unstructured.Unstructured) gitOpsHealth {
+	health := gitOpsHealth{state: gitOpsHealthy}
+	applied, _, _ := unstructured.NestedString(obj.Object, "status", "lastAppliedRevision")
+	attempted, _, _ := unstructured.NestedString(obj.Object, "status", "lastAttemptedRevision")
+	health.revision = firstNonEmpty(applied, attempted)
+
+	if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); suspended {
+		health.state, health.message = gitOpsSuspended, "Reconciliation suspended"
+		return health
+	}
+	for _, condition := range conditions(obj) {
+		if condition.Type != "Ready" || condition.Status != "False" {
+			continue
+		}
+		health.state, health.message = gitOpsDegraded, condition.Message
+		if condition.Reason != "" {
+			health.message = strings.TrimSuffix(condition.Reason+": "+condition.Message, ": ")
+		}
+		// the failing revision is the last attempted one
+		health.revision = firstNonEmpty(attempted, applied)
+	}
+	return health
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// gitOpsEvent returns the event reporting the change of health of a GitOps object,
+// nil when its health state did not change
+func (c *Controller) gitOpsEvent(newEvent Event) *event.Event {
+	if newEvent.eventType != "update" {
+		return nil
+	}
+	obj, ok := newEvent.obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	oldObj, ok := newEvent.oldObj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	health, oldHealth := c.gitops.health(obj), c.gitops.health(oldObj)
+	if health.state == oldHealth.state {
+		return nil
+	}
+
+	status, reason := "Warning", health.message
+	switch health.state {
+	case gitOpsHealthy:
+		status, reason = "Normal", fmt.Sprintf("Recovered from %s", oldHealth.state)
+	case gitOpsDegraded:
+		status = "Danger"
+	}
+	if health.revision != "" {
+		reason += "\nRevision: " + health.revision
+	}
+	return &event.Event{
+		Name:       newEvent.key,
+		Namespace:  newEvent.namespace,
+		Kind:       c.gitops.kind + health.state,
+		APIVersion: newEvent.apiVersion,
+		Status:     status,
+		Reason:     reason,
+		Obj:        newEvent.obj,
+		OldObj:     newEvent.oldObj,
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newGitOpsObject(kind string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	obj.SetKind(kind)
+	obj.SetName("guestbook")
+	obj.SetNamespace("apps")
+	return obj
+}
+
+func TestGitOpsHealth(t *testing.T) {
+	var Tests = []struct {
+		resource gitOpsResource
+		obj      *unstructured.Unstructured
+		expected gitOpsHealth
+	}{
+		{argoApplications, newGitOpsObject("Application", map[string]interface{}{
+			"status": map[string]interface{}{
+				"health": map[string]interface{}{"status": "Healthy"},
+				"sync":   map[string]interface{}{"status": "Synced", "revision": "4f2a9c1"},
+			},
+		}), gitOpsHealth{state: gitOpsHealthy, revision: "4f2a9c1"}},
+		{argoApplications, newGitOpsObject("Application", map[string]interface{}{
+			"status": map[string]interface{}{
+				"health": map[string]interface{}{"status": "Healthy"},
+				"sync":   map[string]interface{}{"status": "OutOfSync", "revision": "4f2a9c1"},
+				"resources": []interface{}{
+					map[string]interface{}{"kind": "Deployment", "name": "guestbook-ui", "status": "OutOfSync"},
+					map[string]interface{}{"kind": "Service", "name": "guestbook-ui", "status": "Synced"},
+				},
+			},
+		}), gitOpsHealth{state: gitOpsOutOfSync, message: "Out of sync resources: Deployment/guestbook-ui", revision: "4f2a9c1"}},
+		{argoApplications, newGitOpsObject("Application", map[string]interface{}{
+			"status": map[string]interface{}{
+				"health":         map[string]interface{}{"status": "Degraded", "message": "Deployment exceeded its progress deadline"},
+				"sync":           map[string]interface{}{"status": "OutOfSync", "revisions": []interface{}{"4f2a9c1", "1.2.0"}},
+				"operationState": map[string]interface{}{"phase": "Failed", "message": "one or more objects failed to apply"},
+			},
+		}), gitOpsHealth{state: gitOpsDegraded, message: "Sync failed: one or more objects failed to apply", revision: "4f2a9c1, 1.2.0"}},
+		{argoApplications, newGitOpsObject("Application", map[string]interface{}{
+			"status": map[string]interface{}{
+				"health": map[string]interface{}{"status": "Missing"},
+			},
+		}), gitOpsHealth{state: gitOpsDegraded, message: "Health status is Missing"}},
+		{argoRollouts, newGitOpsObject("Rollout", map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{rolloutRevisionAnnotation: "7"}},
+			"status":   map[string]interface{}{"phase": "Healthy"},
+		}), gitOpsHealth{state: gitOpsHealthy, revision: "7"}},
+		{argoRollouts, newGitOpsObject("Rollout", map[string]interface{}{
+			"status": map[string]interface{}{
+				"phase":           "Paused",
+				"pauseConditions": []interface{}{map[string]interface{}{"reason": "CanaryPauseStep"}},
+			},
+		}), gitOpsHealth{state: gitOpsSuspended, message: "Rollout paused: CanaryPauseStep"}},
+		{argoRollouts, newGitOpsObject("Rollout", map[string]interface{}{
+			"status": map[string]interface{}{"phase": "Degraded", "abort": true, "message": "RolloutAborted: metric \"success-rate\" assessed Failed"},
+		}), gitOpsHealth{state: gitOpsDegraded, message: "RolloutAborted: metric \"success-rate\" assessed Failed"}},
+		{fluxKustomizations, newGitOpsObject("Kustomization", map[string]interface{}{
+			"status": map[string]interface{}{
+				"lastAppliedRevision":   "main@sha1:4f2a9c1",
+				"lastAttemptedRevision": "main@sha1:8be01d3",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False", "reason": "BuildFailed", "message": "kustomization.yaml not found"},
+				},
+			},
+		}), gitOpsHealth{state: gitOpsDegraded, message: "BuildFailed: kustomization.yaml not found", revision: "main@sha1:8be01d3"}},
+		{fluxHelmReleases, newGitOpsObject("HelmRelease", map[string]interface{}{
+			"spec":   map[string]interface{}{"suspend": true},
+			"status": map[string]interface{}{"lastAttemptedRevision": "6.5.0"},
+		}), gitOpsHealth{state: gitOpsSuspended, message: "Reconciliation suspended", revision: "6.5.0"}},
+		{fluxHelmReleases, newGitOpsObject("HelmRelease", map[string]interface{}{
+			"status": map[string]interface{}{
+				"lastAttemptedRevision": "6.5.0",
+				"conditions":            []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+			},
+		}), gitOpsHealth{state: gitOpsHealthy, revision: "6.5.0"}},
+	}
+
+	for i, test := range Tests {
+		if health := test.resource.health(test.obj); health != test.expected {
+			t.Errorf("%d: health = %+v, expected %+v", i, health, test.expected)
+		}
+	}
+}
+
+func TestGitOpsEvent(t *testing.T) {
+	synced := newGitOpsObject("Application", map[string]interface{}{
+		"status": map[string]interface{}{
+			"health": map[string]interface{}{"status": "Healthy"},
+			"sync":   map[string]interface{}{"status": "Synced", "revision": "4f2a9c1"},
+		},
+	})
+	outOfSync := synced.DeepCopy()
+	unstructured.SetNestedField(outOfSync.Object, "OutOfSync", "status", "sync", "status")
+
+	c := &Controller{gitops: &argoApplications}
+	update := func(old, new *unstructured.Unstructured) Event {
+		return Event{key: "guestbook", namespace: "apps", eventType: "update", apiVersion: "argoproj.io/v1alpha1", obj: new, oldObj: old}
+	}
+
+	if e := c.gitOpsEvent(update(synced, synced)); e != nil {
+		t.Errorf("expected no event without health change, got %+v", e)
+	}
+	e := c.gitOpsEvent(update(synced, outOfSync))
+	if e == nil || e.Kind != "ApplicationOutOfSync" || e.Status != "Warning" {
+		t.Fatalf("expected a Warning ApplicationOutOfSync event, got %+v", e)
+	}
+	if e.Reason != "Out of sync with the target revision\nRevision: 4f2a9c1" {
+		t.Errorf("unexpected reason %q", e.Reason)
+	}
+	if message := e.Message(); !strings.HasPrefix(message, "Argo CD Application `guestbook` in `apps` is out of sync : \n") {
+		t.Errorf("unexpected message %q", message)
+	}
+
+	e = c.gitOpsEvent(update(outOfSync, synced))
+	if e == nil || e.Kind != "ApplicationHealthy" || e.Status != "Normal" || e.Reason != "Recovered from OutOfSync\nRevision: 4f2a9c1" {
+		t.Errorf("expected a Normal ApplicationHealthy event, got %+v", e)
+	}
+}
+
+func TestWithGitOpsCRDs(t *testing.T) {
+	configured := []config.CRD{{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}}
+	crds := withGitOpsCRDs(configured, config.GitOps{ArgoCD: true, Flux: true})
+	if len(crds) != 3 || crds[1].String() != fluxKustomizations.crd.String() || crds[2].String() != fluxHelmReleases.crd.String() {
+		t.Errorf("unexpected custom resources %v", crds)
+	}
+	if r := gitOpsResourceOf(config.GitOps{ArgoCD: true}, configured[0]); r != &argoApplications {
+		t.Errorf("expected Argo CD Applications, got %v", r)
+	}
+	if r := gitOpsResourceOf(config.GitOps{}, configured[0]); r != nil {
+		t.Errorf("expected no GitOps resource when disabled, got %v", r)
+	}
+}
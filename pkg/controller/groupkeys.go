@@ -0,0 +1,84 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GroupKeys are the templates of the group keys of events, by kind
+type GroupKeys map[string]*template.Template
+
+// groupKeyFuncs are the functions of group key templates
+var groupKeyFuncs = template.FuncMap{
+	"controller": controllerOf,
+}
+
+// ParseGroupKeys parses the configured group key templates
+func ParseGroupKeys(keys map[string]string) (GroupKeys, error) {
+	parsed := GroupKeys{}
+	for kind, key := range keys {
+		t, err := template.New(kind).Funcs(groupKeyFuncs).Option("missingkey=zero").Parse(key)
+		if err != nil {
+			return nil, fmt.Errorf("group key of %s: %v", kind, err)
+		}
+		parsed[kind] = t
+	}
+	return parsed, nil
+}
+
+// group sets the group key of e, rendered with the template of its kind, or else
+// namespace/kind/name
+func (cl Cluster) group(e *event.Event) {
+	if e.GroupKey != "" {
+		return
+	}
+	if t, ok := cl.GroupKeys[e.Kind]; ok {
+		var key bytes.Buffer
+		if err := t.Execute(&key, e); err != nil {
+			logrus.WithField("pkg", "kubewatch-groupkeys").Warnf("Error rendering group key of %s: %v", e.Kind, err)
+		} else if k := strings.TrimSpace(key.String()); k != "" {
+			e.GroupKey = k
+			return
+		}
+	}
+	e.GroupKey = e.Kind + "/" + e.Name
+	if e.Namespace != "" {
+		e.GroupKey = e.Namespace + "/" + e.GroupKey
+	}
+}
+
+// controllerOf returns the kind/name of the controller of the object of e, such as the
+// ReplicaSet of a pod, or of the object itself when it has no controller
+func controllerOf(e *event.Event) string {
+	if e.Obj != nil {
+		if accessor, err := meta.Accessor(e.Obj); err == nil {
+			if owner := meta_v1.GetControllerOfNoCopy(accessor); owner != nil {
+				return owner.Kind + "/" + owner.Name
+			}
+		}
+	}
+	return e.Kind + "/" + e.Name
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupKeys(t *testing.T) {
+	keys, err := ParseGroupKeys(map[string]string{
+		"pod":  "{{.Namespace}}/{{controller .}}",
+		"node": "{{index .Labels \"node.kubernetes.io/instance-type\"}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := Cluster{GroupKeys: keys}
+	controlled := true
+	pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{
+		Name:            "api-7d9f-x2k4p",
+		Namespace:       "payments",
+		OwnerReferences: []meta_v1.OwnerReference{{Kind: "ReplicaSet", Name: "api-7d9f", Controller: &controlled}},
+	}}
+
+	var Tests = []struct {
+		event event.Event
+		key   string
+	}{
+		{event.Event{Kind: "pod", Namespace: "payments", Name: "api-7d9f-x2k4p", Obj: pod}, "payments/ReplicaSet/api-7d9f"},
+		{event.Event{Kind: "pod", Namespace: "payments", Name: "debug"}, "payments/pod/debug"},
+		{event.Event{Kind: "deployment", Namespace: "payments", Name: "api"}, "payments/deployment/api"},
+		{event.Event{Kind: "node", Name: "worker-1", Labels: map[string]string{"node.kubernetes.io/instance-type": "m5.large"}}, "m5.large"},
+		{event.Event{Kind: "node", Name: "worker-2"}, "node/worker-2"},
+		{event.Event{Kind: "Alert", Name: "HighLatency", GroupKey: "alertmanager"}, "alertmanager"},
+	}
+
+	for i, test := range Tests {
+		e := test.event
+		cl.group(&e)
+		if e.GroupKey != test.key {
+			t.Errorf("%d: expected group key %q, got %q", i, test.key, e.GroupKey)
+		}
+	}
+
+	if _, err := ParseGroupKeys(map[string]string{"pod": "{{.Namespace"}); err == nil {
+		t.Error("expected an invalid template to be rejected")
+	}
+}
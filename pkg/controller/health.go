@@ -0,0 +1,172 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// handlerFailureThreshold is the number of consecutive failed deliveries after which the
+// handler is considered not functioning
+const handlerFailureThreshold = 3
+
+var health struct {
+	mu sync.Mutex
+	// started is set once the controllers of every cluster are started
+	started bool
+	// controllers holds the running controllers, also listed by CacheSizes
+	controllers map[*Controller]bool
+	// failures counts the consecutive failed deliveries, lastError is the latest delivery error
+	failures  int
+	lastError error
+}
+
+// processed counts the events delivered by the handler
+var processed atomic.Uint64
+
+// ProcessedEvents returns the number of events delivered by the handler since kubewatch
+// started, once however many times they were retried
+func ProcessedEvents() uint64 {
+	return processed.Load()
+}
+
+// Ready returns nil once the informer caches of every running controller are synced, as
+// long as the handler delivers events, or the reason kubewatch is not ready
+func Ready() error {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	if !health.started {
+		return fmt.Errorf("controllers not started")
+	}
+	var unsynced []string
+	for c := range health.controllers {
+		if !c.HasSynced() {
+			unsynced = append(unsynced, c.name())
+		}
+	}
+	if len(unsynced) > 0 {
+		sort.Strings(unsynced)
+		return fmt.Errorf("informer caches not synced: %s", strings.Join(unsynced, ", "))
+	}
+	if health.failures >= handlerFailureThreshold {
+		return fmt.Errorf("the last %d deliveries failed: %v", health.failures, health.lastError)
+	}
+	return nil
+}
+
+// setStarted records that the controllers of every cluster are started
+func setStarted() {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	health.started = true
+}
+
+// setStopped records that the controllers are stopped, kubewatch is no longer ready and
+// the failures of its deliveries are forgotten
+func setStopped() {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	health.started = false
+	health.failures = 0
+	health.lastError = nil
+}
+
+// register tracks the cache sync of c until unregistered
+func register(c *Controller) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	if health.controllers == nil {
+		health.controllers = map[*Controller]bool{}
+	}
+	health.controllers[c] = true
+}
+
+func unregister(c *Controller) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	delete(health.controllers, c)
+}
+
+// recordDelivery records the outcome of a delivery to the handler
+func recordDelivery(err error) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	if err == nil {
+		if health.failures >= handlerFailureThreshold {
+			reportDeliveryRecovery(health.failures)
+		}
+		health.failures = 0
+		return
+	}
+	health.failures++
+	health.lastError = err
+	if health.failures == handlerFailureThreshold {
+		reportDeliveryFailures(health.failures, err)
+	}
+}
+
+// name identifies the controller in readiness reports
+func (c *Controller) name() string {
+	name := c.resourceType
+	if c.namespace != "" {
+		name = c.namespace + "/" + name
+	}
+	if c.clusterName != "" {
+		name = c.clusterName + ":" + name
+	}
+	return name
+}
+
+// CacheSize is the number of objects cached by the informer of a controller
+type CacheSize struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Objects   int    `json:"objects"`
+}
+
+// CacheSizes returns the number of objects cached by every running controller, largest first
+func CacheSizes() []CacheSize {
+	health.mu.Lock()
+	controllers := make([]*Controller, 0, len(health.controllers))
+	for c := range health.controllers {
+		controllers = append(controllers, c)
+	}
+	health.mu.Unlock()
+
+	sizes := make([]CacheSize, 0, len(controllers))
+	for _, c := range controllers {
+		sizes = append(sizes, CacheSize{
+			Cluster:   c.clusterName,
+			Kind:      c.resourceType,
+			Namespace: c.namespace,
+			Objects:   len(c.informer.GetStore().ListKeys()),
+		})
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Objects != sizes[j].Objects {
+			return sizes[i].Objects > sizes[j].Objects
+		}
+		return sizes[i].Cluster+"/"+sizes[i].Namespace+"/"+sizes[i].Kind < sizes[j].Cluster+"/"+sizes[j].Namespace+"/"+sizes[j].Kind
+	})
+	return sizes
+}
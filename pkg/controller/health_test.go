@@ -0,0 +1,95 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestReady(t *testing.T) {
+	health.started, health.controllers, health.failures = false, nil, 0
+
+	if err := Ready(); err == nil {
+		t.Fatal("expected kubewatch not to be ready before controllers are started")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	client := fake.NewSimpleClientset(&api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "team-a"}})
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Pods("team-a").List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Pods("team-a").Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Pod{}, 0, cache.Indexers{},
+	)
+	w := newClusterWatcher(&config.Config{}, &recorder{}, Cluster{Name: "prod", KubeClient: client}, nil, stopCh)
+	c := w.newController(informer, "Pod", V1, "team-a")
+	register(c)
+	setStarted()
+	if err := Ready(); err == nil || !strings.Contains(err.Error(), "prod:team-a/Pod") {
+		t.Fatalf("expected the Pod informer not to be synced, got %v", err)
+	}
+
+	go c.informer.Run(stopCh)
+	deadline := time.Now().Add(5 * time.Second)
+	for Ready() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected kubewatch to be ready once synced, got %v", Ready())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	found := false
+	for _, size := range CacheSizes() {
+		if size.Cluster == "prod" && size.Kind == "Pod" && size.Namespace == "team-a" {
+			found = size.Objects == 1
+		}
+	}
+	if !found {
+		t.Errorf("expected the Pod cache of team-a to hold 1 object, got %+v", CacheSizes())
+	}
+
+	for i := 0; i < handlerFailureThreshold; i++ {
+		recordDelivery(errors.New("connection refused"))
+	}
+	if err := Ready(); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected failing deliveries to make kubewatch not ready, got %v", err)
+	}
+	recordDelivery(nil)
+	if err := Ready(); err != nil {
+		t.Fatalf("expected a successful delivery to make kubewatch ready, got %v", err)
+	}
+
+	unregister(c)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/google/uuid"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// helmReleaseKind is the kind of the events about Helm releases
+const helmReleaseKind = "HelmRelease"
+
+// helmReleaseType is the type of the Secrets Helm stores releases in, one per revision
+const helmReleaseType = "helm.sh/release.v1"
+
+// helmReleaseKey is the key of the release in the data of Helm release Secrets
+const helmReleaseKey = "release"
+
+// helmRelease is the metadata of a revision of a Helm release, decoded from its Secret
+type helmRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Info      struct {
+		Status      string `json:"status"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// decodeHelmRelease decodes the metadata of the release stored in a Helm release Secret.
+// Releases are gzipped JSON, base64 encoded once more by Helm, or the JSON of the
+// metadata only once transformed by stripHelmRelease.
+func decodeHelmRelease(secret *api_v1.Secret) (*helmRelease, error) {
+	data := secret.Data[helmReleaseKey]
+	if !bytes.HasPrefix(data, []byte("{")) {
+		decoded, err := base64.StdEncoding.DecodeString(string(data))
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(decoded, []byte{0x1f, 0x8b}) {
+			r, err := gzip.NewReader(bytes.NewReader(decoded))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			release := &helmRelease{}
+			if err := json.NewDecoder(r).Decode(release); err != nil {
+				return nil, err
+			}
+			return release, nil
+		}
+		data = decoded
+	}
+	release := &helmRelease{}
+	if err := json.Unmarshal(data, release); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// stripHelmRelease replaces the release stored in Helm release Secrets with its metadata
+// before they are cached, dropping its manifest, values and chart templates
+func stripHelmRelease(obj interface{}) (interface{}, error) {
+	secret, ok := obj.(*api_v1.Secret)
+	if !ok || secret.Type != helmReleaseType {
+		return obj, nil
+	}
+	release, err := decodeHelmRelease(secret)
+	if err != nil {
+		return obj, nil
+	}
+	data, err := json.Marshal(release)
+	if err != nil {
+		return obj, nil
+	}
+	secret.Data = map[string][]byte{helmReleaseKey: data}
+	return secret, nil
+}
+
+// helmReleaseEvent returns the event about the Helm release change stored in the Secret of
+// e: a revision installed, upgraded, rolled back or failed, or the release uninstalled.
+// It returns nil for other changes, such as previous revisions being superseded.
+func (c *Controller) helmReleaseEvent(e Event) *event.Event {
+	secret, ok := e.obj.(*api_v1.Secret)
+	if !ok || secret.Type != helmReleaseType {
+		return nil
+	}
+	status := secret.Labels["status"]
+	var oldStatus string
+	switch e.eventType {
+	case "create":
+		if !c.startupInventory && !e.missed && secret.CreationTimestamp.Time.Before(c.startTime) {
+			return nil
+		}
+	case "update":
+		old, ok := e.oldObj.(*api_v1.Secret)
+		if !ok || old.Labels["status"] == status {
+			return nil
+		}
+		oldStatus = old.Labels["status"]
+	case "delete":
+		// releases are marked uninstalling before their Secrets are deleted
+		if status != "uninstalling" {
+			return nil
+		}
+		status = "uninstalled"
+	}
+
+	release, err := decodeHelmRelease(secret)
+	if err != nil {
+		c.logger.Debugf("Error decoding Helm release %s/%s: %v", secret.Namespace, secret.Name, err)
+		return nil
+	}
+	previous := c.previousHelmRelease(secret)
+
+	kbEvent := &event.Event{
+		Name:          release.Name,
+		Namespace:     secret.Namespace,
+		Kind:          helmReleaseKind,
+		APIVersion:    V1,
+		Status:        "Normal",
+		CorrelationID: helmReleaseID(secret.Namespace, release.Name, release.Version),
+	}
+	switch status {
+	case "deployed":
+		switch {
+		case previous == nil:
+			kbEvent.Reason = fmt.Sprintf("Release %s installed at rev %d, %s", release.Name, release.Version, chartOf(release))
+		case oldStatus == "pending-rollback" || strings.HasPrefix(release.Info.Description, "Rollback"):
+			kbEvent.Reason = fmt.Sprintf("Release %s rolled back from rev %d to %d, %s", release.Name, previous.Version, release.Version, chartChange(previous, release))
+			kbEvent.Status = "Warning"
+		default:
+			kbEvent.Reason = fmt.Sprintf("Release %s upgraded from rev %d to %d, %s", release.Name, previous.Version, release.Version, chartChange(previous, release))
+		}
+	case "failed":
+		kbEvent.Status = "Danger"
+		kbEvent.Reason = fmt.Sprintf("Release %s rev %d failed, %s", release.Name, release.Version, chartOf(release))
+		if release.Info.Description != "" {
+			kbEvent.Reason += ": " + release.Info.Description
+		}
+	case "uninstalled":
+		kbEvent.Status = "Danger"
+		kbEvent.Reason = fmt.Sprintf("Release %s uninstalled at rev %d, %s", release.Name, release.Version, chartOf(release))
+	default:
+		return nil
+	}
+	return kbEvent
+}
+
+// previousHelmRelease returns the latest cached revision of the release of secret preceding
+// it, nil if none
+func (c *Controller) previousHelmRelease(secret *api_v1.Secret) *helmRelease {
+	version, _ := strconv.Atoi(secret.Labels["version"])
+	var previous *api_v1.Secret
+	previousVersion := 0
+	for _, item := range c.informer.GetStore().List() {
+		s, ok := item.(*api_v1.Secret)
+		if !ok || s.Type != helmReleaseType || s.Namespace != secret.Namespace || s.Labels["name"] != secret.Labels["name"] {
+			continue
+		}
+		if v, _ := strconv.Atoi(s.Labels["version"]); v < version && v > previousVersion {
+			previous, previousVersion = s, v
+		}
+	}
+	if previous == nil {
+		return nil
+	}
+	release, err := decodeHelmRelease(previous)
+	if err != nil {
+		return nil
+	}
+	return release
+}
+
+// chartOf describes the chart of release, such as "chart api-1.3.0"
+func chartOf(release *helmRelease) string {
+	return fmt.Sprintf("chart %s-%s", release.Chart.Metadata.Name, release.Chart.Metadata.Version)
+}
+
+// chartChange describes the chart change between two revisions, such as
+// "chart version 1.2.0→1.3.0, app version 2.1→2.2"
+func chartChange(from, to *helmRelease) string {
+	fromChart, toChart := from.Chart.Metadata, to.Chart.Metadata
+	var changes []string
+	switch {
+	case fromChart.Name != toChart.Name:
+		changes = append(changes, fmt.Sprintf("chart %s-%s→%s-%s", fromChart.Name, fromChart.Version, toChart.Name, toChart.Version))
+	case fromChart.Version != toChart.Version:
+		changes = append(changes, fmt.Sprintf("chart version %s→%s", fromChart.Version, toChart.Version))
+	default:
+		changes = append(changes, chartOf(to))
+	}
+	if fromChart.AppVersion != toChart.AppVersion {
+		changes = append(changes, fmt.Sprintf("app version %s→%s", fromChart.AppVersion, toChart.AppVersion))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// helmReleaseID returns the correlation ID shared by the events of a revision of a release
+func helmReleaseID(namespace string, name string, version int) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("kubewatch/helmrelease/%s/%s/%d", namespace, name, version))).String()
+}
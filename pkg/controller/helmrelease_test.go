@@ -0,0 +1,119 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newHelmReleaseSecret returns the Secret Helm stores revision version of release api in
+func newHelmReleaseSecret(version int, status string, chartVersion string, description string) *api_v1.Secret {
+	release := fmt.Sprintf(`{"name":"api","namespace":"payments","version":%d,"manifest":"---\nkind: Deployment","info":{"status":%q,"description":%q},"chart":{"metadata":{"name":"api","version":%q,"appVersion":"2.1"},"templates":[{"name":"templates/deployment.yaml","data":"a2luZDogRGVwbG95bWVudA=="}]}}`,
+		version, status, description, chartVersion)
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	w.Write([]byte(release))
+	w.Close()
+
+	return &api_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              fmt.Sprintf("sh.helm.release.v1.api.v%d", version),
+			Namespace:         "payments",
+			Labels:            map[string]string{"name": "api", "owner": "helm", "status": status, "version": fmt.Sprint(version)},
+			CreationTimestamp: meta_v1.Now(),
+		},
+		Type: helmReleaseType,
+		Data: map[string][]byte{helmReleaseKey: []byte(base64.StdEncoding.EncodeToString(gzipped.Bytes()))},
+	}
+}
+
+func TestStripHelmRelease(t *testing.T) {
+	secret := newHelmReleaseSecret(3, "deployed", "1.2.0", "Upgrade complete")
+	stripped, err := stripHelmRelease(secret.DeepCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := string(stripped.(*api_v1.Secret).Data[helmReleaseKey])
+	expected := `{"name":"api","namespace":"payments","version":3,"info":{"status":"deployed","description":"Upgrade complete"},"chart":{"metadata":{"name":"api","version":"1.2.0","appVersion":"2.1"}}}`
+	if data != expected {
+		t.Errorf("stripped release = %s, expected %s", data, expected)
+	}
+
+	for _, s := range []*api_v1.Secret{secret, stripped.(*api_v1.Secret)} {
+		release, err := decodeHelmRelease(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if release.Version != 3 || release.Chart.Metadata.Version != "1.2.0" {
+			t.Errorf("unexpected release %+v", release)
+		}
+	}
+}
+
+func TestHelmReleaseEvent(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(nil, &api_v1.Secret{}, 0, cache.Indexers{})
+	c := &Controller{informer: informer, startTime: time.Now().Add(-time.Minute)}
+	store := informer.GetStore()
+	store.Add(newHelmReleaseSecret(3, "superseded", "1.1.0", "Upgrade complete"))
+	store.Add(newHelmReleaseSecret(4, "deployed", "1.2.0", "Upgrade complete"))
+
+	var Tests = []struct {
+		e      Event
+		status string
+		reason string
+	}{
+		{Event{eventType: "create", obj: newHelmReleaseSecret(5, "pending-upgrade", "1.3.0", "Preparing upgrade")}, "", ""},
+		{Event{eventType: "update", oldObj: newHelmReleaseSecret(5, "pending-upgrade", "1.3.0", "Preparing upgrade"), obj: newHelmReleaseSecret(5, "deployed", "1.3.0", "Upgrade complete")},
+			"Normal", "Release api upgraded from rev 4 to 5, chart version 1.2.0→1.3.0"},
+		{Event{eventType: "update", oldObj: newHelmReleaseSecret(5, "pending-upgrade", "1.3.0", "Preparing upgrade"), obj: newHelmReleaseSecret(5, "failed", "1.3.0", "Upgrade \"api\" failed: timed out waiting for the condition")},
+			"Danger", "Release api rev 5 failed, chart api-1.3.0: Upgrade \"api\" failed: timed out waiting for the condition"},
+		{Event{eventType: "update", oldObj: newHelmReleaseSecret(5, "pending-rollback", "1.1.0", "Rollback to 3"), obj: newHelmReleaseSecret(5, "deployed", "1.1.0", "Rollback to 3")},
+			"Warning", "Release api rolled back from rev 4 to 5, chart version 1.2.0→1.1.0"},
+		{Event{eventType: "update", oldObj: newHelmReleaseSecret(1, "pending-install", "1.0.0", "Initial install underway"), obj: newHelmReleaseSecret(1, "deployed", "1.0.0", "Install complete")},
+			"Normal", "Release api installed at rev 1, chart api-1.0.0"},
+		{Event{eventType: "update", oldObj: newHelmReleaseSecret(4, "deployed", "1.2.0", "Upgrade complete"), obj: newHelmReleaseSecret(4, "superseded", "1.2.0", "Upgrade complete")}, "", ""},
+		{Event{eventType: "delete", obj: newHelmReleaseSecret(3, "superseded", "1.1.0", "Upgrade complete")}, "", ""},
+		{Event{eventType: "delete", obj: newHelmReleaseSecret(4, "uninstalling", "1.2.0", "Deletion in progress")},
+			"Danger", "Release api uninstalled at rev 4, chart api-1.2.0"},
+	}
+
+	for i, test := range Tests {
+		e := c.helmReleaseEvent(test.e)
+		if test.reason == "" {
+			if e != nil {
+				t.Errorf("%d: expected no event, got %+v", i, e)
+			}
+			continue
+		}
+		if e == nil {
+			t.Errorf("%d: expected an event", i)
+			continue
+		}
+		if e.Status != test.status || e.Reason != test.reason || e.Name != "api" || e.Namespace != "payments" || e.Kind != "HelmRelease" {
+			t.Errorf("%d: unexpected event %+v", i, e)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// imagePolicyKind is the kind of the events about images breaking the image policy
+const imagePolicyKind = "ImagePolicyViolation"
+
+// dockerHub is the registry of images without a registry host
+const dockerHub = "docker.io"
+
+// imageReference is a container image split into its parts
+type imageReference struct {
+	// registry is the registry host, docker.io for Docker Hub
+	registry string
+	// repository is the path of the image in the registry, library/nginx for nginx
+	repository string
+	tag        string
+	digest     string
+}
+
+// parseImage splits a container image such as ghcr.io/acme/api:v1.2@sha256:... into its parts
+func parseImage(image string) imageReference {
+	var ref imageReference
+	image, ref.digest, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, ref.tag = image[:i], image[i+1:]
+	}
+	ref.registry, ref.repository = dockerHub, image
+	if host, path, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		ref.registry, ref.repository = host, path
+	}
+	if ref.registry == dockerHub && !strings.Contains(ref.repository, "/") {
+		ref.repository = "library/" + ref.repository
+	}
+	return ref
+}
+
+// imagePolicy checks the container images of workloads against the configured rules
+type imagePolicy struct {
+	disallowLatest    bool
+	allowedRegistries []string
+}
+
+// newImagePolicy returns the image policy configured in conf, nil when no rule is configured
+func newImagePolicy(conf config.ImagePolicy) *imagePolicy {
+	if !conf.Enabled() {
+		return nil
+	}
+	p := &imagePolicy{disallowLatest: conf.DisallowLatest}
+	for _, registry := range conf.AllowedRegistries {
+		p.allowedRegistries = append(p.allowedRegistries, strings.TrimSuffix(registry, "/"))
+	}
+	return p
+}
+
+// violation returns why image breaks the policy, empty when it complies
+func (p *imagePolicy) violation(image string) string {
+	ref := parseImage(image)
+	if len(p.allowedRegistries) > 0 && !p.allowedRegistry(ref) {
+		return fmt.Sprintf("image %s comes from registry %s, which is not allowed", image, ref.registry)
+	}
+	if p.disallowLatest && ref.digest == "" && (ref.tag == "" || ref.tag == "latest") {
+		return fmt.Sprintf("image %s uses the latest tag", image)
+	}
+	return ""
+}
+
+func (p *imagePolicy) allowedRegistry(ref imageReference) bool {
+	name := ref.registry + "/" + ref.repository
+	for _, allowed := range p.allowedRegistries {
+		if allowed == ref.registry || strings.HasPrefix(name, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// violations returns the violations of the containers of obj, nil for objects other than
+// workloads
+func (p *imagePolicy) violations(obj runtime.Object) []string {
+	spec := checkedPodSpec(obj)
+	if spec == nil {
+		return nil
+	}
+	var violations []string
+	for _, c := range containers(spec) {
+		if violation := p.violation(c.Image); violation != "" {
+			violations = append(violations, fmt.Sprintf("container %s: %s", c.Name, violation))
+		}
+	}
+	return violations
+}
+
+// checkedPodSpec returns the pod template of the workload obj, nil for other objects. Pods
+// and Jobs created by another workload are checked through their owner.
+func checkedPodSpec(obj runtime.Object) *api_v1.PodSpec {
+	spec := workloadPodSpec(obj)
+	switch o := obj.(type) {
+	case *api_v1.Pod:
+		if meta_v1.GetControllerOf(o) == nil {
+			spec = &o.Spec
+		}
+	case *batch_v1.Job:
+		if meta_v1.GetControllerOf(o) == nil {
+			spec = &o.Spec.Template.Spec
+		}
+	}
+	return spec
+}
+
+// reportedViolations returns the violations found by check in the workload of a created or
+// updated event, nil when they should not be reported: creations preceding startup, and
+// updates bringing no new violation.
+func (c *Controller) reportedViolations(e Event, check func(runtime.Object) []string) []string {
+	violations := check(e.obj)
+	if len(violations) == 0 {
+		return nil
+	}
+	switch e.eventType {
+	case "create":
+		object, err := meta.Accessor(e.obj)
+		if err != nil || !c.startupInventory && !e.missed && object.GetCreationTimestamp().Time.Before(c.startTime) {
+			return nil
+		}
+	case "update":
+		previous := map[string]bool{}
+		for _, violation := range check(e.oldObj) {
+			previous[violation] = true
+		}
+		added := false
+		for _, violation := range violations {
+			added = added || !previous[violation]
+		}
+		if !added {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return violations
+}
+
+// imagePolicyEvent returns the policy-violation event about a created or updated workload
+// whose images break the image policy, nil when they comply. Updates are only reported
+// when they bring new violations.
+func (c *Controller) imagePolicyEvent(e Event) *event.Event {
+	violations := c.reportedViolations(e, c.imagePolicy.violations)
+	if violations == nil {
+		return nil
+	}
+	return &event.Event{
+		Name:       e.key,
+		Namespace:  e.namespace,
+		Kind:       imagePolicyKind,
+		APIVersion: e.apiVersion,
+		Status:     "Warning",
+		Severity:   event.SeverityPolicyViolation,
+		Reason:     fmt.Sprintf("%s %s breaks the image policy:\n%s", c.resourceType, e.key, strings.Join(violations, "\n")),
+		Obj:        e.obj,
+		OldObj:     e.oldObj,
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseImage(t *testing.T) {
+	var Tests = []struct {
+		image    string
+		expected imageReference
+	}{
+		{"nginx", imageReference{registry: "docker.io", repository: "library/nginx"}},
+		{"bitnami/redis:7.2", imageReference{registry: "docker.io", repository: "bitnami/redis", tag: "7.2"}},
+		{"ghcr.io/acme/api:v1.2@sha256:0123", imageReference{registry: "ghcr.io", repository: "acme/api", tag: "v1.2", digest: "sha256:0123"}},
+		{"localhost:5000/api", imageReference{registry: "localhost:5000", repository: "api"}},
+		{"localhost/api:latest", imageReference{registry: "localhost", repository: "api", tag: "latest"}},
+	}
+
+	for _, test := range Tests {
+		if ref := parseImage(test.image); ref != test.expected {
+			t.Errorf("parseImage(%q) = %+v, expected %+v", test.image, ref, test.expected)
+		}
+	}
+}
+
+func TestImagePolicyViolation(t *testing.T) {
+	p := newImagePolicy(config.ImagePolicy{DisallowLatest: true, AllowedRegistries: []string{"ghcr.io/acme", "docker.io/library", "registry.example.com"}})
+
+	var Tests = []struct {
+		image     string
+		violation string
+	}{
+		{"ghcr.io/acme/api:v1.2", ""},
+		{"ghcr.io/acme/api", "image ghcr.io/acme/api uses the latest tag"},
+		{"ghcr.io/acme/api:latest@sha256:0123", ""},
+		{"ghcr.io/acmecorp/api:v1", "image ghcr.io/acmecorp/api:v1 comes from registry ghcr.io, which is not allowed"},
+		{"nginx:1.25", ""},
+		{"nginx:latest", "image nginx:latest uses the latest tag"},
+		{"bitnami/redis:7.2", "image bitnami/redis:7.2 comes from registry docker.io, which is not allowed"},
+		{"registry.example.com/team/tool:3", ""},
+	}
+
+	for _, test := range Tests {
+		if violation := p.violation(test.image); violation != test.violation {
+			t.Errorf("violation(%q) = %q, expected %q", test.image, violation, test.violation)
+		}
+	}
+	if newImagePolicy(config.ImagePolicy{}) != nil {
+		t.Errorf("expected no policy without rules")
+	}
+}
+
+func TestImagePolicyEvent(t *testing.T) {
+	deployment := func(api, proxy string) *apps_v1.Deployment {
+		d := deploymentWithImages("api", api, "proxy", proxy)
+		d.CreationTimestamp = meta_v1.Now()
+		return d
+	}
+	c := &Controller{
+		resourceType: "Deployment",
+		startTime:    time.Now().Add(-time.Minute),
+		imagePolicy:  newImagePolicy(config.ImagePolicy{DisallowLatest: true}),
+	}
+	e := c.imagePolicyEvent(Event{key: "api", namespace: "payments", eventType: "create", obj: deployment("api:v1", "proxy:latest")})
+	if e == nil {
+		t.Fatal("expected a policy violation event")
+	}
+	if e.Kind != "ImagePolicyViolation" || e.Severity != event.SeverityPolicyViolation || e.Reason != "Deployment api breaks the image policy:\ncontainer proxy: image proxy:latest uses the latest tag" {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	old := deployment("api:v1", "proxy:latest")
+	if e := c.imagePolicyEvent(Event{key: "api", eventType: "update", oldObj: old, obj: deployment("api:v2", "proxy:latest")}); e != nil {
+		t.Errorf("expected no event without new violations, got %+v", e)
+	}
+	if e := c.imagePolicyEvent(Event{key: "api", eventType: "update", oldObj: old, obj: deployment("api", "proxy:latest")}); e == nil {
+		t.Errorf("expected an event for a new violation")
+	}
+
+	owned := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api-x2x", CreationTimestamp: meta_v1.Now(), OwnerReferences: []meta_v1.OwnerReference{{Kind: "ReplicaSet", Name: "api", Controller: new(bool)}}}}
+	*owned.OwnerReferences[0].Controller = true
+	owned.Spec = podSpec("api", "api:latest")
+	if e := c.imagePolicyEvent(Event{key: "api-x2x", eventType: "create", obj: owned}); e != nil {
+		t.Errorf("expected pods of workloads to be checked through their owner, got %+v", e)
+	}
+}
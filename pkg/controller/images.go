@@ -0,0 +1,116 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"regexp"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// workloadPodSpec returns the pod template spec of Deployments, StatefulSets, DaemonSets
+// and CronJobs, nil for other objects
+func workloadPodSpec(obj runtime.Object) *api_v1.PodSpec {
+	switch workload := obj.(type) {
+	case *apps_v1.Deployment:
+		return &workload.Spec.Template.Spec
+	case *apps_v1.StatefulSet:
+		return &workload.Spec.Template.Spec
+	case *apps_v1.DaemonSet:
+		return &workload.Spec.Template.Spec
+	case *batch_v1.CronJob:
+		return &workload.Spec.JobTemplate.Spec.Template.Spec
+	}
+	return nil
+}
+
+// imageChanges returns the container images changed by a workload update,
+// in the order of the updated pod template
+func imageChanges(old, new runtime.Object) []event.ImageChange {
+	oldSpec, newSpec := workloadPodSpec(old), workloadPodSpec(new)
+	if oldSpec == nil || newSpec == nil {
+		return nil
+	}
+
+	oldImages := containerImages(oldSpec)
+	var changes []event.ImageChange
+	for _, c := range containers(newSpec) {
+		oldImage, ok := oldImages[c.Name]
+		delete(oldImages, c.Name)
+		if ok && oldImage == c.Image {
+			continue
+		}
+		changes = append(changes, event.ImageChange{Container: c.Name, Old: oldImage, New: c.Image})
+	}
+	for _, c := range containers(oldSpec) {
+		if image, ok := oldImages[c.Name]; ok {
+			changes = append(changes, event.ImageChange{Container: c.Name, Old: image})
+		}
+	}
+	return changes
+}
+
+// containerImagePath matches the diff paths of container images
+var containerImagePath = regexp.MustCompile(`(^|\.)(initContainers|containers)\[\d+\]\.image$`)
+
+// withoutImageChanges returns changes without the container image updates listed in
+// images, so that they are not reported twice
+func withoutImageChanges(changes []diff.Change, images []event.ImageChange) []diff.Change {
+	if len(images) == 0 {
+		return changes
+	}
+	var kept []diff.Change
+	for _, change := range changes {
+		if !coversImageChange(images, change) {
+			kept = append(kept, change)
+		}
+	}
+	return kept
+}
+
+// coversImageChange reports whether change is the update of a container image in images
+func coversImageChange(images []event.ImageChange, change diff.Change) bool {
+	if !containerImagePath.MatchString(change.Path) {
+		return false
+	}
+	for _, image := range images {
+		if change.Old == image.Old && change.New == image.New {
+			return true
+		}
+	}
+	return false
+}
+
+func containerImages(spec *api_v1.PodSpec) map[string]string {
+	images := map[string]string{}
+	for _, c := range containers(spec) {
+		images[c.Name] = c.Image
+	}
+	return images
+}
+
+// containers returns the init containers then the containers of spec
+func containers(spec *api_v1.PodSpec) []api_v1.Container {
+	all := make([]api_v1.Container, 0, len(spec.InitContainers)+len(spec.Containers))
+	all = append(all, spec.InitContainers...)
+	return append(all, spec.Containers...)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func podSpec(images ...string) api_v1.PodSpec {
+	var spec api_v1.PodSpec
+	for i := 0; i < len(images); i += 2 {
+		spec.Containers = append(spec.Containers, api_v1.Container{Name: images[i], Image: images[i+1]})
+	}
+	return spec
+}
+
+func deploymentWithImages(images ...string) *apps_v1.Deployment {
+	d := &apps_v1.Deployment{}
+	d.Spec.Template.Spec = podSpec(images...)
+	return d
+}
+
+func cronJobWithImages(images ...string) *batch_v1.CronJob {
+	cj := &batch_v1.CronJob{}
+	cj.Spec.JobTemplate.Spec.Template.Spec = podSpec(images...)
+	return cj
+}
+
+func TestImageChanges(t *testing.T) {
+	withInit := deploymentWithImages("web", "nginx:1.25")
+	withInit.Spec.Template.Spec.InitContainers = []api_v1.Container{{Name: "migrate", Image: "migrate:2.0"}}
+
+	var Tests = []struct {
+		old, new runtime.Object
+		changes  []event.ImageChange
+	}{
+		{deploymentWithImages("web", "nginx:1.25"), deploymentWithImages("web", "nginx:1.25"), nil},
+		{
+			deploymentWithImages("web", "nginx:1.25", "proxy", "envoy:1.28"),
+			deploymentWithImages("web", "nginx:1.26", "proxy", "envoy:1.28"),
+			[]event.ImageChange{{Container: "web", Old: "nginx:1.25", New: "nginx:1.26"}},
+		},
+		{
+			deploymentWithImages("web", "nginx:1.25", "proxy", "envoy:1.28"),
+			withInit,
+			[]event.ImageChange{{Container: "migrate", New: "migrate:2.0"}, {Container: "proxy", Old: "envoy:1.28"}},
+		},
+		{
+			cronJobWithImages("backup", "backup:1.0"),
+			cronJobWithImages("backup", "backup:1.1"),
+			[]event.ImageChange{{Container: "backup", Old: "backup:1.0", New: "backup:1.1"}},
+		},
+		{&api_v1.Pod{Spec: podSpec("web", "nginx:1.25")}, &api_v1.Pod{Spec: podSpec("web", "nginx:1.26")}, nil},
+		{nil, deploymentWithImages("web", "nginx:1.25"), nil},
+	}
+
+	for _, test := range Tests {
+		if changes := imageChanges(test.old, test.new); !reflect.DeepEqual(changes, test.changes) {
+			t.Errorf("imageChanges() = %v, expected %v", changes, test.changes)
+		}
+	}
+}
+
+func TestWithoutImageChanges(t *testing.T) {
+	old := deploymentWithImages("web", "nginx:1.25", "proxy", "envoy:1.28")
+	new := deploymentWithImages("web", "nginx:1.26", "proxy", "envoy:1.28")
+	new.Spec.Template.Spec.Containers[1].Args = []string{"--debug"}
+	changes, err := diff.Compute(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var Tests = []struct {
+		images []event.ImageChange
+		paths  []string
+	}{
+		{nil, []string{"spec.template.spec.containers[0].image", "spec.template.spec.containers[1].args"}},
+		{imageChanges(old, new), []string{"spec.template.spec.containers[1].args"}},
+		{[]event.ImageChange{{Container: "web", Old: "nginx:1.24", New: "nginx:1.26"}}, []string{"spec.template.spec.containers[0].image", "spec.template.spec.containers[1].args"}},
+	}
+
+	for _, test := range Tests {
+		var paths []string
+		for _, change := range withoutImageChanges(changes, test.images) {
+			paths = append(paths, change.Path)
+		}
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("withoutImageChanges(%v) paths = %v, expected %v", test.images, paths, test.paths)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// ingressTLSChecker reports the certificates of the TLS Secrets referenced by Ingresses that
+// expire within a window. Each certificate is reported once per expiry, so a renewed
+// certificate is reported again when it approaches its new expiry.
+type ingressTLSChecker struct {
+	w      *clusterWatcher
+	logger *logrus.Entry
+	window time.Duration
+	now    func() time.Time
+	// notified holds the expiry last reported for each Secret
+	notified map[types.UID]time.Time
+}
+
+// secretUsage is a TLS Secret and the Ingresses and hosts it serves
+type secretUsage struct {
+	namespace string
+	name      string
+	ingresses []string
+	hosts     []string
+}
+
+// checkIngressCertificates checks the TLS Secrets of the Ingresses of the watched namespaces
+// every interval until stopCh is closed
+func (w *clusterWatcher) checkIngressCertificates(window, interval time.Duration, stopCh <-chan struct{}) {
+	d := &ingressTLSChecker{
+		w:        w,
+		logger:   logrus.WithField("pkg", "kubewatch-ingress-tls"),
+		window:   window,
+		now:      time.Now,
+		notified: map[types.UID]time.Time{},
+	}
+	if w.clusterName != "" {
+		d.logger = d.logger.WithField("cluster", w.clusterName)
+	}
+	wait.Until(d.check, interval, stopCh)
+}
+
+func (d *ingressTLSChecker) check() {
+	d.w.mu.Lock()
+	namespaces := make([]string, 0, len(d.w.namespaces))
+	for namespace := range d.w.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	d.w.mu.Unlock()
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		for _, usage := range d.secrets(namespace) {
+			if e := d.checkSecret(usage); e != nil {
+				d.w.notify(*e)
+			}
+		}
+	}
+}
+
+// secrets returns the TLS Secrets referenced by the Ingresses of namespace, an empty
+// namespace meaning all namespaces
+func (d *ingressTLSChecker) secrets(namespace string) []*secretUsage {
+	ingresses, err := d.w.kubeClient.NetworkingV1().Ingresses(namespace).List(context.Background(), meta_v1.ListOptions{})
+	if err != nil {
+		d.logger.Warnf("Cannot list the Ingresses of namespace %q: %v", namespace, err)
+		return nil
+	}
+	var usages []*secretUsage
+	byKey := map[string]*secretUsage{}
+	for _, ingress := range ingresses.Items {
+		for _, tls := range ingress.Spec.TLS {
+			if tls.SecretName == "" {
+				continue
+			}
+			key := ingress.Namespace + "/" + tls.SecretName
+			usage, ok := byKey[key]
+			if !ok {
+				usage = &secretUsage{namespace: ingress.Namespace, name: tls.SecretName}
+				byKey[key] = usage
+				usages = append(usages, usage)
+			}
+			usage.ingresses = appendUnique(usage.ingresses, ingress.Name)
+			for _, host := range tls.Hosts {
+				usage.hosts = appendUnique(usage.hosts, host)
+			}
+		}
+	}
+	return usages
+}
+
+// checkSecret returns the event reporting the certificate of the Secret of usage when it
+// expires within the window and was not reported yet, nil otherwise
+func (d *ingressTLSChecker) checkSecret(usage *secretUsage) *event.Event {
+	secret, err := d.w.kubeClient.CoreV1().Secrets(usage.namespace).Get(context.Background(), usage.name, meta_v1.GetOptions{})
+	if err != nil {
+		d.logger.Warnf("Cannot read TLS Secret %s/%s of Ingress %s: %v", usage.namespace, usage.name, strings.Join(usage.ingresses, ", "), err)
+		return nil
+	}
+	cert, err := leafCertificate(secret.Data[api_v1.TLSCertKey])
+	if err != nil {
+		d.logger.Warnf("Cannot read the certificate of TLS Secret %s/%s: %v", usage.namespace, usage.name, err)
+		return nil
+	}
+
+	now := d.now()
+	notAfter := cert.NotAfter
+	if !notAfter.Before(now.Add(d.window)) {
+		return nil
+	}
+	if last, ok := d.notified[secret.UID]; ok && last.Equal(notAfter) {
+		return nil
+	}
+	d.notified[secret.UID] = notAfter
+
+	status, expiry := "Warning", "expires in "+duration.HumanDuration(notAfter.Sub(now))
+	if !notAfter.After(now) {
+		status, expiry = "Danger", "expired "+duration.HumanDuration(now.Sub(notAfter))+" ago"
+	}
+	reason := fmt.Sprintf("TLS Secret %s of Ingress %s %s, at %s",
+		usage.name, strings.Join(usage.ingresses, ", "), expiry, notAfter.Format(time.RFC3339))
+	if len(usage.hosts) > 0 {
+		reason += "\nHosts: " + strings.Join(usage.hosts, ", ")
+	}
+	// only the metadata of the Secret is sent along, never its keys
+	obj := &api_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{
+		Name: secret.Name, Namespace: secret.Namespace, UID: secret.UID, Labels: secret.Labels,
+	}}
+	return &event.Event{
+		Name:       usage.name,
+		Namespace:  usage.namespace,
+		Kind:       "CertificateExpiring",
+		APIVersion: V1,
+		Status:     status,
+		Reason:     reason,
+		Obj:        obj,
+	}
+}
+
+// leafCertificate returns the first certificate of a PEM bundle, the certificate of the
+// server followed by its chain
+func leafCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// appendUnique appends s to values unless it holds it already
+func appendUnique(values []string, s string) []string {
+	for _, v := range values {
+		if v == s {
+			return values
+		}
+	}
+	return append(values, s)
+}
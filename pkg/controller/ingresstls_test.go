@@ -0,0 +1,115 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// certificatePEM returns a self-signed certificate expiring at notAfter, followed by its key
+func certificatePEM(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "shop.example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})...)
+}
+
+func TestIngressTLSChecker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	secret := func(name string, notAfter time.Time) *api_v1.Secret {
+		return &api_v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "shop", UID: types.UID(name)},
+			Type:       api_v1.SecretTypeTLS,
+			Data:       map[string][]byte{api_v1.TLSCertKey: certificatePEM(t, notAfter), api_v1.TLSPrivateKeyKey: []byte("key")},
+		}
+	}
+	ingress := func(name string, secrets ...string) *networking_v1.Ingress {
+		i := &networking_v1.Ingress{ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "shop"}}
+		for _, s := range secrets {
+			i.Spec.TLS = append(i.Spec.TLS, networking_v1.IngressTLS{SecretName: s, Hosts: []string{"shop.example.com"}})
+		}
+		return i
+	}
+	client := fake.NewSimpleClientset(
+		secret("web-tls", now.Add(6*24*time.Hour)),
+		secret("api-tls", now.Add(90*24*time.Hour)),
+		secret("old-tls", now.Add(-time.Hour)),
+		ingress("web", "web-tls"),
+		ingress("web-canary", "web-tls"),
+		ingress("api", "api-tls", "old-tls", "missing-tls"),
+	)
+
+	r := &recorder{}
+	w := newClusterWatcher(&config.Config{}, r, Cluster{KubeClient: client}, nil, nil)
+	w.namespaces[""] = nil
+	d := &ingressTLSChecker{w: w, logger: logrus.WithField("pkg", "test"), window: 30 * 24 * time.Hour,
+		now: func() time.Time { return now }, notified: map[types.UID]time.Time{}}
+	d.check()
+	d.check()
+
+	if len(r.events) != 2 {
+		t.Fatalf("expected the expiring and expired certificates to be reported once, got %q", r.names())
+	}
+	byName := map[string]string{}
+	for _, e := range r.events {
+		byName[e.Name] = e.Status + " " + e.Reason
+		if secret, ok := e.Obj.(*api_v1.Secret); !ok || secret.Data != nil {
+			t.Errorf("expected only the metadata of the Secret to be attached, got %+v", e.Obj)
+		}
+	}
+	expected := map[string]string{
+		"web-tls": "Warning TLS Secret web-tls of Ingress web, web-canary expires in 6d, at 2024-05-07T12:00:00Z\nHosts: shop.example.com",
+		"old-tls": "Danger TLS Secret old-tls of Ingress api expired 60m ago, at 2024-05-01T11:00:00Z\nHosts: shop.example.com",
+	}
+	for name, reason := range expected {
+		if byName[name] != reason {
+			t.Errorf("%s: expected %q, got %q", name, reason, byName[name])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+// internalErrorInterval is the minimum time between two reports of the same internal error
+const internalErrorInterval = time.Hour
+
+var internalErrors struct {
+	mu sync.Mutex
+	// handler receives the internal error events, nil when they are not reported
+	handler handlers.Handler
+	// reported holds when each internal error was last reported
+	reported map[string]time.Time
+}
+
+// SetInternalErrorHandler sets the handler receiving the events about kubewatch failures:
+// repeatedly failing deliveries to the event handler, and watches broken by missing permissions
+func SetInternalErrorHandler(h handlers.Handler) {
+	internalErrors.mu.Lock()
+	defer internalErrors.mu.Unlock()
+	internalErrors.handler = h
+	internalErrors.reported = map[string]time.Time{}
+}
+
+// HandlerFailing returns whether the last deliveries to the event handler failed
+func HandlerFailing() bool {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+	return health.failures >= handlerFailureThreshold
+}
+
+// reportInternalError sends an internal error event to the internal error handler, unless
+// the same error, identified by key, was reported less than internalErrorInterval ago
+func reportInternalError(key string, e event.Event) {
+	internalErrors.mu.Lock()
+	h := internalErrors.handler
+	if h == nil || time.Since(internalErrors.reported[key]) < internalErrorInterval {
+		internalErrors.mu.Unlock()
+		return
+	}
+	internalErrors.reported[key] = time.Now()
+	internalErrors.mu.Unlock()
+
+	e.ID = uuid.New().String()
+	e.Kind = "KubewatchError"
+	e.Timestamp = time.Now()
+	e.Severity = event.SeverityFromStatus(e.Status)
+	go h.Handle(e)
+}
+
+// reportDeliveryFailures reports that the last failures deliveries to the event handler failed
+func reportDeliveryFailures(failures int, err error) {
+	reportInternalError("handler", event.Event{
+		Name:   "handler",
+		Status: "Danger",
+		Reason: fmt.Sprintf("The last %d deliveries to the event handler failed, notifications are being lost: %v", failures, err),
+	})
+}
+
+// reportDeliveryRecovery reports that the event handler delivers events again
+func reportDeliveryRecovery(failures int) {
+	internalErrors.mu.Lock()
+	delete(internalErrors.reported, "handler")
+	internalErrors.mu.Unlock()
+	reportInternalError("handler-recovered", event.Event{
+		Name:   "handler",
+		Status: "Normal",
+		Reason: fmt.Sprintf("The event handler delivers events again, after %d failed deliveries", failures),
+	})
+}
+
+// watchErrorHandler reports the watches of the controller failing for lack of permissions,
+// other errors are only logged
+func (c *Controller) watchErrorHandler(ctx context.Context, r *cache.Reflector, err error) {
+	cache.DefaultWatchErrorHandler(ctx, r, err)
+	if !apierrors.IsForbidden(err) {
+		return
+	}
+	scope := "cluster-wide"
+	if c.namespace != "" {
+		scope = "in namespace " + c.namespace
+	}
+	reportInternalError(fmt.Sprintf("forbidden/%s/%s/%s", c.clusterName, c.resourceType, c.namespace), event.Event{
+		Name:        c.resourceType,
+		Namespace:   c.namespace,
+		ClusterName: c.clusterName,
+		Status:      "Danger",
+		Reason:      fmt.Sprintf("Watching %s %s is forbidden, its events are not reported: %v", c.resourceType, scope, err),
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+)
+
+// waitForStatuses waits for r to receive KubewatchError events with the given statuses
+func waitForStatuses(t *testing.T, r *recorder, expected ...string) {
+	t.Helper()
+	statuses := func() []string {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		var statuses []string
+		for _, e := range r.events {
+			if e.Kind == "KubewatchError" {
+				statuses = append(statuses, e.Status)
+			}
+		}
+		return statuses
+	}
+	err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return len(statuses()) >= len(expected), nil
+	})
+	got := statuses()
+	if err != nil || len(got) != len(expected) {
+		t.Fatalf("expected internal errors %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected internal errors %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestReportDeliveryFailures(t *testing.T) {
+	health.failures = 0
+	r := &recorder{}
+	SetInternalErrorHandler(r)
+	defer SetInternalErrorHandler(nil)
+
+	for i := 0; i < handlerFailureThreshold-1; i++ {
+		recordDelivery(errors.New("connection refused"))
+	}
+	if HandlerFailing() {
+		t.Fatal("expected the handler not to be failing below the threshold")
+	}
+	recordDelivery(errors.New("connection refused"))
+	if !HandlerFailing() {
+		t.Fatal("expected the handler to be failing at the threshold")
+	}
+	waitForStatuses(t, r, "Danger")
+
+	// further failures are not reported again
+	recordDelivery(errors.New("connection refused"))
+	recordDelivery(nil)
+	waitForStatuses(t, r, "Danger", "Normal")
+	if HandlerFailing() {
+		t.Fatal("expected the handler to recover after a successful delivery")
+	}
+
+	// a new outage is reported
+	for i := 0; i < handlerFailureThreshold; i++ {
+		recordDelivery(errors.New("connection refused"))
+	}
+	waitForStatuses(t, r, "Danger", "Normal", "Danger")
+	health.failures = 0
+}
+
+func TestWatchErrorHandler(t *testing.T) {
+	r := &recorder{}
+	SetInternalErrorHandler(r)
+	defer SetInternalErrorHandler(nil)
+
+	c := &Controller{clusterName: "prod", resourceType: "Secret", namespace: "team-a"}
+	reflector := cache.NewReflector(&cache.ListWatch{}, &api_v1.Secret{}, cache.NewStore(cache.MetaNamespaceKeyFunc), 0)
+
+	c.watchErrorHandler(context.Background(), reflector, errors.New("connection reset"))
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("no RBAC policy matched"))
+	c.watchErrorHandler(context.Background(), reflector, forbidden)
+	c.watchErrorHandler(context.Background(), reflector, forbidden)
+	waitForStatuses(t, r, "Danger")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.events[0]; e.Name != "Secret" || e.Namespace != "team-a" || e.ClusterName != "prod" {
+		t.Errorf("expected the forbidden Secret watch of prod/team-a to be reported, got %+v", e)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxInterruptedPods is the number of pods listed in node interruption warnings
+const maxInterruptedPods = 10
+
+// interruptionTaints describe the taints announcing a node is about to go away, by key
+var interruptionTaints = map[string]string{
+	"ToBeDeletedByClusterAutoscaler":                         "scale-down by the cluster autoscaler",
+	"cloud.google.com/impending-node-termination":            "spot or preemptible VM termination",
+	"aws-node-termination-handler/spot-itn":                  "EC2 spot interruption",
+	"aws-node-termination-handler/rebalance-recommendation":  "EC2 rebalance recommendation",
+	"aws-node-termination-handler/scheduled-maintenance":     "EC2 scheduled maintenance",
+	"aws-node-termination-handler/asg-lifecycle-termination": "Auto Scaling group termination",
+}
+
+// interruptionConditions describe the node conditions set by the node problem detector of
+// AKS for the scheduled events of the VM of a node
+var interruptionConditions = map[api_v1.NodeConditionType]string{
+	"PreemptScheduled":   "spot VM eviction",
+	"TerminateScheduled": "scheduled termination",
+	"RebootScheduled":    "scheduled reboot",
+	"RedeployScheduled":  "scheduled redeployment",
+	"FreezeScheduled":    "scheduled maintenance freeze",
+}
+
+// nodeInterruptions warns about the nodes about to be interrupted, as soon as they get an
+// interruption taint or condition, listing the pods that will have to move.
+type nodeInterruptions struct {
+	objects *objectCache
+	// taints describe the interruption taints, the known ones and the configured ones
+	taints map[string]string
+}
+
+func newNodeInterruptions(conf config.NodeInterruptions, objects *objectCache) *nodeInterruptions {
+	taints := make(map[string]string, len(interruptionTaints)+len(conf.Taints))
+	for key, description := range interruptionTaints {
+		taints[key] = description
+	}
+	for _, key := range conf.Taints {
+		if _, ok := taints[key]; !ok {
+			taints[key] = "interruption"
+		}
+	}
+	return &nodeInterruptions{objects: objects, taints: taints}
+}
+
+// nodeInterruptionEvent returns a NodeInterruption event when a node update adds an
+// interruption taint or condition, nil otherwise
+func (c *Controller) nodeInterruptionEvent(e Event) *event.Event {
+	node, ok := e.obj.(*api_v1.Node)
+	if !ok || e.eventType != "update" {
+		return nil
+	}
+	old, ok := e.oldObj.(*api_v1.Node)
+	if !ok {
+		return nil
+	}
+	causes := c.interruptions.causes(old, node)
+	if len(causes) == 0 {
+		return nil
+	}
+	reason := "Expecting " + strings.Join(causes, ", ")
+	if pods := c.interruptions.pods(node.Name); len(pods) > 0 {
+		reason += "\n" + pluralize(len(pods), "pod") + " to move: "
+		if len(pods) > maxInterruptedPods {
+			reason += strings.Join(pods[:maxInterruptedPods], ", ") + fmt.Sprintf(" and %d more", len(pods)-maxInterruptedPods)
+		} else {
+			reason += strings.Join(pods, ", ")
+		}
+	}
+	return newNodeEvent(e, node.Name, "NodeInterruption", "Warning", reason)
+}
+
+// causes describes the interruption taints and conditions node has and old had not
+func (n *nodeInterruptions) causes(old, node *api_v1.Node) []string {
+	var causes []string
+	for _, taint := range node.Spec.Taints {
+		description, ok := n.taints[taint.Key]
+		if ok && !hasTaint(old, taint.Key) {
+			causes = append(causes, fmt.Sprintf("%s (taint %s)", description, taint.Key))
+		}
+	}
+	for _, condition := range node.Status.Conditions {
+		description, ok := interruptionConditions[condition.Type]
+		if ok && condition.Status == api_v1.ConditionTrue && !hasCondition(old, condition.Type) {
+			causes = append(causes, fmt.Sprintf("%s (condition %s)", description, condition.Type))
+		}
+	}
+	return causes
+}
+
+// pods returns the namespaces and names of the watched pods of node that will move once
+// it goes away, leaving out the pods of DaemonSets and the completed pods
+func (n *nodeInterruptions) pods(node string) []string {
+	var pods []string
+	for _, item := range n.objects.list(objName(api_v1.Pod{})) {
+		pod, ok := item.(*api_v1.Pod)
+		if !ok || pod.Spec.NodeName != node || pod.Status.Phase == api_v1.PodSucceeded || pod.Status.Phase == api_v1.PodFailed {
+			continue
+		}
+		if owner := meta_v1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		pods = append(pods, pod.Namespace+"/"+pod.Name)
+	}
+	sort.Strings(pods)
+	return pods
+}
+
+func hasTaint(node *api_v1.Node, key string) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCondition(node *api_v1.Node, conditionType api_v1.NodeConditionType) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == api_v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
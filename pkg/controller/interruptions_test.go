@@ -0,0 +1,108 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNodeInterruptions(t *testing.T) {
+	node := func(taints []string, conditions ...api_v1.NodeConditionType) *api_v1.Node {
+		n := &api_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: "node-1", UID: "node-uid"}}
+		for _, key := range taints {
+			n.Spec.Taints = append(n.Spec.Taints, api_v1.Taint{Key: key, Effect: api_v1.TaintEffectNoSchedule})
+		}
+		for _, condition := range conditions {
+			n.Status.Conditions = append(n.Status.Conditions, api_v1.NodeCondition{Type: condition, Status: api_v1.ConditionTrue})
+		}
+		return n
+	}
+	pod := func(namespace, name, nodeName string, owner string) *api_v1.Pod {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: namespace}}
+		p.Spec.NodeName = nodeName
+		if owner != "" {
+			controller := true
+			p.OwnerReferences = []meta_v1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+		}
+		return p
+	}
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pods.Add(pod("payments", "api-7d9f", "node-1", "ReplicaSet"))
+	pods.Add(pod("kube-system", "kube-proxy-x2", "node-1", "DaemonSet"))
+	pods.Add(pod("batch", "trainer-0", "node-1", "StatefulSet"))
+	pods.Add(pod("payments", "api-a1b2", "node-2", "ReplicaSet"))
+
+	var Tests = []struct {
+		name     string
+		old, new *api_v1.Node
+		expected string
+	}{
+		{"scale-down", node(nil), node([]string{"ToBeDeletedByClusterAutoscaler"}),
+			"Expecting scale-down by the cluster autoscaler (taint ToBeDeletedByClusterAutoscaler)\n2 pods to move: batch/trainer-0, payments/api-7d9f"},
+		{"spot interruption", node([]string{"ToBeDeletedByClusterAutoscaler"}), node([]string{"ToBeDeletedByClusterAutoscaler", "aws-node-termination-handler/spot-itn"}),
+			"Expecting EC2 spot interruption (taint aws-node-termination-handler/spot-itn)\n2 pods to move: batch/trainer-0, payments/api-7d9f"},
+		{"scheduled event", node(nil), node(nil, "PreemptScheduled"),
+			"Expecting spot VM eviction (condition PreemptScheduled)\n2 pods to move: batch/trainer-0, payments/api-7d9f"},
+		{"configured taint", node(nil), node([]string{"example.com/draining"}),
+			"Expecting interruption (taint example.com/draining)\n2 pods to move: batch/trainer-0, payments/api-7d9f"},
+		{"already tainted", node([]string{"ToBeDeletedByClusterAutoscaler"}), node([]string{"ToBeDeletedByClusterAutoscaler"}), ""},
+		{"other taint", node(nil), node([]string{"node.kubernetes.io/unreachable"}), ""},
+	}
+
+	w := newClusterWatcher(&config.Config{}, &recorder{}, Cluster{}, nil, nil)
+	w.objects.add(objName(api_v1.Pod{}), pods, nil)
+	c := &Controller{interruptions: newNodeInterruptions(config.NodeInterruptions{Enabled: true, Taints: []string{"example.com/draining"}}, w.objects)}
+	for _, tt := range Tests {
+		e := c.nodeInterruptionEvent(Event{eventType: "update", oldObj: tt.old, obj: tt.new})
+		switch {
+		case tt.expected == "" && e != nil:
+			t.Errorf("%s: expected no event, got %q", tt.name, e.Reason)
+		case tt.expected != "" && e == nil:
+			t.Errorf("%s: expected an event, got none", tt.name)
+		case e != nil && (e.Kind != "NodeInterruption" || e.Name != "node-1" || e.Reason != tt.expected):
+			t.Errorf("%s: expected %q, got %s %q", tt.name, tt.expected, e.Kind, e.Reason)
+		}
+	}
+}
+
+func TestNodeInterruptionsListsPods(t *testing.T) {
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for i := 0; i < 12; i++ {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: fmt.Sprintf("web-%02d", i), Namespace: "web"}}
+		p.Spec.NodeName = "node-1"
+		pods.Add(p)
+	}
+	w := newClusterWatcher(&config.Config{}, &recorder{}, Cluster{}, nil, nil)
+	w.objects.add(objName(api_v1.Pod{}), pods, nil)
+	old := &api_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: "node-1"}}
+	node := old.DeepCopy()
+	node.Spec.Taints = []api_v1.Taint{{Key: "cloud.google.com/impending-node-termination"}}
+
+	c := &Controller{interruptions: newNodeInterruptions(config.NodeInterruptions{Enabled: true}, w.objects)}
+	e := c.nodeInterruptionEvent(Event{eventType: "update", oldObj: old, obj: node})
+	expected := "Expecting spot or preemptible VM termination (taint cloud.google.com/impending-node-termination)\n" +
+		"12 pods to move: web/web-00, web/web-01, web/web-02, web/web-03, web/web-04, web/web-05, web/web-06, web/web-07, web/web-08, web/web-09 and 2 more"
+	if e == nil || e.Reason != expected {
+		t.Errorf("expected %q, got %+v", expected, e)
+	}
+}
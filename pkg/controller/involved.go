@@ -0,0 +1,180 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// objectCache gives access to the informer caches of every watched kind
+type objectCache struct {
+	mu sync.RWMutex
+	// stores holds the informer stores by kind, one per watched namespace
+	stores map[string][]cache.Store
+}
+
+func newObjectCache() *objectCache {
+	return &objectCache{stores: map[string][]cache.Store{}}
+}
+
+// add makes store available for lookups of kind until stopCh is closed
+func (o *objectCache) add(kind string, store cache.Store, stopCh <-chan struct{}) {
+	o.mu.Lock()
+	o.stores[kind] = append(o.stores[kind], store)
+	o.mu.Unlock()
+
+	go func() {
+		<-stopCh
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		stores := o.stores[kind]
+		for i := range stores {
+			if stores[i] == store {
+				o.stores[kind] = append(stores[:i:i], stores[i+1:]...)
+				break
+			}
+		}
+	}()
+}
+
+// get returns the metadata of the cached object of kind, nil if it is not cached
+func (o *objectCache) get(kind, namespace, name string) meta_v1.Object {
+	if accessor, err := meta.Accessor(o.getObject(kind, namespace, name)); err == nil {
+		return accessor
+	}
+	return nil
+}
+
+// getObject returns the cached object of kind, nil if it is not cached
+func (o *objectCache) getObject(kind, namespace, name string) runtime.Object {
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for _, store := range o.stores[kind] {
+		obj, exists, err := store.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if object, ok := obj.(runtime.Object); ok {
+			return object
+		}
+	}
+	return nil
+}
+
+// list returns the cached objects of kind
+func (o *objectCache) list(kind string) []interface{} {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var objects []interface{}
+	for _, store := range o.stores[kind] {
+		objects = append(objects, store.List()...)
+	}
+	return objects
+}
+
+// byIndex returns the cached objects of kind whose index indexName holds value, from the
+// stores maintaining that index
+func (o *objectCache) byIndex(kind, indexName, value string) []interface{} {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var objects []interface{}
+	for _, store := range o.stores[kind] {
+		indexer, ok := store.(cache.Indexer)
+		if !ok {
+			continue
+		}
+		if indexed, err := indexer.ByIndex(indexName, value); err == nil {
+			objects = append(objects, indexed...)
+		}
+	}
+	return objects
+}
+
+// all returns the cached objects of every kind
+func (o *objectCache) all() []interface{} {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var objects []interface{}
+	for _, stores := range o.stores {
+		for _, store := range stores {
+			objects = append(objects, store.List()...)
+		}
+	}
+	return objects
+}
+
+// involvedObject resolves the object a Kubernetes Event is about from the informer caches,
+// nil if obj is not a Kubernetes Event
+func (c *Controller) involvedObject(obj runtime.Object) *event.InvolvedObject {
+	ref, ok := regarding(obj)
+	if !ok || ref.Kind == "" || ref.Name == "" {
+		return nil
+	}
+
+	involved := &event.InvolvedObject{Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace}
+	if object := c.objects.get(ref.Kind, ref.Namespace, ref.Name); object != nil {
+		involved.Labels = object.GetLabels()
+		involved.Owner = c.owner(object)
+	}
+	if ref.Namespace != "" {
+		if ns := c.objects.get(objName(api_v1.Namespace{}), "", ref.Namespace); ns != nil {
+			involved.NamespaceAnnotations = ns.GetAnnotations()
+		}
+	}
+	return involved
+}
+
+// owner returns the top-level controller of object as "Kind/name", resolving the Deployment
+// of ReplicaSets and the CronJob of Jobs, empty if object has no controller
+func (c *Controller) owner(object meta_v1.Object) string {
+	ref := meta_v1.GetControllerOf(object)
+	if ref == nil {
+		return ""
+	}
+
+	switch ref.Kind {
+	case objName(apps_v1.ReplicaSet{}), objName(batch_v1.Job{}):
+		if parent := c.objects.get(ref.Kind, object.GetNamespace(), ref.Name); parent != nil {
+			if owner := meta_v1.GetControllerOf(parent); owner != nil {
+				return owner.Kind + "/" + owner.Name
+			}
+			return ref.Kind + "/" + ref.Name
+		}
+		// ReplicaSets of Deployments are named after the pod template hash
+		if hash := object.GetLabels()[apps_v1.DefaultDeploymentUniqueLabelKey]; hash != "" && ref.Kind == objName(apps_v1.ReplicaSet{}) {
+			if deployment := strings.TrimSuffix(ref.Name, "-"+hash); deployment != ref.Name {
+				return objName(apps_v1.Deployment{}) + "/" + deployment
+			}
+		}
+	}
+	return ref.Kind + "/" + ref.Name
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+func ownedBy(kind, name string) []meta_v1.OwnerReference {
+	controller := true
+	return []meta_v1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func eventAbout(kind, name string) *api_v1.Event {
+	return &api_v1.Event{
+		ObjectMeta:     meta_v1.ObjectMeta{Name: name + ".17a", Namespace: "default"},
+		InvolvedObject: api_v1.ObjectReference{Kind: kind, Name: name, Namespace: "default"},
+	}
+}
+
+func TestInvolvedObject(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	objects := newObjectCache()
+	store := func(kind string, objs ...interface{}) {
+		s := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		for _, obj := range objs {
+			s.Add(obj)
+		}
+		objects.add(kind, s, stopCh)
+	}
+
+	hashed := newPod("web-5d8f-x2x", time.Now())
+	hashed.Labels = map[string]string{"app": "web", apps_v1.DefaultDeploymentUniqueLabelKey: "5d8f"}
+	hashed.OwnerReferences = ownedBy("ReplicaSet", "web-5d8f")
+	job := newPod("backup-28-abc", time.Now())
+	job.OwnerReferences = ownedBy("Job", "backup-28")
+	standalone := newPod("debug", time.Now())
+	store("Pod", hashed, job, standalone)
+	store("Job", &batch_v1.Job{ObjectMeta: meta_v1.ObjectMeta{Name: "backup-28", Namespace: "default", OwnerReferences: ownedBy("CronJob", "backup")}})
+	store("Namespace", &api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "default", Annotations: map[string]string{"team": "payments"}}})
+
+	c := &Controller{objects: objects}
+	nsAnnotations := map[string]string{"team": "payments"}
+
+	var Tests = []struct {
+		obj      runtime.Object
+		involved *event.InvolvedObject
+	}{
+		{eventAbout("Pod", "web-5d8f-x2x"), &event.InvolvedObject{
+			Kind: "Pod", Name: "web-5d8f-x2x", Namespace: "default",
+			Labels: hashed.Labels, Owner: "Deployment/web", NamespaceAnnotations: nsAnnotations,
+		}},
+		{eventAbout("Pod", "backup-28-abc"), &event.InvolvedObject{
+			Kind: "Pod", Name: "backup-28-abc", Namespace: "default", Owner: "CronJob/backup", NamespaceAnnotations: nsAnnotations,
+		}},
+		{eventAbout("Pod", "debug"), &event.InvolvedObject{
+			Kind: "Pod", Name: "debug", Namespace: "default", NamespaceAnnotations: nsAnnotations,
+		}},
+		{eventAbout("Service", "web"), &event.InvolvedObject{
+			Kind: "Service", Name: "web", Namespace: "default", NamespaceAnnotations: nsAnnotations,
+		}},
+		{newPod("web", time.Now()), nil},
+	}
+
+	for i, test := range Tests {
+		if involved := c.involvedObject(test.obj); !reflect.DeepEqual(involved, test.involved) {
+			t.Errorf("%d: involvedObject() = %+v, expected %+v", i, involved, test.involved)
+		}
+	}
+}
+
+func TestObjectCacheStop(t *testing.T) {
+	objects := newObjectCache()
+	s := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	s.Add(newPod("web", time.Now()))
+	stopCh := make(chan struct{})
+	objects.add("Pod", s, stopCh)
+
+	if objects.get("Pod", "default", "web") == nil {
+		t.Fatal("expected the pod to be cached")
+	}
+	close(stopCh)
+	for i := 0; objects.get("Pod", "default", "web") != nil; i++ {
+		if i == 100 {
+			t.Fatal("expected the store to be removed once stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// jobRun describes the run of a Job completed by an update, nil for other updates
+func jobRun(old, new runtime.Object) *event.JobRun {
+	oldJob, ok := old.(*batch_v1.Job)
+	if !ok {
+		return nil
+	}
+	job, ok := new.(*batch_v1.Job)
+	if !ok || !jobComplete(job) || jobComplete(oldJob) {
+		return nil
+	}
+
+	run := &event.JobRun{Succeeded: job.Status.Succeeded, Failed: job.Status.Failed}
+	if job.Status.StartTime != nil {
+		run.StartTime = job.Status.StartTime.Time
+	} else {
+		run.StartTime = job.CreationTimestamp.Time
+	}
+	if job.Status.CompletionTime != nil {
+		run.CompletionTime = job.Status.CompletionTime.Time
+	} else {
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == batch_v1.JobComplete {
+				run.CompletionTime = condition.LastTransitionTime.Time
+			}
+		}
+	}
+	return run
+}
+
+// jobComplete returns whether job completed successfully
+func jobComplete(job *batch_v1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batch_v1.JobComplete && condition.Status == api_v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJobRun(t *testing.T) {
+	start := meta_v1.NewTime(time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC))
+	end := meta_v1.NewTime(start.Add(252 * time.Second))
+	running := &batch_v1.Job{Status: batch_v1.JobStatus{StartTime: &start, Active: 1, Failed: 1}}
+	completed := running.DeepCopy()
+	completed.Status = batch_v1.JobStatus{
+		StartTime: &start, CompletionTime: &end, Succeeded: 3, Failed: 1,
+		Conditions: []batch_v1.JobCondition{{Type: batch_v1.JobComplete, Status: api_v1.ConditionTrue, LastTransitionTime: end}},
+	}
+	failed := running.DeepCopy()
+	failed.Status.Conditions = []batch_v1.JobCondition{{Type: batch_v1.JobFailed, Status: api_v1.ConditionTrue}}
+
+	run := jobRun(running, completed)
+	if run == nil || run.String() != "completed in 4m12s, 3 pods succeeded, 1 pod failed" {
+		t.Errorf("unexpected job run %+v", run)
+	}
+	if run := jobRun(completed, completed); run != nil {
+		t.Errorf("expected no job run for updates of completed jobs, got %+v", run)
+	}
+	if run := jobRun(running, failed); run != nil {
+		t.Errorf("expected no job run for failed jobs, got %+v", run)
+	}
+}
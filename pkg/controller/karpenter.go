@@ -0,0 +1,244 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	resource_api "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Karpenter resources and the labels Karpenter sets on NodeClaims
+const (
+	karpenterGroup      = "karpenter.sh"
+	karpenterNodeClaims = "nodeclaims"
+	karpenterNodePools  = "nodepools"
+	karpenterComponent  = "karpenter"
+
+	nodePoolLabel     = "karpenter.sh/nodepool"
+	capacityTypeLabel = "karpenter.sh/capacity-type"
+	instanceTypeLabel = "node.kubernetes.io/instance-type"
+	zoneLabel         = "topology.kubernetes.io/zone"
+)
+
+var karpenterCRDs = []config.CRD{
+	{Group: karpenterGroup, Version: "v1", Resource: karpenterNodeClaims},
+	{Group: karpenterGroup, Version: "v1", Resource: karpenterNodePools},
+}
+
+// karpenterResourceOf returns the Karpenter resource of crd, empty for other resources
+func karpenterResourceOf(crd config.CRD) string {
+	if crd.Group == karpenterGroup && (crd.Resource == karpenterNodeClaims || crd.Resource == karpenterNodePools) {
+		return crd.Resource
+	}
+	return ""
+}
+
+// withKarpenterCRDs returns crds with the Karpenter NodeClaims and NodePools appended unless
+// they are already configured
+func withKarpenterCRDs(crds []config.CRD) []config.CRD {
+	for _, r := range karpenterCRDs {
+		configured := false
+		for _, crd := range crds {
+			if crd.Group == r.Group && crd.Resource == r.Resource {
+				configured = true
+				break
+			}
+		}
+		if !configured {
+			crds = append(crds, r)
+		}
+	}
+	return crds
+}
+
+// karpenterEvent returns the event summarizing a change of a Karpenter NodeClaim or NodePool:
+// nodes provisioned, failing to launch, disrupted and removed, and NodePools turning not
+// ready or reaching their limits. It returns nil for other changes.
+func (c *Controller) karpenterEvent(e Event) *event.Event {
+	obj, ok := e.obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	oldObj, _ := e.oldObj.(*unstructured.Unstructured)
+	var kind, status, reason string
+	switch c.karpenter {
+	case karpenterNodeClaims:
+		kind, status, reason = nodeClaimChange(e.eventType, oldObj, obj)
+	case karpenterNodePools:
+		kind, status, reason = nodePoolChange(e.eventType, oldObj, obj)
+	}
+	if kind == "" {
+		return nil
+	}
+	return &event.Event{
+		Name:       e.key,
+		Kind:       kind,
+		APIVersion: e.apiVersion,
+		Status:     status,
+		Reason:     reason,
+		Obj:        e.obj,
+		OldObj:     e.oldObj,
+	}
+}
+
+// nodeClaimChange returns the kind, status and reason of the event about a NodeClaim
+// change, an empty kind when it is not worth reporting
+func nodeClaimChange(eventType string, oldObj, obj *unstructured.Unstructured) (string, string, string) {
+	summary := nodeClaimSummary(obj)
+	switch eventType {
+	case "delete":
+		return "NodeClaimRemoved", "Normal", "Removed " + summary
+	case "update":
+		if oldObj == nil {
+			return "", "", ""
+		}
+	default:
+		return "", "", ""
+	}
+
+	if initialized, ok := findCondition(obj, "Initialized"); ok && initialized.Status == "True" {
+		if old, ok := findCondition(oldObj, "Initialized"); !ok || old.Status != "True" {
+			return "NodeClaimProvisioned", "Normal", "Provisioned " + summary
+		}
+	}
+	if launched, ok := findCondition(obj, "Launched"); ok && launched.Status == "False" {
+		if old, ok := findCondition(oldObj, "Launched"); !ok || old.Status != "False" {
+			return "NodeClaimLaunchFailed", "Warning", "Cannot launch " + summary + ": " + conditionText(launched)
+		}
+	}
+	disruption, disrupting := findCondition(obj, "DisruptionReason")
+	if disrupting && disruption.Status == "True" {
+		if _, ok := findCondition(oldObj, "DisruptionReason"); !ok {
+			return "NodeClaimDisrupting", "Normal", "Disrupting " + summary + ": " + conditionText(disruption)
+		}
+	}
+	if obj.GetDeletionTimestamp() != nil && oldObj.GetDeletionTimestamp() == nil && !disrupting {
+		return "NodeClaimTerminating", "Normal", "Terminating " + summary
+	}
+	return "", "", ""
+}
+
+// nodeClaimSummary describes the node of a NodeClaim, such as "node ip-10-0-1-2 (m5.large
+// spot in us-east-1a) of NodePool default"
+func nodeClaimSummary(obj *unstructured.Unstructured) string {
+	labels := obj.GetLabels()
+	summary := "node"
+	if node, _, _ := unstructured.NestedString(obj.Object, "status", "nodeName"); node != "" {
+		summary += " " + node
+	}
+	var details []string
+	for _, label := range []string{instanceTypeLabel, capacityTypeLabel} {
+		if v := labels[label]; v != "" {
+			details = append(details, v)
+		}
+	}
+	if zone := labels[zoneLabel]; zone != "" {
+		details = append(details, "in "+zone)
+	}
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, " ") + ")"
+	}
+	if nodePool := labels[nodePoolLabel]; nodePool != "" {
+		summary += " of NodePool " + nodePool
+	}
+	return summary
+}
+
+// nodePoolChange returns the kind, status and reason of the event about a NodePool
+// change, an empty kind when it is not worth reporting
+func nodePoolChange(eventType string, oldObj, obj *unstructured.Unstructured) (string, string, string) {
+	if eventType != "update" || oldObj == nil {
+		return "", "", ""
+	}
+	ready, ok := findCondition(obj, "Ready")
+	old, oldOk := findCondition(oldObj, "Ready")
+	switch {
+	case ok && ready.Status == "False" && (!oldOk || old.Status != "False"):
+		return "NodePoolNotReady", "Warning", "No node can be provisioned: " + conditionText(ready)
+	case ok && ready.Status == "True" && oldOk && old.Status == "False":
+		return "NodePoolReady", "Normal", "Recovered from NotReady"
+	}
+	if reached := limitsReached(obj); len(reached) > 0 && len(limitsReached(oldObj)) == 0 {
+		return "NodePoolLimitReached", "Warning", "Limits reached, no more nodes will be provisioned: " + strings.Join(reached, ", ")
+	}
+	return "", "", ""
+}
+
+// limitsReached lists the resources a NodePool uses up to its limits, such as "cpu 1000
+// of 1000"
+func limitsReached(obj *unstructured.Unstructured) []string {
+	limits, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "limits")
+	used, _, _ := unstructured.NestedStringMap(obj.Object, "status", "resources")
+	var reached []string
+	for name, limit := range limits {
+		limitQuantity, err := resource_api.ParseQuantity(limit)
+		if err != nil {
+			continue
+		}
+		usedQuantity, err := resource_api.ParseQuantity(used[name])
+		if err != nil {
+			continue
+		}
+		if usedQuantity.Cmp(limitQuantity) >= 0 {
+			reached = append(reached, fmt.Sprintf("%s %s of %s", name, used[name], limit))
+		}
+	}
+	sort.Strings(reached)
+	return reached
+}
+
+// findCondition returns the condition of obj of type conditionType
+func findCondition(obj runtime.Object, conditionType string) (event.Condition, bool) {
+	for _, condition := range conditions(obj) {
+		if condition.Type == conditionType {
+			return condition, true
+		}
+	}
+	return event.Condition{}, false
+}
+
+// conditionText renders the reason and message of a condition
+func conditionText(condition event.Condition) string {
+	switch {
+	case condition.Reason == "":
+		return condition.Message
+	case condition.Message == "":
+		return condition.Reason
+	}
+	return condition.Reason + ": " + condition.Message
+}
+
+// karpenterPodEvent returns whether obj is a Kubernetes Event of Karpenter about a pod,
+// such as Nominated
+func karpenterPodEvent(obj runtime.Object) bool {
+	switch ev := obj.(type) {
+	case *api_v1.Event:
+		return ev.Source.Component == karpenterComponent && ev.InvolvedObject.Kind == "Pod"
+	case *events_v1.Event:
+		return ev.ReportingController == karpenterComponent && ev.Regarding.Kind == "Pod"
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newNodeClaim(conditions ...map[string]interface{}) *unstructured.Unstructured {
+	list := make([]interface{}, len(conditions))
+	for i, condition := range conditions {
+		list[i] = condition
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"nodeName": "ip-10-0-1-2", "conditions": list},
+	}}
+	obj.SetKind("NodeClaim")
+	obj.SetName("default-x7k2p")
+	obj.SetLabels(map[string]string{
+		nodePoolLabel:     "default",
+		capacityTypeLabel: "spot",
+		instanceTypeLabel: "m5.large",
+		zoneLabel:         "us-east-1a",
+	})
+	return obj
+}
+
+func newNodePool(limit, used string, ready string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"limits": map[string]interface{}{"cpu": limit}},
+		"status": map[string]interface{}{
+			"resources":  map[string]interface{}{"cpu": used},
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": ready, "reason": "NodeClassNotReady", "message": "EC2NodeClass default not ready"}},
+		},
+	}}
+	obj.SetKind("NodePool")
+	obj.SetName("default")
+	return obj
+}
+
+func karpenterCondition(conditionType, status, reason string) map[string]interface{} {
+	return map[string]interface{}{"type": conditionType, "status": status, "reason": reason}
+}
+
+func TestKarpenterChanges(t *testing.T) {
+	deleting := newNodeClaim(karpenterCondition("Initialized", "True", ""))
+	deleting.SetDeletionTimestamp(&meta_v1.Time{Time: time.Now()})
+
+	var Tests = []struct {
+		name      string
+		resource  string
+		eventType string
+		old, new  *unstructured.Unstructured
+		expected  []string
+	}{
+		{"provisioned", karpenterNodeClaims, "update", newNodeClaim(karpenterCondition("Launched", "True", "")),
+			newNodeClaim(karpenterCondition("Launched", "True", ""), karpenterCondition("Initialized", "True", "")),
+			[]string{"NodeClaimProvisioned", "Normal", "Provisioned node ip-10-0-1-2 (m5.large spot in us-east-1a) of NodePool default"}},
+		{"launch failed", karpenterNodeClaims, "update", newNodeClaim(),
+			newNodeClaim(karpenterCondition("Launched", "False", "InsufficientCapacity")),
+			[]string{"NodeClaimLaunchFailed", "Warning", "Cannot launch node ip-10-0-1-2 (m5.large spot in us-east-1a) of NodePool default: InsufficientCapacity"}},
+		{"consolidated", karpenterNodeClaims, "update", newNodeClaim(karpenterCondition("Initialized", "True", "")),
+			newNodeClaim(karpenterCondition("Initialized", "True", ""), karpenterCondition("DisruptionReason", "True", "Underutilized")),
+			[]string{"NodeClaimDisrupting", "Normal", "Disrupting node ip-10-0-1-2 (m5.large spot in us-east-1a) of NodePool default: Underutilized"}},
+		{"terminating", karpenterNodeClaims, "update", newNodeClaim(karpenterCondition("Initialized", "True", "")), deleting,
+			[]string{"NodeClaimTerminating", "Normal", "Terminating node ip-10-0-1-2 (m5.large spot in us-east-1a) of NodePool default"}},
+		{"removed", karpenterNodeClaims, "delete", nil, newNodeClaim(karpenterCondition("Initialized", "True", "")),
+			[]string{"NodeClaimRemoved", "Normal", "Removed node ip-10-0-1-2 (m5.large spot in us-east-1a) of NodePool default"}},
+		{"unchanged", karpenterNodeClaims, "update", newNodeClaim(karpenterCondition("Initialized", "True", "")), newNodeClaim(karpenterCondition("Initialized", "True", "")), nil},
+		{"created", karpenterNodeClaims, "create", nil, newNodeClaim(), nil},
+		{"node pool not ready", karpenterNodePools, "update", newNodePool("1000", "10", "True"), newNodePool("1000", "10", "False"),
+			[]string{"NodePoolNotReady", "Warning", "No node can be provisioned: NodeClassNotReady: EC2NodeClass default not ready"}},
+		{"node pool ready", karpenterNodePools, "update", newNodePool("1000", "10", "False"), newNodePool("1000", "10", "True"),
+			[]string{"NodePoolReady", "Normal", "Recovered from NotReady"}},
+		{"limits reached", karpenterNodePools, "update", newNodePool("100", "96", "True"), newNodePool("100", "100", "True"),
+			[]string{"NodePoolLimitReached", "Warning", "Limits reached, no more nodes will be provisioned: cpu 100 of 100"}},
+		{"limits still reached", karpenterNodePools, "update", newNodePool("100", "100", "True"), newNodePool("100", "102", "True"), nil},
+	}
+
+	for _, tt := range Tests {
+		c := &Controller{karpenter: tt.resource}
+		e := Event{key: tt.new.GetName(), eventType: tt.eventType, obj: tt.new}
+		if tt.old != nil {
+			e.oldObj = tt.old
+		}
+		var got []string
+		if kbEvent := c.karpenterEvent(e); kbEvent != nil {
+			got = []string{kbEvent.Kind, kbEvent.Status, kbEvent.Reason}
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestWithKarpenterCRDs(t *testing.T) {
+	configured := config.CRD{Group: karpenterGroup, Version: "v1beta1", Resource: karpenterNodePools}
+	crds := withKarpenterCRDs([]config.CRD{configured})
+	expected := []config.CRD{configured, karpenterCRDs[0]}
+	if !reflect.DeepEqual(crds, expected) {
+		t.Errorf("expected %+v, got %+v", expected, crds)
+	}
+	if karpenterResourceOf(configured) != karpenterNodePools || karpenterResourceOf(certificateCRD) != "" {
+		t.Errorf("unexpected Karpenter resources of %+v and %+v", configured, certificateCRD)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// KEDA annotations pausing the autoscaling of a ScaledObject
+const (
+	kedaPausedAnnotation         = "autoscaling.keda.sh/paused"
+	kedaPausedReplicasAnnotation = "autoscaling.keda.sh/paused-replicas"
+)
+
+// kedaScaledObjects are the KEDA ScaledObjects, whose health is reported like the health
+// of GitOps resources
+var kedaScaledObjects = gitOpsResource{
+	crd:    config.CRD{Group: "keda.sh", Version: "v1alpha1", Resource: "scaledobjects"},
+	kind:   "ScaledObject",
+	health: scaledObjectHealth,
+}
+
+func isScaledObjectCRD(crd config.CRD) bool {
+	return crd.Group == kedaScaledObjects.crd.Group && crd.Resource == kedaScaledObjects.crd.Resource
+}
+
+// withScaledObjectCRD returns crds with the KEDA ScaledObject resource appended unless it
+// is already configured
+func withScaledObjectCRD(crds []config.CRD) []config.CRD {
+	for _, crd := range crds {
+		if isScaledObjectCRD(crd) {
+			return crds
+		}
+	}
+	return append(crds, kedaScaledObjects.crd)
+}
+
+// scaledObjectHealth reads the health of a KEDA ScaledObject from its Ready, Fallback and
+// Paused conditions and the health of its triggers: a ScaledObject that is not ready or
+// has failing triggers is degraded, a paused one suspended
+func scaledObjectHealth(obj *unstructured.Unstructured) gitOpsHealth {
+	health := gitOpsHealth{state: gitOpsHealthy}
+	ready, _ := findCondition(obj, "Ready")
+	fallback, _ := findCondition(obj, "Fallback")
+	paused, _ := findCondition(obj, "Paused")
+	failing := failingTriggers(obj)
+
+	switch {
+	case ready.Status == "False":
+		health.state, health.message = gitOpsDegraded, conditionText(ready)
+		if health.message == "" {
+			health.message = "ScaledObject not ready"
+		}
+		if len(failing) > 0 {
+			health.message += "\nFailing triggers: " + strings.Join(failing, ", ")
+		}
+	case len(failing) > 0:
+		health.state, health.message = gitOpsDegraded, "Failing triggers: "+strings.Join(failing, ", ")
+		if fallback.Status == "True" {
+			health.message += "\nScaling to the fallback replicas"
+		}
+	case paused.Status == "True" || obj.GetAnnotations()[kedaPausedAnnotation] == "true" || obj.GetAnnotations()[kedaPausedReplicasAnnotation] != "":
+		health.state, health.message = gitOpsSuspended, "Autoscaling paused"
+		if replicas := obj.GetAnnotations()[kedaPausedReplicasAnnotation]; replicas != "" {
+			health.message += " at " + replicas + " replicas"
+		}
+	}
+	return health
+}
+
+// failingTriggers lists the triggers of a KEDA ScaledObject failing to get their metrics,
+// with their number of consecutive failures
+func failingTriggers(obj *unstructured.Unstructured) []string {
+	triggers, _, _ := unstructured.NestedMap(obj.Object, "status", "health")
+	var failing []string
+	for name, item := range triggers {
+		fields, _ := item.(map[string]interface{})
+		if status, _, _ := unstructured.NestedString(fields, "status"); status != "Failing" {
+			continue
+		}
+		if failures, ok, _ := unstructured.NestedInt64(fields, "numberOfFailures"); ok && failures > 0 {
+			name += fmt.Sprintf(" (%s)", pluralize(int(failures), "failure"))
+		}
+		failing = append(failing, name)
+	}
+	sort.Strings(failing)
+	return failing
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newScaledObject(annotations map[string]string, status map[string]interface{}) *unstructured.Unstructured {
+	obj := newGitOpsObject("ScaledObject", map[string]interface{}{"status": status})
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestScaledObjectHealth(t *testing.T) {
+	ready := map[string]interface{}{"type": "Ready", "status": "True", "reason": "ScaledObjectReady"}
+	var Tests = []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		expected gitOpsHealth
+	}{
+		{"healthy", newScaledObject(nil, map[string]interface{}{
+			"conditions": []interface{}{ready},
+			"health":     map[string]interface{}{"s0-prometheus": map[string]interface{}{"status": "Happy", "numberOfFailures": int64(0)}},
+		}), gitOpsHealth{state: gitOpsHealthy}},
+		{"not ready", newScaledObject(nil, map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False", "reason": "ScaledObjectCheckFailed", "message": "ScaledObject doesn't have correct scaleTargetRef specification"}},
+		}), gitOpsHealth{state: gitOpsDegraded, message: "ScaledObjectCheckFailed: ScaledObject doesn't have correct scaleTargetRef specification"}},
+		{"failing triggers", newScaledObject(nil, map[string]interface{}{
+			"conditions": []interface{}{ready, map[string]interface{}{"type": "Fallback", "status": "True"}},
+			"health": map[string]interface{}{
+				"s1-kafka":      map[string]interface{}{"status": "Failing", "numberOfFailures": int64(4)},
+				"s0-prometheus": map[string]interface{}{"status": "Happy", "numberOfFailures": int64(0)},
+			},
+		}), gitOpsHealth{state: gitOpsDegraded, message: "Failing triggers: s1-kafka (4 failures)\nScaling to the fallback replicas"}},
+		{"paused", newScaledObject(map[string]string{kedaPausedReplicasAnnotation: "0"}, map[string]interface{}{
+			"conditions": []interface{}{ready, map[string]interface{}{"type": "Paused", "status": "True", "reason": "ScaledObjectPaused"}},
+		}), gitOpsHealth{state: gitOpsSuspended, message: "Autoscaling paused at 0 replicas"}},
+		{"paused by annotation", newScaledObject(map[string]string{kedaPausedAnnotation: "true"}, map[string]interface{}{
+			"conditions": []interface{}{ready},
+		}), gitOpsHealth{state: gitOpsSuspended, message: "Autoscaling paused"}},
+	}
+
+	for _, tt := range Tests {
+		if health := scaledObjectHealth(tt.obj); !reflect.DeepEqual(health, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.expected, health)
+		}
+	}
+}
+
+func TestScaledObjectEvent(t *testing.T) {
+	c := &Controller{gitops: &kedaScaledObjects}
+	healthy := newScaledObject(nil, map[string]interface{}{})
+	failing := newScaledObject(nil, map[string]interface{}{
+		"health": map[string]interface{}{"s0-rabbitmq": map[string]interface{}{"status": "Failing", "numberOfFailures": int64(1)}},
+	})
+
+	e := c.gitOpsEvent(Event{key: "guestbook", namespace: "apps", eventType: "update", oldObj: healthy, obj: failing})
+	if e == nil || e.Kind != "ScaledObjectDegraded" || e.Status != "Danger" || e.Reason != "Failing triggers: s0-rabbitmq (1 failure)" {
+		t.Errorf("expected a Danger ScaledObjectDegraded event, got %+v", e)
+	}
+	if crds := withScaledObjectCRD([]config.CRD{kedaScaledObjects.crd}); len(crds) != 1 {
+		t.Errorf("expected a configured ScaledObject resource not to be added twice, got %v", crds)
+	}
+}
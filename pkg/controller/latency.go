@@ -0,0 +1,130 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// sloWarningInterval is the minimum time between two latency SLO warnings of a controller
+const sloWarningInterval = 10 * time.Minute
+
+// observeLatency records the time from the change reported by e to its delivery, and
+// warns when it exceeds the latency SLO
+func (c *Controller) observeLatency(e event.Event) {
+	changed := changeTime(e)
+	// changes made before the controller started are not delivered live
+	if changed.IsZero() || changed.Before(c.startTime) {
+		return
+	}
+	latency := time.Since(changed)
+	if latency < 0 {
+		latency = 0
+	}
+	metrics.DeliveryLatency.WithLabelValues(c.clusterName, c.resourceType).Observe(latency.Seconds())
+	if c.latencySLO == 0 || latency <= c.latencySLO {
+		return
+	}
+	metrics.SLOViolationsTotal.WithLabelValues(c.clusterName, c.resourceType).Inc()
+
+	last := c.sloWarned.Load()
+	if !c.notifySLO || time.Since(time.Unix(0, last)) < sloWarningInterval || !c.sloWarned.CompareAndSwap(last, time.Now().UnixNano()) {
+		return
+	}
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+	c.logger.Warnf("Delivery of %s %s took %s, above the %s latency SLO", e.Kind, name, latency.Round(time.Second), c.latencySLO)
+	warning := event.Event{
+		Name:        c.resourceType,
+		Namespace:   c.namespace,
+		Kind:        "LatencySLO",
+		Status:      "Warning",
+		Reason:      fmt.Sprintf("Delivering %s %s took %s, above the %s latency SLO", e.Kind, name, latency.Round(time.Second), c.latencySLO),
+		ClusterName: c.clusterName,
+	}
+	c.cluster.tag(&warning)
+	populate(&warning)
+	c.cluster.group(&warning)
+	c.eventHandler.Handle(warning)
+}
+
+// changeTime returns when the change reported by e was made, as recorded in its object: the
+// last occurrence of Kubernetes Events, the last update of managed fields, or the creation of
+// other objects. It is zero when unknown, such as for objects deleted without finalizers
+// and resyncs.
+func changeTime(e event.Event) time.Time {
+	switch obj := e.Obj.(type) {
+	case *api_v1.Event:
+		changed := latest(obj.EventTime.Time, obj.LastTimestamp.Time, obj.FirstTimestamp.Time)
+		if obj.Series != nil {
+			changed = latest(changed, obj.Series.LastObservedTime.Time)
+		}
+		return changed
+	case *events_v1.Event:
+		changed := latest(obj.EventTime.Time, obj.DeprecatedLastTimestamp.Time, obj.DeprecatedFirstTimestamp.Time)
+		if obj.Series != nil {
+			changed = latest(changed, obj.Series.LastObservedTime.Time)
+		}
+		return changed
+	}
+
+	if e.Obj == nil {
+		return time.Time{}
+	}
+	accessor, err := meta.Accessor(e.Obj)
+	if err != nil {
+		return time.Time{}
+	}
+	// the last state of an object deleted without finalizers tells nothing about its deletion
+	if e.Reason == "Deleted" && accessor.GetDeletionTimestamp() == nil {
+		return time.Time{}
+	}
+	// resyncs replay unchanged objects
+	if old, err := meta.Accessor(e.OldObj); e.OldObj != nil && err == nil && old.GetResourceVersion() == accessor.GetResourceVersion() {
+		return time.Time{}
+	}
+	changed := accessor.GetCreationTimestamp().Time
+	if deletion := accessor.GetDeletionTimestamp(); deletion != nil {
+		changed = latest(changed, deletion.Time)
+	}
+	for _, field := range accessor.GetManagedFields() {
+		if field.Time != nil {
+			changed = latest(changed, field.Time.Time)
+		}
+	}
+	return changed
+}
+
+// latest returns the latest of times
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, candidate := range times {
+		if candidate.After(t) {
+			t = candidate
+		}
+	}
+	return t
+}
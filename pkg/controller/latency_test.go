@@ -0,0 +1,100 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestChangeTime(t *testing.T) {
+	created := meta_v1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	updated := meta_v1.NewTime(created.Add(time.Hour))
+	deleted := meta_v1.NewTime(created.Add(2 * time.Hour))
+
+	pod := func(version string, deletion *meta_v1.Time, managed ...*meta_v1.Time) *api_v1.Pod {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api", ResourceVersion: version, CreationTimestamp: created, DeletionTimestamp: deletion}}
+		for _, at := range managed {
+			p.ManagedFields = append(p.ManagedFields, meta_v1.ManagedFieldsEntry{Manager: "kubectl", Time: at})
+		}
+		return p
+	}
+
+	var Tests = []struct {
+		reason   string
+		obj, old runtime.Object
+		changed  time.Time
+	}{
+		{"Created", pod("1", nil), nil, created.Time},
+		{"Updated", pod("2", nil, &created, &updated), pod("1", nil, &created), updated.Time},
+		{"Updated", pod("2", nil, &created, &updated), pod("2", nil, &created, &updated), time.Time{}},
+		{"Deleted", pod("3", &deleted, &updated), nil, deleted.Time},
+		{"Deleted", pod("3", nil, &updated), nil, time.Time{}},
+		{"Created", &api_v1.Event{LastTimestamp: updated, FirstTimestamp: created}, nil, updated.Time},
+		{"Created", &api_v1.Event{EventTime: meta_v1.NewMicroTime(created.Time), Series: &api_v1.EventSeries{LastObservedTime: meta_v1.NewMicroTime(deleted.Time)}}, nil, deleted.Time},
+		{"Created", nil, nil, time.Time{}},
+	}
+
+	for i, test := range Tests {
+		if changed := changeTime(event.Event{Reason: test.reason, Obj: test.obj, OldObj: test.old}); !changed.Equal(test.changed) {
+			t.Errorf("%d: expected the change time to be %v, got %v", i, test.changed, changed)
+		}
+	}
+}
+
+func TestObserveLatency(t *testing.T) {
+	r := &recorder{}
+	c := &Controller{
+		logger:       logrus.WithField("pkg", "kubewatch-Pod"),
+		eventHandler: r,
+		clusterName:  "prod",
+		resourceType: "Pod",
+		namespace:    "team-a",
+		startTime:    time.Now().Add(-time.Hour),
+		latencySLO:   time.Minute,
+		notifySLO:    true,
+	}
+	deliver := func(age time.Duration) {
+		created := meta_v1.NewTime(time.Now().Add(-age))
+		c.observeLatency(event.Event{Kind: "Pod", Name: "api", Namespace: "team-a", Reason: "Created", Obj: &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{CreationTimestamp: created}}})
+	}
+
+	deliver(10 * time.Second)
+	if len(r.events) != 0 {
+		t.Fatalf("expected no warning within the SLO, got %v", r.names())
+	}
+	deliver(2 * time.Minute)
+	deliver(3 * time.Minute)
+	if len(r.events) != 1 {
+		t.Fatalf("expected a single warning above the SLO, got %v", r.names())
+	}
+	if e := r.events[0]; e.Kind != "LatencySLO" || e.Status != "Warning" || e.Name != "Pod" || e.ClusterName != "prod" {
+		t.Errorf("expected a LatencySLO warning about prod Pods, got %+v", e)
+	}
+
+	// changes made before the controller started are not measured
+	deliver(2 * time.Hour)
+	if len(r.events) != 1 {
+		t.Errorf("expected changes older than the controller not to be measured, got %v", r.names())
+	}
+}
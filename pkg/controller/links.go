@@ -0,0 +1,76 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+// Link is a deep link template, rendered with the events it is appended to
+type Link struct {
+	name string
+	url  *template.Template
+	// kinds restricts the link to events of these kinds, nil for all events
+	kinds map[string]bool
+}
+
+// ParseLinks parses the URL templates of the configured links
+func ParseLinks(links []config.Link) ([]Link, error) {
+	parsed := make([]Link, 0, len(links))
+	for _, l := range links {
+		if l.Name == "" || l.URL == "" {
+			return nil, fmt.Errorf("link %q: name and url are required", l.Name)
+		}
+		url, err := template.New(l.Name).Option("missingkey=zero").Parse(l.URL)
+		if err != nil {
+			return nil, fmt.Errorf("link %q: %v", l.Name, err)
+		}
+		link := Link{name: l.Name, url: url}
+		if len(l.Kinds) > 0 {
+			link.kinds = map[string]bool{}
+			for _, kind := range l.Kinds {
+				link.kinds[kind] = true
+			}
+		}
+		parsed = append(parsed, link)
+	}
+	return parsed, nil
+}
+
+// link appends the links of the cluster matching the kind of e to e
+func (cl Cluster) link(e *event.Event) {
+	for _, l := range cl.Links {
+		if l.kinds != nil && !l.kinds[e.Kind] {
+			continue
+		}
+		var url bytes.Buffer
+		if err := l.url.Execute(&url, e); err != nil {
+			logrus.WithField("pkg", "kubewatch-links").Warnf("Error rendering link %s: %v", l.name, err)
+			continue
+		}
+		if u := strings.TrimSpace(url.String()); u != "" {
+			e.Links = append(e.Links, event.Link{Name: l.name, URL: u})
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestParseLinks(t *testing.T) {
+	var Tests = []struct {
+		link  config.Link
+		valid bool
+	}{
+		{config.Link{Name: "Grafana", URL: "https://grafana/d/pods?var-pod={{.Name}}"}, true},
+		{config.Link{Name: "Grafana", URL: "https://grafana/d/pods?var-pod={{.Name"}, false},
+		{config.Link{URL: "https://grafana"}, false},
+		{config.Link{Name: "Grafana"}, false},
+	}
+
+	for i, test := range Tests {
+		if _, err := ParseLinks([]config.Link{test.link}); (err == nil) != test.valid {
+			t.Errorf("%d: ParseLinks() error = %v, expected valid %t", i, err, test.valid)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	links, err := ParseLinks([]config.Link{
+		{Name: "Logs", URL: "https://logs/search?q={{urlquery .Namespace \"/\" .Name}}"},
+		{Name: "Grafana", URL: "https://grafana/{{.ClusterName}}/d/pods?var-namespace={{.Namespace}}&var-pod={{.Name}}", Kinds: []string{"Pod"}},
+		{Name: "Owner", URL: "{{with .Involved}}https://argocd/applications/{{.Owner}}{{end}}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := Cluster{Links: links}
+
+	var Tests = []struct {
+		e     event.Event
+		links []event.Link
+	}{
+		{event.Event{Kind: "Pod", Namespace: "default", Name: "web", ClusterName: "prod"}, []event.Link{
+			{Name: "Logs", URL: "https://logs/search?q=default%2Fweb"},
+			{Name: "Grafana", URL: "https://grafana/prod/d/pods?var-namespace=default&var-pod=web"},
+		}},
+		{event.Event{Kind: "Service", Namespace: "default", Name: "web"}, []event.Link{
+			{Name: "Logs", URL: "https://logs/search?q=default%2Fweb"},
+		}},
+		{event.Event{Kind: "Event", Namespace: "default", Name: "web.17a", Involved: &event.InvolvedObject{Owner: "web"}}, []event.Link{
+			{Name: "Logs", URL: "https://logs/search?q=default%2Fweb.17a"},
+			{Name: "Owner", URL: "https://argocd/applications/web"},
+		}},
+	}
+
+	for i, test := range Tests {
+		cluster.link(&test.e)
+		if !reflect.DeepEqual(test.e.Links, test.links) {
+			t.Errorf("%d: links = %+v, expected %+v", i, test.e.Links, test.links)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// logsTimeout bounds the time spent fetching logs before handling an event
+const logsTimeout = 10 * time.Second
+
+// redactions hide credentials commonly found in logs
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(authorization["']?\s*[:=]\s*["']?)[^\n"']+`), "$1[REDACTED]"},
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`), "$1 [REDACTED]"},
+	{regexp.MustCompile(`(?i)\b(password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)(["']?\s*[:=]\s*["']?)[^\s"',;&]+`), "$1$2[REDACTED]"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "[REDACTED]"},
+	{regexp.MustCompile(`://([^:/@\s]+):[^@\s]+@`), "://$1:[REDACTED]@"},
+}
+
+// containerFailure is a container that just failed
+type containerFailure struct {
+	container string
+	reason    string
+	exitCode  int32
+	// previous is set when the failed instance was restarted, its logs are the previous ones
+	previous bool
+}
+
+// failedContainer returns the first container of pod that crashed, was OOMKilled or exited
+// with a non-zero code since old
+func failedContainer(old, pod *api_v1.Pod) *containerFailure {
+	oldStatuses := map[string]api_v1.ContainerStatus{}
+	for _, status := range append(append([]api_v1.ContainerStatus{}, old.Status.InitContainerStatuses...), old.Status.ContainerStatuses...) {
+		oldStatuses[status.Name] = status
+	}
+
+	for _, status := range append(append([]api_v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		oldStatus := oldStatuses[status.Name]
+		if status.RestartCount > oldStatus.RestartCount {
+			if terminated := status.LastTerminationState.Terminated; terminated != nil && failed(terminated) {
+				return &containerFailure{container: status.Name, reason: terminated.Reason, exitCode: terminated.ExitCode, previous: true}
+			}
+			continue
+		}
+		if terminated := status.State.Terminated; terminated != nil && oldStatus.State.Terminated == nil && failed(terminated) {
+			return &containerFailure{container: status.Name, reason: terminated.Reason, exitCode: terminated.ExitCode}
+		}
+	}
+	return nil
+}
+
+func failed(terminated *api_v1.ContainerStateTerminated) bool {
+	return terminated.ExitCode != 0 || terminated.Reason == "OOMKilled"
+}
+
+// failureLogs returns the last log lines of the container failed by a pod update, nil if
+// no container failed or its logs cannot be fetched
+func (c *Controller) failureLogs(e Event) *event.ContainerLogs {
+	pod, ok := e.obj.(*api_v1.Pod)
+	if !ok {
+		return nil
+	}
+	old, ok := e.oldObj.(*api_v1.Pod)
+	if !ok {
+		return nil
+	}
+	failure := failedContainer(old, pod)
+	if failure == nil {
+		return nil
+	}
+
+	lines := c.containerLogs.Lines
+	if lines <= 0 {
+		lines = config.DefaultLogLines
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), logsTimeout)
+	defer cancel()
+	raw, err := c.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &api_v1.PodLogOptions{
+		Container: failure.container,
+		Previous:  failure.previous,
+		TailLines: &lines,
+	}).DoRaw(ctx)
+	if err != nil {
+		c.logger.Warnf("Error fetching logs of %s/%s container %s: %v", pod.Namespace, pod.Name, failure.container, err)
+		return nil
+	}
+
+	text, truncated := truncateLogs(redactLogs(string(raw)), c.containerLogs)
+	return &event.ContainerLogs{
+		Container: failure.container,
+		Reason:    failure.reason,
+		ExitCode:  failure.exitCode,
+		Text:      text,
+		Truncated: truncated,
+	}
+}
+
+func redactLogs(text string) string {
+	for _, r := range redactions {
+		text = r.pattern.ReplaceAllString(text, r.replacement)
+	}
+	return text
+}
+
+// truncateLogs keeps the last lines of text fitting the configured size
+func truncateLogs(text string, conf *config.ContainerLogs) (string, bool) {
+	maxBytes := conf.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = config.DefaultLogMaxBytes
+	}
+	if len(text) <= maxBytes {
+		return text, false
+	}
+	text = text[len(text)-maxBytes:]
+	if i := strings.IndexByte(text, '\n'); i >= 0 && i < len(text)-1 {
+		text = text[i+1:]
+	}
+	return text, true
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// startMetadataResource runs a controller backed by a metadata-only (PartialObjectMetadata) informer,
+// so that only object metadata is kept in the informer cache
+func (w *clusterWatcher) startMetadataResource(r resource, namespace string, stopCh <-chan struct{}) {
+	resourceClient := w.metadataClient.Resource(r.groupVersionResource())
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return resourceClient.Namespace(namespace).List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return resourceClient.Namespace(namespace).Watch(context.Background(), options)
+			},
+		},
+		&meta_v1.PartialObjectMetadata{},
+		w.conf.Resync(r.name),
+		cache.Indexers{},
+	)
+
+	c := w.newController(informer, r.name, r.apiVersion, namespace)
+	c.logger.Info("Using metadata-only informer")
+
+	w.run(c, stopCh)
+}
+
+// metadataChanged reports whether an update is worth processing. Updates of metadata-only
+// objects are only processed when their metadata changed, ignoring resourceVersion and
+// managedFields which change on every write; other objects are always processed.
+func metadataChanged(old, new interface{}) bool {
+	oldMeta, ok := old.(*meta_v1.PartialObjectMetadata)
+	if !ok {
+		return true
+	}
+	newMeta, ok := new.(*meta_v1.PartialObjectMetadata)
+	if !ok {
+		return true
+	}
+
+	oldObjectMeta := *oldMeta.ObjectMeta.DeepCopy()
+	newObjectMeta := *newMeta.ObjectMeta.DeepCopy()
+	oldObjectMeta.ResourceVersion, newObjectMeta.ResourceVersion = "", ""
+	oldObjectMeta.ManagedFields, newObjectMeta.ManagedFields = nil, nil
+	return !reflect.DeepEqual(oldObjectMeta, newObjectMeta)
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func partialObject(resourceVersion string, labels map[string]string) *meta_v1.PartialObjectMetadata {
+	return &meta_v1.PartialObjectMetadata{ObjectMeta: meta_v1.ObjectMeta{
+		Name:            "foo",
+		Namespace:       "default",
+		ResourceVersion: resourceVersion,
+		Labels:          labels,
+		ManagedFields:   []meta_v1.ManagedFieldsEntry{{Manager: resourceVersion}},
+	}}
+}
+
+func TestMetadataChanged(t *testing.T) {
+	var Tests = []struct {
+		name     string
+		old, new interface{}
+		expected bool
+	}{
+		{"only resourceVersion changed", partialObject("1", nil), partialObject("2", nil), false},
+		{"labels changed", partialObject("1", nil), partialObject("2", map[string]string{"app": "foo"}), true},
+		{"typed objects are always processed", &api_v1.Pod{}, &api_v1.Pod{}, true},
+	}
+
+	for _, tt := range Tests {
+		if got := metadataChanged(tt.old, tt.new); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestGroupVersionResource(t *testing.T) {
+	for _, r := range resources {
+		gvr := r.groupVersionResource()
+		if gvr.Resource == "" || gvr.Version == "" {
+			t.Errorf("%s: incomplete GroupVersionResource %v", r.name, gvr)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceSelector runs the namespaced controllers of every namespace matching a label selector.
+// Controllers are started when a namespace starts matching the selector (it is created or labeled)
+// and stopped when it stops matching (it is deleted or unlabeled).
+type namespaceSelector struct {
+	logger   *logrus.Entry
+	watcher  *clusterWatcher
+	informer cache.SharedIndexInformer
+
+	mu      sync.Mutex
+	running map[string]chan struct{}
+}
+
+func newNamespaceSelector(w *clusterWatcher, selector string) *namespaceSelector {
+	kubeClient := w.kubeClient
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Namespace{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+
+	logger := logrus.WithField("pkg", "kubewatch-namespace-selector")
+	if w.clusterName != "" {
+		logger = logger.WithField("cluster", w.clusterName)
+	}
+
+	return &namespaceSelector{
+		logger:   logger,
+		watcher:  w,
+		informer: informer,
+		running:  map[string]chan struct{}{},
+	}
+}
+
+// Run watches the selected namespaces until stopCh is closed
+func (n *namespaceSelector) Run(stopCh <-chan struct{}) {
+	n.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*api_v1.Namespace); ok {
+				n.start(ns.Name, stopCh)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*api_v1.Namespace); ok {
+				n.stop(ns.Name)
+			}
+		},
+	})
+
+	n.watcher.objects.add(objName(api_v1.Namespace{}), n.informer.GetStore(), stopCh)
+	n.logger.Info("Starting namespace selector")
+	n.informer.Run(stopCh)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for namespace, nsStopCh := range n.running {
+		close(nsStopCh)
+		delete(n.running, namespace)
+	}
+}
+
+func (n *namespaceSelector) start(namespace string, stopCh <-chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.running[namespace]; ok {
+		return
+	}
+
+	nsStopCh := make(chan struct{})
+	n.running[namespace] = nsStopCh
+	n.logger.Infof("Namespace %s selected, starting its controllers", namespace)
+	n.watcher.startNamespace(namespace, nsStopCh)
+}
+
+func (n *namespaceSelector) stop(namespace string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nsStopCh, ok := n.running[namespace]
+	if !ok {
+		return
+	}
+
+	n.logger.Infof("Namespace %s unselected, stopping its controllers", namespace)
+	close(nsStopCh)
+	delete(n.running, namespace)
+}
+
+// namespaces returns the currently selected namespaces
+func (n *namespaceSelector) namespaces() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	namespaces := make([]string, 0, len(n.running))
+	for namespace := range n.running {
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespaceSelector(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "team-a", Labels: map[string]string{"kubewatch.io/watch": "true"}}},
+		&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "team-b"}},
+	)
+	n := newNamespaceSelector(newClusterWatcher(&config.Config{}, nil, Cluster{KubeClient: client}, nil, nil), "kubewatch.io/watch=true")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go n.Run(stopCh)
+
+	waitForNamespaces := func(expected []string) {
+		t.Helper()
+		err := wait.PollUntilContextTimeout(context.Background(), 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return reflect.DeepEqual(n.namespaces(), expected), nil
+		})
+		if err != nil {
+			t.Fatalf("expected selected namespaces %v, got %v", expected, n.namespaces())
+		}
+	}
+
+	waitForNamespaces([]string{"team-a"})
+
+	if err := client.CoreV1().Namespaces().Delete(context.Background(), "team-a", meta_v1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForNamespaces([]string{})
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// nodeDrains tracks the cordoned nodes whose pods are being evicted
+type nodeDrains struct {
+	mu sync.Mutex
+	// nodes is the store of the node informer, nil when nodes are not watched
+	nodes cache.Store
+	// draining holds the cordoned nodes a drain was reported for
+	draining map[string]bool
+}
+
+func newNodeDrains() *nodeDrains {
+	return &nodeDrains{draining: map[string]bool{}}
+}
+
+func (d *nodeDrains) setNodes(nodes cache.Store) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes = nodes
+}
+
+// start returns the UID of the cordoned node, and true the first time its pods are evicted
+func (d *nodeDrains) start(node string) (types.UID, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.nodes == nil || d.draining[node] {
+		return "", false
+	}
+	obj, exists, err := d.nodes.GetByKey(node)
+	if err != nil || !exists {
+		return "", false
+	}
+	n, ok := obj.(*api_v1.Node)
+	if !ok || !n.Spec.Unschedulable {
+		return "", false
+	}
+	d.draining[node] = true
+	return n.UID, true
+}
+
+// reset forgets the drain of node, once uncordoned or deleted
+func (d *nodeDrains) reset(node string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.draining, node)
+}
+
+// nodeLifecycleEvent turns node cordons, uncordons and deletions into NodeCordoned,
+// NodeUncordoned and NodeDeleted events. It returns false for other events.
+func (c *Controller) nodeLifecycleEvent(e Event) (*event.Event, bool) {
+	node, ok := e.obj.(*api_v1.Node)
+	if !ok {
+		return nil, false
+	}
+
+	switch e.eventType {
+	case "update":
+		old, ok := e.oldObj.(*api_v1.Node)
+		if !ok || old.Spec.Unschedulable == node.Spec.Unschedulable {
+			return nil, false
+		}
+		if node.Spec.Unschedulable {
+			return newNodeEvent(e, node.Name, "NodeCordoned", "Warning", "Node marked unschedulable"), true
+		}
+		c.nodeDrains.reset(node.Name)
+		return newNodeEvent(e, node.Name, "NodeUncordoned", "Normal", "Node marked schedulable"), true
+	case "delete":
+		c.nodeDrains.reset(node.Name)
+		return newNodeEvent(e, node.Name, "NodeDeleted", "Danger", "Node removed from the cluster"), true
+	}
+	return nil, false
+}
+
+// nodeDrainEvent returns a NodeDraining event when the first pod of a cordoned node
+// is evicted, nil otherwise
+func (c *Controller) nodeDrainEvent(e Event) *event.Event {
+	pod, ok := e.obj.(*api_v1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil
+	}
+
+	switch e.eventType {
+	case "update":
+		old, ok := e.oldObj.(*api_v1.Pod)
+		if !ok || old.DeletionTimestamp != nil || pod.DeletionTimestamp == nil {
+			return nil
+		}
+	case "delete":
+	default:
+		return nil
+	}
+
+	uid, ok := c.nodeDrains.start(pod.Spec.NodeName)
+	if !ok {
+		return nil
+	}
+	reason := fmt.Sprintf("Evicting pods of the cordoned node, starting with %s/%s", pod.Namespace, pod.Name)
+	kbEvent := newNodeEvent(e, pod.Spec.NodeName, "NodeDraining", "Warning", reason)
+	// correlate with the other events of the node rather than with the pod
+	kbEvent.CorrelationID = string(uid)
+	return kbEvent
+}
+
+func newNodeEvent(e Event, node string, kind string, status string, reason string) *event.Event {
+	return &event.Event{
+		Name:       node,
+		Kind:       kind,
+		APIVersion: V1,
+		Status:     status,
+		Reason:     reason,
+		Obj:        e.obj,
+		OldObj:     e.oldObj,
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newNode(unschedulable bool) *api_v1.Node {
+	return &api_v1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "node-1"},
+		Spec:       api_v1.NodeSpec{Unschedulable: unschedulable},
+	}
+}
+
+func scheduledPod(name string, node string, terminating bool) *api_v1.Pod {
+	pod := newPod(name, time.Now())
+	pod.Spec.NodeName = node
+	if terminating {
+		now := meta_v1.Now()
+		pod.DeletionTimestamp = &now
+	}
+	return pod
+}
+
+func TestNodeLifecycleEvent(t *testing.T) {
+	c := &Controller{nodeDrains: newNodeDrains()}
+
+	var Tests = []struct {
+		eventType string
+		obj       runtime.Object
+		oldObj    runtime.Object
+		handled   bool
+		kind      string
+	}{
+		{"update", newNode(true), newNode(false), true, "NodeCordoned"},
+		{"update", newNode(false), newNode(true), true, "NodeUncordoned"},
+		{"update", newNode(true), newNode(true), false, ""},
+		{"delete", newNode(true), nil, true, "NodeDeleted"},
+		{"create", newNode(false), nil, false, ""},
+		{"update", newPod("web", time.Now()), newPod("web", time.Now()), false, ""},
+	}
+
+	for i, tt := range Tests {
+		kbEvent, handled := c.nodeLifecycleEvent(Event{eventType: tt.eventType, obj: tt.obj, oldObj: tt.oldObj})
+		if handled != tt.handled {
+			t.Fatalf("%d: expected handled %v, got %v", i, tt.handled, handled)
+		}
+		kind := ""
+		if kbEvent != nil {
+			kind = kbEvent.Kind
+			if kbEvent.Name != "node-1" {
+				t.Errorf("%d: expected an event of node-1, got %+v", i, kbEvent)
+			}
+		}
+		if kind != tt.kind {
+			t.Errorf("%d: expected kind %q, got %q", i, tt.kind, kind)
+		}
+	}
+}
+
+func TestNodeDrainEvent(t *testing.T) {
+	nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	c := &Controller{nodeDrains: newNodeDrains()}
+
+	evict := func(name string) Event {
+		return Event{eventType: "update", obj: scheduledPod(name, "node-1", true), oldObj: scheduledPod(name, "node-1", false)}
+	}
+
+	// nodes are not watched
+	if kbEvent := c.nodeDrainEvent(evict("web-1")); kbEvent != nil {
+		t.Errorf("expected no drain without node watch, got %+v", kbEvent)
+	}
+
+	c.nodeDrains.setNodes(nodes)
+	nodes.Add(newNode(false))
+	if kbEvent := c.nodeDrainEvent(evict("web-1")); kbEvent != nil {
+		t.Errorf("expected no drain of a schedulable node, got %+v", kbEvent)
+	}
+
+	nodes.Update(newNode(true))
+	kbEvent := c.nodeDrainEvent(evict("web-1"))
+	if kbEvent == nil || kbEvent.Kind != "NodeDraining" || kbEvent.Name != "node-1" {
+		t.Fatalf("expected a NodeDraining event of node-1, got %+v", kbEvent)
+	}
+	if kbEvent := c.nodeDrainEvent(evict("web-2")); kbEvent != nil {
+		t.Errorf("expected a single drain event per cordon, got %+v", kbEvent)
+	}
+	if kbEvent := c.nodeDrainEvent(Event{eventType: "update", obj: scheduledPod("web-3", "node-1", true), oldObj: scheduledPod("web-3", "node-1", true)}); kbEvent != nil {
+		t.Errorf("expected no drain for an already terminating pod, got %+v", kbEvent)
+	}
+
+	// uncordoning and cordoning again starts a new drain
+	c.nodeLifecycleEvent(Event{eventType: "update", obj: newNode(false), oldObj: newNode(true)})
+	if kbEvent := c.nodeDrainEvent(Event{eventType: "delete", obj: scheduledPod("web-4", "node-1", false)}); kbEvent == nil {
+		t.Errorf("expected a new drain event after uncordon")
+	}
+}
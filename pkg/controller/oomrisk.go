@@ -0,0 +1,156 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	resource_api "k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// podMetrics is the resource of the metrics API reporting the usage of pods, served by
+// metrics-server
+var podMetrics = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+
+// oomRiskChecker warns about the containers whose memory working set, as reported by the
+// metrics API, reaches a threshold of their memory limit, before they are OOMKilled. Each
+// container is reported once, and again after its usage went back below the threshold.
+type oomRiskChecker struct {
+	w      *clusterWatcher
+	logger *logrus.Entry
+	// threshold is the part of the memory limit, in percent, from which a container is
+	// at risk
+	threshold int
+	// notified holds the namespace of the containers at risk reported, by pod UID and
+	// container name
+	notified map[string]string
+}
+
+// checkMemoryUsage checks the memory usage of the watched pods every interval until stopCh
+// is closed
+func (w *clusterWatcher) checkMemoryUsage(threshold int, interval time.Duration, stopCh <-chan struct{}) {
+	o := &oomRiskChecker{
+		w:         w,
+		logger:    logrus.WithField("pkg", "kubewatch-oom-risk"),
+		threshold: threshold,
+		notified:  map[string]string{},
+	}
+	if w.clusterName != "" {
+		o.logger = o.logger.WithField("cluster", w.clusterName)
+	}
+	wait.Until(o.check, interval, stopCh)
+}
+
+func (o *oomRiskChecker) check() {
+	o.w.mu.Lock()
+	namespaces := make([]string, 0, len(o.w.namespaces))
+	for namespace := range o.w.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	o.w.mu.Unlock()
+	sort.Strings(namespaces)
+
+	atRisk := map[string]bool{}
+	unread := map[string]bool{}
+	for _, namespace := range namespaces {
+		metrics, err := o.w.dynamicClient.Resource(podMetrics).Namespace(namespace).List(context.Background(), meta_v1.ListOptions{})
+		if err != nil {
+			o.logger.Warnf("Cannot read the metrics of the pods of namespace %q, is metrics-server installed? %v", namespace, err)
+			unread[namespace] = true
+			continue
+		}
+		for i := range metrics.Items {
+			for _, e := range o.checkPod(&metrics.Items[i], atRisk) {
+				o.w.notify(e)
+			}
+		}
+	}
+	// containers back below the threshold, or gone, are reported again next time. Those of
+	// namespaces whose metrics could not be read are kept until they are read again.
+	for key, namespace := range o.notified {
+		if !atRisk[key] && !unread[namespace] {
+			delete(o.notified, key)
+		}
+	}
+}
+
+// checkPod returns the events about the containers at risk of the pod of metrics not
+// reported yet, and adds all its containers at risk to atRisk. Pods that are not watched
+// are skipped, their limits being unknown.
+func (o *oomRiskChecker) checkPod(metrics *unstructured.Unstructured, atRisk map[string]bool) []event.Event {
+	pod, ok := o.w.objects.getObject(objName(api_v1.Pod{}), metrics.GetNamespace(), metrics.GetName()).(*api_v1.Pod)
+	if !ok {
+		return nil
+	}
+	limits := map[string]resource_api.Quantity{}
+	for _, container := range pod.Spec.Containers {
+		if limit, ok := container.Resources.Limits[api_v1.ResourceMemory]; ok && !limit.IsZero() {
+			limits[container.Name] = limit
+		}
+	}
+
+	var events []event.Event
+	containers, _, _ := unstructured.NestedSlice(metrics.Object, "containers")
+	for _, item := range containers {
+		fields, _ := item.(map[string]interface{})
+		name, _, _ := unstructured.NestedString(fields, "name")
+		memory, _, _ := unstructured.NestedString(fields, "usage", "memory")
+		limit, ok := limits[name]
+		if !ok || memory == "" {
+			continue
+		}
+		usage, err := resource_api.ParseQuantity(memory)
+		if err != nil {
+			continue
+		}
+		percent := usage.AsApproximateFloat64() / limit.AsApproximateFloat64() * 100
+		if percent < float64(o.threshold) {
+			continue
+		}
+		key := string(pod.UID) + "/" + name
+		atRisk[key] = true
+		if _, ok := o.notified[key]; ok {
+			continue
+		}
+		o.notified[key] = pod.Namespace
+		reason := fmt.Sprintf("Container %s of pod %s uses %s of its %s memory limit (%.0f%%)",
+			name, pod.Name, roundQuantity(api_v1.ResourceMemory, usage), limit.String(), percent)
+		if pod.Spec.NodeName != "" {
+			reason += " on node " + pod.Spec.NodeName
+		}
+		events = append(events, event.Event{
+			Name:       pod.Name,
+			Namespace:  pod.Namespace,
+			Kind:       "OOMRisk",
+			APIVersion: V1,
+			Status:     "Warning",
+			Reason:     reason,
+			Obj:        pod,
+		})
+	}
+	return events
+}
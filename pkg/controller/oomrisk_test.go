@@ -0,0 +1,118 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	resource_api "k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newPodMetrics(name, memory string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "api", "usage": map[string]interface{}{"cpu": "250m", "memory": memory}},
+			map[string]interface{}{"name": "proxy", "usage": map[string]interface{}{"cpu": "10m", "memory": "30Mi"}},
+		},
+	}}
+	obj.SetAPIVersion("metrics.k8s.io/v1beta1")
+	obj.SetKind("PodMetrics")
+	obj.SetName(name)
+	obj.SetNamespace("payments")
+	return obj
+}
+
+func TestOOMRiskChecker(t *testing.T) {
+	pod := func(name, limit string) *api_v1.Pod {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: "payments", UID: types.UID(name)}}
+		p.Spec.NodeName = "node-1"
+		p.Spec.Containers = []api_v1.Container{{Name: "api"}, {Name: "proxy"}}
+		if limit != "" {
+			p.Spec.Containers[0].Resources.Limits = api_v1.ResourceList{api_v1.ResourceMemory: resource_api.MustParse(limit)}
+		}
+		return p
+	}
+	pods := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pods.Add(pod("api-7d9f", "512Mi"))
+	pods.Add(pod("api-a1b2", "512Mi"))
+	pods.Add(pod("api-unlimited", ""))
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{podMetrics: "PodMetricsList"})
+	metrics := client.Resource(podMetrics).Namespace("payments")
+	for name, memory := range map[string]string{"api-7d9f": "503316480", "api-a1b2": "200Mi", "api-unlimited": "4Gi", "not-watched": "4Gi"} {
+		// created through the client, the PodMetrics kind not mapping to the pods resource
+		if _, err := metrics.Create(context.Background(), newPodMetrics(name, memory), meta_v1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &recorder{}
+	w := newClusterWatcher(&config.Config{}, r, Cluster{DynamicClient: client}, nil, nil)
+	w.namespaces["payments"] = nil
+	w.objects.add(objName(api_v1.Pod{}), pods, nil)
+	o := &oomRiskChecker{w: w, logger: logrus.WithField("pkg", "test"), threshold: 90, notified: map[string]string{}}
+
+	o.check()
+	o.check()
+	if len(r.events) != 1 {
+		t.Fatalf("expected the container at risk to be reported once, got %q", r.names())
+	}
+
+	// containers are not reported again after the metrics could not be read
+	unavailable := true
+	client.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if unavailable {
+			return true, nil, errors.New("metrics unavailable")
+		}
+		return false, nil, nil
+	})
+	o.check()
+	unavailable = false
+	o.check()
+	if len(r.events) != 1 {
+		t.Fatalf("expected the container at risk not to be reported again after a metrics outage, got %q", r.names())
+	}
+	e := r.events[0]
+	expected := "Container api of pod api-7d9f uses 480Mi of its 512Mi memory limit (94%) on node node-1"
+	if e.Kind != "OOMRisk" || e.Status != "Warning" || e.Name != "api-7d9f" || e.Reason != expected {
+		t.Errorf("expected an OOMRisk warning %q, got %+v", expected, e)
+	}
+
+	// reported again once back below the threshold
+	for _, memory := range []string{"200Mi", "500Mi"} {
+		if _, err := metrics.Update(context.Background(), newPodMetrics("api-7d9f", memory), meta_v1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		o.check()
+	}
+	if len(r.events) != 2 {
+		t.Errorf("expected the container to be reported again, got %q", r.names())
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceOwnership reads the ownership of namespaces from their annotations, kept in the
+// cache of a namespace informer
+type namespaceOwnership struct {
+	annotations config.OwnershipAnnotations
+	informer    cache.SharedIndexInformer
+}
+
+func newNamespaceOwnership(kubeClient kubernetes.Interface, annotations config.OwnershipAnnotations) *namespaceOwnership {
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Namespace{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+	return &namespaceOwnership{annotations: annotations, informer: informer}
+}
+
+// Run watches the namespaces until stopCh is closed
+func (o *namespaceOwnership) Run(stopCh <-chan struct{}) {
+	o.informer.Run(stopCh)
+}
+
+// lookup returns the ownership of namespace read from its annotations, nil when it has
+// none of them
+func (o *namespaceOwnership) lookup(namespace string) *event.Ownership {
+	if namespace == "" {
+		return nil
+	}
+	item, exists, err := o.informer.GetStore().GetByKey(namespace)
+	if err != nil || !exists {
+		return nil
+	}
+	ns, ok := item.(*api_v1.Namespace)
+	if !ok {
+		return nil
+	}
+	ownership := event.Ownership{
+		Team:         ns.Annotations[o.annotations.Team],
+		Owner:        ns.Annotations[o.annotations.Owner],
+		SlackChannel: ns.Annotations[o.annotations.SlackChannel],
+		PagerService: ns.Annotations[o.annotations.PagerService],
+	}
+	if ownership == (event.Ownership{}) {
+		return nil
+	}
+	return &ownership
+}
+
+// own attaches the ownership of the namespace of e read from its annotations, unless e has
+// one already or o is nil
+func (o *namespaceOwnership) own(e *event.Event) {
+	if o != nil && e.Ownership == nil {
+		e.Ownership = o.lookup(e.Namespace)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNamespaceOwnership(t *testing.T) {
+	conf := &config.Config{}
+	conf.SetDefaults()
+	kubeClient := fake.NewSimpleClientset(
+		&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "checkout", Annotations: map[string]string{
+			"team": "payments", "owner": "alice@example.com", "slack-channel": "#payments",
+		}}},
+		&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "default"}},
+	)
+	o := newNamespaceOwnership(kubeClient, conf.Ownership.Annotations)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go o.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, o.informer.HasSynced) {
+		t.Fatal("namespaces not synced")
+	}
+
+	var Tests = []struct {
+		event    event.Event
+		expected *event.Ownership
+	}{
+		{event.Event{Namespace: "checkout", Name: "api"}, &event.Ownership{Team: "payments", Owner: "alice@example.com", SlackChannel: "#payments"}},
+		{event.Event{Namespace: "checkout", Name: "api", Ownership: &event.Ownership{Team: "platform"}}, &event.Ownership{Team: "platform"}},
+		{event.Event{Namespace: "default", Name: "web"}, nil},
+		{event.Event{Namespace: "unknown", Name: "web"}, nil},
+		{event.Event{Name: "node-1"}, nil},
+	}
+	for i, tt := range Tests {
+		o.own(&tt.event)
+		if !reflect.DeepEqual(tt.event.Ownership, tt.expected) {
+			t.Errorf("%d: expected ownership %+v, got %+v", i, tt.expected, tt.event.Ownership)
+		}
+	}
+
+	var disabled *namespaceOwnership
+	e := event.Event{Namespace: "checkout"}
+	disabled.own(&e)
+	if e.Ownership != nil {
+		t.Errorf("expected no ownership when disabled, got %+v", e.Ownership)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podSecurityKind is the kind of the events about workloads breaking the pod security checks
+const podSecurityKind = "PodSecurityViolation"
+
+// podSecurity checks the pod templates of workloads in the namespaces matching a label
+// selector, kept in the cache of a namespace informer
+type podSecurity struct {
+	informer cache.SharedIndexInformer
+}
+
+func newPodSecurity(kubeClient kubernetes.Interface, selector string) *podSecurity {
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Namespace{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+	return &podSecurity{informer: informer}
+}
+
+// Run watches the restricted namespaces until stopCh is closed
+func (p *podSecurity) Run(stopCh <-chan struct{}) {
+	p.informer.Run(stopCh)
+}
+
+// restricted returns whether namespace matches the selector of restricted namespaces
+func (p *podSecurity) restricted(namespace string) bool {
+	_, exists, err := p.informer.GetStore().GetByKey(namespace)
+	return err == nil && exists
+}
+
+// violations returns what the pod template of the workload obj requests beyond the
+// restricted profile, nil for objects other than workloads
+func (p *podSecurity) violations(obj runtime.Object) []string {
+	spec := checkedPodSpec(obj)
+	if spec == nil {
+		return nil
+	}
+
+	var violations []string
+	if spec.HostNetwork {
+		violations = append(violations, "uses the host network")
+	}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			violations = append(violations, fmt.Sprintf("volume %s mounts host path %s", v.Name, v.HostPath.Path))
+		}
+	}
+	podContext := spec.SecurityContext
+	if podContext == nil {
+		podContext = &api_v1.PodSecurityContext{}
+	}
+	for _, c := range containers(spec) {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &api_v1.SecurityContext{}
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, fmt.Sprintf("container %s is privileged", c.Name))
+		}
+		runAsUser, runAsNonRoot := podContext.RunAsUser, podContext.RunAsNonRoot
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		switch {
+		case runAsUser != nil && *runAsUser == 0:
+			violations = append(violations, fmt.Sprintf("container %s runs as root", c.Name))
+		case runAsUser == nil && (runAsNonRoot == nil || !*runAsNonRoot):
+			violations = append(violations, fmt.Sprintf("container %s may run as root, runAsNonRoot is not set", c.Name))
+		}
+	}
+	return violations
+}
+
+// podSecurityEvent returns the policy-violation event about a created or updated workload
+// of a restricted namespace breaking the pod security checks, nil when it complies. Updates
+// are only reported when they bring new violations.
+func (c *Controller) podSecurityEvent(e Event) *event.Event {
+	if !c.podSecurity.restricted(e.namespace) {
+		return nil
+	}
+	violations := c.reportedViolations(e, c.podSecurity.violations)
+	if violations == nil {
+		return nil
+	}
+	return &event.Event{
+		Name:       e.key,
+		Namespace:  e.namespace,
+		Kind:       podSecurityKind,
+		APIVersion: e.apiVersion,
+		Status:     "Warning",
+		Severity:   event.SeverityPolicyViolation,
+		Reason:     fmt.Sprintf("%s %s breaks the pod security of restricted namespace %s:\n%s", c.resourceType, e.key, e.namespace, strings.Join(violations, "\n")),
+		Obj:        e.obj,
+		OldObj:     e.oldObj,
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// securedDeployment returns a Deployment running a single container as a non-root user,
+// changed by mutate
+func securedDeployment(mutate func(spec *api_v1.PodSpec)) *apps_v1.Deployment {
+	d := deploymentWithImages("api", "ghcr.io/acme/api:v1")
+	nonRoot := true
+	d.Spec.Template.Spec.SecurityContext = &api_v1.PodSecurityContext{RunAsNonRoot: &nonRoot}
+	d.CreationTimestamp = meta_v1.Now()
+	if mutate != nil {
+		mutate(&d.Spec.Template.Spec)
+	}
+	return d
+}
+
+func TestPodSecurityViolations(t *testing.T) {
+	p := &podSecurity{}
+	root, privileged := int64(0), true
+
+	var Tests = []struct {
+		obj        *apps_v1.Deployment
+		violations []string
+	}{
+		{securedDeployment(nil), nil},
+		{securedDeployment(func(spec *api_v1.PodSpec) {
+			spec.HostNetwork = true
+			spec.Volumes = []api_v1.Volume{{Name: "docker", VolumeSource: api_v1.VolumeSource{HostPath: &api_v1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}}}
+		}), []string{"uses the host network", "volume docker mounts host path /var/run/docker.sock"}},
+		{securedDeployment(func(spec *api_v1.PodSpec) {
+			spec.Containers[0].SecurityContext = &api_v1.SecurityContext{Privileged: &privileged, RunAsUser: &root}
+		}), []string{"container api is privileged", "container api runs as root"}},
+		{securedDeployment(func(spec *api_v1.PodSpec) {
+			spec.SecurityContext = nil
+			spec.InitContainers = []api_v1.Container{{Name: "migrate", SecurityContext: &api_v1.SecurityContext{RunAsUser: new(int64)}}}
+		}), []string{"container migrate runs as root", "container api may run as root, runAsNonRoot is not set"}},
+	}
+
+	for i, test := range Tests {
+		if violations := p.violations(test.obj); !reflect.DeepEqual(violations, test.violations) {
+			t.Errorf("%d: expected %q, got %q", i, test.violations, violations)
+		}
+	}
+}
+
+func TestPodSecurityEvent(t *testing.T) {
+	p := newPodSecurity(fake.NewSimpleClientset(), "pod-security.kubernetes.io/enforce=restricted")
+	p.informer.GetStore().Add(&api_v1.Namespace{ObjectMeta: meta_v1.ObjectMeta{Name: "payments"}})
+	c := &Controller{podSecurity: p, resourceType: "Deployment", startTime: time.Now().Add(-time.Minute)}
+
+	hostNetwork := securedDeployment(func(spec *api_v1.PodSpec) { spec.HostNetwork = true })
+	var Tests = []struct {
+		e      Event
+		reason string
+	}{
+		{Event{eventType: "create", key: "api", namespace: "payments", obj: hostNetwork},
+			"Deployment api breaks the pod security of restricted namespace payments:\nuses the host network"},
+		{Event{eventType: "create", key: "api", namespace: "tools", obj: hostNetwork}, ""},
+		{Event{eventType: "create", key: "api", namespace: "payments", obj: securedDeployment(nil)}, ""},
+		{Event{eventType: "update", key: "api", namespace: "payments", oldObj: hostNetwork, obj: hostNetwork}, ""},
+		{Event{eventType: "update", key: "api", namespace: "payments", oldObj: securedDeployment(nil), obj: hostNetwork},
+			"Deployment api breaks the pod security of restricted namespace payments:\nuses the host network"},
+	}
+
+	for i, test := range Tests {
+		e := c.podSecurityEvent(test.e)
+		if test.reason == "" {
+			if e != nil {
+				t.Errorf("%d: expected no event, got %+v", i, e)
+			}
+			continue
+		}
+		if e == nil || e.Reason != test.reason || e.Kind != podSecurityKind || e.Severity != event.SeverityPolicyViolation {
+			t.Errorf("%d: unexpected event %+v", i, e)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// evictionReasons are the reasons of the Kubernetes Events about evictions, summarized in
+// node pressure digests instead of being sent one by one
+var evictionReasons = map[string]bool{
+	"Evicted":              true,
+	"EvictionThresholdMet": true,
+}
+
+// pressureConditions are the node conditions reporting a resource pressure
+var pressureConditions = []api_v1.NodeConditionType{
+	api_v1.NodeMemoryPressure,
+	api_v1.NodeDiskPressure,
+	api_v1.NodePIDPressure,
+}
+
+// nodePressure periodically sends a single digest of the nodes under memory, disk or PID
+// pressure, and of the nodes that evicted at least a threshold of pods since the last
+// digest, counted from the Evicted Kubernetes Events.
+type nodePressure struct {
+	w         *clusterWatcher
+	threshold int
+
+	mu sync.Mutex
+	// counts holds the last count of each eviction Kubernetes Event
+	counts map[types.UID]int32
+	// evictions holds the number of pods evicted from each node since the last digest
+	evictions map[string]int
+}
+
+func newNodePressure(w *clusterWatcher, threshold int) *nodePressure {
+	return &nodePressure{
+		w:         w,
+		threshold: threshold,
+		counts:    map[types.UID]int32{},
+		evictions: map[string]int{},
+	}
+}
+
+// watch watches the evictions of the pods of namespace until stopCh is closed
+func (p *nodePressure) watch(namespace string, stopCh <-chan struct{}) {
+	kubeClient := p.w.kubeClient
+	selector := "involvedObject.kind=Pod,reason=Evicted"
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return kubeClient.CoreV1().Events(namespace).List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return kubeClient.CoreV1().Events(namespace).Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Event{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			p.observe(obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			p.observe(new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			p.forget(obj)
+		},
+	})
+	informer.Run(stopCh)
+}
+
+// run sends a digest every interval until stopCh is closed
+func (p *nodePressure) run(interval time.Duration, stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if e := p.digest(interval); e != nil {
+			p.w.notify(*e)
+		}
+	}, interval, stopCh)
+}
+
+// observe counts the new evictions reported by a Kubernetes Event
+func (p *nodePressure) observe(obj interface{}) {
+	ev, ok := obj.(*api_v1.Event)
+	if !ok || ev.Reason != "Evicted" {
+		return
+	}
+	node := ev.Source.Host
+	if node == "" {
+		if pod, ok := p.w.objects.getObject(objName(api_v1.Pod{}), ev.InvolvedObject.Namespace, ev.InvolvedObject.Name).(*api_v1.Pod); ok {
+			node = pod.Spec.NodeName
+		}
+	}
+	if node == "" {
+		return
+	}
+
+	count := max(ev.Count, 1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if count > p.counts[ev.UID] {
+		p.evictions[node] += int(count - p.counts[ev.UID])
+	}
+	p.counts[ev.UID] = count
+}
+
+// forget drops a deleted eviction Kubernetes Event
+func (p *nodePressure) forget(obj interface{}) {
+	if ev, ok := obj.(*api_v1.Event); ok {
+		p.mu.Lock()
+		delete(p.counts, ev.UID)
+		p.mu.Unlock()
+	}
+}
+
+// digest returns the event summarizing the nodes under pressure and the nodes that evicted
+// at least threshold pods since the last digest, nil when there is none. The eviction
+// counts start over.
+func (p *nodePressure) digest(interval time.Duration) *event.Event {
+	p.mu.Lock()
+	evictions := p.evictions
+	p.evictions = map[string]int{}
+	p.mu.Unlock()
+
+	nodes := map[string][]string{}
+	for _, item := range p.w.objects.list(objName(api_v1.Node{})) {
+		node, ok := item.(*api_v1.Node)
+		if !ok {
+			continue
+		}
+		for _, condition := range node.Status.Conditions {
+			for _, pressure := range pressureConditions {
+				if condition.Type == pressure && condition.Status == api_v1.ConditionTrue {
+					nodes[node.Name] = append(nodes[node.Name], string(pressure))
+				}
+			}
+		}
+	}
+	for node, count := range evictions {
+		if count >= p.threshold {
+			nodes[node] = append(nodes[node], pluralize(count, "pod")+" evicted")
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{fmt.Sprintf("%s under pressure or evicting pods in the last %s:", pluralize(len(names), "node"), duration.HumanDuration(interval))}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s: %s", name, strings.Join(nodes[name], ", ")))
+	}
+	return &event.Event{
+		Kind:       "NodePressure",
+		APIVersion: V1,
+		Status:     "Warning",
+		Reason:     strings.Join(lines, "\n"),
+	}
+}
+
+// evictionEvent returns whether obj is a Kubernetes Event about an eviction
+func evictionEvent(obj runtime.Object) bool {
+	switch ev := obj.(type) {
+	case *api_v1.Event:
+		return evictionReasons[ev.Reason]
+	case *events_v1.Event:
+		return evictionReasons[ev.Reason]
+	}
+	return false
+}
+
+// pluralize renders a number of things, such as "1 node" or "3 nodes"
+func pluralize(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestNodePressure(t *testing.T) {
+	w := newClusterWatcher(&config.Config{}, &recorder{}, Cluster{}, nil, nil)
+	nodes := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	node := func(name string, pressures ...api_v1.NodeConditionType) *api_v1.Node {
+		n := &api_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: name}}
+		n.Status.Conditions = []api_v1.NodeCondition{{Type: api_v1.NodeReady, Status: api_v1.ConditionTrue}}
+		for _, pressure := range pressures {
+			n.Status.Conditions = append(n.Status.Conditions, api_v1.NodeCondition{Type: pressure, Status: api_v1.ConditionTrue})
+		}
+		return n
+	}
+	nodes.Add(node("node-a", api_v1.NodeMemoryPressure))
+	nodes.Add(node("node-b"))
+	nodes.Add(node("node-c"))
+	w.objects.add(objName(api_v1.Node{}), nodes, nil)
+
+	p := newNodePressure(w, 5)
+	evicted := func(uid, node string, count int32) *api_v1.Event {
+		return &api_v1.Event{
+			ObjectMeta:     meta_v1.ObjectMeta{Name: "api-7d9f." + uid, Namespace: "payments", UID: types.UID(uid)},
+			InvolvedObject: api_v1.ObjectReference{Kind: "Pod", Namespace: "payments", Name: "api-7d9f"},
+			Reason:         "Evicted",
+			Source:         api_v1.EventSource{Component: "kubelet", Host: node},
+			Count:          count,
+		}
+	}
+	p.observe(evicted("a", "node-a", 4))
+	p.observe(evicted("a", "node-a", 6))
+	p.observe(evicted("b", "node-b", 3))
+	p.observe(evicted("c", "node-b", 2))
+	p.observe(evicted("d", "node-c", 4))
+	p.observe(evicted("d", "node-c", 4))
+
+	e := p.digest(15 * time.Minute)
+	expected := "2 nodes under pressure or evicting pods in the last 15m:\n" +
+		"node-a: MemoryPressure, 6 pods evicted\n" +
+		"node-b: 5 pods evicted"
+	if e == nil || e.Reason != expected || e.Kind != "NodePressure" || e.Status != "Warning" {
+		t.Fatalf("expected a digest of\n%s\ngot %+v", expected, e)
+	}
+
+	// evictions are counted again from the next digest on
+	p.observe(evicted("a", "node-a", 7))
+	nodes.Update(node("node-a"))
+	if e := p.digest(15 * time.Minute); e != nil {
+		t.Errorf("expected no digest once pressure is relieved, got %q", e.Reason)
+	}
+}
+
+func TestEvictionEvent(t *testing.T) {
+	var Tests = []struct {
+		reason   string
+		expected bool
+	}{
+		{"Evicted", true},
+		{"EvictionThresholdMet", true},
+		{"BackOff", false},
+	}
+
+	for _, test := range Tests {
+		if evicted := evictionEvent(&api_v1.Event{Reason: test.reason}); evicted != test.expected {
+			t.Errorf("evictionEvent(%s) = %v, expected %v", test.reason, evicted, test.expected)
+		}
+	}
+	if evictionEvent(&api_v1.Pod{}) {
+		t.Error("expected pods not to be eviction events")
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/sirupsen/logrus"
+	authorization_v1 "k8s.io/api/authorization/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// watchVerbs are the verbs informers need on a watched resource
+var watchVerbs = []string{"list", "watch"}
+
+// AccessDenial is a watch kubewatch is not allowed to run
+type AccessDenial struct {
+	Cluster   string   `json:"cluster,omitempty"`
+	Group     string   `json:"group,omitempty"`
+	Resource  string   `json:"resource"`
+	Namespace string   `json:"namespace,omitempty"`
+	Verbs     []string `json:"verbs"`
+}
+
+var accessDenials struct {
+	mu   sync.Mutex
+	list []AccessDenial
+}
+
+// AccessDenials returns the watches found to be forbidden by the RBAC self-check run at startup
+func AccessDenials() []AccessDenial {
+	accessDenials.mu.Lock()
+	defer accessDenials.mu.Unlock()
+	return append([]AccessDenial{}, accessDenials.list...)
+}
+
+// checkAccess reviews the permissions kubewatch has on every watched resource, logging
+// the watches that will fail, and sending them to the event handler when notify is set
+func (w *clusterWatcher) checkAccess(notify bool) {
+	logger := logrus.WithField("pkg", "kubewatch-rbac")
+	if w.clusterName != "" {
+		logger = logger.WithField("cluster", w.clusterName)
+	}
+
+	denials, err := w.accessDenials()
+	if err != nil {
+		logger.Warnf("Skipping RBAC self-check: %v", err)
+	}
+	for _, denial := range denials {
+		scope := "cluster-wide"
+		if denial.Namespace != "" {
+			scope = "in namespace " + denial.Namespace
+		}
+		reason := fmt.Sprintf("cannot %s %s %s", strings.Join(denial.Verbs, ", "), denial.Resource, scope)
+		logger.Warnf("Missing RBAC permissions: %s, its events will not be reported", reason)
+		metrics.WatchForbidden.WithLabelValues(w.clusterName, denial.Resource, denial.Namespace).Set(1)
+
+		accessDenials.mu.Lock()
+		accessDenials.list = append(accessDenials.list, denial)
+		accessDenials.mu.Unlock()
+
+		if notify {
+			e := event.Event{
+				Name:        denial.Resource,
+				Namespace:   denial.Namespace,
+				Kind:        "AccessDenied",
+				Status:      "Danger",
+				Reason:      reason,
+				ClusterName: w.clusterName,
+			}
+			w.cluster.tag(&e)
+			populate(&e)
+			w.cluster.group(&e)
+			w.eventHandler.Handle(e)
+		}
+	}
+}
+
+// CheckAccess returns the watches configured in conf that kubewatch is not allowed to run
+// in cluster, without starting them
+func CheckAccess(conf *config.Config, cluster Cluster) ([]AccessDenial, error) {
+	w := newClusterWatcher(conf, nil, cluster, nil, nil)
+	crds := conf.CustomResources
+	if conf.CertManager.Enabled {
+		crds = withCertificateCRD(crds)
+	}
+	crds = withGitOpsCRDs(crds, conf.GitOps)
+	if conf.KEDA.Enabled {
+		crds = withScaledObjectCRD(crds)
+	}
+	if conf.Karpenter.Enabled {
+		crds = withKarpenterCRDs(crds)
+	}
+	if conf.VPA.Enabled {
+		crds = withVPACRD(crds)
+	}
+	for i := range resources {
+		if r := &resources[i]; r.enabled(conf.Resource) {
+			w.watched[r.name] = &watchedResource{builtin: r, namespaced: r.namespaced}
+		}
+	}
+	for _, crd := range crds {
+		if key := crdKey(crd); w.watched[key] == nil {
+			w.watched[key] = &watchedResource{crd: crd, namespaced: isNamespacedCustomResource(cluster.KubeClient, crd)}
+		}
+	}
+	// namespaces matched by a selector are only known at runtime, check cluster-wide access
+	namespaces := []string{""}
+	if conf.NamespaceSelector == "" {
+		namespaces = conf.WatchedNamespaces()
+	}
+	for _, namespace := range namespaces {
+		w.namespaces[namespace] = nil
+	}
+	return w.accessDenials()
+}
+
+// accessDenials returns the watched resources kubewatch lacks permissions for
+func (w *clusterWatcher) accessDenials() ([]AccessDenial, error) {
+	w.mu.Lock()
+	var keys, namespaces []string
+	for key := range w.watched {
+		keys = append(keys, key)
+	}
+	for namespace := range w.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	watched := make(map[string]*watchedResource, len(w.watched))
+	for key, wr := range w.watched {
+		watched[key] = wr
+	}
+	w.mu.Unlock()
+	sort.Strings(keys)
+	sort.Strings(namespaces)
+
+	var denials []AccessDenial
+	for _, key := range keys {
+		wr := watched[key]
+		group, resource := wr.crd.Group, wr.crd.Resource
+		if wr.builtin != nil {
+			gvr := wr.builtin.groupVersionResource()
+			group, resource = gvr.Group, gvr.Resource
+		}
+
+		scopes := []string{""}
+		if wr.namespaced {
+			scopes = namespaces
+		}
+		for _, namespace := range scopes {
+			denial := AccessDenial{Cluster: w.clusterName, Group: group, Resource: resource, Namespace: namespace}
+			for _, verb := range watchVerbs {
+				allowed, err := w.allowed(verb, group, resource, namespace)
+				if err != nil {
+					return denials, err
+				}
+				if !allowed {
+					denial.Verbs = append(denial.Verbs, verb)
+				}
+			}
+			if denial.Verbs != nil {
+				denials = append(denials, denial)
+			}
+		}
+	}
+	return denials, nil
+}
+
+// allowed runs a SelfSubjectAccessReview for verb on a resource
+func (w *clusterWatcher) allowed(verb, group, resource, namespace string) (bool, error) {
+	review, err := w.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), &authorization_v1.SelfSubjectAccessReview{
+		Spec: authorization_v1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorization_v1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     group,
+				Resource:  resource,
+			},
+		},
+	}, meta_v1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	authorization_v1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckAccess(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	// pods can only be listed in team-a, nodes are fully allowed
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorization_v1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Resource == "nodes" ||
+			(attrs.Resource == "pods" && attrs.Namespace == "team-a" && attrs.Verb == "list")
+		return true, review, nil
+	})
+
+	r := &recorder{}
+	w := newClusterWatcher(&config.Config{}, r, Cluster{Name: "prod", KubeClient: client}, nil, nil)
+	for i := range resources {
+		if r := &resources[i]; r.name == "Pod" || r.name == "Node" {
+			w.watched[r.name] = &watchedResource{builtin: r, namespaced: r.namespaced}
+		}
+	}
+	w.namespaces["team-a"] = nil
+	w.namespaces["team-b"] = nil
+
+	expected := []AccessDenial{
+		{Cluster: "prod", Resource: "pods", Namespace: "team-a", Verbs: []string{"watch"}},
+		{Cluster: "prod", Resource: "pods", Namespace: "team-b", Verbs: []string{"list", "watch"}},
+	}
+	if denials, err := w.accessDenials(); err != nil || !reflect.DeepEqual(denials, expected) {
+		t.Fatalf("expected %+v, got %+v, %v", expected, denials, err)
+	}
+
+	w.checkAccess(true)
+	if names := r.names(); len(names) != 2 || names[0] != "cannot watch pods in namespace team-a pods" {
+		t.Fatalf("expected access denied notifications, got %v", names)
+	}
+}
+
+func TestCheckAccessConfig(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorization_v1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != "deployments"
+		return true, review, nil
+	})
+
+	conf := &config.Config{Namespaces: []string{"team-a"}}
+	conf.Resource.Deployment = true
+	conf.Resource.Node = true
+
+	expected := []AccessDenial{
+		{Group: "apps", Resource: "deployments", Namespace: "team-a", Verbs: []string{"list", "watch"}},
+	}
+	if denials, err := CheckAccess(conf, Cluster{KubeClient: client}); err != nil || !reflect.DeepEqual(denials, expected) {
+		t.Fatalf("expected %+v, got %+v, %v", expected, denials, err)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// redactedValue replaces sensitive values
+const redactedValue = "[REDACTED]"
+
+// fingerprintPrefix starts the masks of sensitive values compared in diffs
+const fingerprintPrefix = "[REDACTED:"
+
+// lastAppliedAnnotation holds the full object applied with kubectl, Secret data included
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// sensitiveEnvVar matches the names of env vars whose values are always redacted
+var sensitiveEnvVar = regexp.MustCompile(`(?i)PASSW(OR)?D|TOKEN|SECRET|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY|CREDENTIAL`)
+
+// fingerprintKey keys the fingerprints telling redacted values apart in diffs, it is
+// random so that fingerprints cannot be matched against guessed values
+var fingerprintKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// redactor replaces sensitive values in the objects of events with [REDACTED]
+type redactor struct {
+	envVars     []*regexp.Regexp
+	annotations []string
+}
+
+// newRedactor returns the redactor configured in conf, nil when redaction is disabled
+func newRedactor(conf config.Redaction) *redactor {
+	if conf.Disabled {
+		return nil
+	}
+	r := &redactor{envVars: []*regexp.Regexp{sensitiveEnvVar}, annotations: conf.Annotations}
+	for _, pattern := range conf.EnvVars {
+		// patterns are checked by config validation
+		if re, err := regexp.Compile("(?i)" + pattern); err == nil {
+			r.envVars = append(r.envVars, re)
+		}
+	}
+	return r
+}
+
+// redact replaces the sensitive values of the objects of e, on copies of the objects.
+// Sensitive fields changed by updates stay in the diff, with their values redacted.
+func (r *redactor) redact(e *event.Event) {
+	if r == nil {
+		return
+	}
+	secret := e.Kind == "Secret"
+	obj, changed := r.object(e.Obj, secret)
+	oldObj, oldChanged := r.object(e.OldObj, secret)
+	if !changed && !oldChanged {
+		return
+	}
+	if e.Diff != nil {
+		e.Diff = r.diff(e.OldObj, e.Obj, secret)
+	}
+	e.Obj, e.OldObj = obj, oldObj
+}
+
+// object returns a copy of obj with its sensitive values redacted, and whether it had any.
+// It returns obj when it has none.
+func (r *redactor) object(obj runtime.Object, secret bool) (runtime.Object, bool) {
+	if obj == nil {
+		return nil, false
+	}
+	secret = secret || isSecret(obj)
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u = u.DeepCopy()
+		if !r.fields(u.Object, secret, redact) {
+			return obj, false
+		}
+		return u, true
+	}
+
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil || !r.fields(fields, secret, redact) {
+		return obj, false
+	}
+	redacted := obj.DeepCopyObject()
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(fields, redacted); err != nil {
+		return obj, false
+	}
+	return redacted, true
+}
+
+// diff returns the changes from old to new with sensitive values redacted. Sensitive
+// values are compared by fingerprint, so that their changes are kept.
+func (r *redactor) diff(old, new runtime.Object, secret bool) []diff.Change {
+	masked := func(obj runtime.Object) runtime.Object {
+		if obj == nil {
+			return nil
+		}
+		var fields map[string]interface{}
+		if u, ok := obj.(runtime.Unstructured); ok {
+			fields = runtime.DeepCopyJSON(u.UnstructuredContent())
+		} else if converted, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err == nil {
+			fields = converted
+		} else {
+			return nil
+		}
+		r.fields(fields, secret || isSecret(obj), fingerprint)
+		return &unstructured.Unstructured{Object: fields}
+	}
+	changes, err := diff.Compute(masked(old), masked(new))
+	if err != nil {
+		return nil
+	}
+	for i := range changes {
+		changes[i].Old = unmask(changes[i].Old)
+		changes[i].New = unmask(changes[i].New)
+	}
+	return changes
+}
+
+// fields replaces the sensitive values of the fields of an object with mask, returning
+// whether it had any
+func (r *redactor) fields(fields map[string]interface{}, secret bool, mask func(string) string) bool {
+	found := false
+	if secret {
+		for _, key := range []string{"data", "stringData"} {
+			data, _ := fields[key].(map[string]interface{})
+			for name, value := range data {
+				if s, ok := value.(string); ok {
+					data[name] = mask(s)
+					found = true
+				}
+			}
+		}
+		// data is base64 encoded in objects
+		if data, ok := fields["data"].(map[string]interface{}); ok {
+			for name, value := range data {
+				if s, ok := value.(string); ok {
+					data[name] = base64.StdEncoding.EncodeToString([]byte(s))
+				}
+			}
+		}
+	}
+
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		for key, value := range annotations {
+			if s, ok := value.(string); ok && r.sensitiveAnnotation(key, secret) {
+				annotations[key] = mask(s)
+				found = true
+			}
+		}
+	}
+	return r.envVarValues(fields, mask) || found
+}
+
+// envVarValues replaces the values of sensitive env vars found anywhere in value
+func (r *redactor) envVarValues(value interface{}, mask func(string) string) bool {
+	found := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if list, ok := field.([]interface{}); ok && key == "env" {
+				for _, item := range list {
+					envVar, _ := item.(map[string]interface{})
+					name, _ := envVar["name"].(string)
+					if s, ok := envVar["value"].(string); ok && r.sensitiveEnvVar(name) {
+						envVar["value"] = mask(s)
+						found = true
+					}
+				}
+				continue
+			}
+			if r.envVarValues(field, mask) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if r.envVarValues(item, mask) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func (r *redactor) sensitiveEnvVar(name string) bool {
+	for _, re := range r.envVars {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) sensitiveAnnotation(key string, secret bool) bool {
+	if secret && key == lastAppliedAnnotation {
+		return true
+	}
+	for _, pattern := range r.annotations {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func isSecret(obj runtime.Object) bool {
+	switch o := obj.(type) {
+	case *api_v1.Secret:
+		return true
+	case *unstructured.Unstructured:
+		return o.GetKind() == "Secret" && o.GetAPIVersion() == "v1"
+	case *meta_v1.PartialObjectMetadata:
+		return o.Kind == "Secret"
+	}
+	return false
+}
+
+func redact(string) string {
+	return redactedValue
+}
+
+// fingerprint masks value with a prefix of its keyed hash, telling different values apart
+func fingerprint(value string) string {
+	mac := hmac.New(sha256.New, fingerprintKey)
+	mac.Write([]byte(value))
+	return fingerprintPrefix + hex.EncodeToString(mac.Sum(nil))[:16] + "]"
+}
+
+// unmask replaces the fingerprints in a changed value with [REDACTED]
+func unmask(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, fingerprintPrefix) {
+			return redactedValue
+		}
+		// Secret data is base64 encoded
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil && strings.HasPrefix(string(decoded), fingerprintPrefix) {
+			return redactedValue
+		}
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = unmask(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = unmask(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deploymentWithEnv(version string, env ...api_v1.EnvVar) *apps_v1.Deployment {
+	d := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{
+		Name:        "api",
+		Annotations: map[string]string{"vault.hashicorp.com/role": "api-" + version, "team": "payments"},
+	}}
+	d.Spec.Template.Spec.Containers = []api_v1.Container{{Name: "api", Image: "api:" + version, Env: env}}
+	return d
+}
+
+func TestRedactUpdate(t *testing.T) {
+	r := newRedactor(config.Redaction{EnvVars: []string{"^dsn$"}, Annotations: []string{"vault.hashicorp.com/*"}})
+	old := deploymentWithEnv("v1",
+		api_v1.EnvVar{Name: "DB_PASSWORD", Value: "hunter2"},
+		api_v1.EnvVar{Name: "DSN", Value: "postgres://api:hunter2@db"},
+		api_v1.EnvVar{Name: "LOG_LEVEL", Value: "info"})
+	new := deploymentWithEnv("v2",
+		api_v1.EnvVar{Name: "DB_PASSWORD", Value: "correct-horse"},
+		api_v1.EnvVar{Name: "DSN", Value: "postgres://api:hunter2@db"},
+		api_v1.EnvVar{Name: "LOG_LEVEL", Value: "debug"})
+	changes, _ := diff.Compute(old, new)
+	e := event.Event{Kind: "Deployment", Reason: "Updated", Obj: new, OldObj: old, Diff: changes}
+
+	r.redact(&e)
+
+	env := e.Obj.(*apps_v1.Deployment).Spec.Template.Spec.Containers[0].Env
+	if env[0].Value != redactedValue || env[1].Value != redactedValue || env[2].Value != "debug" {
+		t.Errorf("expected the password and DSN to be redacted, got %v", env)
+	}
+	if e.OldObj.(*apps_v1.Deployment).Annotations["vault.hashicorp.com/role"] != redactedValue {
+		t.Errorf("expected the vault annotation to be redacted, got %v", e.OldObj.(*apps_v1.Deployment).Annotations)
+	}
+	if new.Spec.Template.Spec.Containers[0].Env[0].Value != "correct-horse" {
+		t.Error("expected the informer object to be left unchanged")
+	}
+
+	expected := []diff.Change{
+		{Path: `metadata.annotations["vault.hashicorp.com/role"]`, Old: redactedValue, New: redactedValue},
+		{Path: "spec.template.spec.containers[0].env[0].value", Old: redactedValue, New: redactedValue},
+		{Path: "spec.template.spec.containers[0].env[2].value", Old: "info", New: "debug"},
+		{Path: "spec.template.spec.containers[0].image", Old: "api:v1", New: "api:v2"},
+	}
+	if !reflect.DeepEqual(e.Diff, expected) {
+		t.Errorf("expected the changed password to stay in the diff, redacted, got %+v", e.Diff)
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	r := newRedactor(config.Redaction{})
+	secret := &api_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "db", Annotations: map[string]string{lastAppliedAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`}},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	}
+	e := event.Event{Kind: "Secret", Reason: "Created", Obj: secret}
+	r.redact(&e)
+	redacted := e.Obj.(*api_v1.Secret)
+	if string(redacted.Data["password"]) != redactedValue || redacted.Annotations[lastAppliedAnnotation] != redactedValue {
+		t.Errorf("expected the secret data to be redacted, got %v %v", redacted.Data, redacted.Annotations)
+	}
+
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "db"},
+		"stringData": map[string]interface{}{"password": "hunter2"},
+	}}
+	e = event.Event{Kind: "secrets", Reason: "Created", Obj: u}
+	r.redact(&e)
+	if value, _, _ := unstructured.NestedString(e.Obj.(*unstructured.Unstructured).Object, "stringData", "password"); value != redactedValue {
+		t.Errorf("expected the string data of unstructured secrets to be redacted, got %q", value)
+	}
+
+	if r := newRedactor(config.Redaction{Disabled: true}); r != nil {
+		t.Error("expected no redactor when redaction is disabled")
+	}
+}
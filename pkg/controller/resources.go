@@ -0,0 +1,373 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/bitnami-labs/kubewatch/config"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	networking_v1 "k8s.io/api/networking/v1"
+	rbac_v1 "k8s.io/api/rbac/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resource describes a built-in kind kubewatch knows how to watch
+type resource struct {
+	// name is the kind reported in events
+	name string
+	// plural is the name of the resource in the API
+	plural     string
+	apiVersion string
+	// namespaced resources are watched in the configured namespaces only
+	namespaced bool
+	object     runtime.Object
+	enabled    func(r config.Resource) bool
+	list       func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error)
+	watch      func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error)
+	// transform is applied to objects before they are cached, after the configured cache transform
+	transform cache.TransformFunc
+}
+
+// groupVersionResource returns the GVR of the resource, used by metadata-only informers
+func (r resource) groupVersionResource() schema.GroupVersionResource {
+	gv, _ := schema.ParseGroupVersion(r.apiVersion)
+	return gv.WithResource(r.plural)
+}
+
+var resources = []resource{
+	{
+		name:       objName(api_v1.Event{}),
+		plural:     "events",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.Event{},
+		enabled:    func(r config.Resource) bool { return r.CoreEvent },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = ""
+			return c.CoreV1().Events(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = ""
+			return c.CoreV1().Events(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(events_v1.Event{}),
+		plural:     "events",
+		apiVersion: EVENTS_V1,
+		namespaced: true,
+		object:     &events_v1.Event{},
+		enabled:    func(r config.Resource) bool { return r.Event },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = ""
+			return c.EventsV1().Events(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = ""
+			return c.EventsV1().Events(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.Pod{}),
+		plural:     "pods",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.Pod{},
+		enabled:    func(r config.Resource) bool { return r.Pod },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().Pods(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().Pods(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(autoscaling_v1.HorizontalPodAutoscaler{}),
+		plural:     "horizontalpodautoscalers",
+		apiVersion: AUTOSCALING_V1,
+		namespaced: true,
+		object:     &autoscaling_v1.HorizontalPodAutoscaler{},
+		enabled:    func(r config.Resource) bool { return r.HPA },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.AutoscalingV1().HorizontalPodAutoscalers(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(apps_v1.DaemonSet{}),
+		plural:     "daemonsets",
+		apiVersion: APPS_V1,
+		namespaced: true,
+		object:     &apps_v1.DaemonSet{},
+		enabled:    func(r config.Resource) bool { return r.DaemonSet },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.AppsV1().DaemonSets(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.AppsV1().DaemonSets(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(apps_v1.StatefulSet{}),
+		plural:     "statefulsets",
+		apiVersion: APPS_V1,
+		namespaced: true,
+		object:     &apps_v1.StatefulSet{},
+		enabled:    func(r config.Resource) bool { return r.StatefulSet },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.AppsV1().StatefulSets(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.AppsV1().StatefulSets(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(apps_v1.ReplicaSet{}),
+		plural:     "replicasets",
+		apiVersion: APPS_V1,
+		namespaced: true,
+		object:     &apps_v1.ReplicaSet{},
+		enabled:    func(r config.Resource) bool { return r.ReplicaSet },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.AppsV1().ReplicaSets(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.AppsV1().ReplicaSets(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.Service{}),
+		plural:     "services",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.Service{},
+		enabled:    func(r config.Resource) bool { return r.Services },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().Services(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().Services(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(apps_v1.Deployment{}),
+		plural:     "deployments",
+		apiVersion: APPS_V1,
+		namespaced: true,
+		object:     &apps_v1.Deployment{},
+		enabled:    func(r config.Resource) bool { return r.Deployment },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.AppsV1().Deployments(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.AppsV1().Deployments(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.Namespace{}),
+		plural:     "namespaces",
+		apiVersion: V1,
+		object:     &api_v1.Namespace{},
+		enabled:    func(r config.Resource) bool { return r.Namespace },
+		list: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().Namespaces().List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().Namespaces().Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.ReplicationController{}),
+		plural:     "replicationcontrollers",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.ReplicationController{},
+		enabled:    func(r config.Resource) bool { return r.ReplicationController },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().ReplicationControllers(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().ReplicationControllers(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(batch_v1.Job{}),
+		plural:     "jobs",
+		apiVersion: BATCH_V1,
+		namespaced: true,
+		object:     &batch_v1.Job{},
+		enabled:    func(r config.Resource) bool { return r.Job },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.BatchV1().Jobs(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.BatchV1().Jobs(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(batch_v1.CronJob{}),
+		plural:     "cronjobs",
+		apiVersion: BATCH_V1,
+		namespaced: true,
+		object:     &batch_v1.CronJob{},
+		enabled:    func(r config.Resource) bool { return r.CronJob },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.BatchV1().CronJobs(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.BatchV1().CronJobs(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.Node{}),
+		plural:     "nodes",
+		apiVersion: V1,
+		object:     &api_v1.Node{},
+		enabled:    func(r config.Resource) bool { return r.Node },
+		list: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().Nodes().List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().Nodes().Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.ServiceAccount{}),
+		plural:     "serviceaccounts",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.ServiceAccount{},
+		enabled:    func(r config.Resource) bool { return r.ServiceAccount },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().ServiceAccounts(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().ServiceAccounts(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(rbac_v1.ClusterRole{}),
+		plural:     "clusterroles",
+		apiVersion: RBAC_V1,
+		object:     &rbac_v1.ClusterRole{},
+		enabled:    func(r config.Resource) bool { return r.ClusterRole },
+		list: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.RbacV1().ClusterRoles().List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.RbacV1().ClusterRoles().Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(rbac_v1.ClusterRoleBinding{}),
+		plural:     "clusterrolebindings",
+		apiVersion: RBAC_V1,
+		object:     &rbac_v1.ClusterRoleBinding{},
+		enabled:    func(r config.Resource) bool { return r.ClusterRoleBinding },
+		list: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.RbacV1().ClusterRoleBindings().List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.RbacV1().ClusterRoleBindings().Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.PersistentVolume{}),
+		plural:     "persistentvolumes",
+		apiVersion: V1,
+		object:     &api_v1.PersistentVolume{},
+		enabled:    func(r config.Resource) bool { return r.PersistentVolume },
+		list: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().PersistentVolumes().List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, _ string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().PersistentVolumes().Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(api_v1.Secret{}),
+		plural:     "secrets",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.Secret{},
+		enabled:    func(r config.Resource) bool { return r.Secret },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().Secrets(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().Secrets(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       helmReleaseKind,
+		plural:     "secrets",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.Secret{},
+		enabled:    func(r config.Resource) bool { return r.HelmRelease },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector, options.FieldSelector = "owner=helm", "type="+helmReleaseType
+			return c.CoreV1().Secrets(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector, options.FieldSelector = "owner=helm", "type="+helmReleaseType
+			return c.CoreV1().Secrets(namespace).Watch(context.Background(), options)
+		},
+		transform: stripHelmRelease,
+	},
+	{
+		name:       objName(api_v1.ConfigMap{}),
+		plural:     "configmaps",
+		apiVersion: V1,
+		namespaced: true,
+		object:     &api_v1.ConfigMap{},
+		enabled:    func(r config.Resource) bool { return r.ConfigMap },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.CoreV1().ConfigMaps(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.CoreV1().ConfigMaps(namespace).Watch(context.Background(), options)
+		},
+	},
+	{
+		name:       objName(networking_v1.Ingress{}),
+		plural:     "ingresses",
+		apiVersion: NETWORKING_V1,
+		namespaced: true,
+		object:     &networking_v1.Ingress{},
+		enabled:    func(r config.Resource) bool { return r.Ingress },
+		list: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.NetworkingV1().Ingresses(namespace).List(context.Background(), options)
+		},
+		watch: func(c kubernetes.Interface, namespace string, options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.NetworkingV1().Ingresses(namespace).Watch(context.Background(), options)
+		},
+	},
+}
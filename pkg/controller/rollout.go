@@ -0,0 +1,168 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/google/uuid"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// deploymentRevisionAnnotation holds the rollout revision of Deployments and their ReplicaSets
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// Reasons of the Deployment Progressing condition ending a rollout
+const (
+	rolloutCompleteReason = "NewReplicaSetAvailable"
+	rolloutFailedReason   = "ProgressDeadlineExceeded"
+)
+
+// rolloutEvent folds the events of ReplicaSets owned by a Deployment, and Deployment updates,
+// into Rollout events about the Deployment: rollout started, ReplicaSet created, scaled up or
+// down and deleted, rollout complete or failed.
+// It returns false for events unrelated to rollouts, which are handled as usual, and a nil
+// event for rollout related events not worth a notification, such as status updates.
+func (c *Controller) rolloutEvent(e Event) (*event.Event, bool) {
+	switch obj := e.obj.(type) {
+	case *apps_v1.ReplicaSet:
+		owner := deploymentOwner(obj)
+		if owner == nil {
+			return nil, false
+		}
+		deployment := owner.Name
+		revision := obj.Annotations[deploymentRevisionAnnotation]
+		id := rolloutID(owner.UID, revision)
+
+		switch e.eventType {
+		case "create":
+			if !c.startupInventory && !e.missed && obj.CreationTimestamp.Time.Before(c.startTime) {
+				return nil, true
+			}
+			return newRolloutEvent(e, deployment, id, "Normal", fmt.Sprintf("ReplicaSet %s created for revision %s", obj.Name, revision)), true
+		case "update":
+			old, ok := e.oldObj.(*apps_v1.ReplicaSet)
+			if !ok {
+				return nil, true
+			}
+			from, to := replicaSetReplicas(old), replicaSetReplicas(obj)
+			if from == to {
+				return nil, true
+			}
+			scaled := "scaled up"
+			if to < from {
+				scaled = "scaled down"
+			}
+			return newRolloutEvent(e, deployment, id, "Normal", fmt.Sprintf("ReplicaSet %s (revision %s) %s from %d to %d", obj.Name, revision, scaled, from, to)), true
+		case "delete":
+			return newRolloutEvent(e, deployment, id, "Normal", fmt.Sprintf("ReplicaSet %s (revision %s) deleted", obj.Name, revision)), true
+		}
+	case *apps_v1.Deployment:
+		if e.eventType != "update" {
+			return nil, false
+		}
+		old, ok := e.oldObj.(*apps_v1.Deployment)
+		if !ok {
+			return nil, false
+		}
+		if obj.Generation != old.Generation {
+			// scaling does not roll out new pods, it is reported as a scale update
+			if scaleChange(old, obj) != nil && equality.Semantic.DeepEqual(old.Spec.Template, obj.Spec.Template) {
+				return nil, false
+			}
+			// the revision of the new ReplicaSet is set once it is created
+			id := rolloutID(obj.UID, nextRevision(old.Annotations[deploymentRevisionAnnotation]))
+			return newRolloutEvent(e, obj.Name, id, "Warning", fmt.Sprintf("Rollout started for generation %d", obj.Generation)), true
+		}
+
+		id := rolloutID(obj.UID, obj.Annotations[deploymentRevisionAnnotation])
+		reason, message := deploymentProgress(obj)
+		if oldReason, _ := deploymentProgress(old); reason == oldReason {
+			return nil, true
+		}
+		switch reason {
+		case rolloutCompleteReason:
+			return newRolloutEvent(e, obj.Name, id, "Normal", fmt.Sprintf("Rollout of revision %s complete: %s", obj.Annotations[deploymentRevisionAnnotation], message)), true
+		case rolloutFailedReason:
+			return newRolloutEvent(e, obj.Name, id, "Danger", fmt.Sprintf("Rollout of revision %s failed: %s", obj.Annotations[deploymentRevisionAnnotation], message)), true
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+func newRolloutEvent(e Event, deployment string, correlationID string, status string, reason string) *event.Event {
+	return &event.Event{
+		Name:          deployment,
+		Namespace:     e.namespace,
+		Kind:          "Rollout",
+		APIVersion:    APPS_V1,
+		Status:        status,
+		Reason:        reason,
+		CorrelationID: correlationID,
+		Obj:           e.obj,
+		OldObj:        e.oldObj,
+	}
+}
+
+// rolloutID returns the correlation ID shared by the events of a revision of a Deployment
+func rolloutID(deployment types.UID, revision string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("kubewatch/rollout/"+string(deployment)+"/"+revision)).String()
+}
+
+// nextRevision returns the revision following revision, empty if it is not a number
+func nextRevision(revision string) string {
+	n, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(n+1, 10)
+}
+
+// deploymentOwner returns the reference to the Deployment controlling rs, if any
+func deploymentOwner(rs *apps_v1.ReplicaSet) *meta_v1.OwnerReference {
+	if owner := meta_v1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+		return owner
+	}
+	return nil
+}
+
+func replicaSetReplicas(rs *apps_v1.ReplicaSet) int32 {
+	if rs.Spec.Replicas == nil {
+		return 1
+	}
+	return *rs.Spec.Replicas
+}
+
+// deploymentProgress returns the reason and message of the Deployment Progressing condition
+func deploymentProgress(d *apps_v1.Deployment) (string, string) {
+	for _, condition := range d.Status.Conditions {
+		if condition.Type == apps_v1.DeploymentProgressing {
+			if condition.Status == api_v1.ConditionUnknown {
+				return "", ""
+			}
+			return condition.Reason, condition.Message
+		}
+	}
+	return "", ""
+}
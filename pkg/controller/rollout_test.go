@@ -0,0 +1,134 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newReplicaSet(replicas int32, owned bool) *apps_v1.ReplicaSet {
+	rs := &apps_v1.ReplicaSet{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              "web-5d8f",
+			Namespace:         "default",
+			CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute)),
+			Annotations:       map[string]string{deploymentRevisionAnnotation: "3"},
+		},
+		Spec: apps_v1.ReplicaSetSpec{Replicas: &replicas},
+	}
+	if owned {
+		controller := true
+		rs.OwnerReferences = []meta_v1.OwnerReference{{Kind: "Deployment", Name: "web", Controller: &controller}}
+	}
+	return rs
+}
+
+func newDeployment(generation int64, reason string) *apps_v1.Deployment {
+	d := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{
+		Name:        "web",
+		Namespace:   "default",
+		Generation:  generation,
+		Annotations: map[string]string{deploymentRevisionAnnotation: "3"},
+	}}
+	if reason != "" {
+		d.Status.Conditions = []apps_v1.DeploymentCondition{{
+			Type:    apps_v1.DeploymentProgressing,
+			Status:  api_v1.ConditionTrue,
+			Reason:  reason,
+			Message: "some message",
+		}}
+	}
+	return d
+}
+
+func TestRolloutEvent(t *testing.T) {
+	c := &Controller{startTime: time.Now()}
+
+	var Tests = []struct {
+		eventType string
+		obj       runtime.Object
+		oldObj    runtime.Object
+		handled   bool
+		reason    string
+	}{
+		{"create", newReplicaSet(1, true), nil, true, "ReplicaSet web-5d8f created for revision 3"},
+		{"update", newReplicaSet(3, true), newReplicaSet(1, true), true, "ReplicaSet web-5d8f (revision 3) scaled up from 1 to 3"},
+		{"update", newReplicaSet(0, true), newReplicaSet(3, true), true, "ReplicaSet web-5d8f (revision 3) scaled down from 3 to 0"},
+		{"update", newReplicaSet(3, true), newReplicaSet(3, true), true, ""},
+		{"delete", newReplicaSet(0, true), nil, true, "ReplicaSet web-5d8f (revision 3) deleted"},
+		{"update", newReplicaSet(3, false), newReplicaSet(1, false), false, ""},
+		{"update", newDeployment(2, "ReplicaSetUpdated"), newDeployment(1, "NewReplicaSetAvailable"), true, "Rollout started for generation 2"},
+		{"update", newDeployment(2, "ReplicaSetUpdated"), newDeployment(2, "ReplicaSetUpdated"), true, ""},
+		{"update", scaledDeployment(2, 5), scaledDeployment(1, 2), false, ""},
+		{"update", newDeployment(2, "NewReplicaSetAvailable"), newDeployment(2, "ReplicaSetUpdated"), true, "Rollout of revision 3 complete: some message"},
+		{"update", newDeployment(2, "ProgressDeadlineExceeded"), newDeployment(2, "ReplicaSetUpdated"), true, "Rollout of revision 3 failed: some message"},
+		{"create", newDeployment(1, ""), nil, false, ""},
+		{"update", newPod("web", time.Now()), newPod("web", time.Now()), false, ""},
+	}
+
+	for i, tt := range Tests {
+		kbEvent, handled := c.rolloutEvent(Event{eventType: tt.eventType, namespace: "default", obj: tt.obj, oldObj: tt.oldObj})
+		if handled != tt.handled {
+			t.Fatalf("%d: expected handled %v, got %v", i, tt.handled, handled)
+		}
+		reason := ""
+		if kbEvent != nil {
+			reason = kbEvent.Reason
+			if kbEvent.Kind != "Rollout" || kbEvent.Name != "web" {
+				t.Errorf("%d: expected a Rollout event of web, got %+v", i, kbEvent)
+			}
+		}
+		if reason != tt.reason {
+			t.Errorf("%d: expected reason %q, got %q", i, tt.reason, reason)
+		}
+	}
+}
+
+func TestRolloutCorrelation(t *testing.T) {
+	c := &Controller{startTime: time.Now()}
+	correlation := func(obj, oldObj runtime.Object, eventType string) string {
+		kbEvent, _ := c.rolloutEvent(Event{eventType: eventType, namespace: "default", obj: obj, oldObj: oldObj})
+		if kbEvent == nil {
+			t.Fatalf("expected a rollout event for %s of %T", eventType, obj)
+		}
+		return kbEvent.CorrelationID
+	}
+
+	// rollout of revision 3, from a Deployment at revision 2
+	previous := newDeployment(1, "NewReplicaSetAvailable")
+	previous.Annotations[deploymentRevisionAnnotation] = "2"
+	started := correlation(newDeployment(2, "ReplicaSetUpdated"), previous, "update")
+	created := correlation(newReplicaSet(1, true), nil, "create")
+	scaled := correlation(newReplicaSet(3, true), newReplicaSet(1, true), "update")
+	complete := correlation(newDeployment(2, "NewReplicaSetAvailable"), newDeployment(2, "ReplicaSetUpdated"), "update")
+
+	if started == "" || started != created || created != scaled || scaled != complete {
+		t.Errorf("expected the events of a rollout to share a correlation ID, got %s, %s, %s, %s", started, created, scaled, complete)
+	}
+
+	old := newReplicaSet(0, true)
+	old.Annotations[deploymentRevisionAnnotation] = "2"
+	if other := correlation(old, nil, "delete"); other == created {
+		t.Errorf("expected the events of another revision to have another correlation ID")
+	}
+}
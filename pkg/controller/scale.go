@@ -0,0 +1,119 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// hpaManager is the field manager of HorizontalPodAutoscaler scale updates
+const hpaManager = "kube-controller-manager"
+
+// replicasPath is the diff path of the replica count of workloads
+const replicasPath = "spec.replicas"
+
+// workloadReplicas returns the desired replica count of Deployments, StatefulSets and
+// ReplicaSets, and false for other objects
+func workloadReplicas(obj runtime.Object) (int32, bool) {
+	var replicas *int32
+	switch workload := obj.(type) {
+	case *apps_v1.Deployment:
+		replicas = workload.Spec.Replicas
+	case *apps_v1.StatefulSet:
+		replicas = workload.Spec.Replicas
+	case *apps_v1.ReplicaSet:
+		replicas = workload.Spec.Replicas
+	default:
+		return 0, false
+	}
+	if replicas == nil {
+		return 1, true
+	}
+	return *replicas, true
+}
+
+// scaleChange returns the replica count change of a workload update, nil if the update
+// did not scale the workload. The field manager owning the replica count tells HPA
+// scaling from manual scaling.
+func scaleChange(old, new runtime.Object) *event.ScaleChange {
+	from, ok := workloadReplicas(old)
+	if !ok {
+		return nil
+	}
+	to, _ := workloadReplicas(new)
+	if from == to {
+		return nil
+	}
+
+	change := &event.ScaleChange{From: from, To: to}
+	if accessor, ok := new.(meta_v1.Object); ok {
+		if entry := specFieldManager(accessor.GetManagedFields(), "f:replicas"); entry != nil {
+			change.Manager = entry.Manager
+			change.Autoscaled = entry.Manager == hpaManager && entry.Subresource == "scale"
+		}
+	}
+	return change
+}
+
+// specFieldManager returns the managed fields entry owning the spec field, such as
+// f:replicas, nil if none does
+func specFieldManager(entries []meta_v1.ManagedFieldsEntry, field string) *meta_v1.ManagedFieldsEntry {
+	var owner *meta_v1.ManagedFieldsEntry
+	for i := range entries {
+		entry := &entries[i]
+		if !ownsSpecField(*entry, field) {
+			continue
+		}
+		// with client-side updates the latest writer takes ownership,
+		// prefer it if applied configurations still claim the field
+		if owner == nil || (entry.Time != nil && (owner.Time == nil || owner.Time.Before(entry.Time))) {
+			owner = entry
+		}
+	}
+	return owner
+}
+
+// ownsSpecField reports whether the managed fields entry owns the spec field
+func ownsSpecField(entry meta_v1.ManagedFieldsEntry, field string) bool {
+	if entry.FieldsV1 == nil {
+		return false
+	}
+	var fields struct {
+		Spec map[string]json.RawMessage `json:"f:spec"`
+	}
+	if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+		return false
+	}
+	_, ok := fields.Spec[field]
+	return ok
+}
+
+// onlyScaled reports whether the replica count is the only field changed by an update
+func onlyScaled(changes []diff.Change) bool {
+	for _, change := range changes {
+		if change.Path != replicasPath {
+			return false
+		}
+	}
+	return len(changes) > 0
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func scaledDeployment(generation int64, replicas int32, managers ...meta_v1.ManagedFieldsEntry) *apps_v1.Deployment {
+	d := newDeployment(generation, "")
+	d.Spec.Replicas = &replicas
+	d.ManagedFields = managers
+	return d
+}
+
+func replicasEntry(manager, subresource string, age time.Duration) meta_v1.ManagedFieldsEntry {
+	updated := meta_v1.NewTime(time.Now().Add(-age))
+	return meta_v1.ManagedFieldsEntry{
+		Manager:     manager,
+		Operation:   meta_v1.ManagedFieldsOperationUpdate,
+		Subresource: subresource,
+		Time:        &updated,
+		FieldsV1:    &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+	}
+}
+
+func TestScaleChange(t *testing.T) {
+	labelsEntry := meta_v1.ManagedFieldsEntry{
+		Manager:  "kubectl-client-side-apply",
+		FieldsV1: &meta_v1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{}}}`)},
+	}
+
+	var Tests = []struct {
+		old, new runtime.Object
+		change   *event.ScaleChange
+	}{
+		{scaledDeployment(1, 2), scaledDeployment(1, 2), nil},
+		{scaledDeployment(1, 2), scaledDeployment(2, 5, labelsEntry), &event.ScaleChange{From: 2, To: 5}},
+		{
+			scaledDeployment(1, 2),
+			scaledDeployment(2, 5, labelsEntry, replicasEntry(hpaManager, "scale", 0)),
+			&event.ScaleChange{From: 2, To: 5, Autoscaled: true, Manager: hpaManager},
+		},
+		{
+			scaledDeployment(1, 5),
+			scaledDeployment(2, 1, replicasEntry(hpaManager, "scale", time.Hour), replicasEntry("kubectl", "scale", 0)),
+			&event.ScaleChange{From: 5, To: 1, Manager: "kubectl"},
+		},
+		{newDeployment(1, ""), scaledDeployment(2, 3), &event.ScaleChange{From: 1, To: 3}},
+		{newReplicaSet(1, false), newReplicaSet(0, false), &event.ScaleChange{From: 1, To: 0}},
+		{&api_v1.Pod{}, &api_v1.Pod{}, nil},
+	}
+
+	for i, test := range Tests {
+		if change := scaleChange(test.old, test.new); !reflect.DeepEqual(change, test.change) {
+			t.Errorf("%d: scaleChange() = %+v, expected %+v", i, change, test.change)
+		}
+	}
+}
+
+func TestOnlyScaled(t *testing.T) {
+	var Tests = []struct {
+		changes []diff.Change
+		scaled  bool
+	}{
+		{nil, false},
+		{[]diff.Change{{Path: "spec.replicas", Old: 1, New: 3}}, true},
+		{[]diff.Change{{Path: "spec.replicas", Old: 1, New: 3}, {Path: "spec.template.spec.containers[0].image", Old: "a", New: "b"}}, false},
+	}
+
+	for _, test := range Tests {
+		if scaled := onlyScaled(test.changes); scaled != test.scaled {
+			t.Errorf("onlyScaled(%v) = %v, expected %v", test.changes, scaled, test.scaled)
+		}
+	}
+}
+
+func TestScaleChangeString(t *testing.T) {
+	var Tests = []struct {
+		change   event.ScaleChange
+		expected string
+	}{
+		{event.ScaleChange{From: 2, To: 5, Autoscaled: true, Manager: hpaManager}, "scaled from 2 to 5 by the HorizontalPodAutoscaler"},
+		{event.ScaleChange{From: 5, To: 1, Manager: "kubectl"}, "scaled from 5 to 1 manually (kubectl)"},
+		{event.ScaleChange{From: 1, To: 3}, "scaled from 1 to 3"},
+	}
+
+	for _, test := range Tests {
+		if s := test.change.String(); s != test.expected {
+			t.Errorf("String() = %q, expected %q", s, test.expected)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// startingReasons are the reasons containers wait for before their first start, while
+// their image is pulled or the pod sandbox and network are set up
+var startingReasons = map[string]bool{
+	"ContainerCreating": true,
+	"PodInitializing":   true,
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+}
+
+// slowStarts reports the pods whose containers take longer than a threshold to start for
+// the first time, from the timestamps of their status: the time a container started, or
+// has been waiting to start, since the pod was scheduled or initialized. Each pod is
+// reported once.
+type slowStarts struct {
+	threshold time.Duration
+	now       func() time.Time
+
+	mu sync.Mutex
+	// notified holds the pods already reported
+	notified map[types.UID]bool
+}
+
+func newSlowStarts(threshold time.Duration) *slowStarts {
+	return &slowStarts{threshold: threshold, now: time.Now, notified: map[types.UID]bool{}}
+}
+
+// slowStartEvent returns the SlowStart event about a pod update showing a container took,
+// or has been taking, longer than the threshold to start, nil for other events
+func (c *Controller) slowStartEvent(e Event) *event.Event {
+	pod, ok := e.obj.(*api_v1.Pod)
+	if !ok {
+		return nil
+	}
+	s := c.slowStarts
+	if e.eventType == "delete" {
+		s.mu.Lock()
+		delete(s.notified, pod.UID)
+		s.mu.Unlock()
+		return nil
+	}
+	oldPod, ok := e.oldObj.(*api_v1.Pod)
+	if e.eventType != "update" || !ok {
+		return nil
+	}
+
+	reason := s.check(oldPod, pod)
+	if reason == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.notified[pod.UID] {
+		return nil
+	}
+	s.notified[pod.UID] = true
+	return &event.Event{
+		Name:       e.key,
+		Namespace:  e.namespace,
+		Kind:       "SlowStart",
+		APIVersion: e.apiVersion,
+		Status:     "Warning",
+		Reason:     reason,
+		Obj:        e.obj,
+	}
+}
+
+// check returns why pod is slow to start, updated from oldPod, empty when its containers
+// started in time. Only the containers starting with the update are measured, not those
+// started before.
+func (s *slowStarts) check(oldPod, pod *api_v1.Pod) string {
+	scheduled, ok := podConditionTime(pod, api_v1.PodScheduled)
+	if !ok {
+		return ""
+	}
+	if reason := s.checkContainers(pod, oldPod.Status.InitContainerStatuses, pod.Status.InitContainerStatuses, scheduled); reason != "" {
+		return reason
+	}
+	// containers start once init containers completed
+	since := scheduled
+	if len(pod.Spec.InitContainers) > 0 {
+		if since, ok = podConditionTime(pod, api_v1.PodInitialized); !ok {
+			return ""
+		}
+	}
+	return s.checkContainers(pod, oldPod.Status.ContainerStatuses, pod.Status.ContainerStatuses, since)
+}
+
+// checkContainers returns why a container of statuses, updated from oldStatuses, took
+// longer than the threshold to start since a time, empty when none did
+func (s *slowStarts) checkContainers(pod *api_v1.Pod, oldStatuses, statuses []api_v1.ContainerStatus, since time.Time) string {
+	node := ""
+	if pod.Spec.NodeName != "" {
+		node = " on node " + pod.Spec.NodeName
+	}
+	for _, status := range statuses {
+		if status.RestartCount > 0 {
+			continue
+		}
+		switch {
+		case status.State.Running != nil:
+			if containerStarted(oldStatuses, status.Name) {
+				continue
+			}
+			if took := status.State.Running.StartedAt.Sub(since); took > s.threshold {
+				return fmt.Sprintf("Container %s of pod %s took %s to start%s (image %s)",
+					status.Name, pod.Name, duration.HumanDuration(took), node, status.Image)
+			}
+		case status.State.Waiting != nil && startingReasons[status.State.Waiting.Reason]:
+			if waiting := s.now().Sub(since); waiting > s.threshold {
+				return fmt.Sprintf("Container %s of pod %s has been waiting in %s for %s%s (image %s)",
+					status.Name, pod.Name, status.State.Waiting.Reason, duration.HumanDuration(waiting), node, status.Image)
+			}
+		}
+	}
+	return ""
+}
+
+// containerStarted returns whether the container called name of statuses is running or
+// ran already
+func containerStarted(statuses []api_v1.ContainerStatus, name string) bool {
+	for _, status := range statuses {
+		if status.Name == name {
+			return status.State.Running != nil || status.State.Terminated != nil
+		}
+	}
+	return false
+}
+
+// podConditionTime returns when the condition of pod last turned True
+func podConditionTime(pod *api_v1.Pod, conditionType api_v1.PodConditionType) (time.Time, bool) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == api_v1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
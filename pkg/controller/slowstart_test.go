@@ -0,0 +1,94 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSlowStarts(t *testing.T) {
+	scheduled := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pod := func(initialized time.Time, statuses ...api_v1.ContainerStatus) *api_v1.Pod {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api-7d9f", Namespace: "payments", UID: "pod-uid"}}
+		p.Spec.NodeName = "node-3"
+		p.Status.Conditions = []api_v1.PodCondition{{Type: api_v1.PodScheduled, Status: api_v1.ConditionTrue, LastTransitionTime: meta_v1.NewTime(scheduled)}}
+		if !initialized.IsZero() {
+			p.Spec.InitContainers = []api_v1.Container{{Name: "migrate"}}
+			p.Status.Conditions = append(p.Status.Conditions, api_v1.PodCondition{Type: api_v1.PodInitialized, Status: api_v1.ConditionTrue, LastTransitionTime: meta_v1.NewTime(initialized)})
+		}
+		p.Status.ContainerStatuses = statuses
+		return p
+	}
+	waiting := func(reason string) api_v1.ContainerStatus {
+		return api_v1.ContainerStatus{Name: "api", Image: "registry.example.com/api:v2",
+			State: api_v1.ContainerState{Waiting: &api_v1.ContainerStateWaiting{Reason: reason}}}
+	}
+	running := func(after time.Duration, restarts int32) api_v1.ContainerStatus {
+		return api_v1.ContainerStatus{Name: "api", Image: "registry.example.com/api:v2", RestartCount: restarts,
+			State: api_v1.ContainerState{Running: &api_v1.ContainerStateRunning{StartedAt: meta_v1.NewTime(scheduled.Add(after))}}}
+	}
+
+	var Tests = []struct {
+		name     string
+		old, new *api_v1.Pod
+		expected string
+	}{
+		{"started late", pod(time.Time{}, waiting("ContainerCreating")), pod(time.Time{}, running(7*time.Minute, 0)),
+			"Container api of pod api-7d9f took 7m to start on node node-3 (image registry.example.com/api:v2)"},
+		{"started in time", pod(time.Time{}, waiting("ContainerCreating")), pod(time.Time{}, running(time.Minute, 0)), ""},
+		{"started after init containers", pod(scheduled.Add(6*time.Minute), waiting("PodInitializing")), pod(scheduled.Add(6*time.Minute), running(7*time.Minute, 0)), ""},
+		{"started before", pod(time.Time{}, running(7*time.Minute, 0)), pod(time.Time{}, running(7*time.Minute, 0)), ""},
+		{"restarted", pod(time.Time{}, waiting("CrashLoopBackOff")), pod(time.Time{}, running(time.Hour, 3)), ""},
+		{"pulling for long", pod(time.Time{}, waiting("ErrImagePull")), pod(time.Time{}, waiting("ImagePullBackOff")),
+			"Container api of pod api-7d9f has been waiting in ImagePullBackOff for 10m on node node-3 (image registry.example.com/api:v2)"},
+		{"crashing", pod(time.Time{}, running(time.Minute, 0)), pod(time.Time{}, waiting("CrashLoopBackOff")), ""},
+	}
+
+	for _, tt := range Tests {
+		s := newSlowStarts(5 * time.Minute)
+		s.now = func() time.Time { return scheduled.Add(10 * time.Minute) }
+		if reason := s.check(tt.old, tt.new); reason != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, reason)
+		}
+	}
+}
+
+func TestSlowStartEvent(t *testing.T) {
+	c := &Controller{slowStarts: newSlowStarts(5 * time.Minute)}
+	pod := func(waiting string) *api_v1.Pod {
+		p := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api-7d9f", Namespace: "payments", UID: "pod-uid"}}
+		p.Status.Conditions = []api_v1.PodCondition{{Type: api_v1.PodScheduled, Status: api_v1.ConditionTrue, LastTransitionTime: meta_v1.NewTime(time.Now().Add(-time.Hour))}}
+		p.Status.ContainerStatuses = []api_v1.ContainerStatus{{Name: "api", State: api_v1.ContainerState{Waiting: &api_v1.ContainerStateWaiting{Reason: waiting}}}}
+		return p
+	}
+	update := Event{key: "api-7d9f", namespace: "payments", eventType: "update", apiVersion: V1, oldObj: pod("ErrImagePull"), obj: pod("ImagePullBackOff")}
+
+	if e := c.slowStartEvent(update); e == nil || e.Kind != "SlowStart" || e.Status != "Warning" || e.Name != "api-7d9f" {
+		t.Fatalf("expected a SlowStart event, got %+v", e)
+	}
+	if e := c.slowStartEvent(update); e != nil {
+		t.Errorf("expected the pod to be reported once, got %+v", e)
+	}
+	c.slowStartEvent(Event{key: "api-7d9f", namespace: "payments", eventType: "delete", obj: pod("ImagePullBackOff")})
+	if len(c.slowStarts.notified) != 0 {
+		t.Errorf("expected deleted pods to be forgotten, got %v", c.slowStarts.notified)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+)
+
+// cacheMetricsInterval is the interval between updates of the informer cache metrics
+const cacheMetricsInterval = 30 * time.Second
+
+// watchedSpecFields are the spec fields whose ownership is kept in trimmed managed fields,
+// for events to tell who changed them: f:replicas for scale events and f:suspend for CronJob
+// suspensions
+var watchedSpecFields = []string{"f:replicas", "f:suspend"}
+
+// cacheTransform returns the transform stripping the fields configured in conf from
+// objects before they are cached, nil when none is configured
+func cacheTransform(conf config.Cache) cache.TransformFunc {
+	if !conf.TrimManagedFields && !conf.StripLastApplied && !conf.StripPodEnv {
+		return nil
+	}
+	return func(obj interface{}) (interface{}, error) {
+		if accessor, err := meta.Accessor(obj); err == nil {
+			if conf.TrimManagedFields {
+				accessor.SetManagedFields(trimManagedFields(accessor.GetManagedFields()))
+			}
+			if annotations := accessor.GetAnnotations(); conf.StripLastApplied && annotations[lastAppliedAnnotation] != "" {
+				stripped := make(map[string]string, len(annotations)-1)
+				for key, value := range annotations {
+					if key != lastAppliedAnnotation {
+						stripped[key] = value
+					}
+				}
+				accessor.SetAnnotations(stripped)
+			}
+		}
+		if pod, ok := obj.(*api_v1.Pod); ok && conf.StripPodEnv {
+			stripEnv(pod.Spec.InitContainers)
+			stripEnv(pod.Spec.Containers)
+			for i := range pod.Spec.EphemeralContainers {
+				pod.Spec.EphemeralContainers[i].Env = nil
+				pod.Spec.EphemeralContainers[i].EnvFrom = nil
+			}
+		}
+		return obj, nil
+	}
+}
+
+// chainTransforms returns the transform applying each of transforms in turn, skipping nil ones
+func chainTransforms(transforms ...cache.TransformFunc) cache.TransformFunc {
+	return func(obj interface{}) (interface{}, error) {
+		for _, transform := range transforms {
+			if transform == nil {
+				continue
+			}
+			var err error
+			if obj, err = transform(obj); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+}
+
+// trimManagedFields drops the field sets of managed fields entries, the bulk of their size,
+// keeping their manager and time. Ownership of watchedSpecFields is kept.
+func trimManagedFields(entries []meta_v1.ManagedFieldsEntry) []meta_v1.ManagedFieldsEntry {
+	if len(entries) == 0 {
+		return entries
+	}
+	trimmed := make([]meta_v1.ManagedFieldsEntry, len(entries))
+	for i, entry := range entries {
+		owned := map[string]struct{}{}
+		for _, field := range watchedSpecFields {
+			if ownsSpecField(entry, field) {
+				owned[field] = struct{}{}
+			}
+		}
+		entry.FieldsV1 = nil
+		if len(owned) > 0 {
+			if raw, err := json.Marshal(map[string]interface{}{"f:spec": owned}); err == nil {
+				entry.FieldsV1 = &meta_v1.FieldsV1{Raw: raw}
+			}
+		}
+		trimmed[i] = entry
+	}
+	return trimmed
+}
+
+func stripEnv(containers []api_v1.Container) {
+	for i := range containers {
+		containers[i].Env = nil
+		containers[i].EnvFrom = nil
+	}
+}
+
+// reportCacheSizes updates the informer cache metrics from CacheSizes until stopCh is closed
+func reportCacheSizes(stopCh <-chan struct{}) {
+	type key struct{ cluster, kind string }
+	reported := map[key]bool{}
+	wait.Until(func() {
+		objects := map[key]int{}
+		for _, size := range CacheSizes() {
+			objects[key{size.Cluster, size.Kind}] += size.Objects
+		}
+		for k, n := range objects {
+			metrics.InformerCacheObjects.WithLabelValues(k.cluster, k.kind).Set(float64(n))
+			reported[k] = true
+		}
+		for k := range reported {
+			if _, ok := objects[k]; !ok {
+				metrics.InformerCacheObjects.DeleteLabelValues(k.cluster, k.kind)
+				delete(reported, k)
+			}
+		}
+	}, cacheMetricsInterval, stopCh)
+}
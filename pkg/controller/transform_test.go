@@ -0,0 +1,125 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCacheTransform(t *testing.T) {
+	if cacheTransform(config.Cache{}) != nil {
+		t.Error("cacheTransform() returned a transform without any option set")
+	}
+
+	now := meta_v1.Now()
+	managedFields := []meta_v1.ManagedFieldsEntry{
+		{Manager: "kubectl", Operation: meta_v1.ManagedFieldsOperationApply, Time: &now, FieldsV1: &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:containers":{}}}`)}},
+		{Manager: hpaManager, Operation: meta_v1.ManagedFieldsOperationUpdate, Subresource: "scale", FieldsV1: &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}},
+	}
+	pod := &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:          "web",
+			Annotations:   map[string]string{lastAppliedAnnotation: `{"kind":"Pod"}`, "team": "payments"},
+			ManagedFields: managedFields,
+		},
+		Spec: api_v1.PodSpec{
+			InitContainers: []api_v1.Container{{Name: "init", Env: []api_v1.EnvVar{{Name: "A", Value: "1"}}}},
+			Containers:     []api_v1.Container{{Name: "app", Image: "web:1", Env: []api_v1.EnvVar{{Name: "B", Value: "2"}}, EnvFrom: []api_v1.EnvFromSource{{Prefix: "C"}}}},
+		},
+	}
+
+	transform := cacheTransform(config.Cache{TrimManagedFields: true, StripLastApplied: true, StripPodEnv: true})
+	obj, err := transform(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed := obj.(*api_v1.Pod)
+	if _, ok := transformed.Annotations[lastAppliedAnnotation]; ok || transformed.Annotations["team"] != "payments" {
+		t.Errorf("annotations = %v, expected only the team annotation", transformed.Annotations)
+	}
+	if transformed.Spec.InitContainers[0].Env != nil || transformed.Spec.Containers[0].Env != nil || transformed.Spec.Containers[0].EnvFrom != nil {
+		t.Errorf("containers = %+v, expected no env", transformed.Spec.Containers)
+	}
+	if transformed.Spec.Containers[0].Image != "web:1" {
+		t.Errorf("image = %q, expected it to be kept", transformed.Spec.Containers[0].Image)
+	}
+	fields := transformed.ManagedFields
+	if len(fields) != 2 || fields[0].Manager != "kubectl" || fields[0].Time == nil || fields[0].FieldsV1 != nil {
+		t.Errorf("managed fields = %+v, expected the kubectl entry without field set", fields)
+	}
+	if entry := specFieldManager(fields, "f:replicas"); entry == nil || entry.Manager != hpaManager {
+		t.Errorf("specFieldManager() = %+v, expected the %s entry to keep owning spec.replicas", entry, hpaManager)
+	}
+	if managedFields[0].FieldsV1 == nil {
+		t.Error("cacheTransform() modified the managed fields slice in place")
+	}
+
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":        "foo",
+			"annotations": map[string]interface{}{lastAppliedAnnotation: "{}"},
+		},
+	}}
+	u.SetManagedFields(managedFields)
+	if _, err := transform(u); err != nil {
+		t.Fatal(err)
+	}
+	if len(u.GetAnnotations()) != 0 || u.GetManagedFields()[0].FieldsV1 != nil {
+		t.Errorf("unstructured object = %v, expected its last-applied annotation and field sets dropped", u.Object)
+	}
+
+	// tombstones are left as they are
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/web", Obj: pod}
+	if obj, err := transform(tombstone); err != nil || obj != tombstone {
+		t.Errorf("transform(tombstone) = %v, %v", obj, err)
+	}
+}
+
+func TestTrimmedManagedFieldsTellSuspensions(t *testing.T) {
+	yes := true
+	old := &batch_v1.CronJob{ObjectMeta: meta_v1.ObjectMeta{Name: "nightly", Namespace: "batch"}}
+	cronJob := old.DeepCopy()
+	cronJob.Spec.Suspend = &yes
+	cronJob.ManagedFields = []meta_v1.ManagedFieldsEntry{
+		{Manager: "argocd-controller", Operation: meta_v1.ManagedFieldsOperationApply, FieldsV1: &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:jobTemplate":{},"f:schedule":{}}}`)}},
+		{Manager: "kubectl-patch", Operation: meta_v1.ManagedFieldsOperationUpdate, FieldsV1: &meta_v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:suspend":{}}}`)}},
+	}
+
+	transform := cacheTransform(config.Cache{TrimManagedFields: true})
+	obj, err := transform(cronJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := obj.(*batch_v1.CronJob).ManagedFields; fields[0].FieldsV1 != nil {
+		t.Errorf("managed fields = %+v, expected the argocd-controller entry without field set", fields)
+	}
+
+	e := cronJobSuspendEvent(Event{eventType: "update", key: "nightly", namespace: "batch", oldObj: old, obj: obj.(*batch_v1.CronJob)})
+	expected := "CronJob nightly suspended by an update from kubectl-patch, scheduled runs are skipped until it is resumed"
+	if e == nil || e.Reason != expected {
+		t.Errorf("cronJobSuspendEvent() = %+v, expected reason %q", e, expected)
+	}
+}
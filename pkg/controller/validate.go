@@ -0,0 +1,104 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ValidateKinds checks the kinds configuration is keyed by (metadataonly, resyncperiods,
+// queue.concurrency) are built-in kinds or custom resources kubewatch can watch
+func ValidateKinds(conf *config.Config) []error {
+	known := map[string]bool{}
+	for _, r := range resources {
+		known[strings.ToLower(r.name)] = true
+	}
+	for _, crd := range conf.CustomResources {
+		known[strings.ToLower(crd.Resource)] = true
+	}
+
+	var errs []error
+	check := func(path, kind string) {
+		if !known[strings.ToLower(kind)] {
+			errs = append(errs, fmt.Errorf("%s: unknown kind %q", path, kind))
+		}
+	}
+	for i, kind := range conf.MetadataOnly {
+		check(fmt.Sprintf("metadataonly[%d]", i), kind)
+	}
+	for _, kind := range sortedKeys(conf.ResyncPeriods) {
+		check("resyncperiods", kind)
+	}
+	for _, kind := range sortedKeys(conf.Queue.Concurrency) {
+		check("queue.concurrency", kind)
+	}
+	return errs
+}
+
+// ValidateResources checks the built-in and custom resources enabled in conf are served
+// by the cluster, using the discovery API
+func ValidateResources(conf *config.Config, cluster Cluster) []error {
+	served := map[string]map[string]bool{}
+	var errs []error
+	isServed := func(gv schema.GroupVersion, resource string) (bool, error) {
+		if _, ok := served[gv.String()]; !ok {
+			list, err := cluster.KubeClient.Discovery().ServerResourcesForGroupVersion(gv.String())
+			if err != nil {
+				return false, err
+			}
+			served[gv.String()] = map[string]bool{}
+			for _, r := range list.APIResources {
+				served[gv.String()][r.Name] = true
+			}
+		}
+		return served[gv.String()][resource], nil
+	}
+	report := func(path string, gv schema.GroupVersion, resource string) {
+		ok, err := isServed(gv, resource)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: cannot discover %s in cluster %q: %v", path, gv, cluster.Name, err))
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: %s (%s) is not served by cluster %q", path, resource, gv, cluster.Name))
+		}
+	}
+
+	for _, r := range resources {
+		if r.enabled(conf.Resource) {
+			gvr := r.groupVersionResource()
+			report("resource", gvr.GroupVersion(), gvr.Resource)
+		}
+	}
+	for i, crd := range conf.CustomResources {
+		report(fmt.Sprintf("customresources[%d]", i), schema.GroupVersion{Group: crd.Group, Version: crd.Version}, crd.Resource)
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
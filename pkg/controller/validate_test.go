@@ -0,0 +1,69 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateKinds(t *testing.T) {
+	conf := &config.Config{
+		CustomResources: []config.CRD{{Group: "example.com", Version: "v1", Resource: "widgets"}},
+		MetadataOnly:    []string{"Secret", "pods"},
+		ResyncPeriods:   map[string]time.Duration{"widgets": time.Hour, "Widget": time.Hour},
+		Queue:           config.Queue{Concurrency: map[string]int{"Pod": 4}},
+	}
+
+	expected := []string{`metadataonly[1]: unknown kind "pods"`, `resyncperiods: unknown kind "Widget"`}
+	if errs := fmt.Sprint(ValidateKinds(conf)); errs != fmt.Sprint(expected) {
+		t.Errorf("ValidateKinds() = %v, expected %v", errs, expected)
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Resources = []*meta_v1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []meta_v1.APIResource{{Name: "pods"}}},
+		{GroupVersion: "example.com/v1", APIResources: []meta_v1.APIResource{{Name: "widgets"}}},
+	}
+	conf := &config.Config{
+		Resource: config.Resource{Pod: true, Secret: true},
+		CustomResources: []config.CRD{
+			{Group: "example.com", Version: "v1", Resource: "widgets"},
+			{Group: "example.com", Version: "v1", Resource: "gadgets"},
+		},
+	}
+
+	var errs []string
+	for _, err := range ValidateResources(conf, Cluster{Name: "prod", KubeClient: client}) {
+		errs = append(errs, err.Error())
+	}
+	expected := []string{
+		`resource: secrets (v1) is not served by cluster "prod"`,
+		`customresources[1]: gadgets (example.com/v1) is not served by cluster "prod"`,
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("ValidateResources() = %q, expected %q", errs, expected)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// volumeFailureReasons are the reasons of the Kubernetes Events of volume failures
+var volumeFailureReasons = map[string]bool{
+	"FailedMount":        true,
+	"FailedAttachVolume": true,
+}
+
+var (
+	// failedVolume matches the volume of messages such as
+	// MountVolume.SetUp failed for volume "data" : ...
+	failedVolume = regexp.MustCompile(`for volume "([^"]+)"`)
+	// unmountedVolumes matches the volumes of messages such as
+	// Unable to attach or mount volumes: unmounted volumes=[data cache], ...
+	unmountedVolumes = regexp.MustCompile(`unmounted volumes=\[([^\]]*)\]`)
+)
+
+// volumeFailures reports the pods whose volumes keep failing to attach or mount for longer
+// than a threshold, from the FailedMount and FailedAttachVolume Kubernetes Events. Each pod
+// and volume is reported once, until the Kubernetes Events about it expire.
+type volumeFailures struct {
+	w         *clusterWatcher
+	threshold time.Duration
+
+	mu sync.Mutex
+	// events holds the UIDs of the Kubernetes Events about the failures of each pod volume
+	events map[string]map[types.UID]bool
+	// notified holds the pod volumes already reported
+	notified map[string]bool
+}
+
+func newVolumeFailures(w *clusterWatcher, threshold time.Duration) *volumeFailures {
+	return &volumeFailures{
+		w:         w,
+		threshold: threshold,
+		events:    map[string]map[types.UID]bool{},
+		notified:  map[string]bool{},
+	}
+}
+
+// watch watches the warnings about the pods of namespace until stopCh is closed
+func (v *volumeFailures) watch(namespace string, stopCh <-chan struct{}) {
+	kubeClient := v.w.kubeClient
+	selector := "involvedObject.kind=Pod,type=" + api_v1.EventTypeWarning
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = selector
+				return kubeClient.CoreV1().Events(namespace).List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = selector
+				return kubeClient.CoreV1().Events(namespace).Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Event{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			v.observe(obj)
+		},
+		UpdateFunc: func(old, new interface{}) {
+			v.observe(new)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			v.forget(obj)
+		},
+	})
+	informer.Run(stopCh)
+}
+
+// observe records a Kubernetes Event about a volume failure and reports the pod volumes
+// failing for longer than the threshold
+func (v *volumeFailures) observe(obj interface{}) {
+	ev, ok := obj.(*api_v1.Event)
+	if !ok || !volumeFailureReasons[ev.Reason] {
+		return
+	}
+	failing := lastSeen(ev).Sub(firstSeen(ev))
+
+	var events []event.Event
+	v.mu.Lock()
+	for _, volume := range failedVolumes(ev.Message) {
+		key := volumeFailureKey(ev, volume)
+		if v.events[key] == nil {
+			v.events[key] = map[types.UID]bool{}
+		}
+		v.events[key][ev.UID] = true
+		if failing < v.threshold || v.notified[key] {
+			continue
+		}
+		v.notified[key] = true
+		events = append(events, volumeFailureEvent(ev, volume, failing))
+	}
+	v.mu.Unlock()
+
+	for _, e := range events {
+		v.w.notify(e)
+	}
+}
+
+// forget drops a deleted Kubernetes Event, so that pod volumes failing again once all the
+// Kubernetes Events about them expired are reported again
+func (v *volumeFailures) forget(obj interface{}) {
+	ev, ok := obj.(*api_v1.Event)
+	if !ok || !volumeFailureReasons[ev.Reason] {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, volume := range failedVolumes(ev.Message) {
+		key := volumeFailureKey(ev, volume)
+		delete(v.events[key], ev.UID)
+		if len(v.events[key]) == 0 {
+			delete(v.events, key)
+			delete(v.notified, key)
+		}
+	}
+}
+
+// volumeFailureEvent returns the event reporting the failures of volume of the pod ev is about
+func volumeFailureEvent(ev *api_v1.Event, volume string, failing time.Duration) event.Event {
+	verb := "mount"
+	if ev.Reason == "FailedAttachVolume" {
+		verb = "attach"
+	}
+	return event.Event{
+		Name:       ev.InvolvedObject.Name,
+		Namespace:  ev.InvolvedObject.Namespace,
+		Kind:       ev.Reason,
+		APIVersion: V1,
+		Status:     "Danger",
+		Reason: fmt.Sprintf("Volume %s of pod %s has been failing to %s for %s (%d times):\n%s",
+			volume, ev.InvolvedObject.Name, verb, duration.HumanDuration(failing), max(ev.Count, 1), ev.Message),
+		Obj: ev,
+	}
+}
+
+// failedVolumes returns the volumes a volume failure message is about, a single empty name
+// when the message does not tell
+func failedVolumes(message string) []string {
+	if match := failedVolume.FindStringSubmatch(message); match != nil {
+		return []string{match[1]}
+	}
+	if match := unmountedVolumes.FindStringSubmatch(message); match != nil {
+		if volumes := strings.Fields(match[1]); len(volumes) > 0 {
+			return volumes
+		}
+	}
+	return []string{""}
+}
+
+// volumeFailureKey identifies a volume of the pod ev is about
+func volumeFailureKey(ev *api_v1.Event, volume string) string {
+	return string(ev.InvolvedObject.UID) + "/" + ev.InvolvedObject.Namespace + "/" + ev.InvolvedObject.Name + "/" + volume
+}
+
+// firstSeen returns when the occurrences of ev started
+func firstSeen(ev *api_v1.Event) time.Time {
+	if !ev.FirstTimestamp.IsZero() {
+		return ev.FirstTimestamp.Time
+	}
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+// lastSeen returns the last occurrence of ev
+func lastSeen(ev *api_v1.Event) time.Time {
+	if ev.Series != nil && !ev.Series.LastObservedTime.IsZero() {
+		return ev.Series.LastObservedTime.Time
+	}
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	return firstSeen(ev)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestFailedVolumes(t *testing.T) {
+	var Tests = []struct {
+		message string
+		volumes []string
+	}{
+		{`MountVolume.SetUp failed for volume "config" : configmap "api-config" not found`, []string{"config"}},
+		{`Multi-Attach error for volume "pvc-0b3c" Volume is already exclusively attached to one node and can't be attached to another`, []string{"pvc-0b3c"}},
+		{`Unable to attach or mount volumes: unmounted volumes=[data cache], unattached volumes=[data cache kube-api-access-x2k]: timed out waiting for the condition`, []string{"data", "cache"}},
+		{`timed out waiting for the condition`, []string{""}},
+	}
+
+	for _, test := range Tests {
+		if volumes := failedVolumes(test.message); !reflect.DeepEqual(volumes, test.volumes) {
+			t.Errorf("failedVolumes(%q) = %q, expected %q", test.message, volumes, test.volumes)
+		}
+	}
+}
+
+func TestVolumeFailures(t *testing.T) {
+	r := &recorder{}
+	w := newClusterWatcher(&config.Config{}, r, Cluster{}, nil, nil)
+	v := newVolumeFailures(w, 5*time.Minute)
+
+	start := time.Now().Add(-time.Hour)
+	failedMount := func(uid string, failing time.Duration, count int32) *api_v1.Event {
+		return &api_v1.Event{
+			ObjectMeta:     meta_v1.ObjectMeta{Name: "api-7d9f." + uid, Namespace: "payments", UID: types.UID(uid)},
+			InvolvedObject: api_v1.ObjectReference{Kind: "Pod", Namespace: "payments", Name: "api-7d9f", UID: "pod-uid"},
+			Reason:         "FailedMount",
+			Message:        `MountVolume.SetUp failed for volume "config" : configmap "api-config" not found`,
+			Type:           api_v1.EventTypeWarning,
+			FirstTimestamp: meta_v1.NewTime(start),
+			LastTimestamp:  meta_v1.NewTime(start.Add(failing)),
+			Count:          count,
+		}
+	}
+
+	v.observe(failedMount("a", 2*time.Minute, 3))
+	if len(r.events) != 0 {
+		t.Fatalf("expected no event before the threshold, got %+v", r.events)
+	}
+	v.observe(failedMount("a", 6*time.Minute, 5))
+	v.observe(failedMount("a", 8*time.Minute, 6))
+	expected := []string{"Volume config of pod api-7d9f has been failing to mount for 6m (5 times):\n" +
+		`MountVolume.SetUp failed for volume "config" : configmap "api-config" not found api-7d9f`}
+	if names := r.names(); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %q, got %q", expected, names)
+	}
+	if e := r.events[0]; e.Kind != "FailedMount" || e.Namespace != "payments" || e.Status != "Danger" {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	// failures reported again once the Kubernetes Events about them expired
+	v.forget(failedMount("a", 8*time.Minute, 6))
+	v.observe(failedMount("b", 10*time.Minute, 7))
+	if len(r.events) != 2 {
+		t.Errorf("expected the failure to be reported again, got %d events", len(r.events))
+	}
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	batch_v1 "k8s.io/api/batch/v1"
+	api_v1 "k8s.io/api/core/v1"
+	resource_api "k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// vpaCRD is the VerticalPodAutoscaler resource watched when VPA drift reports are enabled
+var vpaCRD = config.CRD{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}
+
+// vpaResources are the container resources whose recommendations are compared to requests
+var vpaResources = []api_v1.ResourceName{api_v1.ResourceCPU, api_v1.ResourceMemory}
+
+func isVPACRD(crd config.CRD) bool {
+	return crd.Group == vpaCRD.Group && crd.Resource == vpaCRD.Resource
+}
+
+// withVPACRD returns crds with the VerticalPodAutoscaler resource appended unless it is
+// already configured
+func withVPACRD(crds []config.CRD) []config.CRD {
+	for _, crd := range crds {
+		if isVPACRD(crd) {
+			return crds
+		}
+	}
+	return append(crds, vpaCRD)
+}
+
+// vpaDrift reports the VerticalPodAutoscalers whose target recommendations differ from the
+// requests of the containers of their workload by more than a threshold, as a right-sizing
+// nudge. A drift is reported again only when the drifting containers or resources change.
+type vpaDrift struct {
+	kubeClient kubernetes.Interface
+	objects    *objectCache
+	// threshold is the difference, in percent of the requests, beyond which a
+	// recommendation drifts
+	threshold int
+
+	mu sync.Mutex
+	// notified holds the drift last reported for each VerticalPodAutoscaler
+	notified map[types.UID]string
+}
+
+func newVPADrift(kubeClient kubernetes.Interface, objects *objectCache, threshold int) *vpaDrift {
+	return &vpaDrift{kubeClient: kubeClient, objects: objects, threshold: threshold, notified: map[types.UID]string{}}
+}
+
+// vpaDriftEvent returns the VPADrift event about a VerticalPodAutoscaler whose
+// recommendations started drifting from the requests, nil otherwise
+func (c *Controller) vpaDriftEvent(e Event) *event.Event {
+	obj, ok := e.obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	d := c.vpaDrift
+	if e.eventType == "delete" {
+		d.mu.Lock()
+		delete(d.notified, obj.GetUID())
+		d.mu.Unlock()
+		return nil
+	}
+
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "name")
+	recommendations := vpaRecommendations(obj)
+	if kind == "" || name == "" || len(recommendations) == 0 {
+		return nil
+	}
+	spec := d.podSpec(kind, obj.GetNamespace(), name)
+	if spec == nil {
+		return nil
+	}
+	drifts, drifting := d.drifts(spec, recommendations)
+
+	key := strings.Join(drifting, ",")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.notified[obj.GetUID()] == key {
+		return nil
+	}
+	d.notified[obj.GetUID()] = key
+	if len(drifts) == 0 {
+		return nil
+	}
+	reason := fmt.Sprintf("Recommendations for %s/%s differ from the requests by more than %d%%:\n%s",
+		kind, name, d.threshold, strings.Join(drifts, "\n"))
+	return &event.Event{
+		Name:       e.key,
+		Namespace:  e.namespace,
+		Kind:       "VPADrift",
+		APIVersion: e.apiVersion,
+		Status:     "Normal",
+		Reason:     reason,
+		Obj:        e.obj,
+	}
+}
+
+// drifts describes the containers of spec whose requests differ from the recommendations
+// by more than the threshold, such as "api: cpu 500m → 120m (-76%)", along with the
+// drifting resources, such as "api/cpu"
+func (d *vpaDrift) drifts(spec *api_v1.PodSpec, recommendations map[string]api_v1.ResourceList) ([]string, []string) {
+	var drifts, drifting []string
+	for _, container := range spec.Containers {
+		recommended, ok := recommendations[container.Name]
+		if !ok {
+			continue
+		}
+		var changes []string
+		for _, name := range vpaResources {
+			request, ok := container.Resources.Requests[name]
+			target, found := recommended[name]
+			if !ok || !found || request.IsZero() {
+				continue
+			}
+			percent := (target.AsApproximateFloat64() - request.AsApproximateFloat64()) / request.AsApproximateFloat64() * 100
+			if math.Abs(percent) > float64(d.threshold) {
+				changes = append(changes, fmt.Sprintf("%s %s → %s (%+.0f%%)", name, request.String(), roundQuantity(name, target), percent))
+				drifting = append(drifting, container.Name+"/"+string(name))
+			}
+		}
+		if len(changes) > 0 {
+			drifts = append(drifts, container.Name+": "+strings.Join(changes, ", "))
+		}
+	}
+	return drifts, drifting
+}
+
+// podSpec returns the pod template of the workload a VerticalPodAutoscaler targets, from
+// the caches of the watched resources or from the API server
+func (d *vpaDrift) podSpec(kind, namespace, name string) *api_v1.PodSpec {
+	var obj runtime.Object
+	switch kind {
+	case "Deployment":
+		obj = d.objects.getObject(objName(apps_v1.Deployment{}), namespace, name)
+	case "StatefulSet":
+		obj = d.objects.getObject(objName(apps_v1.StatefulSet{}), namespace, name)
+	case "DaemonSet":
+		obj = d.objects.getObject(objName(apps_v1.DaemonSet{}), namespace, name)
+	case "CronJob":
+		obj = d.objects.getObject(objName(batch_v1.CronJob{}), namespace, name)
+	default:
+		return nil
+	}
+	if obj == nil {
+		obj = d.getWorkload(kind, namespace, name)
+	}
+	if obj == nil {
+		return nil
+	}
+	return workloadPodSpec(obj)
+}
+
+// getWorkload reads a workload from the API server, nil when it cannot be read
+func (d *vpaDrift) getWorkload(kind, namespace, name string) runtime.Object {
+	ctx, options := context.Background(), meta_v1.GetOptions{}
+	var obj runtime.Object
+	var err error
+	switch kind {
+	case "Deployment":
+		obj, err = d.kubeClient.AppsV1().Deployments(namespace).Get(ctx, name, options)
+	case "StatefulSet":
+		obj, err = d.kubeClient.AppsV1().StatefulSets(namespace).Get(ctx, name, options)
+	case "DaemonSet":
+		obj, err = d.kubeClient.AppsV1().DaemonSets(namespace).Get(ctx, name, options)
+	case "CronJob":
+		obj, err = d.kubeClient.BatchV1().CronJobs(namespace).Get(ctx, name, options)
+	}
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// vpaRecommendations returns the target recommendations of a VerticalPodAutoscaler, by
+// container name
+func vpaRecommendations(obj *unstructured.Unstructured) map[string]api_v1.ResourceList {
+	list, _, _ := unstructured.NestedSlice(obj.Object, "status", "recommendation", "containerRecommendations")
+	recommendations := map[string]api_v1.ResourceList{}
+	for _, item := range list {
+		fields, _ := item.(map[string]interface{})
+		container, _, _ := unstructured.NestedString(fields, "containerName")
+		target, _, _ := unstructured.NestedStringMap(fields, "target")
+		resources := api_v1.ResourceList{}
+		for name, value := range target {
+			if quantity, err := resource_api.ParseQuantity(value); err == nil {
+				resources[api_v1.ResourceName(name)] = quantity
+			}
+		}
+		if container != "" && len(resources) > 0 {
+			recommendations[container] = resources
+		}
+	}
+	return recommendations
+}
+
+// roundQuantity renders a recommendation, rounded to mebibytes for memory since the VPA
+// recommends memory in bytes
+func roundQuantity(name api_v1.ResourceName, q resource_api.Quantity) string {
+	if name == api_v1.ResourceMemory && q.Value() >= 1<<20 {
+		return fmt.Sprintf("%dMi", int64(math.Round(float64(q.Value())/(1<<20))))
+	}
+	return q.String()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	resource_api "k8s.io/apimachinery/pkg/api/resource"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newVPA(cpu, memory string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "api"},
+		},
+		"status": map[string]interface{}{
+			"recommendation": map[string]interface{}{
+				"containerRecommendations": []interface{}{
+					map[string]interface{}{
+						"containerName": "api",
+						"target":        map[string]interface{}{"cpu": cpu, "memory": memory},
+					},
+				},
+			},
+		},
+	}}
+	obj.SetKind("VerticalPodAutoscaler")
+	obj.SetName("api")
+	obj.SetNamespace("payments")
+	obj.SetUID("vpa-uid")
+	return obj
+}
+
+func TestVPADrift(t *testing.T) {
+	deployment := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "payments"}}
+	deployment.Spec.Template.Spec.Containers = []api_v1.Container{{
+		Name: "api",
+		Resources: api_v1.ResourceRequirements{Requests: api_v1.ResourceList{
+			api_v1.ResourceCPU:    resource_api.MustParse("500m"),
+			api_v1.ResourceMemory: resource_api.MustParse("256Mi"),
+		}},
+	}}
+	c := &Controller{vpaDrift: newVPADrift(fake.NewSimpleClientset(deployment), newObjectCache(), 30)}
+
+	var Tests = []struct {
+		name     string
+		vpa      *unstructured.Unstructured
+		expected string
+	}{
+		{"within threshold", newVPA("450m", "280Mi"), ""},
+		{"drifting", newVPA("120m", "943718400"),
+			"Recommendations for Deployment/api differ from the requests by more than 30%:\napi: cpu 500m → 120m (-76%), memory 256Mi → 900Mi (+252%)"},
+		{"same drift", newVPA("110m", "943718400"), ""},
+		{"cpu only", newVPA("110m", "262144k"),
+			"Recommendations for Deployment/api differ from the requests by more than 30%:\napi: cpu 500m → 110m (-78%)"},
+		{"back within threshold", newVPA("500m", "256Mi"), ""},
+		{"drifting again", newVPA("2", "256Mi"),
+			"Recommendations for Deployment/api differ from the requests by more than 30%:\napi: cpu 500m → 2 (+300%)"},
+	}
+
+	for _, tt := range Tests {
+		e := c.vpaDriftEvent(Event{key: "api", namespace: "payments", eventType: "update", obj: tt.vpa})
+		switch {
+		case tt.expected == "" && e != nil:
+			t.Errorf("%s: expected no event, got %q", tt.name, e.Reason)
+		case tt.expected != "" && (e == nil || e.Kind != "VPADrift" || e.Reason != tt.expected):
+			t.Errorf("%s: expected %q, got %+v", tt.name, tt.expected, e)
+		}
+	}
+}
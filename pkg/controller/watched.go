@@ -0,0 +1,158 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+)
+
+// configReloadInterval is how often the config file is checked for watched resource changes
+const configReloadInterval = 10 * time.Second
+
+// watchedResource is a built-in or custom resource watched in a cluster.
+// Its controllers run until stopCh is closed, when the resource is no longer configured.
+type watchedResource struct {
+	// builtin is nil for custom resources
+	builtin    *resource
+	crd        config.CRD
+	namespaced bool
+	// healthOnly is set for GitOps, Karpenter and VerticalPodAutoscaler resources watched
+	// for their dedicated events only
+	healthOnly bool
+	stopCh     chan struct{}
+}
+
+func crdKey(crd config.CRD) string {
+	return fmt.Sprintf("%s/%s/%s", crd.Group, crd.Version, crd.Resource)
+}
+
+// reload applies the watched resources of conf.
+// Other settings are only read at startup and require a restart.
+func (w *clusterWatcher) reload(conf *config.Config) {
+	started, stopped := w.reconcile(conf.Resource, conf.CustomResources)
+
+	logger := logrus.WithField("pkg", "kubewatch-reload")
+	if w.clusterName != "" {
+		logger = logger.WithField("cluster", w.clusterName)
+	}
+	for _, key := range started {
+		logger.Infof("Resource %s enabled, starting its controllers", key)
+	}
+	for _, key := range stopped {
+		logger.Infof("Resource %s disabled, stopping its controllers", key)
+	}
+}
+
+// reconcile starts the controllers of resources enabled in resourceConf and crds that are not
+// watched yet, and stops those of watched resources no longer enabled. It returns the keys
+// of started and stopped resources.
+func (w *clusterWatcher) reconcile(resourceConf config.Resource, crds []config.CRD) (started []string, stopped []string) {
+	configured := map[string]bool{}
+	for _, crd := range crds {
+		configured[crdKey(crd)] = true
+	}
+	if w.conf.CertManager.Enabled {
+		crds = withCertificateCRD(crds)
+	}
+	crds = withGitOpsCRDs(crds, w.conf.GitOps)
+	if w.conf.KEDA.Enabled {
+		crds = withScaledObjectCRD(crds)
+	}
+	if w.conf.Karpenter.Enabled {
+		crds = withKarpenterCRDs(crds)
+	}
+	if w.conf.VPA.Enabled {
+		crds = withVPACRD(crds)
+	}
+
+	var keys []string
+	desired := map[string]*watchedResource{}
+	for i := range resources {
+		r := &resources[i]
+		if r.enabled(resourceConf) {
+			keys = append(keys, r.name)
+			desired[r.name] = &watchedResource{builtin: r, namespaced: r.namespaced}
+		}
+	}
+	for _, crd := range crds {
+		key := crdKey(crd)
+		if _, ok := desired[key]; !ok {
+			keys = append(keys, key)
+			desired[key] = &watchedResource{crd: crd, healthOnly: !configured[key]}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for key, wr := range w.watched {
+		if _, ok := desired[key]; !ok {
+			close(wr.stopCh)
+			delete(w.watched, key)
+			stopped = append(stopped, key)
+		}
+	}
+
+	for _, key := range keys {
+		if _, ok := w.watched[key]; ok {
+			continue
+		}
+		wr := desired[key]
+		if wr.builtin == nil {
+			wr.namespaced = isNamespacedCustomResource(w.kubeClient, wr.crd)
+		}
+		wr.stopCh = make(chan struct{})
+		w.watched[key] = wr
+		started = append(started, key)
+
+		if !wr.namespaced {
+			w.startWatched(wr, "", w.stopCh)
+			continue
+		}
+		for namespace, nsStopCh := range w.namespaces {
+			w.startWatched(wr, namespace, nsStopCh)
+		}
+	}
+	return started, stopped
+}
+
+// startWatched runs the controller of wr in namespace until either stopCh or wr.stopCh is closed
+func (w *clusterWatcher) startWatched(wr *watchedResource, namespace string, stopCh <-chan struct{}) {
+	stop := anyClosed(stopCh, wr.stopCh)
+	if wr.builtin != nil {
+		w.startResource(*wr.builtin, namespace, stop)
+		return
+	}
+	w.startCustomResource(wr.crd, wr.healthOnly, namespace, stop)
+}
+
+// anyClosed returns a channel closed as soon as a or b is closed
+func anyClosed(a, b <-chan struct{}) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		select {
+		case <-a:
+		case <-b:
+		}
+		close(ch)
+	}()
+	return ch
+}
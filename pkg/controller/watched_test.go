@@ -0,0 +1,59 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReconcile(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	r := &recorder{}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	w := startCluster(&config.Config{Resource: config.Resource{Pod: true}}, r, Cluster{KubeClient: client}, nil, stopCh)
+
+	started, stopped := w.reconcile(config.Resource{Pod: true, Node: true}, nil)
+	if !reflect.DeepEqual(started, []string{"Node"}) || len(stopped) != 0 {
+		t.Fatalf("expected Node to be started, got started %v, stopped %v", started, stopped)
+	}
+
+	started, stopped = w.reconcile(config.Resource{Node: true}, nil)
+	if len(started) != 0 || !reflect.DeepEqual(stopped, []string{"Pod"}) {
+		t.Fatalf("expected Pod to be stopped, got started %v, stopped %v", started, stopped)
+	}
+
+	// give the Node informer time to list and the Pod controller time to stop
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.CoreV1().Pods("default").Create(context.Background(), newPod("pod", time.Now().Add(time.Minute)), meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	node := &api_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: "node", CreationTimestamp: meta_v1.NewTime(time.Now().Add(time.Minute))}}
+	if _, err := client.CoreV1().Nodes().Create(context.Background(), node, meta_v1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.waitFor(t, "Created node")
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// maxFormattedChanges is the number of changes rendered by Format
+const maxFormattedChanges = 10
+
+// maxFormattedValue is the length above which rendered values are truncated
+const maxFormattedValue = 80
+
+// ignoredFields are not compared, they change on every update or are redundant
+var ignoredFields = [][]string{
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"status"},
+}
+
+// Change is a field changed between two versions of an object.
+// Old is nil for added fields, New is nil for removed fields.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Compute returns the fields changed from old to new, sorted by path.
+// Status, managed fields and other bookkeeping fields are ignored.
+func Compute(old, new runtime.Object) ([]Change, error) {
+	if old == nil || new == nil {
+		return nil, nil
+	}
+	oldFields, err := toFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toFields(new)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range ignoredFields {
+		removeField(oldFields, path)
+		removeField(newFields, path)
+	}
+
+	var changes []Change
+	compare("", oldFields, newFields, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// toFields converts obj to a map of fields that can be modified without affecting obj
+func toFields(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func removeField(fields map[string]interface{}, path []string) {
+	for i, key := range path {
+		if i == len(path)-1 {
+			delete(fields, key)
+			return
+		}
+		next, ok := fields[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields = next
+	}
+}
+
+func compare(path string, old, new interface{}, changes *[]Change) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	// added and removed maps are reported field by field
+	if (oldIsMap || old == nil) && (newIsMap || new == nil) && (oldIsMap || newIsMap) {
+		for key, oldValue := range oldMap {
+			compare(join(path, key), oldValue, newMap[key], changes)
+		}
+		for key, newValue := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				compare(join(path, key), nil, newValue, changes)
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList && len(oldList) == len(newList) {
+		for i := range oldList {
+			compare(fmt.Sprintf("%s[%d]", path, i), oldList[i], newList[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: path, Old: old, New: new})
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	return path + "." + key
+}
+
+// Format renders changes as one readable line per changed field
+func Format(changes []Change) string {
+	var lines []string
+	for i, change := range changes {
+		if i == maxFormattedChanges {
+			lines = append(lines, fmt.Sprintf("... and %d more changes", len(changes)-maxFormattedChanges))
+			break
+		}
+		switch {
+		case change.Old == nil:
+			lines = append(lines, fmt.Sprintf("%s: added %s", change.Path, formatValue(change.New)))
+		case change.New == nil:
+			lines = append(lines, fmt.Sprintf("%s: removed %s", change.Path, formatValue(change.Old)))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", change.Path, formatValue(change.Old), formatValue(change.New)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatValue(value interface{}) string {
+	var s string
+	if str, ok := value.(string); ok {
+		s = str
+	} else if b, err := json.Marshal(value); err == nil {
+		s = string(b)
+	} else {
+		s = fmt.Sprint(value)
+	}
+	if len(s) > maxFormattedValue {
+		s = s[:maxFormattedValue] + "..."
+	}
+	return s
+}
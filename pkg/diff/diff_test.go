@@ -0,0 +1,113 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDeployment(replicas int32, image string, labels map[string]string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            "web",
+			Labels:          labels,
+			ResourceVersion: image,
+			ManagedFields:   []meta_v1.ManagedFieldsEntry{{Manager: image}},
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: api_v1.PodTemplateSpec{Spec: api_v1.PodSpec{
+				Containers: []api_v1.Container{{Name: "web", Image: image}},
+			}},
+		},
+		Status: apps_v1.DeploymentStatus{Replicas: replicas},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	old := newDeployment(1, "nginx:1.25", map[string]string{"app": "web", "app.kubernetes.io/version": "1"})
+	new := newDeployment(3, "nginx:1.26", map[string]string{"app.kubernetes.io/version": "2", "tier": "front"})
+
+	changes, err := Compute(old, new)
+	if err != nil {
+		t.Fatalf("Compute(): %v", err)
+	}
+	expected := []Change{
+		{Path: "metadata.labels.app", Old: "web"},
+		{Path: "metadata.labels.tier", New: "front"},
+		{Path: `metadata.labels["app.kubernetes.io/version"]`, Old: "1", New: "2"},
+		{Path: "spec.replicas", Old: int64(1), New: int64(3)},
+		{Path: "spec.template.spec.containers[0].image", Old: "nginx:1.25", New: "nginx:1.26"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, changes)
+	}
+
+	if changes, _ := Compute(old, old.DeepCopy()); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+	if changes, _ := Compute(nil, new); changes != nil {
+		t.Fatalf("expected no changes without old object, got %+v", changes)
+	}
+}
+
+func TestComputeUnstructured(t *testing.T) {
+	newObj := func(size int64) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "db", "resourceVersion": "1"},
+			"spec":     map[string]interface{}{"size": size},
+		}}
+	}
+	old, new := newObj(1), newObj(2)
+
+	changes, err := Compute(old, new)
+	if err != nil {
+		t.Fatalf("Compute(): %v", err)
+	}
+	if expected := []Change{{Path: "spec.size", Old: int64(1), New: int64(2)}}; !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, changes)
+	}
+	if old.GetResourceVersion() != "1" {
+		t.Fatalf("expected objects to be left untouched")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	changes := []Change{
+		{Path: "metadata.labels.app", Old: "web"},
+		{Path: "metadata.labels.tier", New: "front"},
+		{Path: "spec.replicas", Old: int64(1), New: int64(3)},
+	}
+	expected := "metadata.labels.app: removed web\nmetadata.labels.tier: added front\nspec.replicas: 1 -> 3"
+	if got := Format(changes); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	for i := 0; i < 20; i++ {
+		changes = append(changes, Change{Path: "spec.x", Old: "a", New: "b"})
+	}
+	if got := Format(changes); !strings.HasSuffix(got, "\n... and 13 more changes") {
+		t.Fatalf("expected truncated changes, got %q", got)
+	}
+}
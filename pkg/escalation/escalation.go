@@ -0,0 +1,398 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package escalation
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	slackhandler "github.com/bitnami-labs/kubewatch/pkg/handlers/slack"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"github.com/slack-go/slack"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// APIPath is where events are acknowledged on the metrics server
+const APIPath = "/api/v1/acks"
+
+// SlackPath is where the Slack app sends the clicks on the Acknowledge buttons of messages
+const SlackPath = "/slack/interactions"
+
+// stateFile is the file of the notification states, in the directory of the event store
+const stateFile = "escalations.json"
+
+// checkInterval is how often the notifications are checked for escalation
+const checkInterval = 30 * time.Second
+
+// keep is how long the state of acknowledged or escalated notifications is kept
+const keep = 24 * time.Hour
+
+// ErrNotFound is returned when acknowledging an event that is not waiting for it
+var ErrNotFound = errors.New("no notification waiting for acknowledgement with this event ID")
+
+// Notification is the state of an urgent event sent to the handler
+type Notification struct {
+	Event       event.Payload `json:"event"`
+	NotifiedAt  time.Time     `json:"notifiedAt"`
+	AckedAt     *time.Time    `json:"ackedAt,omitempty"`
+	AckedBy     string        `json:"ackedBy,omitempty"`
+	EscalatedAt *time.Time    `json:"escalatedAt,omitempty"`
+}
+
+// Escalator re-sends the urgent events not acknowledged within a delay to an escalation
+// handler. The state of the notifications is persisted in the directory of the event
+// store, so that escalations survive restarts.
+type Escalator struct {
+	path     string
+	after    time.Duration
+	severity event.Severity
+	handler  handlers.Handler
+	now      func() time.Time
+
+	mu            sync.Mutex
+	notifications map[string]*Notification
+}
+
+// Open returns the Escalator of conf sending escalations to handler, loading the state of
+// the notifications from dir
+func Open(dir string, conf config.Escalation, handler handlers.Handler) (*Escalator, error) {
+	x := &Escalator{
+		path:          filepath.Join(dir, stateFile),
+		after:         conf.After,
+		severity:      event.Severity(conf.Severity),
+		handler:       handler,
+		now:           time.Now,
+		notifications: map[string]*Notification{},
+	}
+	data, err := os.ReadFile(x.path)
+	if os.IsNotExist(err) {
+		return x, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var notifications []*Notification
+	if err := json.Unmarshal(data, &notifications); err != nil {
+		return nil, err
+	}
+	for _, n := range notifications {
+		x.notifications[n.Event.ID] = n
+	}
+	return x, nil
+}
+
+// Escalates returns whether e is urgent enough to be escalated
+func (x *Escalator) Escalates(e event.Event) bool {
+	return e.ID != "" && e.ResolvedSeverity().AtLeast(x.severity)
+}
+
+// Track records that e was sent, to escalate it unless acknowledged in time
+func (x *Escalator) Track(e event.Event) error {
+	if !x.Escalates(e) {
+		return nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.notifications[e.ID] = &Notification{Event: e.Payload(), NotifiedAt: x.now()}
+	return x.save()
+}
+
+// Ack acknowledges the event with id on behalf of by, so that it is not escalated
+func (x *Escalator) Ack(id, by string) (Notification, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n, ok := x.notifications[id]
+	if !ok {
+		return Notification{}, ErrNotFound
+	}
+	if n.AckedAt == nil {
+		now := x.now()
+		n.AckedAt, n.AckedBy = &now, by
+		if err := x.save(); err != nil {
+			return *n, err
+		}
+	}
+	return *n, nil
+}
+
+// Pending returns the notifications waiting for acknowledgement, oldest first
+func (x *Escalator) Pending() []Notification {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	pending := []Notification{}
+	for _, n := range x.notifications {
+		if n.AckedAt == nil {
+			pending = append(pending, *n)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].NotifiedAt.Before(pending[j].NotifiedAt) })
+	return pending
+}
+
+// Escalate sends the events not acknowledged within the delay to the escalation handler,
+// and forgets the notifications acknowledged or escalated for long
+func (x *Escalator) Escalate() {
+	x.mu.Lock()
+	now := x.now()
+	var due []event.Event
+	for id, n := range x.notifications {
+		switch {
+		case n.AckedAt != nil && now.Sub(*n.AckedAt) > keep, n.EscalatedAt != nil && now.Sub(*n.EscalatedAt) > keep:
+			delete(x.notifications, id)
+		case n.AckedAt == nil && n.EscalatedAt == nil && now.Sub(n.NotifiedAt) >= x.after:
+			due = append(due, n.Event.Event())
+		}
+	}
+	if err := x.save(); err != nil {
+		logrus.WithField("pkg", "kubewatch-escalation").Errorf("Error saving escalations: %v", err)
+	}
+	x.mu.Unlock()
+
+	// events are marked as escalated once delivered, failed escalations are retried on the
+	// next check
+	for _, e := range due {
+		logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-escalation").Infof("Escalating event not acknowledged within %s", x.after)
+		if err := handlers.Deliver(context.Background(), x.handler, e); err != nil {
+			logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-escalation").Errorf("Error escalating event, it is retried on the next check: %v", err)
+			continue
+		}
+		x.escalated(e.ID, now)
+	}
+}
+
+// escalated marks the notification of the event with id as escalated at
+func (x *Escalator) escalated(id string, at time.Time) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n, ok := x.notifications[id]
+	if !ok {
+		return
+	}
+	n.EscalatedAt = &at
+	if err := x.save(); err != nil {
+		logrus.WithField("pkg", "kubewatch-escalation").Errorf("Error saving escalations: %v", err)
+	}
+}
+
+// Run escalates the unacknowledged events until stopCh is closed
+func (x *Escalator) Run(stopCh <-chan struct{}) {
+	wait.Until(x.Escalate, checkInterval, stopCh)
+}
+
+// save writes the notification states, x.mu must be held. The file is written then
+// renamed, so a crash never leaves a truncated file.
+func (x *Escalator) save() error {
+	notifications := make([]*Notification, 0, len(x.notifications))
+	for _, n := range x.notifications {
+		notifications = append(notifications, n)
+	}
+	data, err := json.Marshal(notifications)
+	if err != nil {
+		return err
+	}
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, x.path)
+}
+
+// ackRequest is the optional body of acknowledgements
+type ackRequest struct {
+	// By names who acknowledges the event
+	By string `json:"by"`
+}
+
+// API returns the admin API acknowledging events under APIPath, requiring token as bearer
+// token:
+//
+//	GET  /api/v1/acks             lists the notifications waiting for acknowledgement
+//	POST /api/v1/acks/<event id>  acknowledges an event
+func (x *Escalator) API(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, APIPath), "/")
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			writeJSON(w, http.StatusOK, x.Pending())
+		case r.Method == http.MethodPost && id != "":
+			var req ackRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.By == "" {
+				req.By = "admin API"
+			}
+			x.ack(w, id, req.By)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// SlackInteractions returns the handler of the clicks on the Acknowledge buttons of Slack
+// messages, checking the requests are signed with the signing secret of the Slack app
+func (x *Escalator) SlackInteractions(signingSecret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+		if err == nil {
+			verifier.Write(body)
+			err = verifier.Ensure()
+		}
+		if err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var callback slack.InteractionCallback
+		if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		actions := callback.ActionCallback.AttachmentActions
+		if callback.CallbackID != slackhandler.AckCallbackID || len(actions) == 0 {
+			http.Error(w, "unknown interaction", http.StatusBadRequest)
+			return
+		}
+
+		by := callback.User.Name
+		if by == "" {
+			by = callback.User.ID
+		}
+		n, err := x.Ack(actions[0].Value, by)
+		text := "Acknowledged by <@" + callback.User.ID + ">"
+		switch {
+		case err == ErrNotFound:
+			text = "This event is no longer waiting for acknowledgement"
+		case err != nil:
+			logrus.WithField("pkg", "kubewatch-escalation").Errorf("Error saving escalations: %v", err)
+		case n.AckedBy != by:
+			text = "Already acknowledged by " + n.AckedBy
+		}
+		writeJSON(w, http.StatusOK, slack.Msg{Text: text, ResponseType: slack.ResponseTypeInChannel, ReplaceOriginal: false})
+	})
+}
+
+// ack acknowledges the event with id on behalf of by, answering the notification state
+func (x *Escalator) ack(w http.ResponseWriter, id, by string) {
+	n, err := x.Ack(id, by)
+	if err == ErrNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logrus.WithField("pkg", "kubewatch-escalation").Infof("Event %s acknowledged by %s", id, n.AckedBy)
+	writeJSON(w, http.StatusOK, n)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Handler wraps a handler, tracking the urgent events it delivers for escalation
+type Handler struct {
+	Escalator *Escalator
+	Handler   handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler and tracks it.
+func (h *Handler) Handle(e event.Event) {
+	h.Handler.Handle(e)
+	h.track(e)
+}
+
+// HandleWithError sends the event to the wrapped handler, reporting delivery failures when
+// the handler reports them. Only delivered events are tracked.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext sends e to the wrapped handler with ctx, tracking it for escalation once
+// delivered
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	if err := handlers.Deliver(ctx, h.Handler, e); err != nil {
+		return err
+	}
+	h.track(e)
+	return nil
+}
+
+// track tracks e for escalation, and acknowledges the escalation of the problem e resolves
+func (h *Handler) track(e event.Event) {
+	if e.Resolution != nil && e.Resolution.ProblemID != "" {
+		if _, err := h.Escalator.Ack(e.Resolution.ProblemID, "resolved"); err != nil && err != ErrNotFound {
+			logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-escalation").Errorf("Error acknowledging resolved event: %v", err)
+		}
+	}
+	if err := h.Escalator.Track(e); err != nil {
+		logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-escalation").Errorf("Error tracking event for escalation: %v", err)
+	}
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *Handler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
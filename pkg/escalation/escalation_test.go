@@ -0,0 +1,204 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package escalation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// recordingHandler records the IDs of the events it receives, after failing to deliver
+// the first failures ones
+type recordingHandler struct {
+	ids      []string
+	failures int
+}
+
+func (r *recordingHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *recordingHandler) Handle(e event.Event) {
+	r.ids = append(r.ids, e.ID)
+}
+
+func (r *recordingHandler) HandleWithError(e event.Event) error {
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("endpoint unavailable")
+	}
+	r.Handle(e)
+	return nil
+}
+
+// openEscalator returns an Escalator of dir escalating critical events after 15 minutes
+// to r, at the time current points to
+func openEscalator(t *testing.T, dir string, r *recordingHandler, current *time.Time) *Escalator {
+	x, err := Open(dir, config.Escalation{After: 15 * time.Minute, Severity: "critical"}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.now = func() time.Time { return *current }
+	return x
+}
+
+func TestEscalator(t *testing.T) {
+	dir := t.TempDir()
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	escalated := &recordingHandler{}
+	x := openEscalator(t, dir, escalated, &current)
+
+	sent := &recordingHandler{}
+	h := &Handler{Escalator: x, Handler: sent}
+	h.Handle(event.Event{ID: "crash", Kind: "Backoff", Status: "Danger"})
+	h.Handle(event.Event{ID: "oom", Kind: "OOMKilled", Severity: event.SeverityCritical})
+	h.Handle(event.Event{ID: "scaled", Kind: "Deployment", Status: "Normal"})
+	if len(sent.ids) != 3 {
+		t.Fatalf("expected every event to be sent, got %v", sent.ids)
+	}
+	if pending := x.Pending(); len(pending) != 2 {
+		t.Fatalf("expected the critical events to wait for acknowledgement, got %+v", pending)
+	}
+
+	current = current.Add(10 * time.Minute)
+	if n, err := x.Ack("oom", "alice"); err != nil || n.AckedBy != "alice" {
+		t.Fatalf("Ack() = %+v, %v", n, err)
+	}
+	if _, err := x.Ack("scaled", "alice"); err != ErrNotFound {
+		t.Errorf("expected events not tracked not to be found, got %v", err)
+	}
+	x.Escalate()
+	if len(escalated.ids) != 0 {
+		t.Fatalf("expected no escalation before the delay, got %v", escalated.ids)
+	}
+
+	// the state survives restarts
+	current = current.Add(5 * time.Minute)
+	x = openEscalator(t, dir, escalated, &current)
+	escalated.failures = 1
+	x.Escalate()
+	if len(escalated.ids) != 0 || len(x.Pending()) != 1 || x.Pending()[0].EscalatedAt != nil {
+		t.Fatalf("expected the failed escalation not to be marked as escalated, got %v", escalated.ids)
+	}
+	x.Escalate()
+	x.Escalate()
+	if len(escalated.ids) != 1 || escalated.ids[0] != "crash" {
+		t.Errorf("expected the unacknowledged event to be escalated once, got %v", escalated.ids)
+	}
+
+	current = current.Add(25 * time.Hour)
+	x.Escalate()
+	if pending := x.Pending(); len(pending) != 0 {
+		t.Errorf("expected old notifications to be forgotten, got %+v", pending)
+	}
+}
+
+func TestHandlerAcksResolved(t *testing.T) {
+	current := time.Now()
+	x := openEscalator(t, t.TempDir(), &recordingHandler{}, &current)
+	h := &Handler{Escalator: x, Handler: &recordingHandler{}}
+	h.Handle(event.Event{ID: "crash", Kind: "Event", Status: "Danger"})
+	h.Handle(event.Event{ID: "resolved", Kind: "Resolved", Status: "Normal", Resolution: &event.Resolution{Problem: "CrashLoopBackOff", ProblemID: "crash"}})
+	if pending := x.Pending(); len(pending) != 0 {
+		t.Errorf("expected the resolved problem not to be escalated, got %+v", pending)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	current := time.Now()
+	x := openEscalator(t, t.TempDir(), &recordingHandler{}, &current)
+	if err := x.Track(event.Event{ID: "crash", Status: "Danger"}); err != nil {
+		t.Fatal(err)
+	}
+	api := x.API("secret")
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, APIPath+"/crash", "wrong", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong token to be rejected, got %d", w.Code)
+	}
+	var pending []Notification
+	w := do(http.MethodGet, APIPath, "secret", "")
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil || len(pending) != 1 || pending[0].Event.ID != "crash" {
+		t.Errorf("expected the event to wait for acknowledgement, got %+v (%v)", pending, err)
+	}
+	if w := do(http.MethodPost, APIPath+"/unknown", "secret", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected unknown events not to be found, got %d", w.Code)
+	}
+	w = do(http.MethodPost, APIPath+"/crash", "secret", `{"by":"bob"}`)
+	var acked Notification
+	if err := json.NewDecoder(w.Body).Decode(&acked); err != nil || w.Code != http.StatusOK || acked.AckedBy != "bob" {
+		t.Errorf("expected the event to be acknowledged by bob, got %d %+v (%v)", w.Code, acked, err)
+	}
+}
+
+func TestSlackInteractions(t *testing.T) {
+	current := time.Now()
+	x := openEscalator(t, t.TempDir(), &recordingHandler{}, &current)
+	if err := x.Track(event.Event{ID: "crash", Status: "Danger"}); err != nil {
+		t.Fatal(err)
+	}
+	handler := x.SlackInteractions("signing-secret")
+	click := func(secret string) *httptest.ResponseRecorder {
+		payload := `{"type":"interactive_message","callback_id":"kubewatch-ack","user":{"id":"U0ALICE","name":"alice"},"actions":[{"name":"ack","type":"button","value":"crash"}]}`
+		body := url.Values{"payload": {payload}}.Encode()
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+		req := httptest.NewRequest(http.MethodPost, SlackPath, strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := click("other-secret"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected unsigned requests to be rejected, got %d", w.Code)
+	}
+	w := click("signing-secret")
+	var reply struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil || w.Code != http.StatusOK || reply.Text != "Acknowledged by <@U0ALICE>" {
+		t.Errorf("expected the event to be acknowledged, got %d %q (%v)", w.Code, reply.Text, err)
+	}
+	if pending := x.Pending(); len(pending) != 0 {
+		t.Errorf("expected no event waiting for acknowledgement, got %+v", pending)
+	}
+}
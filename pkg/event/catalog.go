@@ -0,0 +1,135 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// locales holds the builtin catalogs, one YAML file per language
+//
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// formatVerb matches the verbs of format strings
+var formatVerb = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z]`)
+
+// Catalog translates the messages kubewatch generates into a language. Translations are
+// keyed by the English text: formats such as "Pod `%s` in `%s` Crashed : \n%s", translated
+// with the same arguments, possibly reordered with explicit indexes such as %[2]s, or words
+// such as "created". Text without translation stays English, and so does text coming from
+// the cluster, such as the reasons of Kubernetes Events.
+type Catalog struct {
+	Language string
+	messages map[string]string
+}
+
+// Languages returns the languages of the builtin catalogs
+func Languages() []string {
+	entries, _ := locales.ReadDir("locales")
+	languages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		languages = append(languages, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// LoadCatalog returns the catalog of language, the builtin one completed by the
+// translations of the YAML file at path when set. Languages without builtin catalog
+// require a file.
+func LoadCatalog(language, file string) (*Catalog, error) {
+	c := &Catalog{Language: language, messages: map[string]string{}}
+	builtin, err := locales.ReadFile(path.Join("locales", language+".yaml"))
+	switch {
+	case err == nil:
+		if err := c.add(builtin); err != nil {
+			return nil, fmt.Errorf("builtin catalog %s: %v", language, err)
+		}
+	case file == "":
+		return nil, fmt.Errorf("no catalog for language %q, expected one of %s or a catalog file", language, strings.Join(Languages(), ", "))
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.add(data); err != nil {
+			return nil, fmt.Errorf("catalog %s: %v", file, err)
+		}
+	}
+	return c, nil
+}
+
+// add adds the translations of a YAML document mapping English messages to their
+// translation, checking translations take the arguments of their message
+func (c *Catalog) add(data []byte) error {
+	var messages map[string]string
+	if err := yaml.Unmarshal(data, &messages); err != nil {
+		return err
+	}
+	for msg, translation := range messages {
+		if err := checkTranslation(msg, translation); err != nil {
+			return fmt.Errorf("translation of %q: %v", msg, err)
+		}
+		c.messages[msg] = translation
+	}
+	return nil
+}
+
+// anyArg formats as "x" whatever the verb, for translations to be checked with any verb
+type anyArg struct{}
+
+func (anyArg) Format(f fmt.State, verb rune) {
+	f.Write([]byte("x"))
+}
+
+// checkTranslation returns an error when translation does not use the arguments of msg,
+// missing some, having extra ones or referring to arguments beyond them
+func checkTranslation(msg, translation string) error {
+	if translation == "" {
+		return fmt.Errorf("is empty")
+	}
+	args := make([]interface{}, len(formatVerb.FindAllString(strings.ReplaceAll(msg, "%%", ""), -1)))
+	for i := range args {
+		args[i] = anyArg{}
+	}
+	if rendered := fmt.Sprintf(translation, args...); strings.Contains(rendered, "%!") {
+		return fmt.Errorf("%q does not take the %d arguments of the message: %s", translation, len(args), rendered)
+	}
+	return nil
+}
+
+// T returns the translation of msg, msg itself without translation or catalog
+func (c *Catalog) T(msg string) string {
+	if c != nil {
+		if translation, ok := c.messages[msg]; ok {
+			return translation
+		}
+	}
+	return msg
+}
+
+// Sprintf formats the arguments with the translation of format
+func (c *Catalog) Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(c.T(format), args...)
+}
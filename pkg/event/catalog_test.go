@@ -0,0 +1,119 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuiltinCatalogs(t *testing.T) {
+	for _, language := range Languages() {
+		if _, err := LoadCatalog(language, ""); err != nil {
+			t.Errorf("%s: %v", language, err)
+		}
+	}
+	if _, err := LoadCatalog("pt", ""); err == nil {
+		t.Error("expected an error for a language without catalog")
+	}
+}
+
+func TestTranslatedMessage(t *testing.T) {
+	de, err := LoadCatalog("de", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := Event{
+		Kind: "Deployment", Namespace: "payments", Name: "api", Reason: "Updated",
+		ChangedBy: "alice", Scale: &ScaleChange{From: 3, To: 5, Autoscaled: true}, Catalog: de,
+	}
+	if summary, expected := e.Summary(), "Deployment payments/api aktualisiert: Replikate 3→5 (automatisch skaliert)"; summary != expected {
+		t.Errorf("Summary() = %q, expected %q", summary, expected)
+	}
+	expected := "Deployment in Namespace `payments` wurde `aktualisiert`:\n`api`\nGeändert von alice\nskaliert von 3 auf 5 durch den HorizontalPodAutoscaler"
+	if message := e.Message(); message != expected {
+		t.Errorf("Message() = %q, expected %q", message, expected)
+	}
+	if language := e.Payload().Language; language != "de" {
+		t.Errorf("payload language = %q, expected de", language)
+	}
+
+	// kinds without translation stay English
+	e = Event{Kind: "Pod", Namespace: "payments", Name: "api", Reason: "Created"}
+	if summary := e.Summary(); summary != "Pod payments/api created" {
+		t.Errorf("English Summary() = %q", summary)
+	}
+}
+
+func TestCatalogFile(t *testing.T) {
+	dir := t.TempDir()
+	var Tests = []struct {
+		catalog string
+		valid   bool
+	}{
+		{`"%s scaled": "%s escalado"`, true},
+		{`"Changed by %s": "Alterado por %[1]s"`, true},
+		{`"Changed by %s": "Alterado"`, false},
+		{`"Changed by %s": "Alterado por %s e %s"`, false},
+		{`"Changed by %s": "Alterado por %[2]s"`, false},
+		{`"Changed by %s": ""`, false},
+		{`[not, a, map]`, false},
+	}
+
+	for i, tt := range Tests {
+		file := filepath.Join(dir, "pt.yaml")
+		if err := os.WriteFile(file, []byte(tt.catalog), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		c, err := LoadCatalog("pt", file)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("%d: LoadCatalog() error = %v, expected valid %v", i, err, tt.valid)
+			continue
+		}
+		if c != nil && c.Language != "pt" {
+			t.Errorf("%d: language = %q", i, c.Language)
+		}
+	}
+
+	// files complete the builtin catalog of their language
+	file := filepath.Join(dir, "fr.yaml")
+	if err := os.WriteFile(file, []byte(`"%s scaled": "%s mis à l'échelle"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fr, err := LoadCatalog("fr", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fr.Sprintf("%s scaled", "web"); s != "web mis à l'échelle" {
+		t.Errorf("overridden translation = %q", s)
+	}
+	if s := fr.T("deleted"); s != "supprimé" {
+		t.Errorf("builtin translation = %q", s)
+	}
+}
+
+func TestCapitalizedTranslation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fr.yaml")
+	if err := os.WriteFile(file, []byte(`"Deleted by %s": "éliminé par %s"`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fr, err := LoadCatalog("fr", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := (Deletion{DeletedBy: "alice"}).text(fr); s != "Éliminé par alice" {
+		t.Errorf("Deletion.text() = %q, expected %q", s, "Éliminé par alice")
+	}
+}
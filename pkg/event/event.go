@@ -15,7 +15,15 @@ package event
 
 import (
 	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
 )
 
 // Event represent an event got from k8s api server
@@ -24,14 +32,392 @@ import (
 type Event struct {
 	Namespace  string
 	Kind       string
+	APIVersion string
+	// Deprecated: use APIVersion. ApiVersion is read when APIVersion is empty, and is set
+	// to APIVersion by the controller for the handlers still reading it.
 	ApiVersion string
 	Component  string
 	Host       string
 	Reason     string
 	Status     string
 	Name       string
-	Obj        runtime.Object
-	OldObj     runtime.Object
+	UID        types.UID
+	Labels     map[string]string
+	// ID uniquely identifies the notification
+	ID string
+	// CorrelationID is shared by the notifications of a single change, such as the
+	// events of a rollout, or of a same object
+	CorrelationID string
+	// GroupKey groups the notifications of related objects into a single thread, such as
+	// the pods of a ReplicaSet. Defaults to namespace/kind/name.
+	GroupKey string
+	// Severity is derived from Status by the controller, see ResolvedSeverity
+	Severity Severity
+	// Timestamp is when kubewatch observed the event
+	Timestamp time.Time
+	// ClusterName is the name of the cluster the event comes from,
+	// empty unless clusters or clustername are configured
+	ClusterName string
+	// Environment, Region and ClusterLabels describe the cluster the event comes from
+	Environment   string
+	Region        string
+	ClusterLabels map[string]string
+	Obj           runtime.Object
+	// OldObj is the previous version of Obj on updates of every kind, nil for other events
+	// and for changes made while kubewatch was not running
+	OldObj runtime.Object
+	// Diff holds the fields changed between OldObj and Obj on updates
+	Diff []diff.Change
+	// ImageChanges holds the container images changed by workload updates
+	ImageChanges []ImageChange
+	// Scale holds the replica count change of scaled workloads
+	Scale *ScaleChange
+	// JobRun describes the run of Jobs that just completed
+	JobRun *JobRun
+	// ChangedBy is who made the change of updates, a user or a field manager, when known
+	ChangedBy string
+	// Involved describes the object a Kubernetes Event is about
+	Involved *InvolvedObject
+	// Ownership describes who owns the namespace of the event, when ownership is configured
+	Ownership *Ownership
+	// Logs holds the last log lines of a failing container
+	Logs *ContainerLogs
+	// Description summarizes the state of a failing object, as kubectl describe does
+	Description *Description
+	// Fields are the fields extracted from the object, configured for custom resources
+	Fields []Field
+	// Links are the deep links configured for the object
+	Links []Link
+	// Deletion describes who deleted the object of Deleted events, when known
+	Deletion *Deletion
+	// Resolution describes the problem a Resolved event reports the end of
+	Resolution *Resolution
+	// Truncated lists the details dropped to fit the payload size limit of a handler
+	Truncated []string
+	// Catalog translates the messages of handlers configured with a language, English when nil
+	Catalog *Catalog
+	// Theme customizes the emoji, colors and prefixes of messages, the default theme when nil
+	Theme *Theme
+}
+
+// Deletion describes the deletion of an object, from its last known state
+type Deletion struct {
+	// DeletedBy is who deleted the object, as recorded in the kubewatch.io/deleted-by annotation
+	DeletedBy string `json:"deletedBy,omitempty"`
+	// LastManager is the field manager of the last change made to the object
+	LastManager string `json:"lastManager,omitempty"`
+	// Finalizers are the finalizers the object had before its deletion completed
+	Finalizers []string `json:"finalizers,omitempty"`
+	// FinalStateUnknown is set when the deletion was missed while the watch was interrupted,
+	// the object is then the last state kubewatch saw
+	FinalStateUnknown bool `json:"finalStateUnknown,omitempty"`
+}
+
+// String renders the deletion details in a sentence, empty when nothing is known
+func (d Deletion) String() string {
+	return d.text(nil)
+}
+
+func (d Deletion) text(c *Catalog) string {
+	var details []string
+	if d.DeletedBy != "" {
+		details = append(details, c.Sprintf("Deleted by %s", d.DeletedBy))
+	}
+	if d.LastManager != "" {
+		details = append(details, c.Sprintf("last changed by %s", d.LastManager))
+	}
+	if d.FinalStateUnknown {
+		details = append(details, c.T("deletion missed while the watch was interrupted"))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return capitalize(strings.Join(details, ", "))
+}
+
+// Ownership describes the team owning a namespace and how to reach it
+type Ownership struct {
+	Team         string `json:"team,omitempty" yaml:"team,omitempty"`
+	Owner        string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	SlackChannel string `json:"slackChannel,omitempty" yaml:"slackChannel,omitempty"`
+	PagerService string `json:"pagerService,omitempty" yaml:"pagerService,omitempty"`
+}
+
+// String renders the ownership in a sentence, such as
+// "Owned by team payments (alice@example.com), paging payments-api", empty when unknown
+func (o Ownership) String() string {
+	return o.text(nil)
+}
+
+func (o Ownership) text(c *Catalog) string {
+	owner := o.Team
+	if owner != "" {
+		owner = c.Sprintf("team %s", owner)
+	}
+	switch {
+	case owner == "":
+		owner = o.Owner
+	case o.Owner != "":
+		owner += " (" + o.Owner + ")"
+	}
+	var details []string
+	if owner != "" {
+		details = append(details, c.Sprintf("Owned by %s", owner))
+	}
+	if o.SlackChannel != "" {
+		details = append(details, c.Sprintf("channel %s", o.SlackChannel))
+	}
+	if o.PagerService != "" {
+		details = append(details, c.Sprintf("paging %s", o.PagerService))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return capitalize(strings.Join(details, ", "))
+}
+
+// capitalize upper-cases the first letter of s, which translations may write with
+// several bytes
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// Field is a value extracted from the object of an event
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// String renders the field as "name: value"
+func (f Field) String() string {
+	return fmt.Sprintf("%s: %s", f.Name, f.Value)
+}
+
+// Link is a deep link to a page about the object of an event
+type Link struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// String renders the link as "name: url"
+func (l Link) String() string {
+	return fmt.Sprintf("%s: %s", l.Name, l.URL)
+}
+
+// Description is a condensed kubectl describe of an object, assembled from informer caches
+type Description struct {
+	// Owner is the top-level controller of the object, such as "Deployment/web"
+	Owner      string      `json:"owner,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+	// Events are the most recent Kubernetes Events about the object, newest first
+	Events []RecentEvent `json:"events,omitempty"`
+}
+
+// Condition is a status condition of an object
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// RecentEvent is a Kubernetes Event about a described object
+type RecentEvent struct {
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+	Count   int32  `json:"count,omitempty"`
+}
+
+// String renders the description in the layout of kubectl describe
+func (d Description) String() string {
+	var lines []string
+	if d.Owner != "" {
+		lines = append(lines, "Controlled By: "+d.Owner)
+	}
+	if len(d.Conditions) > 0 {
+		lines = append(lines, "Conditions:")
+		for _, c := range d.Conditions {
+			line := fmt.Sprintf("  %s %s", c.Type, c.Status)
+			if c.Reason != "" {
+				line += " " + c.Reason
+			}
+			if c.Message != "" {
+				line += ": " + c.Message
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(d.Events) > 0 {
+		lines = append(lines, "Events:")
+		for _, e := range d.Events {
+			line := fmt.Sprintf("  %s %s", e.Type, e.Reason)
+			if e.Count > 1 {
+				line += fmt.Sprintf(" (x%d)", e.Count)
+			}
+			if e.Message != "" {
+				line += ": " + e.Message
+			}
+			lines = append(lines, line)
+		}
+	}
+	return fmt.Sprintf("```\n%s\n```", strings.Join(lines, "\n"))
+}
+
+// ContainerLogs are the last log lines of a failing container, redacted and truncated
+type ContainerLogs struct {
+	Container string `json:"container"`
+	// Reason is why the container failed, such as OOMKilled or Error
+	Reason   string `json:"reason,omitempty"`
+	ExitCode int32  `json:"exitCode"`
+	Text     string `json:"text"`
+	// Truncated is set when older lines were dropped to fit the size limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// String renders the logs as a code block, preceded by the container and failure
+func (l ContainerLogs) String() string {
+	return l.text(nil)
+}
+
+func (l ContainerLogs) text(c *Catalog) string {
+	failure := c.Sprintf("exit code %d", l.ExitCode)
+	if l.Reason != "" {
+		failure = l.Reason + ", " + failure
+	}
+	return c.Sprintf("Last logs of `%s` (%s):", l.Container, failure) + fmt.Sprintf("\n```\n%s\n```", strings.TrimRight(l.Text, "\n"))
+}
+
+// InvolvedObject is the object a Kubernetes Event is about, as resolved from informer caches
+type InvolvedObject struct {
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// Owner is the top-level controller of the object, such as "Deployment/web"
+	Owner string `json:"owner,omitempty"`
+	// NamespaceAnnotations are the annotations of the namespace of the object
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+}
+
+// String renders the object as "Involved Kind `name` of Owner"
+func (o InvolvedObject) String() string {
+	return o.text(nil)
+}
+
+func (o InvolvedObject) text(c *Catalog) string {
+	if o.Owner != "" {
+		return c.Sprintf("Involved %s `%s` of %s", o.Kind, o.Name, o.Owner)
+	}
+	return c.Sprintf("Involved %s `%s`", o.Kind, o.Name)
+}
+
+// ImageChange is a container image changed by a workload update.
+// Old is empty for added containers, New is empty for removed containers.
+type ImageChange struct {
+	Container string `json:"container"`
+	Old       string `json:"old,omitempty"`
+	New       string `json:"new,omitempty"`
+}
+
+// String renders the change as "`container` image: old -> new"
+func (c ImageChange) String() string {
+	return c.text(nil)
+}
+
+func (c ImageChange) text(catalog *Catalog) string {
+	switch {
+	case c.Old == "":
+		return catalog.Sprintf("`%s` image: added %s", c.Container, c.New)
+	case c.New == "":
+		return catalog.Sprintf("`%s` image: removed %s", c.Container, c.Old)
+	}
+	return catalog.Sprintf("`%s` image: %s -> %s", c.Container, c.Old, c.New)
+}
+
+// ScaleChange is a replica count change of a workload
+type ScaleChange struct {
+	From int32 `json:"from"`
+	To   int32 `json:"to"`
+	// Autoscaled is set when the change was made by a HorizontalPodAutoscaler
+	Autoscaled bool `json:"autoscaled"`
+	// Manager is the field manager that made the change, such as kubectl
+	Manager string `json:"manager,omitempty"`
+}
+
+// String renders the change as "scaled from X to Y by ..."
+func (s ScaleChange) String() string {
+	return s.text(nil)
+}
+
+func (s ScaleChange) text(c *Catalog) string {
+	switch {
+	case s.Autoscaled:
+		return c.Sprintf("scaled from %d to %d by the HorizontalPodAutoscaler", s.From, s.To)
+	case s.Manager != "":
+		return c.Sprintf("scaled from %d to %d manually (%s)", s.From, s.To, s.Manager)
+	}
+	return c.Sprintf("scaled from %d to %d", s.From, s.To)
+}
+
+// JobRun describes the run of a completed Job
+type JobRun struct {
+	StartTime      time.Time `json:"startTime"`
+	CompletionTime time.Time `json:"completionTime"`
+	// Succeeded and Failed are the numbers of pods of the Job that succeeded and failed
+	Succeeded int32 `json:"succeeded"`
+	Failed    int32 `json:"failed"`
+}
+
+// Duration returns the time the Job took to complete
+func (r JobRun) Duration() time.Duration {
+	return r.CompletionTime.Sub(r.StartTime)
+}
+
+// String renders the run as "completed in 4m12s, 3 pods succeeded, 1 pod failed"
+func (r JobRun) String() string {
+	return r.text(nil)
+}
+
+func (r JobRun) text(c *Catalog) string {
+	return c.Sprintf("completed in %s, %s succeeded, %s failed",
+		duration.HumanDuration(r.Duration()), pods(c, r.Succeeded), pods(c, r.Failed))
+}
+
+// Resolution describes a problem that cleared, such as a pod no longer in CrashLoopBackOff
+type Resolution struct {
+	// Problem names the problem, such as CrashLoopBackOff, NodeNotReady or RolloutFailed
+	Problem string `json:"problem"`
+	// ProblemID is the ID of the notification that reported the problem
+	ProblemID  string    `json:"problemId,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+// Duration returns how long the problem lasted
+func (r Resolution) Duration() time.Duration {
+	return r.ResolvedAt.Sub(r.StartedAt)
+}
+
+// String renders the resolution as "CrashLoopBackOff resolved after 12m"
+func (r Resolution) String() string {
+	return r.text(nil)
+}
+
+func (r Resolution) text(c *Catalog) string {
+	return c.Sprintf("%s resolved after %s", r.Problem, duration.HumanDuration(r.Duration()))
+}
+
+// pods renders a number of pods, such as "1 pod" or "3 pods"
+func pods(c *Catalog, n int32) string {
+	if n == 1 {
+		return c.T("1 pod")
+	}
+	return c.Sprintf("%d pods", n)
 }
 
 var m = map[string]string{
@@ -39,60 +425,3 @@ var m = map[string]string{
 	"deleted": "Danger",
 	"updated": "Warning",
 }
-
-// Message returns event message in standard format.
-// included as a part of event packege to enhance code resuablity across handlers.
-func (e *Event) Message() (msg string) {
-	// using switch over if..else, since the format could vary based on the kind of the object in future.
-	switch e.Kind {
-	case "namespace":
-		msg = fmt.Sprintf(
-			"A namespace `%s` has been `%s`",
-			e.Name,
-			e.Reason,
-		)
-	case "node":
-		msg = fmt.Sprintf(
-			"A node `%s` has been `%s`",
-			e.Name,
-			e.Reason,
-		)
-	case "cluster role":
-		msg = fmt.Sprintf(
-			"A cluster role `%s` has been `%s`",
-			e.Name,
-			e.Reason,
-		)
-	case "NodeReady":
-		msg = fmt.Sprintf(
-			"Node `%s` is Ready : \nNodeReady",
-			e.Name,
-		)
-	case "NodeNotReady":
-		msg = fmt.Sprintf(
-			"Node `%s` is Not Ready : \nNodeNotReady",
-			e.Name,
-		)
-	case "NodeRebooted":
-		msg = fmt.Sprintf(
-			"Node `%s` Rebooted : \nNodeRebooted",
-			e.Name,
-		)
-	case "Backoff":
-		msg = fmt.Sprintf(
-			"Pod `%s` in `%s` Crashed : \nCrashLoopBackOff %s",
-			e.Name,
-			e.Namespace,
-			e.Reason,
-		)
-	default:
-		msg = fmt.Sprintf(
-			"A `%s` in namespace `%s` has been `%s`:\n`%s`",
-			e.Kind,
-			e.Namespace,
-			e.Reason,
-			e.Name,
-		)
-	}
-	return msg
-}
@@ -0,0 +1,483 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// shortDigest is the number of digest characters kept in summaries
+const shortDigest = 12
+
+// Message returns event message in standard format.
+// included as a part of event packege to enhance code resuablity across handlers.
+func (e *Event) Message() string {
+	lines := append([]string{e.headline()}, e.details()...)
+	return e.withPrefix(e.withCluster(strings.Join(lines, "\n")))
+}
+
+// Summary returns a one-line human sentence about the event, such as
+// "Deployment payments/api updated: image v1.2→v1.3, replicas 3→5", used as title or subject
+func (e *Event) Summary() string {
+	c := e.Catalog
+	subject := e.Kind
+	if name := e.objectName(); name != "" {
+		subject += " " + name
+	}
+
+	var summary string
+	switch e.Operation() {
+	case "notify":
+		reason, _, _ := strings.Cut(strings.TrimSpace(e.Reason), "\n")
+		return e.withPrefix(e.withCluster(subject + ": " + reason))
+	case "scale":
+		summary = c.Sprintf("%s scaled", subject)
+	default:
+		summary = c.Sprintf("%s %s", subject, c.T(strings.ToLower(e.Reason)))
+	}
+	if highlights := e.highlights(); len(highlights) > 0 {
+		summary += ": " + strings.Join(highlights, ", ")
+	}
+	return e.withPrefix(e.withCluster(summary))
+}
+
+// gitOpsResources names the GitOps and KEDA resources whose health changes are reported,
+// by kind
+var gitOpsResources = map[string]string{
+	"Rollout":       "Argo Rollout",
+	"Application":   "Argo CD Application",
+	"Kustomization": "Flux Kustomization",
+	"HelmRelease":   "Flux HelmRelease",
+	"ScaledObject":  "KEDA ScaledObject",
+}
+
+// karpenterChanges describes the changes of Karpenter resources, by kind of event
+var karpenterChanges = map[string]string{
+	"NodeClaimProvisioned":  "provisioned",
+	"NodeClaimLaunchFailed": "failed to launch",
+	"NodeClaimDisrupting":   "disrupting",
+	"NodeClaimTerminating":  "terminating",
+	"NodeClaimRemoved":      "removed",
+	"NodePoolNotReady":      "not ready",
+	"NodePoolReady":         "ready again",
+	"NodePoolLimitReached":  "at its limits",
+}
+
+// gitOpsStates describes the health states of GitOps resources
+var gitOpsStates = map[string]string{
+	"Healthy":   "healthy again",
+	"Degraded":  "degraded",
+	"Suspended": "suspended",
+	"OutOfSync": "out of sync",
+}
+
+// gitOpsKind splits the kind of GitOps health events, such as ApplicationOutOfSync,
+// into the described resource and state
+func gitOpsKind(kind string) (resource string, state string, ok bool) {
+	for suffix, description := range gitOpsStates {
+		if name, found := strings.CutSuffix(kind, suffix); found && gitOpsResources[name] != "" {
+			return gitOpsResources[name], description, true
+		}
+	}
+	return "", "", false
+}
+
+// headline returns the first line of the message, depending on the kind of event
+func (e *Event) headline() (msg string) {
+	c := e.Catalog
+	// using switch over if..else, since the format could vary based on the kind of the object in future.
+	switch e.Kind {
+	case "namespace":
+		msg = c.Sprintf(
+			"A namespace `%s` has been `%s`",
+			e.Name,
+			c.T(e.Reason),
+		)
+	case "node":
+		msg = c.Sprintf(
+			"A node `%s` has been `%s`",
+			e.Name,
+			c.T(e.Reason),
+		)
+	case "cluster role":
+		msg = c.Sprintf(
+			"A cluster role `%s` has been `%s`",
+			e.Name,
+			c.T(e.Reason),
+		)
+	case "NodeReady":
+		msg = c.Sprintf(
+			"Node `%s` is Ready : \nNodeReady",
+			e.Name,
+		)
+	case "NodeNotReady":
+		msg = c.Sprintf(
+			"Node `%s` is Not Ready : \nNodeNotReady",
+			e.Name,
+		)
+	case "NodeRebooted":
+		msg = c.Sprintf(
+			"Node `%s` Rebooted : \nNodeRebooted",
+			e.Name,
+		)
+	case "ClusterAutoscaler":
+		msg = c.Sprintf(
+			"Cluster autoscaler node group `%s` : \n%s",
+			e.Name,
+			e.Reason,
+		)
+	case "NodeInterruption":
+		msg = c.Sprintf(
+			"Node `%s` about to be interrupted : \n%s",
+			e.Name,
+			e.Reason,
+		)
+	case "NodeCordoned", "NodeUncordoned", "NodeDraining", "NodeDeleted":
+		msg = c.Sprintf(
+			"Node `%s` %s : \n%s",
+			e.Name,
+			c.T(strings.ToLower(strings.TrimPrefix(e.Kind, "Node"))),
+			e.Reason,
+		)
+	case "Backoff":
+		msg = c.Sprintf(
+			"Pod `%s` in `%s` Crashed : \nCrashLoopBackOff %s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "CertificateNotReady":
+		msg = c.Sprintf(
+			"Certificate `%s` in `%s` is Not Ready : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "CertificateExpiring":
+		msg = c.Sprintf(
+			"Certificate `%s` in `%s` is expiring : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "Rollout":
+		msg = c.Sprintf(
+			"Deployment `%s` in `%s` rollout : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "CronJobSuspended", "CronJobResumed":
+		msg = c.Sprintf(
+			"CronJob `%s` in `%s` %s : \n%s",
+			e.Name,
+			e.Namespace,
+			c.T(strings.ToLower(strings.TrimPrefix(e.Kind, "CronJob"))),
+			e.Reason,
+		)
+	case "ImagePolicyViolation":
+		msg = c.Sprintf(
+			"Image policy violation in `%s` : \n%s",
+			e.Namespace,
+			e.Reason,
+		)
+	case "PodSecurityViolation":
+		msg = c.Sprintf(
+			"Pod security violation in `%s` : \n%s",
+			e.Namespace,
+			e.Reason,
+		)
+	case "SlowStart":
+		msg = c.Sprintf(
+			"Pod `%s` in `%s` slow to start : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "PodEvicted", "PodPreempted":
+		msg = c.Sprintf(
+			"Pod `%s` in `%s` %s : \n%s",
+			e.Name,
+			e.Namespace,
+			c.T(strings.ToLower(strings.TrimPrefix(e.Kind, "Pod"))),
+			e.Reason,
+		)
+	case "FailedMount", "FailedAttachVolume":
+		msg = c.Sprintf(
+			"Pod `%s` in `%s` volume failure : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "HelmRelease":
+		msg = c.Sprintf(
+			"Helm release `%s` in `%s` : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "DeprecatedAPI":
+		msg = c.Sprintf(
+			"Deprecated API `%s` in use : \n%s",
+			e.APIVersion,
+			e.Reason,
+		)
+	case "VersionSkew":
+		msg = c.Sprintf(
+			"Node `%s` kubelet version skew : \n%s",
+			e.Name,
+			e.Reason,
+		)
+	case "NodePressure":
+		msg = c.Sprintf(
+			"Node pressure : \n%s",
+			e.Reason,
+		)
+	case "Heartbeat":
+		msg = c.Sprintf(
+			"Kubewatch heartbeat : \n%s",
+			e.Reason,
+		)
+	case "EventStorm":
+		msg = c.Sprintf(
+			"Event storm : \n%s",
+			e.Reason,
+		)
+	case "KubewatchError":
+		msg = c.Sprintf(
+			"Kubewatch is failing : \n%s",
+			e.Reason,
+		)
+	case "LatencySLO":
+		msg = c.Sprintf(
+			"Kubewatch delivers `%s` events late : \n%s",
+			e.Name,
+			e.Reason,
+		)
+	case "Alert":
+		msg = c.Sprintf(
+			"Alert `%s` : \n%s",
+			e.Name,
+			e.Reason,
+		)
+	case "Resolved":
+		msg = c.Sprintf(
+			"`%s` resolved : \n%s",
+			e.objectName(),
+			e.Reason,
+		)
+	case "AccessDenied":
+		msg = c.Sprintf(
+			"Kubewatch is not allowed to watch `%s` : \n%s",
+			e.Name,
+			e.Reason,
+		)
+	case "OOMRisk":
+		msg = c.Sprintf(
+			"Pod `%s` in `%s` at risk of OOM : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "VPADrift":
+		msg = c.Sprintf(
+			"VerticalPodAutoscaler `%s` in `%s` recommends resizing : \n%s",
+			e.Name,
+			e.Namespace,
+			e.Reason,
+		)
+	case "NodeClaimProvisioned", "NodeClaimLaunchFailed", "NodeClaimDisrupting", "NodeClaimTerminating", "NodeClaimRemoved":
+		msg = c.Sprintf(
+			"Karpenter NodeClaim `%s` %s : \n%s",
+			e.Name,
+			c.T(karpenterChanges[e.Kind]),
+			e.Reason,
+		)
+	case "NodePoolNotReady", "NodePoolReady", "NodePoolLimitReached":
+		msg = c.Sprintf(
+			"Karpenter NodePool `%s` %s : \n%s",
+			e.Name,
+			c.T(karpenterChanges[e.Kind]),
+			e.Reason,
+		)
+	default:
+		if resource, state, ok := gitOpsKind(e.Kind); ok {
+			return c.Sprintf(
+				"%s `%s` in `%s` is %s : \n%s",
+				resource,
+				e.Name,
+				e.Namespace,
+				c.T(state),
+				e.Reason,
+			)
+		}
+		msg = c.Sprintf(
+			"A `%s` in namespace `%s` has been `%s`:\n`%s`",
+			e.Kind,
+			e.Namespace,
+			c.T(e.Reason),
+			e.Name,
+		)
+	}
+	return msg
+}
+
+// details returns the lines describing the event after its headline
+func (e *Event) details() []string {
+	c := e.Catalog
+	var lines []string
+	if e.ChangedBy != "" {
+		lines = append(lines, c.Sprintf("Changed by %s", e.ChangedBy))
+	}
+	if e.Involved != nil {
+		lines = append(lines, e.Involved.text(c))
+	}
+	if e.Ownership != nil && e.Ownership.String() != "" {
+		lines = append(lines, e.Ownership.text(c))
+	}
+	if e.Scale != nil {
+		lines = append(lines, e.Scale.text(c))
+	}
+	if e.JobRun != nil {
+		lines = append(lines, c.Sprintf("Job %s", e.JobRun.text(c)))
+	}
+	for _, change := range e.ImageChanges {
+		lines = append(lines, change.text(c))
+	}
+	if len(e.Diff) > 0 {
+		lines = append(lines, diff.Format(e.Diff))
+	}
+	if e.Deletion != nil && e.Deletion.String() != "" {
+		lines = append(lines, e.Deletion.text(c))
+	}
+	if e.Resolution != nil {
+		lines = append(lines, e.Resolution.text(c))
+	}
+	if e.Logs != nil {
+		lines = append(lines, e.Logs.text(c))
+	}
+	if e.Description != nil {
+		lines = append(lines, e.Description.String())
+	}
+	for _, field := range e.Fields {
+		lines = append(lines, field.String())
+	}
+	for _, link := range e.Links {
+		lines = append(lines, link.String())
+	}
+	if len(e.Truncated) > 0 {
+		lines = append(lines, c.Sprintf("_Truncated: %s_", strings.Join(e.Truncated, ", ")))
+	}
+	return lines
+}
+
+// highlights returns the most notable changes of the event, in a few words each
+func (e *Event) highlights() []string {
+	c := e.Catalog
+	var highlights []string
+	for _, change := range e.ImageChanges {
+		highlights = append(highlights, change.summary(c, len(e.ImageChanges) > 1))
+	}
+	if e.Scale != nil {
+		replicas := c.Sprintf("replicas %d→%d", e.Scale.From, e.Scale.To)
+		if e.Scale.Autoscaled {
+			replicas = c.Sprintf("replicas %d→%d (autoscaled)", e.Scale.From, e.Scale.To)
+		}
+		highlights = append(highlights, replicas)
+	}
+	if e.JobRun != nil {
+		highlights = append(highlights, c.Sprintf("completed in %s", duration.HumanDuration(e.JobRun.Duration())))
+	}
+	if e.Logs != nil {
+		failure := c.Sprintf("exit code %d", e.Logs.ExitCode)
+		if e.Logs.Reason != "" {
+			failure = e.Logs.Reason
+		}
+		highlights = append(highlights, c.Sprintf("container %s %s", e.Logs.Container, failure))
+	}
+	if highlights == nil {
+		switch len(e.Diff) {
+		case 0:
+		case 1:
+			highlights = append(highlights, c.Sprintf("%s changed", e.Diff[0].Path))
+		default:
+			highlights = append(highlights, c.Sprintf("%d fields changed", len(e.Diff)))
+		}
+	}
+	return highlights
+}
+
+// objectName returns the name of the object, prefixed by its namespace
+func (e *Event) objectName() string {
+	if e.Namespace == "" || e.Name == "" {
+		return e.Name
+	}
+	return e.Namespace + "/" + e.Name
+}
+
+// withCluster prefixes msg with the name, environment and region of the cluster, if any
+func (e *Event) withCluster(msg string) string {
+	var cluster []string
+	for _, tag := range []string{e.ClusterName, e.Environment, e.Region} {
+		if tag != "" {
+			cluster = append(cluster, tag)
+		}
+	}
+	if len(cluster) > 0 {
+		msg = fmt.Sprintf("[%s] %s", strings.Join(cluster, ", "), msg)
+	}
+	return msg
+}
+
+// summary renders the change in a few words, "image v1.2→v1.3", naming the container
+// when named is set. Only tags or digests are shown for images of a same repository.
+func (c ImageChange) summary(catalog *Catalog, named bool) string {
+	image := catalog.T("image")
+	if named {
+		image = catalog.Sprintf("image %s", c.Container)
+	}
+	switch {
+	case c.Old == "":
+		return catalog.Sprintf("%s %s added", image, c.New)
+	case c.New == "":
+		return catalog.Sprintf("%s %s removed", image, c.Old)
+	}
+
+	old, new := c.Old, c.New
+	oldRepository, oldVersion := splitImage(old)
+	newRepository, newVersion := splitImage(new)
+	if oldRepository == newRepository {
+		old, new = oldVersion, newVersion
+	}
+	return fmt.Sprintf("%s %s→%s", image, old, new)
+}
+
+// splitImage splits an image reference into its repository and tag, or shortened digest
+func splitImage(image string) (string, string) {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		repository, digest := image[:i], image[i+1:]
+		if _, hash, ok := strings.Cut(digest, ":"); ok && len(hash) > shortDigest {
+			digest = hash[:shortDigest]
+		}
+		return repository, digest
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+)
+
+func TestSummary(t *testing.T) {
+	var Tests = []struct {
+		e       Event
+		summary string
+	}{
+		{Event{Kind: "Pod", Namespace: "default", Name: "web", Reason: "Created"}, "Pod default/web created"},
+		{Event{Kind: "Namespace", Name: "payments", Reason: "Deleted"}, "Namespace payments deleted"},
+		{Event{
+			Kind: "Deployment", Namespace: "payments", Name: "api", Reason: "Updated",
+			ImageChanges: []ImageChange{{Container: "api", Old: "registry:5000/payments/api:v1.2", New: "registry:5000/payments/api:v1.3"}},
+			Scale:        &ScaleChange{From: 3, To: 5},
+			Diff:         []diff.Change{{Path: "spec.replicas", Old: 3, New: 5}},
+		}, "Deployment payments/api updated: image v1.2→v1.3, replicas 3→5"},
+		{Event{
+			Kind: "Deployment", Namespace: "payments", Name: "api", Reason: "Updated",
+			ImageChanges: []ImageChange{
+				{Container: "api", Old: "nginx", New: "nginx@sha256:0123456789abcdef0123"},
+				{Container: "proxy", Old: "envoy:1.29", New: "istio/proxyv2:1.21"},
+				{Container: "init", New: "busybox:1.36"},
+			},
+		}, "Deployment payments/api updated: image api latest→0123456789ab, image proxy envoy:1.29→istio/proxyv2:1.21, image init busybox:1.36 added"},
+		{Event{Kind: "Deployment", Namespace: "payments", Name: "api", Reason: "Scaled", Scale: &ScaleChange{From: 2, To: 4, Autoscaled: true}},
+			"Deployment payments/api scaled: replicas 2→4 (autoscaled)"},
+		{Event{Kind: "ConfigMap", Namespace: "payments", Name: "api", Reason: "Updated", Diff: []diff.Change{{Path: "data.mode", Old: "a", New: "b"}}},
+			"ConfigMap payments/api updated: data.mode changed"},
+		{Event{Kind: "ConfigMap", Namespace: "payments", Name: "api", Reason: "Updated", Diff: []diff.Change{{Path: "data.a"}, {Path: "data.b"}}},
+			"ConfigMap payments/api updated: 2 fields changed"},
+		{Event{Kind: "Pod", Namespace: "payments", Name: "api-x2x", Reason: "Updated", Logs: &ContainerLogs{Container: "api", Reason: "OOMKilled", ExitCode: 137}},
+			"Pod payments/api-x2x updated: container api OOMKilled"},
+		{Event{Kind: "Rollout", Namespace: "payments", Name: "api", Reason: "Rollout started for generation 4"},
+			"Rollout payments/api: Rollout started for generation 4"},
+		{Event{Kind: "NodeCordoned", Name: "node-1", Reason: "Node cordoned\nby kubectl", ClusterName: "prod", Region: "eu-west-1"},
+			"[prod, eu-west-1] NodeCordoned node-1: Node cordoned"},
+	}
+
+	for i, test := range Tests {
+		if summary := test.e.Summary(); summary != test.summary {
+			t.Errorf("%d: Summary() = %q, expected %q", i, summary, test.summary)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SchemaVersion is the version of the Payload schema. Fields may be added within a
+// version, renaming or removing fields requires a new version.
+const SchemaVersion = "kubewatch.event/v1"
+
+// JSONSchema is the JSON Schema document describing Payload
+//
+//go:embed schema/v1.json
+var JSONSchema []byte
+
+// Payload is the canonical JSON representation of an event, sent by the structured
+// handlers (webhook, CloudEvent) so that consumers can rely on a stable contract
+type Payload struct {
+	SchemaVersion string `json:"schemaVersion"`
+	ID            string `json:"id,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+	GroupKey      string `json:"groupKey,omitempty"`
+	// Kind is the kind of the object, or of the notification (e.g. Rollout, NodeCordoned)
+	Kind string `json:"kind"`
+	// Operation is one of create, update, delete, scale or notify
+	Operation string `json:"operation"`
+	Reason    string `json:"reason"`
+	// Summary is a one-line sentence about the event, Message has the details
+	Summary string `json:"summary"`
+	Message string `json:"message"`
+	// Language is the language Summary and Message are translated to, empty for English
+	Language  string    `json:"language,omitempty"`
+	Severity  Severity  `json:"severity"`
+	Timestamp time.Time `json:"timestamp"`
+	Object    ObjectRef `json:"object"`
+	// Cluster is omitted for unnamed single-cluster setups without metadata
+	Cluster        *ClusterRef     `json:"cluster,omitempty"`
+	Diff           []diff.Change   `json:"diff,omitempty"`
+	ImageChanges   []ImageChange   `json:"imageChanges,omitempty"`
+	Scale          *ScaleChange    `json:"scale,omitempty"`
+	JobRun         *JobRun         `json:"jobRun,omitempty"`
+	ChangedBy      string          `json:"changedBy,omitempty"`
+	InvolvedObject *InvolvedObject `json:"involvedObject,omitempty"`
+	Ownership      *Ownership      `json:"ownership,omitempty"`
+	Logs           *ContainerLogs  `json:"logs,omitempty"`
+	Description    *Description    `json:"description,omitempty"`
+	Fields         []Field         `json:"fields,omitempty"`
+	Links          []Link          `json:"links,omitempty"`
+	Deletion       *Deletion       `json:"deletion,omitempty"`
+	Resolution     *Resolution     `json:"resolution,omitempty"`
+	Truncated      []string        `json:"truncated,omitempty"`
+}
+
+// ObjectRef identifies the object an event is about
+type ObjectRef struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace,omitempty"`
+	UID        string            `json:"uid,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// ClusterRef describes the cluster an event comes from
+type ClusterRef struct {
+	Name        string            `json:"name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Region      string            `json:"region,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// ResolvedAPIVersion returns the API version of e, APIVersion or the deprecated ApiVersion
+// when only it is set
+func (e *Event) ResolvedAPIVersion() string {
+	if e.APIVersion != "" {
+		return e.APIVersion
+	}
+	return e.ApiVersion
+}
+
+// Operation returns what happened to the object: create, update, delete or scale,
+// and notify for other notifications
+func (e *Event) Operation() string {
+	switch e.Reason {
+	case "Created":
+		return "create"
+	case "Updated":
+		return "update"
+	case "Deleted":
+		return "delete"
+	case "Scaled":
+		return "scale"
+	}
+	return "notify"
+}
+
+// Payload returns the canonical representation of e
+func (e *Event) Payload() Payload {
+	p := Payload{
+		SchemaVersion: SchemaVersion,
+		ID:            e.ID,
+		CorrelationID: e.CorrelationID,
+		GroupKey:      e.GroupKey,
+		Kind:          e.Kind,
+		Operation:     e.Operation(),
+		Reason:        e.Reason,
+		Summary:       e.Summary(),
+		Message:       e.Message(),
+		Severity:      e.ResolvedSeverity(),
+		Timestamp:     e.Timestamp,
+		Object: ObjectRef{
+			APIVersion: e.ResolvedAPIVersion(),
+			Name:       e.Name,
+			Namespace:  e.Namespace,
+			UID:        string(e.UID),
+			Labels:     e.Labels,
+		},
+		Diff:           e.Diff,
+		ImageChanges:   e.ImageChanges,
+		Scale:          e.Scale,
+		JobRun:         e.JobRun,
+		ChangedBy:      e.ChangedBy,
+		InvolvedObject: e.Involved,
+		Ownership:      e.Ownership,
+		Logs:           e.Logs,
+		Description:    e.Description,
+		Fields:         e.Fields,
+		Links:          e.Links,
+		Deletion:       e.Deletion,
+		Resolution:     e.Resolution,
+		Truncated:      e.Truncated,
+	}
+	if e.Catalog != nil {
+		p.Language = e.Catalog.Language
+	}
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now()
+	}
+	if e.ClusterName != "" || e.Environment != "" || e.Region != "" || len(e.ClusterLabels) > 0 {
+		p.Cluster = &ClusterRef{
+			Name:        e.ClusterName,
+			Environment: e.Environment,
+			Region:      e.Region,
+			Labels:      e.ClusterLabels,
+		}
+	}
+	return p
+}
+
+// Event returns the event p represents, for stored events to be sent again. Objects are
+// not part of payloads, the event only has their reference.
+func (p Payload) Event() Event {
+	e := Event{
+		ID:            p.ID,
+		CorrelationID: p.CorrelationID,
+		GroupKey:      p.GroupKey,
+		Kind:          p.Kind,
+		Reason:        p.Reason,
+		Severity:      p.Severity,
+		Timestamp:     p.Timestamp,
+		APIVersion:    p.Object.APIVersion,
+		Name:          p.Object.Name,
+		Namespace:     p.Object.Namespace,
+		UID:           types.UID(p.Object.UID),
+		Labels:        p.Object.Labels,
+		Diff:          p.Diff,
+		ImageChanges:  p.ImageChanges,
+		Scale:         p.Scale,
+		JobRun:        p.JobRun,
+		ChangedBy:     p.ChangedBy,
+		Involved:      p.InvolvedObject,
+		Ownership:     p.Ownership,
+		Logs:          p.Logs,
+		Description:   p.Description,
+		Fields:        p.Fields,
+		Links:         p.Links,
+		Deletion:      p.Deletion,
+		Resolution:    p.Resolution,
+		Truncated:     p.Truncated,
+	}
+	switch p.Severity {
+	case SeverityCritical:
+		e.Status = "Danger"
+	case SeverityWarning, SeverityPolicyViolation:
+		e.Status = "Warning"
+	default:
+		e.Status = "Normal"
+	}
+	if p.Cluster != nil {
+		e.ClusterName = p.Cluster.Name
+		e.Environment = p.Cluster.Environment
+		e.Region = p.Cluster.Region
+		e.ClusterLabels = p.Cluster.Labels
+	}
+	return e
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+)
+
+var update = flag.Bool("update", false, "update the golden files of serialized events")
+
+var observed = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+var goldenEvents = map[string]Event{
+	"created": {
+		Kind: "Pod", APIVersion: "v1", Name: "web-5d8f-x2x", Namespace: "default",
+		UID: "8e6f", Labels: map[string]string{"app": "web"},
+		Reason: "Created", Status: "Normal", Timestamp: observed,
+		Links: []Link{{Name: "Grafana", URL: "https://grafana.example.com/d/pods?var-namespace=default&var-pod=web-5d8f-x2x"}},
+	},
+	"updated": {
+		Kind: "Deployment", APIVersion: "apps/v1", Name: "web", Namespace: "default",
+		Reason: "Updated", Status: "Warning", Timestamp: observed,
+		ClusterName: "prod-eu", Environment: "production", Region: "eu-west-1",
+		ClusterLabels: map[string]string{"team": "platform"},
+		ChangedBy:     "alice@example.com",
+		Diff: []diff.Change{
+			{Path: "metadata.labels.tier", New: "frontend"},
+			{Path: "spec.template.spec.containers[0].image", Old: "nginx:1.25", New: "nginx:1.26"},
+		},
+		ImageChanges: []ImageChange{{Container: "web", Old: "nginx:1.25", New: "nginx:1.26"}},
+	},
+	"scaled": {
+		Kind: "Deployment", APIVersion: "apps/v1", Name: "web", Namespace: "default",
+		ID: "0b7a3c1e-6a53-4b0f-9d43-0c4b1f9e3a11", CorrelationID: "5f1c0c55-3d38-4b8f-8a2e-2f6f8f0e9b42",
+		Reason: "Scaled", Status: "Normal", Timestamp: observed,
+		Scale: &ScaleChange{From: 2, To: 5, Autoscaled: true, Manager: "kube-controller-manager"},
+	},
+	"job-completed": {
+		Kind: "Job", APIVersion: "batch/v1", Name: "nightly-report-28512", Namespace: "default",
+		Reason: "Updated", Status: "Normal", Timestamp: observed,
+		JobRun: &JobRun{StartTime: observed.Add(-252 * time.Second), CompletionTime: observed.Add(-time.Second), Succeeded: 3, Failed: 1},
+	},
+	"kubernetes-event": {
+		Kind: "Event", APIVersion: "v1", Name: "web-5d8f-x2x.17a", Namespace: "default",
+		Reason: "Deleted", Status: "Danger", Timestamp: observed,
+		Involved: &InvolvedObject{
+			Kind: "Pod", Name: "web-5d8f-x2x", Namespace: "default", Owner: "Deployment/web",
+			NamespaceAnnotations: map[string]string{"team": "payments"},
+		},
+	},
+	"crashed": {
+		Kind: "Pod", APIVersion: "v1", Name: "web-5d8f-x2x", Namespace: "default",
+		Reason: "Updated", Status: "Warning", Timestamp: observed,
+		Logs: &ContainerLogs{Container: "web", Reason: "OOMKilled", ExitCode: 137, Text: "loading cache\nallocating 2Gi\n", Truncated: true},
+	},
+	"described": {
+		Kind: "Pod", APIVersion: "v1", Name: "web-5d8f-x2x", Namespace: "default",
+		Reason: "Updated", Status: "Danger", Timestamp: observed,
+		Description: &Description{
+			Owner:      "Deployment/web",
+			Conditions: []Condition{{Type: "Ready", Status: "False", Reason: "ContainersNotReady", Message: "containers with unready status: [web]"}},
+			Events:     []RecentEvent{{Type: "Warning", Reason: "BackOff", Message: "Back-off restarting failed container", Count: 5}},
+		},
+	},
+	"deleted": {
+		Kind: "ConfigMap", APIVersion: "v1", Name: "web-config", Namespace: "default",
+		Reason: "Deleted", Status: "Danger", Timestamp: observed,
+		Deletion: &Deletion{DeletedBy: "alice@example.com", LastManager: "kubectl-edit", FinalStateUnknown: true},
+	},
+	"resolved": {
+		Kind: "Resolved", APIVersion: "v1", Name: "web-5d8f-x2x", Namespace: "default",
+		ID: "3c2d7a58-1f0e-4d8e-b0a4-8e1f6c2b9d10", CorrelationID: "8e6f",
+		Reason: "Pod is running again", Status: "Normal", Timestamp: observed,
+		Resolution: &Resolution{
+			Problem: "CrashLoopBackOff", ProblemID: "0b7a3c1e-6a53-4b0f-9d43-0c4b1f9e3a11",
+			StartedAt: observed.Add(-12 * time.Minute), ResolvedAt: observed,
+		},
+	},
+	"custom-resource": {
+		Kind: "Application", APIVersion: "argoproj.io/v1alpha1", Name: "guestbook", Namespace: "apps",
+		Reason: "Updated", Status: "Normal", Timestamp: observed,
+		Fields: []Field{{Name: "Revision", Value: "4f2a9c1"}, {Name: "Health", Value: "Healthy"}},
+	},
+	"owned": {
+		Kind: "Pod", APIVersion: "v1", Name: "api-7d9f", Namespace: "checkout",
+		Reason: "Updated", Status: "Danger", Timestamp: observed,
+		Ownership: &Ownership{Team: "payments", Owner: "alice@example.com", SlackChannel: "#payments", PagerService: "payments-api"},
+	},
+	"rollout": {
+		Kind: "Rollout", APIVersion: "apps/v1", Name: "web", Namespace: "default",
+		Reason: "Rollout of revision 3 failed: deadline exceeded", Status: "Danger", Timestamp: observed,
+	},
+}
+
+func TestPayloadGolden(t *testing.T) {
+	for name, e := range goldenEvents {
+		got, err := json.MarshalIndent(e.Payload(), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, '\n')
+
+		path := filepath.Join("testdata", name+".json")
+		if *update {
+			if err := os.WriteFile(path, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v (run go test -update to create it)", name, err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s: serialized event changed, update the schema version if the change is not backward compatible:\n%s", name, got)
+		}
+	}
+}
+
+// TestPayloadEvent checks stored payloads are sent again as they were first sent
+func TestPayloadEvent(t *testing.T) {
+	for name, e := range goldenEvents {
+		p := e.Payload()
+		e := p.Event()
+		if replayed := e.Payload(); !reflect.DeepEqual(replayed, p) {
+			t.Errorf("%s: expected the payload of the event to be unchanged, got\n%+v\ninstead of\n%+v", name, replayed, p)
+		}
+	}
+}
+
+// TestPayloadMatchesSchema checks every serialized field is declared in the JSON Schema
+func TestPayloadMatchesSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		t.Fatalf("invalid JSON schema: %v", err)
+	}
+	if version := schema["properties"].(map[string]interface{})["schemaVersion"].(map[string]interface{})["const"]; version != SchemaVersion {
+		t.Errorf("JSON schema is for version %v, expected %s", version, SchemaVersion)
+	}
+
+	for name, e := range goldenEvents {
+		b, err := json.Marshal(e.Payload())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Fatal(err)
+		}
+		checkDeclared(t, name, "", payload, schema)
+	}
+}
+
+func checkDeclared(t *testing.T, name, path string, value map[string]interface{}, schema map[string]interface{}) {
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key, v := range value {
+		property, ok := properties[key].(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: field %s%s is not declared in the JSON schema", name, path, key)
+			continue
+		}
+		if items, ok := property["items"].(map[string]interface{}); ok {
+			for _, item := range v.([]interface{}) {
+				checkDeclared(t, name, path+key+"[].", item.(map[string]interface{}), items)
+			}
+		}
+		if object, ok := v.(map[string]interface{}); ok {
+			checkDeclared(t, name, path+key+".", object, property)
+		}
+	}
+}
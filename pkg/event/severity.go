@@ -0,0 +1,94 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+// Severity is how urgent an event is, rendered the same way by every handler
+type Severity string
+
+// Severities of events, from the least to the most urgent. Policy violations are objects
+// breaking a configured policy, such as the image policy.
+const (
+	SeverityInfo            Severity = "info"
+	SeverityWarning         Severity = "warning"
+	SeverityPolicyViolation Severity = "policy-violation"
+	SeverityCritical        Severity = "critical"
+)
+
+// severityRanks orders the severities, from the least to the most urgent
+var severityRanks = map[Severity]int{
+	SeverityInfo:            0,
+	SeverityWarning:         1,
+	SeverityPolicyViolation: 2,
+	SeverityCritical:        3,
+}
+
+var severityColors = map[Severity]string{
+	SeverityInfo:            "#2EB886",
+	SeverityWarning:         "#DAA038",
+	SeverityCritical:        "#A30200",
+	SeverityPolicyViolation: "#7D3C98",
+}
+
+var severityEmojis = map[Severity]string{
+	SeverityInfo:            "✅",
+	SeverityWarning:         "⚠️",
+	SeverityCritical:        "🚨",
+	SeverityPolicyViolation: "🛡️",
+}
+
+// SeverityFromStatus maps the Normal, Warning and Danger statuses to severities
+func SeverityFromStatus(status string) Severity {
+	switch status {
+	case "Danger":
+		return SeverityCritical
+	case "Warning":
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// Color returns the hex color of the severity, as "#RRGGBB"
+func (s Severity) Color() string {
+	if color, ok := severityColors[s]; ok {
+		return color
+	}
+	return severityColors[SeverityInfo]
+}
+
+// Emoji returns the emoji prefixing messages of text-only handlers
+func (s Severity) Emoji() string {
+	if emoji, ok := severityEmojis[s]; ok {
+		return emoji
+	}
+	return severityEmojis[SeverityInfo]
+}
+
+// Known returns whether s is one of the severities
+func (s Severity) Known() bool {
+	_, ok := severityRanks[s]
+	return ok
+}
+
+// AtLeast returns whether s is as urgent as min or more, unknown severities ranking as info
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRanks[s] >= severityRanks[min]
+}
+
+// ResolvedSeverity returns the severity of e, derived from its status when not set
+func (e *Event) ResolvedSeverity() Severity {
+	if e.Severity != "" {
+		return e.Severity
+	}
+	return SeverityFromStatus(e.Status)
+}
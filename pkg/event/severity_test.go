@@ -0,0 +1,63 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import "testing"
+
+func TestResolvedSeverity(t *testing.T) {
+	var Tests = []struct {
+		event    Event
+		severity Severity
+		color    string
+	}{
+		{Event{Status: "Normal"}, SeverityInfo, "#2EB886"},
+		{Event{Status: "Warning"}, SeverityWarning, "#DAA038"},
+		{Event{Status: "Danger"}, SeverityCritical, "#A30200"},
+		{Event{}, SeverityInfo, "#2EB886"},
+		{Event{Status: "Normal", Severity: SeverityCritical}, SeverityCritical, "#A30200"},
+	}
+
+	for _, test := range Tests {
+		severity := test.event.ResolvedSeverity()
+		if severity != test.severity {
+			t.Errorf("ResolvedSeverity() of %+v = %q, expected %q", test.event, severity, test.severity)
+		}
+		if color := severity.Color(); color != test.color {
+			t.Errorf("Color() of %q = %q, expected %q", severity, color, test.color)
+		}
+	}
+
+	if color := Severity("unknown").Color(); color != SeverityInfo.Color() {
+		t.Errorf("expected unknown severities to render as info, got %q", color)
+	}
+}
+
+func TestSeverityAtLeast(t *testing.T) {
+	var Tests = []struct {
+		severity, min Severity
+		atLeast       bool
+	}{
+		{SeverityCritical, SeverityCritical, true},
+		{SeverityPolicyViolation, SeverityCritical, false},
+		{SeverityCritical, SeverityWarning, true},
+		{SeverityInfo, SeverityWarning, false},
+		{Severity("unknown"), SeverityInfo, true},
+	}
+
+	for _, test := range Tests {
+		if atLeast := test.severity.AtLeast(test.min); atLeast != test.atLeast {
+			t.Errorf("%q.AtLeast(%q) = %v, expected %v", test.severity, test.min, atLeast, test.atLeast)
+		}
+	}
+}
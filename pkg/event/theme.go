@@ -0,0 +1,170 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// hexColor matches the colors of themes, as "#RRGGBB"
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// operations are the operations of events, see Operation
+var operations = []string{"create", "update", "delete", "scale", "notify"}
+
+// Theme customizes how every handler renders events: the emoji prefixing the messages of
+// text-only handlers and the colors of messages, by severity, and the prefixes of messages
+// and summaries, by severity and by operation
+type Theme struct {
+	Name   string
+	emoji  map[Severity]string
+	colors map[Severity]string
+	// prefixes are keyed by severity or operation
+	prefixes map[string]string
+}
+
+// themes are the builtin themes: default, with an emoji and a color per severity, and
+// minimal, without emoji, messages starting with their severity such as "[CRITICAL]"
+var themes = map[string]Theme{
+	"default": {emoji: severityEmojis, colors: severityColors},
+	"minimal": {
+		colors: severityColors,
+		prefixes: map[string]string{
+			string(SeverityInfo):            "[INFO]",
+			string(SeverityWarning):         "[WARNING]",
+			string(SeverityPolicyViolation): "[POLICY VIOLATION]",
+			string(SeverityCritical):        "[CRITICAL]",
+		},
+	},
+}
+
+// Themes returns the names of the builtin themes
+func Themes() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTheme returns the builtin theme called name, default when empty, with its emoji,
+// colors and prefixes overridden by those given. Empty values remove the emoji or prefix
+// of the builtin theme.
+func NewTheme(name string, emoji, colors, prefixes map[string]string) (*Theme, error) {
+	if name == "" {
+		name = "default"
+	}
+	base, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(Themes(), ", "))
+	}
+	t := &Theme{
+		Name:     name,
+		emoji:    map[Severity]string{},
+		colors:   map[Severity]string{},
+		prefixes: map[string]string{},
+	}
+	for severity, e := range base.emoji {
+		t.emoji[severity] = e
+	}
+	for severity, color := range base.colors {
+		t.colors[severity] = color
+	}
+	for key, prefix := range base.prefixes {
+		t.prefixes[key] = prefix
+	}
+
+	for severity, e := range emoji {
+		if !Severity(severity).Known() {
+			return nil, fmt.Errorf("emoji of unknown severity %q", severity)
+		}
+		t.emoji[Severity(severity)] = e
+	}
+	for severity, color := range colors {
+		if !Severity(severity).Known() {
+			return nil, fmt.Errorf("color of unknown severity %q", severity)
+		}
+		if !hexColor.MatchString(color) {
+			return nil, fmt.Errorf("color of %s: expected #RRGGBB, got %q", severity, color)
+		}
+		t.colors[Severity(severity)] = color
+	}
+	for key, prefix := range prefixes {
+		if !Severity(key).Known() && !knownOperation(key) {
+			return nil, fmt.Errorf("prefix of %q, expected a severity or an operation", key)
+		}
+		t.prefixes[key] = prefix
+	}
+	return t, nil
+}
+
+// knownOperation returns whether op is one of the operations of events
+func knownOperation(op string) bool {
+	for _, known := range operations {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+// theme returns the theme of e, the default one when not set
+func (e *Event) theme() *Theme {
+	if e.Theme != nil {
+		return e.Theme
+	}
+	t := themes["default"]
+	return &t
+}
+
+// Emoji returns the emoji of the severity of e, prefixing the messages of text-only
+// handlers, empty when the theme has none
+func (e *Event) Emoji() string {
+	t := e.theme()
+	if emoji, ok := t.emoji[e.ResolvedSeverity()]; ok {
+		return emoji
+	}
+	return t.emoji[SeverityInfo]
+}
+
+// Color returns the color of the severity of e, as "#RRGGBB"
+func (e *Event) Color() string {
+	t := e.theme()
+	if color, ok := t.colors[e.ResolvedSeverity()]; ok {
+		return color
+	}
+	if color, ok := t.colors[SeverityInfo]; ok {
+		return color
+	}
+	return severityColors[SeverityInfo]
+}
+
+// withPrefix prefixes msg with the prefixes of the severity and operation of e, if any
+func (e *Event) withPrefix(msg string) string {
+	t := e.theme()
+	var prefixes []string
+	for _, key := range []string{string(e.ResolvedSeverity()), e.Operation()} {
+		if prefix := t.prefixes[key]; prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return msg
+	}
+	return strings.Join(prefixes, " ") + " " + msg
+}
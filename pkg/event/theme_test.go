@@ -0,0 +1,81 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import "testing"
+
+func TestTheme(t *testing.T) {
+	minimal, err := NewTheme("minimal", nil, map[string]string{"critical": "#FF0000"}, map[string]string{"delete": "[DELETED]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := NewTheme("", map[string]string{"critical": "🔥", "info": ""}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := Event{Kind: "Pod", Namespace: "default", Name: "web", Reason: "Deleted", Status: "Danger"}
+	created := Event{Kind: "Pod", Namespace: "default", Name: "web", Reason: "Created", Status: "Normal"}
+
+	var Tests = []struct {
+		theme   *Theme
+		event   Event
+		emoji   string
+		color   string
+		summary string
+	}{
+		{nil, deleted, "🚨", "#A30200", "Pod default/web deleted"},
+		{minimal, deleted, "", "#FF0000", "[CRITICAL] [DELETED] Pod default/web deleted"},
+		{minimal, created, "", "#2EB886", "[INFO] Pod default/web created"},
+		{custom, deleted, "🔥", "#A30200", "Pod default/web deleted"},
+		{custom, created, "", "#2EB886", "Pod default/web created"},
+	}
+
+	for i, tt := range Tests {
+		e := tt.event
+		e.Theme = tt.theme
+		if emoji := e.Emoji(); emoji != tt.emoji {
+			t.Errorf("%d: Emoji() = %q, expected %q", i, emoji, tt.emoji)
+		}
+		if color := e.Color(); color != tt.color {
+			t.Errorf("%d: Color() = %q, expected %q", i, color, tt.color)
+		}
+		if summary := e.Summary(); summary != tt.summary {
+			t.Errorf("%d: Summary() = %q, expected %q", i, summary, tt.summary)
+		}
+	}
+
+	e := created
+	e.Theme = minimal
+	if msg, expected := e.Message(), "[INFO] A `Pod` in namespace `default` has been `Created`:\n`web`"; msg != expected {
+		t.Errorf("Message() = %q, expected %q", msg, expected)
+	}
+}
+
+func TestNewThemeErrors(t *testing.T) {
+	var Tests = []struct {
+		name                    string
+		emoji, colors, prefixes map[string]string
+	}{
+		{"plain", nil, nil, nil},
+		{"", map[string]string{"urgent": "!"}, nil, nil},
+		{"", nil, map[string]string{"critical": "red"}, nil},
+		{"", nil, nil, map[string]string{"Deleted": "x"}},
+	}
+
+	for i, tt := range Tests {
+		if _, err := NewTheme(tt.name, tt.emoji, tt.colors, tt.prefixes); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// truncatedValue is the length truncated diff values are cut to
+const truncatedValue = 80
+
+// truncatedChanges is the number of changes kept in truncated diffs
+const truncatedChanges = 10
+
+// truncatedReason is the length truncated reasons are cut to
+const truncatedReason = 500
+
+// truncatedLogs is the length of the last log lines kept in truncated logs
+const truncatedLogs = 1000
+
+// Size returns the size of e when serialized, as the JSON of its payload and objects sent
+// by structured handlers
+func (e *Event) Size() int {
+	b, err := json.Marshal(struct {
+		Payload Payload        `json:"payload"`
+		Obj     runtime.Object `json:"obj,omitempty"`
+		OldObj  runtime.Object `json:"oldObj,omitempty"`
+	}{e.Payload(), e.Obj, e.OldObj})
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// MessageSize returns the size of the message rendered for e, as sent by chat handlers
+func (e *Event) MessageSize() int {
+	return len(e.Message())
+}
+
+// Truncate shrinks e until its size, as measured by size, is at most maxBytes, dropping
+// the largest details first and keeping what identifies the change: objects, then older
+// log lines, the description, diff values, changes beyond the first ones, and finally the
+// end of the reason. Details whose removal does not make e smaller, such as objects for
+// rendered messages, are kept. The dropped details are listed in Truncated. Shared slices
+// are never modified. It returns false when e is still too large.
+func (e *Event) Truncate(maxBytes int, size func(*Event) int) bool {
+	steps := []func(){
+		func() {
+			if e.OldObj != nil {
+				e.OldObj = nil
+				e.Truncated = append(e.Truncated, "previous object")
+			}
+		},
+		func() {
+			if e.Obj != nil {
+				e.Obj = nil
+				e.Truncated = append(e.Truncated, "object")
+			}
+		},
+		func() {
+			if e.Logs != nil && len(e.Logs.Text) > truncatedLogs {
+				logs := *e.Logs
+				logs.Text, logs.Truncated = lastLines(logs.Text, truncatedLogs), true
+				e.Logs = &logs
+				e.Truncated = append(e.Truncated, "older log lines")
+			}
+		},
+		func() {
+			if e.Description != nil {
+				e.Description = nil
+				e.Truncated = append(e.Truncated, "description")
+			}
+		},
+		func() {
+			if changes, ok := truncateValues(e.Diff); ok {
+				e.Diff = changes
+				e.Truncated = append(e.Truncated, "diff values")
+			}
+		},
+		func() {
+			if len(e.Diff) > truncatedChanges {
+				e.Truncated = append(e.Truncated, fmt.Sprintf("%d of %d changes", len(e.Diff)-truncatedChanges, len(e.Diff)))
+				e.Diff = e.Diff[:truncatedChanges:truncatedChanges]
+			}
+		},
+		func() {
+			if len(e.Reason) > truncatedReason {
+				e.Reason = strings.ToValidUTF8(e.Reason[:truncatedReason], "") + "..."
+				e.Truncated = append(e.Truncated, "reason")
+			}
+		},
+	}
+
+	for _, step := range steps {
+		current := size(e)
+		if current <= maxBytes {
+			return true
+		}
+		before := *e
+		step()
+		if size(e) >= current {
+			*e = before
+		}
+	}
+	return size(e) <= maxBytes
+}
+
+// lastLines returns the last whole lines of text fitting in n bytes
+func lastLines(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	text = text[len(text)-n:]
+	if i := strings.IndexByte(text, '\n'); i >= 0 && i < len(text)-1 {
+		return text[i+1:]
+	}
+	return strings.ToValidUTF8(text, "")
+}
+
+// truncateValues returns a copy of changes with long values cut, false when none is long
+func truncateValues(changes []diff.Change) ([]diff.Change, bool) {
+	var truncated []diff.Change
+	for i, change := range changes {
+		old, oldCut := truncateValue(change.Old)
+		new, newCut := truncateValue(change.New)
+		if !oldCut && !newCut {
+			continue
+		}
+		if truncated == nil {
+			truncated = append([]diff.Change{}, changes...)
+		}
+		truncated[i] = diff.Change{Path: change.Path, Old: old, New: new}
+	}
+	return truncated, truncated != nil
+}
+
+// truncateValue cuts value when it is longer than truncatedValue once serialized
+func truncateValue(value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	s, ok := value.(string)
+	if !ok {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return value, false
+		}
+		s = string(b)
+	}
+	if len(s) <= truncatedValue {
+		return value, false
+	}
+	return strings.ToValidUTF8(s[:truncatedValue], "") + "...", true
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTruncate(t *testing.T) {
+	large := strings.Repeat("x", 4000)
+	logs := strings.Repeat("panic: runtime error\n", 200)
+	changes := make([]diff.Change, 30)
+	for i := range changes {
+		changes[i] = diff.Change{Path: "spec.template.metadata.annotations.checksum" + strings.Repeat("/", i), Old: large[:200], New: large[:300]}
+	}
+	newEvent := func() Event {
+		return Event{
+			Kind: "ConfigMap", Namespace: "payments", Name: "settings", Reason: "Updated", Status: "Normal",
+			Obj:    &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "settings"}, Data: map[string]string{"large": large}},
+			OldObj: &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "settings"}, Data: map[string]string{"large": large}},
+			Diff:   changes,
+			Logs:   &ContainerLogs{Container: "app", ExitCode: 2, Text: logs},
+		}
+	}
+
+	var Tests = []struct {
+		maxBytes  int
+		size      func(*Event) int
+		fits      bool
+		truncated []string
+	}{
+		{1 << 20, (*Event).Size, true, nil},
+		{30000, (*Event).Size, true, []string{"previous object", "object"}},
+		{15000, (*Event).Size, true, []string{"previous object", "object", "older log lines", "diff values"}},
+		{10000, (*Event).Size, true, []string{"previous object", "object", "older log lines", "diff values", "20 of 30 changes"}},
+		{1000, (*Event).Size, false, []string{"previous object", "object", "older log lines", "diff values", "20 of 30 changes"}},
+		// objects are not rendered in messages, they are kept
+		{10000, (*Event).MessageSize, true, nil},
+		{5000, (*Event).MessageSize, true, []string{"older log lines"}},
+		{1000, (*Event).MessageSize, false, []string{"older log lines", "20 of 30 changes"}},
+	}
+
+	for _, tt := range Tests {
+		e := newEvent()
+		if fits := e.Truncate(tt.maxBytes, tt.size); fits != tt.fits {
+			t.Errorf("Truncate(%d) = %v, expected %v", tt.maxBytes, fits, tt.fits)
+		}
+		if strings.Join(e.Truncated, ",") != strings.Join(tt.truncated, ",") {
+			t.Errorf("Truncate(%d) dropped %q, expected %q", tt.maxBytes, e.Truncated, tt.truncated)
+		}
+		if tt.fits && tt.size(&e) > tt.maxBytes {
+			t.Errorf("Truncate(%d) left %d bytes", tt.maxBytes, tt.size(&e))
+		}
+		if e.Name != "settings" || e.Reason != "Updated" {
+			t.Errorf("Truncate(%d) changed the identity of the event: %s %s", tt.maxBytes, e.Name, e.Reason)
+		}
+	}
+
+	// the slices of the original event are not modified
+	e := newEvent()
+	e.Truncate(10000, (*Event).Size)
+	if changes[0].New != large[:300] {
+		t.Error("Truncate() modified the diff of the original event")
+	}
+	if !strings.HasPrefix(e.Logs.Text, "panic") || len(e.Logs.Text) > truncatedLogs || !e.Logs.Truncated {
+		t.Errorf("Truncate() kept logs %q, expected the last whole lines", e.Logs.Text)
+	}
+	if !strings.Contains(e.Message(), "_Truncated: previous object, object") {
+		t.Errorf("Message() does not mention the truncation:\n%s", e.Message())
+	}
+}
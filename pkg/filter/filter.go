@@ -28,25 +28,19 @@ import (
 	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
 	events_v1 "k8s.io/api/events/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Filter is the main filter struct
 type Filter struct {
 	enabled bool
+	// jobSuccess sends the updates of Jobs that just completed successfully
+	jobSuccess bool
 }
 
 // NewFilter creates a new filter instance
 func NewFilter() *Filter {
-	enabled := false
-	if envVal := os.Getenv("ADVANCED_FILTERS"); envVal != "" {
-		parsedVal, err := strconv.ParseBool(envVal)
-		if err == nil {
-			enabled = parsedVal
-		} else {
-			logrus.Warnf("Invalid ADVANCED_FILTERS value: %s, defaulting to false", envVal)
-		}
-	}
-
+	enabled := boolEnv("ADVANCED_FILTERS")
 	if enabled {
 		logrus.Info("Advanced filtering is ENABLED")
 	} else {
@@ -54,10 +48,25 @@ func NewFilter() *Filter {
 	}
 
 	return &Filter{
-		enabled: enabled,
+		enabled:    enabled,
+		jobSuccess: boolEnv("ADVANCED_FILTERS_JOB_SUCCESS"),
 	}
 }
 
+// boolEnv returns the boolean value of the environment variable name, false when unset
+func boolEnv(name string) bool {
+	envVal := os.Getenv(name)
+	if envVal == "" {
+		return false
+	}
+	parsedVal, err := strconv.ParseBool(envVal)
+	if err != nil {
+		logrus.Warnf("Invalid %s value: %s, defaulting to false", name, envVal)
+		return false
+	}
+	return parsedVal
+}
+
 // ShouldSendEvent determines if an event should be sent to Robusta
 func (f *Filter) ShouldSendEvent(e event.Event) bool {
 	// If filtering is disabled, send all events
@@ -65,6 +74,11 @@ func (f *Filter) ShouldSendEvent(e event.Event) bool {
 		return true
 	}
 
+	// Metadata-only objects carry no spec or status to filter on
+	if _, ok := e.Obj.(*meta_v1.PartialObjectMetadata); ok {
+		return true
+	}
+
 	// Apply filtering rules based on resource kind
 	switch e.Kind {
 	case "Event":
@@ -134,7 +148,7 @@ func (f *Filter) shouldSendJobEvent(e event.Event) bool {
 		return true
 	}
 
-	// For Update events, check if spec changed or job failed
+	// For Update events, check if spec changed, job failed or, optionally, job completed
 	if e.Reason == "Updated" {
 		job, ok := e.Obj.(*batch_v1.Job)
 		if !ok {
@@ -162,6 +176,12 @@ func (f *Filter) shouldSendJobEvent(e event.Event) bool {
 			}
 		}
 
+		// Check if job just completed
+		if f.jobSuccess && jobComplete(job) && !jobComplete(oldJob) {
+			logrus.Debugf("Job %s completed, sending update event", job.Name)
+			return true
+		}
+
 		logrus.Debugf("Filtering out Job update event - no spec change or failure detected")
 		return false
 	}
@@ -170,6 +190,16 @@ func (f *Filter) shouldSendJobEvent(e event.Event) bool {
 	return false
 }
 
+// jobComplete checks if the job completed successfully
+func jobComplete(job *batch_v1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batch_v1.JobComplete && condition.Status == api_v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
 // shouldSendPodEvent filters Pod events
 func (f *Filter) shouldSendPodEvent(e event.Event) bool {
 	// Always send Create and Delete events
@@ -247,6 +247,42 @@ func TestShouldSendJobEvent(t *testing.T) {
 	}
 }
 
+func TestShouldSendJobSuccessEvent(t *testing.T) {
+	running := &batch_v1.Job{}
+	completed := &batch_v1.Job{
+		Status: batch_v1.JobStatus{
+			Conditions: []batch_v1.JobCondition{
+				{
+					Type:   batch_v1.JobComplete,
+					Status: api_v1.ConditionTrue,
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		jobSuccess bool
+		oldJob     *batch_v1.Job
+		job        *batch_v1.Job
+		expected   bool
+	}{
+		{"Job Completed - Should Filter by Default", false, running, completed, false},
+		{"Job Completed - Should Send with Job Success", true, running, completed, true},
+		{"Completed Job Updated - Should Filter", true, completed, completed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &Filter{enabled: true, jobSuccess: tt.jobSuccess}
+			e := event.Event{Kind: "Job", Reason: "Updated", Obj: tt.job, OldObj: tt.oldJob}
+			if result := filter.ShouldSendEvent(e); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestShouldSendPodEvent(t *testing.T) {
 	filter := &Filter{enabled: true}
 
@@ -17,18 +17,21 @@ limitations under the License.
 package cloudevent
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
 
-	"bytes"
 	"encoding/json"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/bitnami-labs/kubewatch/pkg/filter"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
 	"github.com/bitnami-labs/kubewatch/pkg/metrics"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -52,6 +55,7 @@ type CloudEvent struct {
 	StartTime uint64
 	Counter   uint64
 	Filter    *filter.Filter
+	client    *httpclient.Client
 }
 
 type CloudEventMessage struct {
@@ -67,13 +71,26 @@ type CloudEventMessage struct {
 
 // EventMeta containes the meta data about the event occurred
 type CloudEventMessageData struct {
-	Operation   string         `json:"operation"`
-	Kind        string         `json:"kind"`
-	ClusterUid  string         `json:"clusterUid"`
-	Description string         `json:"description"`
-	ApiVersion  string         `json:"apiVersion"`
-	Obj         runtime.Object `json:"obj"`
-	OldObj      runtime.Object `json:"oldObj"`
+	Operation      string                `json:"operation"`
+	Kind           string                `json:"kind"`
+	ClusterUid     string                `json:"clusterUid"`
+	ClusterName    string                `json:"clusterName,omitempty"`
+	Environment    string                `json:"environment,omitempty"`
+	Region         string                `json:"region,omitempty"`
+	ClusterLabels  map[string]string     `json:"clusterLabels,omitempty"`
+	Description    string                `json:"description"`
+	ApiVersion     string                `json:"apiVersion"`
+	UID            string                `json:"uid,omitempty"`
+	Labels         map[string]string     `json:"labels,omitempty"`
+	Severity       event.Severity        `json:"severity"`
+	Obj            runtime.Object        `json:"obj"`
+	OldObj         runtime.Object        `json:"oldObj"`
+	Diff           []diff.Change         `json:"diff,omitempty"`
+	ImageChanges   []event.ImageChange   `json:"imageChanges,omitempty"`
+	Scale          *event.ScaleChange    `json:"scale,omitempty"`
+	InvolvedObject *event.InvolvedObject `json:"involvedObject,omitempty"`
+	// Event is the canonical representation of the event, see event.JSONSchema
+	Event event.Payload `json:"event"`
 }
 
 func (m *CloudEvent) Init(c *config.Config) error {
@@ -90,14 +107,32 @@ func (m *CloudEvent) Init(c *config.Config) error {
 		return fmt.Errorf(cloudEventErrMsg, "Missing cloudevent url")
 	}
 
+	client, err := httpclient.New(c, "cloudevent")
+	if err != nil {
+		return err
+	}
+	m.client = client
 	return nil
 }
 
 func (m *CloudEvent) Handle(e event.Event) {
+	if err := m.HandleWithError(e); err != nil {
+		logging.HandlerLogger("cloudevent", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (m *CloudEvent) HandleWithError(e event.Event) error {
+	return m.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e as a CloudEvent unless filtered out, the request being canceled
+// when ctx is done
+func (m *CloudEvent) HandleContext(ctx context.Context, e event.Event) error {
 	// Apply filtering if enabled
 	if !m.Filter.ShouldSendEvent(e) {
-		logrus.Debugf("Event filtered out - Kind: %s, Reason: %s, Name: %s", e.Kind, e.Reason, e.Name)
-		return
+		logging.HandlerLogger("cloudevent", e).Debugf("Event filtered out, reason: %s", e.Reason)
+		return nil
 	}
 
 	// Increment the sent metrics counter
@@ -116,34 +151,61 @@ func (m *CloudEvent) Handle(e event.Event) {
 		metrics.EventsSentTotal.WithLabelValues(e.Kind, eventType).Inc()
 	}
 
-	m.Counter++ // TODO: do we have to worry about threadsafety here?
-	message := m.prepareMessage(e)
+	message := m.prepareMessage(e, atomic.AddUint64(&m.Counter, 1))
 
-	err := m.postMessage(message)
+	err := m.postMessage(ctx, message)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to %s at %s ", m.Url, time.Now())
+	logging.HandlerLogger("cloudevent", e).Infof("Message successfully sent to %s", m.Url)
+	return nil
 }
 
-func (m *CloudEvent) prepareMessage(e event.Event) *CloudEventMessage {
+// eventID returns the ID of the notification, or the sequence number seq for events without ID
+func (m *CloudEvent) eventID(e event.Event, seq uint64) string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return fmt.Sprintf("%v-%v", m.StartTime, seq)
+}
+
+// eventTime returns when the event was observed, defaulting to now
+func eventTime(e event.Event) time.Time {
+	if e.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return e.Timestamp
+}
+
+func (m *CloudEvent) prepareMessage(e event.Event, seq uint64) *CloudEventMessage {
 	return &CloudEventMessage{
 		SpecVersion:     "1.0",
 		Type:            "KUBERNETES_TOPOLOGY_CHANGE",
 		Source:          "https://github.com/aantn/kubewatch",
-		ID:              fmt.Sprintf("%v-%v", m.StartTime, m.Counter),
-		Time:            time.Now(), // TODO: verify that time format is correct - note that this is the time of sending not time of event
+		ID:              m.eventID(e, seq),
+		Time:            eventTime(e),
 		DataContentType: "application/json",
 		Data: CloudEventMessageData{
-			Operation:   m.formatReason(e),
-			Kind:        e.Kind,
-			ApiVersion:  e.ApiVersion,
-			ClusterUid:  "TODO",
-			Description: e.Message(),
-			Obj:         e.Obj,
-			OldObj:      e.OldObj,
+			Operation:      m.formatReason(e),
+			Kind:           e.Kind,
+			ApiVersion:     e.APIVersion,
+			UID:            string(e.UID),
+			Labels:         e.Labels,
+			Severity:       e.ResolvedSeverity(),
+			ClusterUid:     "TODO",
+			ClusterName:    e.ClusterName,
+			Environment:    e.Environment,
+			Region:         e.Region,
+			ClusterLabels:  e.ClusterLabels,
+			Description:    e.Message(),
+			Obj:            e.Obj,
+			OldObj:         e.OldObj,
+			Diff:           e.Diff,
+			ImageChanges:   e.ImageChanges,
+			Scale:          e.Scale,
+			InvolvedObject: e.Involved,
+			Event:          e.Payload(),
 		},
 	}
 }
@@ -161,24 +223,20 @@ func (m *CloudEvent) formatReason(e event.Event) string {
 	}
 }
 
-func (m *CloudEvent) postMessage(webhookMessage *CloudEventMessage) error {
+func (m *CloudEvent) postMessage(ctx context.Context, webhookMessage *CloudEventMessage) error {
 	message, err := json.Marshal(webhookMessage)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", m.Url, bytes.NewBuffer(message))
+	resp, err := m.client.PostContext(ctx, m.Url, "application/json", message)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("cloudevent url %s returned %s", m.Url, resp.Status)
 	}
-	defer resp.Body.Close()
 
 	return nil
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/stats"
+)
+
+// Counted wraps a handler, counting the events it delivers and fails to deliver
+// in the statistics and metrics of Name
+type Counted struct {
+	Name    string
+	Handler Handler
+}
+
+// Init initializes the wrapped handler
+func (c *Counted) Init(conf *config.Config) error {
+	return c.Handler.Init(conf)
+}
+
+// Handle sends the event to the wrapped handler.
+func (c *Counted) Handle(e event.Event) {
+	if err := c.HandleWithError(e); err != nil {
+		logging.HandlerLogger(c.Name, e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError sends the event to the wrapped handler, reporting delivery failures
+// when the handler reports them.
+func (c *Counted) HandleWithError(e event.Event) error {
+	return c.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx, counting the delivery in the
+// statistics and metrics of Name
+func (c *Counted) HandleContext(ctx context.Context, e event.Event) error {
+	err := Deliver(ctx, c.Handler, e)
+	stats.Delivered(c.Name, err)
+	metrics.DeliveriesTotal.WithLabelValues(c.Name, deliveryResult(err)).Inc()
+	return err
+}
+
+// deliveryResult returns the result of a delivery failing with err, as counted in the
+// metrics
+func deliveryResult(err error) string {
+	switch {
+	case err == nil:
+		return "sent"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "failed"
+	}
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (c *Counted) Flush() error {
+	if f, ok := c.Handler.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/stats"
+)
+
+// failingHandler fails every delivery
+type failingHandler struct {
+	recordingHandler
+}
+
+func (f *failingHandler) HandleWithError(e event.Event) error {
+	return errors.New("connection refused")
+}
+
+func TestCounted(t *testing.T) {
+	e := event.Event{Kind: "Pod", Namespace: "new", Name: "foo", Reason: "Created", Status: "Normal"}
+
+	wrapped := &recordingHandler{}
+	c := &Counted{Name: "counted-webhook", Handler: wrapped}
+	c.Handle(e)
+	if err := c.HandleWithError(e); err != nil {
+		t.Errorf("HandleWithError() = %v, expected no error", err)
+	}
+	if len(wrapped.handled) != 2 {
+		t.Errorf("Counted sent %d events to the wrapped handler, expected 2", len(wrapped.handled))
+	}
+
+	failing := &Counted{Name: "counted-slack", Handler: &failingHandler{}}
+	if err := failing.HandleWithError(e); err == nil {
+		t.Error("HandleWithError() succeeded, expected the error of the wrapped handler")
+	}
+
+	handlers := stats.Get().Handlers
+	if d := handlers["counted-webhook"]; d.Sent != 2 || d.Failed != 0 {
+		t.Errorf("expected 2 events sent by the webhook, got %+v", d)
+	}
+	if d := handlers["counted-slack"]; d.Sent != 0 || d.Failed != 1 {
+		t.Errorf("expected 1 event failed by slack, got %+v", d)
+	}
+}
+
+func TestDeliveryResult(t *testing.T) {
+	for _, tt := range []struct {
+		err      error
+		expected string
+	}{
+		{nil, "sent"},
+		{errors.New("connection refused"), "failed"},
+		{fmt.Errorf("posting: %w", context.DeadlineExceeded), "timeout"},
+	} {
+		if result := deliveryResult(tt.err); result != tt.expected {
+			t.Errorf("deliveryResult(%v) = %s, expected %s", tt.err, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// DryRun wraps a handler, logging the events it would have sent instead of sending them
+type DryRun struct {
+	Name    string
+	Handler Handler
+}
+
+// Init initializes the wrapped handler, so that its configuration is checked
+func (d *DryRun) Init(c *config.Config) error {
+	return d.Handler.Init(c)
+}
+
+// Handle logs the event instead of sending it.
+func (d *DryRun) Handle(e event.Event) {
+	logger := logrus.WithFields(logging.EventFields(e)).WithFields(logrus.Fields{"pkg": "kubewatch-dryrun", "handler": d.Name})
+	logger.Infof("Dry run, not sending: %s\n%s", e.Summary(), e.Message())
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		if payload, err := json.Marshal(e.Payload()); err == nil {
+			logger.Debugf("Dry run payload: %s", payload)
+		}
+	}
+}
+
+// HandleWithError logs the event instead of sending it, it never fails.
+func (d *DryRun) HandleWithError(e event.Event) error {
+	d.Handle(e)
+	return nil
+}
+
+// HandleContext logs the event instead of sending it, it never fails.
+func (d *DryRun) HandleContext(ctx context.Context, e event.Event) error {
+	d.Handle(e)
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+type recordingHandler struct {
+	initialized bool
+	handled     []event.Event
+}
+
+func (r *recordingHandler) Init(c *config.Config) error {
+	r.initialized = true
+	return nil
+}
+
+func (r *recordingHandler) Handle(e event.Event) {
+	r.handled = append(r.handled, e)
+}
+
+func TestDryRun(t *testing.T) {
+	hook := test.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	wrapped := &recordingHandler{}
+	d := &DryRun{Name: "slack", Handler: wrapped}
+	if err := d.Init(&config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if !wrapped.initialized {
+		t.Errorf("Init() did not initialize the wrapped handler")
+	}
+
+	e := event.Event{Kind: "Pod", Namespace: "new", Name: "foo", Reason: "Created", Status: "Normal"}
+	if err := d.HandleWithError(e); err != nil {
+		t.Errorf("HandleWithError() = %v, expected no error", err)
+	}
+	if len(wrapped.handled) != 0 {
+		t.Errorf("HandleWithError() sent %d events to the wrapped handler, expected none", len(wrapped.handled))
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("HandleWithError() logged nothing")
+	}
+	if entry.Data["handler"] != "slack" || !strings.Contains(entry.Message, e.Summary()) {
+		t.Errorf("HandleWithError() logged %q with %v, expected the summary of the event and the handler name", entry.Message, entry.Data)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+)
+
+// Filtered wraps a handler, only sending it the events of the kinds and operations it
+// wants, so that handlers do not filter events themselves
+type Filtered struct {
+	Name string
+	// Kinds and Operations of the sent events, all when nil
+	Kinds      map[string]bool
+	Operations map[string]bool
+	Handler    Handler
+}
+
+// NewFiltered returns handler name wrapped by the filter f
+func NewFiltered(name string, f config.HandlerFilter, handler Handler) *Filtered {
+	return &Filtered{Name: name, Kinds: stringSet(f.Kinds), Operations: stringSet(f.Operations), Handler: handler}
+}
+
+// stringSet returns the set of values, nil when empty
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	s := make(map[string]bool, len(values))
+	for _, v := range values {
+		s[v] = true
+	}
+	return s
+}
+
+// Init initializes the wrapped handler
+func (f *Filtered) Init(c *config.Config) error {
+	return f.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler when it wants it.
+func (f *Filtered) Handle(e event.Event) {
+	if f.wants(e) {
+		f.Handler.Handle(e)
+	}
+}
+
+// HandleWithError sends the event to the wrapped handler when it wants it, reporting
+// delivery failures when the handler reports them.
+func (f *Filtered) HandleWithError(e event.Event) error {
+	return f.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx when it has one of the kinds
+// and operations of the filter
+func (f *Filtered) HandleContext(ctx context.Context, e event.Event) error {
+	if !f.wants(e) {
+		return nil
+	}
+	return Deliver(ctx, f.Handler, e)
+}
+
+// wants returns whether e has one of the kinds and operations of f
+func (f *Filtered) wants(e event.Event) bool {
+	if (f.Kinds != nil && !f.Kinds[e.Kind]) || (f.Operations != nil && !f.Operations[e.Operation()]) {
+		logging.HandlerLogger(f.Name, e).Debugf("Event filtered out, the handler does not want %s events", e.Kind)
+		return false
+	}
+	return true
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (f *Filtered) Flush() error {
+	if fl, ok := f.Handler.(Flusher); ok {
+		return fl.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestFiltered(t *testing.T) {
+	var Tests = []struct {
+		filter config.HandlerFilter
+		event  event.Event
+		sent   bool
+	}{
+		{config.HandlerFilter{}, event.Event{Kind: "Pod", Reason: "Created"}, true},
+		{config.HandlerFilter{Kinds: []string{"Job"}}, event.Event{Kind: "Job", Reason: "Updated"}, true},
+		{config.HandlerFilter{Kinds: []string{"Job"}}, event.Event{Kind: "Pod", Reason: "Updated"}, false},
+		{config.HandlerFilter{Operations: []string{"notify"}}, event.Event{Kind: "NodeNotReady", Reason: "Node node-1 is not ready"}, true},
+		{config.HandlerFilter{Operations: []string{"notify"}}, event.Event{Kind: "Pod", Reason: "Deleted"}, false},
+		{config.HandlerFilter{Kinds: []string{"Job"}, Operations: []string{"delete"}}, event.Event{Kind: "Job", Reason: "Updated"}, false},
+	}
+
+	for i, tt := range Tests {
+		wrapped := &recordingHandler{}
+		f := NewFiltered("webhook", tt.filter, wrapped)
+		if err := f.HandleWithError(tt.event); err != nil {
+			t.Fatal(err)
+		}
+		if sent := len(wrapped.handled) == 1; sent != tt.sent {
+			t.Errorf("%d: sent = %v, expected %v", i, sent, tt.sent)
+		}
+	}
+}
@@ -17,25 +17,19 @@ limitations under the License.
 package flock
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
 
-	"bytes"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
 )
 
-var flockColors = map[string]string{
-	"Normal":  "#00FF00",
-	"Warning": "#FFFF00",
-	"Danger":  "#FF0000",
-}
-
 var flockErrMsg = `
 %s
 
@@ -51,7 +45,8 @@ Command line flags will override environment variables
 // Flock handler implements handler.Handler interface,
 // Notify event to Flock channel
 type Flock struct {
-	Url string
+	Url    string
+	client *httpclient.Client
 }
 
 // FlockMessage struct
@@ -83,20 +78,39 @@ func (f *Flock) Init(c *config.Config) error {
 
 	f.Url = url
 
+	client, err := httpclient.New(c, "flock")
+	if err != nil {
+		return err
+	}
+	f.client = client
+
 	return checkMissingFlockVars(f)
 }
 
 // Handle handles an event.
 func (f *Flock) Handle(e event.Event) {
+	if err := f.HandleWithError(e); err != nil {
+		logging.HandlerLogger("flock", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (f *Flock) HandleWithError(e event.Event) error {
+	return f.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e to the Flock incoming webhook, the request being canceled when
+// ctx is done
+func (f *Flock) HandleContext(ctx context.Context, e event.Event) error {
 	flockMessage := prepareFlockMessage(e, f)
 
-	err := postMessage(f.Url, flockMessage)
+	err := postMessage(ctx, f.client, f.Url, flockMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to channel %s at %s", f.Url, time.Now())
+	logging.HandlerLogger("flock", e).Infof("Message successfully sent to channel %s", f.Url)
+	return nil
 }
 
 func checkMissingFlockVars(s *Flock) error {
@@ -114,28 +128,25 @@ func prepareFlockMessage(e event.Event, f *Flock) *FlockMessage {
 		Attachements: []FlockMessageAttachement{
 			{
 				Title: e.Message(),
-				Color: flockColors[e.Status],
+				Color: e.Color(),
 			},
 		},
 	}
 }
 
-func postMessage(url string, flockMessage *FlockMessage) error {
+func postMessage(ctx context.Context, client *httpclient.Client, url string, flockMessage *FlockMessage) error {
 	message, err := json.Marshal(flockMessage)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
+	resp, err := client.PostContext(ctx, url, "application/json", message)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
-	if err != nil {
-		return err
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("flock %s returned %s", url, resp.Status)
 	}
 
 	return nil
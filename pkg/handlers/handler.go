@@ -17,6 +17,8 @@ limitations under the License.
 package handlers
 
 import (
+	"context"
+
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/flock"
@@ -28,6 +30,7 @@ import (
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/slackwebhook"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/smtp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
 )
 
 // Handler is implemented by any handler.
@@ -37,6 +40,94 @@ type Handler interface {
 	Handle(e event.Event)
 }
 
+// RetryableHandler is implemented by handlers able to report delivery failures.
+// Events whose delivery failed are retried by the controller.
+type RetryableHandler interface {
+	Handler
+	HandleWithError(e event.Event) error
+}
+
+// ContextHandler is the interface of handlers taking the context of deliveries, canceled
+// when a delivery times out or kubewatch stops before it completes, and reporting delivery
+// failures. Events whose delivery failed are retried by the controller, and counted as
+// failed deliveries in the metrics. Adapt makes a ContextHandler of any Handler.
+type ContextHandler interface {
+	Init(c *config.Config) error
+	HandleContext(ctx context.Context, e event.Event) error
+}
+
+// Deliver sends e to h, with ctx when h is a ContextHandler. The error of the delivery is
+// only reported by ContextHandlers and RetryableHandlers. Other handlers, such as smtp and
+// hipchat, cannot be canceled: they are called synchronously and ctx is ignored, so that a
+// delivery is never reported as failed while it may still succeed, and then retried.
+func Deliver(ctx context.Context, h Handler, e event.Event) error {
+	if c, ok := h.(ContextHandler); ok {
+		return c.HandleContext(ctx, e)
+	}
+	return handle(h, e)
+}
+
+// handle sends e to h, reporting delivery failures when h is a RetryableHandler
+func handle(h Handler, e event.Event) error {
+	if r, ok := h.(RetryableHandler); ok {
+		return r.HandleWithError(e)
+	}
+	h.Handle(e)
+	return nil
+}
+
+// Adapt returns h as a ContextHandler: h when it is one, or a ContextHandler delivering
+// events to h as Deliver does
+func Adapt(h Handler) ContextHandler {
+	if c, ok := h.(ContextHandler); ok {
+		return c
+	}
+	return adapted{h}
+}
+
+// adapted is a Handler adapted to ContextHandler
+type adapted struct {
+	Handler
+}
+
+// HandleContext sends e to the handler
+func (a adapted) HandleContext(ctx context.Context, e event.Event) error {
+	return Deliver(ctx, a.Handler, e)
+}
+
+// FromContext returns h as a Handler, for the ContextHandlers without Handle and
+// HandleWithError methods. Events are delivered without deadline unless Deliver is
+// given a context.
+func FromContext(h ContextHandler) Handler {
+	if handler, ok := h.(Handler); ok {
+		return handler
+	}
+	return fromContext{h}
+}
+
+// fromContext is a ContextHandler adapted to Handler
+type fromContext struct {
+	ContextHandler
+}
+
+// Handle sends e to the handler, logging delivery failures
+func (f fromContext) Handle(e event.Event) {
+	if err := f.HandleWithError(e); err != nil {
+		logging.HandlerLogger("", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError sends e to the handler and reports delivery failures
+func (f fromContext) HandleWithError(e event.Event) error {
+	return f.HandleContext(context.Background(), e)
+}
+
+// Flusher is implemented by handlers buffering events before sending them.
+// Flush is called once queued events are handled, before kubewatch exits.
+type Flusher interface {
+	Flush() error
+}
+
 // Map maps each event handler function to a name for easily lookup
 var Map = map[string]interface{}{
 	"default":      &Default{},
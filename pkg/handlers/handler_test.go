@@ -0,0 +1,109 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// contextHandler records the contexts of its deliveries, it has no Handle method
+type contextHandler struct {
+	contexts []context.Context
+	err      error
+}
+
+func (c *contextHandler) Init(conf *config.Config) error {
+	return nil
+}
+
+func (c *contextHandler) HandleContext(ctx context.Context, e event.Event) error {
+	c.contexts = append(c.contexts, ctx)
+	return c.err
+}
+
+// slowHandler takes a while to deliver events, without taking their context
+type slowHandler struct {
+	recordingHandler
+}
+
+func (s *slowHandler) HandleWithError(e event.Event) error {
+	time.Sleep(50 * time.Millisecond)
+	s.recordingHandler.Handle(e)
+	return nil
+}
+
+type key struct{}
+
+func TestDeliver(t *testing.T) {
+	e := event.Event{Kind: "Pod", Namespace: "new", Name: "foo", Reason: "Created", Status: "Normal"}
+	ctx := context.WithValue(context.Background(), key{}, "delivery")
+
+	failing := &contextHandler{err: errors.New("connection refused")}
+	wrapped := &Filtered{Handler: FromContext(failing)}
+	if err := Deliver(ctx, wrapped, e); err == nil || err.Error() != "connection refused" {
+		t.Errorf("Deliver() = %v, expected connection refused", err)
+	}
+	if len(failing.contexts) != 1 || failing.contexts[0].Value(key{}) != "delivery" {
+		t.Errorf("the context of the delivery was not passed through the wrapping handlers")
+	}
+
+	if err := Deliver(ctx, &failingHandler{}, e); err == nil {
+		t.Errorf("Deliver() to a RetryableHandler did not report its failure")
+	}
+	recorder := &recordingHandler{}
+	if err := Deliver(ctx, recorder, e); err != nil || len(recorder.handled) != 1 {
+		t.Errorf("Deliver() = %v with %d events handled, expected the event to be handled", err, len(recorder.handled))
+	}
+
+	// handlers without context are waited for, their delivery cannot be canceled
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	slow := &slowHandler{}
+	if err := Deliver(timeout, slow, e); err != nil || len(slow.handled) != 1 {
+		t.Errorf("Deliver() = %v with %d events handled, expected the event to be handled past the deadline", err, len(slow.handled))
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	e := event.Event{Kind: "Pod", Namespace: "new", Name: "foo", Reason: "Created", Status: "Normal"}
+
+	recorder := &recordingHandler{}
+	adapted := Adapt(recorder)
+	if err := adapted.HandleContext(context.Background(), e); err != nil || len(recorder.handled) != 1 {
+		t.Errorf("HandleContext() = %v with %d events handled, expected the event to be handled", err, len(recorder.handled))
+	}
+	counted := &Counted{Name: "webhook", Handler: recorder}
+	if Adapt(counted) != ContextHandler(counted) {
+		t.Errorf("Adapt() wrapped a ContextHandler")
+	}
+
+	handler := &contextHandler{}
+	legacy := FromContext(handler)
+	legacy.Handle(e)
+	if err := legacy.(RetryableHandler).HandleWithError(e); err != nil || len(handler.contexts) != 2 {
+		t.Errorf("HandleWithError() = %v with %d events handled, expected 2 events to be handled", err, len(handler.contexts))
+	}
+	if FromContext(counted) != Handler(counted) {
+		t.Errorf("FromContext() wrapped a Handler")
+	}
+}
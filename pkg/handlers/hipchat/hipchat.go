@@ -18,7 +18,6 @@ package hipchat
 
 import (
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
 
 	hipchat "github.com/tbruyelle/hipchat-go/hipchat"
@@ -27,12 +26,16 @@ import (
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
 )
 
-var hipchatColors = map[string]hipchat.Color{
-	"Normal":  hipchat.ColorGreen,
-	"Warning": hipchat.ColorYellow,
-	"Danger":  hipchat.ColorRed,
+// hipchatColors maps severities to the few colors HipChat supports
+var hipchatColors = map[event.Severity]hipchat.Color{
+	event.SeverityInfo:            hipchat.ColorGreen,
+	event.SeverityWarning:         hipchat.ColorYellow,
+	event.SeverityCritical:        hipchat.ColorRed,
+	event.SeverityPolicyViolation: hipchat.ColorPurple,
 }
 
 var hipchatErrMsg = `
@@ -52,9 +55,10 @@ Command line flags will override environment variables
 // Hipchat handler implements handler.Handler interface,
 // Notify event to hipchat room
 type Hipchat struct {
-	Token string
-	Room  string
-	Url   string
+	Token  string
+	Room   string
+	Url    string
+	client *httpclient.Client
 }
 
 // Init prepares hipchat configuration
@@ -79,12 +83,26 @@ func (s *Hipchat) Init(c *config.Config) error {
 	s.Room = room
 	s.Url = url
 
+	client, err := httpclient.New(c, "hipchat")
+	if err != nil {
+		return err
+	}
+	s.client = client
+
 	return checkMissingHipchatVars(s)
 }
 
 // Handle handles the notification.
 func (s *Hipchat) Handle(e event.Event) {
+	if err := s.HandleWithError(e); err != nil {
+		logging.HandlerLogger("hipchat", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (s *Hipchat) HandleWithError(e event.Event) error {
 	client := hipchat.NewClient(s.Token)
+	client.SetHTTPClient(s.client.HTTPClient())
 	if s.Url != "" {
 		baseUrl, err := url.Parse(s.Url)
 		if err != nil {
@@ -97,11 +115,11 @@ func (s *Hipchat) Handle(e event.Event) {
 	_, err := client.Room.Notification(s.Room, &notificationRequest)
 
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to room %s", s.Room)
+	logging.HandlerLogger("hipchat", e).Infof("Message successfully sent to room %s", s.Room)
+	return nil
 }
 
 func checkMissingHipchatVars(s *Hipchat) error {
@@ -119,7 +137,7 @@ func prepareHipchatNotification(e event.Event) hipchat.NotificationRequest {
 		From:    "kubewatch",
 	}
 
-	if color, ok := hipchatColors[e.Status]; ok {
+	if color, ok := hipchatColors[e.ResolvedSeverity()]; ok {
 
 		notification.Color = color
 	}
@@ -17,17 +17,18 @@ limitations under the License.
 package lark
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
 
-	"bytes"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/render"
 )
 
 var webhookErrMsg = `
@@ -45,7 +46,8 @@ Command line flags will override environment variables
 // Webhook handler implements handler.Handler interface,
 // Notify event to Webhook channel
 type Webhook struct {
-	Url string
+	Url    string
+	client *httpclient.Client
 }
 
 // TextMessage for messages
@@ -65,19 +67,38 @@ func (m *Webhook) Init(c *config.Config) error {
 		url = os.Getenv("KW_LARK_WEBHOOK_URL")
 	}
 	m.Url = url
+	client, err := httpclient.New(c, "lark")
+	if err != nil {
+		return err
+	}
+	m.client = client
+
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
 func (m *Webhook) Handle(e event.Event) {
+	if err := m.HandleWithError(e); err != nil {
+		logging.HandlerLogger("lark", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (m *Webhook) HandleWithError(e event.Event) error {
+	return m.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e to the Lark custom bot, the request being canceled when ctx is
+// done
+func (m *Webhook) HandleContext(ctx context.Context, e event.Event) error {
 	webhookMessage := prepareWebhookMessage(e, m)
 
-	err := postMessage(m.Url, webhookMessage)
+	err := postMessage(ctx, m.client, m.Url, webhookMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
-	logrus.Printf("Message successfully sent to lark webhook: %s at %s ", m.Url, time.Now())
+	logging.HandlerLogger("lark", e).Infof("Message successfully sent to lark webhook: %s", m.Url)
+	return nil
 }
 
 func checkMissingWebhookVars(s *Webhook) error {
@@ -90,25 +111,22 @@ func checkMissingWebhookVars(s *Webhook) error {
 func prepareWebhookMessage(e event.Event, m *Webhook) *TextMessage {
 	return &TextMessage{
 		MsgType: "text",
-		Content: &TextContent{Text: e.Message()},
+		Content: &TextContent{Text: render.TextMessage(render.Text, &e)},
 	}
 }
 
-func postMessage(url string, textMessage *TextMessage) error {
+func postMessage(ctx context.Context, client *httpclient.Client, url string, textMessage *TextMessage) error {
 	message, err := json.Marshal(textMessage)
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
+	resp, err := client.PostContext(ctx, url, "application/json", message)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
-	if err != nil {
-		return err
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("lark %s returned %s", url, resp.Status)
 	}
 	return nil
 }
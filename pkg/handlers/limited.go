@@ -0,0 +1,84 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+)
+
+// payloadHandlers send events as JSON payloads, the other handlers send rendered messages
+var payloadHandlers = map[string]bool{"webhook": true, "cloudevent": true}
+
+// Limited wraps a handler, truncating events larger than MaxBytes before sending them,
+// so that services limiting the size of messages do not reject them. Events of the
+// webhook and CloudEvent handlers are measured as their JSON payload, objects included,
+// and events of the other handlers as their rendered message.
+type Limited struct {
+	Name     string
+	MaxBytes int
+	Handler  Handler
+}
+
+// Init initializes the wrapped handler
+func (l *Limited) Init(conf *config.Config) error {
+	return l.Handler.Init(conf)
+}
+
+// Handle truncates the event when it is too large and sends it to the wrapped handler.
+func (l *Limited) Handle(e event.Event) {
+	if err := l.HandleWithError(e); err != nil {
+		logging.HandlerLogger(l.Name, e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError truncates the event when it is too large and sends it to the wrapped
+// handler, reporting delivery failures when the handler reports them. Events
+// still too large once truncated are sent anyway.
+func (l *Limited) HandleWithError(e event.Event) error {
+	return l.HandleContext(context.Background(), e)
+}
+
+// HandleContext truncates e to MaxBytes and delivers it to the wrapped handler with ctx
+func (l *Limited) HandleContext(ctx context.Context, e event.Event) error {
+	if l.MaxBytes > 0 {
+		measure := (*event.Event).MessageSize
+		if payloadHandlers[l.Name] {
+			measure = (*event.Event).Size
+		}
+		size := measure(&e)
+		if size > l.MaxBytes {
+			if e.Truncate(l.MaxBytes, measure) {
+				logging.HandlerLogger(l.Name, e).Debugf("Truncated event of %d bytes to the %d bytes limit, dropping %v", size, l.MaxBytes, e.Truncated)
+			} else {
+				logging.HandlerLogger(l.Name, e).Warnf("Event of %d bytes is still %d bytes once truncated, above the %d bytes limit", size, measure(&e), l.MaxBytes)
+			}
+		}
+	}
+	return Deliver(ctx, l.Handler, e)
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (l *Limited) Flush() error {
+	if f, ok := l.Handler.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
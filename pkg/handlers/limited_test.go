@@ -0,0 +1,68 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLimited(t *testing.T) {
+	large := &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "settings"}, Data: map[string]string{"large": strings.Repeat("x", 10000)}}
+	logs := &event.ContainerLogs{Container: "app", ExitCode: 2, Text: strings.Repeat("panic: runtime error\n", 200)}
+	e := event.Event{Kind: "ConfigMap", Namespace: "payments", Name: "settings", Reason: "Created", Status: "Normal", Obj: large, Logs: logs}
+
+	var Tests = []struct {
+		handler   string
+		maxBytes  int
+		truncated []string
+		size      func(*event.Event) int
+	}{
+		{"webhook", 0, nil, (*event.Event).Size},
+		{"webhook", 100000, nil, (*event.Event).Size},
+		{"webhook", 10000, []string{"object"}, (*event.Event).Size},
+		// chat handlers do not send objects, their messages are measured
+		{"slack", 8000, nil, (*event.Event).MessageSize},
+		{"slack", 2000, []string{"older log lines"}, (*event.Event).MessageSize},
+	}
+
+	for _, tt := range Tests {
+		wrapped := &recordingHandler{}
+		l := &Limited{Name: tt.handler, MaxBytes: tt.maxBytes, Handler: wrapped}
+		if err := l.HandleWithError(e); err != nil {
+			t.Errorf("HandleWithError() = %v, expected no error", err)
+		}
+		if len(wrapped.handled) != 1 {
+			t.Fatalf("HandleWithError() sent %d events, expected 1", len(wrapped.handled))
+		}
+		sent := wrapped.handled[0]
+		if !reflect.DeepEqual(sent.Truncated, tt.truncated) {
+			t.Errorf("%s MaxBytes %d: truncated %v, expected %v", tt.handler, tt.maxBytes, sent.Truncated, tt.truncated)
+		}
+		if tt.maxBytes > 0 && tt.size(&sent) > tt.maxBytes {
+			t.Errorf("%s MaxBytes %d: sent %d bytes", tt.handler, tt.maxBytes, tt.size(&sent))
+		}
+	}
+	if e.Obj == nil || e.Truncated != nil {
+		t.Error("HandleWithError() modified the event of the caller")
+	}
+}
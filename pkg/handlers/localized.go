@@ -0,0 +1,61 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Localized wraps a handler, translating the messages of the events it sends with Catalog
+type Localized struct {
+	Catalog *event.Catalog
+	Handler Handler
+}
+
+// Init initializes the wrapped handler
+func (l *Localized) Init(conf *config.Config) error {
+	return l.Handler.Init(conf)
+}
+
+// Handle sends the event to the wrapped handler, translated.
+func (l *Localized) Handle(e event.Event) {
+	e.Catalog = l.Catalog
+	l.Handler.Handle(e)
+}
+
+// HandleWithError sends the event to the wrapped handler, translated, reporting delivery
+// failures when the handler reports them.
+func (l *Localized) HandleWithError(e event.Event) error {
+	return l.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e, rendered with Catalog, to the wrapped handler with ctx
+func (l *Localized) HandleContext(ctx context.Context, e event.Event) error {
+	e.Catalog = l.Catalog
+	return Deliver(ctx, l.Handler, e)
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (l *Localized) Flush() error {
+	if f, ok := l.Handler.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
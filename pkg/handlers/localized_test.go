@@ -0,0 +1,38 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestLocalized(t *testing.T) {
+	fr, err := event.LoadCatalog("fr", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := &recordingHandler{}
+	l := &Localized{Catalog: fr, Handler: wrapped}
+	if err := l.HandleWithError(event.Event{Kind: "Pod", Namespace: "default", Name: "web", Reason: "Deleted"}); err != nil {
+		t.Fatal(err)
+	}
+	if summary, expected := wrapped.handled[0].Summary(), "Pod default/web supprimé"; summary != expected {
+		t.Errorf("Summary() = %q, expected %q", summary, expected)
+	}
+}
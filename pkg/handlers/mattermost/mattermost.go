@@ -17,25 +17,19 @@ limitations under the License.
 package mattermost
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
 
-	"bytes"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
 )
 
-var mattermostColors = map[string]string{
-	"Normal":  "#00FF00",
-	"Warning": "#FFFF00",
-	"Danger":  "#FF0000",
-}
-
 var mattermostErrMsg = `
 %s
 
@@ -56,6 +50,7 @@ type Mattermost struct {
 	Channel  string
 	Url      string
 	Username string
+	client   *httpclient.Client
 }
 
 // MattermostMessage struct for messages
@@ -95,20 +90,39 @@ func (m *Mattermost) Init(c *config.Config) error {
 	m.Url = url
 	m.Username = username
 
+	client, err := httpclient.New(c, "mattermost")
+	if err != nil {
+		return err
+	}
+	m.client = client
+
 	return checkMissingMattermostVars(m)
 }
 
 // Handle handles an event.
 func (m *Mattermost) Handle(e event.Event) {
+	if err := m.HandleWithError(e); err != nil {
+		logging.HandlerLogger("mattermost", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (m *Mattermost) HandleWithError(e event.Event) error {
+	return m.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e to the Mattermost incoming webhook, the request being canceled
+// when ctx is done
+func (m *Mattermost) HandleContext(ctx context.Context, e event.Event) error {
 	mattermostMessage := prepareMattermostMessage(e, m)
 
-	err := postMessage(m.Url, mattermostMessage)
+	err := postMessage(ctx, m.client, m.Url, mattermostMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to channel %s at %s", m.Channel, time.Now())
+	logging.HandlerLogger("mattermost", e).Infof("Message successfully sent to channel %s", m.Channel)
+	return nil
 }
 
 func checkMissingMattermostVars(s *Mattermost) error {
@@ -127,28 +141,25 @@ func prepareMattermostMessage(e event.Event, m *Mattermost) *MattermostMessage {
 		Attachements: []MattermostMessageAttachement{
 			{
 				Title: e.Message(),
-				Color: mattermostColors[e.Status],
+				Color: e.Color(),
 			},
 		},
 	}
 }
 
-func postMessage(url string, mattermostMessage *MattermostMessage) error {
+func postMessage(ctx context.Context, client *httpclient.Client, url string, mattermostMessage *MattermostMessage) error {
 	message, err := json.Marshal(mattermostMessage)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
+	resp, err := client.PostContext(ctx, url, "application/json", message)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
-	if err != nil {
-		return err
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("mattermost %s returned %s", url, resp.Status)
 	}
 
 	return nil
@@ -18,15 +18,18 @@ package msteam
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/sirupsen/logrus"
-	"io"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/render"
 )
 
 var msteamsErrMsg = `
@@ -41,16 +44,15 @@ Command line flags will override environment variables
 
 `
 
-var msTeamsColors = map[string]string{
-	"Normal":  "2DC72D",
-	"Warning": "DEFF22",
-	"Danger":  "8C1A1A",
+// themeColor returns the card color of a "#RRGGBB" color, Teams expects hex colors without "#"
+func themeColor(color string) string {
+	return strings.TrimPrefix(color, "#")
 }
 
 // Constants for Sending a Card
 const (
 	messageType = "MessageCard"
-	context     = "http://schema.org/extensions"
+	cardContext = "http://schema.org/extensions"
 )
 
 // TeamsMessageCard is for the Card Fields to send in Teams
@@ -84,29 +86,23 @@ type TeamsMessageCardSectionFacts struct {
 type MSTeams struct {
 	// TeamsWebhookURL is the webhook url of the Teams connector
 	TeamsWebhookURL string
+	client          *httpclient.Client
 }
 
 // sendCard sends the JSON Encoded TeamsMessageCard to the webhook URL
-func sendCard(ms *MSTeams, card *TeamsMessageCard) (*http.Response, error) {
+func sendCard(ctx context.Context, ms *MSTeams, card *TeamsMessageCard) (*httpclient.Response, error) {
 	buffer := new(bytes.Buffer)
 	if err := json.NewEncoder(buffer).Encode(card); err != nil {
 		return nil, fmt.Errorf("Failed encoding message card: %v", err)
 	}
-	res, err := http.Post(ms.TeamsWebhookURL, "application/json", buffer)
+	res, err := ms.client.PostContext(ctx, ms.TeamsWebhookURL, "application/json", buffer.Bytes())
 	if err != nil {
 		return nil, fmt.Errorf("Failed sending to webhook url %s. Got the error: %v",
 			ms.TeamsWebhookURL, err)
 	}
 	if res.StatusCode != http.StatusOK {
-		resMessage, err := io.ReadAll(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("Failed reading Teams http response: %v", err)
-		}
 		return nil, fmt.Errorf("Failed sending to the Teams Channel. Teams http response: %s, %s",
-			res.Status, string(resMessage))
-	}
-	if err := res.Body.Close(); err != nil {
-		return nil, err
+			res.Status, string(res.Body))
 	}
 	return res, nil
 }
@@ -124,30 +120,48 @@ func (ms *MSTeams) Init(c *config.Config) error {
 	}
 
 	ms.TeamsWebhookURL = webhookURL
+	client, err := httpclient.New(c, "msteams")
+	if err != nil {
+		return err
+	}
+	ms.client = client
 	return nil
 }
 
 // Handle handles notification.
 func (ms *MSTeams) Handle(e event.Event) {
+	if err := ms.HandleWithError(e); err != nil {
+		logging.HandlerLogger("msteams", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (ms *MSTeams) HandleWithError(e event.Event) error {
+	return ms.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e as a message card to the Microsoft Teams webhook, the request
+// being canceled when ctx is done
+func (ms *MSTeams) HandleContext(ctx context.Context, e event.Event) error {
 	card := &TeamsMessageCard{
 		Type:    messageType,
-		Context: context,
+		Context: cardContext,
 		Title:   "kubewatch",
-		// Set a default Summary, this is required for Microsoft Teams
-		Summary: "kubewatch notification received",
+		// Summary is required by Microsoft Teams, it is shown in notifications
+		Summary: e.Summary(),
 	}
 
-	card.ThemeColor = msTeamsColors[e.Status]
+	card.ThemeColor = themeColor(e.Color())
 
 	var s TeamsMessageCardSection
-	s.ActivityTitle = e.Message()
+	s.ActivityTitle = render.Message(render.Teams, &e)
 	s.Markdown = true
 	card.Sections = append(card.Sections, s)
 
-	if _, err := sendCard(ms, card); err != nil {
-		logrus.Printf("%s\n", err)
-		return
+	if _, err := sendCard(ctx, ms, card); err != nil {
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to MS Teams")
+	logging.HandlerLogger("msteams", e).Infof("Message successfully sent to MS Teams")
+	return nil
 }
@@ -38,14 +38,14 @@ func TestInit(t *testing.T) {
 func TestObjectCreated(t *testing.T) {
 	expectedCard := TeamsMessageCard{
 		Type:       messageType,
-		Context:    context,
-		ThemeColor: msTeamsColors["Normal"],
-		Summary:    "kubewatch notification received",
+		Context:    cardContext,
+		ThemeColor: themeColor(event.SeverityInfo.Color()),
+		Summary:    "pod new/foo created",
 		Title:      "kubewatch",
 		Text:       "",
 		Sections: []TeamsMessageCardSection{
 			{
-				ActivityTitle: "A `pod` in namespace `new` has been `Created`:\n`foo`",
+				ActivityTitle: "A **pod** in namespace **new** has been **Created**:\n\n**foo**",
 				Markdown:      true,
 			},
 		},
@@ -82,14 +82,14 @@ func TestObjectCreated(t *testing.T) {
 func TestObjectDeleted(t *testing.T) {
 	expectedCard := TeamsMessageCard{
 		Type:       messageType,
-		Context:    context,
-		ThemeColor: msTeamsColors["Danger"],
-		Summary:    "kubewatch notification received",
+		Context:    cardContext,
+		ThemeColor: themeColor(event.SeverityCritical.Color()),
+		Summary:    "pod new/foo deleted",
 		Title:      "kubewatch",
 		Text:       "",
 		Sections: []TeamsMessageCardSection{
 			{
-				ActivityTitle: "A `pod` in namespace `new` has been `Deleted`:\n`foo`",
+				ActivityTitle: "A **pod** in namespace **new** has been **Deleted**:\n\n**foo**",
 				Markdown:      true,
 			},
 		},
@@ -127,14 +127,14 @@ func TestObjectDeleted(t *testing.T) {
 func TestObjectUpdated(t *testing.T) {
 	expectedCard := TeamsMessageCard{
 		Type:       messageType,
-		Context:    context,
-		ThemeColor: msTeamsColors["Warning"],
-		Summary:    "kubewatch notification received",
+		Context:    cardContext,
+		ThemeColor: themeColor(event.SeverityWarning.Color()),
+		Summary:    "pod new/foo updated",
 		Title:      "kubewatch",
 		Text:       "",
 		Sections: []TeamsMessageCardSection{
 			{
-				ActivityTitle: "A `pod` in namespace `new` has been `Updated`:\n`foo`",
+				ActivityTitle: "A **pod** in namespace **new** has been **Updated**:\n\n**foo**",
 				Markdown:      true,
 			},
 		},
@@ -0,0 +1,82 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Reloadable forwards events to a handler that can be replaced while events are handled,
+// when its configuration changes
+type Reloadable struct {
+	mu      sync.RWMutex
+	handler Handler
+}
+
+// NewReloadable returns a Reloadable forwarding events to handler
+func NewReloadable(handler Handler) *Reloadable {
+	return &Reloadable{handler: handler}
+}
+
+// Set replaces the handler events are forwarded to, returning the previous one
+func (r *Reloadable) Set(handler Handler) Handler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.handler
+	r.handler = handler
+	return previous
+}
+
+func (r *Reloadable) current() Handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handler
+}
+
+// Init initializes the current handler
+func (r *Reloadable) Init(c *config.Config) error {
+	return r.current().Init(c)
+}
+
+// Handle forwards the event to the current handler.
+func (r *Reloadable) Handle(e event.Event) {
+	r.current().Handle(e)
+}
+
+// HandleWithError forwards the event to the current handler, reporting delivery failures
+// when the handler reports them.
+func (r *Reloadable) HandleWithError(e event.Event) error {
+	return r.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e with ctx to the handler of the latest config
+func (r *Reloadable) HandleContext(ctx context.Context, e event.Event) error {
+	handler := r.current()
+	return Deliver(ctx, handler, e)
+}
+
+// Flush flushes the current handler when it buffers events
+func (r *Reloadable) Flush() error {
+	if f, ok := r.current().(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"errors"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Route sends the events of its severities and teams within its schedule to Handler
+type Route struct {
+	// Severities of the routed events, all when empty
+	Severities map[event.Severity]bool
+	// Teams owning the namespaces of the routed events, all when empty
+	Teams    map[string]bool
+	Schedule *config.Schedule
+	Handler  Handler
+}
+
+// NewRoute returns the route of conf sending events to handler
+func NewRoute(conf config.Route, handler Handler) (Route, error) {
+	schedule, err := conf.Schedule()
+	if err != nil {
+		return Route{}, err
+	}
+	r := Route{Schedule: schedule, Handler: handler}
+	for _, severity := range conf.Severities {
+		if r.Severities == nil {
+			r.Severities = map[event.Severity]bool{}
+		}
+		r.Severities[event.Severity(severity)] = true
+	}
+	for _, team := range conf.Teams {
+		if r.Teams == nil {
+			r.Teams = map[string]bool{}
+		}
+		r.Teams[team] = true
+	}
+	return r, nil
+}
+
+// Matches returns whether e, sent at t, is routed by r
+func (r Route) Matches(e event.Event, t time.Time) bool {
+	if r.Severities != nil && !r.Severities[e.ResolvedSeverity()] {
+		return false
+	}
+	if r.Teams != nil && (e.Ownership == nil || !r.Teams[e.Ownership.Team]) {
+		return false
+	}
+	return r.Schedule.Includes(t)
+}
+
+// Router sends each event to the handler of the first route matching it, or to Default
+// when none matches
+type Router struct {
+	Routes  []Route
+	Default Handler
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewRouter returns a Router sending the events matching no route to defaultHandler
+func NewRouter(routes []Route, defaultHandler Handler) *Router {
+	return &Router{Routes: routes, Default: defaultHandler, now: time.Now}
+}
+
+// route returns the handler e is routed to
+func (r *Router) route(e event.Event) Handler {
+	now := r.now()
+	for _, route := range r.Routes {
+		if route.Matches(e, now) {
+			return route.Handler
+		}
+	}
+	return r.Default
+}
+
+// Init initializes the default handler and the handlers of the routes
+func (r *Router) Init(c *config.Config) error {
+	var err error
+	for _, handler := range r.handlers() {
+		err = errors.Join(err, handler.Init(c))
+	}
+	return err
+}
+
+// Handle sends the event to the handler it is routed to.
+func (r *Router) Handle(e event.Event) {
+	r.route(e).Handle(e)
+}
+
+// HandleWithError sends the event to the handler it is routed to, reporting delivery
+// failures when the handler reports them.
+func (r *Router) HandleWithError(e event.Event) error {
+	return r.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e with ctx to the handler of the first route matching it, or to
+// the default handler
+func (r *Router) HandleContext(ctx context.Context, e event.Event) error {
+	handler := r.route(e)
+	return Deliver(ctx, handler, e)
+}
+
+// Flush flushes the default handler and the handlers of the routes that buffer events
+func (r *Router) Flush() error {
+	var err error
+	for _, handler := range r.handlers() {
+		if f, ok := handler.(Flusher); ok {
+			err = errors.Join(err, f.Flush())
+		}
+	}
+	return err
+}
+
+// handlers returns the default handler and the handlers of the routes, once each
+func (r *Router) handlers() []Handler {
+	all := []Handler{r.Default}
+	seen := map[Handler]bool{r.Default: true}
+	for _, route := range r.Routes {
+		if !seen[route.Handler] {
+			seen[route.Handler] = true
+			all = append(all, route.Handler)
+		}
+	}
+	return all
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestRouter(t *testing.T) {
+	chat, pager, fallback := &recordingHandler{}, &recordingHandler{}, &recordingHandler{}
+	businessHours, err := NewRoute(config.Route{Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "America/New_York"}, chat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offHours, err := NewRoute(config.Route{Severities: []string{"critical"}}, pager)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter([]Route{businessHours, offHours}, fallback)
+	if err := r.Init(&config.Config{}); err != nil || !chat.initialized || !pager.initialized || !fallback.initialized {
+		t.Fatalf("Init() = %v, expected every handler to be initialized", err)
+	}
+
+	newYork, _ := time.LoadLocation("America/New_York")
+	var Tests = []struct {
+		time    time.Time
+		status  string
+		handler *recordingHandler
+	}{
+		// Wednesday 1 May 2024
+		{time.Date(2024, 5, 1, 10, 0, 0, 0, newYork), "Danger", chat},
+		{time.Date(2024, 5, 1, 10, 0, 0, 0, newYork), "Normal", chat},
+		{time.Date(2024, 5, 1, 22, 0, 0, 0, newYork), "Danger", pager},
+		{time.Date(2024, 5, 4, 10, 0, 0, 0, newYork), "Danger", pager},
+		{time.Date(2024, 5, 4, 10, 0, 0, 0, newYork), "Warning", fallback},
+	}
+
+	for i, test := range Tests {
+		r.now = func() time.Time { return test.time }
+		sent := len(test.handler.handled)
+		if err := r.HandleWithError(event.Event{Name: "api", Status: test.status}); err != nil {
+			t.Fatal(err)
+		}
+		if len(test.handler.handled) != sent+1 {
+			t.Errorf("%d: expected the %s event of %s to be routed to another handler", i, test.status, test.time.Format(time.RFC1123))
+		}
+	}
+	if total := len(chat.handled) + len(pager.handled) + len(fallback.handled); total != len(Tests) {
+		t.Errorf("expected each event to be sent once, got %d events", total)
+	}
+}
+
+func TestRouterTeams(t *testing.T) {
+	payments, fallback := &recordingHandler{}, &recordingHandler{}
+	route, err := NewRoute(config.Route{Teams: []string{"payments"}}, payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter([]Route{route}, fallback)
+
+	var Tests = []struct {
+		ownership *event.Ownership
+		handler   *recordingHandler
+	}{
+		{&event.Ownership{Team: "payments", SlackChannel: "#payments"}, payments},
+		{&event.Ownership{Team: "search"}, fallback},
+		{nil, fallback},
+	}
+	for i, test := range Tests {
+		sent := len(test.handler.handled)
+		r.Handle(event.Event{Namespace: "checkout", Name: "api", Ownership: test.ownership})
+		if len(test.handler.handled) != sent+1 {
+			t.Errorf("%d: expected the event owned by %+v to be routed to another handler", i, test.ownership)
+		}
+	}
+}
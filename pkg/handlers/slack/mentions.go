@@ -0,0 +1,60 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slack
+
+import (
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// defaultMentionSeverity is the minimum severity of events mentioning the owning team
+const defaultMentionSeverity = event.SeverityCritical
+
+// Mention returns the Slack mention of the team owning the object of e, such as
+// <!subteam^S0123ABCD>, or an empty string when e is not urgent enough or no team owns it.
+// The team is named by the configured label of the object, or else by the ownership of
+// its namespace, or else by its namespace.
+func Mention(c config.SlackMentions, e event.Event) string {
+	min := event.Severity(c.Severity)
+	if min == "" {
+		min = defaultMentionSeverity
+	}
+	if !e.ResolvedSeverity().AtLeast(min) {
+		return ""
+	}
+
+	id := ""
+	if team := e.Labels[c.Label]; c.Label != "" && team != "" {
+		id = c.Teams[team]
+	}
+	if id == "" && e.Ownership != nil && e.Ownership.Team != "" {
+		id = c.Teams[e.Ownership.Team]
+	}
+	if id == "" && e.Namespace != "" {
+		id = c.Namespaces[e.Namespace]
+	}
+	switch {
+	case id == "":
+		return ""
+	case strings.HasPrefix(id, "S"):
+		return "<!subteam^" + id + ">"
+	default:
+		return "<@" + id + ">"
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slack
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestMention(t *testing.T) {
+	mentions := config.SlackMentions{
+		Label:      "team",
+		Teams:      map[string]string{"payments": "S0PAYMENTS"},
+		Namespaces: map[string]string{"search": "U0SEARCH", "payments": "S0PLATFORM"},
+	}
+	team := map[string]string{"team": "payments"}
+
+	var Tests = []struct {
+		mentions config.SlackMentions
+		event    event.Event
+		mention  string
+	}{
+		{mentions, event.Event{Namespace: "payments", Labels: team, Status: "Danger"}, "<!subteam^S0PAYMENTS>"},
+		{mentions, event.Event{Namespace: "payments", Status: "Danger"}, "<!subteam^S0PLATFORM>"},
+		{mentions, event.Event{Namespace: "search", Labels: map[string]string{"team": "search"}, Status: "Danger"}, "<@U0SEARCH>"},
+		{mentions, event.Event{Namespace: "payments", Labels: team, Status: "Warning"}, ""},
+		{mentions, event.Event{Namespace: "payments", Labels: team, Severity: event.SeverityPolicyViolation}, ""},
+		{mentions, event.Event{Namespace: "default", Status: "Danger"}, ""},
+		{mentions, event.Event{Namespace: "checkout", Ownership: &event.Ownership{Team: "payments"}, Status: "Danger"}, "<!subteam^S0PAYMENTS>"},
+		{config.SlackMentions{Severity: "warning", Label: "team", Teams: mentions.Teams},
+			event.Event{Namespace: "payments", Labels: team, Status: "Warning"}, "<!subteam^S0PAYMENTS>"},
+	}
+
+	for i, test := range Tests {
+		if mention := Mention(test.mentions, test.event); mention != test.mention {
+			t.Errorf("%d: Mention() = %q, expected %q", i, mention, test.mention)
+		}
+	}
+}
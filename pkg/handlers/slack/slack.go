@@ -17,22 +17,19 @@ limitations under the License.
 package slack
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
 
 	"github.com/slack-go/slack"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/render"
 )
 
-var slackColors = map[string]string{
-	"Normal":  "good",
-	"Warning": "warning",
-	"Danger":  "danger",
-}
-
 var slackErrMsg = `
 %s
 
@@ -46,12 +43,24 @@ Command line flags will override environment variables
 
 `
 
+// AckCallbackID identifies the Acknowledge buttons of the messages of escalated events
+const AckCallbackID = "kubewatch-ack"
+
 // Slack handler implements handler.Handler interface,
 // Notify event to slack channel
 type Slack struct {
-	Token   string
-	Channel string
-	Title   string
+	Token    string
+	Channel  string
+	Title    string
+	Mentions config.SlackMentions
+	// OwnerChannel posts events to the Slack channel of the owner of their namespace
+	OwnerChannel bool
+	// AckSeverity is the minimum severity of the events with an Acknowledge button, none
+	// when empty
+	AckSeverity event.Severity
+	client      *httpclient.Client
+	// threads holds the threads of group keys, nil unless events are threaded
+	threads *threads
 }
 
 // Init prepares slack configuration
@@ -78,24 +87,82 @@ func (s *Slack) Init(c *config.Config) error {
 	s.Token = token
 	s.Channel = channel
 	s.Title = title
+	s.Mentions = c.Handler.Slack.Mentions
+	s.OwnerChannel = c.Handler.Slack.OwnerChannel
+	if c.Handler.Slack.Threads {
+		s.threads = newThreads()
+	}
+	if c.Escalation.Handler != "" && c.Escalation.SlackSigningSecret != "" {
+		s.AckSeverity = event.Severity(c.Escalation.Severity)
+	}
+
+	client, err := httpclient.New(c, "slack")
+	if err != nil {
+		return err
+	}
+	s.client = client
 
 	return checkMissingSlackVars(s)
 }
 
 // Handle handles the notification.
 func (s *Slack) Handle(e event.Event) {
-	api := slack.New(s.Token)
+	if err := s.HandleWithError(e); err != nil {
+		logging.HandlerLogger("slack", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (s *Slack) HandleWithError(e event.Event) error {
+	return s.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e to its Slack channel, in the thread of its group when threads are
+// enabled, the request being canceled when ctx is done
+func (s *Slack) HandleContext(ctx context.Context, e event.Event) error {
+	api := slack.New(s.Token, slack.OptionHTTPClient(s.client.HTTPClient()))
 	attachment := prepareSlackAttachment(e, s)
 
-	channelID, timestamp, err := api.PostMessage(s.Channel,
+	options := []slack.MsgOption{
 		slack.MsgOptionAttachments(attachment),
-		slack.MsgOptionAsUser(true))
+		slack.MsgOptionAsUser(true),
+	}
+	if mention := Mention(s.Mentions, e); mention != "" {
+		options = append(options, slack.MsgOptionText(mention, false))
+	}
+	channel := s.channel(e)
+	// threads belong to a channel
+	threadKey := e.GroupKey
+	if channel != s.Channel {
+		threadKey = channel + " " + e.GroupKey
+	}
+	threaded := s.threads != nil && e.GroupKey != ""
+	thread := ""
+	if threaded {
+		if thread = s.threads.get(threadKey); thread != "" {
+			options = append(options, slack.MsgOptionTS(thread))
+		}
+	}
+
+	channelID, timestamp, err := api.PostMessageContext(ctx, channel, options...)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
+	}
+	if threaded && thread == "" {
+		s.threads.start(threadKey, timestamp)
 	}
 
-	logrus.Printf("Message successfully sent to channel %s at %s", channelID, timestamp)
+	logging.HandlerLogger("slack", e).Infof("Message successfully sent to channel %s at %s", channelID, timestamp)
+	return nil
+}
+
+// channel returns the channel of e, the Slack channel of the owner of its namespace when
+// OwnerChannel is set and the channel is known
+func (s *Slack) channel(e event.Event) string {
+	if s.OwnerChannel && e.Ownership != nil && e.Ownership.SlackChannel != "" {
+		return e.Ownership.SlackChannel
+	}
+	return s.Channel
 }
 
 func checkMissingSlackVars(s *Slack) error {
@@ -112,16 +179,25 @@ func prepareSlackAttachment(e event.Event, s *Slack) slack.Attachment {
 		Fields: []slack.AttachmentField{
 			{
 				Title: s.Title,
-				Value: e.Message(),
+				Value: render.Message(render.Slack, &e),
 			},
 		},
 	}
 
-	if color, ok := slackColors[e.Status]; ok {
-		attachment.Color = color
-	}
+	attachment.Color = e.Color()
 
 	attachment.MarkdownIn = []string{"fields"}
 
+	if s.AckSeverity != "" && e.ID != "" && e.ResolvedSeverity().AtLeast(s.AckSeverity) {
+		attachment.CallbackID = AckCallbackID
+		attachment.Actions = []slack.AttachmentAction{{
+			Name:  "ack",
+			Text:  "Acknowledge",
+			Type:  "button",
+			Style: "primary",
+			Value: e.ID,
+		}}
+	}
+
 	return attachment
 }
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
 )
 
 func TestSlackInit(t *testing.T) {
@@ -46,3 +47,37 @@ func TestSlackInit(t *testing.T) {
 		}
 	}
 }
+
+func TestPrepareSlackAttachment(t *testing.T) {
+	s := &Slack{Title: "kubewatch", AckSeverity: event.SeverityCritical}
+
+	attachment := prepareSlackAttachment(event.Event{ID: "crash", Kind: "Backoff", Status: "Danger"}, s)
+	if attachment.CallbackID != AckCallbackID || len(attachment.Actions) != 1 || attachment.Actions[0].Value != "crash" {
+		t.Errorf("expected an Acknowledge button for critical events, got %+v", attachment)
+	}
+	attachment = prepareSlackAttachment(event.Event{ID: "scaled", Kind: "Deployment", Status: "Normal"}, s)
+	if len(attachment.Actions) != 0 {
+		t.Errorf("expected no Acknowledge button for other events, got %+v", attachment.Actions)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	owned := event.Event{Namespace: "checkout", Ownership: &event.Ownership{Team: "payments", SlackChannel: "#payments"}}
+
+	var Tests = []struct {
+		ownerChannel bool
+		event        event.Event
+		channel      string
+	}{
+		{true, owned, "#payments"},
+		{false, owned, "#alerts"},
+		{true, event.Event{Namespace: "checkout", Ownership: &event.Ownership{Team: "payments"}}, "#alerts"},
+		{true, event.Event{Namespace: "checkout"}, "#alerts"},
+	}
+	for i, tt := range Tests {
+		s := &Slack{Channel: "#alerts", OwnerChannel: tt.ownerChannel}
+		if channel := s.channel(tt.event); channel != tt.channel {
+			t.Errorf("%d: channel() = %q, expected %q", i, channel, tt.channel)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// threadTTL is how long the events of a group key are posted in the thread of the first one
+const threadTTL = 24 * time.Hour
+
+// threads holds the Slack threads of the group keys of events
+type threads struct {
+	mu sync.Mutex
+	// started holds the timestamp of the first message of each group key, and when it was posted
+	started map[string]thread
+	// now is replaced in tests
+	now func() time.Time
+}
+
+type thread struct {
+	timestamp string
+	at        time.Time
+}
+
+func newThreads() *threads {
+	return &threads{started: map[string]thread{}, now: time.Now}
+}
+
+// get returns the timestamp of the thread of key, empty when there is no recent thread
+func (t *threads) get(key string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	th, ok := t.started[key]
+	if !ok || t.now().Sub(th.at) > threadTTL {
+		return ""
+	}
+	return th.timestamp
+}
+
+// start records that the thread of key starts with the message posted at timestamp,
+// forgetting the expired threads
+func (t *threads) start(key, timestamp string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	for k, th := range t.started {
+		if now.Sub(th.at) > threadTTL {
+			delete(t.started, k)
+		}
+	}
+	t.started[key] = thread{timestamp: timestamp, at: now}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThreads(t *testing.T) {
+	th := newThreads()
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	th.now = func() time.Time { return current }
+
+	if ts := th.get("payments/ReplicaSet/api-7d9f"); ts != "" {
+		t.Errorf("expected no thread yet, got %q", ts)
+	}
+	th.start("payments/ReplicaSet/api-7d9f", "1714557600.000100")
+	current = current.Add(time.Hour)
+	if ts := th.get("payments/ReplicaSet/api-7d9f"); ts != "1714557600.000100" {
+		t.Errorf("expected the events to be posted in the thread, got %q", ts)
+	}
+	if ts := th.get("payments/ReplicaSet/web-5c8b"); ts != "" {
+		t.Errorf("expected other group keys not to be threaded, got %q", ts)
+	}
+
+	current = current.Add(threadTTL)
+	if ts := th.get("payments/ReplicaSet/api-7d9f"); ts != "" {
+		t.Errorf("expected the thread to expire, got %q", ts)
+	}
+	th.start("payments/ReplicaSet/web-5c8b", "1714651200.000200")
+	if len(th.started) != 1 {
+		t.Errorf("expected expired threads to be forgotten, got %v", th.started)
+	}
+}
@@ -38,15 +38,18 @@ Example Message send to slack webhook (In Python):
 package slackwebhook
 
 import (
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"os"
-	"time"
 
 	"github.com/slack-go/slack"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	slackhandler "github.com/bitnami-labs/kubewatch/pkg/handlers/slack"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/render"
 )
 
 var webhookErrMsg = `
@@ -71,6 +74,8 @@ type SlackWebhook struct {
 	Username        string
 	Emoji           string
 	Slackwebhookurl string
+	Mentions        config.SlackMentions
+	client          *httpclient.Client
 }
 
 // Init prepares Webhook configuration
@@ -97,30 +102,53 @@ func (m *SlackWebhook) Init(c *config.Config) error {
 	m.Username = username
 	m.Emoji = emoji
 	m.Slackwebhookurl = slackwebhookurl
+	m.Mentions = c.Handler.SlackWebhook.Mentions
+
+	client, err := httpclient.New(c, "slackwebhook")
+	if err != nil {
+		return err
+	}
+	m.client = client
 
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
 func (m *SlackWebhook) Handle(e event.Event) {
+	if err := m.HandleWithError(e); err != nil {
+		logging.HandlerLogger("slackwebhook", e).Errorf("Error sending message: %v", err)
+	}
+}
 
+// HandleWithError handles an event and reports delivery failures.
+func (m *SlackWebhook) HandleWithError(e event.Event) error {
+	return m.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e to the Slack incoming webhook, the request being canceled when
+// ctx is done
+func (m *SlackWebhook) HandleContext(ctx context.Context, e event.Event) error {
+	text := render.TextMessage(render.Slack, &e)
+	if mention := slackhandler.Mention(m.Mentions, e); mention != "" {
+		text = mention + " " + text
+	}
 	webhookMessage := slack.WebhookMessage{
 		Channel:   m.Channel,
 		Username:  m.Username,
-		Text:      e.Message(),
+		Text:      text,
 		IconEmoji: m.Emoji,
 	}
 
-	logrus.Printf("slackwebhook-handle():Slackwebhook WebHookMessage: %s", webhookMessage.Text)
+	logging.HandlerLogger("slackwebhook", e).Debugf("Sending message: %s", webhookMessage.Text)
 
-	err := slack.PostWebhook(m.Slackwebhookurl, &webhookMessage)
+	err := slack.PostWebhookCustomHTTPContext(ctx, m.Slackwebhookurl, m.client.HTTPClient(), &webhookMessage)
 
 	if err != nil {
-		logrus.Printf("slackwebhook-handle() Error: %s\n", err)
-		return
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to %s at %s. Message: %s", m.Slackwebhookurl, time.Now(), webhookMessage.Text)
+	logging.HandlerLogger("slackwebhook", e).Infof("Message successfully sent to %s. Message: %s", m.Slackwebhookurl, webhookMessage.Text)
+	return nil
 }
 
 func checkMissingWebhookVars(s *SlackWebhook) error {
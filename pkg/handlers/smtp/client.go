@@ -42,7 +42,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func sendEmail(conf config.SMTP, msg string) error {
+// sendEmail sends an email with plain text and HTML alternative bodies
+func sendEmail(conf config.SMTP, text, html string) error {
 	ctx := context.Background()
 
 	host, port, err := net.SplitHostPort(conf.Smarthost)
@@ -149,9 +150,12 @@ func sendEmail(conf config.SMTP, msg string) error {
 	}
 	defer message.Close()
 
-	if conf.Headers == nil {
-		conf.Headers = map[string]string{}
+	// the configured headers are shared by every email
+	headers := make(map[string]string, len(conf.Headers)+3)
+	for header, value := range conf.Headers {
+		headers[header] = value
 	}
+	conf.Headers = headers
 	if _, ok := conf.Headers["Subject"]; !ok {
 		s := conf.Subject
 		if s == "" {
@@ -190,22 +194,28 @@ func sendEmail(conf config.SMTP, msg string) error {
 	if err != nil {
 		return fmt.Errorf("write headers: %w", err)
 	}
-	w, err := multipartWriter.CreatePart(textproto.MIMEHeader{
-		"Content-Transfer-Encoding": {"quoted-printable"},
-		"Content-Type":              {"text/plain; charset=UTF-8"},
-	})
-	if err != nil {
-		return fmt.Errorf("create part for text template: %w", err)
-	}
+	// clients show the last part they support, HTML over plain text
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", text},
+		{"text/html", html},
+	} {
+		w, err := multipartWriter.CreatePart(textproto.MIMEHeader{
+			"Content-Transfer-Encoding": {"quoted-printable"},
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+		})
+		if err != nil {
+			return fmt.Errorf("create %s part: %w", part.contentType, err)
+		}
 
-	qw := quotedprintable.NewWriter(w)
-	_, err = qw.Write([]byte(msg))
-	if err != nil {
-		return fmt.Errorf("write text part: %w", err)
-	}
-	err = qw.Close()
-	if err != nil {
-		return fmt.Errorf("close text part: %w", err)
+		qw := quotedprintable.NewWriter(w)
+		_, err = qw.Write([]byte(part.body))
+		if err != nil {
+			return fmt.Errorf("write %s part: %w", part.contentType, err)
+		}
+		err = qw.Close()
+		if err != nil {
+			return fmt.Errorf("close %s part: %w", part.contentType, err)
+		}
 	}
 
 	err = multipartWriter.Close()
@@ -219,7 +229,7 @@ func sendEmail(conf config.SMTP, msg string) error {
 		return fmt.Errorf("write body buffer: %w", err)
 	}
 
-	logrus.Printf("sending via %s:%s, to: %q, from: %q : %s ", host, port, conf.To, conf.From, msg)
+	logrus.Printf("sending via %s:%s, to: %q, from: %q : %s ", host, port, conf.To, conf.From, text)
 	return nil
 }
 
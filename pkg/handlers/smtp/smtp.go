@@ -23,11 +23,11 @@ package smtp
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
-	"github.com/sirupsen/logrus"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/render"
 )
 
 const (
@@ -71,16 +71,26 @@ func (s *SMTP) Init(c *config.Config) error {
 
 // Handle handles the notification.
 func (s *SMTP) Handle(e event.Event) {
-	send(s.cfg, e.Message())
-	logrus.Printf("Message successfully sent to %s at %s ", s.cfg.To, time.Now())
+	if err := s.HandleWithError(e); err != nil {
+		logging.HandlerLogger("smtp", e).Errorf("Error sending message: %v", err)
+	}
 }
 
-func formatEmail(e event.Event) (string, error) {
-	return e.Message(), nil
+// HandleWithError handles the notification and reports delivery failures.
+func (s *SMTP) HandleWithError(e event.Event) error {
+	text, html := formatEmail(e)
+	cfg := s.cfg
+	if cfg.Subject == "" {
+		cfg.Subject = e.Summary()
+	}
+	if err := sendEmail(cfg, text, html); err != nil {
+		return err
+	}
+	logging.HandlerLogger("smtp", e).Infof("Message successfully sent to %s", s.cfg.To)
+	return nil
 }
 
-func send(conf config.SMTP, msg string) {
-	if err := sendEmail(conf, msg); err != nil {
-		logrus.Error(err)
-	}
+// formatEmail returns the plain text and HTML bodies of the email about e
+func formatEmail(e event.Event) (string, string) {
+	return render.TextMessage(render.Text, &e), "<html><body>\n" + render.TextMessage(render.HTML, &e) + "\n</body></html>"
 }
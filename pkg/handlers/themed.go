@@ -0,0 +1,61 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Themed wraps a handler, rendering the events it sends with Theme
+type Themed struct {
+	Theme   *event.Theme
+	Handler Handler
+}
+
+// Init initializes the wrapped handler
+func (t *Themed) Init(conf *config.Config) error {
+	return t.Handler.Init(conf)
+}
+
+// Handle sends the event to the wrapped handler, themed.
+func (t *Themed) Handle(e event.Event) {
+	e.Theme = t.Theme
+	t.Handler.Handle(e)
+}
+
+// HandleWithError sends the event to the wrapped handler, themed, reporting delivery
+// failures when the handler reports them.
+func (t *Themed) HandleWithError(e event.Event) error {
+	return t.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e, rendered with Theme, to the wrapped handler with ctx
+func (t *Themed) HandleContext(ctx context.Context, e event.Event) error {
+	e.Theme = t.Theme
+	return Deliver(ctx, t.Handler, e)
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (t *Themed) Flush() error {
+	if f, ok := t.Handler.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestThemed(t *testing.T) {
+	minimal, err := event.NewTheme("minimal", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := &recordingHandler{}
+	h := &Themed{Theme: minimal, Handler: wrapped}
+	h.Handle(event.Event{Kind: "Pod", Namespace: "default", Name: "web", Reason: "Deleted", Status: "Danger"})
+	if summary, expected := wrapped.handled[0].Summary(), "[CRITICAL] Pod default/web deleted"; summary != expected {
+		t.Errorf("Summary() = %q, expected %q", summary, expected)
+	}
+	if emoji := wrapped.handled[0].Emoji(); emoji != "" {
+		t.Errorf("Emoji() = %q, expected none", emoji)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/sirupsen/logrus"
+)
+
+// transformFuncs are the functions of transform templates, besides the builtin ones
+var transformFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"regexReplace": func(expr, repl, s string) (string, error) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(s, repl), nil
+	},
+	"default": func(value, s string) string {
+		if s == "" {
+			return value
+		}
+		return s
+	},
+}
+
+// Transform sets a field of events to the value rendered by a template
+type Transform struct {
+	// kinds restricts the transform to events of these kinds, nil for all events
+	kinds    map[string]bool
+	field    string
+	template *template.Template
+}
+
+// ParseTransforms parses the templates of the configured transforms
+func ParseTransforms(transforms []config.Transform) ([]Transform, error) {
+	parsed := make([]Transform, 0, len(transforms))
+	for i, t := range transforms {
+		if !config.TransformField(t.Field) {
+			return nil, fmt.Errorf("transform %d: unknown field %q", i, t.Field)
+		}
+		tmpl, err := template.New(t.Field).Funcs(transformFuncs).Option("missingkey=zero").Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d of %s: %v", i, t.Field, err)
+		}
+		parsed = append(parsed, Transform{kinds: stringSet(t.Kinds), field: t.Field, template: tmpl})
+	}
+	return parsed, nil
+}
+
+// apply sets the field of e to the value rendered with e, leaving it unchanged when the
+// value is empty
+func (t Transform) apply(e *event.Event) {
+	if t.kinds != nil && !t.kinds[e.Kind] {
+		return
+	}
+	var value bytes.Buffer
+	if err := t.template.Execute(&value, e); err != nil {
+		logrus.WithFields(logging.EventFields(*e)).WithField("pkg", "kubewatch-transforms").Warnf("Error rendering %s: %v", t.field, err)
+		return
+	}
+	v := strings.TrimSpace(value.String())
+	if v == "" {
+		return
+	}
+	switch {
+	case t.field == "name":
+		e.Name = v
+	case t.field == "namespace":
+		e.Namespace = v
+	case t.field == "kind":
+		e.Kind = v
+	case t.field == "reason":
+		e.Reason = v
+	case t.field == "status":
+		e.Status = v
+		e.Severity = event.SeverityFromStatus(v)
+	case t.field == "severity":
+		if !event.Severity(v).Known() {
+			logrus.WithFields(logging.EventFields(*e)).WithField("pkg", "kubewatch-transforms").Warnf("Ignoring unknown severity %q", v)
+			return
+		}
+		e.Severity = event.Severity(v)
+	case t.field == "groupkey":
+		e.GroupKey = v
+	case strings.HasPrefix(t.field, "labels."):
+		// labels are shared with the object of the event
+		labels := make(map[string]string, len(e.Labels)+1)
+		for key, value := range e.Labels {
+			labels[key] = value
+		}
+		labels[strings.TrimPrefix(t.field, "labels.")] = v
+		e.Labels = labels
+	case strings.HasPrefix(t.field, "fields."):
+		e.Fields = append(e.Fields[:len(e.Fields):len(e.Fields)], event.Field{Name: strings.TrimPrefix(t.field, "fields."), Value: v})
+	}
+}
+
+// Transformed wraps a handler, applying transforms to events before sending them
+type Transformed struct {
+	Transforms []Transform
+	Handler    Handler
+}
+
+// Init initializes the wrapped handler
+func (t *Transformed) Init(c *config.Config) error {
+	return t.Handler.Init(c)
+}
+
+// Handle sends the transformed event to the wrapped handler.
+func (t *Transformed) Handle(e event.Event) {
+	t.Handler.Handle(t.transform(e))
+}
+
+// HandleWithError sends the transformed event to the wrapped handler, reporting delivery
+// failures when the handler reports them.
+func (t *Transformed) HandleWithError(e event.Event) error {
+	return t.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx once transformed
+func (t *Transformed) HandleContext(ctx context.Context, e event.Event) error {
+	e = t.transform(e)
+	return Deliver(ctx, t.Handler, e)
+}
+
+// transform applies the transforms to e in order, each seeing the changes of the previous
+func (t *Transformed) transform(e event.Event) event.Event {
+	for _, transform := range t.Transforms {
+		transform.apply(&e)
+	}
+	return e
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (t *Transformed) Flush() error {
+	if f, ok := t.Handler.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestTransformed(t *testing.T) {
+	transforms, err := ParseTransforms([]config.Transform{
+		{Field: "name", Template: `{{regexReplace "-[a-z0-9]{5}$" "" .Name}}`},
+		{Kinds: []string{"Job"}, Field: "reason", Template: `{{if eq .Reason "BackoffLimitExceeded"}}Job failed{{end}}`},
+		{Field: "labels.team", Template: `{{with .Ownership}}{{.Team}}{{end}}`},
+		{Field: "fields.cluster", Template: `{{default "unknown" .ClusterName}}`},
+		{Field: "status", Template: `{{if eq .Kind "Job"}}Danger{{end}}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := map[string]string{"app": "backup"}
+	wrapped := &recordingHandler{}
+	h := &Transformed{Transforms: transforms, Handler: wrapped}
+	h.Handle(event.Event{
+		Name:      "backup-x7k2p",
+		Kind:      "Job",
+		Status:    "Warning",
+		Severity:  event.SeverityWarning,
+		Reason:    "BackoffLimitExceeded",
+		Labels:    labels,
+		Ownership: &event.Ownership{Team: "payments"},
+	})
+	h.Handle(event.Event{Name: "web", Kind: "Pod", Reason: "BackoffLimitExceeded", ClusterName: "prod"})
+
+	job, pod := wrapped.handled[0], wrapped.handled[1]
+	if job.Name != "backup" || job.Reason != "Job failed" || job.Status != "Danger" || job.Severity != event.SeverityCritical {
+		t.Errorf("transformed Job = %+v", job)
+	}
+	if expected := map[string]string{"app": "backup", "team": "payments"}; !reflect.DeepEqual(job.Labels, expected) {
+		t.Errorf("labels = %v, expected %v", job.Labels, expected)
+	}
+	if len(labels) != 1 {
+		t.Errorf("the labels of the original event were changed: %v", labels)
+	}
+	if expected := []event.Field{{Name: "cluster", Value: "unknown"}}; !reflect.DeepEqual(job.Fields, expected) {
+		t.Errorf("fields = %v, expected %v", job.Fields, expected)
+	}
+	// transforms rendering nothing leave fields unchanged
+	if pod.Name != "web" || pod.Reason != "BackoffLimitExceeded" || pod.Labels != nil || pod.Status != "" {
+		t.Errorf("transformed Pod = %+v", pod)
+	}
+	if expected := []event.Field{{Name: "cluster", Value: "prod"}}; !reflect.DeepEqual(pod.Fields, expected) {
+		t.Errorf("fields = %v, expected %v", pod.Fields, expected)
+	}
+
+	if _, err := ParseTransforms([]config.Transform{{Field: "uid", Template: "x"}}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, err := ParseTransforms([]config.Transform{{Field: "name", Template: "{{"}}); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
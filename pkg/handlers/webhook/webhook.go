@@ -17,19 +17,20 @@ limitations under the License.
 package webhook
 
 import (
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"os"
 
-	"bytes"
 	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
 )
 
 var webhookErrMsg = `
@@ -48,7 +49,8 @@ Command line flags will override environment variables
 // Webhook handler implements handler.Handler interface,
 // Notify event to Webhook channel
 type Webhook struct {
-	Url string
+	Url    string
+	client *httpclient.Client
 }
 
 // WebhookMessage for messages
@@ -56,6 +58,8 @@ type WebhookMessage struct {
 	EventMeta EventMeta `json:"eventmeta"`
 	Text      string    `json:"text"`
 	Time      time.Time `json:"time"`
+	// Event is the canonical representation of the event, see event.JSONSchema
+	Event event.Payload `json:"event"`
 }
 
 // EventMeta containes the meta data about the event occurred
@@ -64,6 +68,25 @@ type EventMeta struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
 	Reason    string `json:"reason"`
+	// APIVersion, UID and Labels describe the object the event is about
+	APIVersion string            `json:"apiVersion,omitempty"`
+	UID        string            `json:"uid,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Severity is one of info, warning or critical
+	Severity event.Severity `json:"severity"`
+	Cluster  string         `json:"cluster,omitempty"`
+	// Environment, Region and ClusterLabels describe the cluster the event comes from
+	Environment   string            `json:"environment,omitempty"`
+	Region        string            `json:"region,omitempty"`
+	ClusterLabels map[string]string `json:"clusterLabels,omitempty"`
+	// Diff holds the fields changed by an update
+	Diff []diff.Change `json:"diff,omitempty"`
+	// ImageChanges holds the container images changed by a workload update
+	ImageChanges []event.ImageChange `json:"imageChanges,omitempty"`
+	// Scale holds the replica count change of a scaled workload
+	Scale *event.ScaleChange `json:"scale,omitempty"`
+	// InvolvedObject describes the object a Kubernetes Event is about
+	InvolvedObject *event.InvolvedObject `json:"involvedObject,omitempty"`
 }
 
 // Init prepares Webhook configuration
@@ -81,38 +104,43 @@ func (m *Webhook) Init(c *config.Config) error {
 
 	m.Url = url
 
-	if tlsSkip {
-		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	} else {
-		if cert == "" {
-			logrus.Printf("No webhook cert is given")
-		} else {
-			caCert, err := os.ReadFile(cert)
-			if err != nil {
-				logrus.Printf("%s\n", err)
-				return err
-			}
-			caCertPool := x509.NewCertPool()
-			caCertPool.AppendCertsFromPEM(caCert)
-			http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: caCertPool}
-		}
-
+	if !tlsSkip && cert == "" {
+		logrus.Printf("No webhook cert is given")
+	}
+	client, err := httpclient.NewTLS(c, "webhook", httpclient.TLS{CAFile: cert, InsecureSkipVerify: tlsSkip})
+	if err != nil {
+		logrus.Printf("%s\n", err)
+		return err
 	}
+	m.client = client
 
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
 func (m *Webhook) Handle(e event.Event) {
+	if err := m.HandleWithError(e); err != nil {
+		logging.HandlerLogger("webhook", e).Errorf("Error sending message: %v", err)
+	}
+}
+
+// HandleWithError handles an event and reports delivery failures.
+func (m *Webhook) HandleWithError(e event.Event) error {
+	return m.HandleContext(context.Background(), e)
+}
+
+// HandleContext posts e as JSON to the webhook URL, the request being canceled when ctx
+// is done
+func (m *Webhook) HandleContext(ctx context.Context, e event.Event) error {
 	webhookMessage := prepareWebhookMessage(e, m)
 
-	err := postMessage(m.Url, webhookMessage)
+	err := postMessage(ctx, m.client, m.Url, webhookMessage)
 	if err != nil {
-		logrus.Printf("%s\n", err)
-		return
+		return err
 	}
 
-	logrus.Printf("Message successfully sent to %s at %s ", m.Url, time.Now())
+	logging.HandlerLogger("webhook", e).Infof("Message successfully sent to %s", m.Url)
+	return nil
 }
 
 func checkMissingWebhookVars(s *Webhook) error {
@@ -126,32 +154,50 @@ func checkMissingWebhookVars(s *Webhook) error {
 func prepareWebhookMessage(e event.Event, m *Webhook) *WebhookMessage {
 	return &WebhookMessage{
 		EventMeta: EventMeta{
-			Kind:      e.Kind,
-			Name:      e.Name,
-			Namespace: e.Namespace,
-			Reason:    e.Reason,
+			Kind:           e.Kind,
+			APIVersion:     e.APIVersion,
+			Name:           e.Name,
+			Namespace:      e.Namespace,
+			UID:            string(e.UID),
+			Labels:         e.Labels,
+			Reason:         e.Reason,
+			Severity:       e.ResolvedSeverity(),
+			Cluster:        e.ClusterName,
+			Environment:    e.Environment,
+			Region:         e.Region,
+			ClusterLabels:  e.ClusterLabels,
+			Diff:           e.Diff,
+			ImageChanges:   e.ImageChanges,
+			Scale:          e.Scale,
+			InvolvedObject: e.Involved,
 		},
-		Text: e.Message(),
-		Time: time.Now(),
+		Text:  e.Message(),
+		Time:  eventTime(e),
+		Event: e.Payload(),
 	}
 }
 
-func postMessage(url string, webhookMessage *WebhookMessage) error {
+// eventTime returns when the event was observed, defaulting to now
+func eventTime(e event.Event) time.Time {
+	if e.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return e.Timestamp
+}
+
+func postMessage(ctx context.Context, client *httpclient.Client, url string, webhookMessage *WebhookMessage) error {
 	message, err := json.Marshal(webhookMessage)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
+	resp, err := client.PostContext(ctx, url, "application/json", message)
 	if err != nil {
 		return err
 	}
-	req.Header.Add("Content-Type", "application/json")
 
-	client := &http.Client{}
-	_, err = client.Do(req)
-	if err != nil {
-		return err
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook %s returned %s", url, resp.Status)
 	}
 
 	return nil
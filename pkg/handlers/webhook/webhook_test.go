@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
 )
 
 func TestWebhookInit(t *testing.T) {
@@ -44,3 +45,15 @@ func TestWebhookInit(t *testing.T) {
 		}
 	}
 }
+
+func TestPrepareWebhookMessage(t *testing.T) {
+	e := event.Event{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Deleted", Status: "Danger"}
+	message := prepareWebhookMessage(e, &Webhook{})
+
+	if message.Event.SchemaVersion != event.SchemaVersion || message.Event.Operation != "delete" || message.Event.Severity != event.SeverityCritical {
+		t.Errorf("expected the canonical event in the message, got %+v", message.Event)
+	}
+	if message.Event.Message != message.Text || message.EventMeta.Name != "web" {
+		t.Errorf("expected the legacy fields to be kept, got %+v", message)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package handlerstest helps testing handlers, built-in or written by third parties:
+// canonical events to send them, a Server recording the requests of handlers sending
+// their messages over HTTP, and a conformance Suite checking how they format events and
+// report delivery failures.
+package handlerstest
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/diff"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Observed is when the canonical events were observed
+var Observed = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// Fixture is a canonical event, named after what it covers
+type Fixture struct {
+	Name  string
+	Event event.Event
+}
+
+// Fixtures returns the canonical events handlers are tested with: the events of the
+// lifecycle of objects, with the details the controller adds to them, failures, cluster
+// scoped objects and values needing to be escaped. The events are built on each call, so
+// that tests can modify them.
+func Fixtures() []Fixture {
+	return []Fixture{
+		{
+			Name: "created",
+			Event: event.Event{
+				ID:          "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a01",
+				Namespace:   "default",
+				Kind:        "Deployment",
+				APIVersion:  "apps/v1",
+				Name:        "web",
+				UID:         "9b2a5c1e-4d7f-4b8a-8c3e-2f1d0e9a7b6c",
+				Labels:      map[string]string{"app": "web"},
+				Reason:      "Created",
+				Status:      "Normal",
+				Severity:    event.SeverityInfo,
+				Timestamp:   Observed,
+				ClusterName: "prod",
+			},
+		},
+		{
+			Name: "updated",
+			Event: event.Event{
+				ID:         "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a02",
+				Namespace:  "default",
+				Kind:       "Deployment",
+				APIVersion: "apps/v1",
+				Name:       "web",
+				UID:        "9b2a5c1e-4d7f-4b8a-8c3e-2f1d0e9a7b6c",
+				Labels:     map[string]string{"app": "web"},
+				Reason:     "Updated",
+				Status:     "Warning",
+				Severity:   event.SeverityWarning,
+				Timestamp:  Observed.Add(time.Minute),
+				ChangedBy:  "kubectl-set",
+				Diff: []diff.Change{
+					{Path: "spec.template.spec.containers[0].image", Old: "nginx:1.25", New: "nginx:1.26"},
+					{Path: "spec.replicas", Old: int64(2), New: int64(3)},
+				},
+				ImageChanges: []event.ImageChange{{Container: "nginx", Old: "nginx:1.25", New: "nginx:1.26"}},
+				Scale:        &event.ScaleChange{From: 2, To: 3, Manager: "kubectl-scale"},
+				Links:        []event.Link{{Name: "Dashboard", URL: "https://grafana.example.com/d/web?var-namespace=default"}},
+			},
+		},
+		{
+			Name: "deleted",
+			Event: event.Event{
+				ID:         "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a03",
+				Namespace:  "default",
+				Kind:       "Pod",
+				APIVersion: "v1",
+				Name:       "web-7d9c8b6f5-x2l4q",
+				UID:        "0c4e8a2f-6b1d-4f3a-9e7c-5a2b1d0f8e3c",
+				Reason:     "Deleted",
+				Status:     "Danger",
+				Severity:   event.SeverityWarning,
+				Timestamp:  Observed.Add(2 * time.Minute),
+				Deletion:   &event.Deletion{DeletedBy: "alice@example.com", Finalizers: []string{"example.com/cleanup"}},
+			},
+		},
+		{
+			Name: "failure",
+			Event: event.Event{
+				ID:         "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a04",
+				Namespace:  "payments",
+				Kind:       "Pod",
+				APIVersion: "v1",
+				Name:       "api-5f7b9c-k8m2n",
+				Reason:     "Back-off restarting failed container api in pod api-5f7b9c-k8m2n",
+				Status:     "Danger",
+				Severity:   event.SeverityCritical,
+				Timestamp:  Observed.Add(3 * time.Minute),
+				Logs: &event.ContainerLogs{
+					Container: "api",
+					Reason:    "Error",
+					ExitCode:  1,
+					Text:      "starting api\nconnecting to postgres://db:5432\npanic: connection refused\n",
+				},
+				Involved: &event.InvolvedObject{Kind: "Pod", Name: "api-5f7b9c-k8m2n", Namespace: "payments", Owner: "Deployment/api"},
+			},
+		},
+		{
+			Name: "cluster scoped",
+			Event: event.Event{
+				ID:         "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a05",
+				Kind:       "Node",
+				APIVersion: "v1",
+				Name:       "ip-10-0-1-23.ec2.internal",
+				Reason:     "Updated",
+				Status:     "Normal",
+				Severity:   event.SeverityInfo,
+				Timestamp:  Observed.Add(4 * time.Minute),
+				Diff:       []diff.Change{{Path: "spec.unschedulable", New: true}},
+			},
+		},
+		{
+			Name: "resolved",
+			Event: event.Event{
+				ID:            "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a06",
+				CorrelationID: "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a04",
+				Namespace:     "payments",
+				Kind:          "Resolved",
+				APIVersion:    "v1",
+				Name:          "api-5f7b9c-k8m2n",
+				Reason:        "Pod payments/api-5f7b9c-k8m2n is no longer in CrashLoopBackOff",
+				Status:        "Normal",
+				Severity:      event.SeverityInfo,
+				Timestamp:     Observed.Add(15 * time.Minute),
+				Resolution: &event.Resolution{
+					Problem:    "CrashLoopBackOff",
+					ProblemID:  "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a04",
+					StartedAt:  Observed.Add(3 * time.Minute),
+					ResolvedAt: Observed.Add(15 * time.Minute),
+				},
+			},
+		},
+		{
+			Name: "special characters",
+			Event: event.Event{
+				ID:         "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a07",
+				Namespace:  "i18n",
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+				Name:       "greetings",
+				Labels:     map[string]string{"team": "web_&_mobile"},
+				Reason:     "Value <b>\"héllo\"</b> & *bonjour* _hola_ `こんにちは` 👋\nsecond line\twith tab \\ backslash",
+				Status:     "Warning",
+				Severity:   event.SeverityWarning,
+				Timestamp:  Observed.Add(5 * time.Minute),
+				Fields:     []event.Field{{Name: "Greeting", Value: "[hi](https://example.com) | <!channel> @here"}},
+			},
+		},
+		{
+			Name: "large",
+			Event: event.Event{
+				ID:         "3f6d1c3e-0b7e-4a8e-9a52-6f0f0c7d2a08",
+				Namespace:  "batch",
+				Kind:       "Pod",
+				APIVersion: "v1",
+				Name:       "report-28471920-9qz7c",
+				Reason:     "OOMKilled",
+				Status:     "Danger",
+				Severity:   event.SeverityCritical,
+				Timestamp:  Observed.Add(6 * time.Minute),
+				Logs: &event.ContainerLogs{
+					Container: "report",
+					Reason:    "OOMKilled",
+					ExitCode:  137,
+					Text:      strings.Repeat("processing batch of 10000 rows from the orders table\n", 200),
+					Truncated: true,
+				},
+			},
+		},
+	}
+}
+
+// Get returns the canonical event called name, panicking if there is none
+func Get(name string) event.Event {
+	for _, f := range Fixtures() {
+		if f.Name == name {
+			return f.Event
+		}
+	}
+	panic("handlerstest: no fixture called " + name)
+}
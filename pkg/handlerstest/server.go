@@ -0,0 +1,141 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlerstest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Request is a request received by a Server
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	// Body is the content of the request, decompressed when sent with Content-Encoding: gzip
+	Body []byte
+}
+
+// JSON decodes the body of r into v
+func (r Request) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Server is an HTTP server recording the requests of the handlers under test. It answers
+// 200 OK, unless set to answer with another status or to be slow.
+type Server struct {
+	// URL of the server, to configure the handlers with
+	URL string
+
+	mu       sync.Mutex
+	requests []Request
+	status   int
+	delay    time.Duration
+
+	server *httptest.Server
+	closed chan struct{}
+}
+
+// NewServer starts a Server, closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{status: http.StatusOK, closed: make(chan struct{})}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: b})
+	status, delay := s.status, s.delay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		case <-s.closed:
+			return
+		}
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, http.StatusText(status))
+}
+
+// Requests returns the requests received since the server started or was reset
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets the requests received, and answers 200 OK without delay again
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests, s.status, s.delay = nil, http.StatusOK, 0
+}
+
+// Respond sets the status of the responses to the next requests
+func (s *Server) Respond(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// Delay sets how long the next requests are held before being answered. Held requests
+// are dropped when they are canceled or the server is closed.
+func (s *Server) Delay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Close stops the server, dropping the requests it holds. Handlers then fail to deliver
+// events to its URL.
+func (s *Server) Close() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+	close(s.closed)
+	s.server.Close()
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlerstest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+)
+
+const (
+	// deliveryTimeout bounds the deliveries of the suite
+	deliveryTimeout = 5 * time.Second
+	// cancelAfter is the timeout of the deliveries to a server that does not answer
+	cancelAfter = 100 * time.Millisecond
+)
+
+// Suite is the conformance suite of handlers sending their messages over HTTP. Handlers
+// only implementing handlers.ContextHandler are given with handlers.FromContext.
+type Suite struct {
+	// New returns a handler sending its messages to url, and the configuration the suite
+	// initializes it with
+	New func(url string) (handlers.Handler, *config.Config)
+	// Check validates the requests the handler sent for the event of f, such as their
+	// formatting, on top of the checks of the suite. Optional.
+	Check func(t testing.TB, f Fixture, requests []Request)
+	// Fixtures are the events sent to the handler, the canonical ones when empty
+	Fixtures []Fixture
+}
+
+// Run checks that the handler:
+//   - is initialized with its configuration
+//   - sends every fixture in requests naming its object, with UTF-8 bodies that are valid
+//     JSON when their content type is JSON
+//   - reports delivery failures when the server answers with an error status or cannot
+//     be reached, so that the controller retries them
+//   - stops being waited for once the context of the delivery is done
+//   - delivers events concurrently, run the tests with -race to check data races
+func (s Suite) Run(t *testing.T) {
+	fixtures := s.Fixtures
+	if len(fixtures) == 0 {
+		fixtures = Fixtures()
+	}
+
+	t.Run("fixtures", func(t *testing.T) {
+		for _, f := range fixtures {
+			t.Run(f.Name, func(t *testing.T) {
+				server := NewServer(t)
+				h := s.start(t, server.URL)
+				if err := deliver(context.Background(), h, f.Event); err != nil {
+					t.Fatalf("delivery failed: %v", err)
+				}
+				requests := server.Requests()
+				CheckRequests(t, f.Event, requests)
+				if s.Check != nil {
+					s.Check(t, f, requests)
+				}
+			})
+		}
+	})
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		t.Run(fmt.Sprintf("status %d", status), func(t *testing.T) {
+			server := NewServer(t)
+			h := s.start(t, server.URL)
+			server.Respond(status)
+			if err := deliver(context.Background(), h, fixtures[0].Event); err == nil {
+				t.Errorf("delivery answered with %d succeeded, expected the handler to report the failure%s", status, reportsFailures(h))
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := NewServer(t)
+		h := s.start(t, server.URL)
+		server.Close()
+		if err := deliver(context.Background(), h, fixtures[0].Event); err == nil {
+			t.Errorf("delivery to a closed server succeeded, expected the handler to report the failure%s", reportsFailures(h))
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		server := NewServer(t)
+		h := s.start(t, server.URL)
+		server.Delay(time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), cancelAfter)
+		defer cancel()
+		start := time.Now()
+		if err := deliver(ctx, h, fixtures[0].Event); err == nil {
+			t.Errorf("delivery to a server not answering succeeded, expected the handler to report the failure")
+		}
+		if elapsed := time.Since(start); elapsed > deliveryTimeout {
+			t.Errorf("delivery returned after %v, expected the handler to stop once the context is done", elapsed)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		const rounds = 3
+		server := NewServer(t)
+		h := s.start(t, server.URL)
+		var wg sync.WaitGroup
+		errs := make(chan error, rounds*len(fixtures))
+		for i := 0; i < rounds; i++ {
+			for _, f := range fixtures {
+				wg.Add(1)
+				go func(e event.Event) {
+					defer wg.Done()
+					errs <- deliver(context.Background(), h, e)
+				}(f.Event)
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("concurrent delivery failed: %v", err)
+			}
+		}
+		if n := len(server.Requests()); n < rounds*len(fixtures) {
+			t.Errorf("server received %d requests, expected at least %d", n, rounds*len(fixtures))
+		}
+	})
+}
+
+// start returns the handler under test, initialized
+func (s Suite) start(t testing.TB, url string) handlers.Handler {
+	t.Helper()
+	h, conf := s.New(url)
+	if conf == nil {
+		conf = &config.Config{}
+	}
+	if err := h.Init(conf); err != nil {
+		t.Fatalf("Init() = %v, expected no error", err)
+	}
+	return h
+}
+
+// deliver sends e to h within deliveryTimeout, flushing the handlers buffering events
+func deliver(ctx context.Context, h handlers.Handler, e event.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	if err := handlers.Deliver(ctx, h, e); err != nil {
+		return err
+	}
+	if f, ok := h.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// reportsFailures explains why h cannot report delivery failures, empty if it can
+func reportsFailures(h handlers.Handler) string {
+	switch h.(type) {
+	case handlers.ContextHandler, handlers.RetryableHandler:
+		return ""
+	}
+	return ", which requires implementing handlers.ContextHandler"
+}
+
+// CheckRequests checks the requests a handler sent for e: at least one request, bodies
+// that are valid UTF-8, and valid JSON when their content type is JSON, and naming the
+// object of e
+func CheckRequests(t testing.TB, e event.Event, requests []Request) {
+	t.Helper()
+	if len(requests) == 0 {
+		t.Fatal("no request sent")
+	}
+
+	named := false
+	for i, r := range requests {
+		if len(r.Body) == 0 {
+			t.Errorf("request %d: empty body", i)
+		}
+		if !utf8.Valid(r.Body) {
+			t.Errorf("request %d: body is not valid UTF-8", i)
+		}
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			if !json.Valid(r.Body) {
+				t.Errorf("request %d: body is not valid JSON: %s", i, r.Body)
+			}
+		}
+		named = named || bytes.Contains(r.Body, []byte(e.Name))
+	}
+	if !named {
+		t.Errorf("no request names the object %s", e.Name)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlerstest_test
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/cloudevent"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/flock"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/lark"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/mattermost"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/msteam"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/slackwebhook"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/handlerstest"
+)
+
+// TestConformance runs the suite against the handlers sending their messages over HTTP
+func TestConformance(t *testing.T) {
+	var Tests = []struct {
+		name  string
+		suite handlerstest.Suite
+	}{
+		{
+			name: "webhook",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.Webhook.Url = url
+					return &webhook.Webhook{}, conf
+				},
+				Check: func(t testing.TB, f handlerstest.Fixture, requests []handlerstest.Request) {
+					var message webhook.WebhookMessage
+					if err := requests[0].JSON(&message); err != nil {
+						t.Fatal(err)
+					}
+					if message.Text != f.Event.Message() {
+						t.Errorf("text = %q, expected %q", message.Text, f.Event.Message())
+					}
+					if message.Event.ID != f.Event.ID {
+						t.Errorf("event.id = %q, expected %q", message.Event.ID, f.Event.ID)
+					}
+				},
+			},
+		},
+		{
+			name: "cloudevent",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.CloudEvent.Url = url
+					return &cloudevent.CloudEvent{}, conf
+				},
+			},
+		},
+		{
+			name: "msteams",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.MSTeams.WebhookURL = url
+					return &msteam.MSTeams{}, conf
+				},
+			},
+		},
+		{
+			name: "mattermost",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.Mattermost = config.Mattermost{Channel: "kubewatch", Url: url, Username: "kubewatch"}
+					return &mattermost.Mattermost{}, conf
+				},
+			},
+		},
+		{
+			name: "flock",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.Flock.Url = url
+					return &flock.Flock{}, conf
+				},
+			},
+		},
+		{
+			name: "lark",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.Lark.WebhookURL = url
+					return &lark.Webhook{}, conf
+				},
+			},
+		},
+		{
+			name: "slackwebhook",
+			suite: handlerstest.Suite{
+				New: func(url string) (handlers.Handler, *config.Config) {
+					conf := &config.Config{}
+					conf.Handler.SlackWebhook = config.SlackWebhook{Slackwebhookurl: url, Channel: "#kubewatch", Username: "kubewatch", Emoji: ":kubernetes:"}
+					return &slackwebhook.SlackWebhook{}, conf
+				},
+			},
+		},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.name, tt.suite.Run)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+)
+
+// defaultLimit is the number of events returned by queries without limit
+const defaultLimit = 100
+
+// Buffer keeps the last events in memory, and streams new events to its subscribers
+type Buffer struct {
+	mu     sync.Mutex
+	events []event.Payload
+	// next is the index of the next event in events, which is full once next wrapped around
+	next        int
+	full        bool
+	subscribers map[*subscriber]struct{}
+}
+
+// NewBuffer returns a Buffer keeping the last size events
+func NewBuffer(size int) *Buffer {
+	return &Buffer{events: make([]event.Payload, size)}
+}
+
+// Add keeps p, dropping the oldest event when the buffer is full, and sends it to the
+// subscribers it matches
+func (b *Buffer) Add(p event.Payload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publish(p)
+	if len(b.events) == 0 {
+		return
+	}
+	b.events[b.next] = p
+	b.next = (b.next + 1) % len(b.events)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Query filters the events kept in the buffer
+type Query struct {
+	Cluster   string
+	Namespace string
+	Kind      string
+	Name      string
+	Severity  event.Severity
+	// Since drops the events older than this time, when set
+	Since time.Time
+	// Limit is the maximum number of events returned
+	Limit int
+}
+
+// Matches returns whether p is selected by q, ignoring its limit
+func (q Query) Matches(p event.Payload) bool {
+	cluster := ""
+	if p.Cluster != nil {
+		cluster = p.Cluster.Name
+	}
+	return (q.Cluster == "" || q.Cluster == cluster) &&
+		(q.Namespace == "" || q.Namespace == p.Object.Namespace) &&
+		(q.Kind == "" || q.Kind == p.Kind) &&
+		(q.Name == "" || q.Name == p.Object.Name) &&
+		(q.Severity == "" || q.Severity == p.Severity) &&
+		(q.Since.IsZero() || !p.Timestamp.Before(q.Since))
+}
+
+// ParseQuery returns the query of the parameters of a request: cluster, namespace, kind,
+// name, severity, since as a duration (e.g. 30m) or an RFC 3339 time, and limit
+func ParseQuery(values url.Values) (Query, error) {
+	q := Query{
+		Cluster:   values.Get("cluster"),
+		Namespace: values.Get("namespace"),
+		Kind:      values.Get("kind"),
+		Name:      values.Get("name"),
+		Severity:  event.Severity(values.Get("severity")),
+		Limit:     defaultLimit,
+	}
+	if since := values.Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			q.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			q.Since = t
+		} else {
+			return q, fmt.Errorf("since: expected a duration or an RFC 3339 time, got %q", since)
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return q, fmt.Errorf("limit: expected a positive number, got %q", limit)
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+// Query returns the events matching q, most recent first
+func (b *Buffer) Query(q Query) []event.Payload {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count := b.next
+	if b.full {
+		count = len(b.events)
+	}
+	matches := []event.Payload{}
+	for i := 1; i <= count && (q.Limit <= 0 || len(matches) < q.Limit); i++ {
+		p := b.events[(b.next-i+len(b.events))%len(b.events)]
+		if q.Matches(p) {
+			matches = append(matches, p)
+		}
+	}
+	return matches
+}
+
+// ServeHTTP answers queries of the events API, such as /api/v1/events?namespace=payments&since=30m
+func (b *Buffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveQuery(w, r, func(q Query) ([]event.Payload, error) {
+		return b.Query(q), nil
+	})
+}
+
+// serveQuery answers a query of the events API with the events returned by query
+func serveQuery(w http.ResponseWriter, r *http.Request, query func(Query) ([]event.Payload, error)) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Events []event.Payload `json:"events"`
+	}{events})
+}
+
+// Handler wraps a handler, keeping the events it delivers in Buffer
+type Handler struct {
+	Buffer  *Buffer
+	Handler handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler and keeps it.
+func (h *Handler) Handle(e event.Event) {
+	h.Handler.Handle(e)
+	h.Buffer.Add(e.Payload())
+}
+
+// HandleWithError sends the event to the wrapped handler, reporting delivery failures when
+// the handler reports them. Only delivered events are kept, so that retried events
+// are kept once.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx and keeps it once delivered
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	if err := handlers.Deliver(ctx, h.Handler, e); err != nil {
+		return err
+	}
+	h.Buffer.Add(e.Payload())
+	return nil
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *Handler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
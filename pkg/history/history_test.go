@@ -0,0 +1,136 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func payload(namespace, name string, age time.Duration) event.Payload {
+	return event.Payload{Kind: "Pod", Object: event.ObjectRef{Namespace: namespace, Name: name}, Timestamp: time.Now().Add(-age)}
+}
+
+func names(payloads []event.Payload) []string {
+	var names []string
+	for _, p := range payloads {
+		names = append(names, p.Object.Name)
+	}
+	return names
+}
+
+func TestBufferQuery(t *testing.T) {
+	b := NewBuffer(3)
+	b.Add(payload("team-a", "dropped", time.Hour))
+	b.Add(payload("team-a", "old", time.Hour))
+	b.Add(payload("team-b", "api", 10*time.Minute))
+	b.Add(payload("team-a", "web", time.Minute))
+
+	var Tests = []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"web", "api", "old"}},
+		{"namespace=team-a", []string{"web", "old"}},
+		{"since=30m", []string{"web", "api"}},
+		{"limit=1", []string{"web"}},
+		{"kind=Node", nil},
+	}
+	for _, test := range Tests {
+		values, _ := url.ParseQuery(test.query)
+		q, err := ParseQuery(values)
+		if err != nil {
+			t.Fatalf("%q: %v", test.query, err)
+		}
+		if got := names(b.Query(q)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.query, test.expected, got)
+		}
+	}
+
+	for _, query := range []string{"since=yesterday", "limit=0"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := ParseQuery(values); err == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	b := NewBuffer(10)
+	b.Add(payload("team-a", "api", time.Minute))
+
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?namespace=team-a", nil))
+	var response struct {
+		Events []event.Payload `json:"events"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Events) != 1 || response.Events[0].Object.Name != "api" {
+		t.Errorf("expected the api event, got %+v", response.Events)
+	}
+
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?limit=-1", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid limit to be rejected, got %d", w.Code)
+	}
+}
+
+// flakyHandler fails its first delivery
+type flakyHandler struct {
+	failed bool
+}
+
+func (f *flakyHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (f *flakyHandler) Handle(e event.Event) {}
+
+func (f *flakyHandler) HandleWithError(e event.Event) error {
+	if !f.failed {
+		f.failed = true
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	b := NewBuffer(10)
+	h := &Handler{Buffer: b, Handler: &flakyHandler{}}
+	e := event.Event{Kind: "Pod", Name: "api", Namespace: "team-a", Reason: "Created"}
+	if err := h.HandleWithError(e); err == nil {
+		t.Fatal("expected the first delivery to fail")
+	}
+	if err := h.HandleWithError(e); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(b.Query(Query{})); len(got) != 1 || got[0] != "api" {
+		t.Errorf("expected the delivered event to be kept once, got %v", got)
+	}
+}
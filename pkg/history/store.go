@@ -0,0 +1,362 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// segmentLayout names the hourly segment files of a Store, in UTC
+const segmentLayout = "2006-01-02T15"
+
+// segmentExt is the extension of segment files
+const segmentExt = ".jsonl"
+
+// pruneInterval is how often segments older than the retention are deleted
+const pruneInterval = 10 * time.Minute
+
+// maxRecordSize is the size of the largest record read back from a segment
+const maxRecordSize = 4 << 20
+
+// Store persists events on disk, in hourly segment files of JSON lines under a directory,
+// deleting the segments older than its retention. It remembers the objects it stored the
+// events of, so that events sent before a restart are not sent again.
+type Store struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	file    *os.File
+	segment string
+	// keys maps the keys of the stored events to their segment
+	keys map[string]string
+}
+
+// record is a line of a segment file
+type record struct {
+	Key   string        `json:"key,omitempty"`
+	Event event.Payload `json:"event"`
+}
+
+// OpenStore opens the store of events kept for retention under dir, creating dir if needed
+func OpenStore(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, retention: retention, now: time.Now, keys: map[string]string{}}
+	if err := s.Prune(); err != nil {
+		return nil, err
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		err := s.scan(segment, func(r record) bool {
+			if r.Key != "" {
+				s.keys[r.Key] = segment
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Key returns the key identifying the change e notifies: the kind and reason of the event,
+// and the UID and resourceVersion of its object. It is empty for events without object.
+func Key(e event.Event) string {
+	if e.Obj == nil {
+		return ""
+	}
+	accessor, err := meta.Accessor(e.Obj)
+	if err != nil || accessor.GetUID() == "" || accessor.GetResourceVersion() == "" {
+		return ""
+	}
+	return strings.Join([]string{e.ClusterName, e.Kind, e.Reason, string(accessor.GetUID()), accessor.GetResourceVersion()}, "/")
+}
+
+// Seen returns whether an event with key was stored
+func (s *Store) Seen(key string) bool {
+	if key == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[key]
+	return ok
+}
+
+// Add appends p, notifying the change identified by key, to the current segment
+func (s *Store) Add(key string, p event.Payload) error {
+	line, err := json.Marshal(record{Key: key, Event: p})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	segment := s.now().UTC().Format(segmentLayout) + segmentExt
+	if s.file == nil || s.segment != segment {
+		if s.file != nil {
+			s.file.Close()
+		}
+		f, err := os.OpenFile(filepath.Join(s.dir, segment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			s.file = nil
+			return err
+		}
+		s.file, s.segment = f, segment
+	}
+	if _, err := s.file.Write(line); err != nil {
+		return err
+	}
+	if key != "" {
+		s.keys[key] = segment
+	}
+	return nil
+}
+
+// Query returns the stored events matching q, most recent first
+func (s *Store) Query(q Query) ([]event.Payload, error) {
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := s.now().Add(-s.retention)
+	if q.Since.After(cutoff) {
+		cutoff = q.Since
+	}
+
+	matches := []event.Payload{}
+	for i := len(segments) - 1; i >= 0 && (q.Limit <= 0 || len(matches) < q.Limit); i-- {
+		if end, ok := segmentEnd(segments[i]); ok && end.Before(cutoff) {
+			break
+		}
+		var found []event.Payload
+		err := s.scan(segments[i], func(r record) bool {
+			if q.Matches(r.Event) && !r.Event.Timestamp.Before(cutoff) {
+				found = append(found, r.Event)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		for j := len(found) - 1; j >= 0 && (q.Limit <= 0 || len(matches) < q.Limit); j-- {
+			matches = append(matches, found[j])
+		}
+	}
+	return matches, nil
+}
+
+// ServeHTTP answers queries of the events API from the stored events
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveQuery(w, r, s.Query)
+}
+
+// Prune deletes the segments older than the retention
+func (s *Store) Prune() error {
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	cutoff := s.now().Add(-s.retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := map[string]bool{}
+	for _, segment := range segments {
+		end, ok := segmentEnd(segment)
+		if !ok || !end.Before(cutoff) {
+			break
+		}
+		if segment == s.segment {
+			s.file.Close()
+			s.file, s.segment = nil, ""
+		}
+		if err := os.Remove(filepath.Join(s.dir, segment)); err != nil {
+			return err
+		}
+		pruned[segment] = true
+	}
+	if len(pruned) > 0 {
+		for key, segment := range s.keys {
+			if pruned[segment] {
+				delete(s.keys, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Run periodically deletes the segments older than the retention until stopCh is closed
+func (s *Store) Run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := s.Prune(); err != nil {
+			logrus.WithField("pkg", "kubewatch-store").Errorf("Error pruning events: %v", err)
+		}
+	}, pruneInterval, stopCh)
+}
+
+// Close closes the current segment
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file, s.segment = nil, ""
+	return err
+}
+
+// segments returns the names of the segment files, oldest first
+func (s *Store) segments() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []string
+	for _, entry := range entries {
+		if _, ok := segmentEnd(entry.Name()); ok && !entry.IsDir() {
+			segments = append(segments, entry.Name())
+		}
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// segmentEnd returns the end of the hour covered by segment
+func segmentEnd(segment string) (time.Time, bool) {
+	t, err := time.Parse(segmentLayout, strings.TrimSuffix(segment, segmentExt))
+	if err != nil || !strings.HasSuffix(segment, segmentExt) {
+		return time.Time{}, false
+	}
+	return t.Add(time.Hour), true
+}
+
+// scan calls fn with the records of segment in order, until fn returns false. Lines that
+// cannot be decoded, such as a line cut by a crash, are skipped.
+func (s *Store) scan(segment string, fn func(record) bool) error {
+	f, err := os.Open(filepath.Join(s.dir, segment))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if !fn(r) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// StoreHandler wraps a handler, persisting the events it delivers in Store and dropping
+// the events already stored, such as changes notified again after a restart
+type StoreHandler struct {
+	Store   *Store
+	Handler handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *StoreHandler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler unless it was stored, and stores it.
+func (h *StoreHandler) Handle(e event.Event) {
+	key := Key(e)
+	if h.duplicate(e, key) {
+		return
+	}
+	h.Handler.Handle(e)
+	h.add(e, key)
+}
+
+// HandleWithError sends the event to the wrapped handler unless it was stored, reporting
+// delivery failures when the handler reports them. Only delivered events are stored.
+func (h *StoreHandler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx unless it was stored, and
+// stores it once delivered
+func (h *StoreHandler) HandleContext(ctx context.Context, e event.Event) error {
+	key := Key(e)
+	if h.duplicate(e, key) {
+		return nil
+	}
+	if err := handlers.Deliver(ctx, h.Handler, e); err != nil {
+		return err
+	}
+	h.add(e, key)
+	return nil
+}
+
+func (h *StoreHandler) duplicate(e event.Event, key string) bool {
+	if !h.Store.Seen(key) {
+		return false
+	}
+	logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-store").Debugf("Event already sent, skipping it")
+	return true
+}
+
+func (h *StoreHandler) add(e event.Event, key string) {
+	if err := h.Store.Add(key, e.Payload()); err != nil {
+		logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-store").Errorf("Error storing event: %v", err)
+	}
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *StoreHandler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
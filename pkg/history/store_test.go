@@ -0,0 +1,156 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func openStore(t *testing.T, dir string, current *time.Time) *Store {
+	s, err := OpenStore(dir, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return *current }
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	// segments are pruned with the actual time when the store is opened
+	current := time.Now().Truncate(time.Hour).Add(-25*time.Hour - 30*time.Minute)
+	s := openStore(t, dir, &current)
+
+	add := func(key, namespace, name string) {
+		p := payload(namespace, name, 0)
+		p.Timestamp = current
+		if err := s.Add(key, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("", "team-a", "expired")
+	current = current.Add(24 * time.Hour)
+	add("api-1", "team-a", "old")
+	current = current.Add(time.Hour)
+	add("api-2", "team-b", "api")
+	current = current.Add(10 * time.Minute)
+	add("", "team-a", "web")
+
+	var Tests = []struct {
+		query    Query
+		expected []string
+	}{
+		{Query{}, []string{"web", "api", "old"}},
+		{Query{Namespace: "team-a"}, []string{"web", "old"}},
+		{Query{Since: current.Add(-30 * time.Minute)}, []string{"web", "api"}},
+		{Query{Limit: 2}, []string{"web", "api"}},
+		{Query{Kind: "Node"}, nil},
+	}
+	for i, test := range Tests {
+		got, err := s.Query(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if names := names(got); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%d: expected %v, got %v", i, test.expected, names)
+		}
+	}
+
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segments) != 2 {
+		t.Errorf("expected the expired segment to be deleted, got %v", segments)
+	}
+
+	// a line cut by a crash is skipped when reopening
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"key":"cut","event":{"kind":`)
+	f.Close()
+	s.Close()
+
+	s = openStore(t, dir, &current)
+	for key, seen := range map[string]bool{"api-1": true, "api-2": true, "cut": false, "": false} {
+		if s.Seen(key) != seen {
+			t.Errorf("%q: expected Seen() to be %v after a restart", key, seen)
+		}
+	}
+}
+
+// countingHandler counts the events it receives
+type countingHandler struct {
+	count int
+}
+
+func (c *countingHandler) Init(conf *config.Config) error {
+	return nil
+}
+
+func (c *countingHandler) Handle(e event.Event) {
+	c.count++
+}
+
+func TestStoreHandler(t *testing.T) {
+	dir := t.TempDir()
+	current := time.Now()
+	pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api", UID: "4f1c", ResourceVersion: "12"}}
+	created := event.Event{Kind: "Pod", Name: "api", Reason: "Created", Obj: pod}
+	heartbeat := event.Event{Kind: "Heartbeat", Reason: "kubewatch is running"}
+
+	c := &countingHandler{}
+	h := &StoreHandler{Store: openStore(t, dir, &current), Handler: c}
+	for _, e := range []event.Event{created, heartbeat, created} {
+		if err := h.HandleWithError(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Store.Close()
+
+	// the same change listed again after a restart is not sent twice
+	h.Store = openStore(t, dir, &current)
+	h.Handle(created)
+	h.Handle(heartbeat)
+	updated := created
+	updated.Reason, updated.Obj = "Updated", pod.DeepCopy()
+	updated.Obj.(*api_v1.Pod).ResourceVersion = "13"
+	h.Handle(updated)
+
+	if c.count != 4 {
+		t.Errorf("expected the pod creation to be sent once, got %d deliveries", c.count)
+	}
+	stored, err := h.Store.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 4 {
+		t.Errorf("expected the delivered events to be stored, got %d", len(stored))
+	}
+}
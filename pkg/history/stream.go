@@ -0,0 +1,161 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+const (
+	// subscriberBuffer is the number of events a subscriber can lag behind before
+	// events are dropped
+	subscriberBuffer = 100
+	// keepAliveInterval is the interval between comments sent on idle streams, so that
+	// proxies do not close them
+	keepAliveInterval = 30 * time.Second
+)
+
+type subscriber struct {
+	query  Query
+	events chan event.Payload
+}
+
+// Subscribe returns a channel receiving the new events matching q, ignoring its limit, and
+// a function to call to unsubscribe. Events are dropped while the channel is full.
+func (b *Buffer) Subscribe(q Query) (<-chan event.Payload, func()) {
+	s := &subscriber{query: q, events: make(chan event.Payload, subscriberBuffer)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = map[*subscriber]struct{}{}
+	}
+	b.subscribers[s] = struct{}{}
+	return s.events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, s)
+	}
+}
+
+// publish sends p to the subscribers it matches, b.mu must be held
+func (b *Buffer) publish(p event.Payload) {
+	for s := range b.subscribers {
+		if !s.query.Matches(p) {
+			continue
+		}
+		select {
+		case s.events <- p:
+		default:
+		}
+	}
+}
+
+// Stream streams the events matching the query of the request as Server-Sent Events, such
+// as /api/v1/events/stream?namespace=payments. With since, the recent matching events are
+// sent first.
+func (b *Buffer) Stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	q, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// subscribe before reading the recent events, so that no event is missed in between
+	events, unsubscribe := b.Subscribe(q)
+	defer unsubscribe()
+	sent := map[string]bool{}
+	var recent []event.Payload
+	if !q.Since.IsZero() {
+		recent = b.Query(q)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	for i := len(recent) - 1; i >= 0; i-- {
+		sent[recent[i].ID] = true
+		writeEvent(w, recent[i])
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case p := <-events:
+			if p.ID != "" && sent[p.ID] {
+				continue
+			}
+			writeEvent(w, p)
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes p as a Server-Sent Event of type event
+func writeEvent(w http.ResponseWriter, p event.Payload) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	if p.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", p.ID)
+	}
+	fmt.Fprintf(w, "event: event\ndata: %s\n\n", data)
+}
+
+// ReadStream reads the events of a stream written by Stream, calling fn for each event
+// until r ends or fn returns an error
+func ReadStream(r io.Reader, fn func(event.Payload) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var p event.Payload
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return fmt.Errorf("invalid event in stream: %v", err)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
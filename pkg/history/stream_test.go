@@ -0,0 +1,91 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func (b *Buffer) subscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+func TestStream(t *testing.T) {
+	b := NewBuffer(10)
+	old := payload("team-a", "old", time.Minute)
+	old.ID = "1"
+	b.Add(old)
+	b.Add(payload("team-b", "other", time.Minute))
+
+	server := httptest.NewServer(http.HandlerFunc(b.Stream))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?namespace=team-a&since=1h", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.subscriberCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the stream to subscribe to new events")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.Add(payload("team-b", "ignored", 0))
+	b.Add(payload("team-a", "new", 0))
+
+	var streamed []string
+	errDone := errors.New("done")
+	err = ReadStream(resp.Body, func(p event.Payload) error {
+		streamed = append(streamed, p.Object.Name)
+		if len(streamed) == 2 {
+			return errDone
+		}
+		return nil
+	})
+	if err != errDone {
+		t.Fatalf("expected the stream to be read, got %v", err)
+	}
+	if len(streamed) != 2 || streamed[0] != "old" || streamed[1] != "new" {
+		t.Errorf("expected the recent then the new events of team-a, got %v", streamed)
+	}
+
+	cancel()
+	deadline = time.Now().Add(5 * time.Second)
+	for b.subscriberCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the stream to unsubscribe once the client is gone")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpclient provides the HTTP client of the handlers. Handlers share pooled
+// connections, kept alive between notifications, instead of opening one per notification.
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+// maxResponseBody is the size of the response bodies kept, the rest is discarded
+const maxResponseBody = 64 << 10
+
+// TLS configures the verification of the certificates of servers
+type TLS struct {
+	// CAFile is the PEM file of the CA certificates trusted in addition to the system ones
+	CAFile string
+	// InsecureSkipVerify disables the verification of certificates
+	InsecureSkipVerify bool
+}
+
+// transportKey identifies the transports shared by clients
+type transportKey struct {
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	disableKeepAlives   bool
+	tls                 TLS
+}
+
+var (
+	mu         sync.Mutex
+	transports = map[transportKey]*http.Transport{}
+)
+
+// defaultClient is used by handlers created without configuration
+var defaultClient = &Client{client: &http.Client{
+	Timeout: config.DefaultHTTPTimeout,
+	Transport: mustTransport(transportKey{
+		maxIdleConnsPerHost: config.DefaultMaxIdleConnsPerHost,
+		idleConnTimeout:     config.DefaultIdleConnTimeout,
+	}),
+}}
+
+// Client sends the requests of a handler
+type Client struct {
+	client *http.Client
+	gzip   bool
+}
+
+// Response is a response read in full, so that its connection is reused
+type Response struct {
+	StatusCode int
+	Status     string
+	// Body holds the first 64KiB of the body
+	Body []byte
+}
+
+// New returns the client of handler name configured by conf.HTTP
+func New(conf *config.Config, name string) (*Client, error) {
+	return NewTLS(conf, name, TLS{})
+}
+
+// NewTLS returns the client of handler name configured by conf.HTTP, verifying the
+// certificates of servers as configured by t
+func NewTLS(conf *config.Config, name string, t TLS) (*Client, error) {
+	key := transportKey{
+		maxIdleConnsPerHost: orDefault(conf.HTTP.MaxIdleConnsPerHost, config.DefaultMaxIdleConnsPerHost),
+		idleConnTimeout:     orDefault(conf.HTTP.IdleConnTimeout, config.DefaultIdleConnTimeout),
+		disableKeepAlives:   conf.HTTP.DisableKeepAlives,
+		tls:                 t,
+	}
+	transport, err := sharedTransport(key)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{client: &http.Client{
+		Timeout:   orDefault(conf.HTTP.TimeoutFor(name), config.DefaultHTTPTimeout),
+		Transport: transport,
+	}}
+	for _, handler := range conf.HTTP.Gzip {
+		if handler == name {
+			c.gzip = true
+		}
+	}
+	return c, nil
+}
+
+// HTTPClient returns the http.Client of c, for libraries sending their own requests
+func (c *Client) HTTPClient() *http.Client {
+	if c == nil {
+		return defaultClient.client
+	}
+	return c.client
+}
+
+// Post sends body to url as PostContext does, within the timeout of the client only.
+func (c *Client) Post(url, contentType string, body []byte) (*Response, error) {
+	return c.PostContext(context.Background(), url, contentType, body)
+}
+
+// PostContext sends body to url, gzip compressed when enabled for the handler, canceling
+// the request when ctx is done. The response is read and closed before PostContext returns.
+func (c *Client) PostContext(ctx context.Context, url, contentType string, body []byte) (*Response, error) {
+	if c == nil {
+		c = defaultClient
+	}
+	var encoding string
+	if c.gzip {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		body, encoding = compressed.Bytes(), "gzip"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, err
+	}
+	// connections are only reused once their response is read to the end
+	io.Copy(io.Discard, resp.Body)
+	return &Response{StatusCode: resp.StatusCode, Status: resp.Status, Body: respBody}, nil
+}
+
+// sharedTransport returns the transport of key, created on first use
+func sharedTransport(key transportKey) (*http.Transport, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if transport, ok := transports[key]; ok {
+		return transport, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 0
+	transport.MaxIdleConnsPerHost = key.maxIdleConnsPerHost
+	transport.IdleConnTimeout = key.idleConnTimeout
+	transport.DisableKeepAlives = key.disableKeepAlives
+	if key.tls.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	} else if key.tls.CAFile != "" {
+		caCert, err := os.ReadFile(key.tls.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in %s", key.tls.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	transports[key] = transport
+	return transport, nil
+}
+
+func mustTransport(key transportKey) *http.Transport {
+	transport, err := sharedTransport(key)
+	if err != nil {
+		panic(err)
+	}
+	return transport
+}
+
+func orDefault[T int | time.Duration](value, defaultValue T) T {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
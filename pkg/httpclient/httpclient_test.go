@@ -0,0 +1,114 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+)
+
+func TestPost(t *testing.T) {
+	var encoding, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		reader := io.Reader(r.Body)
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader = gz
+		}
+		b, _ := io.ReadAll(reader)
+		body = string(b)
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "queued")
+	}))
+	defer ts.Close()
+
+	conf := &config.Config{HTTP: config.HTTPClient{Gzip: []string{"webhook"}}}
+	var Tests = []struct {
+		handler  string
+		encoding string
+	}{
+		{"webhook", "gzip"},
+		{"mattermost", ""},
+	}
+
+	for _, tt := range Tests {
+		c, err := New(conf, tt.handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.Post(ts.URL, "application/json", []byte(`{"text":"hello"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoding != tt.encoding || body != `{"text":"hello"}` {
+			t.Errorf("%s: received %q with encoding %q", tt.handler, body, encoding)
+		}
+		if resp.StatusCode != http.StatusAccepted || string(resp.Body) != "queued" {
+			t.Errorf("%s: response %d %q, expected 202 queued", tt.handler, resp.StatusCode, resp.Body)
+		}
+	}
+
+	// handlers created without Init use the default client
+	var c *Client
+	if _, err := c.Post(ts.URL, "application/json", nil); err != nil {
+		t.Errorf("Post() with the default client: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.PostContext(ctx, ts.URL, "application/json", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("PostContext() with a canceled context = %v, expected it to be canceled", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	conf := &config.Config{HTTP: config.HTTPClient{
+		Timeout:  5 * time.Second,
+		Timeouts: map[string]time.Duration{"webhook": 30 * time.Second},
+	}}
+	webhook, _ := New(conf, "webhook")
+	slack, _ := New(conf, "slack")
+	if webhook.HTTPClient().Timeout != 30*time.Second || slack.HTTPClient().Timeout != 5*time.Second {
+		t.Errorf("timeouts = %s and %s, expected 30s and 5s", webhook.HTTPClient().Timeout, slack.HTTPClient().Timeout)
+	}
+	if webhook.HTTPClient().Transport != slack.HTTPClient().Transport {
+		t.Error("handlers with the same settings do not share their transport")
+	}
+
+	insecure, err := NewTLS(conf, "webhook", TLS{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insecure.HTTPClient().Transport == slack.HTTPClient().Transport {
+		t.Error("handler skipping TLS verification shares the transport of other handlers")
+	}
+	if _, err := NewTLS(conf, "webhook", TLS{CAFile: "testdata/missing.pem"}); err == nil {
+		t.Error("NewTLS() with a missing CA file: expected an error")
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// modulePrefix is the prefix of the pkg field of kubewatch log entries
+const modulePrefix = "kubewatch-"
+
+// Configure sets the format and the level of the logs, and the levels of modules, named
+// after the pkg field of log entries without the kubewatch- prefix. Empty format and
+// level leave the current ones unchanged.
+func Configure(format string, level string, modules map[string]string) error {
+	var formatter logrus.Formatter
+	switch format {
+	case "":
+		formatter = logrus.StandardLogger().Formatter
+		if f, ok := formatter.(*moduleFormatter); ok {
+			formatter = f.Formatter
+		}
+	case FormatText:
+		formatter = new(logrus.TextFormatter)
+	case FormatJSON:
+		formatter = new(logrus.JSONFormatter)
+	default:
+		return fmt.Errorf("unknown log format %q, expected text or json", format)
+	}
+
+	defaultLevel := logrus.GetLevel()
+	if f, ok := logrus.StandardLogger().Formatter.(*moduleFormatter); ok {
+		defaultLevel = f.level
+	}
+	if level != "" {
+		var err error
+		if defaultLevel, err = logrus.ParseLevel(level); err != nil {
+			return err
+		}
+	}
+
+	if len(modules) == 0 {
+		logrus.SetFormatter(formatter)
+		logrus.SetLevel(defaultLevel)
+		return nil
+	}
+
+	f := &moduleFormatter{Formatter: formatter, level: defaultLevel, modules: map[string]logrus.Level{}}
+	// entries are filtered by module, the logger lets through those of the most verbose one
+	loggerLevel := defaultLevel
+	for module, moduleLevel := range modules {
+		l, err := logrus.ParseLevel(moduleLevel)
+		if err != nil {
+			return fmt.Errorf("module %s: %v", module, err)
+		}
+		f.modules[strings.ToLower(module)] = l
+		if l > loggerLevel {
+			loggerLevel = l
+		}
+	}
+	logrus.SetFormatter(f)
+	logrus.SetLevel(loggerLevel)
+	return nil
+}
+
+// moduleFormatter drops the entries above the level of their module
+type moduleFormatter struct {
+	logrus.Formatter
+	level   logrus.Level
+	modules map[string]logrus.Level
+}
+
+func (f *moduleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	level := f.level
+	if pkg, ok := entry.Data["pkg"].(string); ok {
+		if l, ok := f.modules[strings.ToLower(strings.TrimPrefix(pkg, modulePrefix))]; ok {
+			level = l
+		}
+	}
+	if entry.Level > level {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// EventFields returns the fields identifying the object of e in log entries
+func EventFields(e event.Event) logrus.Fields {
+	fields := logrus.Fields{"kind": e.Kind, "name": e.Name}
+	if e.Namespace != "" {
+		fields["namespace"] = e.Namespace
+	}
+	if e.ClusterName != "" {
+		fields["cluster"] = e.ClusterName
+	}
+	return fields
+}
+
+// HandlerLogger returns the logger of handler for e
+func HandlerLogger(handler string, e event.Event) *logrus.Entry {
+	return logrus.WithFields(EventFields(e)).WithFields(logrus.Fields{"pkg": modulePrefix + "handler", "handler": handler})
+}
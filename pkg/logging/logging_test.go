@@ -0,0 +1,85 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestConfigure(t *testing.T) {
+	logger := logrus.StandardLogger()
+	out, formatter, level := logger.Out, logger.Formatter, logger.Level
+	defer func() {
+		logger.SetOutput(out)
+		logger.SetFormatter(formatter)
+		logger.SetLevel(level)
+	}()
+
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	if err := Configure(FormatJSON, "warning", map[string]string{"rbac": "debug", "Pod": "error"}); err != nil {
+		t.Fatal(err)
+	}
+
+	logrus.WithField("pkg", "kubewatch-rbac").Debug("rbac debug")
+	logrus.WithField("pkg", "kubewatch-Pod").Warn("pod warning")
+	logrus.WithField("pkg", "kubewatch-Pod").Error("pod error")
+	logrus.WithField("pkg", "kubewatch-reload").Info("reload info")
+	logrus.WithField("pkg", "kubewatch-reload").Warn("reload warning")
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("expected JSON logs, got %q: %v", line, err)
+		}
+		messages = append(messages, entry["msg"].(string))
+	}
+	if expected := "rbac debug,pod error,reload warning"; strings.Join(messages, ",") != expected {
+		t.Errorf("expected %s to be logged, got %v", expected, messages)
+	}
+
+	// empty settings keep the current format and levels
+	buf.Reset()
+	if err := Configure("", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	logrus.Info("info")
+	logrus.Warn("warning")
+	if !strings.HasPrefix(buf.String(), `{`) || strings.Contains(buf.String(), `"info"`) {
+		t.Errorf("expected JSON warnings only, got %s", buf.String())
+	}
+
+	var Tests = []struct {
+		format, level string
+		modules       map[string]string
+	}{
+		{"xml", "", nil},
+		{"", "verbose", nil},
+		{"", "", map[string]string{"rbac": "verbose"}},
+	}
+	for _, tt := range Tests {
+		if err := Configure(tt.format, tt.level, tt.modules); err == nil {
+			t.Errorf("Configure(%q, %q, %v) expected an error", tt.format, tt.level, tt.modules)
+		}
+	}
+}
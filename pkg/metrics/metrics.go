@@ -22,11 +22,44 @@ import (
 )
 
 var (
+	// EventsTotal tracks events observed by the controllers before filtering
+	EventsTotal *prometheus.CounterVec
+
 	// EventsSentTotal tracks events sent to handlers after filtering
 	EventsSentTotal *prometheus.CounterVec
+
+	// WatchForbidden flags watches denied by RBAC
+	WatchForbidden *prometheus.GaugeVec
+
+	// DeliveryLatency tracks the time from object changes to the delivery of their events
+	DeliveryLatency *prometheus.HistogramVec
+
+	// SLOViolationsTotal tracks deliveries slower than the latency SLO
+	SLOViolationsTotal *prometheus.CounterVec
+
+	// OutboxPending tracks the events persisted in the outbox and not delivered yet
+	OutboxPending prometheus.Gauge
+
+	// InformerCacheObjects tracks the objects held in the informer caches
+	InformerCacheObjects *prometheus.GaugeVec
+
+	// StormActive flags ongoing event storms
+	StormActive prometheus.Gauge
+
+	// DeliveriesTotal tracks the deliveries of events to each handler by result
+	DeliveriesTotal *prometheus.CounterVec
 )
 
 func init() {
+	// Initialize the observed events metric
+	EventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_events_total",
+			Help: "The total number of Kubernetes events observed by Kubewatch, labeled by resource and event type",
+		},
+		[]string{"resourceType", "eventType"},
+	)
+
 	// Initialize the sent events metric
 	EventsSentTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -35,4 +68,66 @@ func init() {
 		},
 		[]string{"resourceType", "eventType"},
 	)
+
+	// Initialize the forbidden watches metric
+	WatchForbidden = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_watch_forbidden",
+			Help: "Set to 1 for watches kubewatch lacks RBAC permissions for, labeled by cluster, resource and namespace",
+		},
+		[]string{"cluster", "resource", "namespace"},
+	)
+
+	// Initialize the delivery latency metric
+	DeliveryLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kubewatch_delivery_latency_seconds",
+			Help:    "The time from Kubernetes object changes to the delivery of their events to the handler, labeled by cluster and resource",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"cluster", "resourceType"},
+	)
+
+	// Initialize the latency SLO violations metric
+	SLOViolationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_delivery_slo_violations_total",
+			Help: "The total number of events delivered later than the latency SLO, labeled by cluster and resource",
+		},
+		[]string{"cluster", "resourceType"},
+	)
+
+	// Initialize the pending outbox events metric
+	OutboxPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_outbox_pending",
+			Help: "The number of events persisted in the outbox and not delivered to the handler yet",
+		},
+	)
+
+	// Initialize the informer cache size metric
+	InformerCacheObjects = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_informer_cache_objects",
+			Help: "The number of objects held in the informer caches, labeled by cluster and resource",
+		},
+		[]string{"cluster", "resource"},
+	)
+
+	// Initialize the event storm metric
+	StormActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kubewatch_event_storm",
+			Help: "Set to 1 while an event storm is ongoing and events are summarized instead of being sent",
+		},
+	)
+
+	// Initialize the deliveries metric
+	DeliveriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubewatch_deliveries_total",
+			Help: "The total number of deliveries of events to handlers, labeled by handler and result: sent, failed or timeout",
+		},
+		[]string{"handler", "result"},
+	)
 }
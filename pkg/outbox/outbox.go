@@ -0,0 +1,271 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// entryExt is the extension of the files of pending events
+const entryExt = ".json"
+
+// Outbox persists events until they are delivered, one file per event under a directory,
+// so that the events not delivered when kubewatch stops are delivered when it restarts
+type Outbox struct {
+	dir    string
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]bool
+	// left holds the pending events found when the outbox was opened
+	left []string
+}
+
+// entry is the file of a pending event
+type entry struct {
+	QueuedAt time.Time     `json:"queuedAt"`
+	Event    event.Payload `json:"event"`
+}
+
+// Open opens the outbox under dir, creating dir if needed. Pending events older than
+// maxAge are dropped when redelivered, none when maxAge is 0.
+func Open(dir string, maxAge time.Duration) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	o := &Outbox{dir: dir, maxAge: maxAge, now: time.Now, pending: map[string]bool{}}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if name := e.Name(); strings.HasSuffix(name, entryExt) && !e.IsDir() {
+			o.pending[name] = true
+			o.left = append(o.left, name)
+		}
+	}
+	metrics.OutboxPending.Set(float64(len(o.pending)))
+	return o, nil
+}
+
+// fileName returns the name of the file of the event with id
+func fileName(id string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("kubewatch/outbox/"+id)).String() + entryExt
+}
+
+// Put persists p until Done is called with its ID. The file is synced before Put returns,
+// so that the event survives a crash.
+func (o *Outbox) Put(p event.Payload) error {
+	data, err := json.Marshal(entry{QueuedAt: o.now(), Event: p})
+	if err != nil {
+		return err
+	}
+	name := fileName(p.ID)
+
+	// write then rename, so a crash never leaves a truncated file
+	tmp, err := os.CreateTemp(o.dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(o.dir, name)); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[name] = true
+	metrics.OutboxPending.Set(float64(len(o.pending)))
+	return nil
+}
+
+// Done removes the event with id, once delivered
+func (o *Outbox) Done(id string) error {
+	name := fileName(id)
+	if err := os.Remove(filepath.Join(o.dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.pending, name)
+	metrics.OutboxPending.Set(float64(len(o.pending)))
+	return nil
+}
+
+// Pending returns the events left undelivered by a previous run, in the order they were
+// queued. Events queued since the outbox was opened are not returned.
+func (o *Outbox) Pending() ([]event.Payload, error) {
+	o.mu.Lock()
+	var names []string
+	for _, name := range o.left {
+		if o.pending[name] {
+			names = append(names, name)
+		}
+	}
+	o.mu.Unlock()
+
+	var entries []entry
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(o.dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			logrus.WithField("pkg", "kubewatch-outbox").Warnf("Dropping unreadable outbox entry %s: %v", name, err)
+			o.remove(name)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].QueuedAt.Before(entries[j].QueuedAt) })
+
+	cutoff := o.now().Add(-o.maxAge)
+	payloads := make([]event.Payload, 0, len(entries))
+	for _, e := range entries {
+		if o.maxAge > 0 && e.QueuedAt.Before(cutoff) {
+			logrus.WithField("pkg", "kubewatch-outbox").Warnf("Dropping %s event %s queued %s ago, older than the outbox max age", e.Event.Kind, e.Event.ID, o.now().Sub(e.QueuedAt).Round(time.Second))
+			o.remove(fileName(e.Event.ID))
+			continue
+		}
+		payloads = append(payloads, e.Event)
+	}
+	return payloads, nil
+}
+
+func (o *Outbox) remove(name string) {
+	os.Remove(filepath.Join(o.dir, name))
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.pending, name)
+	metrics.OutboxPending.Set(float64(len(o.pending)))
+}
+
+// Handler wraps a handler, persisting events in Outbox before sending them and removing
+// them once delivered, for at-least-once delivery across restarts
+type Handler struct {
+	Outbox  *Outbox
+	Handler handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle persists the event and sends it to the wrapped handler. Handlers that do not
+// report delivery failures are considered to deliver every event.
+func (h *Handler) Handle(e event.Event) {
+	if err := h.HandleWithError(e); err != nil {
+		logging.HandlerLogger("outbox", e).Errorf("Error sending event, it stays in the outbox: %v", err)
+	}
+}
+
+// HandleWithError persists the event and sends it to the wrapped handler, reporting
+// delivery failures when the handler reports them. Failed events stay in the
+// outbox, retries of the same event, which keep its ID, replacing them.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext persists e in the outbox, delivers it to the wrapped handler with ctx and
+// removes it from the outbox once delivered
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	logger := logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-outbox")
+	persisted := true
+	if err := h.Outbox.Put(e.Payload()); err != nil {
+		logger.Errorf("Error persisting event, it will not be redelivered after a restart: %v", err)
+		persisted = false
+	}
+
+	if err := handlers.Deliver(ctx, h.Handler, e); err != nil {
+		return err
+	}
+
+	if persisted {
+		if err := h.Outbox.Done(e.ID); err != nil {
+			logger.Errorf("Error removing delivered event from the outbox: %v", err)
+		}
+	}
+	return nil
+}
+
+// Redeliver sends the events left in the outbox by a previous run to the wrapped handler,
+// oldest first. Events failing again stay in the outbox until the next restart.
+func (h *Handler) Redeliver() {
+	logger := logrus.WithField("pkg", "kubewatch-outbox")
+	pending, err := h.Outbox.Pending()
+	if err != nil {
+		logger.Errorf("Error reading the outbox: %v", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+	logger.Infof("Redelivering %d events left in the outbox", len(pending))
+	for _, p := range pending {
+		e := p.Event()
+		if err := handlers.Deliver(context.Background(), h.Handler, e); err != nil {
+			logger.WithFields(logging.EventFields(e)).Errorf("Error redelivering event, it stays in the outbox: %v", err)
+			continue
+		}
+		if err := h.Outbox.Done(e.ID); err != nil {
+			logger.WithFields(logging.EventFields(e)).Errorf("Error removing delivered event from the outbox: %v", err)
+		}
+	}
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *Handler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package outbox
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// sink records the IDs of the events it delivers, failing while down
+type sink struct {
+	down      bool
+	delivered []string
+}
+
+func (s *sink) Init(c *config.Config) error {
+	return nil
+}
+
+func (s *sink) Handle(e event.Event) {
+	s.HandleWithError(e)
+}
+
+func (s *sink) HandleWithError(e event.Event) error {
+	if s.down {
+		return errors.New("ticketing system unavailable")
+	}
+	s.delivered = append(s.delivered, e.ID)
+	return nil
+}
+
+func open(t *testing.T, dir string, current *time.Time) *Outbox {
+	o, err := Open(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.now = func() time.Time { return *current }
+	return o
+}
+
+func TestRedeliver(t *testing.T) {
+	dir := t.TempDir()
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s := &sink{}
+	h := &Handler{Outbox: open(t, dir, &current), Handler: s}
+
+	for _, id := range []string{"delivered", "expired"} {
+		if id == "expired" {
+			s.down = true
+		}
+		h.HandleWithError(event.Event{ID: id, Kind: "Job", Name: id})
+	}
+	current = current.Add(2 * time.Hour)
+	for _, id := range []string{"failed-1", "failed-2"} {
+		if err := h.HandleWithError(event.Event{ID: id, Kind: "Job", Name: id}); err == nil {
+			t.Fatalf("%s: expected the delivery to fail", id)
+		}
+		current = current.Add(time.Minute)
+	}
+
+	// kubewatch restarts, the sink is back
+	s.down = false
+	h.Outbox = open(t, dir, &current)
+	h.HandleWithError(event.Event{ID: "new", Kind: "Job", Name: "new"})
+	h.Redeliver()
+
+	expected := []string{"delivered", "new", "failed-1", "failed-2"}
+	if !reflect.DeepEqual(s.delivered, expected) {
+		t.Errorf("expected the failed events to be redelivered in order, got %v", s.delivered)
+	}
+	pending, err := open(t, dir, &current).Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected the outbox to be empty, got %d events, the expired one being dropped", len(pending))
+	}
+}
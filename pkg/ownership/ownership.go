@@ -0,0 +1,192 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ownership attaches the ownership of namespaces, their team, owner, Slack channel
+// and paging service, to events. The ownership is read from a metadata service or a
+// ConfigMap, and refreshed periodically so that events are not delayed by the lookups.
+package ownership
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/httpclient"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxBodySize is the maximum size of the ownership returned by metadata services
+const maxBodySize = 4 << 20
+
+// Directory holds the ownership of namespaces
+type Directory struct {
+	interval time.Duration
+	logger   *logrus.Entry
+	// read returns the ownership of the namespaces
+	read func() (map[string]event.Ownership, error)
+
+	mu     sync.RWMutex
+	owners map[string]event.Ownership
+}
+
+// New returns the directory configured by conf.Ownership, reading its ConfigMap with
+// kubeClient, nil when ownership is not configured
+func New(conf *config.Config, kubeClient kubernetes.Interface) (*Directory, error) {
+	d := &Directory{
+		interval: conf.Ownership.Interval,
+		logger:   logrus.WithField("pkg", "kubewatch-ownership"),
+	}
+	switch {
+	case conf.Ownership.URL != "":
+		client, err := httpclient.New(conf, "ownership")
+		if err != nil {
+			return nil, err
+		}
+		d.read = func() (map[string]event.Ownership, error) {
+			return readURL(client.HTTPClient(), conf.Ownership.URL)
+		}
+	case conf.Ownership.ConfigMap != "":
+		namespace, name, _ := strings.Cut(conf.Ownership.ConfigMap, "/")
+		d.read = func() (map[string]event.Ownership, error) {
+			return readConfigMap(kubeClient, namespace, name)
+		}
+	default:
+		return nil, nil
+	}
+	return d, nil
+}
+
+// Run reads the ownership every interval until stopCh is closed
+func (d *Directory) Run(stopCh <-chan struct{}) {
+	wait.Until(d.refresh, d.interval, stopCh)
+}
+
+// refresh reads the ownership, keeping the last one read when it cannot be read
+func (d *Directory) refresh() {
+	owners, err := d.read()
+	if err != nil {
+		d.logger.Warnf("Cannot read the ownership of namespaces: %v", err)
+		return
+	}
+	d.mu.Lock()
+	d.owners = owners
+	d.mu.Unlock()
+	d.logger.Debugf("Read the ownership of %d namespaces", len(owners))
+}
+
+// Lookup returns the ownership of namespace, nil when unknown
+func (d *Directory) Lookup(namespace string) *event.Ownership {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	o, ok := d.owners[namespace]
+	if !ok {
+		return nil
+	}
+	return &o
+}
+
+// readURL returns the ownership returned by the metadata service at url, a JSON object
+// by namespace
+func readURL(client *http.Client, url string) (map[string]event.Ownership, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	var owners map[string]event.Ownership
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&owners); err != nil {
+		return nil, fmt.Errorf("GET %s: %v", url, err)
+	}
+	return owners, nil
+}
+
+// readConfigMap returns the ownership held by a ConfigMap, whose keys are namespaces and
+// values their JSON or YAML ownership
+func readConfigMap(kubeClient kubernetes.Interface, namespace, name string) (map[string]event.Ownership, error) {
+	cm, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]event.Ownership, len(cm.Data))
+	for ns, data := range cm.Data {
+		var o event.Ownership
+		if err := yaml.Unmarshal([]byte(data), &o); err != nil {
+			return nil, fmt.Errorf("ConfigMap %s/%s, key %s: %v", namespace, name, ns, err)
+		}
+		owners[ns] = o
+	}
+	return owners, nil
+}
+
+// Handler wraps a handler, attaching the ownership of their namespace to events
+type Handler struct {
+	Directory *Directory
+	Handler   handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event with its ownership to the wrapped handler.
+func (h *Handler) Handle(e event.Event) {
+	h.Handler.Handle(h.enrich(e))
+}
+
+// HandleWithError sends the event with its ownership to the wrapped handler, reporting
+// delivery failures when the handler reports them.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx, with the ownership of its
+// namespace attached
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	e = h.enrich(e)
+	return handlers.Deliver(ctx, h.Handler, e)
+}
+
+// enrich attaches the ownership of the namespace of e, unless e has one already
+func (h *Handler) enrich(e event.Event) event.Event {
+	if e.Ownership == nil && e.Namespace != "" {
+		e.Ownership = h.Directory.Lookup(e.Namespace)
+	}
+	return e
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *Handler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ownership
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recordingHandler records the events it handles
+type recordingHandler struct {
+	handled []event.Event
+}
+
+func (h *recordingHandler) Init(c *config.Config) error { return nil }
+func (h *recordingHandler) Handle(e event.Event)        { h.handled = append(h.handled, e) }
+
+func TestReadURL(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"checkout": {"team": "payments", "owner": "alice@example.com", "slackChannel": "#payments", "pagerService": "payments-api"}}`))
+	}))
+	defer server.Close()
+
+	conf := &config.Config{Ownership: config.Ownership{URL: server.URL}}
+	d, err := New(conf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.refresh()
+	expected := &event.Ownership{Team: "payments", Owner: "alice@example.com", SlackChannel: "#payments", PagerService: "payments-api"}
+	if o := d.Lookup("checkout"); !reflect.DeepEqual(o, expected) {
+		t.Errorf("Lookup(checkout) = %+v, expected %+v", o, expected)
+	}
+	if o := d.Lookup("search"); o != nil {
+		t.Errorf("expected no ownership of namespace search, got %+v", o)
+	}
+
+	// the last ownership read is kept when the metadata service fails
+	failing = true
+	d.refresh()
+	if o := d.Lookup("checkout"); !reflect.DeepEqual(o, expected) {
+		t.Errorf("expected the ownership to be kept, got %+v", o)
+	}
+}
+
+func TestReadConfigMap(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&api_v1.ConfigMap{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "kubewatch", Name: "owners"},
+		Data: map[string]string{
+			"checkout": "team: payments\nslackChannel: '#payments'\n",
+			"search":   `{"team": "search", "pagerService": "search"}`,
+		},
+	})
+	conf := &config.Config{Ownership: config.Ownership{ConfigMap: "kubewatch/owners"}}
+	d, err := New(conf, kubeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.refresh()
+
+	h := &Handler{Directory: d, Handler: &recordingHandler{}}
+	recorder := h.Handler.(*recordingHandler)
+	h.Handle(event.Event{Namespace: "checkout", Name: "api"})
+	h.Handle(event.Event{Namespace: "search", Name: "indexer"})
+	h.Handle(event.Event{Name: "node-1"})
+	h.Handle(event.Event{Namespace: "checkout", Name: "api", Ownership: &event.Ownership{Team: "platform"}})
+
+	expected := []*event.Ownership{
+		{Team: "payments", SlackChannel: "#payments"},
+		{Team: "search", PagerService: "search"},
+		nil,
+		{Team: "platform"},
+	}
+	for i, e := range recorder.handled {
+		if !reflect.DeepEqual(e.Ownership, expected[i]) {
+			t.Errorf("%d: expected ownership %+v, got %+v", i, expected[i], e.Ownership)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if d, err := New(&config.Config{}, nil); d != nil || err != nil {
+		t.Errorf("expected no directory without url nor configmap, got %v, %v", d, err)
+	}
+}
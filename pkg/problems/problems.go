@@ -0,0 +1,471 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package problems
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The problems tracked until they clear
+const (
+	CrashLoopBackOff = "CrashLoopBackOff"
+	NodeNotReady     = "NodeNotReady"
+	RolloutFailed    = "RolloutFailed"
+)
+
+// stateFile is the file of the open problems, in the directory of the event store
+const stateFile = "problems.json"
+
+// expireInterval is how often the problems no longer reported are forgotten
+const expireInterval = time.Hour
+
+// Problem is a problem reported by events and not cleared yet
+type Problem struct {
+	Problem string            `json:"problem"`
+	Kind    string            `json:"kind"`
+	Object  event.ObjectRef   `json:"object"`
+	Cluster *event.ClusterRef `json:"cluster,omitempty"`
+	// EventID, CorrelationID and GroupKey are those of the first event reporting the problem
+	EventID       string    `json:"eventId,omitempty"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	GroupKey      string    `json:"groupKey,omitempty"`
+	StartedAt     time.Time `json:"startedAt"`
+	// SeenAt is when an event last reported the problem
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// state is what an event tells about a problem of its object
+type state int
+
+const (
+	// ongoing problems are opened, unless open already
+	ongoing state = iota
+	// cleared problems are resolved
+	cleared
+	// gone objects are deleted, their problems are forgotten
+	gone
+)
+
+// report is what an event tells about a problem of an object
+type report struct {
+	problem    string
+	state      state
+	kind       string
+	apiVersion string
+	namespace  string
+	name       string
+	// reason describes how the problem cleared
+	reason string
+}
+
+// Tracker tracks the problems reported by events, such as pods in CrashLoopBackOff, until
+// later events show they cleared. The open problems are persisted in the directory of the
+// event store when set, so that problems started before a restart are resolved after it.
+type Tracker struct {
+	path   string
+	expire time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	problems map[string]*Problem
+}
+
+// Open returns the Tracker of conf, loading the open problems from dir, or keeping them in
+// memory when dir is empty
+func Open(dir string, conf config.Resolutions) (*Tracker, error) {
+	t := &Tracker{
+		expire:   conf.Expire,
+		now:      time.Now,
+		problems: map[string]*Problem{},
+	}
+	if dir == "" {
+		return t, nil
+	}
+	t.path = filepath.Join(dir, stateFile)
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var problems []*Problem
+	if err := json.Unmarshal(data, &problems); err != nil {
+		return nil, err
+	}
+	for _, p := range problems {
+		t.problems[problemKey(p.Problem, p.Cluster, p.Kind, p.Object.Namespace, p.Object.Name)] = p
+	}
+	return t, nil
+}
+
+// Observe records the problems e reports, and returns the Resolved events of the problems
+// it shows cleared. Cleared problems stay open until their Resolved event is passed to
+// Resolve, so that a resolution failing to be delivered is sent again by the next event
+// showing them cleared.
+func (t *Tracker) Observe(e event.Event) []event.Event {
+	reports := reportsOf(e)
+	if len(reports) == 0 {
+		return nil
+	}
+	cluster := clusterOf(e)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	changed := false
+	var resolved []event.Event
+	for _, r := range reports {
+		key := problemKey(r.problem, cluster, r.kind, r.namespace, r.name)
+		p, open := t.problems[key]
+		switch {
+		case r.state == ongoing && open:
+			p.SeenAt = now
+		case r.state == ongoing:
+			t.problems[key] = &Problem{
+				Problem:       r.problem,
+				Kind:          r.kind,
+				Object:        event.ObjectRef{APIVersion: r.apiVersion, Name: r.name, Namespace: r.namespace},
+				Cluster:       cluster,
+				EventID:       e.ID,
+				CorrelationID: e.CorrelationID,
+				GroupKey:      e.GroupKey,
+				StartedAt:     now,
+				SeenAt:        now,
+			}
+			changed = true
+		case r.state == cleared && open:
+			resolved = append(resolved, p.resolved(r.reason, now))
+		case r.state == gone && open:
+			delete(t.problems, key)
+			changed = true
+		}
+	}
+	if changed {
+		if err := t.save(); err != nil {
+			logrus.WithField("pkg", "kubewatch-problems").Errorf("Error saving problems: %v", err)
+		}
+	}
+	return resolved
+}
+
+// Resolve closes the problem of the Resolved event e, once e is delivered
+func (t *Tracker) Resolve(e event.Event) {
+	if e.Resolution == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, p := range t.problems {
+		if p.Problem == e.Resolution.Problem && p.EventID == e.Resolution.ProblemID && p.Object.Name == e.Name && p.Object.Namespace == e.Namespace {
+			delete(t.problems, key)
+			if err := t.save(); err != nil {
+				logrus.WithField("pkg", "kubewatch-problems").Errorf("Error saving problems: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// Problems returns the open problems, oldest first
+func (t *Tracker) Problems() []Problem {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	problems := make([]Problem, 0, len(t.problems))
+	for _, p := range t.problems {
+		problems = append(problems, *p)
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].StartedAt.Before(problems[j].StartedAt) })
+	return problems
+}
+
+// Expire forgets the problems not reported for longer than the expiry, such as those of
+// objects deleted while kubewatch was not running
+func (t *Tracker) Expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	changed := false
+	for key, p := range t.problems {
+		if now.Sub(p.SeenAt) > t.expire {
+			delete(t.problems, key)
+			changed = true
+		}
+	}
+	if changed {
+		if err := t.save(); err != nil {
+			logrus.WithField("pkg", "kubewatch-problems").Errorf("Error saving problems: %v", err)
+		}
+	}
+}
+
+// Run forgets the expired problems until stopCh is closed
+func (t *Tracker) Run(stopCh <-chan struct{}) {
+	wait.Until(t.Expire, expireInterval, stopCh)
+}
+
+// save writes the open problems, t.mu must be held. The file is written then renamed, so
+// a crash never leaves a truncated file.
+func (t *Tracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+	problems := make([]*Problem, 0, len(t.problems))
+	for _, p := range t.problems {
+		problems = append(problems, p)
+	}
+	data, err := json.Marshal(problems)
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// resolved returns the Resolved event of p, cleared at now. It shares the correlation ID
+// and group key of the event that reported the problem, so that it is threaded with it and
+// consumers can resolve the matching alert.
+func (p *Problem) resolved(reason string, now time.Time) event.Event {
+	e := event.Event{
+		ID:            uuid.New().String(),
+		CorrelationID: p.CorrelationID,
+		GroupKey:      p.GroupKey,
+		Kind:          "Resolved",
+		APIVersion:    p.Object.APIVersion,
+		Name:          p.Object.Name,
+		Namespace:     p.Object.Namespace,
+		Status:        "Normal",
+		Severity:      event.SeverityInfo,
+		Reason:        reason,
+		Timestamp:     now,
+		Resolution: &event.Resolution{
+			Problem:    p.Problem,
+			ProblemID:  p.EventID,
+			StartedAt:  p.StartedAt,
+			ResolvedAt: now,
+		},
+	}
+	if p.Cluster != nil {
+		e.ClusterName = p.Cluster.Name
+		e.Environment = p.Cluster.Environment
+		e.Region = p.Cluster.Region
+		e.ClusterLabels = p.Cluster.Labels
+	}
+	return e
+}
+
+// clusterOf returns the cluster e comes from, nil for unnamed single-cluster setups
+// without metadata
+func clusterOf(e event.Event) *event.ClusterRef {
+	if e.ClusterName == "" && e.Environment == "" && e.Region == "" && len(e.ClusterLabels) == 0 {
+		return nil
+	}
+	return &event.ClusterRef{Name: e.ClusterName, Environment: e.Environment, Region: e.Region, Labels: e.ClusterLabels}
+}
+
+// problemKey identifies a problem of an object
+func problemKey(problem string, cluster *event.ClusterRef, kind, namespace, name string) string {
+	var clusterName string
+	if cluster != nil {
+		clusterName = cluster.Name
+	}
+	return strings.Join([]string{clusterName, problem, kind, namespace, name}, "/")
+}
+
+// reportsOf returns what e tells about the problems of its object
+func reportsOf(e event.Event) []report {
+	deleted := e.Reason == "Deleted"
+	switch obj := e.Obj.(type) {
+	case *api_v1.Event:
+		return kubernetesEventReports(obj.Reason, obj.Message, obj.InvolvedObject.Kind, obj.InvolvedObject.APIVersion, obj.InvolvedObject.Namespace, obj.InvolvedObject.Name, deleted)
+	case *events_v1.Event:
+		return kubernetesEventReports(obj.Reason, obj.Note, obj.Regarding.Kind, obj.Regarding.APIVersion, obj.Regarding.Namespace, obj.Regarding.Name, deleted)
+	case *api_v1.Pod:
+		r := report{problem: CrashLoopBackOff, kind: "Pod", apiVersion: "v1", namespace: obj.Namespace, name: obj.Name}
+		switch {
+		case deleted:
+			r.state = gone
+		case podCrashLooping(obj):
+			r.state = ongoing
+		case podReady(obj):
+			r.state, r.reason = cleared, "Pod is running again"
+		default:
+			return nil
+		}
+		return []report{r}
+	case *api_v1.Node:
+		r := report{problem: NodeNotReady, kind: "Node", apiVersion: "v1", name: obj.Name}
+		switch status := nodeReady(obj); {
+		case deleted:
+			r.state = gone
+		case status == api_v1.ConditionTrue:
+			r.state, r.reason = cleared, "Node is Ready again"
+		case status != "":
+			r.state = ongoing
+		default:
+			return nil
+		}
+		return []report{r}
+	case *apps_v1.Deployment:
+		r := report{problem: RolloutFailed, kind: "Deployment", apiVersion: "apps/v1", namespace: obj.Namespace, name: obj.Name}
+		switch {
+		case deleted:
+			r.state = gone
+		case e.Kind != "Rollout":
+			return nil
+		case e.Status == "Danger":
+			r.state = ongoing
+		case e.Status == "Normal":
+			r.state, r.reason = cleared, e.Reason
+		default:
+			return nil
+		}
+		return []report{r}
+	}
+	return nil
+}
+
+// kubernetesEventReports returns what a Kubernetes Event with reason and message, about an
+// object, tells about its problems. Deleted Kubernetes Events tell nothing, they expire.
+func kubernetesEventReports(reason, message, kind, apiVersion, namespace, name string, deleted bool) []report {
+	if deleted {
+		return nil
+	}
+	r := report{kind: kind, apiVersion: apiVersion, namespace: namespace, name: name}
+	switch {
+	case kind == "Pod" && reason == "BackOff" && strings.Contains(message, "restarting failed container"):
+		r.problem, r.state = CrashLoopBackOff, ongoing
+	case kind == "Node" && reason == "NodeNotReady":
+		r.problem, r.state = NodeNotReady, ongoing
+	case kind == "Node" && reason == "NodeReady":
+		r.problem, r.state, r.reason = NodeNotReady, cleared, "Node is Ready again"
+	default:
+		return nil
+	}
+	return []report{r}
+}
+
+// podCrashLooping returns whether a container of pod is waiting to restart after crashing
+func podCrashLooping(pod *api_v1.Pod) bool {
+	for _, statuses := range [][]api_v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			if status.State.Waiting != nil && status.State.Waiting.Reason == CrashLoopBackOff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// podReady returns whether pod is ready, or completed successfully
+func podReady(pod *api_v1.Pod) bool {
+	if pod.Status.Phase == api_v1.PodSucceeded {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == api_v1.PodReady {
+			return condition.Status == api_v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeReady returns the status of the Ready condition of node, empty when unknown yet
+func nodeReady(node *api_v1.Node) api_v1.ConditionStatus {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == api_v1.NodeReady {
+			return condition.Status
+		}
+	}
+	return ""
+}
+
+// Handler wraps a handler, tracking the problems reported by the events it receives and
+// sending Resolved events when they clear
+type Handler struct {
+	Tracker *Tracker
+	Handler handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler, followed by the Resolved events of the
+// problems it clears
+func (h *Handler) Handle(e event.Event) {
+	resolved := h.Tracker.Observe(e)
+	h.Handler.Handle(e)
+	for _, r := range resolved {
+		h.Handler.Handle(r)
+		h.Tracker.Resolve(r)
+	}
+}
+
+// HandleWithError sends the event to the wrapped handler, reporting delivery failures when
+// the handler reports them, followed by the Resolved events of the problems it
+// clears. Failures to deliver Resolved events are reported too, so that the event is
+// retried and sends them again.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx, followed by the resolutions of
+// the problems it resolves. The problems stay open until their resolution is delivered.
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	resolved := h.Tracker.Observe(e)
+	if err := handlers.Deliver(ctx, h.Handler, e); err != nil {
+		return err
+	}
+	for _, r := range resolved {
+		if err := handlers.Deliver(ctx, h.Handler, r); err != nil {
+			return fmt.Errorf("sending the resolution of %s: %v", r.Resolution.Problem, err)
+		}
+		h.Tracker.Resolve(r)
+	}
+	return nil
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *Handler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package problems
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// recordingHandler records the events it receives, after failing to deliver the first
+// failures events of kind failing
+type recordingHandler struct {
+	events   []event.Event
+	failing  string
+	failures int
+}
+
+func (r *recordingHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *recordingHandler) Handle(e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *recordingHandler) HandleWithError(e event.Event) error {
+	if r.failures > 0 && e.Kind == r.failing {
+		r.failures--
+		return errors.New("endpoint unavailable")
+	}
+	r.Handle(e)
+	return nil
+}
+
+// openTracker returns a Tracker of dir forgetting problems after a day, at the time
+// current points to
+func openTracker(t *testing.T, dir string, current *time.Time) *Tracker {
+	tracker, err := Open(dir, config.Resolutions{Enabled: true, Expire: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.now = func() time.Time { return *current }
+	return tracker
+}
+
+func pod(waiting string, ready api_v1.ConditionStatus) *api_v1.Pod {
+	pod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api-7d9f", Namespace: "payments"}}
+	pod.Status.Phase = api_v1.PodRunning
+	pod.Status.Conditions = []api_v1.PodCondition{{Type: api_v1.PodReady, Status: ready}}
+	status := api_v1.ContainerStatus{Name: "api"}
+	if waiting != "" {
+		status.State.Waiting = &api_v1.ContainerStateWaiting{Reason: waiting}
+	}
+	pod.Status.ContainerStatuses = []api_v1.ContainerStatus{status}
+	return pod
+}
+
+func TestReportsOf(t *testing.T) {
+	backOff := &api_v1.Event{
+		InvolvedObject: api_v1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: "payments", Name: "api-7d9f"},
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container api in pod api-7d9f",
+	}
+	pullBackOff := backOff.DeepCopy()
+	pullBackOff.Message = `Back-off pulling image "api:v2"`
+	notReady := &api_v1.Node{ObjectMeta: meta_v1.ObjectMeta{Name: "node-1"}}
+	notReady.Status.Conditions = []api_v1.NodeCondition{{Type: api_v1.NodeReady, Status: api_v1.ConditionUnknown}}
+	deployment := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "payments"}}
+
+	var Tests = []struct {
+		name    string
+		e       event.Event
+		problem string
+		state   state
+	}{
+		{"crash loop event", event.Event{Kind: "Event", Reason: "Created", Obj: backOff}, CrashLoopBackOff, ongoing},
+		{"image pull back-off", event.Event{Kind: "Event", Reason: "Created", Obj: pullBackOff}, "", 0},
+		{"crash looping pod", event.Event{Kind: "Pod", Reason: "Updated", Obj: pod(CrashLoopBackOff, api_v1.ConditionFalse)}, CrashLoopBackOff, ongoing},
+		{"ready pod", event.Event{Kind: "Pod", Reason: "Updated", Obj: pod("", api_v1.ConditionTrue)}, CrashLoopBackOff, cleared},
+		{"starting pod", event.Event{Kind: "Pod", Reason: "Updated", Obj: pod("", api_v1.ConditionFalse)}, "", 0},
+		{"deleted pod", event.Event{Kind: "Pod", Reason: "Deleted", Obj: pod(CrashLoopBackOff, api_v1.ConditionFalse)}, CrashLoopBackOff, gone},
+		{"not ready node", event.Event{Kind: "Node", Reason: "Updated", Obj: notReady}, NodeNotReady, ongoing},
+		{"failed rollout", event.Event{Kind: "Rollout", Status: "Danger", Obj: deployment}, RolloutFailed, ongoing},
+		{"complete rollout", event.Event{Kind: "Rollout", Status: "Normal", Reason: "Rollout of revision 4 complete", Obj: deployment}, RolloutFailed, cleared},
+		{"started rollout", event.Event{Kind: "Rollout", Status: "Warning", Obj: deployment}, "", 0},
+		{"updated deployment", event.Event{Kind: "Deployment", Reason: "Updated", Obj: deployment}, "", 0},
+	}
+
+	for _, tt := range Tests {
+		reports := reportsOf(tt.e)
+		switch {
+		case tt.problem == "" && len(reports) != 0:
+			t.Errorf("%s: expected no report, got %+v", tt.name, reports)
+		case tt.problem != "" && (len(reports) != 1 || reports[0].problem != tt.problem || reports[0].state != tt.state):
+			t.Errorf("%s: expected %s in state %d, got %+v", tt.name, tt.problem, tt.state, reports)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	sent := &recordingHandler{}
+	h := &Handler{Tracker: openTracker(t, dir, &current), Handler: sent}
+
+	crash := event.Event{ID: "crash", CorrelationID: "pod-uid", Kind: "Pod", Name: "api-7d9f", Namespace: "payments", Reason: "Updated", ClusterName: "prod", Obj: pod(CrashLoopBackOff, api_v1.ConditionFalse)}
+	h.Handle(crash)
+	current = current.Add(5 * time.Minute)
+	crash.ID = "crash-again"
+	h.Handle(crash)
+
+	// open problems survive restarts
+	current = current.Add(7 * time.Minute)
+	h.Tracker = openTracker(t, dir, &current)
+	if problems := h.Tracker.Problems(); len(problems) != 1 || problems[0].EventID != "crash" {
+		t.Fatalf("expected the crash loop to be open, got %+v", problems)
+	}
+	h.Handle(event.Event{ID: "ready", Kind: "Pod", Name: "api-7d9f", Namespace: "payments", Reason: "Updated", ClusterName: "prod", Obj: pod("", api_v1.ConditionTrue)})
+	h.Handle(event.Event{ID: "ready-again", Kind: "Pod", Name: "api-7d9f", Namespace: "payments", Reason: "Updated", ClusterName: "prod", Obj: pod("", api_v1.ConditionTrue)})
+
+	if len(sent.events) != 5 {
+		t.Fatalf("expected the events and a single resolution, got %d events", len(sent.events))
+	}
+	resolved := sent.events[3]
+	if resolved.Kind != "Resolved" || resolved.Name != "api-7d9f" || resolved.Namespace != "payments" || resolved.ClusterName != "prod" || resolved.CorrelationID != "pod-uid" {
+		t.Errorf("unexpected resolution %+v", resolved)
+	}
+	if r := resolved.Resolution; r == nil || r.Problem != CrashLoopBackOff || r.ProblemID != "crash" || r.Duration() != 12*time.Minute {
+		t.Errorf("unexpected resolution details %+v", r)
+	}
+	if problems := h.Tracker.Problems(); len(problems) != 0 {
+		t.Errorf("expected no open problem, got %+v", problems)
+	}
+}
+
+func TestHandlerRetriesResolutions(t *testing.T) {
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	sent := &recordingHandler{failing: "Resolved", failures: 1}
+	h := &Handler{Tracker: openTracker(t, t.TempDir(), &current), Handler: sent}
+
+	if err := h.HandleWithError(event.Event{ID: "crash", Kind: "Pod", Name: "api-7d9f", Namespace: "payments", Reason: "Updated", Obj: pod(CrashLoopBackOff, api_v1.ConditionFalse)}); err != nil {
+		t.Fatal(err)
+	}
+	ready := event.Event{ID: "ready", Kind: "Pod", Name: "api-7d9f", Namespace: "payments", Reason: "Updated", Obj: pod("", api_v1.ConditionTrue)}
+	if err := h.HandleWithError(ready); err == nil {
+		t.Fatal("expected the failed resolution to be reported")
+	}
+	if problems := h.Tracker.Problems(); len(problems) != 1 {
+		t.Fatalf("expected the crash loop to stay open until its resolution is sent, got %+v", problems)
+	}
+
+	// the retried event sends the resolution
+	if err := h.HandleWithError(ready); err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, e := range sent.events {
+		kinds = append(kinds, e.Kind)
+	}
+	if len(kinds) != 4 || kinds[3] != "Resolved" {
+		t.Errorf("expected the resolution to be sent once retried, got %v", kinds)
+	}
+	if problems := h.Tracker.Problems(); len(problems) != 0 {
+		t.Errorf("expected no open problem, got %+v", problems)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tracker := openTracker(t, "", &current)
+	tracker.Observe(event.Event{Kind: "Pod", Reason: "Updated", Obj: pod(CrashLoopBackOff, api_v1.ConditionFalse)})
+
+	current = current.Add(23 * time.Hour)
+	tracker.Expire()
+	if len(tracker.Problems()) != 1 {
+		t.Fatal("expected the problem to be kept until it expires")
+	}
+	current = current.Add(2 * time.Hour)
+	tracker.Expire()
+	if len(tracker.Problems()) != 0 {
+		t.Error("expected the problem not reported for a day to be forgotten")
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render renders the messages of events in the markup of each handler: Slack
+// mrkdwn, Microsoft Teams card text, HTML and plain text, from a single model of the
+// message, so that formatting is fixed once for every handler.
+package render
+
+import (
+	"html"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// codeFence opens and closes the code blocks of messages
+const codeFence = "```"
+
+// Document is the model of a message, a sequence of lines and code blocks
+type Document struct {
+	Blocks []Block
+}
+
+// Block is a line of text, or a code block when Code is set
+type Block struct {
+	Spans []Span
+	// Emphasis is set for lines emphasized as a whole, such as the truncation note
+	Emphasis bool
+	Code     bool
+	// Lines are the lines of code blocks
+	Lines []string
+}
+
+// Span is a run of text of a line, inline code when Code is set, such as the names of
+// objects
+type Span struct {
+	Text string
+	Code bool
+}
+
+// Parse returns the document of a message in the lightweight markup of kubewatch messages:
+// `inline code`, code blocks fenced by ``` lines, and _emphasized_ lines. Unmatched
+// backticks are kept as text.
+func Parse(markup string) Document {
+	var d Document
+	var code *Block
+	for _, line := range strings.Split(markup, "\n") {
+		if strings.TrimSpace(line) == codeFence {
+			if code == nil {
+				code = &Block{Code: true}
+			} else {
+				d.Blocks = append(d.Blocks, *code)
+				code = nil
+			}
+			continue
+		}
+		if code != nil {
+			code.Lines = append(code.Lines, line)
+			continue
+		}
+		if len(line) > 2 && strings.HasPrefix(line, "_") && strings.HasSuffix(line, "_") {
+			d.Blocks = append(d.Blocks, Block{Spans: parseSpans(line[1 : len(line)-1]), Emphasis: true})
+			continue
+		}
+		d.Blocks = append(d.Blocks, Block{Spans: parseSpans(line)})
+	}
+	// unterminated code blocks end with the message
+	if code != nil {
+		d.Blocks = append(d.Blocks, *code)
+	}
+	return d
+}
+
+// parseSpans splits a line into text and inline code spans
+func parseSpans(line string) []Span {
+	var spans []Span
+	text := func(s string) {
+		if s == "" {
+			return
+		}
+		if n := len(spans); n > 0 && !spans[n-1].Code {
+			spans[n-1].Text += s
+			return
+		}
+		spans = append(spans, Span{Text: s})
+	}
+	for {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(line[start+1:], "`")
+		if end < 0 {
+			break
+		}
+		text(line[:start])
+		if code := line[start+1 : start+1+end]; code != "" {
+			spans = append(spans, Span{Text: code, Code: true})
+		}
+		line = line[start+end+2:]
+	}
+	text(line)
+	return spans
+}
+
+// Renderer renders documents in the markup of a handler
+type Renderer interface {
+	Render(d Document) string
+}
+
+// Renderers of the markups of handlers
+var (
+	// Slack renders Slack mrkdwn, escaping the characters Slack reserves
+	Slack Renderer = slackRenderer{}
+	// Teams renders the text of Microsoft Teams cards, which have no code formatting:
+	// inline code is bold and code blocks are plain lines
+	Teams Renderer = teamsRenderer{}
+	// HTML renders an HTML fragment, for emails
+	HTML Renderer = htmlRenderer{}
+	// Text renders plain text, without markup
+	Text Renderer = textRenderer{}
+)
+
+// Message renders the message of e with r
+func Message(r Renderer, e *event.Event) string {
+	return r.Render(Parse(e.Message()))
+}
+
+// TextMessage renders the message of e with r, prefixed with the emoji of its severity as
+// text-only handlers send it
+func TextMessage(r Renderer, e *event.Event) string {
+	msg := Message(r, e)
+	if emoji := e.Emoji(); emoji != "" {
+		return emoji + " " + msg
+	}
+	return msg
+}
+
+type slackRenderer struct{}
+
+// slackEscaper escapes the control characters of Slack mrkdwn
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (slackRenderer) Render(d Document) string {
+	lines := make([]string, 0, len(d.Blocks))
+	for _, b := range d.Blocks {
+		if b.Code {
+			lines = append(lines, codeFence+"\n"+slackEscaper.Replace(strings.Join(b.Lines, "\n"))+"\n"+codeFence)
+			continue
+		}
+		line := renderSpans(b.Spans, slackEscaper.Replace, func(code string) string {
+			return "`" + slackEscaper.Replace(code) + "`"
+		})
+		if b.Emphasis {
+			line = "_" + line + "_"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+type teamsRenderer struct{}
+
+// teamsEscaper escapes the markdown characters of Teams card text
+var teamsEscaper = strings.NewReplacer("*", `\*`, "_", `\_`)
+
+func (teamsRenderer) Render(d Document) string {
+	var lines []string
+	for _, b := range d.Blocks {
+		if b.Code {
+			for _, line := range b.Lines {
+				lines = append(lines, teamsEscaper.Replace(line))
+			}
+			continue
+		}
+		line := renderSpans(b.Spans, teamsEscaper.Replace, func(code string) string {
+			return "**" + teamsEscaper.Replace(code) + "**"
+		})
+		if b.Emphasis {
+			line = "_" + line + "_"
+		}
+		lines = append(lines, line)
+	}
+	// Teams breaks lines on blank lines only
+	return strings.Join(lines, "\n\n")
+}
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) Render(d Document) string {
+	var b strings.Builder
+	for i, block := range d.Blocks {
+		if block.Code {
+			b.WriteString("<pre>" + html.EscapeString(strings.Join(block.Lines, "\n")) + "</pre>\n")
+			continue
+		}
+		line := renderSpans(block.Spans, html.EscapeString, func(code string) string {
+			return "<code>" + html.EscapeString(code) + "</code>"
+		})
+		if block.Emphasis {
+			line = "<em>" + line + "</em>"
+		}
+		b.WriteString(line)
+		if i < len(d.Blocks)-1 && !d.Blocks[i+1].Code {
+			b.WriteString("<br>")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+type textRenderer struct{}
+
+func (textRenderer) Render(d Document) string {
+	var lines []string
+	for _, b := range d.Blocks {
+		if b.Code {
+			lines = append(lines, b.Lines...)
+			continue
+		}
+		lines = append(lines, renderSpans(b.Spans, identity, identity))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderSpans renders the spans of a line, text with text and inline code with code
+func renderSpans(spans []Span, text, code func(string) string) string {
+	var b strings.Builder
+	for _, s := range spans {
+		if s.Code {
+			b.WriteString(code(s.Text))
+		} else {
+			b.WriteString(text(s.Text))
+		}
+	}
+	return b.String()
+}
+
+func identity(s string) string {
+	return s
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// message exercises every markup of messages
+const message = "A `Pod` in namespace `default` has been `Updated`:\n`web`\n" +
+	"Last logs of `web` (exit code 1):\n```\nerror: x < y & *z*\n```\n" +
+	"Grafana: https://grafana.example.com/d?a=1&b=2\n_Truncated: diff_"
+
+func TestParse(t *testing.T) {
+	expected := Document{Blocks: []Block{
+		{Spans: []Span{{Text: "A "}, {Text: "Pod", Code: true}, {Text: " in namespace "}, {Text: "default", Code: true}, {Text: " has been "}, {Text: "Updated", Code: true}, {Text: ":"}}},
+		{Spans: []Span{{Text: "web", Code: true}}},
+		{Spans: []Span{{Text: "Last logs of "}, {Text: "web", Code: true}, {Text: " (exit code 1):"}}},
+		{Code: true, Lines: []string{"error: x < y & *z*"}},
+		{Spans: []Span{{Text: "Grafana: https://grafana.example.com/d?a=1&b=2"}}},
+		{Spans: []Span{{Text: "Truncated: diff"}}, Emphasis: true},
+	}}
+	if d := Parse(message); !reflect.DeepEqual(d, expected) {
+		t.Errorf("Parse() = %+v, expected %+v", d, expected)
+	}
+
+	// unmatched backticks and unterminated code blocks
+	expected = Document{Blocks: []Block{
+		{Spans: []Span{{Text: "it's `quoted"}}},
+		{Code: true, Lines: []string{"tail"}},
+	}}
+	if d := Parse("it's `quoted\n```\ntail"); !reflect.DeepEqual(d, expected) {
+		t.Errorf("Parse() = %+v, expected %+v", d, expected)
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	var Tests = []struct {
+		name     string
+		renderer Renderer
+		expected string
+	}{
+		{"slack", Slack, "A `Pod` in namespace `default` has been `Updated`:\n`web`\n" +
+			"Last logs of `web` (exit code 1):\n```\nerror: x &lt; y &amp; *z*\n```\n" +
+			"Grafana: https://grafana.example.com/d?a=1&amp;b=2\n_Truncated: diff_"},
+		{"teams", Teams, "A **Pod** in namespace **default** has been **Updated**:\n\n**web**\n\n" +
+			"Last logs of **web** (exit code 1):\n\nerror: x < y & \\*z\\*\n\n" +
+			"Grafana: https://grafana.example.com/d?a=1&b=2\n\n_Truncated: diff_"},
+		{"html", HTML, "A <code>Pod</code> in namespace <code>default</code> has been <code>Updated</code>:<br>\n<code>web</code><br>\n" +
+			"Last logs of <code>web</code> (exit code 1):\n<pre>error: x &lt; y &amp; *z*</pre>\n" +
+			"Grafana: https://grafana.example.com/d?a=1&amp;b=2<br>\n<em>Truncated: diff</em>"},
+		{"text", Text, "A Pod in namespace default has been Updated:\nweb\n" +
+			"Last logs of web (exit code 1):\nerror: x < y & *z*\n" +
+			"Grafana: https://grafana.example.com/d?a=1&b=2\nTruncated: diff"},
+	}
+
+	d := Parse(message)
+	for _, tt := range Tests {
+		if rendered := tt.renderer.Render(d); rendered != tt.expected {
+			t.Errorf("%s: Render() = %q, expected %q", tt.name, rendered, tt.expected)
+		}
+	}
+}
+
+func TestTextMessage(t *testing.T) {
+	e := &event.Event{Kind: "Pod", Namespace: "default", Name: "web", Reason: "Deleted", Status: "Danger"}
+	if msg, expected := TextMessage(Text, e), "🚨 A Pod in namespace default has been Deleted:\nweb"; msg != expected {
+		t.Errorf("TextMessage() = %q, expected %q", msg, expected)
+	}
+}
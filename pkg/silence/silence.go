@@ -0,0 +1,279 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package silence
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/stats"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// APIPath is where silences are managed on the metrics server
+const APIPath = "/api/v1/silences"
+
+// Silence mutes the events matching all its patterns until it expires. Patterns are shell
+// globs (e.g. api-*), empty patterns match everything.
+type Silence struct {
+	ID        string    `json:"id"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Kind      string    `json:"kind,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Matches returns whether e is muted by s
+func (s Silence) Matches(e event.Event) bool {
+	return match(s.Cluster, e.ClusterName) && match(s.Namespace, e.Namespace) &&
+		match(s.Kind, e.Kind) && match(s.Name, e.Name)
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+// validate returns an error when s has no pattern, an invalid pattern, or is expired
+func (s Silence) validate(now time.Time) error {
+	patterns := []string{s.Cluster, s.Namespace, s.Kind, s.Name}
+	if strings.Join(patterns, "") == "" {
+		return errors.New("a silence needs at least one of cluster, namespace, kind or name")
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	if !s.ExpiresAt.After(now) {
+		return errors.New("a silence needs an expiry in the future")
+	}
+	return nil
+}
+
+// Store holds the silences created at runtime
+type Store struct {
+	mu       sync.Mutex
+	silences map[string]Silence
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewStore returns an empty Store
+func NewStore() *Store {
+	return &Store{silences: map[string]Silence{}, now: time.Now}
+}
+
+// Add stores s with a new ID, returning the stored silence
+func (st *Store) Add(s Silence) (Silence, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+	if err := s.validate(now); err != nil {
+		return s, err
+	}
+	s.ID = uuid.New().String()
+	s.CreatedAt = now
+	st.silences[s.ID] = s
+	return s, nil
+}
+
+// Delete removes the silence with id, returning whether it existed
+func (st *Store) Delete(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	_, ok := st.silences[id]
+	delete(st.silences, id)
+	return ok
+}
+
+// List returns the active silences, expiring first
+func (st *Store) List() []Silence {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.prune()
+	list := []Silence{}
+	for _, s := range st.silences {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].ExpiresAt.Equal(list[j].ExpiresAt) {
+			return list[i].ExpiresAt.Before(list[j].ExpiresAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Match returns the active silence muting e, nil if e is not muted
+func (st *Store) Match(e event.Event) *Silence {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.prune()
+	for _, s := range st.silences {
+		if s.Matches(e) {
+			return &s
+		}
+	}
+	return nil
+}
+
+// prune drops the expired silences, st.mu must be held
+func (st *Store) prune() {
+	now := st.now()
+	for id, s := range st.silences {
+		if !s.ExpiresAt.After(now) {
+			delete(st.silences, id)
+		}
+	}
+}
+
+// request is the body of silence creations, setting either Duration or ExpiresAt
+type request struct {
+	Silence
+	// Duration of the silence, such as 2h
+	Duration string `json:"duration,omitempty"`
+}
+
+// API returns the admin API managing the silences of st under APIPath, requiring token as
+// bearer token:
+//
+//	GET    /api/v1/silences       lists the active silences
+//	POST   /api/v1/silences       creates a silence
+//	DELETE /api/v1/silences/<id>  deletes a silence
+func (st *Store) API(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, APIPath), "/")
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			writeJSON(w, http.StatusOK, st.List())
+		case r.Method == http.MethodPost && id == "":
+			var req request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Duration != "" {
+				d, err := time.ParseDuration(req.Duration)
+				if err != nil {
+					http.Error(w, "duration: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+				req.ExpiresAt = st.now().Add(d)
+			}
+			s, err := st.Add(req.Silence)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logrus.WithField("pkg", "kubewatch-silence").Infof("Silence %s created until %s: %s", s.ID, s.ExpiresAt.Format(time.RFC3339), s.Comment)
+			writeJSON(w, http.StatusCreated, s)
+		case r.Method == http.MethodDelete && id != "":
+			if !st.Delete(id) {
+				http.Error(w, "silence not found", http.StatusNotFound)
+				return
+			}
+			logrus.WithField("pkg", "kubewatch-silence").Infof("Silence %s deleted", id)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Handler wraps a handler, dropping the events muted by the silences of Store
+type Handler struct {
+	Store   *Store
+	Handler handlers.Handler
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler unless it is muted.
+func (h *Handler) Handle(e event.Event) {
+	if !h.muted(e) {
+		h.Handler.Handle(e)
+	}
+}
+
+// HandleWithError sends the event to the wrapped handler unless it is muted, reporting
+// delivery failures when the handler reports them.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx unless a silence mutes it
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	if h.muted(e) {
+		return nil
+	}
+	return handlers.Deliver(ctx, h.Handler, e)
+}
+
+func (h *Handler) muted(e event.Event) bool {
+	s := h.Store.Match(e)
+	if s == nil {
+		return false
+	}
+	logrus.WithFields(logging.EventFields(e)).WithField("pkg", "kubewatch-silence").Debugf("Event muted by silence %s", s.ID)
+	stats.Silenced()
+	return true
+}
+
+// Flush flushes the wrapped handler when it buffers events
+func (h *Handler) Flush() error {
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package silence
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestMatches(t *testing.T) {
+	e := event.Event{ClusterName: "prod", Namespace: "payments", Kind: "Pod", Name: "api-7d9f"}
+
+	var Tests = []struct {
+		silence Silence
+		matches bool
+	}{
+		{Silence{Namespace: "payments"}, true},
+		{Silence{Namespace: "payments", Kind: "Pod", Name: "api-*"}, true},
+		{Silence{Cluster: "staging", Namespace: "payments"}, false},
+		{Silence{Name: "web-*"}, false},
+		{Silence{Namespace: "pay*", Kind: "Deployment"}, false},
+	}
+	for i, test := range Tests {
+		if matches := test.silence.Matches(e); matches != test.matches {
+			t.Errorf("%d: expected Matches() to be %v, got %v", i, test.matches, matches)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	st := NewStore()
+	current := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	st.now = func() time.Time { return current }
+
+	for _, invalid := range []Silence{
+		{ExpiresAt: current.Add(time.Hour)},
+		{Name: "[", ExpiresAt: current.Add(time.Hour)},
+		{Name: "api", ExpiresAt: current},
+	} {
+		if _, err := st.Add(invalid); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+
+	short, err := st.Add(Silence{Namespace: "payments", ExpiresAt: current.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := st.Add(Silence{Kind: "Node", ExpiresAt: current.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := st.List(); len(list) != 2 || list[0].ID != short.ID || list[1].ID != long.ID {
+		t.Errorf("expected the silences expiring first first, got %+v", list)
+	}
+	if s := st.Match(event.Event{Namespace: "payments", Kind: "Pod"}); s == nil || s.ID != short.ID {
+		t.Errorf("expected the payments silence to match, got %+v", s)
+	}
+
+	current = current.Add(90 * time.Minute)
+	if s := st.Match(event.Event{Namespace: "payments", Kind: "Pod"}); s != nil {
+		t.Errorf("expected the expired silence not to match, got %+v", s)
+	}
+	if !st.Delete(long.ID) || st.Delete(long.ID) {
+		t.Error("expected the silence to be deleted once")
+	}
+	if list := st.List(); len(list) != 0 {
+		t.Errorf("expected no silence left, got %+v", list)
+	}
+}
+
+func TestAPI(t *testing.T) {
+	st := NewStore()
+	current := time.Now()
+	st.now = func() time.Time { return current }
+	api := st.API("secret")
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, APIPath, "wrong", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong token to be rejected, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, APIPath, "secret", `{"namespace":"payments","duration":"forever"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid duration to be rejected, got %d", w.Code)
+	}
+
+	w := do(http.MethodPost, APIPath, "secret", `{"namespace":"payments","name":"api-*","duration":"2h","comment":"flapping"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected the silence to be created, got %d: %s", w.Code, w.Body)
+	}
+	var created Silence
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.ExpiresAt.Sub(created.CreatedAt) != 2*time.Hour {
+		t.Errorf("expected a 2h silence with an ID, got %+v", created)
+	}
+
+	var listed []Silence
+	w = do(http.MethodGet, APIPath, "secret", "")
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil || len(listed) != 1 || listed[0].ID != created.ID {
+		t.Errorf("expected the created silence to be listed, got %+v (%v)", listed, err)
+	}
+
+	if w := do(http.MethodDelete, APIPath+"/"+created.ID, "secret", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected the silence to be deleted, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, APIPath+"/"+created.ID, "secret", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected a deleted silence not to be found, got %d", w.Code)
+	}
+}
+
+// recordingHandler records the names of the events it receives
+type recordingHandler struct {
+	names []string
+}
+
+func (r *recordingHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *recordingHandler) Handle(e event.Event) {
+	r.names = append(r.names, e.Name)
+}
+
+func TestHandler(t *testing.T) {
+	st := NewStore()
+	if _, err := st.Add(Silence{Namespace: "payments", Name: "api-*", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	r := &recordingHandler{}
+	h := &Handler{Store: st, Handler: r}
+	h.Handle(event.Event{Namespace: "payments", Name: "api-7d9f"})
+	if err := h.HandleWithError(event.Event{Namespace: "payments", Name: "web-5c8b"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.names) != 1 || r.names[0] != "web-5c8b" {
+		t.Errorf("expected only the web event to be sent, got %v", r.names)
+	}
+}
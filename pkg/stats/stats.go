@@ -0,0 +1,231 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// window is the period of the recent statistics
+	window = time.Hour
+	// bucketWidth is the resolution of the recent statistics
+	bucketWidth = time.Minute
+	// topCount is the number of namespaces and kinds listed in the recent statistics
+	topCount = 10
+)
+
+// Summary holds the statistics of the events handled since kubewatch started
+type Summary struct {
+	Since time.Time `json:"since"`
+	// Seen is the number of events observed by the informers, before filtering
+	Seen uint64 `json:"seen"`
+	// Sent is the number of events delivered by the event handler, once however many times
+	// they were retried
+	Sent uint64 `json:"sent"`
+	// Failed is the number of failed deliveries of events by the event handler, each retry
+	// counting
+	Failed uint64 `json:"failed"`
+	// Filtered is the number of events seen but not sent
+	Filtered uint64 `json:"filtered"`
+	// Silenced is the number of events sent but muted by a silence
+	Silenced uint64 `json:"silenced"`
+	// Handlers holds the deliveries of each handler, including heartbeats and internal errors
+	Handlers map[string]Deliveries `json:"handlers"`
+	// LastHour holds the events seen over the last hour
+	LastHour Recent `json:"lastHour"`
+}
+
+// Deliveries counts the events delivered by a handler
+type Deliveries struct {
+	Sent   uint64 `json:"sent"`
+	Failed uint64 `json:"failed"`
+}
+
+// Recent holds the statistics of the events seen over a recent period
+type Recent struct {
+	Seen uint64 `json:"seen"`
+	// TopNamespaces are the namespaces with the most events, cluster-scoped objects excluded
+	TopNamespaces []Count `json:"topNamespaces"`
+	// TopKinds are the kinds with the most events
+	TopKinds []Count `json:"topKinds"`
+}
+
+// Count is the number of events of a namespace or kind
+type Count struct {
+	Cluster string `json:"cluster,omitempty"`
+	Name    string `json:"name"`
+	Events  uint64 `json:"events"`
+}
+
+type countKey struct {
+	cluster, name string
+}
+
+// bucket counts the events seen in bucketWidth from start
+type bucket struct {
+	start      time.Time
+	seen       uint64
+	namespaces map[countKey]uint64
+	kinds      map[countKey]uint64
+}
+
+// now is replaced in tests
+var now = time.Now
+
+var stats = struct {
+	mu       sync.Mutex
+	since    time.Time
+	seen     uint64
+	sent     uint64
+	failed   uint64
+	silenced uint64
+	handlers map[string]*Deliveries
+	buckets  []*bucket
+}{since: now(), handlers: map[string]*Deliveries{}}
+
+// Observed counts an event of kind in namespace, empty for cluster-scoped objects,
+// seen by the informers of cluster
+func Observed(cluster, kind, namespace string) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.seen++
+	b := currentBucket()
+	b.seen++
+	b.kinds[countKey{cluster, kind}]++
+	if namespace != "" {
+		b.namespaces[countKey{cluster, namespace}]++
+	}
+}
+
+// Sent counts an event delivered by the event handler
+func Sent() {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.sent++
+}
+
+// Failed counts a failed delivery of an event by the event handler
+func Failed() {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.failed++
+}
+
+// Silenced counts an event muted by a silence
+func Silenced() {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.silenced++
+}
+
+// Delivered counts an event delivered by handler, failed when err is not nil
+func Delivered(handler string, err error) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	d := stats.handlers[handler]
+	if d == nil {
+		d = &Deliveries{}
+		stats.handlers[handler] = d
+	}
+	if err != nil {
+		d.Failed++
+	} else {
+		d.Sent++
+	}
+}
+
+// Get returns the statistics of the events handled so far
+func Get() Summary {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	pruneBuckets()
+
+	summary := Summary{
+		Since:    stats.since,
+		Seen:     stats.seen,
+		Sent:     stats.sent,
+		Failed:   stats.failed,
+		Silenced: stats.silenced,
+		Handlers: map[string]Deliveries{},
+	}
+	if stats.seen > stats.sent {
+		summary.Filtered = stats.seen - stats.sent
+	}
+	for name, d := range stats.handlers {
+		summary.Handlers[name] = *d
+	}
+
+	namespaces, kinds := map[countKey]uint64{}, map[countKey]uint64{}
+	for _, b := range stats.buckets {
+		summary.LastHour.Seen += b.seen
+		for key, n := range b.namespaces {
+			namespaces[key] += n
+		}
+		for key, n := range b.kinds {
+			kinds[key] += n
+		}
+	}
+	summary.LastHour.TopNamespaces = top(namespaces)
+	summary.LastHour.TopKinds = top(kinds)
+	return summary
+}
+
+// currentBucket returns the bucket of the current time, creating it when needed
+func currentBucket() *bucket {
+	start := now().Truncate(bucketWidth)
+	if n := len(stats.buckets); n > 0 && stats.buckets[n-1].start.Equal(start) {
+		return stats.buckets[n-1]
+	}
+	pruneBuckets()
+	b := &bucket{start: start, namespaces: map[countKey]uint64{}, kinds: map[countKey]uint64{}}
+	stats.buckets = append(stats.buckets, b)
+	return b
+}
+
+// pruneBuckets drops the buckets older than window
+func pruneBuckets() {
+	oldest := now().Add(-window)
+	i := 0
+	for i < len(stats.buckets) && !stats.buckets[i].start.After(oldest) {
+		i++
+	}
+	stats.buckets = stats.buckets[i:]
+}
+
+// top returns the topCount largest counts, largest first
+func top(counts map[countKey]uint64) []Count {
+	list := make([]Count, 0, len(counts))
+	for key, n := range counts {
+		list = append(list, Count{Cluster: key.cluster, Name: key.name, Events: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Events != list[j].Events {
+			return list[i].Events > list[j].Events
+		}
+		if list[i].Cluster != list[j].Cluster {
+			return list[i].Cluster < list[j].Cluster
+		}
+		return list[i].Name < list[j].Name
+	})
+	if len(list) > topCount {
+		list = list[:topCount]
+	}
+	return list
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	current := start
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+	stats.seen, stats.sent, stats.failed, stats.handlers, stats.buckets = 0, 0, 0, map[string]*Deliveries{}, nil
+
+	for i := 0; i < 3; i++ {
+		Observed("", "Pod", "team-a")
+	}
+	Observed("", "Node", "")
+	current = start.Add(30 * time.Minute)
+	Observed("", "Pod", "team-b")
+	Observed("prod", "Pod", "team-b")
+	Failed()
+	Sent()
+	Delivered("slack", nil)
+	Delivered("slack", errors.New("timeout"))
+
+	summary := Get()
+	if summary.Seen != 6 || summary.Sent != 1 || summary.Failed != 1 || summary.Filtered != 5 {
+		t.Errorf("expected 6 events seen, 1 sent after a failure and 5 filtered, got %+v", summary)
+	}
+	if d := summary.Handlers["slack"]; d.Sent != 1 || d.Failed != 1 {
+		t.Errorf("expected 1 event sent and 1 failed by slack, got %+v", d)
+	}
+	if top := summary.LastHour.TopNamespaces; len(top) != 3 || top[0] != (Count{Name: "team-a", Events: 3}) || top[1] != (Count{Name: "team-b", Events: 1}) || top[2] != (Count{Cluster: "prod", Name: "team-b", Events: 1}) {
+		t.Errorf("expected team-a then team-b of both clusters as top namespaces, got %+v", top)
+	}
+	if top := summary.LastHour.TopKinds; len(top) != 3 || top[0] != (Count{Name: "Pod", Events: 4}) || top[1] != (Count{Name: "Node", Events: 1}) {
+		t.Errorf("expected Pod then Node as top kinds, got %+v", top)
+	}
+
+	// the events of the first minute leave the last hour
+	current = start.Add(time.Hour + time.Minute)
+	summary = Get()
+	if summary.Seen != 6 || summary.LastHour.Seen != 2 {
+		t.Errorf("expected 6 events seen, 2 in the last hour, got %+v", summary)
+	}
+	if top := summary.LastHour.TopNamespaces; len(top) != 2 || top[0].Name != "team-b" {
+		t.Errorf("expected only team-b in the last hour, got %+v", top)
+	}
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/logging"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Kind is the kind of the notifications about event storms
+const Kind = "EventStorm"
+
+// topEntries is the number of kinds and namespaces listed in storm summaries
+const topEntries = 5
+
+// Handler wraps a handler, summarizing events while more than Threshold events arrive
+// within Window. The first event above the threshold starts a storm, notified once; the
+// events of the storm are then counted instead of being sent, and a summary of their
+// counts is sent once fewer than Threshold events arrive within a window. Critical events
+// are always sent.
+type Handler struct {
+	Threshold   int
+	Window      time.Duration
+	ClusterName string
+	Handler     handlers.Handler
+	now         func() time.Time
+
+	mu sync.Mutex
+	// arrivals holds the arrival times of the events of the current window
+	arrivals []time.Time
+	// storm is the ongoing storm, nil when events are sent as they come
+	storm *storm
+}
+
+// storm counts the events summarized during a storm
+type storm struct {
+	id      string
+	started time.Time
+	events  int
+	// period and recent count the events of the current window, to tell when the storm ends
+	period      time.Time
+	recent      int
+	byChange    map[string]int
+	byNamespace map[string]int
+	byCluster   map[string]int
+}
+
+// NewHandler returns the handler configured by conf.Storm, wrapping handler
+func NewHandler(conf *config.Config, handler handlers.Handler) *Handler {
+	return &Handler{
+		Threshold:   conf.Storm.Threshold,
+		Window:      conf.Storm.Window,
+		ClusterName: conf.ClusterName,
+		Handler:     handler,
+		now:         time.Now,
+	}
+}
+
+// Init initializes the wrapped handler
+func (h *Handler) Init(c *config.Config) error {
+	return h.Handler.Init(c)
+}
+
+// Handle sends the event to the wrapped handler, or counts it during storms.
+func (h *Handler) Handle(e event.Event) {
+	if err := h.HandleWithError(e); err != nil {
+		logging.HandlerLogger("storm", e).Errorf("Error sending event: %v", err)
+	}
+}
+
+// HandleWithError sends the event to the wrapped handler, or counts it during storms,
+// reporting delivery failures when the handler reports them.
+func (h *Handler) HandleWithError(e event.Event) error {
+	return h.HandleContext(context.Background(), e)
+}
+
+// HandleContext delivers e to the wrapped handler with ctx, or counts it while a storm
+// goes on
+func (h *Handler) HandleContext(ctx context.Context, e event.Event) error {
+	critical := e.ResolvedSeverity() == event.SeverityCritical
+	h.mu.Lock()
+	now := h.now()
+	if s := h.storm; s != nil {
+		s.recent++
+		if !critical {
+			s.add(e)
+			h.mu.Unlock()
+			return nil
+		}
+		h.mu.Unlock()
+		return h.send(ctx, e)
+	}
+
+	cutoff := now.Add(-h.Window)
+	first := sort.Search(len(h.arrivals), func(i int) bool { return h.arrivals[i].After(cutoff) })
+	h.arrivals = append(h.arrivals[first:], now)
+	if len(h.arrivals) <= h.Threshold {
+		h.mu.Unlock()
+		return h.send(ctx, e)
+	}
+
+	s := &storm{
+		id:          uuid.New().String(),
+		started:     now,
+		period:      now,
+		recent:      1,
+		byChange:    map[string]int{},
+		byNamespace: map[string]int{},
+		byCluster:   map[string]int{},
+	}
+	if !critical {
+		s.add(e)
+	}
+	h.storm = s
+	arrivals := h.arrivals
+	h.arrivals = nil
+	started := h.notification(s, "Warning", fmt.Sprintf("%d events in the last %s, notifications are summarized until the storm ends", h.Threshold+1, h.Window))
+	h.mu.Unlock()
+
+	metrics.StormActive.Set(1)
+	logrus.WithField("pkg", "kubewatch-storm").Warnf("Event storm started, more than %d events in %s", h.Threshold, h.Window)
+	if err := h.send(ctx, started); err != nil {
+		// the storm did not start, the retry of e starts it again
+		h.mu.Lock()
+		if h.storm == s {
+			h.storm, h.arrivals = nil, arrivals[:len(arrivals)-1]
+			metrics.StormActive.Set(0)
+		}
+		h.mu.Unlock()
+		return err
+	}
+	if !critical {
+		return nil
+	}
+	return h.send(ctx, e)
+}
+
+// check ends the ongoing storm once fewer than Threshold events arrived within a window,
+// sending its summary
+func (h *Handler) check() {
+	h.mu.Lock()
+	now := h.now()
+	s := h.storm
+	if s == nil || now.Sub(s.period) < h.Window {
+		h.mu.Unlock()
+		return
+	}
+	if s.recent > h.Threshold {
+		s.period, s.recent = now, 0
+		h.mu.Unlock()
+		return
+	}
+	h.storm = nil
+	h.mu.Unlock()
+	h.end(s, now)
+}
+
+// end sends the summary of storm s
+func (h *Handler) end(s *storm, now time.Time) {
+	metrics.StormActive.Set(0)
+	logrus.WithField("pkg", "kubewatch-storm").Infof("Event storm ended, %d events summarized", s.events)
+	if err := h.send(context.Background(), h.notification(s, "Normal", s.summary(now.Sub(s.started)))); err != nil {
+		logrus.WithField("pkg", "kubewatch-storm").Errorf("Error sending event storm summary: %v", err)
+	}
+}
+
+// Run ends storms once they calm down, until stopCh is closed
+func (h *Handler) Run(stopCh <-chan struct{}) {
+	wait.Until(h.check, h.Window/4, stopCh)
+}
+
+// Flush sends the summary of the ongoing storm and flushes the wrapped handler when it
+// buffers events
+func (h *Handler) Flush() error {
+	h.mu.Lock()
+	s := h.storm
+	h.storm = nil
+	h.mu.Unlock()
+	if s != nil {
+		h.end(s, h.now())
+	}
+	if f, ok := h.Handler.(handlers.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (h *Handler) send(ctx context.Context, e event.Event) error {
+	return handlers.Deliver(ctx, h.Handler, e)
+}
+
+// notification returns the notification about storm s
+func (h *Handler) notification(s *storm, status string, reason string) event.Event {
+	return event.Event{
+		ID:            uuid.New().String(),
+		CorrelationID: s.id,
+		Kind:          Kind,
+		Reason:        reason,
+		Status:        status,
+		Severity:      event.SeverityFromStatus(status),
+		Timestamp:     h.now(),
+		ClusterName:   h.ClusterName,
+	}
+}
+
+func (s *storm) add(e event.Event) {
+	s.events++
+	change := e.Kind
+	if e.Operation() != "notify" {
+		change += " " + strings.ToLower(e.Reason)
+	}
+	s.byChange[change]++
+	if e.Namespace != "" {
+		s.byNamespace[e.Namespace]++
+	}
+	if e.ClusterName != "" {
+		s.byCluster[e.ClusterName]++
+	}
+}
+
+// summary describes the events counted during the storm
+func (s *storm) summary(duration time.Duration) string {
+	lines := []string{fmt.Sprintf("Storm ended after %s, %d events summarized", duration.Round(time.Second), s.events)}
+	for _, entry := range top(s.byChange) {
+		lines = append(lines, fmt.Sprintf("%s: %d", entry.name, entry.count))
+	}
+	if namespaces := top(s.byNamespace); len(namespaces) > 0 {
+		lines = append(lines, "Namespaces: "+format(namespaces))
+	}
+	if clusters := top(s.byCluster); len(clusters) > 1 {
+		lines = append(lines, "Clusters: "+format(clusters))
+	}
+	return strings.Join(lines, "\n")
+}
+
+type entry struct {
+	name  string
+	count int
+}
+
+// top returns the topEntries entries of counts with the highest counts
+func top(counts map[string]int) []entry {
+	entries := make([]entry, 0, len(counts))
+	for name, count := range counts {
+		entries = append(entries, entry{name, count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].name < entries[j].name
+	})
+	if len(entries) > topEntries {
+		entries = entries[:topEntries]
+	}
+	return entries
+}
+
+func format(entries []entry) string {
+	formatted := make([]string, len(entries))
+	for i, e := range entries {
+		formatted[i] = fmt.Sprintf("%s (%d)", e.name, e.count)
+	}
+	return strings.Join(formatted, ", ")
+}
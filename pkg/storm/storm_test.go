@@ -0,0 +1,183 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// sink records the events it delivers, after failing to deliver the first failures events
+// of kind failing
+type sink struct {
+	delivered []event.Event
+	failing   string
+	failures  int
+}
+
+func (s *sink) Init(c *config.Config) error {
+	return nil
+}
+
+func (s *sink) Handle(e event.Event) {
+	s.delivered = append(s.delivered, e)
+}
+
+func (s *sink) HandleWithError(e event.Event) error {
+	if s.failures > 0 && e.Kind == s.failing {
+		s.failures--
+		return errors.New("endpoint unavailable")
+	}
+	s.Handle(e)
+	return nil
+}
+
+func TestStorm(t *testing.T) {
+	current := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &sink{}
+	h := NewHandler(&config.Config{Storm: config.Storm{Threshold: 3, Window: time.Minute}}, s)
+	h.now = func() time.Time { return current }
+
+	pod := func(namespace, reason string) event.Event {
+		return event.Event{Kind: "Pod", Namespace: namespace, Name: "web", Reason: reason, Status: "Normal"}
+	}
+
+	// events spread over more than a window are sent
+	for i := 0; i < 5; i++ {
+		h.Handle(pod("default", "Updated"))
+		current = current.Add(30 * time.Second)
+	}
+	if len(s.delivered) != 5 {
+		t.Fatalf("sent %d events below the threshold, expected 5", len(s.delivered))
+	}
+
+	// a burst starts a storm
+	s.delivered = nil
+	current = current.Add(time.Hour)
+	for i := 0; i < 10; i++ {
+		namespace := "payments"
+		if i%3 == 0 {
+			namespace = "default"
+		}
+		reason := "Updated"
+		if i == 9 {
+			reason = "Deleted"
+		}
+		h.Handle(pod(namespace, reason))
+		current = current.Add(time.Second)
+	}
+	if len(s.delivered) != 4 {
+		t.Fatalf("sent %d events during the burst, expected 3 events and the storm notification", len(s.delivered))
+	}
+	started := s.delivered[3]
+	if started.Kind != Kind || started.Status != "Warning" || !strings.Contains(started.Reason, "summarized") {
+		t.Errorf("storm notification = %+v", started)
+	}
+
+	// the storm goes on while events keep arriving, and ends once they calm down
+	h.check()
+	current = current.Add(time.Minute)
+	h.check()
+	if len(s.delivered) != 4 {
+		t.Fatalf("storm ended while events kept arriving")
+	}
+	current = current.Add(time.Minute)
+	h.check()
+	if len(s.delivered) != 5 {
+		t.Fatalf("sent %d events, expected the storm summary", len(s.delivered))
+	}
+	summary := s.delivered[4]
+	expected := "Storm ended after 2m7s, 7 events summarized\nPod updated: 6\nPod deleted: 1\nNamespaces: payments (4), default (3)"
+	if summary.Reason != expected || summary.Status != "Normal" {
+		t.Errorf("storm summary = %q, expected %q", summary.Reason, expected)
+	}
+	if summary.CorrelationID != started.CorrelationID {
+		t.Errorf("storm notifications have different correlation IDs %q and %q", started.CorrelationID, summary.CorrelationID)
+	}
+
+	// events are sent again once the storm ended
+	h.Handle(pod("default", "Created"))
+	if len(s.delivered) != 6 || s.delivered[5].Kind != "Pod" {
+		t.Errorf("event after the storm was not sent")
+	}
+}
+
+func TestCriticalEventsDuringStorm(t *testing.T) {
+	s := &sink{}
+	h := NewHandler(&config.Config{Storm: config.Storm{Threshold: 1, Window: time.Minute}}, s)
+	h.Handle(event.Event{Kind: "Pod", Name: "web", Reason: "Updated", Status: "Normal"})
+	h.Handle(event.Event{Kind: "Node", Name: "node-1", Reason: "Updated", Status: "Danger"})
+	h.Handle(event.Event{Kind: "Pod", Name: "web", Reason: "Updated", Status: "Normal"})
+	h.Handle(event.Event{Kind: "Node", Name: "node-2", Reason: "Deleted", Status: "Danger"})
+
+	var sent []string
+	for _, e := range s.delivered {
+		sent = append(sent, e.Kind+" "+e.Name)
+	}
+	expected := "Pod web, EventStorm , Node node-1, Node node-2"
+	if strings.Join(sent, ", ") != expected {
+		t.Errorf("sent %q, expected the critical events besides the storm notification: %q", strings.Join(sent, ", "), expected)
+	}
+	if h.storm == nil || h.storm.events != 1 || h.storm.recent != 3 {
+		t.Errorf("storm = %+v, expected the critical events counted as arrivals only", h.storm)
+	}
+}
+
+func TestFailedStormStart(t *testing.T) {
+	s := &sink{failing: Kind, failures: 1}
+	h := NewHandler(&config.Config{Storm: config.Storm{Threshold: 1, Window: time.Minute}}, s)
+	pod := event.Event{Kind: "Pod", Name: "web", Reason: "Updated", Status: "Normal"}
+	if err := h.HandleWithError(pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.HandleWithError(pod); err == nil {
+		t.Fatal("expected the failed storm notification to be reported")
+	}
+	if h.storm != nil {
+		t.Fatalf("storm = %+v, expected the storm not to start", h.storm)
+	}
+
+	// the retried event starts the storm and is counted once
+	if err := h.HandleWithError(pod); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.delivered) != 2 || s.delivered[1].Kind != Kind {
+		t.Errorf("sent %+v, expected the event and the storm notification", s.delivered)
+	}
+	if h.storm == nil || h.storm.events != 1 || h.storm.recent != 1 {
+		t.Errorf("storm = %+v, expected the retried event counted once", h.storm)
+	}
+}
+
+func TestFlushEndsStorm(t *testing.T) {
+	s := &sink{}
+	h := NewHandler(&config.Config{Storm: config.Storm{Threshold: 1, Window: time.Minute}}, s)
+	for i := 0; i < 3; i++ {
+		h.Handle(event.Event{Kind: "Pod", Name: "web", Reason: "Updated", Status: "Normal"})
+	}
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.delivered) != 3 || !strings.HasPrefix(s.delivered[2].Reason, "Storm ended") {
+		t.Errorf("Flush() did not send the storm summary: %+v", s.delivered)
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Path is where the dashboard is served on the metrics server
+const Path = "/ui/"
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the dashboard under Path. It reads the recent events, statistics and
+// health of kubewatch from the endpoints of the metrics server.
+func Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(Path, http.FileServer(http.FS(files)))
+}
@@ -40,7 +40,7 @@ func GetClient() kubernetes.Interface {
 		logrus.Fatalf("Can not get kubernetes config: %v", err)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfig(WithProtobuf(config))
 	if err != nil {
 		logrus.Fatalf("Can not create kubernetes client: %v", err)
 	}
@@ -48,12 +48,88 @@ func GetClient() kubernetes.Interface {
 	return clientset
 }
 
+// WithProtobuf returns a copy of config requesting protobuf encoded objects, which are
+// cheaper to serve and decode than JSON. It only applies to clients of built-in types:
+// custom resources are only served as JSON.
+func WithProtobuf(config *rest.Config) *rest.Config {
+	config = rest.CopyConfig(config)
+	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+	config.ContentType = "application/vnd.kubernetes.protobuf"
+	return config
+}
+
+// KubeconfigOptions select the cluster and identity used by kubewatch, like the kubectl flags of the same names
+type KubeconfigOptions struct {
+	// Kubeconfig is the kubeconfig file to use instead of $KUBECONFIG or ~/.kube/config
+	Kubeconfig string
+	// Context is the kubeconfig context to use instead of the current one
+	Context string
+	// As and AsGroups are the user and groups to impersonate
+	As       string
+	AsGroups []string
+}
+
+// KubeconfigFlags are the KubeconfigOptions set on the command line, they apply to every cluster
+var KubeconfigFlags KubeconfigOptions
+
 func buildOutOfClusterConfig() (*rest.Config, error) {
-	kubeconfigPath := os.Getenv("KUBECONFIG")
+	config, _, err := GetClusterConfig("", "")
+	return config, err
+}
+
+// GetConfig returns the in-cluster config, or the out-of-cluster one when not running in a cluster
+// or when a kubeconfig file or context is selected by KubeconfigFlags
+func GetConfig() (*rest.Config, error) {
+	if KubeconfigFlags.Kubeconfig == "" && KubeconfigFlags.Context == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			return impersonate(config), nil
+		}
+	}
+	return buildOutOfClusterConfig()
+}
+
+// GetClusterConfig returns the config of a kubeconfig context along with the resolved context name.
+// Empty kubeconfigPath and context fall back to KubeconfigFlags, then to the default loading rules,
+// which merge the files listed in $KUBECONFIG, and the current context.
+func GetClusterConfig(kubeconfigPath, context string) (*rest.Config, string, error) {
 	if kubeconfigPath == "" {
-		kubeconfigPath = os.Getenv("HOME") + "/.kube/config"
+		kubeconfigPath = KubeconfigFlags.Kubeconfig
+	}
+	if context == "" {
+		context = KubeconfigFlags.Context
 	}
-	return clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfigPath != "" {
+		rules.ExplicitPath = kubeconfigPath
+	}
+	// exec credential plugins may prompt the user when kubewatch runs in a terminal
+	clientConfig := clientcmd.NewInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: context}, os.Stdin)
+
+	if context == "" {
+		rawConfig, err := clientConfig.RawConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		context = rawConfig.CurrentContext
+	}
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, context, err
+	}
+	return impersonate(config), context, nil
+}
+
+// impersonate sets the impersonated user and groups of KubeconfigFlags on config
+func impersonate(config *rest.Config) *rest.Config {
+	if KubeconfigFlags.As != "" {
+		config.Impersonate.UserName = KubeconfigFlags.As
+	}
+	if len(KubeconfigFlags.AsGroups) > 0 {
+		config.Impersonate.Groups = KubeconfigFlags.AsGroups
+	}
+	return config
 }
 
 // GetClientOutOfCluster returns a k8s clientset to the request from outside of cluster
@@ -63,7 +139,7 @@ func GetClientOutOfCluster() kubernetes.Interface {
 		logrus.Fatalf("Can not get kubernetes config: %v", err)
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfig(WithProtobuf(config))
 	if err != nil {
 		logrus.Fatalf("Can not get kubernetes config: %v", err)
 	}
@@ -104,6 +180,8 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *batch_v1.Job:
 		objectMeta = object.ObjectMeta
+	case *batch_v1.CronJob:
+		objectMeta = object.ObjectMeta
 	case *api_v1.PersistentVolume:
 		objectMeta = object.ObjectMeta
 	case *api_v1.Namespace:
@@ -132,6 +210,8 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *events_v1.Event:
 		objectMeta = object.ObjectMeta
+	case *meta_v1.PartialObjectMetadata:
+		objectMeta = object.ObjectMeta
 	}
 	return objectMeta
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const kubeconfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: https://%[1]s.example.com
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: %[1]s-token
+`
+
+func writeKubeconfig(t *testing.T, name string, current bool) string {
+	content := fmt.Sprintf(kubeconfigTemplate, name)
+	if current {
+		content += "current-context: " + name + "\n"
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGetClusterConfig(t *testing.T) {
+	staging := writeKubeconfig(t, "staging", true)
+	prod := writeKubeconfig(t, "prod", false)
+	t.Setenv("KUBECONFIG", staging+string(filepath.ListSeparator)+prod)
+
+	var Tests = []struct {
+		name    string
+		flags   func()
+		arg     string
+		context string
+		host    string
+		user    string
+	}{
+		{"current context of the KUBECONFIG list", func() {}, "", "staging", "https://staging.example.com", ""},
+		{"context of the KUBECONFIG list", func() { KubeconfigFlags.Context = "prod" }, "", "prod", "https://prod.example.com", ""},
+		{"kubeconfig flag", func() { KubeconfigFlags.Kubeconfig = staging }, "", "staging", "https://staging.example.com", ""},
+		{"cluster settings override flags", func() { KubeconfigFlags.Context = "staging" }, "prod", "prod", "https://prod.example.com", ""},
+		{"impersonation", func() { KubeconfigFlags.As = "jane" }, "", "staging", "https://staging.example.com", "jane"},
+	}
+
+	for _, tt := range Tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { KubeconfigFlags = KubeconfigOptions{} }()
+			tt.flags()
+
+			config, context, err := GetClusterConfig("", tt.arg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if context != tt.context || config.Host != tt.host || config.Impersonate.UserName != tt.user {
+				t.Errorf("expected context %s, host %s and user %q, got %s, %s and %q", tt.context, tt.host, tt.user, context, config.Host, config.Impersonate.UserName)
+			}
+		})
+	}
+}